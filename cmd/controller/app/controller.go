@@ -28,10 +28,13 @@ import (
 	nuclioioclient "github.com/nuclio/nuclio/pkg/platform/kube/client/clientset/versioned"
 	"github.com/nuclio/nuclio/pkg/platform/kube/controller"
 	"github.com/nuclio/nuclio/pkg/platform/kube/functionres"
+	"github.com/nuclio/nuclio/pkg/platform/kube/httproute"
 	"github.com/nuclio/nuclio/pkg/platform/kube/ingress"
+	"github.com/nuclio/nuclio/pkg/platform/kube/keda"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 
 	"github.com/nuclio/errors"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 
@@ -168,8 +171,29 @@ func createController(kubeconfigPath string,
 		return nil, errors.Wrap(err, "Failed to create nuclio client set")
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create k8s dynamic client")
+	}
+
+	// create HTTPRoute manager
+	httpRouteManager, err := httproute.NewManager(rootLogger, dynamicClient, platformConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create HTTPRoute manager")
+	}
+
+	// create KEDA manager
+	kedaManager, err := keda.NewManager(rootLogger, dynamicClient, platformConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create KEDA manager")
+	}
+
 	// create a client for function deployments
-	functionresClient, err := functionres.NewLazyClient(rootLogger, kubeClientSet, nuclioClientSet)
+	functionresClient, err := functionres.NewLazyClient(rootLogger,
+		kubeClientSet,
+		nuclioClientSet,
+		httpRouteManager,
+		kedaManager)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to create function deployment client")
 	}
@@ -187,7 +211,11 @@ func createController(kubeconfigPath string,
 	}
 
 	// create api gateway provisioner
-	apigatewayresClient, err := apigatewayres.NewLazyClient(rootLogger, kubeClientSet, nuclioClientSet, ingressManager)
+	apigatewayresClient, err := apigatewayres.NewLazyClient(rootLogger,
+		kubeClientSet,
+		nuclioClientSet,
+		ingressManager,
+		httpRouteManager)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to create api gateway provisioner")
 	}
@@ -32,7 +32,9 @@ import (
 	"github.com/nuclio/nuclio/pkg/dashboard/healthcheck"
 	"github.com/nuclio/nuclio/pkg/dockerclient"
 	"github.com/nuclio/nuclio/pkg/loggersink"
+	"github.com/nuclio/nuclio/pkg/platform"
 	"github.com/nuclio/nuclio/pkg/platform/factory"
+	"github.com/nuclio/nuclio/pkg/platform/local/apigatewayproxy"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/restful"
 
@@ -75,7 +77,15 @@ func Run(listenAddress string,
 	authConfigIguazioVerificationDataEnrichmentURL string,
 	authConfigIguazioCacheSize string,
 	authConfigIguazioCacheExpirationTimeout string,
-	authConfigIguazioVerificationMethod string) error {
+	authConfigIguazioVerificationMethod string,
+	authConfigOIDCIssuerURL string,
+	authConfigOIDCJWKSURL string,
+	authConfigOIDCAudience string,
+	authConfigOIDCUsernameClaim string,
+	authConfigOIDCUserIDClaim string,
+	authConfigOIDCGroupIDsClaim string,
+	authConfigOIDCCacheExpirationTimeout string,
+	authConfigOIDCSkipTLSVerification bool) error {
 
 	// get platform configuration
 	platformConfiguration, err := platformconfig.NewPlatformConfig(platformConfigurationPath)
@@ -119,6 +129,19 @@ func Run(listenAddress string,
 			return errors.Wrap(err, "Failed to enrich auth config")
 		}
 	}
+	if authConfig.OIDC != nil {
+		if err := enrichOIDCAuthConfig(authConfig,
+			authConfigOIDCIssuerURL,
+			authConfigOIDCJWKSURL,
+			authConfigOIDCAudience,
+			authConfigOIDCUsernameClaim,
+			authConfigOIDCUserIDClaim,
+			authConfigOIDCGroupIDsClaim,
+			authConfigOIDCCacheExpirationTimeout,
+			authConfigOIDCSkipTLSVerification); err != nil {
+			return errors.Wrap(err, "Failed to enrich oidc auth config")
+		}
+	}
 
 	dashboardInstance.server, err = newDashboardServer(&CreateDashboardServerOptions{
 		logger:                dashboardInstance.logger,
@@ -167,10 +190,15 @@ func Run(listenAddress string,
 			return errors.Wrapf(err, "Failed to parse string '%s' to duration", monitorDockerDeamonIntervalStr)
 		}
 
-		// create docker client
-		dockerClient, err := dockerclient.NewShellClient(rootLogger, nil)
+		// create docker client, of the kind the platform uses
+		var dockerClientKind dockerclient.ClientKind
+		if platformConfiguration.ContainerBuilderConfiguration != nil {
+			dockerClientKind = platformConfiguration.ContainerBuilderConfiguration.DockerClientKind
+		}
+
+		dockerClient, err := dockerclient.NewClient(rootLogger, nil, dockerClientKind)
 		if err != nil {
-			return errors.Wrap(err, "Failed to create docker shell client")
+			return errors.Wrap(err, "Failed to create docker client")
 		}
 
 		ctx, cancel := context.WithCancel(context.Background())
@@ -181,6 +209,16 @@ func Run(listenAddress string,
 		defer cancel()
 	}
 
+	// serve api gateways of the local platform, until the dashboard stops
+	if platformInstance.GetName() == common.LocalPlatformName && platformConfiguration.Local.APIGatewayProxy.Enabled {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		if err := createAndStartAPIGatewayProxy(ctx, platformConfiguration, rootLogger, platformInstance); err != nil {
+			return errors.Wrap(err, "Failed to create and start api gateway proxy")
+		}
+	}
+
 	if platformInstance.GetName() == common.KubePlatformName {
 		rest.SetDefaultWarningHandler(common.NewKubernetesClientWarningHandler(rootLogger.GetChild("kube_warnings")))
 	}
@@ -239,6 +277,47 @@ func enrichAuthConfig(authConfig *auth.Config,
 	return nil
 }
 
+func enrichOIDCAuthConfig(authConfig *auth.Config,
+	authConfigOIDCIssuerURL string,
+	authConfigOIDCJWKSURL string,
+	authConfigOIDCAudience string,
+	authConfigOIDCUsernameClaim string,
+	authConfigOIDCUserIDClaim string,
+	authConfigOIDCGroupIDsClaim string,
+	authConfigOIDCCacheExpirationTimeout string,
+	authConfigOIDCSkipTLSVerification bool) error {
+	var err error
+
+	if authConfigOIDCIssuerURL == "" {
+		return errors.New("OIDC issuer url must be provided")
+	}
+
+	authConfig.OIDC.IssuerURL = authConfigOIDCIssuerURL
+	authConfig.OIDC.JWKSURL = authConfigOIDCJWKSURL
+	authConfig.OIDC.Audience = authConfigOIDCAudience
+	authConfig.OIDC.SkipTLSVerification = authConfigOIDCSkipTLSVerification
+
+	if authConfigOIDCUsernameClaim != "" {
+		authConfig.OIDC.UsernameClaim = authConfigOIDCUsernameClaim
+	}
+
+	if authConfigOIDCUserIDClaim != "" {
+		authConfig.OIDC.UserIDClaim = authConfigOIDCUserIDClaim
+	}
+
+	if authConfigOIDCGroupIDsClaim != "" {
+		authConfig.OIDC.GroupIDsClaim = authConfigOIDCGroupIDsClaim
+	}
+
+	if authConfigOIDCCacheExpirationTimeout != "" {
+		authConfig.OIDC.CacheExpirationTimeout, err = time.ParseDuration(authConfigOIDCCacheExpirationTimeout)
+		if err != nil {
+			return errors.Wrap(err, "Failed to parse auth config oidc cache expiration timeout")
+		}
+	}
+	return nil
+}
+
 func newDashboardServer(createDashboardServerOptions *CreateDashboardServerOptions) (restful.Server, error) {
 	rootLogger := createDashboardServerOptions.logger
 	var err error
@@ -402,6 +481,23 @@ func createAndStartHealthCheckServer(platformConfiguration *platformconfig.Confi
 	return server, nil
 }
 
+func createAndStartAPIGatewayProxy(ctx context.Context,
+	platformConfiguration *platformconfig.Config,
+	loggerInstance logger.Logger,
+	platformInstance platform.Platform) error {
+
+	proxy, err := apigatewayproxy.NewProxy(loggerInstance, platformInstance, &platformConfiguration.Local.APIGatewayProxy)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create api gateway proxy")
+	}
+
+	if err := proxy.Start(ctx); err != nil {
+		return errors.Wrap(err, "Failed to start api gateway proxy")
+	}
+
+	return nil
+}
+
 func getDefaultCredRefreshInterval(logger logger.Logger, defaultCredRefreshIntervalString string) *time.Duration {
 	var defaultCredRefreshInterval time.Duration
 	defaultInterval := 12 * time.Hour
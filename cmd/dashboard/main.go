@@ -66,13 +66,21 @@ func main() {
 	monitorDockerDeamonMaxConsecutiveErrorsStr := flag.String("monitor-docker-deamon-max-consecutive-errors", common.GetEnvOrDefaultString("NUCLIO_MONITOR_DOCKER_DAEMON_MAX_CONSECUTIVE_ERRORS", "5"), "Docker deamon connectivity monitor max consecutive errors before declaring docker connection is unhealthy (used in conjunction with 'monitor-docker-deamon')")
 
 	// auth options
-	authConfigKind := flag.String("auth-config-kind", common.GetEnvOrDefaultString("NUCLIO_AUTH_KIND", "nop"), "Authentication kind, either nop or iguazio")
+	authConfigKind := flag.String("auth-config-kind", common.GetEnvOrDefaultString("NUCLIO_AUTH_KIND", "nop"), "Authentication kind, one of nop, iguazio or oidc")
 	authConfigIguazioVerificationURL := flag.String("auth-config-iguazio-verification-url", common.GetEnvOrDefaultString("NUCLIO_AUTH_IGUAZIO_VERIFICATION_URL", ""), "Iguazio authentication verification url")
 	authConfigIguazioVerificationMethod := flag.String("auth-config-iguazio-verification-method", common.GetEnvOrDefaultString("NUCLIO_AUTH_IGUAZIO_VERIFICATION_METHOD", "POST"), "Iguazio authentication verification method")
 	authConfigIguazioVerificationDataEnrichmentURL := flag.String("auth-config-iguazio-verification-data-enrichment-url", common.GetEnvOrDefaultString("NUCLIO_AUTH_IGUAZIO_VERIFICATION_DATA_ENRICHMENT_URL", ""), "Iguazio authentication verification and data enrichment url")
 	authConfigIguazioTimeout := flag.String("auth-config-iguazio-timeout", common.GetEnvOrDefaultString("NUCLIO_AUTH_IGUAZIO_TIMEOUT", ""), "Iguazio authentication request timeout (golang duration string)")
 	authConfigIguazioCacheSize := flag.String("auth-config-iguazio-cache-size", common.GetEnvOrDefaultString("NUCLIO_AUTH_IGUAZIO_CACHE_SIZE", ""), "Iguazio authentication cache size")
 	authConfigIguazioCacheTimeout := flag.String("auth-config-iguazio-cache-expiration-timeout", common.GetEnvOrDefaultString("NUCLIO_AUTH_IGUAZIO_CACHE_EXPIRATION_TIMEOUT", "30s"), "Iguazio authentication cache expiration timeout (golang duration string)")
+	authConfigOIDCIssuerURL := flag.String("auth-config-oidc-issuer-url", common.GetEnvOrDefaultString("NUCLIO_AUTH_OIDC_ISSUER_URL", ""), "OIDC issuer url, which tokens must be issued by")
+	authConfigOIDCJWKSURL := flag.String("auth-config-oidc-jwks-url", common.GetEnvOrDefaultString("NUCLIO_AUTH_OIDC_JWKS_URL", ""), "OIDC issuer JWKS url (discovered from the issuer if not given)")
+	authConfigOIDCAudience := flag.String("auth-config-oidc-audience", common.GetEnvOrDefaultString("NUCLIO_AUTH_OIDC_AUDIENCE", ""), "OIDC audience tokens must be issued for (not verified if not given)")
+	authConfigOIDCUsernameClaim := flag.String("auth-config-oidc-username-claim", common.GetEnvOrDefaultString("NUCLIO_AUTH_OIDC_USERNAME_CLAIM", ""), "OIDC token claim holding the username")
+	authConfigOIDCUserIDClaim := flag.String("auth-config-oidc-user-id-claim", common.GetEnvOrDefaultString("NUCLIO_AUTH_OIDC_USER_ID_CLAIM", ""), "OIDC token claim holding the user ID")
+	authConfigOIDCGroupIDsClaim := flag.String("auth-config-oidc-group-ids-claim", common.GetEnvOrDefaultString("NUCLIO_AUTH_OIDC_GROUP_IDS_CLAIM", ""), "OIDC token claim holding the group IDs (nested claims are separated by dots)")
+	authConfigOIDCCacheTimeout := flag.String("auth-config-oidc-cache-expiration-timeout", common.GetEnvOrDefaultString("NUCLIO_AUTH_OIDC_CACHE_EXPIRATION_TIMEOUT", ""), "OIDC authentication cache expiration timeout (golang duration string)")
+	authConfigOIDCSkipTLSVerification := flag.Bool("auth-config-oidc-skip-tls-verification", common.GetEnvOrDefaultBool("NUCLIO_AUTH_OIDC_SKIP_TLS_VERIFICATION", false), "Skip TLS verification when talking to the OIDC issuer")
 
 	// get the namespace from args -> env -> default
 	*namespace = common.ResolveNamespace(*namespace, "NUCLIO_DASHBOARD_NAMESPACE")
@@ -111,6 +119,14 @@ func main() {
 		*authConfigIguazioCacheSize,
 		*authConfigIguazioCacheTimeout,
 		*authConfigIguazioVerificationMethod,
+		*authConfigOIDCIssuerURL,
+		*authConfigOIDCJWKSURL,
+		*authConfigOIDCAudience,
+		*authConfigOIDCUsernameClaim,
+		*authConfigOIDCUserIDClaim,
+		*authConfigOIDCGroupIDsClaim,
+		*authConfigOIDCCacheTimeout,
+		*authConfigOIDCSkipTLSVerification,
 	); err != nil {
 
 		errors.PrintErrorStack(os.Stderr, err, 5)
@@ -51,6 +51,7 @@ import (
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 	"github.com/v3io/version-go"
 
 	// load all runtimes
@@ -63,6 +64,8 @@ import (
 	_ "github.com/nuclio/nuclio/pkg/processor/runtime/shell"
 	// load all triggers
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/cron"
+	_ "github.com/nuclio/nuclio/pkg/processor/trigger/filewatch"
+	_ "github.com/nuclio/nuclio/pkg/processor/trigger/grpc"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/kafka"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/kickstart"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/kinesis"
@@ -70,6 +73,7 @@ import (
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/mqtt/iotcore"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/nats"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/partitioned/eventhub"
+	_ "github.com/nuclio/nuclio/pkg/processor/trigger/poller/httppoller"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/poller/v3ioitempoller"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/pubsub"
 	_ "github.com/nuclio/nuclio/pkg/processor/trigger/rabbitmq"
@@ -82,7 +86,10 @@ import (
 type Processor struct {
 	logger                    logger.Logger
 	functionLogger            logger.Logger
+	configuration             *processor.Configuration
 	triggers                  []trigger.Trigger
+	triggersLock              sync.RWMutex
+	controlMessageBroker      *controlcommunication.AbstractControlMessageBroker
 	webAdminServer            *webadmin.Server
 	healthCheckServer         commonhealthcheck.Server
 	metricSinks               []metricsink.MetricSink
@@ -100,6 +107,7 @@ func NewProcessor(configurationPath string, platformConfigurationPath string) (*
 
 	newProcessor := &Processor{
 		namedWorkerAllocators:     worker.NewAllocatorSyncMap(),
+		controlMessageBroker:      controlcommunication.NewAbstractControlMessageBroker(),
 		stop:                      make(chan bool, 1),
 		stopRestartTriggerRoutine: make(chan bool, 1),
 		restartTriggerChan:        make(chan trigger.Trigger, 1),
@@ -149,6 +157,7 @@ func NewProcessor(configurationPath string, platformConfigurationPath string) (*
 
 	// save platform configuration in process configuration
 	processorConfiguration.PlatformConfig = platformConfiguration
+	newProcessor.configuration = processorConfiguration
 
 	if processorConfiguration.Spec.EventTimeout != "" {
 		clock.SetResolution(1 * time.Second)
@@ -210,10 +219,12 @@ func (p *Processor) Start() error {
 	// handles system signals (for now only SIGTERM)
 	go p.handleSignals()
 
-	p.logger.DebugWith("Starting triggers", "triggers", p.triggers)
+	triggers := p.GetTriggers()
+
+	p.logger.DebugWith("Starting triggers", "triggers", triggers)
 
 	// iterate over all triggers and start them
-	for _, triggerInstance := range p.triggers {
+	for _, triggerInstance := range triggers {
 		if err := triggerInstance.Start(nil); err != nil {
 			p.logger.ErrorWith("Failed to start trigger",
 				"kind", triggerInstance.GetKind(),
@@ -244,12 +255,154 @@ func (p *Processor) Start() error {
 
 	time.Sleep(5 * time.Second) // Give triggers etc time to finish
 
+	// index the logs still buffered by logger sinks
+	if err := loggersink.RegistrySingleton.Close(); err != nil {
+		return errors.Wrap(err, "Failed to close logger sinks")
+	}
+
 	return nil
 }
 
 // GetTriggers returns triggers
 func (p *Processor) GetTriggers() []trigger.Trigger {
-	return p.triggers
+	p.triggersLock.RLock()
+	defer p.triggersLock.RUnlock()
+
+	// return a copy, so that callers can iterate while triggers are added / removed
+	triggers := make([]trigger.Trigger, len(p.triggers))
+	copy(triggers, p.triggers)
+
+	return triggers
+}
+
+// CreateTrigger creates a trigger from the given configuration, starts it and adds it to the processor
+func (p *Processor) CreateTrigger(triggerName string,
+	triggerConfiguration *functionconfig.Trigger) (trigger.Trigger, error) {
+
+	p.triggersLock.Lock()
+	defer p.triggersLock.Unlock()
+
+	if _, triggerIndex := p.findTriggerByID(triggerName); triggerIndex != -1 {
+		return nil, nuclio.NewErrConflict("Trigger already exists")
+	}
+
+	triggerInstance, err := p.createTrigger(p.configuration, triggerName, *triggerConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create trigger")
+	}
+
+	if triggerInstance == nil {
+		return nil, nuclio.NewErrBadRequest(fmt.Sprintf("Unknown trigger kind: %s", triggerConfiguration.Kind))
+	}
+
+	p.logger.InfoWith("Starting created trigger",
+		"kind", triggerInstance.GetKind(),
+		"name", triggerInstance.GetName())
+
+	if err := triggerInstance.Start(nil); err != nil {
+		p.stopTriggerWorkers(triggerInstance, triggerConfiguration.WorkerAllocatorName)
+		return nil, errors.Wrap(err, "Failed to start trigger")
+	}
+
+	p.triggers = append(p.triggers, triggerInstance)
+	p.setTriggerConfiguration(triggerName, triggerConfiguration)
+	p.addTriggerToMetricSinks(triggerInstance)
+
+	return triggerInstance, nil
+}
+
+// UpdateTrigger replaces an existing trigger with one created from the given configuration. the existing
+// trigger is gracefully stopped and its checkpoint is handed to the new trigger
+func (p *Processor) UpdateTrigger(triggerID string,
+	triggerConfiguration *functionconfig.Trigger) (trigger.Trigger, error) {
+
+	p.triggersLock.Lock()
+	defer p.triggersLock.Unlock()
+
+	existingTriggerInstance, triggerIndex := p.findTriggerByID(triggerID)
+	if triggerIndex == -1 {
+		return nil, nuclio.NewErrNotFound("Trigger not found")
+	}
+
+	// create the new trigger before stopping the existing one, so that a bad configuration
+	// doesn't leave us without a trigger
+	triggerInstance, err := p.createTrigger(p.configuration, triggerID, *triggerConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create trigger")
+	}
+
+	if triggerInstance == nil {
+		return nil, nuclio.NewErrBadRequest(fmt.Sprintf("Unknown trigger kind: %s", triggerConfiguration.Kind))
+	}
+
+	p.logger.InfoWith("Stopping trigger for update",
+		"kind", existingTriggerInstance.GetKind(),
+		"name", existingTriggerInstance.GetName())
+
+	checkpoint, err := existingTriggerInstance.Stop(false)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to stop existing trigger")
+	}
+
+	if err := triggerInstance.Start(checkpoint); err != nil {
+		p.logger.WarnWith("Failed to start updated trigger, restarting existing trigger",
+			"kind", triggerInstance.GetKind(),
+			"name", triggerInstance.GetName(),
+			"err", err.Error())
+
+		if restartErr := existingTriggerInstance.Start(checkpoint); restartErr != nil {
+			p.logger.ErrorWith("Failed to restart existing trigger",
+				"kind", existingTriggerInstance.GetKind(),
+				"name", existingTriggerInstance.GetName(),
+				"err", restartErr.Error())
+		}
+
+		p.stopTriggerWorkers(triggerInstance, triggerConfiguration.WorkerAllocatorName)
+		return nil, errors.Wrap(err, "Failed to start updated trigger")
+	}
+
+	// the existing trigger is gone, release its workers
+	p.stopTriggerWorkers(existingTriggerInstance, p.getTriggerWorkerAllocatorName(triggerID))
+
+	p.triggers[triggerIndex] = triggerInstance
+	p.setTriggerConfiguration(triggerID, triggerConfiguration)
+
+	// the metrics of the new trigger replace those of the existing one
+	p.removeTriggerFromMetricSinks(existingTriggerInstance)
+	p.addTriggerToMetricSinks(triggerInstance)
+
+	return triggerInstance, nil
+}
+
+// DeleteTrigger stops a trigger and removes it from the processor
+func (p *Processor) DeleteTrigger(triggerID string, force bool) error {
+	p.triggersLock.Lock()
+	defer p.triggersLock.Unlock()
+
+	triggerInstance, triggerIndex := p.findTriggerByID(triggerID)
+	if triggerIndex == -1 {
+		return nuclio.NewErrNotFound("Trigger not found")
+	}
+
+	p.logger.InfoWith("Stopping deleted trigger",
+		"kind", triggerInstance.GetKind(),
+		"name", triggerInstance.GetName(),
+		"force", force)
+
+	if _, err := triggerInstance.Stop(force); err != nil {
+		return errors.Wrap(err, "Failed to stop trigger")
+	}
+
+	p.stopTriggerWorkers(triggerInstance, p.getTriggerWorkerAllocatorName(triggerID))
+
+	p.triggers = append(p.triggers[:triggerIndex], p.triggers[triggerIndex+1:]...)
+	p.removeTriggerFromMetricSinks(triggerInstance)
+
+	if p.configuration != nil {
+		delete(p.configuration.Spec.Triggers, triggerID)
+	}
+
+	return nil
 }
 
 // GetWorkers returns workers
@@ -257,7 +410,7 @@ func (p *Processor) GetWorkers() []*worker.Worker {
 	var workers []*worker.Worker
 
 	// iterate over the processor's triggers
-	for _, triggerInstance := range p.triggers {
+	for _, triggerInstance := range p.GetTriggers() {
 		workers = append(workers, triggerInstance.GetWorkers()...)
 	}
 
@@ -374,12 +527,16 @@ func (p *Processor) getSecretsMap(scrubber *functionconfig.Scrubber) (map[string
 
 func (p *Processor) createTriggers(processorConfiguration *processor.Configuration) ([]trigger.Trigger, error) {
 	var triggers []trigger.Trigger
-	abstractControlMessageBroker := controlcommunication.NewAbstractControlMessageBroker()
 
 	// create error group
 	errGroup, _ := errgroup.WithContext(context.Background(), p.logger)
 	lock := sync.Mutex{}
 
+	// all triggers share the same control message broker
+	if p.controlMessageBroker == nil {
+		p.controlMessageBroker = controlcommunication.NewAbstractControlMessageBroker()
+	}
+
 	platformKind := processorConfiguration.PlatformConfig.Kind
 	if processorConfiguration.Meta.Labels == nil {
 
@@ -405,20 +562,7 @@ func (p *Processor) createTriggers(processorConfiguration *processor.Configurati
 		}
 
 		errGroup.Go("Creating trigger", func() error {
-
-			// create an event source based on event source configuration and runtime configuration
-			triggerInstance, err := trigger.RegistrySingleton.NewTrigger(p.logger,
-				triggerConfiguration.Kind,
-				triggerName,
-				&triggerConfiguration,
-				&runtime.Configuration{
-					Configuration:        processorConfiguration,
-					FunctionLogger:       p.functionLogger,
-					ControlMessageBroker: abstractControlMessageBroker,
-				},
-				p.namedWorkerAllocators,
-				p.restartTriggerChan)
-
+			triggerInstance, err := p.createTrigger(processorConfiguration, triggerName, triggerConfiguration)
 			if err != nil {
 				return errors.Wrapf(err, "Failed to create triggers")
 			}
@@ -445,6 +589,110 @@ func (p *Processor) createTriggers(processorConfiguration *processor.Configurati
 	return triggers, nil
 }
 
+func (p *Processor) createTrigger(processorConfiguration *processor.Configuration,
+	triggerName string,
+	triggerConfiguration functionconfig.Trigger) (trigger.Trigger, error) {
+
+	// create an event source based on event source configuration and runtime configuration
+	return trigger.RegistrySingleton.NewTrigger(p.logger,
+		triggerConfiguration.Kind,
+		triggerName,
+		&triggerConfiguration,
+		&runtime.Configuration{
+			Configuration:        processorConfiguration,
+			FunctionLogger:       p.functionLogger,
+			ControlMessageBroker: p.controlMessageBroker,
+		},
+		p.namedWorkerAllocators,
+		p.restartTriggerChan)
+}
+
+// findTriggerByID returns the trigger and its index in the triggers list, or -1 if not found.
+// must be called while holding the triggers lock
+func (p *Processor) findTriggerByID(triggerID string) (trigger.Trigger, int) {
+	for triggerIndex, triggerInstance := range p.triggers {
+		if triggerInstance.GetID() == triggerID {
+			return triggerInstance, triggerIndex
+		}
+	}
+
+	return nil, -1
+}
+
+// triggerExists returns whether the trigger instance is one of the processor's triggers.
+// must be called while holding the triggers lock
+func (p *Processor) triggerExists(triggerInstance trigger.Trigger) bool {
+	for _, existingTriggerInstance := range p.triggers {
+		if existingTriggerInstance == triggerInstance {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setTriggerConfiguration stores the trigger configuration in the processor configuration, so that
+// it reflects the triggers that are currently running
+func (p *Processor) setTriggerConfiguration(triggerName string, triggerConfiguration *functionconfig.Trigger) {
+	if p.configuration == nil {
+		return
+	}
+
+	if p.configuration.Spec.Triggers == nil {
+		p.configuration.Spec.Triggers = map[string]functionconfig.Trigger{}
+	}
+
+	p.configuration.Spec.Triggers[triggerName] = *triggerConfiguration
+}
+
+// addTriggerToMetricSinks has the metric sinks process the metrics of a trigger that was created after them.
+// failing to do so doesn't fail the trigger, which is already running
+func (p *Processor) addTriggerToMetricSinks(triggerInstance trigger.Trigger) {
+	for _, metricSink := range p.metricSinks {
+		if err := metricSink.AddTrigger(triggerInstance); err != nil {
+			p.logger.WarnWith("Failed to add trigger to metric sink",
+				"triggerKind", triggerInstance.GetKind(),
+				"triggerName", triggerInstance.GetName(),
+				"metricSinkName", metricSink.GetName(),
+				"err", err.Error())
+		}
+	}
+}
+
+// removeTriggerFromMetricSinks has the metric sinks stop processing the metrics of a trigger that is gone
+func (p *Processor) removeTriggerFromMetricSinks(triggerInstance trigger.Trigger) {
+	for _, metricSink := range p.metricSinks {
+		metricSink.RemoveTrigger(triggerInstance)
+	}
+}
+
+// getTriggerWorkerAllocatorName returns the name of the worker allocator the trigger was created with
+func (p *Processor) getTriggerWorkerAllocatorName(triggerID string) string {
+	if p.configuration == nil {
+		return ""
+	}
+
+	return p.configuration.Spec.Triggers[triggerID].WorkerAllocatorName
+}
+
+// stopTriggerWorkers stops the workers of a trigger that is no longer used. workers of a shared
+// worker allocator are kept alive, as other triggers may still use them
+func (p *Processor) stopTriggerWorkers(triggerInstance trigger.Trigger, workerAllocatorName string) {
+	if workerAllocatorName != "" {
+		return
+	}
+
+	for _, workerInstance := range triggerInstance.GetWorkers() {
+		if err := workerInstance.Stop(); err != nil {
+			p.logger.WarnWith("Failed to stop trigger worker",
+				"triggerKind", triggerInstance.GetKind(),
+				"triggerName", triggerInstance.GetName(),
+				"workerIndex", workerInstance.GetIndex(),
+				"err", err.Error())
+		}
+	}
+}
+
 func (p *Processor) createWebAdminServer(platformConfiguration *platformconfig.Config) (*webadmin.Server, error) {
 
 	// if enabled not passed, default to true
@@ -570,6 +818,18 @@ func (p *Processor) listenOnRestartTriggerChannel() {
 
 func (p *Processor) restartTrigger(triggerInstance trigger.Trigger) error {
 
+	// hold the triggers lock throughout, so that the trigger isn't deleted or replaced while it restarts
+	p.triggersLock.Lock()
+	defer p.triggersLock.Unlock()
+
+	// the trigger may have been deleted or replaced since it asked to be restarted
+	if !p.triggerExists(triggerInstance) {
+		p.logger.InfoWith("Trigger no longer exists, skipping restart",
+			"kind", triggerInstance.GetKind(),
+			"name", triggerInstance.GetName())
+		return nil
+	}
+
 	// force stop the trigger
 	p.logger.InfoWith("Stopping trigger",
 		"kind", triggerInstance.GetKind(),
@@ -647,7 +907,7 @@ func (p *Processor) terminateAllTriggers(signal os.Signal) {
 	p.logger.WarnWith("Got system signal", "signal", signal.String())
 
 	wg := &sync.WaitGroup{}
-	for _, triggerInstance := range p.triggers {
+	for _, triggerInstance := range p.GetTriggers() {
 		wg.Add(1)
 
 		// drains all workers in trigger (for each trigger in parallel)
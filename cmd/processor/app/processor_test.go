@@ -20,6 +20,7 @@ package app
 
 import (
 	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
@@ -28,6 +29,8 @@ import (
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
+	"github.com/nuclio/nuclio/pkg/processor/metricsink"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
 
@@ -147,6 +150,10 @@ func (suite *TriggerTestSuite) TestRestartTriggers() {
 	testTriggerInstance.On("GetName").Return("testTriggerName")
 	testTriggerInstance.On("GetID").Return("testTriggerID")
 
+	processorInstance.triggersLock.Lock()
+	processorInstance.triggers = []trigger.Trigger{testTriggerInstance}
+	processorInstance.triggersLock.Unlock()
+
 	// signal the processor to stop the trigger
 	restartChannel <- testTriggerInstance
 
@@ -154,6 +161,121 @@ func (suite *TriggerTestSuite) TestRestartTriggers() {
 
 	testTriggerInstance.AssertCalled(suite.T(), "Stop", mock.Anything)
 	testTriggerInstance.AssertCalled(suite.T(), "Start", mock.Anything)
+
+	// a trigger that was deleted or replaced in the meantime isn't brought back
+	removedTriggerInstance := &testTrigger{}
+	removedTriggerInstance.On("GetKind").Return("testTriggerKind")
+	removedTriggerInstance.On("GetName").Return("testTriggerName")
+	removedTriggerInstance.On("GetID").Return("testTriggerID")
+
+	restartChannel <- removedTriggerInstance
+
+	time.Sleep(time.Second)
+
+	removedTriggerInstance.AssertNotCalled(suite.T(), "Stop", mock.Anything)
+	removedTriggerInstance.AssertNotCalled(suite.T(), "Start", mock.Anything)
+}
+
+func (suite *TriggerTestSuite) TestCreateUpdateDeleteTrigger() {
+	metricSinkInstance := &testMetricSink{
+		triggers: map[string]trigger.Trigger{},
+	}
+
+	processorInstance := Processor{
+		metricSinks:           []metricsink.MetricSink{metricSinkInstance},
+		logger:                suite.logger,
+		functionLogger:        suite.logger.GetChild("some-function-logger"),
+		namedWorkerAllocators: worker.NewAllocatorSyncMap(),
+		controlMessageBroker:  controlcommunication.NewAbstractControlMessageBroker(),
+		configuration: &processor.Configuration{
+			Config: functionconfig.Config{
+				Meta: functionconfig.Meta{
+					Labels: map[string]string{},
+				},
+				Spec: functionconfig.Spec{
+					Runtime:  "golang",
+					Handler:  "nuclio:builtin",
+					Triggers: map[string]functionconfig.Trigger{},
+				},
+			},
+			PlatformConfig: &platformconfig.Config{
+				Kind: common.LocalPlatformName,
+			},
+		},
+	}
+
+	triggerName := "dynamic-cron"
+	triggerConfiguration := &functionconfig.Trigger{
+		Kind: "cron",
+		Attributes: map[string]interface{}{
+			"interval": "24h",
+		},
+	}
+
+	// create the trigger
+	triggerInstance, err := processorInstance.CreateTrigger(triggerName, triggerConfiguration)
+	suite.Require().NoError(err)
+	suite.Require().Equal(triggerName, triggerInstance.GetID())
+	suite.Require().Len(processorInstance.GetTriggers(), 1)
+	suite.Require().Contains(processorInstance.configuration.Spec.Triggers, triggerName)
+	suite.Require().Same(triggerInstance, metricSinkInstance.triggers[triggerName])
+
+	// creating a trigger with the same name should fail
+	_, err = processorInstance.CreateTrigger(triggerName, triggerConfiguration)
+	suite.Require().Error(err)
+	suite.Require().Equal(http.StatusConflict, common.ResolveErrorStatusCodeOrDefault(err, http.StatusOK))
+
+	// update the trigger, and verify it was replaced
+	updatedTriggerConfiguration := &functionconfig.Trigger{
+		Kind: "cron",
+		Attributes: map[string]interface{}{
+			"interval": "12h",
+		},
+	}
+	updatedTriggerInstance, err := processorInstance.UpdateTrigger(triggerName, updatedTriggerConfiguration)
+	suite.Require().NoError(err)
+	suite.Require().NotSame(triggerInstance, updatedTriggerInstance)
+	suite.Require().Len(processorInstance.GetTriggers(), 1)
+	suite.Require().Same(updatedTriggerInstance, processorInstance.GetTriggers()[0])
+	suite.Require().Equal("12h",
+		processorInstance.configuration.Spec.Triggers[triggerName].Attributes["interval"])
+	suite.Require().Same(updatedTriggerInstance, metricSinkInstance.triggers[triggerName])
+
+	// updating a non existing trigger should fail
+	_, err = processorInstance.UpdateTrigger("not-exists", updatedTriggerConfiguration)
+	suite.Require().Error(err)
+	suite.Require().Equal(http.StatusNotFound, common.ResolveErrorStatusCodeOrDefault(err, http.StatusOK))
+
+	// delete the trigger
+	suite.Require().NoError(processorInstance.DeleteTrigger(triggerName, false))
+	suite.Require().Empty(processorInstance.GetTriggers())
+	suite.Require().NotContains(processorInstance.configuration.Spec.Triggers, triggerName)
+	suite.Require().Empty(metricSinkInstance.triggers)
+
+	// deleting it again should fail
+	err = processorInstance.DeleteTrigger(triggerName, false)
+	suite.Require().Error(err)
+	suite.Require().Equal(http.StatusNotFound, common.ResolveErrorStatusCodeOrDefault(err, http.StatusOK))
+}
+
+// mock metric sink
+
+type testMetricSink struct {
+	metricsink.AbstractMetricSink
+	triggers map[string]trigger.Trigger
+}
+
+func (ms *testMetricSink) AddTrigger(triggerInstance trigger.Trigger) error {
+	ms.triggers[triggerInstance.GetID()] = triggerInstance
+	return nil
+}
+
+func (ms *testMetricSink) RemoveTrigger(triggerInstance trigger.Trigger) {
+
+	// a replaced trigger is removed before the new one is added
+	if ms.triggers[triggerInstance.GetID()] == triggerInstance {
+		delete(ms.triggers, triggerInstance.GetID())
+	}
 }
 
 // mock trigger
@@ -207,6 +329,11 @@ func (t *testTrigger) GetStatistics() *trigger.Statistics {
 	return nil
 }
 
+func (t *testTrigger) GetStatisticsWindow() *trigger.StatisticsWindow {
+	t.Called()
+	return nil
+}
+
 func (t *testTrigger) GetWorkers() []*worker.Worker {
 	t.Called()
 	return nil
@@ -247,6 +374,14 @@ func (t *testTrigger) SignalWorkersToContinue() error {
 	return nil
 }
 
+func (t *testTrigger) AddObserver(observer trigger.Observer) {
+	t.Called(observer)
+}
+
+func (t *testTrigger) RemoveObserver(observer trigger.Observer) {
+	t.Called(observer)
+}
+
 func TestTriggerTestSuite(t *testing.T) {
 	suite.Run(t, new(TriggerTestSuite))
 }
@@ -20,6 +20,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/auth"
 	"github.com/nuclio/nuclio/pkg/auth/iguazio"
 	"github.com/nuclio/nuclio/pkg/auth/nop"
+	"github.com/nuclio/nuclio/pkg/auth/oidc"
 
 	"github.com/nuclio/logger"
 )
@@ -28,6 +29,8 @@ func NewAuth(logger logger.Logger, authConfig *auth.Config) auth.Auth {
 	switch authConfig.Kind {
 	case auth.KindIguazio:
 		return iguazio.NewAuth(logger, authConfig)
+	case auth.KindOIDC:
+		return oidc.NewAuth(logger, authConfig)
 	case auth.KindNop:
 		return nop.NewAuth(logger, authConfig)
 	default:
@@ -0,0 +1,271 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	authpkg "github.com/nuclio/nuclio/pkg/auth"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"k8s.io/apimachinery/pkg/util/cache"
+)
+
+// signing methods accepted in tokens. symmetric methods are not, as the issuer's keys are public
+var validSigningMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+}
+
+type Auth struct {
+	logger logger.Logger
+	config *authpkg.Config
+	keySet *keySet
+	cache  *cache.LRUExpireCache
+	parser *jwt.Parser
+}
+
+func NewAuth(logger logger.Logger, config *authpkg.Config) authpkg.Auth {
+	authLogger := logger.GetChild("oidc-auth")
+	httpClient := &http.Client{
+		Timeout: config.OIDC.Timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: config.OIDC.SkipTLSVerification},
+		},
+	}
+
+	return &Auth{
+		logger: authLogger,
+		config: config,
+		keySet: newKeySet(authLogger,
+			httpClient,
+			config.OIDC.IssuerURL,
+			config.OIDC.JWKSURL,
+			config.OIDC.JWKSRefreshInterval,
+			config.OIDC.JWKSMinRefreshInterval),
+		cache: cache.NewLRUExpireCache(config.OIDC.CacheSize),
+
+		// claims are validated explicitly, to allow for clock skew
+		parser: jwt.NewParser(jwt.WithValidMethods(validSigningMethods), jwt.WithoutClaimsValidation()),
+	}
+}
+
+// Authenticate will verify the request's bearer token against the issuer's keys, and create a session
+// from its claims
+func (a *Auth) Authenticate(request *http.Request, options *authpkg.Options) (authpkg.Session, error) {
+	ctx := request.Context()
+
+	authorization := request.Header.Get("authorization")
+	if authorization == "" {
+		return nil, nuclio.NewErrForbidden("Authentication headers are missing")
+	}
+
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "bearer") || token == "" {
+		return nil, nuclio.NewErrUnauthorized("Authorization header must hold a bearer token")
+	}
+
+	cacheKey := sha256.Sum256([]byte(token))
+
+	// try resolve from cache
+	if cacheData, found := a.cache.Get(cacheKey); found {
+		return cacheData.(*authpkg.OIDCSession), nil
+	}
+
+	claims, err := a.verifyToken(ctx, token)
+	if err != nil {
+		a.logger.WarnWithCtx(ctx,
+			"Token verification failed",
+			"err", err.Error(),
+			"tokenLength", len(token))
+		return nil, nuclio.WrapErrUnauthorized(err)
+	}
+
+	session, err := a.createSession(claims, token)
+	if err != nil {
+		return nil, nuclio.WrapErrUnauthorized(err)
+	}
+
+	// don't cache sessions beyond their token's expiration
+	cacheExpiration := a.config.OIDC.CacheExpirationTimeout
+	if expiresAt, ok := a.getTimeClaim(claims, "exp"); ok {
+		if untilExpiration := time.Until(expiresAt); untilExpiration < cacheExpiration {
+			cacheExpiration = untilExpiration
+		}
+	}
+
+	if cacheExpiration > 0 {
+		a.cache.Add(cacheKey, session, cacheExpiration)
+	}
+
+	a.logger.InfoWithCtx(ctx,
+		"Authentication succeeded",
+		"username", session.GetUsername())
+	return session, nil
+}
+
+// Middleware will authenticate the incoming request and store the session within the request context
+func (a *Auth) Middleware(options *authpkg.Options) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			session, err := a.Authenticate(r, options)
+			if err != nil {
+				a.logger.WarnWithCtx(ctx,
+					"Authentication failed",
+					"err", errors.GetErrorStackString(err, 10))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			a.logger.DebugWithCtx(ctx,
+				"Successfully authenticated incoming request",
+				"sessionUsername", session.GetUsername())
+			enrichedCtx := context.WithValue(ctx, authpkg.OIDCContextKey, session)
+			next.ServeHTTP(w, r.WithContext(enrichedCtx))
+		})
+	}
+}
+
+func (a *Auth) Kind() authpkg.Kind {
+	return a.config.Kind
+}
+
+func (a *Auth) verifyToken(ctx context.Context, token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(token, claims, func(parsedToken *jwt.Token) (interface{}, error) {
+		keyID, _ := parsedToken.Header["kid"].(string)
+		return a.keySet.getKey(ctx, keyID)
+	}); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse token")
+	}
+
+	now := time.Now()
+	clockSkew := a.config.OIDC.ClockSkew
+
+	expiresAt, ok := a.getTimeClaim(claims, "exp")
+	if !ok {
+		return nil, errors.New("Token has no expiration")
+	}
+
+	if now.After(expiresAt.Add(clockSkew)) {
+		return nil, errors.New("Token is expired")
+	}
+
+	if notBefore, ok := a.getTimeClaim(claims, "nbf"); ok && now.Add(clockSkew).Before(notBefore) {
+		return nil, errors.New("Token is not valid yet")
+	}
+
+	if issuedAt, ok := a.getTimeClaim(claims, "iat"); ok && now.Add(clockSkew).Before(issuedAt) {
+		return nil, errors.New("Token was issued in the future")
+	}
+
+	if issuer, _ := claims["iss"].(string); strings.TrimSuffix(issuer, "/") != a.keySet.issuerURL {
+		return nil, errors.Errorf("Unexpected token issuer: %s", issuer)
+	}
+
+	if a.config.OIDC.Audience != "" && !claims.VerifyAudience(a.config.OIDC.Audience, true) {
+		return nil, errors.New("Token was not issued for this audience")
+	}
+
+	return claims, nil
+}
+
+func (a *Auth) createSession(claims jwt.MapClaims, token string) (*authpkg.OIDCSession, error) {
+	session := &authpkg.OIDCSession{
+		UserID: a.getStringClaim(claims, a.config.OIDC.UserIDClaim),
+		Token:  token,
+	}
+
+	if session.UserID == "" {
+		return nil, errors.Errorf("Token has no %s claim", a.config.OIDC.UserIDClaim)
+	}
+
+	// fallback to the subject, which is always there
+	session.Username = a.getStringClaim(claims, a.config.OIDC.UsernameClaim)
+	if session.Username == "" {
+		session.Username = a.getStringClaim(claims, "sub")
+	}
+
+	switch typedGroupIDs := a.getClaim(claims, a.config.OIDC.GroupIDsClaim).(type) {
+	case []interface{}:
+		for _, groupID := range typedGroupIDs {
+			if groupID := fmt.Sprint(groupID); groupID != "" {
+				session.GroupIDs = append(session.GroupIDs, groupID)
+			}
+		}
+	case string:
+		for _, groupID := range strings.Split(typedGroupIDs, ",") {
+			if groupID = strings.TrimSpace(groupID); groupID != "" {
+				session.GroupIDs = append(session.GroupIDs, groupID)
+			}
+		}
+	}
+
+	return session, nil
+}
+
+// getClaim returns a claim by its name, where nested claims are separated by dots (e.g. realm_access.roles)
+func (a *Auth) getClaim(claims jwt.MapClaims, name string) interface{} {
+	if name == "" {
+		return nil
+	}
+
+	if claim, found := claims[name]; found {
+		return claim
+	}
+
+	var current interface{} = map[string]interface{}(claims)
+	for _, part := range strings.Split(name, ".") {
+		currentMap, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = currentMap[part]
+	}
+
+	return current
+}
+
+func (a *Auth) getStringClaim(claims jwt.MapClaims, name string) string {
+	switch typedClaim := a.getClaim(claims, name).(type) {
+	case string:
+		return typedClaim
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(typedClaim)
+	}
+}
+
+func (a *Auth) getTimeClaim(claims jwt.MapClaims, name string) (time.Time, bool) {
+	switch typedClaim := claims[name].(type) {
+	case float64:
+		return time.Unix(int64(typedClaim), 0), true
+	default:
+		return time.Time{}, false
+	}
+}
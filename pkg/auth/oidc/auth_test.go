@@ -0,0 +1,295 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/auth"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuthTestSuite struct {
+	suite.Suite
+	logger logger.Logger
+
+	issuer        *httptest.Server
+	jwks          atomic.Value
+	jwksRequests  int32
+	rsaPrivateKey *rsa.PrivateKey
+	ecPrivateKey  *ecdsa.PrivateKey
+	authConfig    *auth.Config
+	auth          auth.Auth
+}
+
+func (suite *AuthTestSuite) SetupSuite() {
+	var err error
+	suite.logger, err = nucliozap.NewNuclioZapTest("oidc-auth")
+	suite.Require().NoError(err)
+
+	suite.rsaPrivateKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+
+	suite.ecPrivateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+}
+
+func (suite *AuthTestSuite) SetupTest() {
+	suite.jwksRequests = 0
+	suite.jwks.Store([]map[string]string{
+		suite.encodeRSAKey("rsa-key", &suite.rsaPrivateKey.PublicKey),
+		suite.encodeECKey("ec-key", &suite.ecPrivateKey.PublicKey),
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{ // nolint: errcheck
+			"issuer":   suite.issuer.URL,
+			"jwks_uri": suite.issuer.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&suite.jwksRequests, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": suite.jwks.Load()}) // nolint: errcheck
+	})
+	suite.issuer = httptest.NewServer(mux)
+
+	suite.authConfig = auth.NewConfig(auth.KindOIDC)
+	suite.authConfig.OIDC.IssuerURL = suite.issuer.URL
+	suite.authConfig.OIDC.Audience = "nuclio"
+	suite.auth = NewAuth(suite.logger, suite.authConfig)
+}
+
+func (suite *AuthTestSuite) TearDownTest() {
+	suite.issuer.Close()
+}
+
+func (suite *AuthTestSuite) TestAuthenticate() {
+	token := suite.signToken(jwt.SigningMethodRS256, "rsa-key", suite.rsaPrivateKey, jwt.MapClaims{
+		"preferred_username": "jdoe",
+		"groups":             []string{"group-a", "group-b"},
+	})
+
+	session, err := suite.auth.Authenticate(suite.createRequest(token), nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal("jdoe", session.GetUsername())
+	suite.Require().Equal("some-user-id", session.GetUserID())
+	suite.Require().Equal([]string{"group-a", "group-b"}, session.GetGroupIDs())
+	suite.Require().Empty(session.GetPassword())
+
+	// ecdsa keys are supported too
+	token = suite.signToken(jwt.SigningMethodES256, "ec-key", suite.ecPrivateKey, jwt.MapClaims{})
+	session, err = suite.auth.Authenticate(suite.createRequest(token), nil)
+	suite.Require().NoError(err)
+
+	// falls back to the subject
+	suite.Require().Equal("some-user-id", session.GetUsername())
+	suite.Require().Empty(session.GetGroupIDs())
+}
+
+func (suite *AuthTestSuite) TestAuthenticateNestedGroupIDsClaim() {
+	suite.authConfig.OIDC.GroupIDsClaim = "realm_access.roles"
+	token := suite.signToken(jwt.SigningMethodRS256, "rsa-key", suite.rsaPrivateKey, jwt.MapClaims{
+		"realm_access": map[string]interface{}{"roles": []string{"admins"}},
+	})
+
+	session, err := suite.auth.Authenticate(suite.createRequest(token), nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"admins"}, session.GetGroupIDs())
+}
+
+func (suite *AuthTestSuite) TestAuthenticateCaching() {
+	token := suite.signToken(jwt.SigningMethodRS256, "rsa-key", suite.rsaPrivateKey, jwt.MapClaims{})
+
+	for i := 0; i < 3; i++ {
+		_, err := suite.auth.Authenticate(suite.createRequest(token), nil)
+		suite.Require().NoError(err)
+	}
+
+	suite.Require().Equal(int32(1), atomic.LoadInt32(&suite.jwksRequests))
+}
+
+func (suite *AuthTestSuite) TestAuthenticateKeyRotation() {
+	token := suite.signToken(jwt.SigningMethodRS256, "rsa-key", suite.rsaPrivateKey, jwt.MapClaims{})
+	_, err := suite.auth.Authenticate(suite.createRequest(token), nil)
+	suite.Require().NoError(err)
+
+	// issuer rotates its keys
+	rotatedPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+	suite.jwks.Store([]map[string]string{suite.encodeRSAKey("rotated-key", &rotatedPrivateKey.PublicKey)})
+	rotatedToken := suite.signToken(jwt.SigningMethodRS256, "rotated-key", rotatedPrivateKey, jwt.MapClaims{})
+
+	// keys were just refreshed, so unknown keys aren't looked up yet
+	_, err = suite.auth.Authenticate(suite.createRequest(rotatedToken), nil)
+	suite.Require().Error(err)
+
+	suite.auth.(*Auth).keySet.minRefreshInterval = 0
+	_, err = suite.auth.Authenticate(suite.createRequest(rotatedToken), nil)
+	suite.Require().NoError(err)
+}
+
+func (suite *AuthTestSuite) TestAuthenticateInvalidTokens() {
+	otherPrivateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+
+	for _, testCase := range []struct {
+		name  string
+		token string
+	}{
+		{
+			name: "expired",
+			token: suite.signToken(jwt.SigningMethodRS256, "rsa-key", suite.rsaPrivateKey, jwt.MapClaims{
+				"exp": time.Now().Add(-time.Hour).Unix(),
+			}),
+		},
+		{
+			name: "notYetValid",
+			token: suite.signToken(jwt.SigningMethodRS256, "rsa-key", suite.rsaPrivateKey, jwt.MapClaims{
+				"nbf": time.Now().Add(time.Hour).Unix(),
+			}),
+		},
+		{
+			name: "otherIssuer",
+			token: suite.signToken(jwt.SigningMethodRS256, "rsa-key", suite.rsaPrivateKey, jwt.MapClaims{
+				"iss": "https://evil.example.com",
+			}),
+		},
+		{
+			name: "otherAudience",
+			token: suite.signToken(jwt.SigningMethodRS256, "rsa-key", suite.rsaPrivateKey, jwt.MapClaims{
+				"aud": "other",
+			}),
+		},
+		{
+			name:  "badSignature",
+			token: suite.signToken(jwt.SigningMethodRS256, "rsa-key", otherPrivateKey, jwt.MapClaims{}),
+		},
+		{
+			name:  "symmetricSigningMethod",
+			token: suite.signToken(jwt.SigningMethodHS256, "rsa-key", []byte("secret"), jwt.MapClaims{}),
+		},
+		{
+			name:  "malformed",
+			token: "not.a.token",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			_, err := suite.auth.Authenticate(suite.createRequest(testCase.token), nil)
+			suite.Require().Error(err)
+		})
+	}
+
+	// missing / non bearer authorization
+	request, err := http.NewRequest(http.MethodGet, "http://dashboard", nil)
+	suite.Require().NoError(err)
+	_, err = suite.auth.Authenticate(request, nil)
+	suite.Require().Error(err)
+
+	request.Header.Set("Authorization", "Basic YWJjOmVmZw==")
+	_, err = suite.auth.Authenticate(request, nil)
+	suite.Require().Error(err)
+}
+
+func (suite *AuthTestSuite) TestMiddleware() {
+	var session auth.Session
+	handler := suite.auth.Middleware(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session = r.Context().Value(auth.ContextKeyByKind(auth.KindOIDC)).(auth.Session)
+	}))
+
+	responseRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, suite.createRequest("bad"))
+	suite.Require().Equal(http.StatusUnauthorized, responseRecorder.Code)
+
+	token := suite.signToken(jwt.SigningMethodRS256, "rsa-key", suite.rsaPrivateKey, jwt.MapClaims{})
+	responseRecorder = httptest.NewRecorder()
+	handler.ServeHTTP(responseRecorder, suite.createRequest(token))
+	suite.Require().Equal(http.StatusOK, responseRecorder.Code)
+	suite.Require().Equal("some-user-id", session.GetUserID())
+}
+
+func (suite *AuthTestSuite) signToken(method jwt.SigningMethod,
+	keyID string,
+	key interface{},
+	claims jwt.MapClaims) string {
+
+	tokenClaims := jwt.MapClaims{
+		"iss": suite.issuer.URL,
+		"sub": "some-user-id",
+		"aud": []string{"nuclio"},
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for claimName, claimValue := range claims {
+		tokenClaims[claimName] = claimValue
+	}
+
+	token := jwt.NewWithClaims(method, tokenClaims)
+	token.Header["kid"] = keyID
+
+	signedToken, err := token.SignedString(key)
+	suite.Require().NoError(err)
+	return signedToken
+}
+
+func (suite *AuthTestSuite) createRequest(token string) *http.Request {
+	request, err := http.NewRequest(http.MethodGet, "http://dashboard", nil)
+	suite.Require().NoError(err)
+	request.Header.Set("Authorization", "Bearer "+token)
+	return request
+}
+
+func (suite *AuthTestSuite) encodeRSAKey(keyID string, publicKey *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": keyID,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+	}
+}
+
+func (suite *AuthTestSuite) encodeECKey(keyID string, publicKey *ecdsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"kid": keyID,
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(publicKey.X.Bytes()),
+		"y":   base64.RawURLEncoding.EncodeToString(publicKey.Y.Bytes()),
+	}
+}
+
+func TestAuthTestSuite(t *testing.T) {
+	suite.Run(t, new(AuthTestSuite))
+}
@@ -0,0 +1,281 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// keySet holds the issuer's signing keys, as published in its JWKS. keys are refreshed periodically,
+// and whenever a token is signed by an unknown key (e.g. following key rotation)
+type keySet struct {
+	logger             logger.Logger
+	httpClient         *http.Client
+	issuerURL          string
+	jwksURL            string
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+
+	lock        sync.Mutex
+	keys        map[string]interface{}
+	lastRefresh time.Time
+}
+
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+
+	// rsa
+	N string `json:"n"`
+	E string `json:"e"`
+
+	// ecdsa
+	Curve string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+}
+
+func newKeySet(parentLogger logger.Logger,
+	httpClient *http.Client,
+	issuerURL string,
+	jwksURL string,
+	refreshInterval time.Duration,
+	minRefreshInterval time.Duration) *keySet {
+	return &keySet{
+		logger:             parentLogger.GetChild("jwks"),
+		httpClient:         httpClient,
+		issuerURL:          strings.TrimSuffix(issuerURL, "/"),
+		jwksURL:            jwksURL,
+		refreshInterval:    refreshInterval,
+		minRefreshInterval: minRefreshInterval,
+		keys:               map[string]interface{}{},
+	}
+}
+
+// getKey returns the key with the given key ID. an empty key ID is allowed when the issuer has a single key
+func (ks *keySet) getKey(ctx context.Context, keyID string) (interface{}, error) {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+
+	sinceLastRefresh := time.Since(ks.lastRefresh)
+
+	// refresh periodically, or when the key is unknown - but not too often, so that tokens with
+	// made up key IDs won't flood the issuer
+	if sinceLastRefresh > ks.refreshInterval ||
+		(ks.lookupKey(keyID) == nil && sinceLastRefresh > ks.minRefreshInterval) {
+		if err := ks.refresh(ctx); err != nil {
+
+			// keep using the keys we have
+			if len(ks.keys) == 0 {
+				return nil, errors.Wrap(err, "Failed to get issuer keys")
+			}
+
+			ks.logger.WarnWithCtx(ctx, "Failed to refresh issuer keys, using cached keys", "err", err.Error())
+		}
+	}
+
+	key := ks.lookupKey(keyID)
+	if key == nil {
+		return nil, errors.Errorf("Unknown signing key: %s", keyID)
+	}
+
+	return key, nil
+}
+
+func (ks *keySet) lookupKey(keyID string) interface{} {
+	if keyID == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key
+		}
+	}
+
+	return ks.keys[keyID]
+}
+
+func (ks *keySet) refresh(ctx context.Context) error {
+
+	// mark the attempt, so that failures won't be retried on every request
+	ks.lastRefresh = time.Now()
+
+	if ks.jwksURL == "" {
+		jwksURL, err := ks.discoverJWKSURL(ctx)
+		if err != nil {
+			return errors.Wrap(err, "Failed to discover JWKS url")
+		}
+		ks.jwksURL = jwksURL
+	}
+
+	jwks := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if err := ks.getJSON(ctx, ks.jwksURL, &jwks); err != nil {
+		return errors.Wrap(err, "Failed to get JWKS")
+	}
+
+	keys := map[string]interface{}{}
+	for _, webKey := range jwks.Keys {
+
+		// skip keys that aren't used for signatures
+		if webKey.Use != "" && webKey.Use != "sig" {
+			continue
+		}
+
+		key, err := parseJSONWebKey(&webKey)
+		if err != nil {
+			ks.logger.WarnWithCtx(ctx, "Skipping unsupported issuer key",
+				"keyID", webKey.KeyID,
+				"keyType", webKey.KeyType,
+				"err", err.Error())
+			continue
+		}
+
+		keys[webKey.KeyID] = key
+	}
+
+	if len(keys) == 0 {
+		return errors.New("Issuer has no supported signing keys")
+	}
+
+	ks.keys = keys
+	ks.logger.DebugWithCtx(ctx, "Refreshed issuer keys", "jwksURL", ks.jwksURL, "numKeys", len(keys))
+	return nil
+}
+
+func (ks *keySet) discoverJWKSURL(ctx context.Context) (string, error) {
+	openIDConfiguration := struct {
+		Issuer  string `json:"issuer"`
+		JWKSURL string `json:"jwks_uri"`
+	}{}
+
+	if err := ks.getJSON(ctx, ks.issuerURL+"/.well-known/openid-configuration", &openIDConfiguration); err != nil {
+		return "", errors.Wrap(err, "Failed to get openid configuration")
+	}
+
+	if strings.TrimSuffix(openIDConfiguration.Issuer, "/") != ks.issuerURL {
+		return "", errors.Errorf("Openid configuration issuer %s does not match %s",
+			openIDConfiguration.Issuer,
+			ks.issuerURL)
+	}
+
+	if openIDConfiguration.JWKSURL == "" {
+		return "", errors.New("Openid configuration has no JWKS url")
+	}
+
+	return openIDConfiguration.JWKSURL, nil
+}
+
+func (ks *keySet) getJSON(ctx context.Context, url string, body interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create http request")
+	}
+
+	response, err := ks.httpClient.Do(request)
+	if err != nil {
+		return errors.Wrap(err, "Failed to send http request")
+	}
+	defer response.Body.Close() // nolint: errcheck
+
+	if response.StatusCode != http.StatusOK {
+		return errors.Errorf("Unexpected status code: %d", response.StatusCode)
+	}
+
+	encodedBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return errors.Wrap(err, "Failed to read response body")
+	}
+
+	if err := json.Unmarshal(encodedBody, body); err != nil {
+		return errors.Wrap(err, "Failed to unmarshal response body")
+	}
+
+	return nil
+}
+
+func parseJSONWebKey(webKey *jsonWebKey) (interface{}, error) {
+	switch webKey.KeyType {
+	case "RSA":
+		modulus, err := decodeBigInt(webKey.N)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to decode modulus")
+		}
+
+		exponent, err := decodeBigInt(webKey.E)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to decode exponent")
+		}
+
+		return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch webKey.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("Unsupported curve: %s", webKey.Curve)
+		}
+
+		x, err := decodeBigInt(webKey.X)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to decode x coordinate")
+		}
+
+		y, err := decodeBigInt(webKey.Y)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to decode y coordinate")
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+
+	default:
+		return nil, errors.Errorf("Unsupported key type: %s", webKey.KeyType)
+	}
+}
+
+func decodeBigInt(encoded string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(decoded) == 0 {
+		return nil, errors.New("Empty value")
+	}
+
+	return new(big.Int).SetBytes(decoded), nil
+}
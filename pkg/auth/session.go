@@ -24,6 +24,7 @@ import (
 type abstractSession struct {
 	Iguazio *IguazioSession
 	Nop     *NopSession
+	OIDC    *OIDCSession
 }
 
 func (a *abstractSession) GetUsername() string {
@@ -78,3 +79,32 @@ func (a *IguazioSession) GetGroupIDs() []string {
 type NopSession struct {
 	*abstractSession
 }
+
+type OIDCSession struct {
+	*abstractSession
+	Username string
+	UserID   string
+	GroupIDs []string
+	Token    string
+}
+
+func (a *OIDCSession) GetUsername() string {
+	return a.Username
+}
+
+func (a *OIDCSession) CompileAuthorizationBasic() string {
+	return ""
+}
+
+func (a *OIDCSession) GetUserID() string {
+	return a.UserID
+}
+
+// GetPassword returns nothing, as the bearer token must not be passed on as a password
+func (a *OIDCSession) GetPassword() string {
+	return ""
+}
+
+func (a *OIDCSession) GetGroupIDs() []string {
+	return a.GroupIDs
+}
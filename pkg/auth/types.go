@@ -26,6 +26,7 @@ type Kind string
 const (
 	KindNop     = "nop"
 	KindIguazio = "iguazio"
+	KindOIDC    = "oidc"
 )
 
 type SessionContextKey string
@@ -33,6 +34,7 @@ type SessionContextKey string
 const (
 	IguazioContextKey     SessionContextKey = "IguazioSession"
 	NopContextKey         SessionContextKey = "NopSession"
+	OIDCContextKey        SessionContextKey = "OIDCSession"
 	AuthSessionContextKey SessionContextKey = "AuthSession"
 )
 
@@ -42,6 +44,8 @@ func ContextKeyByKind(kind Kind) SessionContextKey {
 		return NopContextKey
 	case KindIguazio:
 		return IguazioContextKey
+	case KindOIDC:
+		return OIDCContextKey
 	default:
 		return NopContextKey
 	}
@@ -57,9 +61,34 @@ type IguazioConfig struct {
 	SkipTLSVerification           bool
 }
 
+type OIDCConfig struct {
+
+	// the issuer tokens must be issued by. unless JWKSURL is given, its keys are discovered from
+	// the issuer's openid configuration
+	IssuerURL string
+	JWKSURL   string
+
+	// if set, tokens must be issued for this audience
+	Audience string
+
+	// claims to map into the session. group IDs claim may be a nested claim (e.g. realm_access.roles)
+	UsernameClaim string
+	UserIDClaim   string
+	GroupIDsClaim string
+
+	Timeout                time.Duration
+	CacheSize              int
+	CacheExpirationTimeout time.Duration
+	JWKSRefreshInterval    time.Duration
+	JWKSMinRefreshInterval time.Duration
+	ClockSkew              time.Duration
+	SkipTLSVerification    bool
+}
+
 type Config struct {
 	Kind    Kind
 	Iguazio *IguazioConfig
+	OIDC    *OIDCConfig
 }
 
 func NewConfig(kind Kind) *Config {
@@ -76,6 +105,19 @@ func NewConfig(kind Kind) *Config {
 			SkipTLSVerification:    skipTLSVerification,
 		}
 	}
+	if kind == KindOIDC {
+		config.OIDC = &OIDCConfig{
+			UsernameClaim:          "preferred_username",
+			UserIDClaim:            "sub",
+			GroupIDsClaim:          "groups",
+			Timeout:                30 * time.Second,
+			CacheSize:              100,
+			CacheExpirationTimeout: 30 * time.Second,
+			JWKSRefreshInterval:    time.Hour,
+			JWKSMinRefreshInterval: 30 * time.Second,
+			ClockSkew:              30 * time.Second,
+		}
+	}
 	return config
 }
 
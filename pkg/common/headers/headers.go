@@ -68,6 +68,10 @@ const (
 	FilterContains = "X-Nuclio-Filter-Contains"
 	StreamNoAck    = "X-Nuclio-Stream-No-Ack"
 	Arguments      = "X-Nuclio-Arguments"
+	StatusCode     = "X-Nuclio-Status-Code"
+
+	// WebSocket headers
+	WebSocketConnectionID = "X-Nuclio-Websocket-Connection-Id"
 )
 
 func IsNuclioHeader(headerName string) bool {
@@ -42,7 +42,12 @@ type Docker struct {
 
 func NewDocker(logger logger.Logger, builderConfiguration *ContainerBuilderConfiguration) (*Docker, error) {
 
-	dockerClient, err := dockerclient.NewShellClient(logger, nil)
+	var dockerClientKind dockerclient.ClientKind
+	if builderConfiguration != nil {
+		dockerClientKind = builderConfiguration.DockerClientKind
+	}
+
+	dockerClient, err := dockerclient.NewClient(logger, nil, dockerClientKind)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to create docker client")
 	}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/dockerclient"
 	"github.com/nuclio/nuclio/pkg/processor/build/runtime"
 
 	"github.com/nuclio/errors"
@@ -73,6 +74,7 @@ type ContainerBuilderConfiguration struct {
 	InsecurePullRegistry                 bool
 	PushImagesRetries                    int
 	ImageFSExtractionRetries             int
+	DockerClientKind                     dockerclient.ClientKind
 }
 
 func NewContainerBuilderConfiguration() (*ContainerBuilderConfiguration, error) {
@@ -104,6 +106,9 @@ func NewContainerBuilderConfiguration() (*ContainerBuilderConfiguration, error)
 		containerBuilderConfiguration.KanikoImagePullPolicy = common.GetEnvOrDefaultString(
 			"NUCLIO_KANIKO_CONTAINER_IMAGE_PULL_POLICY", "IfNotPresent")
 	}
+	if containerBuilderConfiguration.DockerClientKind == "" {
+		containerBuilderConfiguration.DockerClientKind = dockerclient.ResolveClientKind("")
+	}
 	if containerBuilderConfiguration.JobPrefix == "" {
 		containerBuilderConfiguration.JobPrefix = common.GetEnvOrDefaultString("NUCLIO_DASHBOARD_JOB_NAME_PREFIX",
 			"kanikojob")
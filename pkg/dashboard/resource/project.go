@@ -32,7 +32,6 @@ import (
 	"github.com/nuclio/nuclio/pkg/opa"
 	"github.com/nuclio/nuclio/pkg/platform"
 	"github.com/nuclio/nuclio/pkg/platform/abstract/project/external/leader/iguazio"
-	"github.com/nuclio/nuclio/pkg/platform/kube"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/restful"
 
@@ -563,7 +562,7 @@ func (pr *projectResource) importProjectAPIGateways(request *http.Request,
 	// iterate over all api gateways and try to create each
 	for _, apiGateway := range projectImportInfoInstance.APIGateways {
 
-		if err := kube.ValidateAPIGatewaySpec(apiGateway.Spec); err != nil {
+		if err := platform.ValidateAPIGatewaySpec(apiGateway.Spec); err != nil {
 			failedAPIGateways = append(failedAPIGateways, restful.Attributes{
 				"apiGateway": apiGateway.Spec.Name,
 				"error":      err.Error(),
@@ -332,7 +332,7 @@ func (s *Server) loadDockerKeys(dockerKeyDir string) error {
 func createDockerClient(parentLogger logger.Logger, containerBuilderKind string) (
 	dockerclient.Client, error) {
 	if containerBuilderKind == "docker" {
-		return dockerclient.NewShellClient(parentLogger, nil)
+		return dockerclient.NewClient(parentLogger, nil, "")
 	}
 
 	// if docker won't be use, return nil as a client
@@ -0,0 +1,1703 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+
+	"github.com/docker/distribution/reference"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+const (
+	defaultDockerHost = "unix:///var/run/docker.sock"
+
+	// the newest engine API version the client was written against. older daemons are talked to in their own version
+	maxAPIVersion = "1.41"
+
+	// the name the dockerfile is sent as, when it resides outside the build context
+	outOfContextDockerfileName = ".nuclio.Dockerfile"
+)
+
+// APIClient is a docker client that talks to the docker engine API directly
+type APIClient struct {
+	logger             logger.Logger
+	httpClient         *http.Client
+	baseURL            string
+	apiVersion         string
+	authConfigs        map[string]apiAuthConfig
+	authConfigsLock    sync.Mutex
+	buildTimeout       time.Duration
+	buildRetryInterval time.Duration
+}
+
+// NewAPIClient creates a new docker engine API client. host is in the form of DOCKER_HOST
+// (e.g. unix:///var/run/docker.sock, tcp://127.0.0.1:2375). if empty, DOCKER_HOST is used
+func NewAPIClient(parentLogger logger.Logger, host string) (*APIClient, error) {
+	if host == "" {
+		host = common.GetEnvOrDefaultString("DOCKER_HOST", defaultDockerHost)
+	}
+
+	newClient := &APIClient{
+		logger:             parentLogger.GetChild("docker"),
+		authConfigs:        map[string]apiAuthConfig{},
+		buildTimeout:       1 * time.Hour,
+		buildRetryInterval: 3 * time.Second,
+	}
+
+	hostURL, err := url.Parse(host)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse docker host %s", host)
+	}
+
+	switch hostURL.Scheme {
+	case "unix":
+		socketPath := hostURL.Path
+		newClient.baseURL = "http://docker"
+		newClient.httpClient = &http.Client{
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+				},
+			},
+		}
+	case "tcp", "http":
+		newClient.baseURL = "http://" + hostURL.Host
+		newClient.httpClient = &http.Client{}
+	default:
+		return nil, errors.Errorf("Unsupported docker host scheme: %s", hostURL.Scheme)
+	}
+
+	// verify docker engine is available, and agree on an api version
+	if err := newClient.negotiateAPIVersion(); err != nil {
+		return nil, errors.Wrap(err, "No docker engine found")
+	}
+
+	return newClient, nil
+}
+
+// Build will build a docker image, given build options
+func (c *APIClient) Build(buildOptions *BuildOptions) error {
+	c.logger.DebugWith("Building image", "buildOptions", buildOptions)
+
+	if _, err := reference.Parse(buildOptions.Image); err != nil {
+		return errors.Wrap(err, "Invalid image name in build options")
+	}
+
+	// if context dir is not passed, use the dir containing the dockerfile
+	if buildOptions.ContextDir == "" && buildOptions.DockerfilePath != "" {
+		buildOptions.ContextDir = path.Dir(buildOptions.DockerfilePath)
+	}
+
+	// user can only specify context directory
+	if buildOptions.DockerfilePath == "" && buildOptions.ContextDir != "" {
+		buildOptions.DockerfilePath = path.Join(buildOptions.ContextDir, "Dockerfile")
+	}
+
+	query, err := c.compileBuildQuery(buildOptions)
+	if err != nil {
+		return errors.Wrap(err, "Invalid build options passed")
+	}
+
+	retryOnErrorMessages := []string{
+
+		// when one of the underlying image is gone (from cache)
+		"^No such image: sha256:",
+		"^unknown parent image ID sha256:",
+		"^failed to set parent sha256:",
+		"^failed to export image:",
+
+		// when overlay image is gone (from disk)
+		"^failed to get digest sha256:",
+
+		// when trying to reuse a missing nuclio-onbuild between functions
+		"^Unable to find image 'nuclio-onbuild-.*' locally",
+	}
+
+	var lastBuildErr error
+
+	// retry build on predefined errors that occur during race condition and collisions between
+	// shared onbuild layers
+	common.RetryUntilSuccessfulOnErrorPatterns(c.buildTimeout, // nolint: errcheck
+		c.buildRetryInterval,
+		retryOnErrorMessages,
+		func(int) (string, error) {
+			lastBuildErr = c.build(buildOptions, query)
+			if lastBuildErr != nil {
+				return errors.Cause(lastBuildErr).Error(), lastBuildErr
+			}
+			return "", nil
+		})
+
+	if lastBuildErr != nil {
+		return errors.Wrap(lastBuildErr, "Failed to build")
+	}
+
+	c.logger.DebugWith("Successfully built image", "image", buildOptions.Image)
+	return nil
+}
+
+// CopyObjectsFromImage copies objects (files, directories) from a given image to local storage. it does
+// this through an intermediate container which is deleted afterwards
+func (c *APIClient) CopyObjectsFromImage(imageName string,
+	objectsToCopy map[string]string,
+	allowCopyErrors bool) error {
+
+	if _, err := reference.Parse(imageName); err != nil {
+		return errors.Wrap(err, "Invalid image name to create container from")
+	}
+
+	// create container from image
+	containerID, err := c.createContainer(&apiContainerCreateRequest{
+		Image: imageName,
+		Cmd:   []string{"/bin/sh"},
+	}, "")
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create container from %s", imageName)
+	}
+
+	// delete once done copying objects
+	defer c.RemoveContainer(containerID) // nolint: errcheck
+
+	// copy objects
+	for objectImagePath, objectLocalPath := range objectsToCopy {
+		if err := c.copyObjectFromContainer(containerID,
+			objectImagePath,
+			objectLocalPath); err != nil && !allowCopyErrors {
+			return errors.Wrapf(err, "Can't copy %s:%s -> %s", containerID, objectImagePath, objectLocalPath)
+		}
+	}
+
+	return nil
+}
+
+// PushImage pushes a local image to a remote docker repository
+func (c *APIClient) PushImage(imageName string, registryURL string) error {
+	taggedImage := common.CompileImageName(registryURL, imageName)
+
+	c.logger.InfoWith("Pushing image", "from", imageName, "to", taggedImage)
+
+	if _, err := reference.Parse(imageName); err != nil {
+		return errors.Wrap(err, "Invalid image name to tag/push")
+	}
+
+	taggedImageReference, err := reference.ParseNormalizedNamed(taggedImage)
+	if err != nil {
+		return errors.Wrap(err, "Invalid tagged image name to tag/push")
+	}
+
+	repository, tag := c.splitImageReference(taggedImageReference)
+
+	// tag
+	if _, err := c.sendRequest(http.MethodPost,
+		fmt.Sprintf("/images/%s/tag", imageName),
+		url.Values{"repo": {repository}, "tag": {tag}},
+		nil,
+		nil,
+		nil); err != nil {
+		return errors.Wrap(err, "Failed to tag image")
+	}
+
+	// push
+	response, err := c.sendStreamRequest(http.MethodPost,
+		fmt.Sprintf("/images/%s/push", repository),
+		url.Values{"tag": {tag}},
+		nil,
+		map[string]string{"X-Registry-Auth": c.encodeImageAuthConfig(taggedImageReference)})
+	if err != nil {
+		return errors.Wrap(err, "Failed to push image")
+	}
+	defer response.Body.Close() // nolint: errcheck
+
+	if err := c.readJSONMessages(response.Body, nil); err != nil {
+		return errors.Wrap(err, "Failed to push image")
+	}
+
+	return nil
+}
+
+// PullImage pulls an image from a remote docker repository
+func (c *APIClient) PullImage(imageURL string) error {
+	c.logger.InfoWith("Pulling image", "imageName", imageURL)
+
+	imageReference, err := reference.ParseNormalizedNamed(imageURL)
+	if err != nil {
+		return errors.Wrap(err, "Invalid image URL to pull")
+	}
+
+	// pull latest if no tag was given, like the docker cli does
+	imageReference = reference.TagNameOnly(imageReference)
+	repository, tag := c.splitImageReference(imageReference)
+
+	response, err := c.sendStreamRequest(http.MethodPost,
+		"/images/create",
+		url.Values{"fromImage": {repository}, "tag": {tag}},
+		nil,
+		map[string]string{"X-Registry-Auth": c.encodeImageAuthConfig(imageReference)})
+	if err != nil {
+		return errors.Wrap(err, "Failed to pull image")
+	}
+	defer response.Body.Close() // nolint: errcheck
+
+	return c.readJSONMessages(response.Body, nil)
+}
+
+// RemoveImage will remove (delete) a local image
+func (c *APIClient) RemoveImage(imageName string) error {
+	c.logger.DebugWith("Removing image", "imageName", imageName)
+
+	if _, err := reference.Parse(imageName); err != nil {
+		return errors.Wrap(err, "Invalid image name to remove")
+	}
+
+	_, err := c.sendRequest(http.MethodDelete,
+		fmt.Sprintf("/images/%s", imageName),
+		url.Values{"force": {"1"}},
+		nil,
+		nil,
+		nil)
+	return err
+}
+
+// RunContainer will run a container based on an image and run options
+func (c *APIClient) RunContainer(imageName string, runOptions *RunOptions) (string, error) {
+	c.logger.DebugWith("Running container", "imageName", imageName, "runOptions", runOptions)
+
+	if _, err := reference.Parse(imageName); err != nil {
+		return "", errors.Wrap(err, "Invalid image name passed to run command")
+	}
+
+	createRequest, err := c.compileContainerCreateRequest(imageName, runOptions)
+	if err != nil {
+		return "", errors.Wrap(err, "Invalid run options passed")
+	}
+
+	containerID, err := c.createContainer(createRequest, runOptions.ContainerName)
+	if err != nil {
+
+		// keep the engine error as is, callers look for well known messages (e.g. name is already in use)
+		return "", err
+	}
+
+	if _, err := c.sendRequest(http.MethodPost,
+		fmt.Sprintf("/containers/%s/start", containerID),
+		nil,
+		nil,
+		nil,
+		nil); err != nil {
+		c.logger.WarnWith("Failed to start container", "containerID", containerID, "err", err.Error())
+		return "", err
+	}
+
+	if !runOptions.Attach {
+		return containerID, nil
+	}
+
+	return containerID, c.awaitAttachedContainer(containerID, runOptions)
+}
+
+// ExecInContainer will run a command in a container
+func (c *APIClient) ExecInContainer(containerID string, execOptions *ExecOptions) error {
+	c.logger.DebugWith("Executing in container", "containerID", containerID, "execOptions", execOptions)
+
+	command, err := splitCommand(execOptions.Command)
+	if err != nil {
+		return errors.Wrap(err, "Invalid exec command passed")
+	}
+
+	execCreateResponse := struct {
+		ID string `json:"Id"`
+	}{}
+
+	// keep the engine error as is, callers look for well known messages (e.g. no such container)
+	if _, err := c.sendRequest(http.MethodPost,
+		fmt.Sprintf("/containers/%s/exec", containerID),
+		nil,
+		map[string]interface{}{
+			"Cmd":          command,
+			"Env":          c.compileEnv(execOptions.Env),
+			"AttachStdout": true,
+			"AttachStderr": true,
+		},
+		&execCreateResponse,
+		nil); err != nil {
+		return err
+	}
+
+	response, err := c.sendStreamRequest(http.MethodPost,
+		fmt.Sprintf("/exec/%s/start", execCreateResponse.ID),
+		nil,
+		map[string]interface{}{"Detach": false, "Tty": false},
+		nil)
+	if err != nil {
+		return errors.Wrap(err, "Failed to start exec")
+	}
+	defer response.Body.Close() // nolint: errcheck
+
+	var stdout, stderr bytes.Buffer
+	if err := demultiplexStream(response.Body, &stdout, &stderr); err != nil {
+		return errors.Wrap(err, "Failed to read exec output")
+	}
+
+	execInspectResponse := struct {
+		ExitCode int
+	}{}
+	if _, err := c.sendRequest(http.MethodGet,
+		fmt.Sprintf("/exec/%s/json", execCreateResponse.ID),
+		nil,
+		nil,
+		&execInspectResponse,
+		nil); err != nil {
+		return errors.Wrap(err, "Failed to inspect exec")
+	}
+
+	if execInspectResponse.ExitCode != 0 {
+		return errors.Errorf("Command exited with code %d\n\nstdout:\n%s\n\nstderr:\n%s",
+			execInspectResponse.ExitCode,
+			stdout.String(),
+			stderr.String())
+	}
+
+	// if user requested, set stdout / stderr
+	if execOptions.Stdout != nil {
+		*execOptions.Stdout = stdout.String()
+	}
+
+	if execOptions.Stderr != nil {
+		*execOptions.Stderr = stderr.String()
+	}
+
+	return nil
+}
+
+// RemoveContainer removes a container given a container ID
+func (c *APIClient) RemoveContainer(containerID string) error {
+	c.logger.DebugWith("Removing container", "containerID", containerID)
+
+	_, err := c.sendRequest(http.MethodDelete,
+		fmt.Sprintf("/containers/%s", containerID),
+		url.Values{"force": {"1"}},
+		nil,
+		nil,
+		nil)
+	return err
+}
+
+// StopContainer stops a container given a container ID
+func (c *APIClient) StopContainer(containerID string) error {
+	c.logger.DebugWith("Stopping container", "containerID", containerID)
+
+	_, err := c.sendRequest(http.MethodPost,
+		fmt.Sprintf("/containers/%s/stop", containerID),
+		nil,
+		nil,
+		nil,
+		nil)
+	return err
+}
+
+// StartContainer starts a container given a container ID
+func (c *APIClient) StartContainer(containerID string) error {
+	c.logger.DebugWith("Starting container", "containerID", containerID)
+
+	_, err := c.sendRequest(http.MethodPost,
+		fmt.Sprintf("/containers/%s/start", containerID),
+		nil,
+		nil,
+		nil,
+		nil)
+	return err
+}
+
+// GetContainerPort returns container port
+func (c *APIClient) GetContainerPort(container *Container, boundPort int) (int, error) {
+	return resolveContainerPort(container, boundPort)
+}
+
+// GetContainerLogs returns raw logs from a given container ID
+func (c *APIClient) GetContainerLogs(containerID string) (string, error) {
+	c.logger.DebugWith("Getting container logs", "containerID", containerID)
+
+	logStream, err := c.GetContainerLogStream(context.Background(), containerID, &ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to get container logs")
+	}
+	defer logStream.Close() // nolint: errcheck
+
+	logs, err := io.ReadAll(logStream)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to read container logs")
+	}
+
+	return string(logs), nil
+}
+
+// GetContainers returns a list of containers which match a certain criteria
+func (c *APIClient) GetContainers(options *GetContainerOptions) ([]Container, error) {
+	c.logger.DebugWith("Getting containers", "options", options)
+
+	filters := map[string][]string{}
+	if options.Name != "" {
+		filters["name"] = []string{fmt.Sprintf("^/%s$", options.Name)}
+	}
+
+	if options.ID != "" {
+		filters["id"] = []string{options.ID}
+	}
+
+	for labelName, labelValue := range options.Labels {
+		filters["label"] = append(filters["label"], fmt.Sprintf("%s=%s", labelName, labelValue))
+	}
+
+	encodedFilters, err := json.Marshal(filters)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode container filters")
+	}
+
+	query := url.Values{"filters": {string(encodedFilters)}}
+	if options.Stopped {
+		query.Set("all", "1")
+	}
+
+	var containerSummaries []struct {
+		ID string `json:"Id"`
+	}
+	if _, err := c.sendRequest(http.MethodGet,
+		"/containers/json",
+		query,
+		nil,
+		&containerSummaries,
+		nil); err != nil {
+		return nil, errors.Wrap(err, "Failed to get containers")
+	}
+
+	containers := []Container{}
+	for _, containerSummary := range containerSummaries {
+		container, err := c.inspectContainer(containerSummary.ID)
+		if err != nil {
+
+			// container may have been removed in between
+			if common.ResolveErrorStatusCodeOrDefault(err, 0) == http.StatusNotFound {
+				continue
+			}
+			return nil, errors.Wrap(err, "Failed to inspect containers")
+		}
+
+		containers = append(containers, *container)
+	}
+
+	return containers, nil
+}
+
+// GetContainerEvents returns a list of container events which occurred within a time range
+func (c *APIClient) GetContainerEvents(containerName string, since string, until string) ([]string, error) {
+	c.logger.DebugWith("Getting container events",
+		"containerName", containerName,
+		"since", since,
+		"until", until)
+
+	encodedFilters, err := json.Marshal(map[string][]string{"container": {containerName}})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode event filters")
+	}
+
+	now := time.Now()
+	response, err := c.sendStreamRequest(http.MethodGet,
+		"/events",
+		url.Values{
+			"filters": {string(encodedFilters)},
+			"since":   {resolveEventsTimestamp(since, now)},
+			"until":   {resolveEventsTimestamp(until, now)},
+		},
+		nil,
+		nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get container events")
+	}
+	defer response.Body.Close() // nolint: errcheck
+
+	var events []string
+	decoder := json.NewDecoder(response.Body)
+	for {
+		event := apiEvent{}
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.Wrap(err, "Failed to decode container event")
+		}
+
+		events = append(events, event.String())
+	}
+
+	return events, nil
+}
+
+// AwaitContainerHealth blocks until the given container is healthy or the timeout passes
+func (c *APIClient) AwaitContainerHealth(containerID string, timeout *time.Duration) error {
+	c.logger.DebugWith("Awaiting container health", "containerID", containerID, "timeout", timeout)
+
+	return awaitContainerHealth(c.logger, c, containerID, timeout)
+}
+
+// LogIn allows docker client to access secured registries
+func (c *APIClient) LogIn(options *LogInOptions) error {
+	c.logger.DebugWith("Performing docker login", "URL", options.URL)
+
+	authConfig := apiAuthConfig{
+		Username:      options.Username,
+		Password:      options.Password,
+		ServerAddress: options.URL,
+	}
+
+	// let the engine verify the credentials, like the docker cli does
+	if _, err := c.sendRequest(http.MethodPost, "/auth", nil, authConfig, nil, nil); err != nil {
+		return errors.Wrap(err, "Failed to log in")
+	}
+
+	c.authConfigsLock.Lock()
+	c.authConfigs[normalizeRegistryAddress(options.URL)] = authConfig
+	c.authConfigsLock.Unlock()
+
+	return nil
+}
+
+// CreateNetwork creates a docker network
+func (c *APIClient) CreateNetwork(options *CreateNetworkOptions) error {
+	c.logger.DebugWith("Creating docker network", "options", options)
+
+	_, err := c.sendRequest(http.MethodPost,
+		"/networks/create",
+		nil,
+		map[string]interface{}{"Name": options.Name, "CheckDuplicate": true},
+		nil,
+		nil)
+	return err
+}
+
+// GetContainerNetworkSettings returns container network settings
+func (c *APIClient) GetContainerNetworkSettings(containerID string) (*NetworkSettings, error) {
+	c.logger.DebugWith("Getting container network settings", "containerID", containerID)
+
+	container, err := c.inspectContainer(containerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to inspect container")
+	}
+
+	if container.NetworkSettings == nil {
+		return &NetworkSettings{}, nil
+	}
+
+	return container.NetworkSettings, nil
+}
+
+// DeleteNetwork deletes a docker network
+func (c *APIClient) DeleteNetwork(networkName string) error {
+	c.logger.DebugWith("Deleting docker network", "networkName", networkName)
+
+	_, err := c.sendRequest(http.MethodDelete,
+		fmt.Sprintf("/networks/%s", networkName),
+		nil,
+		nil,
+		nil,
+		nil)
+	return err
+}
+
+// CreateVolume creates a docker volume
+func (c *APIClient) CreateVolume(options *CreateVolumeOptions) error {
+	c.logger.DebugWith("Creating docker volume", "options", options)
+
+	_, err := c.sendRequest(http.MethodPost,
+		"/volumes/create",
+		nil,
+		map[string]interface{}{"Name": options.Name},
+		nil,
+		nil)
+	return err
+}
+
+// DeleteVolume deletes a docker volume
+func (c *APIClient) DeleteVolume(volumeName string) error {
+	c.logger.DebugWith("Deleting docker volume", "volumeName", volumeName)
+
+	_, err := c.sendRequest(http.MethodDelete,
+		fmt.Sprintf("/volumes/%s", volumeName),
+		url.Values{"force": {"1"}},
+		nil,
+		nil,
+		nil)
+	return err
+}
+
+// Save saves a docker image as tar in specified path
+func (c *APIClient) Save(imageName string, outPath string) error {
+	c.logger.DebugWith("Docker saving to path", "outPath", outPath, "imageName", imageName)
+
+	if _, err := reference.Parse(imageName); err != nil {
+		return errors.Wrap(err, "Invalid image name to save")
+	}
+
+	response, err := c.sendStreamRequest(http.MethodGet,
+		"/images/get",
+		url.Values{"names": {imageName}},
+		nil,
+		nil)
+	if err != nil {
+		return errors.Wrap(err, "Failed to save image")
+	}
+	defer response.Body.Close() // nolint: errcheck
+
+	outFile, err := os.Create(outPath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create image archive file")
+	}
+	defer outFile.Close() // nolint: errcheck
+
+	if _, err := io.Copy(outFile, response.Body); err != nil {
+		return errors.Wrap(err, "Failed to write image archive")
+	}
+
+	return nil
+}
+
+// Load loads a docker image from tar as cached image
+func (c *APIClient) Load(inPath string) error {
+	c.logger.DebugWith("Docker loading from path", "inPath", inPath)
+
+	inFile, err := os.Open(inPath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to open image archive file")
+	}
+	defer inFile.Close() // nolint: errcheck
+
+	response, err := c.sendStreamRequest(http.MethodPost,
+		"/images/load",
+		url.Values{"quiet": {"1"}},
+		inFile,
+		map[string]string{"Content-Type": "application/x-tar"})
+	if err != nil {
+		return errors.Wrap(err, "Failed to load image")
+	}
+	defer response.Body.Close() // nolint: errcheck
+
+	return c.readJSONMessages(response.Body, nil)
+}
+
+// GetVersion returns docker engine version
+func (c *APIClient) GetVersion(quiet bool) (string, error) {
+	response, err := c.sendRequest(http.MethodGet, "/version", nil, nil, nil, nil)
+	if err != nil {
+		if !quiet {
+			c.logger.WarnWith("Failed to get docker version", "err", err.Error())
+		}
+		return "", errors.Wrap(err, "Failed to get docker version")
+	}
+
+	return string(response), nil
+}
+
+// GetContainerIPAddresses return list of container ip addresses
+func (c *APIClient) GetContainerIPAddresses(containerID string) ([]string, error) {
+	c.logger.DebugWith("Getting container IP addresses", "containerID", containerID)
+
+	networkSettings, err := c.GetContainerNetworkSettings(containerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get container ip addresses")
+	}
+
+	var addresses []string
+	for _, network := range networkSettings.Networks {
+		if network != nil && network.IPAddress != "" {
+			addresses = append(addresses, network.IPAddress)
+		}
+	}
+
+	return addresses, nil
+}
+
+// GetContainerLogStream return container log stream
+func (c *APIClient) GetContainerLogStream(ctx context.Context,
+	containerID string,
+	logOptions *ContainerLogsOptions) (io.ReadCloser, error) {
+
+	if logOptions == nil {
+		logOptions = &ContainerLogsOptions{
+			Follow: true,
+		}
+	}
+
+	// the docker cli shows both streams, unless told otherwise
+	showStdout := logOptions.ShowStdout || !logOptions.ShowStderr
+	showStderr := logOptions.ShowStderr || !logOptions.ShowStdout
+
+	query := url.Values{
+		"stdout":     {strconv.FormatBool(showStdout)},
+		"stderr":     {strconv.FormatBool(showStderr)},
+		"follow":     {strconv.FormatBool(logOptions.Follow)},
+		"timestamps": {strconv.FormatBool(logOptions.Timestamps)},
+		"details":    {strconv.FormatBool(logOptions.Details)},
+	}
+
+	now := time.Now()
+	if logOptions.Since != "" {
+		query.Set("since", resolveEventsTimestamp(logOptions.Since, now))
+	}
+	if logOptions.Until != "" {
+		query.Set("until", resolveEventsTimestamp(logOptions.Until, now))
+	}
+	if logOptions.Tail != "" {
+		query.Set("tail", logOptions.Tail)
+	}
+
+	// logs of containers with a tty are not multiplexed
+	container, err := c.inspectContainer(containerID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to inspect container")
+	}
+
+	response, err := c.sendStreamRequestWithContext(ctx,
+		http.MethodGet,
+		fmt.Sprintf("/containers/%s/logs", containerID),
+		query,
+		nil,
+		nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get container log stream")
+	}
+
+	if container.Config != nil && container.Config.Tty {
+		return response.Body, nil
+	}
+
+	// interlace stdout and stderr into a single stream
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		defer response.Body.Close() // nolint: errcheck
+
+		pipeWriter.CloseWithError(demultiplexStream(response.Body, pipeWriter, pipeWriter)) // nolint: errcheck
+	}()
+
+	return pipeReader, nil
+}
+
+func (c *APIClient) negotiateAPIVersion() error {
+	versionResponse := struct {
+		APIVersion string `json:"ApiVersion"`
+	}{}
+
+	if _, err := c.sendRequest(http.MethodGet, "/version", nil, nil, &versionResponse, nil); err != nil {
+		return errors.Wrap(err, "Failed to get docker engine version")
+	}
+
+	c.apiVersion = maxAPIVersion
+	if versionResponse.APIVersion != "" && compareAPIVersions(versionResponse.APIVersion, maxAPIVersion) < 0 {
+		c.apiVersion = versionResponse.APIVersion
+	}
+
+	c.logger.DebugWith("Negotiated docker engine API version",
+		"engineAPIVersion", versionResponse.APIVersion,
+		"apiVersion", c.apiVersion)
+
+	return nil
+}
+
+func (c *APIClient) build(buildOptions *BuildOptions, query url.Values) error {
+	buildContext, dockerfileName, err := c.archiveBuildContext(buildOptions.ContextDir, buildOptions.DockerfilePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to archive build context")
+	}
+
+	query.Set("dockerfile", dockerfileName)
+
+	headers := map[string]string{"Content-Type": "application/x-tar"}
+	if registryConfig := c.encodeRegistryConfig(); registryConfig != "" {
+		headers["X-Registry-Config"] = registryConfig
+	}
+
+	response, err := c.sendStreamRequest(http.MethodPost, "/build", query, buildContext, headers)
+	if err != nil {
+		return errors.Wrap(err, "Failed to send build request")
+	}
+	defer response.Body.Close() // nolint: errcheck
+
+	var buildOutput strings.Builder
+	if err := c.readJSONMessages(response.Body, &buildOutput); err != nil {
+		c.logger.WarnWith("Failed to build image", "err", err.Error(), "output", buildOutput.String())
+		return err
+	}
+
+	c.logger.DebugWith("Image build output", "image", buildOptions.Image, "output", buildOutput.String())
+	return nil
+}
+
+func (c *APIClient) compileBuildQuery(buildOptions *BuildOptions) (url.Values, error) {
+	query := url.Values{
+		"t":       {buildOptions.Image},
+		"forcerm": {"1"},
+	}
+
+	if buildOptions.NoCache {
+		query.Set("nocache", "1")
+	}
+
+	if buildOptions.Pull {
+		query.Set("pull", "1")
+	}
+
+	if networkMode := resolveDockerBuildNetworkMode(); networkMode != "" {
+		query.Set("networkmode", networkMode)
+	}
+
+	if len(buildOptions.BuildArgs) > 0 {
+		encodedBuildArgs, err := json.Marshal(buildOptions.BuildArgs)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to encode build args")
+		}
+		query.Set("buildargs", string(encodedBuildArgs))
+	}
+
+	// build flags are given in their docker cli form
+	labels := map[string]string{}
+	for buildFlag := range buildOptions.BuildFlags {
+		flagName, flagValue, _ := strings.Cut(strings.Trim(buildFlag, `'"`), "=")
+		switch flagName {
+		case "--no-cache":
+			query.Set("nocache", "1")
+		case "--pull":
+			query.Set("pull", "1")
+		case "--rm", "--force-rm":
+			query.Set(strings.TrimPrefix(flagName, "--"), "1")
+		case "--squash":
+			query.Set("squash", "1")
+		case "--network":
+			query.Set("networkmode", flagValue)
+		case "--platform", "--target":
+			query.Set(strings.TrimPrefix(flagName, "--"), flagValue)
+		case "--label":
+			labelName, labelValue, _ := strings.Cut(flagValue, "=")
+			labels[labelName] = labelValue
+		default:
+			return nil, errors.Errorf("Build flag %s is not supported by the docker engine API client", flagName)
+		}
+	}
+
+	if len(labels) > 0 {
+		encodedLabels, err := json.Marshal(labels)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to encode build labels")
+		}
+		query.Set("labels", string(encodedLabels))
+	}
+
+	return query, nil
+}
+
+// archiveBuildContext tars the build context. the engine expects the dockerfile within the context,
+// so when it resides elsewhere it is added to the archive
+func (c *APIClient) archiveBuildContext(contextDir string, dockerfilePath string) (io.Reader, string, error) {
+	buildContext := &bytes.Buffer{}
+	tarWriter := tar.NewWriter(buildContext)
+
+	if err := addDirToTar(tarWriter, contextDir, ""); err != nil {
+		return nil, "", errors.Wrap(err, "Failed to add context dir to archive")
+	}
+
+	dockerfileName, err := filepath.Rel(contextDir, dockerfilePath)
+	if err != nil || strings.HasPrefix(dockerfileName, "..") {
+		dockerfileName = outOfContextDockerfileName
+		if err := addFileToTar(tarWriter, dockerfilePath, dockerfileName); err != nil {
+			return nil, "", errors.Wrap(err, "Failed to add dockerfile to archive")
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, "", errors.Wrap(err, "Failed to close archive")
+	}
+
+	return buildContext, filepath.ToSlash(dockerfileName), nil
+}
+
+func (c *APIClient) compileContainerCreateRequest(imageName string,
+	runOptions *RunOptions) (*apiContainerCreateRequest, error) {
+
+	createRequest := &apiContainerCreateRequest{
+		Image:        imageName,
+		Env:          c.compileEnv(runOptions.Env),
+		Labels:       runOptions.Labels,
+		AttachStdout: runOptions.Attach,
+		AttachStderr: runOptions.Attach,
+		HostConfig: apiHostConfig{
+			NetworkMode: runOptions.Network,
+		},
+	}
+
+	if runOptions.Command != "" {
+		command, err := splitCommand(runOptions.Command)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid command")
+		}
+		createRequest.Cmd = command
+	}
+
+	for localPort, dockerPort := range runOptions.Ports {
+		if localPort == RunOptionsNoPort {
+			continue
+		}
+
+		containerPort := Port(fmt.Sprintf("%d/tcp", dockerPort))
+		hostPort := ""
+		if localPort != RunOptionsRandomPort {
+			hostPort = strconv.Itoa(localPort)
+		}
+
+		if createRequest.ExposedPorts == nil {
+			createRequest.ExposedPorts = map[Port]struct{}{}
+			createRequest.HostConfig.PortBindings = PortMap{}
+		}
+		createRequest.ExposedPorts[containerPort] = struct{}{}
+		createRequest.HostConfig.PortBindings[containerPort] = append(
+			createRequest.HostConfig.PortBindings[containerPort],
+			PortBinding{HostPort: hostPort})
+	}
+
+	if runOptions.RestartPolicy != nil && runOptions.RestartPolicy.Name != RestartPolicyNameNo {
+
+		// combining a restart policy with the clean up results in an error
+		if runOptions.Remove {
+			return nil, errors.Errorf("Cannot combine restart policy with container removal")
+		}
+
+		createRequest.HostConfig.RestartPolicy = &RestartPolicy{Name: runOptions.RestartPolicy.Name}
+		if runOptions.RestartPolicy.Name == RestartPolicyNameOnFailure &&
+			runOptions.RestartPolicy.MaximumRetryCount >= 0 {
+			createRequest.HostConfig.RestartPolicy.MaximumRetryCount = runOptions.RestartPolicy.MaximumRetryCount
+		}
+	}
+
+	// attached containers are removed once their output is collected
+	createRequest.HostConfig.AutoRemove = runOptions.Remove && !runOptions.Attach
+
+	if runOptions.GPUs != "" {
+		deviceRequest, err := parseGPUs(runOptions.GPUs)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid GPUs")
+		}
+		createRequest.HostConfig.DeviceRequests = []apiDeviceRequest{*deviceRequest}
+	}
+
+	if runOptions.Memory != "" {
+		memory, err := parseMemory(runOptions.Memory)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid memory")
+		}
+		createRequest.HostConfig.Memory = memory
+	}
+
+	if runOptions.CPUs != "" {
+		cpus, err := strconv.ParseFloat(runOptions.CPUs, 64)
+		if err != nil {
+			return nil, errors.Wrap(err, "Invalid CPUs")
+		}
+		createRequest.HostConfig.NanoCPUs = int64(cpus * 1e9)
+	}
+
+	for volumeHostPath, volumeContainerPath := range runOptions.Volumes {
+		createRequest.HostConfig.Binds = append(createRequest.HostConfig.Binds,
+			fmt.Sprintf("%s:%s", volumeHostPath, volumeContainerPath))
+	}
+
+	// keep binds in a consistent order
+	sort.Strings(createRequest.HostConfig.Binds)
+
+	for _, mountPoint := range runOptions.MountPoints {
+		mountType := mountPoint.Type
+		if mountType == "" {
+			mountType = "volume"
+		}
+		createRequest.HostConfig.Mounts = append(createRequest.HostConfig.Mounts, apiMount{
+			Type:     mountType,
+			Source:   mountPoint.Source,
+			Target:   mountPoint.Destination,
+			ReadOnly: !mountPoint.RW,
+		})
+	}
+
+	for _, device := range runOptions.Devices {
+		createRequest.HostConfig.Devices = append(createRequest.HostConfig.Devices, parseDevice(device))
+	}
+
+	if runOptions.RunAsUser != nil || runOptions.RunAsGroup != nil {
+		if runOptions.RunAsUser != nil {
+			createRequest.User = strconv.FormatInt(*runOptions.RunAsUser, 10)
+		}
+		if runOptions.RunAsGroup != nil {
+			createRequest.User += ":" + strconv.FormatInt(*runOptions.RunAsGroup, 10)
+		}
+	}
+
+	if runOptions.FSGroup != nil {
+		createRequest.HostConfig.GroupAdd = []string{strconv.FormatInt(*runOptions.FSGroup, 10)}
+	}
+
+	return createRequest, nil
+}
+
+func (c *APIClient) createContainer(createRequest *apiContainerCreateRequest, containerName string) (string, error) {
+	var query url.Values
+	if containerName != "" {
+		query = url.Values{"name": {containerName}}
+	}
+
+	createResponse := struct {
+		ID string `json:"Id"`
+	}{}
+
+	_, err := c.sendRequest(http.MethodPost, "/containers/create", query, createRequest, &createResponse, nil)
+
+	// pull the image if it doesn't exist locally, like the docker cli does
+	if err != nil && common.ResolveErrorStatusCodeOrDefault(err, 0) == http.StatusNotFound && strings.Contains(err.Error(), "No such image") {
+		c.logger.DebugWith("Image does not exist locally, pulling", "image", createRequest.Image)
+
+		if err := c.PullImage(createRequest.Image); err != nil {
+			return "", errors.Wrap(err, "Failed to pull image")
+		}
+
+		_, err = c.sendRequest(http.MethodPost, "/containers/create", query, createRequest, &createResponse, nil)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return createResponse.ID, nil
+}
+
+func (c *APIClient) awaitAttachedContainer(containerID string, runOptions *RunOptions) error {
+	waitResponse := struct {
+		StatusCode int
+	}{}
+
+	if _, err := c.sendRequest(http.MethodPost,
+		fmt.Sprintf("/containers/%s/wait", containerID),
+		nil,
+		nil,
+		&waitResponse,
+		nil); err != nil {
+		return errors.Wrap(err, "Failed to wait for container")
+	}
+
+	response, err := c.sendStreamRequest(http.MethodGet,
+		fmt.Sprintf("/containers/%s/logs", containerID),
+		url.Values{"stdout": {"1"}, "stderr": {"1"}},
+		nil,
+		nil)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get container logs")
+	}
+	defer response.Body.Close() // nolint: errcheck
+
+	var stdout, stderr bytes.Buffer
+	if err := demultiplexStream(response.Body, &stdout, &stderr); err != nil {
+		return errors.Wrap(err, "Failed to read container logs")
+	}
+
+	if runOptions.Remove {
+		if err := c.RemoveContainer(containerID); err != nil {
+			c.logger.WarnWith("Failed to remove container", "containerID", containerID, "err", err.Error())
+		}
+	}
+
+	// if user requested, set stdout / stderr
+	if runOptions.Stdout != nil {
+		*runOptions.Stdout = stdout.String()
+	}
+
+	if runOptions.Stderr != nil {
+		*runOptions.Stderr = stderr.String()
+	}
+
+	if waitResponse.StatusCode != 0 {
+		return errors.Errorf("Container exited with code %d\n\nstdout:\n%s\n\nstderr:\n%s",
+			waitResponse.StatusCode,
+			stdout.String(),
+			stderr.String())
+	}
+
+	return nil
+}
+
+func (c *APIClient) inspectContainer(containerID string) (*Container, error) {
+	container := &Container{}
+	if _, err := c.sendRequest(http.MethodGet,
+		fmt.Sprintf("/containers/%s/json", containerID),
+		nil,
+		nil,
+		container,
+		nil); err != nil {
+		return nil, err
+	}
+
+	return container, nil
+}
+
+func (c *APIClient) copyObjectFromContainer(containerID string, objectImagePath string, objectLocalPath string) error {
+	response, err := c.sendStreamRequest(http.MethodGet,
+		fmt.Sprintf("/containers/%s/archive", containerID),
+		url.Values{"path": {objectImagePath}},
+		nil,
+		nil)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get object archive")
+	}
+	defer response.Body.Close() // nolint: errcheck
+
+	// the archive is rooted at the object's base name, which is copied as the local path
+	tarReader := tar.NewReader(response.Body)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "Failed to read object archive")
+		}
+
+		relativePath := ""
+		if _, rest, found := strings.Cut(strings.TrimPrefix(header.Name, "/"), "/"); found {
+			relativePath = rest
+		}
+
+		targetPath := filepath.Join(objectLocalPath, filepath.FromSlash(relativePath))
+		if err := extractTarEntry(tarReader, header, targetPath); err != nil {
+			return errors.Wrapf(err, "Failed to extract %s", header.Name)
+		}
+	}
+}
+
+func (c *APIClient) compileEnv(env map[string]string) []string {
+	var compiledEnv []string
+	for envName, envValue := range env {
+		compiledEnv = append(compiledEnv, fmt.Sprintf("%s=%s", envName, envValue))
+	}
+
+	sort.Strings(compiledEnv)
+	return compiledEnv
+}
+
+func (c *APIClient) splitImageReference(imageReference reference.Named) (string, string) {
+	tag := ""
+	if tagged, ok := imageReference.(reference.Tagged); ok {
+		tag = tagged.Tag()
+	}
+
+	return reference.FamiliarName(imageReference), tag
+}
+
+func (c *APIClient) encodeImageAuthConfig(imageReference reference.Named) string {
+	c.authConfigsLock.Lock()
+	authConfig := c.authConfigs[normalizeRegistryAddress(reference.Domain(imageReference))]
+	c.authConfigsLock.Unlock()
+
+	return encodeAuthConfig(authConfig)
+}
+
+func (c *APIClient) encodeRegistryConfig() string {
+	c.authConfigsLock.Lock()
+	defer c.authConfigsLock.Unlock()
+
+	if len(c.authConfigs) == 0 {
+		return ""
+	}
+
+	return encodeAuthConfig(c.authConfigs)
+}
+
+func (c *APIClient) readJSONMessages(reader io.Reader, output io.Writer) error {
+	decoder := json.NewDecoder(reader)
+	for {
+		message := apiJSONMessage{}
+		if err := decoder.Decode(&message); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return errors.Wrap(err, "Failed to decode docker engine message")
+		}
+
+		if message.ErrorDetail != nil && message.ErrorDetail.Message != "" {
+			return errors.New(strings.TrimSpace(message.ErrorDetail.Message))
+		}
+
+		if message.Error != "" {
+			return errors.New(strings.TrimSpace(message.Error))
+		}
+
+		if output != nil {
+			output.Write([]byte(message.Stream)) // nolint: errcheck
+		}
+	}
+}
+
+func (c *APIClient) sendRequest(method string,
+	requestPath string,
+	query url.Values,
+	requestBody interface{},
+	responseBody interface{},
+	headers map[string]string) ([]byte, error) {
+
+	response, err := c.sendStreamRequest(method, requestPath, query, requestBody, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close() // nolint: errcheck
+
+	encodedResponseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read response body")
+	}
+
+	if responseBody != nil && len(encodedResponseBody) > 0 {
+		if err := json.Unmarshal(encodedResponseBody, responseBody); err != nil {
+			return nil, errors.Wrap(err, "Failed to decode response body")
+		}
+	}
+
+	return encodedResponseBody, nil
+}
+
+func (c *APIClient) sendStreamRequest(method string,
+	requestPath string,
+	query url.Values,
+	requestBody interface{},
+	headers map[string]string) (*http.Response, error) {
+	return c.sendStreamRequestWithContext(context.Background(), method, requestPath, query, requestBody, headers)
+}
+
+// sendStreamRequestWithContext sends a request and returns the response for the caller to read (and close) its body.
+// engine errors are returned as errors with the matching status code
+func (c *APIClient) sendStreamRequestWithContext(ctx context.Context,
+	method string,
+	requestPath string,
+	query url.Values,
+	requestBody interface{},
+	headers map[string]string) (*http.Response, error) {
+
+	var bodyReader io.Reader
+	switch typedRequestBody := requestBody.(type) {
+	case nil:
+	case io.Reader:
+		bodyReader = typedRequestBody
+	default:
+		encodedBody, err := json.Marshal(typedRequestBody)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to encode request body")
+		}
+		bodyReader = bytes.NewReader(encodedBody)
+	}
+
+	requestURL := c.baseURL
+	if c.apiVersion != "" {
+		requestURL += "/v" + c.apiVersion
+	}
+	requestURL += requestPath
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, bodyReader)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create request")
+	}
+
+	if bodyReader != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	for headerName, headerValue := range headers {
+		request.Header.Set(headerName, headerValue)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to send request to docker engine: %s %s", method, requestPath)
+	}
+
+	if response.StatusCode >= http.StatusBadRequest {
+		defer response.Body.Close() // nolint: errcheck
+
+		errorResponse := struct {
+			Message string `json:"message"`
+		}{}
+
+		encodedErrorResponse, _ := io.ReadAll(response.Body)
+		if err := json.Unmarshal(encodedErrorResponse, &errorResponse); err != nil || errorResponse.Message == "" {
+			errorResponse.Message = strings.TrimSpace(string(encodedErrorResponse))
+		}
+
+		return nil, nuclio.GetByStatusCode(response.StatusCode)(errorResponse.Message)
+	}
+
+	return response, nil
+}
+
+// demultiplexStream splits a multiplexed stdout / stderr engine stream, where each frame is prefixed by
+// an 8 byte header holding the stream type and frame size
+func demultiplexStream(reader io.Reader, stdout io.Writer, stderr io.Writer) error {
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return errors.Wrap(err, "Failed to read stream frame header")
+		}
+
+		frameWriter := stdout
+		if header[0] == 2 {
+			frameWriter = stderr
+		}
+
+		frameSize := int64(binary.BigEndian.Uint32(header[4:]))
+		if _, err := io.CopyN(frameWriter, reader, frameSize); err != nil {
+			return errors.Wrap(err, "Failed to read stream frame")
+		}
+	}
+}
+
+// splitCommand splits a command to its arguments, honoring quotes and escapes like a posix shell does
+func splitCommand(command string) ([]string, error) {
+	var arguments []string
+	var currentArgument strings.Builder
+	var quote rune
+	inArgument := false
+	escaped := false
+
+	for _, char := range command {
+		switch {
+		case escaped:
+			currentArgument.WriteRune(char)
+			escaped = false
+		case char == '\\' && quote != '\'':
+			escaped = true
+			inArgument = true
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else {
+				currentArgument.WriteRune(char)
+			}
+		case char == '\'' || char == '"':
+			quote = char
+			inArgument = true
+		case char == ' ' || char == '\t' || char == '\n':
+			if inArgument {
+				arguments = append(arguments, currentArgument.String())
+				currentArgument.Reset()
+				inArgument = false
+			}
+		default:
+			currentArgument.WriteRune(char)
+			inArgument = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, errors.Errorf("Unterminated quote or escape in command: %s", command)
+	}
+
+	if inArgument {
+		arguments = append(arguments, currentArgument.String())
+	}
+
+	return arguments, nil
+}
+
+func compareAPIVersions(first string, second string) int {
+	firstParts := strings.Split(first, ".")
+	secondParts := strings.Split(second, ".")
+
+	for partIndex := 0; partIndex < len(firstParts) || partIndex < len(secondParts); partIndex++ {
+		var firstPart, secondPart int
+		if partIndex < len(firstParts) {
+			firstPart, _ = strconv.Atoi(firstParts[partIndex])
+		}
+		if partIndex < len(secondParts) {
+			secondPart, _ = strconv.Atoi(secondParts[partIndex])
+		}
+
+		if firstPart != secondPart {
+			if firstPart < secondPart {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return 0
+}
+
+// resolveEventsTimestamp converts a timestamp the docker cli accepts (RFC3339 or a duration relative to now)
+// to the unix timestamp the engine expects
+func resolveEventsTimestamp(value string, now time.Time) string {
+	for _, layout := range []string{time.RFC3339Nano, time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+		if parsedTime, err := time.Parse(layout, value); err == nil {
+			return formatUnixTimestamp(parsedTime)
+		}
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return formatUnixTimestamp(now.Add(-duration))
+	}
+
+	// already a unix timestamp
+	return value
+}
+
+func formatUnixTimestamp(value time.Time) string {
+	return fmt.Sprintf("%d.%09d", value.Unix(), value.Nanosecond())
+}
+
+func resolveDockerBuildNetworkMode() string {
+
+	// may contain none as a value
+	networkInterface := os.Getenv("NUCLIO_DOCKER_BUILD_NETWORK")
+	if networkInterface == "" {
+		networkInterface = common.GetEnvOrDefaultString("NUCLIO_BUILD_USE_HOST_NET", "host")
+	}
+
+	switch networkInterface {
+	case "host", "default", "none":
+		return networkInterface
+	default:
+		return ""
+	}
+}
+
+// parseMemory parses a memory limit the way the docker cli does (e.g. 512m, 1g)
+func parseMemory(memory string) (int64, error) {
+	memory = strings.ToLower(strings.TrimSpace(memory))
+	units := map[string]int64{
+		"b": 1,
+		"k": 1 << 10,
+		"m": 1 << 20,
+		"g": 1 << 30,
+		"t": 1 << 40,
+	}
+
+	multiplier := int64(1)
+	numberPart := strings.TrimSuffix(memory, "b")
+	if numberPart == "" {
+		numberPart = memory
+	}
+	if unitMultiplier, found := units[numberPart[len(numberPart)-1:]]; found {
+		multiplier = unitMultiplier
+		numberPart = numberPart[:len(numberPart)-1]
+	}
+
+	value, err := strconv.ParseFloat(numberPart, 64)
+	if err != nil || value < 0 {
+		return 0, errors.Errorf("Invalid memory value: %s", memory)
+	}
+
+	return int64(value * float64(multiplier)), nil
+}
+
+// parseGPUs parses a gpus request the way the docker cli does (e.g. all, 2, device=0,1)
+func parseGPUs(gpus string) (*apiDeviceRequest, error) {
+	deviceRequest := &apiDeviceRequest{
+		Capabilities: [][]string{{"gpu"}},
+	}
+
+	gpus = strings.Trim(gpus, `'"`)
+	switch {
+	case gpus == "all":
+		deviceRequest.Count = -1
+	case strings.HasPrefix(gpus, "device="):
+		deviceRequest.DeviceIDs = strings.Split(strings.TrimPrefix(gpus, "device="), ",")
+	default:
+		count, err := strconv.Atoi(gpus)
+		if err != nil {
+			return nil, errors.Errorf("Invalid GPUs value: %s", gpus)
+		}
+		deviceRequest.Count = count
+	}
+
+	return deviceRequest, nil
+}
+
+// parseDevice parses a device mapping the way the docker cli does (host path[:container path[:permissions]])
+func parseDevice(device string) apiDeviceMapping {
+	deviceMapping := apiDeviceMapping{
+		CgroupPermissions: "rwm",
+	}
+
+	deviceParts := strings.Split(device, ":")
+	deviceMapping.PathOnHost = deviceParts[0]
+	deviceMapping.PathInContainer = deviceParts[0]
+	if len(deviceParts) > 1 && deviceParts[1] != "" {
+		deviceMapping.PathInContainer = deviceParts[1]
+	}
+	if len(deviceParts) > 2 && deviceParts[2] != "" {
+		deviceMapping.CgroupPermissions = deviceParts[2]
+	}
+
+	return deviceMapping
+}
+
+// normalizeRegistryAddress returns the registry host, so that logins and image references can be matched
+func normalizeRegistryAddress(address string) string {
+	address = strings.TrimPrefix(strings.TrimPrefix(address, "https://"), "http://")
+	address, _, _ = strings.Cut(address, "/")
+
+	switch address {
+	case "", "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+
+	return address
+}
+
+func encodeAuthConfig(authConfig interface{}) string {
+	encodedAuthConfig, _ := json.Marshal(authConfig)
+	return base64.URLEncoding.EncodeToString(encodedAuthConfig)
+}
+
+func addDirToTar(tarWriter *tar.Writer, dirPath string, archivePrefix string) error {
+	return filepath.Walk(dirPath, func(filePath string, fileInfo os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(dirPath, filePath)
+		if err != nil {
+			return err
+		}
+
+		if relativePath == "." {
+			return nil
+		}
+
+		archivePath := filepath.ToSlash(filepath.Join(archivePrefix, relativePath))
+
+		linkTarget := ""
+		if fileInfo.Mode()&os.ModeSymlink != 0 {
+			if linkTarget, err = os.Readlink(filePath); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(fileInfo, linkTarget)
+		if err != nil {
+			return err
+		}
+		header.Name = archivePath
+
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !fileInfo.Mode().IsRegular() {
+			return nil
+		}
+
+		return copyFileToWriter(filePath, tarWriter)
+	})
+}
+
+func addFileToTar(tarWriter *tar.Writer, filePath string, archivePath string) error {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(fileInfo, "")
+	if err != nil {
+		return err
+	}
+	header.Name = archivePath
+
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+
+	return copyFileToWriter(filePath, tarWriter)
+}
+
+func copyFileToWriter(filePath string, writer io.Writer) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close() // nolint: errcheck
+
+	_, err = io.Copy(writer, file)
+	return err
+}
+
+func extractTarEntry(tarReader *tar.Reader, header *tar.Header, targetPath string) error {
+	switch header.Typeflag {
+	case tar.TypeDir:
+		return os.MkdirAll(targetPath, os.FileMode(header.Mode)|0700)
+	case tar.TypeSymlink:
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+			return err
+		}
+		return os.Symlink(header.Linkname, targetPath)
+	case tar.TypeReg:
+		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+			return err
+		}
+
+		file, err := os.OpenFile(targetPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, os.FileMode(header.Mode))
+		if err != nil {
+			return err
+		}
+		defer file.Close() // nolint: errcheck
+
+		_, err = io.Copy(file, tarReader) // nolint: gosec
+		return err
+	default:
+
+		// devices, fifos and the like aren't copied
+		return nil
+	}
+}
@@ -0,0 +1,487 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dockerclient
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type APIClientTestSuite struct {
+	suite.Suite
+	logger      logger.Logger
+	server      *httptest.Server
+	handlers    map[string]http.HandlerFunc
+	requests    []*http.Request
+	requestsMux sync.Mutex
+	apiClient   *APIClient
+}
+
+func (suite *APIClientTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err, "Failed to create logger")
+
+	suite.requests = nil
+	suite.handlers = map[string]http.HandlerFunc{
+		"GET /version": func(responseWriter http.ResponseWriter, request *http.Request) {
+			suite.writeJSON(responseWriter, map[string]string{"ApiVersion": "1.40", "Version": "19.03.0"})
+		},
+	}
+
+	// a stand-in for the docker engine, routing by method and path (without the version prefix)
+	suite.server = httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter,
+		request *http.Request) {
+		suite.requestsMux.Lock()
+		suite.requests = append(suite.requests, request)
+		suite.requestsMux.Unlock()
+
+		requestPath := request.URL.Path
+		if strings.HasPrefix(requestPath, "/v1.") {
+			requestPath = requestPath[strings.Index(requestPath[1:], "/")+1:]
+		}
+
+		handler, found := suite.handlers[request.Method+" "+requestPath]
+		if !found {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			suite.writeJSON(responseWriter, map[string]string{"message": "page not found"})
+			return
+		}
+
+		handler(responseWriter, request)
+	}))
+
+	suite.apiClient, err = NewAPIClient(suite.logger, "tcp://"+strings.TrimPrefix(suite.server.URL, "http://"))
+	suite.Require().NoError(err, "Failed to create api client")
+
+	suite.apiClient.buildRetryInterval = 1 * time.Millisecond
+}
+
+func (suite *APIClientTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *APIClientTestSuite) TestNegotiateAPIVersion() {
+	suite.Require().Equal("1.40", suite.apiClient.apiVersion)
+
+	// newer engines are talked to in the client's version
+	suite.handlers["GET /version"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.writeJSON(responseWriter, map[string]string{"ApiVersion": "1.43"})
+	}
+	suite.Require().NoError(suite.apiClient.negotiateAPIVersion())
+	suite.Require().Equal(maxAPIVersion, suite.apiClient.apiVersion)
+
+	_, err := suite.apiClient.GetVersion(true)
+	suite.Require().NoError(err)
+	suite.Require().Equal("/v"+maxAPIVersion+"/version", suite.lastRequest().URL.Path)
+}
+
+func (suite *APIClientTestSuite) TestRunContainer() {
+	var createRequest apiContainerCreateRequest
+	created := 0
+
+	suite.handlers["POST /containers/create"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.Require().Equal("somename", request.URL.Query().Get("name"))
+
+		// fail the first time, as if the image is missing
+		created++
+		if created == 1 {
+			responseWriter.WriteHeader(http.StatusNotFound)
+			suite.writeJSON(responseWriter, map[string]string{"message": "No such image: alpine:latest"})
+			return
+		}
+
+		suite.Require().NoError(json.NewDecoder(request.Body).Decode(&createRequest))
+		suite.writeJSON(responseWriter, map[string]string{"Id": "abc123"})
+	}
+
+	pulled := false
+	suite.handlers["POST /images/create"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.Require().Equal("alpine", request.URL.Query().Get("fromImage"))
+		suite.Require().Equal("latest", request.URL.Query().Get("tag"))
+		pulled = true
+		suite.writeJSON(responseWriter, map[string]string{"status": "Downloaded newer image"})
+	}
+
+	started := false
+	suite.handlers["POST /containers/abc123/start"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		started = true
+		responseWriter.WriteHeader(http.StatusNoContent)
+	}
+
+	runAsUser := int64(1000)
+	containerID, err := suite.apiClient.RunContainer("alpine:latest", &RunOptions{
+		ContainerName: "somename",
+		Ports:         map[int]int{7779: 8080, RunOptionsRandomPort: 9090},
+		Env:           map[string]string{"B": "2", "A": "1"},
+		Command:       `sh -c "echo 'hello world'"`,
+		Memory:        "512m",
+		CPUs:          "1.5",
+		GPUs:          "all",
+		RunAsUser:     &runAsUser,
+		RestartPolicy: &RestartPolicy{Name: RestartPolicyNameOnFailure, MaximumRetryCount: 3},
+		Volumes:       map[string]string{"/host": "/container"},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("abc123", containerID)
+	suite.Require().True(pulled)
+	suite.Require().True(started)
+
+	suite.Require().Equal([]string{"sh", "-c", "echo 'hello world'"}, createRequest.Cmd)
+	suite.Require().Equal([]string{"A=1", "B=2"}, createRequest.Env)
+	suite.Require().Equal("1000", createRequest.User)
+	suite.Require().Equal(int64(512*1024*1024), createRequest.HostConfig.Memory)
+	suite.Require().Equal(int64(1.5e9), createRequest.HostConfig.NanoCPUs)
+	suite.Require().Equal(-1, createRequest.HostConfig.DeviceRequests[0].Count)
+	suite.Require().Equal(RestartPolicyNameOnFailure, createRequest.HostConfig.RestartPolicy.Name)
+	suite.Require().Equal(3, createRequest.HostConfig.RestartPolicy.MaximumRetryCount)
+	suite.Require().Equal([]string{"/host:/container"}, createRequest.HostConfig.Binds)
+	suite.Require().Equal("7779", createRequest.HostConfig.PortBindings["8080/tcp"][0].HostPort)
+	suite.Require().Equal("", createRequest.HostConfig.PortBindings["9090/tcp"][0].HostPort)
+	suite.Require().Contains(createRequest.ExposedPorts, Port("8080/tcp"))
+}
+
+func (suite *APIClientTestSuite) TestRunContainerNameConflict() {
+	conflictMessage := `Conflict. The container name "/somename" is already in use by container "abc123"`
+	suite.handlers["POST /containers/create"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusConflict)
+		suite.writeJSON(responseWriter, map[string]string{"message": conflictMessage})
+	}
+
+	_, err := suite.apiClient.RunContainer("alpine", &RunOptions{ContainerName: "somename"})
+	suite.Require().Error(err)
+
+	// callers look for the engine message as is
+	suite.Require().Equal(conflictMessage, err.Error())
+	suite.Require().Equal(http.StatusConflict, common.ResolveErrorStatusCodeOrDefault(err, 0))
+}
+
+func (suite *APIClientTestSuite) TestRunContainerFailValidation() {
+	_, err := suite.apiClient.RunContainer("alpine", &RunOptions{
+		Remove:        true,
+		RestartPolicy: &RestartPolicy{Name: RestartPolicyNameAlways},
+	})
+	suite.Require().Error(err)
+
+	_, err = suite.apiClient.RunContainer("alpine", &RunOptions{Command: `sh -c "unterminated`})
+	suite.Require().Error(err)
+}
+
+func (suite *APIClientTestSuite) TestRunContainerAttached() {
+	suite.handlers["POST /containers/create"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.writeJSON(responseWriter, map[string]string{"Id": "abc123"})
+	}
+	suite.handlers["POST /containers/abc123/start"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusNoContent)
+	}
+	suite.handlers["POST /containers/abc123/wait"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.writeJSON(responseWriter, map[string]int{"StatusCode": 0})
+	}
+	suite.handlers["GET /containers/abc123/logs"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.writeMultiplexed(responseWriter, 1, "out")
+		suite.writeMultiplexed(responseWriter, 2, "err")
+	}
+	removed := false
+	suite.handlers["DELETE /containers/abc123"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		removed = true
+		responseWriter.WriteHeader(http.StatusNoContent)
+	}
+
+	var stdout, stderr string
+	_, err := suite.apiClient.RunContainer("alpine", &RunOptions{
+		Attach: true,
+		Remove: true,
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("out", stdout)
+	suite.Require().Equal("err", stderr)
+	suite.Require().True(removed)
+}
+
+func (suite *APIClientTestSuite) TestExecInContainer() {
+	exitCode := 0
+	suite.handlers["POST /containers/abc123/exec"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		execRequest := map[string]interface{}{}
+		suite.Require().NoError(json.NewDecoder(request.Body).Decode(&execRequest))
+		suite.Require().Equal([]interface{}{"cat", "/etc/nuclio/some file"}, execRequest["Cmd"])
+		suite.writeJSON(responseWriter, map[string]string{"Id": "exec1"})
+	}
+	suite.handlers["POST /exec/exec1/start"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.writeMultiplexed(responseWriter, 1, "line1\n")
+		suite.writeMultiplexed(responseWriter, 2, "warning\n")
+		suite.writeMultiplexed(responseWriter, 1, "line2\n")
+	}
+	suite.handlers["GET /exec/exec1/json"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.writeJSON(responseWriter, map[string]int{"ExitCode": exitCode})
+	}
+
+	var stdout, stderr string
+	err := suite.apiClient.ExecInContainer("abc123", &ExecOptions{
+		Command: `cat "/etc/nuclio/some file"`,
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("line1\nline2\n", stdout)
+	suite.Require().Equal("warning\n", stderr)
+
+	// non zero exit code fails
+	exitCode = 1
+	err = suite.apiClient.ExecInContainer("abc123", &ExecOptions{Command: `cat "/etc/nuclio/some file"`})
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "warning")
+}
+
+func (suite *APIClientTestSuite) TestExecInMissingContainer() {
+	suite.handlers["POST /containers/missing/exec"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.WriteHeader(http.StatusNotFound)
+		suite.writeJSON(responseWriter, map[string]string{"message": "No such container: missing"})
+	}
+
+	err := suite.apiClient.ExecInContainer("missing", &ExecOptions{Command: "ls"})
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "No such container")
+}
+
+func (suite *APIClientTestSuite) TestGetContainers() {
+	suite.handlers["GET /containers/json"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		filters := map[string][]string{}
+		suite.Require().NoError(json.Unmarshal([]byte(request.URL.Query().Get("filters")), &filters))
+		suite.Require().Equal([]string{"^/somename$"}, filters["name"])
+		suite.Require().Equal([]string{"nuclio.io/project-name=default"}, filters["label"])
+		suite.Require().Equal("1", request.URL.Query().Get("all"))
+
+		suite.writeJSON(responseWriter, []map[string]string{{"Id": "abc123"}, {"Id": "gone"}})
+	}
+	suite.handlers["GET /containers/abc123/json"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.writeJSON(responseWriter, Container{
+			ID:    "abc123",
+			Name:  "/somename",
+			State: &ContainerState{Status: "running"},
+			HostConfig: &HostConfig{
+				PortBindings: PortMap{"8080/tcp": {{HostPort: "0"}}},
+			},
+			NetworkSettings: &NetworkSettings{
+				NetworkSettingsBase: NetworkSettingsBase{
+					Ports: PortMap{"8080/tcp": {{HostPort: "32768"}}},
+				},
+			},
+		})
+	}
+
+	containers, err := suite.apiClient.GetContainers(&GetContainerOptions{
+		Name:    "somename",
+		Labels:  map[string]string{"nuclio.io/project-name": "default"},
+		Stopped: true,
+	})
+	suite.Require().NoError(err)
+
+	// containers removed in between listing and inspecting are skipped
+	suite.Require().Len(containers, 1)
+	suite.Require().Equal("abc123", containers[0].ID)
+
+	port, err := suite.apiClient.GetContainerPort(&containers[0], 8080)
+	suite.Require().NoError(err)
+	suite.Require().Equal(32768, port)
+}
+
+func (suite *APIClientTestSuite) TestGetContainerLogStream() {
+	suite.handlers["GET /containers/abc123/json"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.writeJSON(responseWriter, Container{ID: "abc123", Config: &Config{}})
+	}
+	suite.handlers["GET /containers/abc123/logs"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.Require().Equal("true", request.URL.Query().Get("follow"))
+		suite.Require().Equal("10", request.URL.Query().Get("tail"))
+		suite.writeMultiplexed(responseWriter, 1, "first\n")
+		suite.writeMultiplexed(responseWriter, 2, "second\n")
+	}
+
+	logStream, err := suite.apiClient.GetContainerLogStream(context.Background(),
+		"abc123",
+		&ContainerLogsOptions{Follow: true, Tail: "10"})
+	suite.Require().NoError(err)
+	defer logStream.Close() // nolint: errcheck
+
+	logs, err := io.ReadAll(logStream)
+	suite.Require().NoError(err)
+	suite.Require().Equal("first\nsecond\n", string(logs))
+}
+
+func (suite *APIClientTestSuite) TestGetContainerEvents() {
+	suite.handlers["GET /events"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.Require().Equal("1700000000.000000000", request.URL.Query().Get("since"))
+		suite.writeJSON(responseWriter, map[string]interface{}{
+			"Type":     "container",
+			"Action":   "die",
+			"Actor":    map[string]interface{}{"ID": "abc123", "Attributes": map[string]string{"name": "somename"}},
+			"timeNano": 1700000001000000000,
+		})
+	}
+
+	events, err := suite.apiClient.GetContainerEvents("somename", "2023-11-14T22:13:20Z", "1s")
+	suite.Require().NoError(err)
+	suite.Require().Len(events, 1)
+	suite.Require().Contains(events[0], "container die abc123 (name=somename)")
+}
+
+func (suite *APIClientTestSuite) TestBuild() {
+	tempDir := suite.T().TempDir()
+	suite.Require().NoError(os.WriteFile(path.Join(tempDir, "Dockerfile"), []byte("FROM alpine"), 0644))
+	suite.Require().NoError(os.WriteFile(path.Join(tempDir, "handler.sh"), []byte("echo"), 0644))
+
+	attempts := 0
+	suite.handlers["POST /build"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.Require().Equal("Dockerfile", request.URL.Query().Get("dockerfile"))
+		suite.Require().Equal("1", request.URL.Query().Get("nocache"))
+		suite.Require().Equal(`{"VERSION":"1"}`, request.URL.Query().Get("buildargs"))
+
+		// retry on well known errors
+		attempts++
+		if attempts == 1 {
+			suite.writeJSON(responseWriter, map[string]string{"stream": "Step 1/1 : FROM alpine\n"})
+			suite.writeJSON(responseWriter, map[string]string{"error": "failed to export image: whatever"})
+			return
+		}
+
+		suite.writeJSON(responseWriter, map[string]string{"stream": "Successfully built\n"})
+	}
+
+	err := suite.apiClient.Build(&BuildOptions{
+		Image:      "someimage:latest",
+		ContextDir: tempDir,
+		BuildArgs:  map[string]string{"VERSION": "1"},
+		BuildFlags: map[string]bool{"--no-cache": true},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(2, attempts)
+
+	// unknown errors bail out
+	suite.handlers["POST /build"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.writeJSON(responseWriter, map[string]string{"error": "something bad"})
+	}
+
+	err = suite.apiClient.Build(&BuildOptions{
+		Image:      "someimage:latest",
+		ContextDir: tempDir,
+	})
+	suite.Require().Error(err)
+
+	// unsupported build flags fail
+	err = suite.apiClient.Build(&BuildOptions{
+		Image:      "someimage:latest",
+		ContextDir: tempDir,
+		BuildFlags: map[string]bool{"--ssh=default": true},
+	})
+	suite.Require().Error(err)
+}
+
+func (suite *APIClientTestSuite) TestPushImage() {
+	suite.handlers["POST /auth"] = func(responseWriter http.ResponseWriter, request *http.Request) {
+		suite.writeJSON(responseWriter, map[string]string{"Status": "Login Succeeded"})
+	}
+	suite.handlers["POST /images/someimage:latest/tag"] = func(responseWriter http.ResponseWriter,
+		request *http.Request) {
+		suite.Require().Equal("localhost:5000/someimage", request.URL.Query().Get("repo"))
+		suite.Require().Equal("latest", request.URL.Query().Get("tag"))
+		responseWriter.WriteHeader(http.StatusCreated)
+	}
+
+	var registryAuth string
+	suite.handlers["POST /images/localhost:5000/someimage/push"] = func(responseWriter http.ResponseWriter,
+		request *http.Request) {
+		registryAuth = request.Header.Get("X-Registry-Auth")
+		suite.writeJSON(responseWriter, map[string]string{"status": "Pushed"})
+	}
+
+	err := suite.apiClient.LogIn(&LogInOptions{Username: "user", Password: "pass", URL: "localhost:5000"})
+	suite.Require().NoError(err)
+
+	err = suite.apiClient.PushImage("someimage:latest", "localhost:5000")
+	suite.Require().NoError(err)
+	suite.Require().Equal(encodeAuthConfig(apiAuthConfig{
+		Username:      "user",
+		Password:      "pass",
+		ServerAddress: "localhost:5000",
+	}), registryAuth)
+}
+
+func (suite *APIClientTestSuite) TestSplitCommand() {
+	for _, testCase := range []struct {
+		command   string
+		arguments []string
+		expectErr bool
+	}{
+		{command: "ls -la", arguments: []string{"ls", "-la"}},
+		{command: `  sh   -c "echo 'a b'"  `, arguments: []string{"sh", "-c", "echo 'a b'"}},
+		{command: `echo a\ b ""`, arguments: []string{"echo", "a b", ""}},
+		{command: `echo 'a"b'`, arguments: []string{"echo", `a"b`}},
+		{command: `echo "a`, expectErr: true},
+	} {
+		arguments, err := splitCommand(testCase.command)
+		if testCase.expectErr {
+			suite.Require().Error(err, testCase.command)
+			continue
+		}
+		suite.Require().NoError(err, testCase.command)
+		suite.Require().Equal(testCase.arguments, arguments, testCase.command)
+	}
+}
+
+func (suite *APIClientTestSuite) writeJSON(responseWriter http.ResponseWriter, body interface{}) {
+	encodedBody, err := json.Marshal(body)
+	suite.Require().NoError(err)
+	responseWriter.Write(append(encodedBody, '\n')) // nolint: errcheck
+}
+
+func (suite *APIClientTestSuite) writeMultiplexed(responseWriter http.ResponseWriter, stream byte, payload string) {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	responseWriter.Write(append(header, []byte(payload)...)) // nolint: errcheck
+}
+
+func (suite *APIClientTestSuite) lastRequest() *http.Request {
+	suite.requestsMux.Lock()
+	defer suite.requestsMux.Unlock()
+
+	return suite.requests[len(suite.requests)-1]
+}
+
+func TestAPIClientTestSuite(t *testing.T) {
+	suite.Run(t, new(APIClientTestSuite))
+}
@@ -18,10 +18,59 @@ package dockerclient
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"strconv"
 	"time"
+
+	"github.com/nuclio/nuclio/pkg/cmdrunner"
+	"github.com/nuclio/nuclio/pkg/common"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// ClientKind is the kind of docker client to use
+type ClientKind string
+
+const (
+	// ClientKindShell runs docker cli commands
+	ClientKindShell ClientKind = "shell"
+
+	// ClientKindAPI talks to the docker engine API directly
+	ClientKindAPI ClientKind = "api"
 )
 
+// ResolveClientKind returns the given kind, falling back to NUCLIO_DOCKER_CLIENT_KIND and then to the shell client
+func ResolveClientKind(kind ClientKind) ClientKind {
+	if kind == "" {
+		kind = ClientKind(common.GetEnvOrDefaultString("NUCLIO_DOCKER_CLIENT_KIND", string(ClientKindShell)))
+	}
+
+	return kind
+}
+
+// NewClient creates a docker client of the given kind. runner is used by the shell client only, and may be nil
+func NewClient(parentLogger logger.Logger, runner cmdrunner.CmdRunner, kind ClientKind) (Client, error) {
+	var client Client
+	var err error
+
+	switch ResolveClientKind(kind) {
+	case ClientKindShell:
+		client, err = NewShellClient(parentLogger, runner)
+	case ClientKindAPI:
+		client, err = NewAPIClient(parentLogger, "")
+	default:
+		return nil, errors.Errorf("Unknown docker client kind: %s", kind)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
 type Client interface {
 
 	// Build will build a docker image, given build options
@@ -103,3 +152,127 @@ type Client interface {
 	// GetContainerLogStream return container log stream
 	GetContainerLogStream(ctx context.Context, containerID string, logOptions *ContainerLogsOptions) (io.ReadCloser, error)
 }
+
+func resolveContainerPort(container *Container, boundPort int) (int, error) {
+	functionHostPort := Port(fmt.Sprintf("%d/tcp", boundPort))
+
+	var portBindings, ports []PortBinding
+	if container.HostConfig != nil {
+		portBindings = container.HostConfig.PortBindings[functionHostPort]
+	}
+	if container.NetworkSettings != nil {
+		ports = container.NetworkSettings.Ports[functionHostPort]
+	}
+	if len(portBindings) == 0 && len(ports) == 0 {
+		return 0, nil
+	}
+
+	// by default take the port binding, as if the user requested
+	if len(portBindings) != 0 &&
+		portBindings[0].HostPort != "" && // docker version < 20.10
+		portBindings[0].HostPort != "0" { // on docker version >= 20.10, the host port would by 0 and not empty string.
+		return strconv.Atoi(portBindings[0].HostPort)
+	}
+
+	// port was not explicit by user, take port assigned by docker daemon
+	if len(ports) != 0 && ports[0].HostPort != "" {
+		return strconv.Atoi(ports[0].HostPort)
+	}
+
+	// function might failed during deploying and did not assign a port
+	return 0, nil
+}
+
+func awaitContainerHealth(loggerInstance logger.Logger,
+	client Client,
+	containerID string,
+	timeout *time.Duration) error {
+	timedOut := false
+
+	containerHealthy := make(chan error, 1)
+	var timeoutChan <-chan time.Time
+
+	// if no timeout is given, create a channel that we'll never send on
+	if timeout == nil {
+		timeoutChan = make(<-chan time.Time, 1)
+	} else {
+		timeoutChan = time.After(*timeout)
+	}
+
+	go func() {
+
+		// start with a small interval between health checks, increasing it gradually
+		inspectInterval := 100 * time.Millisecond
+
+		for !timedOut {
+			containers, err := client.GetContainers(&GetContainerOptions{
+				ID:      containerID,
+				Stopped: true,
+			})
+			if err == nil && len(containers) > 0 && containers[0].State != nil {
+				container := containers[0]
+
+				// container is healthy
+				if container.State.Health != nil && container.State.Health.Status == "healthy" {
+					containerHealthy <- nil
+					return
+				}
+
+				// container exited, bail out
+				if container.State.Status == "exited" {
+					containerHealthy <- errors.Errorf("Container exited with status: %d", container.State.ExitCode)
+					return
+				}
+
+				// container is dead, bail out
+				// https://docs.docker.com/engine/reference/commandline/ps/#filtering
+				if container.State.Status == "dead" {
+					containerHealthy <- errors.New("Container seems to be dead")
+					return
+				}
+
+				// wait a bit before retrying
+				loggerInstance.DebugWith("Container not healthy yet, retrying soon",
+					"timeout", timeout,
+					"containerID", containerID,
+					"containerState", container.State,
+					"nextCheckIn", inspectInterval)
+			}
+
+			time.Sleep(inspectInterval)
+
+			// increase the interval up to a cap
+			if inspectInterval < 800*time.Millisecond {
+				inspectInterval *= 2
+			}
+		}
+	}()
+
+	// wait for either the container to be healthy or the timeout
+	select {
+	case err := <-containerHealthy:
+		if err != nil {
+			return errors.Wrapf(err, "Container %s is not healthy", containerID)
+		}
+		loggerInstance.DebugWith("Container is healthy", "containerID", containerID)
+	case <-timeoutChan:
+		timedOut = true
+
+		containerLogs, err := client.GetContainerLogs(containerID)
+		if err != nil {
+			loggerInstance.ErrorWith("Container wasn't healthy within timeout (failed to get logs)",
+				"containerID", containerID,
+				"timeout", timeout,
+				"err", err)
+		} else {
+			loggerInstance.WarnWith("Container wasn't healthy within timeout",
+				"containerID", containerID,
+				"timeout", timeout,
+				"logs", containerLogs)
+		}
+
+		return errors.New("Container wasn't healthy in time")
+	}
+
+	return nil
+}
@@ -23,7 +23,6 @@ import (
 	"os"
 	"path"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
@@ -461,29 +460,7 @@ func (c *ShellClient) StartContainer(containerID string) error {
 }
 
 func (c *ShellClient) GetContainerPort(container *Container, boundPort int) (int, error) {
-	functionHostPort := Port(fmt.Sprintf("%d/tcp", boundPort))
-
-	portBindings := container.HostConfig.PortBindings[functionHostPort]
-	ports := container.NetworkSettings.Ports[functionHostPort]
-	if len(portBindings) == 0 && len(ports) == 0 {
-		return 0, nil
-	}
-
-	// by default take the port binding, as if the user requested
-	if len(portBindings) != 0 &&
-		portBindings[0].HostPort != "" && // docker version < 20.10
-		portBindings[0].HostPort != "0" { // on docker version >= 20.10, the host port would by 0 and not empty string.
-		return strconv.Atoi(portBindings[0].HostPort)
-	}
-
-	// port was not explicit by user, take port assigned by docker daemon
-	if len(ports) != 0 && ports[0].HostPort != "" {
-		return strconv.Atoi(ports[0].HostPort)
-	}
-
-	// function might failed during deploying and did not assign a port
-	return 0, nil
-
+	return resolveContainerPort(container, boundPort)
 }
 
 // GetContainerLogs returns raw logs from a given container ID
@@ -512,94 +489,7 @@ func (c *ShellClient) AwaitContainerHealth(containerID string, timeout *time.Dur
 		return errors.New("Invalid container ID to await health for")
 	}
 
-	timedOut := false
-
-	containerHealthy := make(chan error, 1)
-	var timeoutChan <-chan time.Time
-
-	// if no timeout is given, create a channel that we'll never send on
-	if timeout == nil {
-		timeoutChan = make(<-chan time.Time, 1)
-	} else {
-		timeoutChan = time.After(*timeout)
-	}
-
-	go func() {
-
-		// start with a small interval between health checks, increasing it gradually
-		inspectInterval := 100 * time.Millisecond
-
-		for !timedOut {
-			containers, err := c.GetContainers(&GetContainerOptions{
-				ID:      containerID,
-				Stopped: true,
-			})
-			if err == nil && len(containers) > 0 {
-				container := containers[0]
-
-				// container is healthy
-				if container.State.Health.Status == "healthy" {
-					containerHealthy <- nil
-					return
-				}
-
-				// container exited, bail out
-				if container.State.Status == "exited" {
-					containerHealthy <- errors.Errorf("Container exited with status: %d", container.State.ExitCode)
-					return
-				}
-
-				// container is dead, bail out
-				// https://docs.docker.com/engine/reference/commandline/ps/#filtering
-				if container.State.Status == "dead" {
-					containerHealthy <- errors.New("Container seems to be dead")
-					return
-				}
-
-				// wait a bit before retrying
-				c.logger.DebugWith("Container not healthy yet, retrying soon",
-					"timeout", timeout,
-					"containerID", containerID,
-					"containerState", container.State,
-					"nextCheckIn", inspectInterval)
-			}
-
-			time.Sleep(inspectInterval)
-
-			// increase the interval up to a cap
-			if inspectInterval < 800*time.Millisecond {
-				inspectInterval *= 2
-			}
-		}
-	}()
-
-	// wait for either the container to be healthy or the timeout
-	select {
-	case err := <-containerHealthy:
-		if err != nil {
-			return errors.Wrapf(err, "Container %s is not healthy", containerID)
-		}
-		c.logger.DebugWith("Container is healthy", "containerID", containerID)
-	case <-timeoutChan:
-		timedOut = true
-
-		containerLogs, err := c.GetContainerLogs(containerID)
-		if err != nil {
-			c.logger.ErrorWith("Container wasn't healthy within timeout (failed to get logs)",
-				"containerID", containerID,
-				"timeout", timeout,
-				"err", err)
-		} else {
-			c.logger.WarnWith("Container wasn't healthy within timeout",
-				"containerID", containerID,
-				"timeout", timeout,
-				"logs", containerLogs)
-		}
-
-		return errors.New("Container wasn't healthy in time")
-	}
-
-	return nil
+	return awaitContainerHealth(c.logger, c, containerID, timeout)
 }
 
 // GetContainers returns a list of container IDs which match a certain criteria
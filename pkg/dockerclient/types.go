@@ -18,6 +18,10 @@ package dockerclient
 
 import (
 	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
 )
 
 type RestartPolicyName string
@@ -376,3 +380,100 @@ type ContainerLogsOptions struct {
 	Tail       string
 	Details    bool
 }
+
+// apiContainerCreateRequest is the body of the Engine API:
+// POST "/containers/create"
+type apiContainerCreateRequest struct {
+	Image        string
+	Cmd          []string          `json:",omitempty"`
+	Env          []string          `json:",omitempty"`
+	Labels       map[string]string `json:",omitempty"`
+	User         string            `json:",omitempty"`
+	ExposedPorts PortSet           `json:",omitempty"`
+	AttachStdout bool
+	AttachStderr bool
+	HostConfig   apiHostConfig
+}
+
+// apiHostConfig is the part of HostConfig the client sets when creating containers
+type apiHostConfig struct {
+	Binds          []string           `json:",omitempty"`
+	NetworkMode    string             `json:",omitempty"`
+	PortBindings   PortMap            `json:",omitempty"`
+	RestartPolicy  *RestartPolicy     `json:",omitempty"`
+	AutoRemove     bool               `json:",omitempty"`
+	GroupAdd       []string           `json:",omitempty"`
+	Memory         int64              `json:",omitempty"`
+	NanoCPUs       int64              `json:"NanoCpus,omitempty"`
+	Devices        []apiDeviceMapping `json:",omitempty"`
+	DeviceRequests []apiDeviceRequest `json:",omitempty"`
+	Mounts         []apiMount         `json:",omitempty"`
+}
+
+// apiMount represents a mount of a created container
+type apiMount struct {
+	Type     string
+	Source   string
+	Target   string
+	ReadOnly bool
+}
+
+// apiDeviceMapping represents a device mapping between the host and the container
+type apiDeviceMapping struct {
+	PathOnHost        string
+	PathInContainer   string
+	CgroupPermissions string
+}
+
+// apiDeviceRequest represents a request for devices from device drivers (e.g. gpus)
+type apiDeviceRequest struct {
+	Driver       string     `json:",omitempty"`
+	Count        int        `json:",omitempty"`
+	DeviceIDs    []string   `json:",omitempty"`
+	Capabilities [][]string `json:",omitempty"`
+}
+
+// apiAuthConfig holds registry credentials, as passed to the Engine API
+type apiAuthConfig struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	ServerAddress string `json:"serveraddress,omitempty"`
+}
+
+// apiJSONMessage is a single message of the Engine API progress streams (build, pull, push, load)
+type apiJSONMessage struct {
+	Stream      string `json:"stream,omitempty"`
+	Status      string `json:"status,omitempty"`
+	Error       string `json:"error,omitempty"`
+	ErrorDetail *struct {
+		Message string `json:"message,omitempty"`
+	} `json:"errorDetail,omitempty"`
+}
+
+// apiEvent is a single event of the Engine API:
+// GET "/events"
+type apiEvent struct {
+	Type   string
+	Action string
+	Actor  struct {
+		ID         string
+		Attributes map[string]string
+	}
+	TimeNano int64 `json:"timeNano"`
+}
+
+// String formats the event the way the docker cli does
+func (e apiEvent) String() string {
+	var attributes []string
+	for attributeName, attributeValue := range e.Actor.Attributes {
+		attributes = append(attributes, fmt.Sprintf("%s=%s", attributeName, attributeValue))
+	}
+	sort.Strings(attributes)
+
+	return fmt.Sprintf("%s %s %s %s (%s)",
+		time.Unix(0, e.TimeNano).Format(time.RFC3339Nano),
+		e.Type,
+		e.Action,
+		e.Actor.ID,
+		strings.Join(attributes, ", "))
+}
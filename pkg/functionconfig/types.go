@@ -18,6 +18,7 @@ package functionconfig
 
 import (
 	"fmt"
+	"net"
 	"reflect"
 	"strconv"
 	"time"
@@ -35,6 +36,7 @@ import (
 
 const (
 	NvidiaGPUResourceName = "nvidia.com/gpu"
+	DefaultGRPCPort       = 9090
 )
 
 // DataBinding holds configuration for a databinding
@@ -66,23 +68,24 @@ type Volume struct {
 
 // Trigger holds configuration for a trigger
 type Trigger struct {
-	Class                                 string            `json:"class"`
-	Kind                                  string            `json:"kind"`
-	Name                                  string            `json:"name"`
-	Disabled                              bool              `json:"disabled,omitempty"`
-	NumWorkers                            int               `json:"numWorkers,omitempty"`
-	URL                                   string            `json:"url,omitempty"`
-	Paths                                 []string          `json:"paths,omitempty"`
-	Username                              string            `json:"username,omitempty"`
-	Password                              string            `json:"password,omitempty"`
-	Secret                                string            `json:"secret,omitempty"`
-	Partitions                            []Partition       `json:"partitions,omitempty"`
-	Annotations                           map[string]string `json:"annotations,omitempty"`
-	WorkerAvailabilityTimeoutMilliseconds *int              `json:"workerAvailabilityTimeoutMilliseconds,omitempty"`
-	WorkerAllocatorName                   string            `json:"workerAllocatorName,omitempty"`
-	ExplicitAckMode                       ExplicitAckMode   `json:"explicitAckMode,omitempty"`
-	WaitExplicitAckDuringRebalanceTimeout string            `json:"waitExplicitAckDuringRebalanceTimeout,omitempty"`
-	WorkerTerminationTimeout              string            `json:"workerTerminationTimeout,omitempty"`
+	Class                                 string              `json:"class"`
+	Kind                                  string              `json:"kind"`
+	Name                                  string              `json:"name"`
+	Disabled                              bool                `json:"disabled,omitempty"`
+	NumWorkers                            int                 `json:"numWorkers,omitempty"`
+	URL                                   string              `json:"url,omitempty"`
+	Paths                                 []string            `json:"paths,omitempty"`
+	Username                              string              `json:"username,omitempty"`
+	Password                              string              `json:"password,omitempty"`
+	Secret                                string              `json:"secret,omitempty"`
+	Partitions                            []Partition         `json:"partitions,omitempty"`
+	Annotations                           map[string]string   `json:"annotations,omitempty"`
+	WorkerAvailabilityTimeoutMilliseconds *int                `json:"workerAvailabilityTimeoutMilliseconds,omitempty"`
+	WorkerAllocatorName                   string              `json:"workerAllocatorName,omitempty"`
+	ExplicitAckMode                       ExplicitAckMode     `json:"explicitAckMode,omitempty"`
+	WaitExplicitAckDuringRebalanceTimeout string              `json:"waitExplicitAckDuringRebalanceTimeout,omitempty"`
+	WorkerTerminationTimeout              string              `json:"workerTerminationTimeout,omitempty"`
+	Batch                                 *BatchConfiguration `json:"batch,omitempty"`
 
 	// Dealer Information
 	TotalTasks        int `json:"total_tasks,omitempty"`
@@ -130,6 +133,36 @@ func ExplicitAckEnabled(mode ExplicitAckMode) bool {
 		})
 }
 
+type BatchMode string
+
+const (
+
+	// BatchModeEnable accumulates events and delivers them to the runtime as a single batch
+	BatchModeEnable BatchMode = "enable"
+
+	// BatchModeDisable delivers events to the runtime one by one (default)
+	BatchModeDisable BatchMode = "disable"
+
+	DefaultBatchSize    = 10
+	DefaultBatchTimeout = "1s"
+)
+
+// BatchConfiguration holds the configuration of event batching for stream triggers
+type BatchConfiguration struct {
+	Mode BatchMode `json:"mode,omitempty"`
+
+	// BatchSize is the maximal number of events in a single batch
+	BatchSize int `json:"batchSize,omitempty"`
+
+	// Timeout is the maximal time to wait for a batch to fill up before delivering it (e.g. "100ms")
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// BatchModeEnabled returns true if the given batch configuration enables batching
+func BatchModeEnabled(batchConfiguration *BatchConfiguration) bool {
+	return batchConfiguration != nil && batchConfiguration.Mode == BatchModeEnable
+}
+
 // GetTriggersByKind returns a map of triggers by their kind
 func GetTriggersByKind(triggers map[string]Trigger, kind string) map[string]Trigger {
 	matchingTrigger := map[string]Trigger{}
@@ -498,6 +531,33 @@ func (s *Spec) GetHTTPPort() int {
 	return 0
 }
 
+// GetGRPCPort returns the port the function's grpc trigger listens on, or 0 if it has none
+func (s *Spec) GetGRPCPort() int {
+	for _, trigger := range s.Triggers {
+		if trigger.Kind != "grpc" {
+			continue
+		}
+
+		if trigger.URL == "" {
+			return DefaultGRPCPort
+		}
+
+		_, grpcPort, err := net.SplitHostPort(trigger.URL)
+		if err != nil {
+			return 0
+		}
+
+		grpcPortNumber, err := strconv.Atoi(grpcPort)
+		if err != nil {
+			return 0
+		}
+
+		return grpcPortNumber
+	}
+
+	return 0
+}
+
 // GetEventTimeout returns the event timeout as time.Duration
 func (s *Spec) GetEventTimeout() (time.Duration, error) {
 	timeout, err := time.ParseDuration(s.EventTimeout)
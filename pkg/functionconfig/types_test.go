@@ -101,6 +101,46 @@ func (suite *ReaderTestSuite) TestGetInvocationURLs() {
 	suite.Require().Equal([]string{"a", "b", "c", "d"}, functionStatus.InvocationURLs())
 }
 
+func (suite *TypesTestSuite) TestGetGRPCPort() {
+	for _, testCase := range []struct {
+		name         string
+		triggers     map[string]Trigger
+		expectedPort int
+	}{
+		{
+			name: "noGRPCTrigger",
+			triggers: map[string]Trigger{
+				"http": {Kind: "http"},
+			},
+		},
+		{
+			name: "defaultURL",
+			triggers: map[string]Trigger{
+				"grpc": {Kind: "grpc"},
+			},
+			expectedPort: DefaultGRPCPort,
+		},
+		{
+			name: "url",
+			triggers: map[string]Trigger{
+				"grpc": {Kind: "grpc", URL: "0.0.0.0:9191"},
+			},
+			expectedPort: 9191,
+		},
+		{
+			name: "invalidURL",
+			triggers: map[string]Trigger{
+				"grpc": {Kind: "grpc", URL: "0.0.0.0"},
+			},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			spec := Spec{Triggers: testCase.triggers}
+			suite.Require().Equal(testCase.expectedPort, spec.GetGRPCPort())
+		})
+	}
+}
+
 func TestTypesTestSuite(t *testing.T) {
 	suite.Run(t, new(TypesTestSuite))
 }
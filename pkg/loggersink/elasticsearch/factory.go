@@ -0,0 +1,81 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"github.com/nuclio/nuclio/pkg/loggersink"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/zap"
+)
+
+type factory struct{}
+
+func (f *factory) Create(name string,
+	loggerSinkConfiguration *platformconfig.LoggerSinkWithLevel) (logger.Logger, error) {
+
+	configuration, err := NewConfiguration(name, loggerSinkConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create elasticsearch configuration")
+	}
+
+	// the bulk writer receives one JSON encoded log record per write and indexes it in the background
+	writer, err := newBulkWriter(configuration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create bulk writer")
+	}
+
+	// flush the records still buffered when the process exits
+	loggersink.RegistrySingleton.RegisterCloser(writer)
+
+	return f.createLogger(configuration, writer)
+}
+
+func (f *factory) createLogger(configuration *Configuration, writer *bulkWriter) (logger.Logger, error) {
+	var level nucliozap.Level
+
+	switch configuration.Level {
+	case logger.LevelInfo:
+		level = nucliozap.InfoLevel
+	case logger.LevelWarn:
+		level = nucliozap.WarnLevel
+	case logger.LevelError:
+		level = nucliozap.ErrorLevel
+	default:
+		level = nucliozap.DebugLevel
+	}
+
+	encoderConfig := nucliozap.NewEncoderConfig()
+	encoderConfig.JSON.LineEnding = "\n"
+	encoderConfig.JSON.TimeFieldName = "@timestamp"
+	encoderConfig.JSON.TimeFieldEncoding = "iso8601"
+	encoderConfig.JSON.VarGroupMode = nucliozap.VarGroupModeFlattened
+
+	return nucliozap.NewNuclioZap(configuration.Name,
+		"json",
+		encoderConfig,
+		writer,
+		writer,
+		level)
+}
+
+// register factory
+func init() {
+	loggersink.RegistrySingleton.Register(string(platformconfig.LoggerSinkKindElasticsearch), &factory{})
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/loggersink"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+const (
+	DefaultIndex          = "nuclio-logs-{{ .Date }}"
+	DefaultFlushInterval  = "5s"
+	DefaultMaxBatchSize   = 500
+	DefaultMaxBufferSize  = 10000
+	DefaultMaxRetries     = 3
+	DefaultRetryInterval  = "1s"
+	DefaultRequestTimeout = "10s"
+)
+
+type Configuration struct {
+	loggersink.Configuration
+
+	// the elasticsearch URL (e.g. http://elasticsearch:9200). taken from the sink URL if not given
+	URL string
+
+	// a go template of the index to write to. can refer to .FunctionName, .ProjectName and .Date (YYYY.MM.DD)
+	Index string
+
+	// credentials - either username / password or an API key
+	Username string
+	Password string
+	APIKey   string
+
+	// flush at least every FlushInterval, or whenever MaxBatchSize log records were accumulated
+	FlushInterval string
+	MaxBatchSize  int
+
+	// the maximum number of log records waiting to be flushed. records logged while the buffer is full
+	// are dropped, so that a slow / unavailable elasticsearch never blocks the logging goroutine
+	MaxBufferSize int
+
+	// how many times to retry a failed bulk request (DefaultMaxRetries when not given, 0 to never retry),
+	// and how long to wait between retries
+	MaxRetries     *int
+	RetryInterval  string
+	RequestTimeout string
+
+	// identify the source of the logs
+	FunctionName string
+	ProjectName  string
+	Replica      string
+
+	parsedIndex          *template.Template
+	parsedFlushInterval  time.Duration
+	parsedRetryInterval  time.Duration
+	parsedRequestTimeout time.Duration
+}
+
+func NewConfiguration(name string, loggerSinkConfiguration *platformconfig.LoggerSinkWithLevel) (*Configuration, error) {
+	var err error
+	newConfiguration := Configuration{}
+
+	// create base
+	newConfiguration.Configuration = *loggersink.NewConfiguration(name, loggerSinkConfiguration)
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Attributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	if newConfiguration.URL == "" {
+		newConfiguration.URL = loggerSinkConfiguration.Sink.URL
+	}
+
+	if newConfiguration.URL == "" {
+		return nil, errors.New("URL is required for Elasticsearch logger sink")
+	}
+
+	if newConfiguration.Index == "" {
+		newConfiguration.Index = DefaultIndex
+	}
+
+	newConfiguration.parsedIndex, err = template.New("index").Parse(newConfiguration.Index)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse index template")
+	}
+
+	if newConfiguration.MaxBatchSize == 0 {
+		newConfiguration.MaxBatchSize = DefaultMaxBatchSize
+	}
+
+	if newConfiguration.MaxBufferSize == 0 {
+		newConfiguration.MaxBufferSize = DefaultMaxBufferSize
+	}
+
+	if newConfiguration.MaxRetries == nil {
+		defaultMaxRetries := DefaultMaxRetries
+		newConfiguration.MaxRetries = &defaultMaxRetries
+	}
+
+	if *newConfiguration.MaxRetries < 0 {
+		return nil, errors.Errorf("Max retries must not be negative: %d", *newConfiguration.MaxRetries)
+	}
+
+	for _, durationField := range []struct {
+		name         string
+		value        *string
+		defaultValue string
+		parsed       *time.Duration
+	}{
+		{"flush interval", &newConfiguration.FlushInterval, DefaultFlushInterval, &newConfiguration.parsedFlushInterval},
+		{"retry interval", &newConfiguration.RetryInterval, DefaultRetryInterval, &newConfiguration.parsedRetryInterval},
+		{"request timeout", &newConfiguration.RequestTimeout, DefaultRequestTimeout, &newConfiguration.parsedRequestTimeout},
+	} {
+		if *durationField.value == "" {
+			*durationField.value = durationField.defaultValue
+		}
+
+		*durationField.parsed, err = time.ParseDuration(*durationField.value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse %s", durationField.name)
+		}
+	}
+
+	// take the function name and project from the function, if we're logging for one
+	if functionMeta := loggerSinkConfiguration.GetFunctionMeta(); functionMeta != nil {
+		if newConfiguration.FunctionName == "" {
+			newConfiguration.FunctionName = functionMeta.Name
+		}
+
+		if newConfiguration.ProjectName == "" {
+			newConfiguration.ProjectName = functionMeta.Labels[common.NuclioResourceLabelKeyProjectName]
+		}
+	}
+
+	if newConfiguration.FunctionName == "" {
+		newConfiguration.FunctionName = os.Getenv("NUCLIO_FUNCTION_NAME")
+	}
+
+	if newConfiguration.Replica == "" {
+		newConfiguration.Replica = os.Getenv("NUCLIO_FUNCTION_INSTANCE")
+	}
+
+	if newConfiguration.Replica == "" {
+		newConfiguration.Replica, _ = os.Hostname()
+	}
+
+	return &newConfiguration, nil
+}
@@ -0,0 +1,356 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nuclio/errors"
+)
+
+// bulkWriter receives JSON encoded log records from the logger and indexes them into elasticsearch
+// in batches, using the bulk API. writing never blocks - if the buffer is full, records are dropped
+type bulkWriter struct {
+
+	// accessed atomically, keep as first field for alignment
+	droppedRecords uint64
+
+	configuration    *Configuration
+	httpClient       *http.Client
+	bulkURL          string
+	records          chan []byte
+	flushRequests    chan chan struct{}
+	stop             chan struct{}
+	stopped          chan struct{}
+	stopOnce         sync.Once
+	errorWriter      io.Writer
+	reportedDropped  uint64
+	staticRecordKeys map[string]string
+}
+
+type bulkResponse struct {
+	Errors bool                                `json:"errors"`
+	Items  []map[string]bulkResponseItemResult `json:"items"`
+}
+
+type bulkResponseItemResult struct {
+	Status int             `json:"status"`
+	Error  json.RawMessage `json:"error,omitempty"`
+}
+
+type indexTemplateParameters struct {
+	FunctionName string
+	ProjectName  string
+	Date         string
+}
+
+func newBulkWriter(configuration *Configuration) (*bulkWriter, error) {
+	newBulkWriter := &bulkWriter{
+		configuration: configuration,
+		httpClient: &http.Client{
+			Timeout: configuration.parsedRequestTimeout,
+		},
+		bulkURL:       strings.TrimSuffix(configuration.URL, "/") + "/_bulk",
+		records:       make(chan []byte, configuration.MaxBufferSize),
+		flushRequests: make(chan chan struct{}),
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+		errorWriter:   os.Stderr,
+		staticRecordKeys: map[string]string{
+			"function": configuration.FunctionName,
+			"project":  configuration.ProjectName,
+			"replica":  configuration.Replica,
+		},
+	}
+
+	go newBulkWriter.flushPeriodically()
+
+	return newBulkWriter, nil
+}
+
+// Write enqueues a single log record. the logger may reuse the buffer, so it is copied
+func (bw *bulkWriter) Write(record []byte) (int, error) {
+	recordCopy := make([]byte, len(record))
+	copy(recordCopy, record)
+
+	select {
+	case bw.records <- recordCopy:
+	default:
+		atomic.AddUint64(&bw.droppedRecords, 1)
+	}
+
+	return len(record), nil
+}
+
+// Flush indexes all the records written so far and waits for it to complete
+func (bw *bulkWriter) Flush() {
+	flushDone := make(chan struct{})
+
+	select {
+	case bw.flushRequests <- flushDone:
+		<-flushDone
+
+	// closed - everything written was already flushed
+	case <-bw.stopped:
+	}
+}
+
+// Close indexes all the records written so far and stops flushing. records written after Close are not indexed
+func (bw *bulkWriter) Close() error {
+	bw.stopOnce.Do(func() {
+		close(bw.stop)
+	})
+
+	<-bw.stopped
+
+	return nil
+}
+
+func (bw *bulkWriter) flushPeriodically() {
+	batch := make([][]byte, 0, bw.configuration.MaxBatchSize)
+	flushTicker := time.NewTicker(bw.configuration.parsedFlushInterval)
+	defer flushTicker.Stop()
+
+	for {
+		select {
+		case record := <-bw.records:
+			batch = append(batch, record)
+
+			if len(batch) >= bw.configuration.MaxBatchSize {
+				batch = bw.flush(batch)
+			}
+
+		case <-flushTicker.C:
+			batch = bw.flush(batch)
+
+		case flushDone := <-bw.flushRequests:
+			batch = bw.flush(bw.drain(batch))
+			close(flushDone)
+
+		case <-bw.stop:
+			bw.flush(bw.drain(batch))
+			close(bw.stopped)
+			return
+		}
+	}
+}
+
+// drain adds whatever is already buffered to the batch, flushing full batches along the way
+func (bw *bulkWriter) drain(batch [][]byte) [][]byte {
+	for {
+		select {
+		case record := <-bw.records:
+			batch = append(batch, record)
+			if len(batch) >= bw.configuration.MaxBatchSize {
+				batch = bw.flush(batch)
+			}
+		default:
+			return batch
+		}
+	}
+}
+
+// flush indexes the batch and returns an empty batch to continue accumulating into
+func (bw *bulkWriter) flush(batch [][]byte) [][]byte {
+	bw.reportDroppedRecords()
+
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := bw.indexRecords(batch); err != nil {
+		bw.reportError(errors.Wrapf(err, "Failed to index %d log records", len(batch)))
+	}
+
+	return batch[:0]
+}
+
+func (bw *bulkWriter) indexRecords(records [][]byte) error {
+	index, err := bw.resolveIndex()
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve index")
+	}
+
+	// the action line is the same for all records
+	actionLine, err := json.Marshal(map[string]interface{}{
+		"index": map[string]string{
+			"_index": index,
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to encode bulk action")
+	}
+
+	documents := make([][]byte, 0, len(records))
+	for _, record := range records {
+		documents = append(documents, bw.recordToDocument(record))
+	}
+
+	for attempt := 0; ; attempt++ {
+		rejectedDocuments, err := bw.sendBulkRequest(actionLine, documents)
+		if err == nil && len(rejectedDocuments) == 0 {
+			return nil
+		}
+
+		if attempt >= *bw.configuration.MaxRetries {
+			if err != nil {
+				return errors.Wrapf(err, "Failed to send bulk request after %d retries", attempt)
+			}
+
+			return errors.Errorf("%d documents were rejected after %d retries", len(rejectedDocuments), attempt)
+		}
+
+		// if only some of the documents were rejected, retry just them
+		if err == nil {
+			documents = rejectedDocuments
+		}
+
+		time.Sleep(bw.configuration.parsedRetryInterval * time.Duration(attempt+1))
+	}
+}
+
+// sendBulkRequest sends the documents in a single bulk request. returns the documents that were rejected
+// due to back-pressure from elasticsearch and should be retried
+func (bw *bulkWriter) sendBulkRequest(actionLine []byte, documents [][]byte) ([][]byte, error) {
+	body := bytes.Buffer{}
+	for _, document := range documents {
+		body.Write(actionLine)
+		body.WriteByte('\n')
+		body.Write(document)
+		body.WriteByte('\n')
+	}
+
+	request, err := http.NewRequest(http.MethodPost, bw.bulkURL, &body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create request")
+	}
+
+	request.Header.Set("Content-Type", "application/x-ndjson")
+
+	switch {
+	case bw.configuration.APIKey != "":
+		request.Header.Set("Authorization", "ApiKey "+bw.configuration.APIKey)
+	case bw.configuration.Username != "":
+		request.SetBasicAuth(bw.configuration.Username, bw.configuration.Password)
+	}
+
+	response, err := bw.httpClient.Do(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to send request")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read response body")
+	}
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return nil, errors.Errorf("Got unexpected status code %d: %s", response.StatusCode, string(responseBody))
+	}
+
+	parsedResponse := bulkResponse{}
+	if err := json.Unmarshal(responseBody, &parsedResponse); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode response body")
+	}
+
+	if !parsedResponse.Errors {
+		return nil, nil
+	}
+
+	// items are returned in the same order as the documents
+	var rejectedDocuments [][]byte
+	for itemIndex, item := range parsedResponse.Items {
+		for _, itemResult := range item {
+			switch {
+			case itemResult.Status == http.StatusTooManyRequests && itemIndex < len(documents):
+				rejectedDocuments = append(rejectedDocuments, documents[itemIndex])
+			case itemResult.Status >= http.StatusBadRequest:
+				bw.reportError(errors.Errorf("Failed to index log record (status %d): %s",
+					itemResult.Status,
+					string(itemResult.Error)))
+			}
+		}
+	}
+
+	return rejectedDocuments, nil
+}
+
+// recordToDocument adds the static identifiers to the record
+func (bw *bulkWriter) recordToDocument(record []byte) []byte {
+	document := map[string]interface{}{}
+
+	// if, for some reason, the record is not a JSON object, index it as a message
+	if err := json.Unmarshal(record, &document); err != nil {
+		document = map[string]interface{}{
+			"message": strings.TrimSpace(string(record)),
+		}
+	}
+
+	for key, value := range bw.staticRecordKeys {
+		if _, keyExists := document[key]; !keyExists && value != "" {
+			document[key] = value
+		}
+	}
+
+	encodedDocument, err := json.Marshal(document)
+	if err != nil {
+		return bytes.TrimSpace(record)
+	}
+
+	return encodedDocument
+}
+
+func (bw *bulkWriter) resolveIndex() (string, error) {
+	index := strings.Builder{}
+
+	if err := bw.configuration.parsedIndex.Execute(&index, &indexTemplateParameters{
+		FunctionName: bw.configuration.FunctionName,
+		ProjectName:  bw.configuration.ProjectName,
+		Date:         time.Now().UTC().Format("2006.01.02"),
+	}); err != nil {
+		return "", err
+	}
+
+	return strings.ToLower(index.String()), nil
+}
+
+func (bw *bulkWriter) reportDroppedRecords() {
+	droppedRecords := atomic.LoadUint64(&bw.droppedRecords)
+	if droppedRecords == bw.reportedDropped {
+		return
+	}
+
+	bw.reportError(errors.Errorf("Dropped %d log records since the buffer was full",
+		droppedRecords-bw.reportedDropped))
+	bw.reportedDropped = droppedRecords
+}
+
+// reportError writes the error to stderr, as there's no logger to log to (we are the logger)
+func (bw *bulkWriter) reportError(err error) {
+	fmt.Fprintf(bw.errorWriter, "elasticsearch logger sink: %s\n", errors.GetErrorStackString(err, 10)) // nolint: errcheck
+}
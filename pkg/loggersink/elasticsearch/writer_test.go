@@ -0,0 +1,292 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package elasticsearch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/nuclio/logger"
+	"github.com/stretchr/testify/suite"
+)
+
+type bulkWriterTestSuite struct {
+	suite.Suite
+	server        *httptest.Server
+	lock          sync.Mutex
+	bulkRequests  []map[string][]map[string]interface{}
+	responseFuncs []func(http.ResponseWriter, [][]byte)
+}
+
+func (suite *bulkWriterTestSuite) SetupTest() {
+	suite.bulkRequests = nil
+	suite.responseFuncs = nil
+	suite.server = httptest.NewServer(http.HandlerFunc(suite.handleBulkRequest))
+}
+
+func (suite *bulkWriterTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func (suite *bulkWriterTestSuite) TestIndexFunctionLogs() {
+	loggerInstance, writer := suite.createLogger(map[string]interface{}{
+		"index":         "logs-{{ .ProjectName }}-{{ .FunctionName }}",
+		"flushInterval": "1h",
+	})
+
+	ctx := context.WithValue(context.Background(), "RequestID", "some-request-id")
+	loggerInstance.InfoWith("First message", "key", "value")
+	loggerInstance.WarnWithCtx(ctx, "Second message")
+	writer.Flush()
+
+	suite.Require().Len(suite.bulkRequests, 1)
+	suite.Require().Contains(suite.bulkRequests[0], "logs-my-project-my-function")
+
+	documents := suite.bulkRequests[0]["logs-my-project-my-function"]
+	suite.Require().Len(documents, 2)
+
+	for _, document := range documents {
+		suite.Require().Equal("my-function", document["function"])
+		suite.Require().Equal("my-project", document["project"])
+		suite.Require().Equal("my-replica", document["replica"])
+		suite.Require().Contains(document, "@timestamp")
+	}
+
+	suite.Require().Equal("First message", documents[0]["message"])
+	suite.Require().Equal("value", documents[0]["key"])
+	suite.Require().Equal("Second message", documents[1]["message"])
+	suite.Require().Equal("some-request-id", documents[1]["requestID"])
+}
+
+func (suite *bulkWriterTestSuite) TestFlushOnMaxBatchSize() {
+	loggerInstance, writer := suite.createLogger(map[string]interface{}{
+		"flushInterval": "1h",
+		"maxBatchSize":  2,
+	})
+
+	for i := 0; i < 5; i++ {
+		loggerInstance.InfoWith("Message", "index", i)
+	}
+	writer.Flush()
+
+	// two full batches, and the remainder on flush
+	suite.Require().Len(suite.bulkRequests, 3)
+}
+
+func (suite *bulkWriterTestSuite) TestRetryRejectedDocuments() {
+
+	// first reject the second document, then accept everything
+	suite.responseFuncs = append(suite.responseFuncs, func(responseWriter http.ResponseWriter, lines [][]byte) {
+		responseWriter.Write([]byte(`{"errors": true, "items": [` + // nolint: errcheck
+			`{"index": {"status": 201}},` +
+			`{"index": {"status": 429, "error": {"type": "es_rejected_execution_exception"}}}]}`))
+	})
+
+	loggerInstance, writer := suite.createLogger(map[string]interface{}{
+		"flushInterval": "1h",
+		"retryInterval": "1ms",
+	})
+
+	loggerInstance.Info("Accepted")
+	loggerInstance.Info("Rejected")
+	writer.Flush()
+
+	suite.Require().Len(suite.bulkRequests, 2)
+
+	var retriedMessages []interface{}
+	for _, documents := range suite.bulkRequests[1] {
+		for _, document := range documents {
+			retriedMessages = append(retriedMessages, document["message"])
+		}
+	}
+	suite.Require().Equal([]interface{}{"Rejected"}, retriedMessages)
+}
+
+func (suite *bulkWriterTestSuite) TestMaxRetries() {
+	configuration := suite.createConfiguration(map[string]interface{}{})
+	suite.Require().Equal(DefaultMaxRetries, *configuration.MaxRetries)
+
+	for i := 0; i < 2; i++ {
+		suite.responseFuncs = append(suite.responseFuncs, func(responseWriter http.ResponseWriter, lines [][]byte) {
+			responseWriter.WriteHeader(http.StatusInternalServerError)
+		})
+	}
+
+	// zero means no retries, rather than the default
+	loggerInstance, writer := suite.createLogger(map[string]interface{}{
+		"flushInterval": "1h",
+		"retryInterval": "1ms",
+		"maxRetries":    0,
+	})
+	writer.errorWriter = io.Discard
+
+	loggerInstance.Info("Failed")
+	writer.Flush()
+
+	suite.Require().Len(suite.bulkRequests, 1)
+
+	// negative retries are invalid
+	_, err := NewConfiguration("processor", suite.createLoggerSinkConfiguration(map[string]interface{}{
+		"maxRetries": -1,
+	}))
+	suite.Require().Error(err)
+}
+
+func (suite *bulkWriterTestSuite) TestClose() {
+	loggerInstance, writer := suite.createLogger(map[string]interface{}{
+		"flushInterval": "1h",
+	})
+
+	loggerInstance.Info("Last message")
+
+	// the records written before closing are indexed
+	suite.Require().NoError(writer.Close())
+	suite.Require().Len(suite.bulkRequests, 1)
+
+	// closing again and flushing after closing return immediately
+	suite.Require().NoError(writer.Close())
+	writer.Flush()
+	suite.Require().Len(suite.bulkRequests, 1)
+}
+
+func (suite *bulkWriterTestSuite) TestDropWhenBufferIsFull() {
+	configuration := suite.createConfiguration(map[string]interface{}{
+		"flushInterval": "1h",
+		"maxBufferSize": 1,
+	})
+
+	// create the writer without its flushing goroutine, so that nothing is consumed
+	writer := &bulkWriter{
+		configuration: configuration,
+		records:       make(chan []byte, configuration.MaxBufferSize),
+	}
+
+	for i := 0; i < 3; i++ {
+		written, err := writer.Write([]byte(`{"message": "hello"}`))
+		suite.Require().NoError(err)
+		suite.Require().Equal(20, written)
+	}
+
+	suite.Require().Len(writer.records, 1)
+	suite.Require().Equal(uint64(2), writer.droppedRecords)
+}
+
+func (suite *bulkWriterTestSuite) createConfiguration(attributes map[string]interface{}) *Configuration {
+	configuration, err := NewConfiguration("processor", suite.createLoggerSinkConfiguration(attributes))
+	suite.Require().NoError(err)
+
+	return configuration
+}
+
+func (suite *bulkWriterTestSuite) createLoggerSinkConfiguration(
+	attributes map[string]interface{}) *platformconfig.LoggerSinkWithLevel {
+	functionConfig := functionconfig.NewConfig()
+	functionConfig.Meta.Name = "my-function"
+	functionConfig.Meta.Labels = map[string]string{
+		common.NuclioResourceLabelKeyProjectName: "my-project",
+	}
+
+	attributes["replica"] = "my-replica"
+
+	platformConfiguration := platformconfig.Config{
+		Logger: platformconfig.Logger{
+			Sinks: map[string]platformconfig.LoggerSink{
+				"es": {
+					Kind:       platformconfig.LoggerSinkKindElasticsearch,
+					URL:        suite.server.URL,
+					Attributes: attributes,
+				},
+			},
+			Functions: []platformconfig.LoggerSinkBinding{
+				{Level: "debug", Sink: "es"},
+			},
+		},
+	}
+
+	loggerSinks, err := platformConfiguration.GetFunctionLoggerSinks(functionConfig)
+	suite.Require().NoError(err)
+
+	loggerSinkConfiguration := loggerSinks["es"]
+	return &loggerSinkConfiguration
+}
+
+func (suite *bulkWriterTestSuite) createLogger(attributes map[string]interface{}) (logger.Logger, *bulkWriter) {
+	configuration := suite.createConfiguration(attributes)
+
+	writer, err := newBulkWriter(configuration)
+	suite.Require().NoError(err)
+
+	loggerInstance, err := (&factory{}).createLogger(configuration, writer)
+	suite.Require().NoError(err)
+
+	return loggerInstance, writer
+}
+
+func (suite *bulkWriterTestSuite) handleBulkRequest(responseWriter http.ResponseWriter, request *http.Request) {
+	suite.lock.Lock()
+	defer suite.lock.Unlock()
+
+	suite.Require().Equal("/_bulk", request.URL.Path)
+	suite.Require().Equal("application/x-ndjson", request.Header.Get("Content-Type"))
+
+	var lines [][]byte
+	scanner := bufio.NewScanner(request.Body)
+	for scanner.Scan() {
+		lines = append(lines, bytes.Clone(scanner.Bytes()))
+	}
+
+	// lines alternate between an action and a document
+	documentsByIndex := map[string][]map[string]interface{}{}
+	for lineIndex := 0; lineIndex+1 < len(lines); lineIndex += 2 {
+		action := map[string]map[string]string{}
+		suite.Require().NoError(json.Unmarshal(lines[lineIndex], &action))
+
+		document := map[string]interface{}{}
+		suite.Require().NoError(json.Unmarshal(lines[lineIndex+1], &document))
+
+		index := action["index"]["_index"]
+		documentsByIndex[index] = append(documentsByIndex[index], document)
+	}
+
+	suite.bulkRequests = append(suite.bulkRequests, documentsByIndex)
+
+	if len(suite.responseFuncs) > 0 {
+		responseFunc := suite.responseFuncs[0]
+		suite.responseFuncs = suite.responseFuncs[1:]
+		responseFunc(responseWriter, lines)
+		return
+	}
+
+	responseWriter.Write([]byte(`{"errors": false, "items": []}`)) // nolint: errcheck
+}
+
+func TestBulkWriterTestSuite(t *testing.T) {
+	suite.Run(t, new(bulkWriterTestSuite))
+}
@@ -17,9 +17,13 @@ limitations under the License.
 package loggersink
 
 import (
+	"io"
+	"sync"
+
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/registry"
 
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 )
 
@@ -32,6 +36,10 @@ type Creator interface {
 
 type Registry struct {
 	registry.Registry
+
+	// resources of created logger sinks (e.g. buffering writers) to close before exiting
+	closersLock sync.Mutex
+	closers     []io.Closer
 }
 
 // RegistrySingleton is a global singleton
@@ -51,3 +59,32 @@ func (r *Registry) NewLoggerSink(kind platformconfig.LoggerSinkKind,
 
 	return registree.(Creator).Create(name, loggerSinkConfiguration)
 }
+
+// RegisterCloser registers a resource of a created logger sink to be closed by Close
+func (r *Registry) RegisterCloser(closer io.Closer) {
+	r.closersLock.Lock()
+	defer r.closersLock.Unlock()
+
+	r.closers = append(r.closers, closer)
+}
+
+// Close closes the resources of the created logger sinks, so that records they buffer are not lost on exit
+func (r *Registry) Close() error {
+	r.closersLock.Lock()
+	closers := r.closers
+	r.closers = nil
+	r.closersLock.Unlock()
+
+	var closeErrors []error
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			closeErrors = append(closeErrors, err)
+		}
+	}
+
+	if len(closeErrors) > 0 {
+		return errors.Errorf("Failed to close %d logger sink resources: %v", len(closeErrors), closeErrors)
+	}
+
+	return nil
+}
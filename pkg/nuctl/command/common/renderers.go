@@ -261,12 +261,16 @@ func RenderAPIGateways(apiGateways []platform.APIGateway,
 			// primary function
 			primaryFunction := apiGateway.GetConfig().Spec.Upstreams[0].NuclioFunction.Name
 
-			// get canaryFunction if it exists
-			canaryFunction := ""
-			canaryPercentage := 0
-			if len(apiGateway.GetConfig().Spec.Upstreams) == 2 {
-				canaryFunction = apiGateway.GetConfig().Spec.Upstreams[1].NuclioFunction.Name
-				canaryPercentage = apiGateway.GetConfig().Spec.Upstreams[1].Percentage
+			// get canary functions if they exist
+			var canaryFunctions []string
+			var canaryPercentages []string
+			primaryUpstream, canaryUpstreams, err := apiGateway.GetConfig().Spec.ResolvePrimaryAndCanaryUpstreams()
+			if err == nil {
+				primaryFunction = primaryUpstream.NuclioFunction.Name
+				for _, canaryUpstream := range canaryUpstreams {
+					canaryFunctions = append(canaryFunctions, canaryUpstream.NuclioFunction.Name)
+					canaryPercentages = append(canaryPercentages, fmt.Sprint(canaryUpstream.Percentage))
+				}
 			}
 
 			// get its fields
@@ -276,8 +280,8 @@ func RenderAPIGateways(apiGateways []platform.APIGateway,
 				apiGateway.GetConfig().Spec.Host,
 				apiGateway.GetConfig().Spec.Path,
 				primaryFunction,
-				canaryFunction,
-				fmt.Sprint(canaryPercentage),
+				strings.Join(canaryFunctions, ","),
+				strings.Join(canaryPercentages, ","),
 			}
 
 			// add fields for wide view
@@ -368,20 +368,17 @@ func (e *exportProjectCommandeer) exportProject(ctx context.Context, projectConf
 		"functionEvents": functionEvents,
 	}
 
-	// api gateways are supported only on k8s platform
-	if e.rootCommandeer.platform.GetName() == common.KubePlatformName {
-		apiGateways, err := e.exportAPIGateways(ctx, projectConfig)
-		if err != nil {
-
-			// in case an error occurred while exporting api gateways - skip this part
-			// (because it may fail when exporting after an upgrade from an older version)
-			e.rootCommandeer.loggerInstance.DebugWith("Failed to export api gateways; continuing with project export",
-				"err", err)
-		}
+	apiGateways, err := e.exportAPIGateways(ctx, projectConfig)
+	if err != nil {
 
-		exportedProject["apiGateways"] = apiGateways
+		// in case an error occurred while exporting api gateways - skip this part
+		// (because it may fail when exporting after an upgrade from an older version)
+		e.rootCommandeer.loggerInstance.DebugWith("Failed to export api gateways; continuing with project export",
+			"err", err)
 	}
 
+	exportedProject["apiGateways"] = apiGateways
+
 	return exportedProject, nil
 }
 
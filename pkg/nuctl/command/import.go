@@ -479,19 +479,15 @@ func (i *importProjectCommandeer) importProject(ctx context.Context,
 		}
 	}
 
-	// api gateways are supported only on k8s platform
-	if i.rootCommandeer.platform.GetName() == common.KubePlatformName {
-
-		// import api gateways
-		apiGatewaysImportErr := i.importAPIGateways(ctx, projectImportOptions.projectImportConfig.APIGateways)
-		if apiGatewaysImportErr != nil {
-			i.rootCommandeer.loggerInstance.WarnWithCtx(ctx, "Unable to import all api gateways",
-				"apiGatewaysImportErr", apiGatewaysImportErr)
-
-			// return this err only if not previously set
-			if err == nil {
-				err = apiGatewaysImportErr
-			}
+	// import api gateways
+	apiGatewaysImportErr := i.importAPIGateways(ctx, projectImportOptions.projectImportConfig.APIGateways)
+	if apiGatewaysImportErr != nil {
+		i.rootCommandeer.loggerInstance.WarnWithCtx(ctx, "Unable to import all api gateways",
+			"apiGatewaysImportErr", apiGatewaysImportErr)
+
+		// return this err only if not previously set
+		if err == nil {
+			err = apiGatewaysImportErr
 		}
 	}
 
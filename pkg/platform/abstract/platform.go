@@ -312,8 +312,8 @@ func (ap *Platform) EnrichLabels(ctx context.Context, labels map[string]string)
 }
 
 func (ap *Platform) enrichUsernameAndDomainLabels(ctx context.Context, labels map[string]string) {
-	// enrich labels with iguazio.com/username of the creating user
-	if authSession, ok := ctx.Value(auth.AuthSessionContextKey).(*auth.IguazioSession); ok {
+	// enrich labels with iguazio.com/username of the creating user, whichever auth kind authenticated them
+	if authSession, ok := ctx.Value(auth.AuthSessionContextKey).(auth.Session); ok && authSession.GetUsername() != "" {
 		if value, exist := labels[iguazio.IguazioUsernameLabel]; !exist || value == "" {
 			fullUsername := authSession.GetUsername()
 
@@ -1797,7 +1797,7 @@ func (ap *Platform) enrichExplicitAckParams(ctx context.Context, functionConfig
 
 	// explicit ack is relevant for stream triggers
 	for triggerName, triggerInstance := range functionconfig.GetTriggersByKinds(functionConfig.Spec.Triggers,
-		[]string{"kafka", "kafka-cluster", "v3ioStream"}) {
+		[]string{"kafka", "kafka-cluster", "v3ioStream", "nats"}) {
 		ap.Logger.DebugWithCtx(ctx, "Enriching explicit ack params",
 			"functionName", functionConfig.Meta.Name)
 
@@ -18,45 +18,378 @@ package mlrun
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
 	"time"
 
+	"github.com/nuclio/nuclio/pkg/auth"
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/platform/abstract/project/external/leader/iguazio"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
 )
 
+const (
+	DefaultRequestTimeout = 60 * time.Second
+)
+
+// Client delegates project operations to mlrun's projects API. the leader's api address is expected to point
+// at mlrun's versioned api (e.g.: http://mlrun-api:8080/api/v1)
 type Client struct {
 	logger                logger.Logger
 	platformConfiguration *platformconfig.Config
+	httpClient            *http.Client
+	namespace             string
 }
 
 func NewClient(parentLogger logger.Logger, platformConfiguration *platformconfig.Config) (*Client, error) {
 	newClient := Client{
 		logger:                parentLogger.GetChild("leader-client-mlrun"),
 		platformConfiguration: platformConfiguration,
+		httpClient: &http.Client{
+			Timeout: DefaultRequestTimeout,
+		},
+	}
+
+	// mlrun projects are not namespaced, synchronize them into the first managed namespace
+	if len(platformConfiguration.ManagedNamespaces) > 0 {
+		newClient.namespace = platformConfiguration.ManagedNamespaces[0]
+	} else {
+		newClient.namespace = common.ResolveDefaultNamespace("@nuclio.selfNamespace")
 	}
 
 	return &newClient, nil
 }
 
 func (c *Client) Get(ctx context.Context, getProjectOptions *platform.GetProjectsOptions) ([]platform.Project, error) {
-	return nil, nuclio.ErrNotImplemented
+	c.logger.DebugWithCtx(ctx,
+		"Fetching projects from leader",
+		"getProjectOptionsMeta", getProjectOptions.Meta)
+
+	getSingleProject := getProjectOptions.Meta.Name != ""
+
+	requestURL := c.generateProjectsURL()
+	if getSingleProject {
+		requestURL = c.generateProjectURL(getProjectOptions.Meta.Name)
+	} else {
+		requestURL += "?format=full"
+	}
+
+	responseBody, err := c.sendRequest(ctx,
+		http.MethodGet,
+		requestURL,
+		nil,
+		nil,
+		getProjectOptions.AuthSession,
+		getProjectOptions.SessionCookie,
+		http.StatusOK)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get projects from leader")
+	}
+
+	return c.resolveGetProjectResponse(getSingleProject, responseBody)
 }
 
 func (c *Client) Create(ctx context.Context, createProjectOptions *platform.CreateProjectOptions) error {
-	return nuclio.ErrNotImplemented
+	c.logger.DebugWithCtx(ctx,
+		"Sending create project request to leader",
+		"name", createProjectOptions.ProjectConfig.Meta.Name,
+		"namespace", createProjectOptions.ProjectConfig.Meta.Namespace)
+
+	// generate request body
+	body, err := c.generateProjectRequestBody(createProjectOptions.ProjectConfig)
+	if err != nil {
+		return errors.Wrap(err, "Failed to generate project request body")
+	}
+
+	// mlrun either creates the project synchronously, or accepts the request and creates it in the background
+	if _, err := c.sendRequest(ctx,
+		http.MethodPost,
+		c.generateProjectsURL(),
+		body,
+		nil,
+		createProjectOptions.AuthSession,
+		createProjectOptions.SessionCookie,
+		http.StatusOK,
+		http.StatusCreated,
+		http.StatusAccepted); err != nil {
+		return errors.Wrap(err, "Failed to create project on leader")
+	}
+
+	c.logger.DebugWithCtx(ctx,
+		"Successfully sent create project request to leader",
+		"name", createProjectOptions.ProjectConfig.Meta.Name)
+
+	if createProjectOptions.WaitForCreateCompletion {
+		if err := c.waitForProjectOnline(ctx, createProjectOptions); err != nil {
+			return errors.Wrap(err, "Failed waiting for project creation completion")
+		}
+
+		c.logger.DebugWithCtx(ctx,
+			"Successfully created project",
+			"name", createProjectOptions.ProjectConfig.Meta.Name)
+	}
+
+	return nil
 }
 
 func (c *Client) Update(ctx context.Context, updateProjectOptions *platform.UpdateProjectOptions) error {
-	return nuclio.ErrNotImplemented
+	c.logger.DebugWithCtx(ctx,
+		"Sending update project request to leader",
+		"name", updateProjectOptions.ProjectConfig.Meta.Name,
+		"namespace", updateProjectOptions.ProjectConfig.Meta.Namespace)
+
+	// generate request body
+	body, err := c.generateProjectRequestBody(&updateProjectOptions.ProjectConfig)
+	if err != nil {
+		return errors.Wrap(err, "Failed to generate project request body")
+	}
+
+	if _, err := c.sendRequest(ctx,
+		http.MethodPut,
+		c.generateProjectURL(updateProjectOptions.ProjectConfig.Meta.Name),
+		body,
+		nil,
+		updateProjectOptions.AuthSession,
+		updateProjectOptions.SessionCookie,
+		http.StatusOK); err != nil {
+		return errors.Wrap(err, "Failed to update project on leader")
+	}
+
+	c.logger.DebugWithCtx(ctx,
+		"Successfully sent update project request to leader",
+		"name", updateProjectOptions.ProjectConfig.Meta.Name,
+		"namespace", updateProjectOptions.ProjectConfig.Meta.Namespace)
+
+	return nil
 }
 
 func (c *Client) Delete(ctx context.Context, deleteProjectOptions *platform.DeleteProjectOptions) error {
-	return nuclio.ErrNotImplemented
+	c.logger.DebugWithCtx(ctx,
+		"Sending delete project request to leader",
+		"name", deleteProjectOptions.Meta.Name,
+		"strategy", deleteProjectOptions.Strategy)
+
+	headers := map[string]string{
+		DeletionStrategyHeaderKey: string(platform.ResolveProjectDeletionStrategyOrDefault(
+			string(deleteProjectOptions.Strategy))),
+	}
+
+	// mlrun either deletes the project synchronously, or accepts the request and deletes it in the background
+	if _, err := c.sendRequest(ctx,
+		http.MethodDelete,
+		c.generateProjectURL(deleteProjectOptions.Meta.Name),
+		nil,
+		headers,
+		deleteProjectOptions.AuthSession,
+		deleteProjectOptions.SessionCookie,
+		http.StatusNoContent,
+		http.StatusAccepted); err != nil {
+		return errors.Wrap(err, "Failed to delete project on leader")
+	}
+
+	c.logger.DebugWithCtx(ctx,
+		"Successfully sent delete project request to leader",
+		"name", deleteProjectOptions.Meta.Name,
+		"namespace", deleteProjectOptions.Meta.Namespace)
+
+	return nil
 }
 
 func (c *Client) GetUpdatedAfter(ctx context.Context, updatedAfterTime *time.Time) ([]platform.Project, error) {
-	return nil, nuclio.ErrNotImplemented
+	responseBody, err := c.sendRequest(ctx,
+		http.MethodGet,
+		c.generateProjectsURL()+"?format=full",
+		nil,
+		nil,
+		nil,
+		nil,
+		http.StatusOK)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get projects from leader")
+	}
+
+	projects, err := c.resolveGetProjectResponse(false, responseBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve projects from response body")
+	}
+
+	if updatedAfterTime == nil || updatedAfterTime.IsZero() {
+		return projects, nil
+	}
+
+	// mlrun can't filter projects by their update time, filter them here instead
+	var updatedProjects []platform.Project
+	for _, projectInstance := range projects {
+		updatedAt := projectInstance.(*Project).GetUpdatedAt()
+
+		// projects with no known update time are always returned, the synchronizer skips unmodified ones
+		if updatedAt == nil || updatedAt.After(*updatedAfterTime) {
+			updatedProjects = append(updatedProjects, projectInstance)
+		}
+	}
+
+	return updatedProjects, nil
+}
+
+func (c *Client) sendRequest(ctx context.Context,
+	method string,
+	requestURL string,
+	body []byte,
+	headers map[string]string,
+	authSession auth.Session,
+	sessionCookie *http.Cookie,
+	expectedStatusCodes ...int) ([]byte, error) {
+
+	requestHeaders := c.generateCommonRequestHeaders()
+	for headerKey, headerValue := range headers {
+		requestHeaders[headerKey] = headerValue
+	}
+
+	var cookies []*http.Cookie
+	switch {
+	case authSession != nil:
+		requestHeaders["authorization"] = authSession.CompileAuthorizationBasic()
+		cookies = append(cookies, &http.Cookie{
+			Name:  "session",
+			Value: url.QueryEscape(fmt.Sprintf(`j:{"sid":"%s"}`, authSession.GetPassword())),
+		})
+	case sessionCookie == nil && c.platformConfiguration.IguazioSessionCookie != "":
+
+		// requests made on behalf of nuclio itself (e.g.: synchronization)
+		cookies = append(cookies, &http.Cookie{
+			Name:  "session",
+			Value: c.platformConfiguration.IguazioSessionCookie,
+		})
+	}
+
+	if sessionCookie != nil {
+		cookies = append(cookies, sessionCookie)
+	}
+
+	// status codes are validated below, as mlrun may respond with more than one successful status code
+	responseBody, response, err := common.SendHTTPRequestWithContext(ctx,
+		c.httpClient,
+		method,
+		requestURL,
+		body,
+		requestHeaders,
+		cookies,
+		0)
+	if err != nil {
+		c.logger.WarnWithCtx(ctx,
+			"Failed to send request to leader",
+			"method", method,
+			"requestURL", requestURL,
+			"err", err.Error())
+		return nil, errors.Wrap(err, "Failed to send request to leader")
+	}
+
+	for _, expectedStatusCode := range expectedStatusCodes {
+		if response.StatusCode == expectedStatusCode {
+			return responseBody, nil
+		}
+	}
+
+	if response.StatusCode >= http.StatusInternalServerError {
+		c.logger.WarnWithCtx(ctx,
+			"Leader responded with an internal server error",
+			"method", method,
+			"requestURL", requestURL,
+			"statusCode", response.StatusCode)
+	}
+
+	// try peek at the error response for a meaningful reason
+	reason := fmt.Sprintf("Got unexpected response status code: %d. Expected one of: %v",
+		response.StatusCode,
+		expectedStatusCodes)
+	var errorResponse ErrorResponse
+	if unmarshalErr := json.Unmarshal(responseBody, &errorResponse); unmarshalErr == nil &&
+		errorResponse.GetReason() != "" {
+		reason = errorResponse.GetReason()
+	}
+
+	return nil, nuclio.GetByStatusCode(response.StatusCode)(reason)
+}
+
+func (c *Client) waitForProjectOnline(ctx context.Context, createProjectOptions *platform.CreateProjectOptions) error {
+	projectName := createProjectOptions.ProjectConfig.Meta.Name
+
+	c.logger.DebugWithCtx(ctx, "Waiting for project to become online", "name", projectName)
+	return common.RetryUntilSuccessful(time.Minute*5,
+		time.Second*5,
+		func() bool {
+			projects, err := c.Get(ctx, &platform.GetProjectsOptions{
+				Meta:          platform.ProjectMeta{Name: projectName},
+				AuthSession:   createProjectOptions.AuthSession,
+				SessionCookie: createProjectOptions.SessionCookie,
+			})
+			if err != nil || len(projects) == 0 {
+				c.logger.DebugWithCtx(ctx,
+					"Project is not available yet",
+					"name", projectName,
+					"err", err)
+				return false
+			}
+
+			state := projects[0].(*Project).Status.State
+			c.logger.DebugWithCtx(ctx,
+				"Inspecting project state",
+				"name", projectName,
+				"state", state)
+			return state == ProjectStateOnline
+		})
+}
+
+func (c *Client) generateCommonRequestHeaders() map[string]string {
+	return map[string]string{
+		iguazio.ProjectsRoleHeaderKey: iguazio.ProjectsRoleHeaderValueNuclio,
+		"Content-Type":                "application/json",
+	}
+}
+
+func (c *Client) generateProjectsURL() string {
+	return fmt.Sprintf("%s/%s", c.platformConfiguration.ProjectsLeader.APIAddress, "projects")
+}
+
+func (c *Client) generateProjectURL(projectName string) string {
+	return fmt.Sprintf("%s/%s", c.generateProjectsURL(), url.PathEscape(projectName))
+}
+
+func (c *Client) generateProjectRequestBody(projectConfig *platform.ProjectConfig) ([]byte, error) {
+	return json.Marshal(NewProjectFromProjectConfig(projectConfig))
+}
+
+func (c *Client) resolveGetProjectResponse(detail bool, body []byte) ([]platform.Project, error) {
+	var projects []*Project
+
+	if detail {
+		project := &Project{}
+		if err := json.Unmarshal(body, project); err != nil {
+			return nil, errors.Wrap(err, "Failed to unmarshal response body")
+		}
+
+		projects = append(projects, project)
+	} else {
+		projectList := ProjectList{}
+		if err := json.Unmarshal(body, &projectList); err != nil {
+			return nil, errors.Wrap(err, "Failed to unmarshal response body")
+		}
+
+		projects = projectList.Projects
+	}
+
+	var platformProjects []platform.Project
+	for _, project := range projects {
+		project.namespace = c.namespace
+		platformProjects = append(platformProjects, project)
+	}
+
+	return platformProjects, nil
 }
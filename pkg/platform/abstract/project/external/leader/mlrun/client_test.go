@@ -0,0 +1,331 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mlrun
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/platform/abstract/project/external/leader/iguazio"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// mlrunStandIn serves a minimal, in memory, version of mlrun's projects API
+type mlrunStandIn struct {
+	lock          sync.Mutex
+	projects      map[string]*Project
+	lastRequest   *http.Request
+	createStatus  int
+	deleteRequest *http.Request
+}
+
+func (m *mlrunStandIn) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.lastRequest = request
+	projectName := strings.TrimPrefix(strings.TrimPrefix(request.URL.Path, "/api/v1/projects"), "/")
+
+	switch {
+	case request.Method == http.MethodGet && projectName == "":
+		projectList := ProjectList{}
+		for _, project := range m.projects {
+			projectList.Projects = append(projectList.Projects, project)
+		}
+		m.writeResponse(responseWriter, http.StatusOK, projectList)
+
+	case request.Method == http.MethodGet:
+		project, found := m.projects[projectName]
+		if !found {
+			m.writeResponse(responseWriter, http.StatusNotFound, ErrorResponse{Detail: "Project not found"})
+			return
+		}
+		m.writeResponse(responseWriter, http.StatusOK, project)
+
+	case request.Method == http.MethodPost:
+		project := &Project{}
+		body, _ := io.ReadAll(request.Body)
+		if err := json.Unmarshal(body, project); err != nil {
+			m.writeResponse(responseWriter, http.StatusBadRequest, ErrorResponse{Detail: err.Error()})
+			return
+		}
+
+		if _, found := m.projects[project.Metadata.Name]; found {
+			m.writeResponse(responseWriter, http.StatusConflict, ErrorResponse{
+				Detail: map[string]interface{}{"reason": "Project already exists"},
+			})
+			return
+		}
+
+		project.Metadata.Created = time.Now().UTC().Format(time.RFC3339Nano)
+		project.Status.State = ProjectStateOnline
+		m.projects[project.Metadata.Name] = project
+		m.writeResponse(responseWriter, m.createStatus, project)
+
+	case request.Method == http.MethodPut:
+		project := &Project{}
+		body, _ := io.ReadAll(request.Body)
+		if err := json.Unmarshal(body, project); err != nil {
+			m.writeResponse(responseWriter, http.StatusBadRequest, ErrorResponse{Detail: err.Error()})
+			return
+		}
+
+		project.Status.State = ProjectStateOnline
+		project.Metadata.Updated = time.Now().UTC().Format(time.RFC3339Nano)
+		m.projects[projectName] = project
+		m.writeResponse(responseWriter, http.StatusOK, project)
+
+	case request.Method == http.MethodDelete:
+		m.deleteRequest = request
+		delete(m.projects, projectName)
+		responseWriter.WriteHeader(http.StatusNoContent)
+
+	default:
+		responseWriter.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (m *mlrunStandIn) writeResponse(responseWriter http.ResponseWriter, statusCode int, body interface{}) {
+	encodedBody, _ := json.Marshal(body)
+	responseWriter.Header().Set("Content-Type", "application/json")
+	responseWriter.WriteHeader(statusCode)
+	responseWriter.Write(encodedBody) // nolint: errcheck
+}
+
+type ClientTestSuite struct {
+	suite.Suite
+
+	logger     logger.Logger
+	ctx        context.Context
+	standIn    *mlrunStandIn
+	httpServer *httptest.Server
+	client     *Client
+}
+
+func (suite *ClientTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.ctx = context.Background()
+	suite.standIn = &mlrunStandIn{
+		projects:     map[string]*Project{},
+		createStatus: http.StatusCreated,
+	}
+	suite.httpServer = httptest.NewServer(suite.standIn)
+
+	suite.client, err = NewClient(suite.logger, &platformconfig.Config{
+		ManagedNamespaces: []string{"some-namespace"},
+		ProjectsLeader: &platformconfig.ProjectsLeader{
+			Kind:       platformconfig.ProjectsLeaderKindMlrun,
+			APIAddress: suite.httpServer.URL + "/api/v1",
+		},
+	})
+	suite.Require().NoError(err)
+}
+
+func (suite *ClientTestSuite) TearDownTest() {
+	suite.httpServer.Close()
+}
+
+func (suite *ClientTestSuite) TestCreateAndGet() {
+	err := suite.client.Create(suite.ctx, &platform.CreateProjectOptions{
+		ProjectConfig: &platform.ProjectConfig{
+			Meta: platform.ProjectMeta{
+				Name:      "my-project",
+				Namespace: "some-namespace",
+				Labels:    map[string]string{"a": "b"},
+			},
+			Spec: platform.ProjectSpec{
+				Description: "some description",
+				Owner:       "admin",
+			},
+		},
+		WaitForCreateCompletion: true,
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(iguazio.ProjectsRoleHeaderValueNuclio,
+		suite.standIn.lastRequest.Header.Get(iguazio.ProjectsRoleHeaderKey))
+
+	// get a single project
+	projects, err := suite.client.Get(suite.ctx, &platform.GetProjectsOptions{
+		Meta: platform.ProjectMeta{Name: "my-project"},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(projects, 1)
+
+	projectConfig := projects[0].GetConfig()
+	suite.Require().Equal("my-project", projectConfig.Meta.Name)
+	suite.Require().Equal("some-namespace", projectConfig.Meta.Namespace)
+	suite.Require().Equal(map[string]string{"a": "b"}, projectConfig.Meta.Labels)
+	suite.Require().Equal("some description", projectConfig.Spec.Description)
+	suite.Require().Equal("admin", projectConfig.Spec.Owner)
+	suite.Require().Equal(ProjectStateOnline, projectConfig.Status.OperationalStatus)
+	suite.Require().Equal(ProjectStateOnline, projectConfig.Status.AdminStatus)
+	suite.Require().NotNil(projectConfig.Status.UpdatedAt)
+
+	// get all projects
+	projects, err = suite.client.Get(suite.ctx, &platform.GetProjectsOptions{})
+	suite.Require().NoError(err)
+	suite.Require().Len(projects, 1)
+	suite.Require().Equal("full", suite.standIn.lastRequest.URL.Query().Get("format"))
+}
+
+func (suite *ClientTestSuite) TestCreateAccepted() {
+	suite.standIn.createStatus = http.StatusAccepted
+
+	err := suite.client.Create(suite.ctx, &platform.CreateProjectOptions{
+		ProjectConfig: &platform.ProjectConfig{
+			Meta: platform.ProjectMeta{Name: "my-project"},
+		},
+	})
+	suite.Require().NoError(err)
+}
+
+func (suite *ClientTestSuite) TestCreateConflict() {
+	suite.standIn.projects["my-project"] = &Project{
+		Metadata: ProjectMetadata{Name: "my-project"},
+	}
+
+	err := suite.client.Create(suite.ctx, &platform.CreateProjectOptions{
+		ProjectConfig: &platform.ProjectConfig{
+			Meta: platform.ProjectMeta{Name: "my-project"},
+		},
+	})
+	suite.Require().Error(err)
+
+	errWithStatusCode, ok := errors.Cause(err).(*nuclio.ErrorWithStatusCode)
+	suite.Require().True(ok)
+	suite.Require().Equal(http.StatusConflict, errWithStatusCode.StatusCode())
+	suite.Require().Contains(errWithStatusCode.Error(), "Project already exists")
+}
+
+func (suite *ClientTestSuite) TestUpdate() {
+	suite.standIn.projects["my-project"] = &Project{
+		Metadata: ProjectMetadata{Name: "my-project"},
+		Spec:     ProjectSpec{Description: "old description"},
+	}
+
+	err := suite.client.Update(suite.ctx, &platform.UpdateProjectOptions{
+		ProjectConfig: platform.ProjectConfig{
+			Meta: platform.ProjectMeta{Name: "my-project"},
+			Spec: platform.ProjectSpec{Description: "new description"},
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(http.MethodPut, suite.standIn.lastRequest.Method)
+	suite.Require().Equal("new description", suite.standIn.projects["my-project"].Spec.Description)
+}
+
+func (suite *ClientTestSuite) TestDelete() {
+	for _, testCase := range []struct {
+		name             string
+		strategy         platform.DeleteProjectStrategy
+		expectedStrategy string
+	}{
+		{
+			name:             "cascading",
+			strategy:         platform.DeleteProjectStrategyCascading,
+			expectedStrategy: "cascading",
+		},
+		{
+			name:             "default",
+			expectedStrategy: "restricted",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.standIn.projects["my-project"] = &Project{
+				Metadata: ProjectMetadata{Name: "my-project"},
+			}
+
+			err := suite.client.Delete(suite.ctx, &platform.DeleteProjectOptions{
+				Meta:     platform.ProjectMeta{Name: "my-project"},
+				Strategy: testCase.strategy,
+			})
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedStrategy,
+				suite.standIn.deleteRequest.Header.Get(DeletionStrategyHeaderKey))
+			suite.Require().Empty(suite.standIn.projects)
+		})
+	}
+}
+
+func (suite *ClientTestSuite) TestGetUpdatedAfter() {
+	now := time.Now().UTC()
+	suite.standIn.projects = map[string]*Project{
+		"old": {
+			Metadata: ProjectMetadata{
+				Name:    "old",
+				Created: now.Add(-time.Hour).Format(time.RFC3339Nano),
+			},
+			Status: ProjectStatus{State: ProjectStateOnline},
+		},
+		"recently-updated": {
+			Metadata: ProjectMetadata{
+				Name:    "recently-updated",
+				Created: now.Add(-time.Hour).Format(time.RFC3339Nano),
+
+				// no timezone
+				Updated: now.Format("2006-01-02T15:04:05.999999"),
+			},
+			Status: ProjectStatus{State: ProjectStateOnline},
+		},
+		"no-timestamps": {
+			Metadata: ProjectMetadata{
+				Name: "no-timestamps",
+			},
+			Status: ProjectStatus{State: ProjectStateOnline},
+		},
+	}
+
+	// get all
+	projects, err := suite.client.GetUpdatedAfter(suite.ctx, nil)
+	suite.Require().NoError(err)
+	suite.Require().Len(projects, 3)
+
+	// only get the projects that were updated recently, or whose update time is unknown
+	updatedAfterTime := now.Add(-time.Minute)
+	projects, err = suite.client.GetUpdatedAfter(suite.ctx, &updatedAfterTime)
+	suite.Require().NoError(err)
+
+	var projectNames []string
+	for _, project := range projects {
+		projectNames = append(projectNames, project.GetConfig().Meta.Name)
+	}
+	suite.Require().ElementsMatch([]string{"recently-updated", "no-timestamps"}, projectNames)
+}
+
+func TestClientTestSuite(t *testing.T) {
+	suite.Run(t, new(ClientTestSuite))
+}
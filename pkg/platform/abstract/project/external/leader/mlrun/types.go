@@ -0,0 +1,152 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mlrun
+
+import (
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/platform"
+)
+
+const (
+	ProjectKind = "project"
+
+	// ProjectStateOnline is the state of a project that is ready to be used
+	ProjectStateOnline = "online"
+
+	// DeletionStrategyHeaderKey tells mlrun how to handle the project's resources when deleting it
+	DeletionStrategyHeaderKey = "x-mlrun-deletion-strategy"
+)
+
+// mlrun may omit the timezone when formatting timestamps, in which case it is UTC
+var projectTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+}
+
+type Project struct {
+	Kind     string          `json:"kind,omitempty"`
+	Metadata ProjectMetadata `json:"metadata,omitempty"`
+	Spec     ProjectSpec     `json:"spec,omitempty"`
+	Status   ProjectStatus   `json:"status,omitempty"`
+
+	// mlrun projects are not namespaced, the client sets the namespace the projects are synchronized into
+	namespace string
+}
+
+func NewProjectFromProjectConfig(projectConfig *platform.ProjectConfig) Project {
+	return Project{
+		Kind: ProjectKind,
+		Metadata: ProjectMetadata{
+			Name:        projectConfig.Meta.Name,
+			Labels:      projectConfig.Meta.Labels,
+			Annotations: projectConfig.Meta.Annotations,
+		},
+		Spec: ProjectSpec{
+			Description:                 projectConfig.Spec.Description,
+			Owner:                       projectConfig.Spec.Owner,
+			DefaultFunctionNodeSelector: projectConfig.Spec.DefaultFunctionNodeSelector,
+		},
+	}
+}
+
+func (p *Project) GetConfig() *platform.ProjectConfig {
+	projectConfig := &platform.ProjectConfig{
+		Meta: platform.ProjectMeta{
+			Name:        p.Metadata.Name,
+			Namespace:   p.namespace,
+			Labels:      p.Metadata.Labels,
+			Annotations: p.Metadata.Annotations,
+		},
+		Spec: platform.ProjectSpec{
+			Description:                 p.Spec.Description,
+			Owner:                       p.Spec.Owner,
+			DefaultFunctionNodeSelector: p.Spec.DefaultFunctionNodeSelector,
+		},
+		Status: platform.ProjectStatus{
+
+			// mlrun has a single state for the project
+			AdminStatus:       p.Status.State,
+			OperationalStatus: p.Status.State,
+		},
+	}
+
+	if updatedAt := p.GetUpdatedAt(); updatedAt != nil {
+		projectConfig.Status.UpdatedAt = updatedAt
+	}
+
+	return projectConfig
+}
+
+// GetUpdatedAt returns the last time the project was updated, falling back to its creation time. returns nil
+// if neither is known
+func (p *Project) GetUpdatedAt() *time.Time {
+	for _, timestamp := range []string{p.Metadata.Updated, p.Metadata.Created} {
+		if timestamp == "" {
+			continue
+		}
+
+		for _, layout := range projectTimeLayouts {
+			if parsedTime, err := time.Parse(layout, timestamp); err == nil {
+				return &parsedTime
+			}
+		}
+	}
+
+	return nil
+}
+
+type ProjectMetadata struct {
+	Name        string            `json:"name,omitempty"`
+	Created     string            `json:"created,omitempty"`
+	Updated     string            `json:"updated,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ProjectSpec struct {
+	Description                 string            `json:"description,omitempty"`
+	Owner                       string            `json:"owner,omitempty"`
+	DefaultFunctionNodeSelector map[string]string `json:"default_function_node_selector,omitempty"`
+}
+
+type ProjectStatus struct {
+	State string `json:"state,omitempty"`
+}
+
+type ProjectList struct {
+	Projects []*Project `json:"projects,omitempty"`
+}
+
+// ErrorResponse is returned by mlrun on failures. detail is either a string or an object with a reason
+type ErrorResponse struct {
+	Detail interface{} `json:"detail,omitempty"`
+}
+
+// GetReason returns a human readable failure reason, or an empty string if none was given
+func (er *ErrorResponse) GetReason() string {
+	switch typedDetail := er.Detail.(type) {
+	case string:
+		return typedDetail
+	case map[string]interface{}:
+		if reason, ok := typedDetail["reason"].(string); ok {
+			return reason
+		}
+	}
+
+	return ""
+}
@@ -17,7 +17,12 @@ limitations under the License.
 package platform
 
 import (
+	"fmt"
+
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 )
 
 type APIGateway interface {
@@ -47,3 +52,121 @@ func NewAbstractAPIGateway(parentLogger logger.Logger,
 func (ap *AbstractAPIGateway) GetConfig() *APIGatewayConfig {
 	return &ap.APIGatewayConfig
 }
+
+// ValidateAPIGatewaySpec validates the api gateway spec, regardless of the platform the api gateway is created on
+func ValidateAPIGatewaySpec(apiGatewaySpec *APIGatewaySpec) error {
+	upstreams := apiGatewaySpec.Upstreams
+
+	if len(upstreams) == 0 {
+		return nuclio.NewErrBadRequest("One or more upstreams must be provided in spec")
+	}
+
+	if apiGatewaySpec.Host == "" {
+		return nuclio.NewErrBadRequest("Host must be provided in spec")
+	}
+
+	// TODO: update this when adding more upstream kinds. for now allow only `nucliofunction` upstreams
+	kind := upstreams[0].Kind
+	if !isSupportedAPIGatewayUpstreamKind(kind) {
+		return nuclio.NewErrBadRequest(fmt.Sprintf("Unsupported upstream kind: '%s'. (Currently supporting only nucliofunction)", kind))
+	}
+
+	// make sure all upstreams have the same kind
+	for _, upstream := range upstreams {
+		if upstream.Kind != kind {
+			return nuclio.NewErrBadRequest("All upstreams must be of the same kind")
+		}
+	}
+
+	_, canaryUpstreams, err := apiGatewaySpec.ResolvePrimaryAndCanaryUpstreams()
+	if err != nil {
+		return nuclio.WrapErrBadRequest(err)
+	}
+
+	upstreamFunctionNames := map[string]bool{}
+	for _, upstream := range upstreams {
+
+		// the ingress controller can't route between two upstreams of the same service
+		if upstream.NuclioFunction == nil || upstream.NuclioFunction.Name == "" {
+			return nuclio.NewErrBadRequest("Upstream function name must be provided")
+		}
+		if upstreamFunctionNames[upstream.NuclioFunction.Name] {
+			return nuclio.NewErrBadRequest(fmt.Sprintf("Function '%s' is used by more than one upstream",
+				upstream.NuclioFunction.Name))
+		}
+		upstreamFunctionNames[upstream.NuclioFunction.Name] = true
+	}
+
+	// canary upstreams split up to 100 percent of the traffic between them, the primary upstream gets the rest
+	canaryPercentageSum := 0
+	for _, canaryUpstream := range canaryUpstreams {
+		if canaryUpstream.Percentage < 0 || canaryUpstream.Percentage > 100 {
+			return nuclio.NewErrBadRequest("Upstream percentage must be between 0 and 100")
+		}
+		canaryPercentageSum += canaryUpstream.Percentage
+
+		if err := validateAPIGatewayUpstreamMatch(canaryUpstream.Match); err != nil {
+			return err
+		}
+	}
+
+	if canaryPercentageSum > 100 {
+		return nuclio.NewErrBadRequest(fmt.Sprintf("Canary upstreams percentages sum up to %d, must not exceed 100",
+			canaryPercentageSum))
+	}
+
+	return nil
+}
+
+// ValidateAPIGatewayCanaryIngressUpstreams validates the api gateway can be rendered as nginx canary ingresses.
+// nginx honors a single canary ingress per host and path, so at most one canary upstream is allowed. N-way
+// weighted splits are only rendered as Gateway API HTTPRoutes, which weigh any number of backends in one rule
+func ValidateAPIGatewayCanaryIngressUpstreams(apiGatewaySpec *APIGatewaySpec) error {
+	_, canaryUpstreams, err := apiGatewaySpec.ResolvePrimaryAndCanaryUpstreams()
+	if err != nil {
+		return nuclio.WrapErrBadRequest(err)
+	}
+
+	if len(canaryUpstreams) > 1 {
+		return nuclio.NewErrBadRequest(fmt.Sprintf(
+			"Received %d canary upstreams, nginx ingresses support a single canary upstream "+
+				"(set the platform ingress kind to %s to split the traffic between more upstreams)",
+			len(canaryUpstreams),
+			platformconfig.IngressKindHTTPRoute))
+	}
+
+	return nil
+}
+
+func validateAPIGatewayUpstreamMatch(match *APIGatewayUpstreamMatchSpec) error {
+	if match == nil {
+		return nil
+	}
+
+	switch {
+	case match.Header == "" && match.Cookie == "":
+		return nuclio.NewErrBadRequest("Upstream match must specify either a header or a cookie")
+	case match.Header != "" && match.Cookie != "":
+		return nuclio.NewErrBadRequest("Upstream match must not specify both a header and a cookie")
+	case match.HeaderValue != "" && match.Header == "":
+		return nuclio.NewErrBadRequest("Upstream match header value must be given along with a header")
+	}
+
+	return nil
+}
+
+func getAPIGatewayUpstreamKinds() []APIGatewayUpstreamKind {
+	return []APIGatewayUpstreamKind{
+		APIGatewayUpstreamKindNuclioFunction,
+	}
+}
+
+func isSupportedAPIGatewayUpstreamKind(upstreamKind APIGatewayUpstreamKind) bool {
+	for _, supportedUpstreamKind := range getAPIGatewayUpstreamKinds() {
+		if upstreamKind == supportedUpstreamKind {
+			return true
+		}
+	}
+
+	return false
+}
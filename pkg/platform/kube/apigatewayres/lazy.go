@@ -29,6 +29,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/platform/kube"
 	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
 	nuclioio_client "github.com/nuclio/nuclio/pkg/platform/kube/client/clientset/versioned"
+	"github.com/nuclio/nuclio/pkg/platform/kube/httproute"
 	"github.com/nuclio/nuclio/pkg/platform/kube/ingress"
 
 	"github.com/nuclio/errors"
@@ -43,22 +44,25 @@ import (
 //
 
 type lazyClient struct {
-	logger          logger.Logger
-	kubeClientSet   kubernetes.Interface
-	nuclioClientSet nuclioio_client.Interface
-	ingressManager  *ingress.Manager
+	logger           logger.Logger
+	kubeClientSet    kubernetes.Interface
+	nuclioClientSet  nuclioio_client.Interface
+	ingressManager   *ingress.Manager
+	httpRouteManager *httproute.Manager
 }
 
 func NewLazyClient(loggerInstance logger.Logger,
 	kubeClientSet kubernetes.Interface,
 	nuclioClientSet nuclioio_client.Interface,
-	ingressManager *ingress.Manager) (Client, error) {
+	ingressManager *ingress.Manager,
+	httpRouteManager *httproute.Manager) (Client, error) {
 
 	newClient := lazyClient{
-		logger:          loggerInstance.GetChild("apigatewayres"),
-		kubeClientSet:   kubeClientSet,
-		nuclioClientSet: nuclioClientSet,
-		ingressManager:  ingressManager,
+		logger:           loggerInstance.GetChild("apigatewayres"),
+		kubeClientSet:    kubeClientSet,
+		nuclioClientSet:  nuclioClientSet,
+		ingressManager:   ingressManager,
+		httpRouteManager: httpRouteManager,
 	}
 
 	return &newClient, nil
@@ -79,40 +83,47 @@ func (lc *lazyClient) CreateOrUpdate(ctx context.Context, apiGateway *nuclioio.N
 		return nil, errors.Wrap(err, "Api gateway spec validation failed")
 	}
 
+	if lc.httpRouteManager.Enabled() {
+		return lc.createOrUpdateHTTPRoute(ctx, apiGateway)
+	}
+
 	// always try to remove previous canary ingress first, because
 	// nginx returns 503 on all requests if primary service == secondary service. (happens on every promotion)
 	// so during promotion all requests will be sent to the primary ingress
-	lc.tryRemovePreviousCanaryIngress(ctx, apiGateway)
+	lc.tryRemovePreviousCanaryIngress(ctx, apiGateway.Namespace, apiGateway.Name)
 
 	// generate an ingress for each upstream
 	ingressesResources := map[string]*ingress.Resources{}
 	var ingressesToCreate []*ingress.Resources
 
-	primaryUpstream, canaryUpstream, err := lc.resolveBaseAndCanaryUpstreamsFromSpec(apiGateway.Spec.Upstreams)
+	primaryUpstream, canaryUpstreams, err := apiGateway.Spec.ResolvePrimaryAndCanaryUpstreams()
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to resolve base and canary upstreams")
+		return nil, errors.Wrap(err, "Failed to resolve primary and canary upstreams")
 	}
 
 	// create primary ingress
-	primaryIngressResources, err := lc.generateNginxIngress(ctx, apiGateway, primaryUpstream)
+	primaryIngressResources, err := lc.generateNginxIngress(ctx,
+		apiGateway,
+		primaryUpstream,
+		kube.IngressNameFromAPIGatewayName(apiGateway.Name, false),
+		false)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to generate primary nginx ingress")
 	}
 
-	lc.enrichPrimaryIngressResources(primaryIngressResources, primaryUpstream, canaryUpstream)
+	lc.enrichPrimaryIngressResources(primaryIngressResources, primaryUpstream, canaryUpstreams)
 
 	ingressesResources[primaryIngressResources.Ingress.Name] = primaryIngressResources
 	ingressesToCreate = append(ingressesToCreate, primaryIngressResources)
 
-	// add the canary ingress
-	if canaryUpstream != nil {
-
-		// percentage range
-		if canaryUpstream.Percentage > 100 || canaryUpstream.Percentage < 1 {
-			return nil, errors.New("The canary upstream percentage must be between 1 and 100")
-		}
-
-		canaryIngressResources, err := lc.generateNginxIngress(ctx, apiGateway, canaryUpstream)
+	// add the canary ingress. nginx honors a single canary ingress per host and path, so validation
+	// allows at most one canary upstream when api gateways are rendered as ingresses
+	for _, canaryUpstream := range canaryUpstreams {
+		canaryIngressResources, err := lc.generateNginxIngress(ctx,
+			apiGateway,
+			canaryUpstream,
+			kube.IngressNameFromAPIGatewayName(apiGateway.Name, true),
+			true)
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to generate the canary nginx ingress")
 		}
@@ -138,6 +149,10 @@ func (lc *lazyClient) CreateOrUpdate(ctx context.Context, apiGateway *nuclioio.N
 }
 
 func (lc *lazyClient) WaitAvailable(ctx context.Context, namespace string, name string) {
+	if lc.httpRouteManager.Enabled() {
+		return
+	}
+
 	lc.logger.DebugWithCtx(ctx, "Sleeping for 4 seconds so nginx controller will stabilize")
 
 	// sleep 4 seconds as a safety, so nginx will finish updating the ingresses properly (it takes time)
@@ -145,6 +160,18 @@ func (lc *lazyClient) WaitAvailable(ctx context.Context, namespace string, name
 }
 
 func (lc *lazyClient) Delete(ctx context.Context, namespace string, name string) {
+	if lc.httpRouteManager.Enabled() {
+		lc.logger.DebugWithCtx(ctx, "Deleting api gateway HTTPRoute", "name", name)
+
+		if err := lc.httpRouteManager.DeleteByName(ctx,
+			namespace,
+			kube.IngressNameFromAPIGatewayName(name, false)); err != nil {
+			lc.logger.WarnWithCtx(ctx, "Failed to delete HTTPRoute",
+				"err", errors.Cause(err).Error())
+		}
+		return
+	}
+
 	lc.logger.DebugWithCtx(ctx, "Deleting api gateway base ingress", "name", name)
 
 	if err := lc.ingressManager.DeleteByName(ctx,
@@ -165,17 +192,17 @@ func (lc *lazyClient) Delete(ctx context.Context, namespace string, name string)
 	}
 }
 
-func (lc *lazyClient) tryRemovePreviousCanaryIngress(ctx context.Context, apiGateway *nuclioio.NuclioAPIGateway) {
+func (lc *lazyClient) tryRemovePreviousCanaryIngress(ctx context.Context, namespace string, name string) {
 	lc.logger.DebugWithCtx(ctx,
 		"Trying to remove previous canary ingress",
-		"apiGatewayName", apiGateway.Name)
+		"apiGatewayName", name)
 
 	// remove old canary ingress if it exists
 	// this works thanks to an assumption that ingress names == api gateway name
-	previousCanaryIngressName := kube.IngressNameFromAPIGatewayName(apiGateway.Name, true)
+	previousCanaryIngressName := kube.IngressNameFromAPIGatewayName(name, true)
 	if err := lc.ingressManager.DeleteByName(ctx,
 		previousCanaryIngressName,
-		apiGateway.Namespace,
+		namespace,
 		true); err != nil {
 		lc.logger.WarnWithCtx(ctx,
 			"Failed to delete previous canary ingress on api gateway update",
@@ -187,10 +214,16 @@ func (lc *lazyClient) tryRemovePreviousCanaryIngress(ctx context.Context, apiGat
 func (lc *lazyClient) validateSpec(ctx context.Context, apiGateway *nuclioio.NuclioAPIGateway) error {
 	upstreams := apiGateway.Spec.Upstreams
 
-	if err := kube.ValidateAPIGatewaySpec(&apiGateway.Spec); err != nil {
+	if err := platform.ValidateAPIGatewaySpec(&apiGateway.Spec); err != nil {
 		return err
 	}
 
+	if !lc.httpRouteManager.Enabled() {
+		if err := platform.ValidateAPIGatewayCanaryIngressUpstreams(&apiGateway.Spec); err != nil {
+			return err
+		}
+	}
+
 	// make sure each upstream is unique - meaning, there's no other api gateway with an upstream with the
 	// same service (currently only nuclio function) name
 	// (this is done because creating multiple ingresses with the same service name breaks nginx ingress controller)
@@ -233,7 +266,9 @@ func (lc *lazyClient) getAllExistingUpstreamFunctionNames(ctx context.Context, n
 
 func (lc *lazyClient) generateNginxIngress(ctx context.Context,
 	apiGateway *nuclioio.NuclioAPIGateway,
-	upstream *platform.APIGatewayUpstreamSpec) (*ingress.Resources, error) {
+	upstream *platform.APIGatewayUpstreamSpec,
+	ingressName string,
+	canary bool) (*ingress.Resources, error) {
 
 	serviceName, servicePort, err := lc.getServiceNameAndPort(upstream)
 	if err != nil {
@@ -285,11 +320,8 @@ func (lc *lazyClient) generateNginxIngress(ctx context.Context,
 		return nil, errors.New("Unsupported ApiGateway authentication mode provided")
 	}
 
-	// if percentage is given, it is the canary deployment
-	canaryDeployment := upstream.Percentage != 0
-	commonIngressSpec.Name = kube.IngressNameFromAPIGatewayName(apiGateway.Name, canaryDeployment)
-
-	commonIngressSpec.Annotations = lc.resolveCommonAnnotations(canaryDeployment, upstream.Percentage)
+	commonIngressSpec.Name = ingressName
+	commonIngressSpec.Annotations = lc.resolveCommonAnnotations(canary, upstream)
 	for annotationKey, annotationValue := range apiGateway.Annotations {
 		commonIngressSpec.Annotations[annotationKey] = annotationValue
 	}
@@ -312,6 +344,166 @@ func (lc *lazyClient) generateNginxIngress(ctx context.Context,
 	return lc.ingressManager.GenerateResources(ctx, commonIngressSpec)
 }
 
+func (lc *lazyClient) createOrUpdateHTTPRoute(ctx context.Context,
+	apiGateway *nuclioio.NuclioAPIGateway) (Resources, error) {
+
+	httpRouteSpec, err := lc.generateHTTPRouteSpec(apiGateway)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to generate HTTPRoute spec")
+	}
+
+	httpRoute, err := lc.httpRouteManager.GenerateResources(*httpRouteSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to generate HTTPRoute")
+	}
+
+	if _, err := lc.httpRouteManager.CreateOrUpdate(ctx, httpRoute); err != nil {
+		lc.logger.WarnWithCtx(ctx, "Failed to create/update api gateway HTTPRoute",
+			"err", errors.Cause(err),
+			"httpRouteName", httpRoute.Name)
+		return nil, errors.New("Failed to create/update api gateway HTTPRoute")
+	}
+
+	return &lazyResources{
+		ingressResourcesMap: map[string]*ingress.Resources{},
+		httpRoute:           httpRoute,
+	}, nil
+}
+
+// generateHTTPRouteSpec generates a single HTTPRoute for all the api gateway's upstreams. header matched
+// canaries get rules of their own, while the primary and weighted canaries share a rule with weighted backends
+func (lc *lazyClient) generateHTTPRouteSpec(apiGateway *nuclioio.NuclioAPIGateway) (*httproute.Spec, error) {
+
+	// the gateway api has no standard authentication
+	if apiGateway.Spec.AuthenticationMode != ingress.AuthenticationModeNone {
+		return nil, errors.Errorf("Authentication mode %s is not supported with HTTPRoutes",
+			apiGateway.Spec.AuthenticationMode)
+	}
+
+	primaryUpstream, canaryUpstreams, err := apiGateway.Spec.ResolvePrimaryAndCanaryUpstreams()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve primary and canary upstreams")
+	}
+
+	// add "/" as path prefix if not already there
+	if !strings.HasPrefix(apiGateway.Spec.Path, "/") {
+		apiGateway.Spec.Path = fmt.Sprintf("/%s", apiGateway.Spec.Path)
+	}
+
+	// set nuclio target header, so functions scaled to zero are woken up by any of the upstreams
+	targetFunctionNames := []string{primaryUpstream.NuclioFunction.Name}
+	for _, canaryUpstream := range canaryUpstreams {
+		targetFunctionNames = append(targetFunctionNames, canaryUpstream.NuclioFunction.Name)
+	}
+	requestHeaders := map[string]string{
+		"X-Nuclio-Target": strings.Join(targetFunctionNames, ","),
+	}
+
+	primaryBackend, err := lc.getHTTPRouteBackend(primaryUpstream)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get primary upstream backend")
+	}
+
+	var rules []httproute.RuleSpec
+	weightedBackends := []httproute.Backend{primaryBackend}
+	primaryWeight := int32(100)
+
+	for _, canaryUpstream := range canaryUpstreams {
+		canaryBackend, err := lc.getHTTPRouteBackend(canaryUpstream)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to get canary upstream backend")
+		}
+
+		if canaryUpstream.Match != nil {
+			if canaryUpstream.Match.Cookie != "" {
+				return nil, errors.New("Cookie matches are not supported with HTTPRoutes")
+			}
+
+			if canaryUpstream.Match.Header != "" {
+
+				// like nginx, a header without a value routes to the canary when set to "always"
+				headerValue := canaryUpstream.Match.HeaderValue
+				if headerValue == "" {
+					headerValue = "always"
+				}
+
+				rules = append(rules, httproute.RuleSpec{
+					Path: apiGateway.Spec.Path,
+					HeaderMatches: []httproute.HeaderMatch{
+						{
+							Name:  canaryUpstream.Match.Header,
+							Value: headerValue,
+						},
+					},
+					Backends:       []httproute.Backend{canaryBackend},
+					RequestHeaders: requestHeaders,
+					RewriteTarget:  canaryUpstream.RewriteTarget,
+				})
+			}
+		}
+
+		if canaryUpstream.Percentage > 0 {
+
+			// rewrite filters apply to the whole rule
+			if canaryUpstream.RewriteTarget != primaryUpstream.RewriteTarget {
+				return nil, errors.New("Weighted upstreams must share the same rewrite target with HTTPRoutes")
+			}
+
+			canaryWeight := int32(canaryUpstream.Percentage)
+			canaryBackend.Weight = &canaryWeight
+			weightedBackends = append(weightedBackends, canaryBackend)
+			primaryWeight -= canaryWeight
+		}
+	}
+
+	if len(weightedBackends) > 1 {
+		weightedBackends[0].Weight = &primaryWeight
+	}
+
+	rules = append(rules, httproute.RuleSpec{
+		Path:           apiGateway.Spec.Path,
+		Backends:       weightedBackends,
+		RequestHeaders: requestHeaders,
+		RewriteTarget:  primaryUpstream.RewriteTarget,
+	})
+
+	annotations := map[string]string{}
+	for annotationKey, annotationValue := range apiGateway.Annotations {
+		annotations[annotationKey] = annotationValue
+	}
+	for annotationKey, annotationValue := range primaryUpstream.ExtraAnnotations {
+		annotations[annotationKey] = annotationValue
+	}
+
+	httpRouteSpec := &httproute.Spec{
+		Name:           kube.IngressNameFromAPIGatewayName(apiGateway.Name, false),
+		Namespace:      apiGateway.Namespace,
+		ProjectName:    apiGateway.Labels[common.NuclioResourceLabelKeyProjectName],
+		APIGatewayName: apiGateway.Name,
+		Rules:          rules,
+		Annotations:    annotations,
+		Labels:         primaryUpstream.ExtraLabels,
+	}
+
+	if apiGateway.Spec.Host != "" {
+		httpRouteSpec.Hostnames = []string{apiGateway.Spec.Host}
+	}
+
+	return httpRouteSpec, nil
+}
+
+func (lc *lazyClient) getHTTPRouteBackend(upstream *platform.APIGatewayUpstreamSpec) (httproute.Backend, error) {
+	serviceName, servicePort, err := lc.getServiceNameAndPort(upstream)
+	if err != nil {
+		return httproute.Backend{}, errors.Wrap(err, "Failed to get service name")
+	}
+
+	return httproute.Backend{
+		ServiceName: serviceName,
+		ServicePort: servicePort,
+	}, nil
+}
+
 func (lc *lazyClient) getServiceNameAndPort(upstream *platform.APIGatewayUpstreamSpec) (string, int, error) {
 	switch upstream.Kind {
 	case platform.APIGatewayUpstreamKindNuclioFunction:
@@ -329,56 +521,44 @@ func (lc *lazyClient) getServiceNameAndPort(upstream *platform.APIGatewayUpstrea
 	}
 }
 
-func (lc *lazyClient) resolveCommonAnnotations(canaryDeployment bool, upstreamPercentage int) map[string]string {
+func (lc *lazyClient) resolveCommonAnnotations(canary bool,
+	upstream *platform.APIGatewayUpstreamSpec) map[string]string {
 	annotations := map[string]string{}
 
 	// add nginx specific annotations
 	annotations["kubernetes.io/ingress.class"] = "nginx"
 
 	// add canary deployment specific annotations
-	if canaryDeployment {
+	// nginx routes by header first, then by cookie and only then by weight
+	if canary {
 		annotations["nginx.ingress.kubernetes.io/canary"] = "true"
-		annotations["nginx.ingress.kubernetes.io/canary-weight"] = strconv.Itoa(upstreamPercentage)
-	}
-	return annotations
-}
-
-func (lc *lazyClient) resolveBaseAndCanaryUpstreamsFromSpec(upstreams []platform.APIGatewayUpstreamSpec) (
-	*platform.APIGatewayUpstreamSpec, *platform.APIGatewayUpstreamSpec, error) {
+		annotations["nginx.ingress.kubernetes.io/canary-weight"] = strconv.Itoa(upstream.Percentage)
 
-	if len(upstreams) == 1 {
-		return &upstreams[0], nil, nil
-	}
-
-	var primary *platform.APIGatewayUpstreamSpec
-	var canary *platform.APIGatewayUpstreamSpec
-
-	// determine which upstream is the canary one
-	switch {
-	case upstreams[0].Percentage != 0:
-		primary = &upstreams[1]
-		canary = &upstreams[0]
-	case upstreams[1].Percentage != 0:
-		primary = &upstreams[0]
-		canary = &upstreams[1]
-	default:
-		return nil, nil, errors.New("Percentage must be set on one of the upstreams (canary)")
+		if upstream.Match != nil {
+			if upstream.Match.Header != "" {
+				annotations["nginx.ingress.kubernetes.io/canary-by-header"] = upstream.Match.Header
+			}
+			if upstream.Match.HeaderValue != "" {
+				annotations["nginx.ingress.kubernetes.io/canary-by-header-value"] = upstream.Match.HeaderValue
+			}
+			if upstream.Match.Cookie != "" {
+				annotations["nginx.ingress.kubernetes.io/canary-by-cookie"] = upstream.Match.Cookie
+			}
+		}
 	}
-
-	return primary, canary, nil
+	return annotations
 }
 
 func (lc *lazyClient) enrichPrimaryIngressResources(primaryIngressResources *ingress.Resources,
 	primaryUpstream *platform.APIGatewayUpstreamSpec,
-	canaryUpstream *platform.APIGatewayUpstreamSpec) {
+	canaryUpstreams []*platform.APIGatewayUpstreamSpec) {
 
 	// set nuclio target header on ingress
-	targetHeaderValue := primaryUpstream.NuclioFunction.Name
-	if canaryUpstream != nil {
-		targetHeaderValue = fmt.Sprintf(`%s,%s`,
-			primaryUpstream.NuclioFunction.Name,
-			canaryUpstream.NuclioFunction.Name)
+	targetFunctionNames := []string{primaryUpstream.NuclioFunction.Name}
+	for _, canaryUpstream := range canaryUpstreams {
+		targetFunctionNames = append(targetFunctionNames, canaryUpstream.NuclioFunction.Name)
 	}
+	targetHeaderValue := strings.Join(targetFunctionNames, ",")
 	encodedPrimaryTargetHeader := fmt.Sprintf(`proxy_set_header X-Nuclio-Target "%s";`, targetHeaderValue)
 	annotations := primaryIngressResources.Ingress.Annotations
 	configurationSnippetHeaderName := "nginx.ingress.kubernetes.io/configuration-snippet"
@@ -396,9 +576,15 @@ func (lc *lazyClient) enrichPrimaryIngressResources(primaryIngressResources *ing
 
 type lazyResources struct {
 	ingressResourcesMap map[string]*ingress.Resources
+	httpRoute           *httproute.HTTPRoute
 }
 
 // Deployment returns the deployment
 func (lr *lazyResources) IngressResourcesMap() map[string]*ingress.Resources {
 	return lr.ingressResourcesMap
 }
+
+// HTTPRoute returns the HTTPRoute
+func (lr *lazyResources) HTTPRoute() *httproute.HTTPRoute {
+	return lr.httpRoute
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/platform"
 	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
 	"github.com/nuclio/nuclio/pkg/platform/kube/client/clientset/versioned/fake"
+	"github.com/nuclio/nuclio/pkg/platform/kube/httproute"
 	"github.com/nuclio/nuclio/pkg/platform/kube/ingress"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 
@@ -36,6 +37,9 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 )
 
@@ -45,6 +49,7 @@ type lazyTestSuite struct {
 	client         Client
 	ingressManager *ingress.Manager
 	mockCmdRunner  *cmdrunner.MockRunner
+	kubeClientSet  *k8sfake.Clientset
 }
 
 func (suite *lazyTestSuite) SetupTest() {
@@ -55,14 +60,15 @@ func (suite *lazyTestSuite) SetupTest() {
 
 	suite.mockCmdRunner = cmdrunner.NewMockRunner()
 
-	kubeClientset := k8sfake.NewSimpleClientset()
-	suite.ingressManager, err = ingress.NewManager(suite.logger, kubeClientset, suite.mockCmdRunner, platformConfig)
+	suite.kubeClientSet = k8sfake.NewSimpleClientset()
+	suite.ingressManager, err = ingress.NewManager(suite.logger, suite.kubeClientSet, suite.mockCmdRunner, platformConfig)
 	suite.Require().NoError(err)
 
 	suite.client, err = NewLazyClient(suite.logger,
-		kubeClientset,
+		suite.kubeClientSet,
 		fake.NewSimpleClientset(),
-		suite.ingressManager)
+		suite.ingressManager,
+		suite.createHTTPRouteManager(platformConfig))
 	suite.Require().NoError(err)
 }
 
@@ -125,6 +131,191 @@ func (suite *lazyTestSuite) TestEnsurePrimaryIngressHasXNuclioTargetHeader() {
 		primaryIngressResources.Ingress.Annotations["nginx.ingress.kubernetes.io/configuration-snippet"])
 }
 
+func (suite *lazyTestSuite) TestCanaryUpstreamMatchRule() {
+	apiGateway := &nuclioio.NuclioAPIGateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-name",
+			Namespace: "test-namespace",
+		},
+		Spec: platform.APIGatewaySpec{
+			Host:               "some-host.com",
+			Name:               "test-name",
+			AuthenticationMode: ingress.AuthenticationModeNone,
+			Upstreams: []platform.APIGatewayUpstreamSpec{
+				{
+					Kind:           platform.APIGatewayUpstreamKindNuclioFunction,
+					NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{Name: "function-a"},
+				},
+				{
+					Kind:           platform.APIGatewayUpstreamKindNuclioFunction,
+					NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{Name: "function-b"},
+					Percentage:     10,
+					Match: &platform.APIGatewayUpstreamMatchSpec{
+						Header:      "x-canary",
+						HeaderValue: "true",
+					},
+				},
+			},
+		},
+	}
+
+	resources, err := suite.client.CreateOrUpdate(context.Background(), apiGateway)
+	suite.Require().NoError(err)
+
+	ingressResourcesMap := resources.IngressResourcesMap()
+	suite.Require().Len(ingressResourcesMap, 2)
+
+	primaryIngress := ingressResourcesMap["nuclio-agw-test-name"].Ingress
+	suite.Require().NotContains(primaryIngress.Annotations, "nginx.ingress.kubernetes.io/canary")
+	suite.Require().Equal(`proxy_set_header X-Nuclio-Target "function-a,function-b";`,
+		primaryIngress.Annotations["nginx.ingress.kubernetes.io/configuration-snippet"])
+
+	canaryIngress := ingressResourcesMap["nuclio-agw-test-name-canary"].Ingress
+	suite.Require().Equal("true", canaryIngress.Annotations["nginx.ingress.kubernetes.io/canary"])
+	suite.Require().Equal("10", canaryIngress.Annotations["nginx.ingress.kubernetes.io/canary-weight"])
+	suite.Require().Equal("x-canary", canaryIngress.Annotations["nginx.ingress.kubernetes.io/canary-by-header"])
+	suite.Require().Equal("true",
+		canaryIngress.Annotations["nginx.ingress.kubernetes.io/canary-by-header-value"])
+	suite.Require().Equal("nuclio-function-b",
+		canaryIngress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name)
+
+	// nginx honors a single canary ingress per host and path, so a second canary upstream is rejected
+	apiGateway.Spec.Upstreams = append(apiGateway.Spec.Upstreams, platform.APIGatewayUpstreamSpec{
+		Kind:           platform.APIGatewayUpstreamKindNuclioFunction,
+		NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{Name: "function-c"},
+		Percentage:     30,
+	})
+	_, err = suite.client.CreateOrUpdate(context.Background(), apiGateway)
+	suite.Require().Error(err)
+
+	// drop the canaries, the canary ingress should be removed
+	apiGateway.Spec.Upstreams = apiGateway.Spec.Upstreams[:1]
+	_, err = suite.client.CreateOrUpdate(context.Background(), apiGateway)
+	suite.Require().NoError(err)
+
+	ingresses, err := suite.kubeClientSet.NetworkingV1().Ingresses("test-namespace").List(context.Background(),
+		metav1.ListOptions{})
+	suite.Require().NoError(err)
+	suite.Require().Len(ingresses.Items, 1)
+	suite.Require().Equal("nuclio-agw-test-name", ingresses.Items[0].Name)
+
+	// delete the api gateway, all of its ingresses should be removed
+	suite.client.Delete(context.Background(), "test-namespace", "test-name")
+	ingresses, err = suite.kubeClientSet.NetworkingV1().Ingresses("test-namespace").List(context.Background(),
+		metav1.ListOptions{})
+	suite.Require().NoError(err)
+	suite.Require().Empty(ingresses.Items)
+}
+
+func (suite *lazyTestSuite) TestHTTPRoute() {
+	platformConfig, err := platformconfig.NewPlatformConfig("")
+	suite.Require().NoError(err)
+	platformConfig.IngressConfig.Kind = platformconfig.IngressKindHTTPRoute
+	platformConfig.IngressConfig.GatewayAPI.GatewayName = "nuclio-gateway"
+
+	httpRouteManager := suite.createHTTPRouteManager(platformConfig)
+	suite.client.(*lazyClient).httpRouteManager = httpRouteManager
+
+	apiGateway := &nuclioio.NuclioAPIGateway{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-name",
+			Namespace: "test-namespace",
+		},
+		Spec: platform.APIGatewaySpec{
+			Host:               "some-host.com",
+			Name:               "test-name",
+			Path:               "api",
+			AuthenticationMode: ingress.AuthenticationModeNone,
+			Upstreams: []platform.APIGatewayUpstreamSpec{
+				{
+					Kind:           platform.APIGatewayUpstreamKindNuclioFunction,
+					NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{Name: "function-a"},
+				},
+				{
+					Kind:           platform.APIGatewayUpstreamKindNuclioFunction,
+					NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{Name: "function-b"},
+					Percentage:     10,
+					Match: &platform.APIGatewayUpstreamMatchSpec{
+						Header:      "x-canary",
+						HeaderValue: "true",
+					},
+				},
+				{
+					Kind:           platform.APIGatewayUpstreamKindNuclioFunction,
+					NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{Name: "function-c"},
+					Percentage:     30,
+				},
+			},
+		},
+	}
+
+	resources, err := suite.client.CreateOrUpdate(context.Background(), apiGateway)
+	suite.Require().NoError(err)
+	suite.Require().Empty(resources.IngressResourcesMap())
+
+	httpRoute, err := httpRouteManager.Get(context.Background(), "test-namespace", "nuclio-agw-test-name")
+	suite.Require().NoError(err)
+	suite.Require().Equal(resources.HTTPRoute().Spec, httpRoute.Spec)
+	suite.Require().Equal("test-name", httpRoute.Labels["nuclio.io/apigateway-name"])
+	suite.Require().Equal([]string{"some-host.com"}, httpRoute.Spec.Hostnames)
+	suite.Require().Equal([]httproute.ParentReference{{Name: "nuclio-gateway"}}, httpRoute.Spec.ParentRefs)
+	suite.Require().Len(httpRoute.Spec.Rules, 2)
+
+	// header matched canary
+	headerMatchRule := httpRoute.Spec.Rules[0]
+	suite.Require().Equal("/api", headerMatchRule.Matches[0].Path.Value)
+	suite.Require().Equal([]httproute.HTTPHeaderMatch{
+		{Type: httproute.HeaderMatchExact, Name: "x-canary", Value: "true"},
+	}, headerMatchRule.Matches[0].Headers)
+	suite.Require().Len(headerMatchRule.BackendRefs, 1)
+	suite.Require().Equal("nuclio-function-b", headerMatchRule.BackendRefs[0].Name)
+	suite.Require().Nil(headerMatchRule.BackendRefs[0].Weight)
+
+	// primary and weighted canaries
+	weightedRule := httpRoute.Spec.Rules[1]
+	suite.Require().Empty(weightedRule.Matches[0].Headers)
+	suite.Require().Equal([]httproute.HTTPHeader{{Name: "X-Nuclio-Target", Value: "function-a,function-b,function-c"}},
+		weightedRule.Filters[0].RequestHeaderModifier.Set)
+
+	backendWeights := map[string]int32{}
+	for _, backendRef := range weightedRule.BackendRefs {
+		backendWeights[backendRef.Name] = *backendRef.Weight
+	}
+	suite.Require().Equal(map[string]int32{
+		"nuclio-function-a": 60,
+		"nuclio-function-b": 10,
+		"nuclio-function-c": 30,
+	}, backendWeights)
+
+	// cookie matches and authentication are not supported
+	apiGateway.Spec.Upstreams[1].Match.Cookie = "canary"
+	_, err = suite.client.CreateOrUpdate(context.Background(), apiGateway)
+	suite.Require().Error(err)
+
+	apiGateway.Spec.Upstreams[1].Match.Cookie = ""
+	apiGateway.Spec.AuthenticationMode = ingress.AuthenticationModeAccessKey
+	_, err = suite.client.CreateOrUpdate(context.Background(), apiGateway)
+	suite.Require().Error(err)
+
+	// delete the api gateway, its HTTPRoute should be removed
+	suite.client.Delete(context.Background(), "test-namespace", "test-name")
+	httpRoutes, err := httpRouteManager.List(context.Background(), "test-namespace", "")
+	suite.Require().NoError(err)
+	suite.Require().Empty(httpRoutes)
+}
+
+func (suite *lazyTestSuite) createHTTPRouteManager(platformConfig *platformconfig.Config) *httproute.Manager {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			httproute.GroupVersionResource: "HTTPRouteList",
+		})
+
+	httpRouteManager, err := httproute.NewManager(suite.logger, dynamicClient, platformConfig)
+	suite.Require().NoError(err)
+
+	return httpRouteManager
+}
+
 func TestLazyTestSuite(t *testing.T) {
 	suite.Run(t, new(lazyTestSuite))
 }
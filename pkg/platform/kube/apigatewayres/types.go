@@ -20,6 +20,7 @@ import (
 	"context"
 
 	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
+	"github.com/nuclio/nuclio/pkg/platform/kube/httproute"
 	"github.com/nuclio/nuclio/pkg/platform/kube/ingress"
 )
 
@@ -46,4 +47,7 @@ type Resources interface {
 
 	// IngressResourcesMap returns a mapping of [ string(ingress's name) -> *ingress.Resources ]
 	IngressResourcesMap() map[string]*ingress.Resources
+
+	// HTTPRoute returns the HTTPRoute of the api gateway, when HTTPRoutes are generated instead of ingresses
+	HTTPRoute() *httproute.HTTPRoute
 }
@@ -27,11 +27,15 @@ import (
 	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
 	"github.com/nuclio/nuclio/pkg/platform/kube/client/clientset/versioned/fake"
 	"github.com/nuclio/nuclio/pkg/platform/kube/functionres"
+	"github.com/nuclio/nuclio/pkg/platform/kube/httproute"
+	"github.com/nuclio/nuclio/pkg/platform/kube/keda"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 
 	"github.com/nuclio/logger"
 	nucliozap "github.com/nuclio/zap"
 	"github.com/stretchr/testify/suite"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 )
 
@@ -66,9 +70,19 @@ func (suite *ControllerTestSuite) SetupTest() {
 	suite.k8sClientSet = k8sfake.NewSimpleClientset()
 	suite.functionClientSet = fake.NewSimpleClientset()
 
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+
+	httpRouteManager, err := httproute.NewManager(suite.logger, dynamicClient, platformConfig)
+	suite.Require().NoError(err)
+
+	kedaManager, err := keda.NewManager(suite.logger, dynamicClient, platformConfig)
+	suite.Require().NoError(err)
+
 	functionresClient, err := functionres.NewLazyClient(suite.logger,
 		suite.k8sClientSet,
-		suite.functionClientSet)
+		suite.functionClientSet,
+		httpRouteManager,
+		kedaManager)
 	suite.Require().NoError(err)
 
 	// create controller
@@ -36,6 +36,8 @@ import (
 	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
 	"github.com/nuclio/nuclio/pkg/platform/kube/client"
 	nuclioioclient "github.com/nuclio/nuclio/pkg/platform/kube/client/clientset/versioned"
+	"github.com/nuclio/nuclio/pkg/platform/kube/httproute"
+	"github.com/nuclio/nuclio/pkg/platform/kube/keda"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/processor"
 	"github.com/nuclio/nuclio/pkg/processor/config"
@@ -68,6 +70,7 @@ const (
 	ContainerHTTPPortName   = "http"
 	containerMetricPort     = 8090
 	containerMetricPortName = "metrics"
+	containerGRPCPortName   = "grpc"
 )
 
 type deploymentResourceMethod string
@@ -85,6 +88,8 @@ type lazyClient struct {
 	logger                        logger.Logger
 	kubeClientSet                 kubernetes.Interface
 	nuclioClientSet               nuclioioclient.Interface
+	httpRouteManager              *httproute.Manager
+	kedaManager                   *keda.Manager
 	classLabels                   labels.Set
 	platformConfigurationProvider PlatformConfigurationProvider
 	nodeScaleUpSleepTimeout       time.Duration
@@ -92,13 +97,17 @@ type lazyClient struct {
 
 func NewLazyClient(parentLogger logger.Logger,
 	kubeClientSet kubernetes.Interface,
-	nuclioClientSet nuclioioclient.Interface) (Client, error) {
+	nuclioClientSet nuclioioclient.Interface,
+	httpRouteManager *httproute.Manager,
+	kedaManager *keda.Manager) (Client, error) {
 
 	newClient := lazyClient{
-		logger:          parentLogger.GetChild("functionres"),
-		kubeClientSet:   kubeClientSet,
-		nuclioClientSet: nuclioClientSet,
-		classLabels:     make(labels.Set),
+		logger:           parentLogger.GetChild("functionres"),
+		kubeClientSet:    kubeClientSet,
+		nuclioClientSet:  nuclioClientSet,
+		httpRouteManager: httpRouteManager,
+		kedaManager:      kedaManager,
+		classLabels:      make(labels.Set),
 
 		// TODO: make this value configurable
 		// from k8s docs (https://github.com/kubernetes/autoscaler/blob/master/cluster-autoscaler/FAQ.md#how-does-scale-up-work):
@@ -223,11 +232,28 @@ func (lc *lazyClient) CreateOrUpdate(ctx context.Context,
 		return nil, errors.Wrap(err, "Failed to create/update deployment")
 	}
 
-	// create or update the HPA
-	if resources.horizontalPodAutoscaler, err = lc.createOrUpdateHorizontalPodAutoscaler(ctx,
-		functionLabels,
-		function); err != nil {
-		return nil, errors.Wrap(err, "Failed to create/update HPA")
+	// stream triggered functions may be scaled by KEDA instead of by an HPA
+	if lc.kedaManager.ScalesFunction(function) {
+		if err := lc.createOrUpdateScaledObject(ctx, functionLabels, function); err != nil {
+			return nil, errors.Wrap(err, "Failed to create/update ScaledObject")
+		}
+	} else {
+
+		// the function may have been scaled by KEDA before (e.g. its stream trigger was removed)
+		if lc.kedaManager.Enabled() {
+			if err := lc.kedaManager.DeleteByName(ctx,
+				function.Namespace,
+				kube.ScaledObjectNameFromFunctionName(function.Name)); err != nil {
+				return nil, errors.Wrap(err, "Failed to delete ScaledObject")
+			}
+		}
+
+		// create or update the HPA
+		if resources.horizontalPodAutoscaler, err = lc.createOrUpdateHorizontalPodAutoscaler(ctx,
+			functionLabels,
+			function); err != nil {
+			return nil, errors.Wrap(err, "Failed to create/update HPA")
+		}
 	}
 
 	// create or update ingress
@@ -447,15 +473,25 @@ func (lc *lazyClient) Delete(ctx context.Context, namespace string, name string)
 		lc.logger.DebugWithCtx(ctx, "Deleted ingress", "namespace", namespace, "ingressName", ingressName)
 	}
 
-	// Delete HPA if exists
-	hpaName := kube.HPANameFromFunctionName(name)
-	err = lc.kubeClientSet.AutoscalingV2().HorizontalPodAutoscalers(namespace).Delete(ctx, hpaName, deleteOptions)
-	if err != nil {
-		if !apierrors.IsNotFound(err) {
-			return errors.Wrap(err, "Failed to delete HPA")
+	// Delete HTTPRoutes
+	if lc.httpRouteManager.Enabled() {
+		if err := lc.deleteHTTPRoutes(ctx, namespace, name, nil); err != nil {
+			return errors.Wrap(err, "Failed to delete HTTPRoutes")
+		}
+	}
+
+	// Delete ScaledObject if exists
+	if lc.kedaManager.Enabled() {
+		if err := lc.kedaManager.DeleteByName(ctx,
+			namespace,
+			kube.ScaledObjectNameFromFunctionName(name)); err != nil {
+			return errors.Wrap(err, "Failed to delete ScaledObject")
 		}
-	} else {
-		lc.logger.DebugWithCtx(ctx, "Deleted HPA", "namespace", namespace, "hpaName", hpaName)
+	}
+
+	// Delete HPA if exists
+	if err := lc.deleteHorizontalPodAutoscaler(ctx, namespace, name); err != nil {
+		return errors.Wrap(err, "Failed to delete HPA")
 	}
 
 	// Delete Service if exists
@@ -527,6 +563,10 @@ func (lc *lazyClient) SetPlatformConfigurationProvider(platformConfigurationProv
 func (lc *lazyClient) waitFunctionIngressReadiness(ctx context.Context,
 	function *nuclioio.NuclioFunction) error {
 
+	if lc.httpRouteManager.Enabled() {
+		return lc.waitFunctionHTTPRoutesReadiness(ctx, function)
+	}
+
 	functionIngresses, err := lc.kubeClientSet.NetworkingV1().
 		Ingresses(function.Namespace).
 		Get(ctx, kube.IngressNameFromFunctionName(function.Name), metav1.GetOptions{})
@@ -548,6 +588,34 @@ func (lc *lazyClient) waitFunctionIngressReadiness(ctx context.Context,
 	return errors.New("Function ingress is not ready yet")
 }
 
+func (lc *lazyClient) waitFunctionHTTPRoutesReadiness(ctx context.Context,
+	function *nuclioio.NuclioFunction) error {
+
+	httpRoutes, err := lc.httpRouteManager.List(ctx,
+		function.Namespace,
+		fmt.Sprintf("%s=%s", common.NuclioResourceLabelKeyFunctionName, function.Name))
+	if err != nil {
+		return errors.Wrap(err, "Failed to list function HTTPRoutes")
+	}
+
+	if len(httpRoutes) == 0 {
+		return errors.New("Function HTTPRoutes were not found")
+	}
+
+	for _, httpRoute := range httpRoutes {
+		if !httproute.IsAccepted(httpRoute) {
+			return errors.Errorf("Function HTTPRoute %s was not accepted yet", httpRoute.Name)
+		}
+	}
+
+	lc.logger.DebugWithCtx(ctx,
+		"Function HTTPRoutes were accepted",
+		"functionName", function.Name,
+		"functionNamespace", function.Namespace)
+
+	return nil
+}
+
 func (lc *lazyClient) waitFunctionDeploymentReadiness(ctx context.Context,
 	function *nuclioio.NuclioFunction,
 	functionResourcesCreateOrUpdateTimestamp time.Time) (error, functionconfig.FunctionState) {
@@ -1461,10 +1529,61 @@ func (lc *lazyClient) createOrUpdateHorizontalPodAutoscaler(ctx context.Context,
 	return resource.(*autosv2.HorizontalPodAutoscaler), err
 }
 
+func (lc *lazyClient) deleteHorizontalPodAutoscaler(ctx context.Context, namespace string, functionName string) error {
+	propagationPolicy := metav1.DeletePropagationForeground
+	deleteOptions := metav1.DeleteOptions{
+		PropagationPolicy: &propagationPolicy,
+	}
+
+	hpaName := kube.HPANameFromFunctionName(functionName)
+	if err := lc.kubeClientSet.AutoscalingV2().
+		HorizontalPodAutoscalers(namespace).
+		Delete(ctx, hpaName, deleteOptions); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	lc.logger.DebugWithCtx(ctx, "Deleted HPA", "namespace", namespace, "hpaName", hpaName)
+	return nil
+}
+
+func (lc *lazyClient) createOrUpdateScaledObject(ctx context.Context,
+	functionLabels labels.Set,
+	function *nuclioio.NuclioFunction) error {
+
+	// KEDA manages an HPA of its own, and rejects scaling a deployment that is already scaled by another one
+	if err := lc.deleteHorizontalPodAutoscaler(ctx, function.Namespace, function.Name); err != nil {
+		return errors.Wrap(err, "Failed to delete HPA")
+	}
+
+	scaledObject, err := lc.kedaManager.GenerateResources(function, functionLabels)
+	if err != nil {
+		return errors.Wrap(err, "Failed to generate ScaledObject")
+	}
+
+	if _, err := lc.kedaManager.CreateOrUpdate(ctx, scaledObject); err != nil {
+		return errors.Wrap(err, "Failed to create/update ScaledObject")
+	}
+
+	return nil
+}
+
 func (lc *lazyClient) createOrUpdateIngress(ctx context.Context,
 	functionLabels labels.Set,
 	function *nuclioio.NuclioFunction) (*networkingv1.Ingress, error) {
 
+	// HTTPRoutes replace the function ingress, which may remain from before they were enabled
+	if lc.httpRouteManager.Enabled() {
+		if err := lc.deleteIngress(ctx, function.Namespace, function.Name); err != nil {
+			return nil, errors.Wrap(err, "Failed to delete ingress")
+		}
+
+		return nil, lc.createOrUpdateHTTPRoutes(ctx, functionLabels, function)
+	}
+
 	getIngress := func() (interface{}, error) {
 		return lc.kubeClientSet.NetworkingV1().
 			Ingresses(function.Namespace).
@@ -1554,6 +1673,166 @@ func (lc *lazyClient) createOrUpdateIngress(ctx context.Context,
 	return resource.(*networkingv1.Ingress), err
 }
 
+func (lc *lazyClient) deleteIngress(ctx context.Context, namespace string, functionName string) error {
+	propagationPolicy := metav1.DeletePropagationForeground
+	deleteOptions := metav1.DeleteOptions{
+		PropagationPolicy: &propagationPolicy,
+	}
+
+	ingressName := kube.IngressNameFromFunctionName(functionName)
+	if err := lc.kubeClientSet.NetworkingV1().
+		Ingresses(namespace).
+		Delete(ctx, ingressName, deleteOptions); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	lc.logger.DebugWithCtx(ctx, "Deleted ingress", "namespace", namespace, "ingressName", ingressName)
+	return nil
+}
+
+// createOrUpdateHTTPRoutes creates an HTTPRoute per host of the function ingresses, as an HTTPRoute's
+// rules apply to all of its hostnames, and removes the HTTPRoutes of hosts that are no longer used
+func (lc *lazyClient) createOrUpdateHTTPRoutes(ctx context.Context,
+	functionLabels labels.Set,
+	function *nuclioio.NuclioFunction) error {
+
+	httpRouteSpecs, err := lc.generateHTTPRouteSpecs(functionLabels, function)
+	if err != nil {
+		return errors.Wrap(err, "Failed to generate HTTPRoute specs")
+	}
+
+	var httpRouteNames []string
+	for _, httpRouteSpec := range httpRouteSpecs {
+		httpRoute, err := lc.httpRouteManager.GenerateResources(httpRouteSpec)
+		if err != nil {
+			return errors.Wrap(err, "Failed to generate HTTPRoute")
+		}
+
+		if _, err := lc.httpRouteManager.CreateOrUpdate(ctx, httpRoute); err != nil {
+			return errors.Wrap(err, "Failed to create/update HTTPRoute")
+		}
+
+		httpRouteNames = append(httpRouteNames, httpRoute.Name)
+	}
+
+	return lc.deleteHTTPRoutes(ctx, function.Namespace, function.Name, httpRouteNames)
+}
+
+func (lc *lazyClient) generateHTTPRouteSpecs(functionLabels labels.Set,
+	function *nuclioio.NuclioFunction) ([]httproute.Spec, error) {
+
+	// take the annotations of the first HTTP trigger, like the function ingress does
+	var annotations map[string]string
+	for _, httpTrigger := range functionconfig.GetTriggersByKind(function.Spec.Triggers, "http") {
+		annotations = httpTrigger.Annotations
+		break
+	}
+
+	// group the function ingresses by host
+	ingressesByHost := map[string][]functionconfig.Ingress{}
+	for _, ingress := range functionconfig.GetFunctionIngresses(client.NuclioioToFunctionConfig(function)) {
+		if err := lc.enrichIngressWithDefaultValues(&ingress); err != nil {
+			return nil, errors.Wrap(err, "Failed to enrich ingress with default values")
+		}
+
+		ingressesByHost[ingress.Host] = append(ingressesByHost[ingress.Host], ingress)
+	}
+
+	// sort the hosts so that route names are kept across reconciliations
+	var hosts []string
+	for host := range ingressesByHost {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	var httpRouteSpecs []httproute.Spec
+	for hostIndex, host := range hosts {
+		httpRouteSpec := httproute.Spec{
+			Name:        kube.HTTPRouteNameFromFunctionName(function.Name, hostIndex),
+			Namespace:   function.Namespace,
+			Annotations: annotations,
+			Labels:      functionLabels,
+		}
+
+		if host != "" {
+			httpRouteSpec.Hostnames = []string{host}
+		}
+
+		for _, ingress := range ingressesByHost[host] {
+			if ingress.TLS.SecretName != "" {
+				httpRouteSpec.TLS = true
+			}
+
+			pathMatchType := httproute.PathMatchPathPrefix
+			if ingress.PathType == networkingv1.PathTypeExact {
+				pathMatchType = httproute.PathMatchExact
+			}
+
+			for _, path := range ingress.Paths {
+				formattedPath, err := lc.formatIngressPattern(path, functionLabels, function)
+				if err != nil {
+					return nil, errors.Wrap(err, "Failed to format ingress pattern")
+				}
+
+				httpRouteSpec.Rules = append(httpRouteSpec.Rules, httproute.RuleSpec{
+					Path:          formattedPath,
+					PathMatchType: pathMatchType,
+					Backends: []httproute.Backend{
+						{
+							ServiceName: kube.ServiceNameFromFunctionName(function.Name),
+							ServicePort: abstract.FunctionContainerHTTPPort,
+						},
+					},
+
+					// set nuclio target header, so the function is woken up if scaled to zero
+					RequestHeaders: map[string]string{
+						"X-Nuclio-Target": function.Name,
+					},
+				})
+			}
+		}
+
+		// ingresses without paths have nothing to route
+		if len(httpRouteSpec.Rules) == 0 {
+			continue
+		}
+
+		httpRouteSpecs = append(httpRouteSpecs, httpRouteSpec)
+	}
+
+	return httpRouteSpecs, nil
+}
+
+// deleteHTTPRoutes deletes the HTTPRoutes of the function, except for the given ones
+func (lc *lazyClient) deleteHTTPRoutes(ctx context.Context,
+	namespace string,
+	functionName string,
+	httpRouteNamesToKeep []string) error {
+
+	httpRoutes, err := lc.httpRouteManager.List(ctx,
+		namespace,
+		fmt.Sprintf("%s=%s", common.NuclioResourceLabelKeyFunctionName, functionName))
+	if err != nil {
+		return errors.Wrap(err, "Failed to list function HTTPRoutes")
+	}
+
+	for _, httpRoute := range httpRoutes {
+		if common.StringSliceContainsString(httpRouteNamesToKeep, httpRoute.Name) {
+			continue
+		}
+
+		if err := lc.httpRouteManager.DeleteByName(ctx, namespace, httpRoute.Name); err != nil {
+			return errors.Wrap(err, "Failed to delete HTTPRoute")
+		}
+	}
+
+	return nil
+}
+
 func (lc *lazyClient) deleteCronJobs(ctx context.Context, functionName, functionNamespace string) error {
 	lc.logger.InfoWithCtx(ctx, "Deleting function cron jobs", "functionName", functionName)
 
@@ -1869,6 +2148,9 @@ func (lc *lazyClient) populateServiceSpec(ctx context.Context,
 
 	// make sure the ports exist (add if not)
 	spec.Ports = lc.ensureServicePortsExist(spec.Ports, platformServicePorts)
+
+	// expose the grpc trigger's port, replacing the one previously exposed as it may have changed or been removed
+	spec.Ports = lc.ensureGRPCServicePort(spec.Ports, function.Spec.GetGRPCPort())
 }
 
 func (lc *lazyClient) getServicePortsFromPlatform(platformConfiguration *platformconfig.Config) []v1.ServicePort {
@@ -1929,6 +2211,25 @@ func (lc *lazyClient) ensureServicePortsExist(to []v1.ServicePort, from []v1.Ser
 	return to
 }
 
+func (lc *lazyClient) ensureGRPCServicePort(servicePorts []v1.ServicePort, grpcPort int) []v1.ServicePort {
+	var ensuredServicePorts []v1.ServicePort
+
+	for _, servicePort := range servicePorts {
+		if servicePort.Name != containerGRPCPortName {
+			ensuredServicePorts = append(ensuredServicePorts, servicePort)
+		}
+	}
+
+	if grpcPort != 0 {
+		ensuredServicePorts = append(ensuredServicePorts, v1.ServicePort{
+			Name: containerGRPCPortName,
+			Port: int32(grpcPort),
+		})
+	}
+
+	return ensuredServicePorts
+}
+
 func (lc *lazyClient) getCronTriggerInvocationURL(resources Resources, namespace string) (string, error) {
 	functionService, err := resources.Service()
 	if err != nil {
@@ -1951,6 +2252,7 @@ func (lc *lazyClient) generateCronTriggerCronJobSpec(ctx context.Context,
 	type cronAttributes struct {
 		Schedule          string
 		Interval          string
+		Timezone          string
 		ConcurrencyPolicy string
 		JobBackoffLimit   int32
 		Event             cron.Event
@@ -1970,6 +2272,10 @@ func (lc *lazyClient) generateCronTriggerCronJobSpec(ctx context.Context,
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to normalize cron schedule")
 		}
+
+		if attributes.Timezone != "" {
+			spec.TimeZone = &attributes.Timezone
+		}
 	}
 
 	// generate a string containing all the headers with --header flag as prefix, to be used by curl later
@@ -2285,6 +2591,15 @@ func (lc *lazyClient) populateDeploymentContainer(ctx context.Context,
 		})
 	}
 
+	// if the function has a grpc trigger, add the port it listens on
+	if grpcPort := function.Spec.GetGRPCPort(); grpcPort != 0 {
+		container.Ports = append(container.Ports, v1.ContainerPort{
+			Name:          containerGRPCPortName,
+			ContainerPort: int32(grpcPort),
+			Protocol:      v1.ProtocolTCP,
+		})
+	}
+
 	container.ReadinessProbe = &v1.Probe{
 		ProbeHandler: v1.ProbeHandler{
 			HTTPGet: &v1.HTTPGetAction{
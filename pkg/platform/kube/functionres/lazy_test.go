@@ -27,12 +27,16 @@ import (
 	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platform/abstract"
+	"github.com/nuclio/nuclio/pkg/platform/kube"
 	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
 	nuclioiofake "github.com/nuclio/nuclio/pkg/platform/kube/client/clientset/versioned/fake"
+	"github.com/nuclio/nuclio/pkg/platform/kube/httproute"
+	"github.com/nuclio/nuclio/pkg/platform/kube/keda"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 
 	"dario.cat/mergo"
 	"github.com/google/go-cmp/cmp"
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/zap"
 	"github.com/stretchr/testify/suite"
@@ -40,8 +44,12 @@ import (
 	autosv2 "k8s.io/api/autoscaling/v2"
 	"k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	apiresource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/fake"
 )
 
@@ -69,17 +77,20 @@ func (suite *lazyTestSuite) SetupTest() {
 	suite.logger, err = nucliozap.NewNuclioZapTest("test")
 	suite.Require().NoError(err)
 
+	// use the default platform configuration
+	defaultPlatformConfiguration, err := platformconfig.NewPlatformConfig("")
+	suite.Require().NoError(err)
+
 	// create client
 	lazyClientInstance, err := NewLazyClient(suite.logger,
 		fake.NewSimpleClientset(),
-		nuclioiofake.NewSimpleClientset())
+		nuclioiofake.NewSimpleClientset(),
+		suite.createHTTPRouteManager(defaultPlatformConfiguration),
+		suite.createKEDAManager(defaultPlatformConfiguration))
 	suite.Require().NoError(err)
 	suite.client = lazyClientInstance.(*lazyClient)
 	suite.ctx = context.Background()
 
-	// use the default platform configuration
-	defaultPlatformConfiguration, err := platformconfig.NewPlatformConfig("")
-	suite.Require().NoError(err)
 	suite.client.SetPlatformConfigurationProvider(&mockedPlatformConfigurationProvider{
 		platformConfiguration: defaultPlatformConfiguration,
 	})
@@ -335,6 +346,196 @@ func (suite *lazyTestSuite) TestEnrichIngressWithDefaultTLSSecret() {
 	suite.Require().Equal("true", ingressInstance.Annotations[sslRedirectAnnotation])
 }
 
+func (suite *lazyTestSuite) TestCreateOrUpdateHTTPRoutes() {
+	platformConfiguration := &platformconfig.Config{
+		IngressConfig: platformconfig.IngressConfig{
+			Kind: platformconfig.IngressKindHTTPRoute,
+			GatewayAPI: platformconfig.GatewayAPIConfig{
+				GatewayName:       "nuclio-gateway",
+				GatewayNamespace:  "gateways",
+				HTTPListenerName:  "http",
+				HTTPSListenerName: "https",
+			},
+		},
+	}
+	suite.client.SetPlatformConfigurationProvider(&mockedPlatformConfigurationProvider{
+		platformConfiguration: platformConfiguration,
+	})
+	suite.client.httpRouteManager = suite.createHTTPRouteManager(platformConfiguration)
+
+	defaultHTTPTrigger := functionconfig.GetDefaultHTTPTrigger()
+	defaultHTTPTrigger.Attributes = map[string]interface{}{
+		"ingresses": map[string]interface{}{
+			"0": map[string]interface{}{
+				"host":  "a.com",
+				"paths": []string{"/", "/v1"},
+			},
+			"1": map[string]interface{}{
+				"host":       "b.com",
+				"paths":      []string{"/b"},
+				"secretName": "b-tls",
+			},
+		},
+	}
+	function := nuclioio.NuclioFunction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-function",
+			Namespace: "default",
+		},
+		Spec: functionconfig.Spec{
+			Triggers: map[string]functionconfig.Trigger{
+				defaultHTTPTrigger.Name: defaultHTTPTrigger,
+			},
+		},
+	}
+	functionLabels := map[string]string{
+		common.NuclioResourceLabelKeyFunctionName: function.Name,
+	}
+
+	// an ingress created before HTTPRoutes were enabled
+	_, err := suite.client.kubeClientSet.NetworkingV1().
+		Ingresses(function.Namespace).
+		Create(suite.ctx, &networkingv1.Ingress{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      kube.IngressNameFromFunctionName(function.Name),
+				Namespace: function.Namespace,
+			},
+		}, metav1.CreateOptions{})
+	suite.Require().NoError(err)
+
+	// HTTPRoutes replace the ingress, which should be removed
+	ingressInstance, err := suite.client.createOrUpdateIngress(suite.ctx, functionLabels, &function)
+	suite.Require().NoError(err)
+	suite.Require().Nil(ingressInstance)
+
+	_, err = suite.client.kubeClientSet.NetworkingV1().
+		Ingresses(function.Namespace).
+		Get(suite.ctx, kube.IngressNameFromFunctionName(function.Name), metav1.GetOptions{})
+	suite.Require().True(apierrors.IsNotFound(err))
+
+	httpRoutes := suite.getHTTPRoutes(function.Namespace)
+	suite.Require().Len(httpRoutes, 2)
+
+	firstHTTPRoute := httpRoutes["nuclio-my-function"]
+	suite.Require().NotNil(firstHTTPRoute)
+	suite.Require().Equal([]string{"a.com"}, firstHTTPRoute.Spec.Hostnames)
+	suite.Require().Equal([]httproute.ParentReference{
+		{Name: "nuclio-gateway", Namespace: "gateways", SectionName: "http"},
+	}, firstHTTPRoute.Spec.ParentRefs)
+	suite.Require().Len(firstHTTPRoute.Spec.Rules, 2)
+
+	for _, rule := range firstHTTPRoute.Spec.Rules {
+		suite.Require().Equal(httproute.PathMatchPathPrefix, rule.Matches[0].Path.Type)
+		suite.Require().Len(rule.BackendRefs, 1)
+		suite.Require().Equal("nuclio-my-function", rule.BackendRefs[0].Name)
+		suite.Require().Equal(int32(abstract.FunctionContainerHTTPPort), *rule.BackendRefs[0].Port)
+		suite.Require().Equal([]httproute.HTTPHeader{{Name: "X-Nuclio-Target", Value: "my-function"}},
+			rule.Filters[0].RequestHeaderModifier.Set)
+	}
+
+	// TLS hosts attach to the HTTPS listener as well
+	secondHTTPRoute := httpRoutes["nuclio-my-function-2"]
+	suite.Require().NotNil(secondHTTPRoute)
+	suite.Require().Equal([]string{"b.com"}, secondHTTPRoute.Spec.Hostnames)
+	suite.Require().Equal([]httproute.ParentReference{
+		{Name: "nuclio-gateway", Namespace: "gateways", SectionName: "https"},
+		{Name: "nuclio-gateway", Namespace: "gateways", SectionName: "http"},
+	}, secondHTTPRoute.Spec.ParentRefs)
+	suite.Require().Equal("/b", secondHTTPRoute.Spec.Rules[0].Matches[0].Path.Value)
+
+	// remove a host, its HTTPRoute should be removed
+	delete(defaultHTTPTrigger.Attributes["ingresses"].(map[string]interface{}), "1")
+	_, err = suite.client.createOrUpdateIngress(suite.ctx, functionLabels, &function)
+	suite.Require().NoError(err)
+
+	httpRoutes = suite.getHTTPRoutes(function.Namespace)
+	suite.Require().Len(httpRoutes, 1)
+	suite.Require().Contains(httpRoutes, "nuclio-my-function")
+
+	// delete the function, its HTTPRoutes should be removed
+	err = suite.client.Delete(suite.ctx, function.Namespace, function.Name)
+	suite.Require().NoError(err)
+	suite.Require().Empty(suite.getHTTPRoutes(function.Namespace))
+}
+
+func (suite *lazyTestSuite) TestCreateOrUpdateScaledObject() {
+	platformConfiguration, err := platformconfig.NewPlatformConfig("")
+	suite.Require().NoError(err)
+	platformConfiguration.Kube.KEDA.Enabled = true
+
+	suite.client.SetPlatformConfigurationProvider(&mockedPlatformConfigurationProvider{
+		platformConfiguration: platformConfiguration,
+	})
+	suite.client.kedaManager = suite.createKEDAManager(platformConfiguration)
+
+	zero := 0
+	four := 4
+	function := nuclioio.NuclioFunction{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "my-function",
+			Namespace: "default",
+		},
+		Spec: functionconfig.Spec{
+			MinReplicas: &zero,
+			MaxReplicas: &four,
+			Triggers: map[string]functionconfig.Trigger{
+				"kafka": {
+					Name: "kafka",
+					Kind: "kafka-cluster",
+					Attributes: map[string]interface{}{
+						"brokers":       []string{"broker:9092"},
+						"topics":        []string{"topic"},
+						"consumerGroup": "my-group",
+					},
+				},
+			},
+		},
+	}
+
+	// the ScaledObject replaces the HPA
+	resources, err := suite.client.CreateOrUpdate(suite.ctx, &function, "")
+	suite.Require().NoError(err)
+
+	horizontalPodAutoscaler, err := resources.HorizontalPodAutoscaler()
+	suite.Require().NoError(err)
+	suite.Require().Nil(horizontalPodAutoscaler)
+
+	scaledObject, err := suite.client.kedaManager.Get(suite.ctx, function.Namespace, "nuclio-my-function")
+	suite.Require().NoError(err)
+	suite.Require().Equal(int32(0), *scaledObject.Spec.MinReplicaCount)
+	suite.Require().Equal(int32(4), *scaledObject.Spec.MaxReplicaCount)
+	suite.Require().Len(scaledObject.Spec.Triggers, 1)
+	suite.Require().Equal("kafka", scaledObject.Spec.Triggers[0].Type)
+
+	// the scaler metadata can't be resolved without a consumer group, the function should fall back to an HPA
+	function.Spec.Triggers["kafka"].Attributes["consumerGroup"] = ""
+	resources, err = suite.client.CreateOrUpdate(suite.ctx, &function, "")
+	suite.Require().NoError(err)
+
+	horizontalPodAutoscaler, err = resources.HorizontalPodAutoscaler()
+	suite.Require().NoError(err)
+	suite.Require().NotNil(horizontalPodAutoscaler)
+
+	_, err = suite.client.kedaManager.Get(suite.ctx, function.Namespace, "nuclio-my-function")
+	suite.Require().True(apierrors.IsNotFound(errors.RootCause(err)))
+
+	// restore the consumer group, then remove the stream trigger, the function should be scaled by an HPA again
+	function.Spec.Triggers["kafka"].Attributes["consumerGroup"] = "my-group"
+	_, err = suite.client.CreateOrUpdate(suite.ctx, &function, "")
+	suite.Require().NoError(err)
+
+	function.Spec.Triggers = map[string]functionconfig.Trigger{}
+	resources, err = suite.client.CreateOrUpdate(suite.ctx, &function, "")
+	suite.Require().NoError(err)
+
+	horizontalPodAutoscaler, err = resources.HorizontalPodAutoscaler()
+	suite.Require().NoError(err)
+	suite.Require().NotNil(horizontalPodAutoscaler)
+
+	_, err = suite.client.kedaManager.Get(suite.ctx, function.Namespace, "nuclio-my-function")
+	suite.Require().True(apierrors.IsNotFound(errors.RootCause(err)))
+}
+
 func (suite *lazyTestSuite) TestNoChanges() {
 	one := 1
 	volumeName := "my-volume"
@@ -690,6 +891,31 @@ func (suite *lazyTestSuite) TestPlatformServicePorts() {
 	suite.Require().Len(toServicePorts, 2)
 }
 
+func (suite *lazyTestSuite) TestGRPCServicePort() {
+	httpServicePort := v1.ServicePort{
+		Name: ContainerHTTPPortName,
+		Port: int32(abstract.FunctionContainerHTTPPort),
+	}
+
+	// should be added
+	servicePorts := suite.client.ensureGRPCServicePort([]v1.ServicePort{httpServicePort}, 9090)
+	suite.Require().Equal([]v1.ServicePort{
+		httpServicePort,
+		{Name: containerGRPCPortName, Port: 9090},
+	}, servicePorts)
+
+	// should be replaced
+	servicePorts = suite.client.ensureGRPCServicePort(servicePorts, 9191)
+	suite.Require().Equal([]v1.ServicePort{
+		httpServicePort,
+		{Name: containerGRPCPortName, Port: 9191},
+	}, servicePorts)
+
+	// should be removed
+	servicePorts = suite.client.ensureGRPCServicePort(servicePorts, 0)
+	suite.Require().Equal([]v1.ServicePort{httpServicePort}, servicePorts)
+}
+
 func (suite *lazyTestSuite) TestEnrichDeploymentFromPlatformConfiguration() {
 	suite.client.SetPlatformConfigurationProvider(&mockedPlatformConfigurationProvider{
 		platformConfiguration: &platformconfig.Config{
@@ -923,6 +1149,39 @@ func (suite *lazyTestSuite) TestResolveAutoScaleMetricSpec() {
 	}
 }
 
+func (suite *lazyTestSuite) createHTTPRouteManager(platformConfiguration *platformconfig.Config) *httproute.Manager {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			httproute.GroupVersionResource: "HTTPRouteList",
+		})
+
+	httpRouteManager, err := httproute.NewManager(suite.logger, dynamicClient, platformConfiguration)
+	suite.Require().NoError(err)
+
+	return httpRouteManager
+}
+
+func (suite *lazyTestSuite) createKEDAManager(platformConfiguration *platformconfig.Config) *keda.Manager {
+	kedaManager, err := keda.NewManager(suite.logger,
+		dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+		platformConfiguration)
+	suite.Require().NoError(err)
+
+	return kedaManager
+}
+
+func (suite *lazyTestSuite) getHTTPRoutes(namespace string) map[string]*httproute.HTTPRoute {
+	httpRoutes, err := suite.client.httpRouteManager.List(suite.ctx, namespace, "")
+	suite.Require().NoError(err)
+
+	httpRoutesByName := map[string]*httproute.HTTPRoute{}
+	for _, httpRoute := range httpRoutes {
+		httpRoutesByName[httpRoute.Name] = httpRoute
+	}
+
+	return httpRoutesByName
+}
+
 func (suite *lazyTestSuite) getIngressRuleByHost(rules []networkingv1.IngressRule, host string) *networkingv1.IngressRule {
 	for _, rule := range rules {
 		if rule.Host == host {
@@ -0,0 +1,362 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httproute
+
+import (
+	"context"
+	"sort"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+)
+
+// Manager generates and applies Gateway API HTTPRoutes. The Gateway API types are not part of
+// the kubernetes client set, so routes are applied through the dynamic client
+type Manager struct {
+	logger                logger.Logger
+	dynamicClient         dynamic.Interface
+	platformConfiguration *platformconfig.Config
+}
+
+func NewManager(parentLogger logger.Logger,
+	dynamicClient dynamic.Interface,
+	platformConfiguration *platformconfig.Config) (*Manager, error) {
+	return &Manager{
+		logger:                parentLogger.GetChild("httproute"),
+		dynamicClient:         dynamicClient,
+		platformConfiguration: platformConfiguration,
+	}, nil
+}
+
+// Enabled returns true if HTTPRoutes should be generated instead of ingresses
+func (m *Manager) Enabled() bool {
+	return m.platformConfiguration.IngressConfig.HTTPRoutesEnabled()
+}
+
+func (m *Manager) GenerateResources(spec Spec) (*HTTPRoute, error) {
+	if len(spec.Rules) == 0 {
+		return nil, errors.New("At least one rule must be provided")
+	}
+
+	labels := map[string]string{}
+	for labelKey, labelValue := range spec.Labels {
+		labels[labelKey] = labelValue
+	}
+
+	if spec.APIGatewayName != "" {
+		m.enrichLabels(spec, labels)
+	}
+
+	// like ingresses, hosts are served over TLS when the system has a TLS secret. the certificate
+	// itself is configured on the gateway's HTTPS listener
+	tls := spec.TLS || m.platformConfiguration.IngressConfig.TLSSecret != ""
+
+	route := &HTTPRoute{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: GroupVersionResource.GroupVersion().String(),
+			Kind:       "HTTPRoute",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        spec.Name,
+			Namespace:   spec.Namespace,
+			Annotations: spec.Annotations,
+			Labels:      labels,
+		},
+		Spec: HTTPRouteSpec{
+			ParentRefs: m.resolveParentRefs(tls),
+			Hostnames:  spec.Hostnames,
+		},
+	}
+
+	for _, ruleSpec := range spec.Rules {
+		rule, err := m.generateRule(ruleSpec)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to generate HTTPRoute rule")
+		}
+
+		route.Spec.Rules = append(route.Spec.Rules, *rule)
+	}
+
+	return route, nil
+}
+
+func (m *Manager) CreateOrUpdate(ctx context.Context, route *HTTPRoute) (*HTTPRoute, error) {
+	m.logger.InfoWithCtx(ctx, "Creating/Updating HTTPRoute", "httpRouteName", route.Name)
+
+	routeClient := m.dynamicClient.Resource(GroupVersionResource).Namespace(route.Namespace)
+
+	encodedRoute, err := toUnstructured(route)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode HTTPRoute")
+	}
+
+	appliedRoute, err := routeClient.Create(ctx, encodedRoute, metav1.CreateOptions{})
+	if err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, errors.Wrap(err, "Failed to create HTTPRoute")
+		}
+
+		// if the route already exists - update it
+		m.logger.InfoWithCtx(ctx, "HTTPRoute already exists. Updating it", "httpRouteName", route.Name)
+
+		existingRoute, err := routeClient.Get(ctx, route.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to get existing HTTPRoute")
+		}
+
+		encodedRoute.SetResourceVersion(existingRoute.GetResourceVersion())
+		if appliedRoute, err = routeClient.Update(ctx, encodedRoute, metav1.UpdateOptions{}); err != nil {
+			return nil, errors.Wrap(err, "Failed to update HTTPRoute")
+		}
+
+		m.logger.InfoWithCtx(ctx, "Successfully updated HTTPRoute", "httpRouteName", route.Name)
+	} else {
+		m.logger.InfoWithCtx(ctx, "Successfully created HTTPRoute", "httpRouteName", route.Name)
+	}
+
+	return fromUnstructured(appliedRoute)
+}
+
+func (m *Manager) Get(ctx context.Context, namespace string, name string) (*HTTPRoute, error) {
+	encodedRoute, err := m.dynamicClient.
+		Resource(GroupVersionResource).
+		Namespace(namespace).
+		Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get HTTPRoute")
+	}
+
+	return fromUnstructured(encodedRoute)
+}
+
+// List returns the HTTPRoutes in the namespace matching the given label selector
+func (m *Manager) List(ctx context.Context, namespace string, labelSelector string) ([]*HTTPRoute, error) {
+	encodedRoutes, err := m.dynamicClient.
+		Resource(GroupVersionResource).
+		Namespace(namespace).
+		List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to list HTTPRoutes")
+	}
+
+	var routes []*HTTPRoute
+	for encodedRouteIndex := range encodedRoutes.Items {
+		route, err := fromUnstructured(&encodedRoutes.Items[encodedRouteIndex])
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to decode HTTPRoute")
+		}
+
+		routes = append(routes, route)
+	}
+
+	return routes, nil
+}
+
+// DeleteByName deletes an HTTPRoute by name, doing nothing if it does not exist
+func (m *Manager) DeleteByName(ctx context.Context, namespace string, name string) error {
+	m.logger.InfoWithCtx(ctx, "Deleting HTTPRoute by name", "httpRouteName", name)
+
+	if err := m.dynamicClient.
+		Resource(GroupVersionResource).
+		Namespace(namespace).
+		Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "Failed to delete HTTPRoute")
+		}
+
+		m.logger.DebugWithCtx(ctx, "HTTPRoute was not found. Nothing to delete", "httpRouteName", name)
+
+		return nil
+	}
+
+	m.logger.DebugWithCtx(ctx, "Successfully deleted HTTPRoute", "httpRouteName", name)
+
+	return nil
+}
+
+// IsAccepted returns true once all the gateways the route attaches to have accepted it
+func IsAccepted(route *HTTPRoute) bool {
+	if len(route.Status.Parents) == 0 {
+		return false
+	}
+
+	for _, parentStatus := range route.Status.Parents {
+		if !meta.IsStatusConditionTrue(parentStatus.Conditions, RouteConditionAccepted) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (m *Manager) generateRule(ruleSpec RuleSpec) (*HTTPRouteRule, error) {
+	if len(ruleSpec.Backends) == 0 {
+		return nil, errors.New("At least one backend must be provided")
+	}
+
+	pathMatchType := ruleSpec.PathMatchType
+	if pathMatchType == "" {
+		pathMatchType = PathMatchPathPrefix
+	}
+
+	routeMatch := HTTPRouteMatch{
+		Path: &HTTPPathMatch{
+			Type:  pathMatchType,
+			Value: ruleSpec.Path,
+		},
+	}
+
+	for _, headerMatch := range ruleSpec.HeaderMatches {
+		routeMatch.Headers = append(routeMatch.Headers, HTTPHeaderMatch{
+			Type:  HeaderMatchExact,
+			Name:  headerMatch.Name,
+			Value: headerMatch.Value,
+		})
+	}
+
+	rule := &HTTPRouteRule{
+		Matches: []HTTPRouteMatch{routeMatch},
+	}
+
+	if len(ruleSpec.RequestHeaders) > 0 {
+		headerFilter := &HTTPHeaderFilter{}
+		for headerName, headerValue := range ruleSpec.RequestHeaders {
+			headerFilter.Set = append(headerFilter.Set, HTTPHeader{
+				Name:  headerName,
+				Value: headerValue,
+			})
+		}
+
+		// keep the generated route stable across reconciliations
+		sort.Slice(headerFilter.Set, func(i, j int) bool {
+			return headerFilter.Set[i].Name < headerFilter.Set[j].Name
+		})
+
+		rule.Filters = append(rule.Filters, HTTPRouteFilter{
+			Type:                  FilterRequestHeaderModifier,
+			RequestHeaderModifier: headerFilter,
+		})
+	}
+
+	if ruleSpec.RewriteTarget != "" {
+		pathModifier := &HTTPPathModifier{}
+
+		// only prefix matches can have their prefix replaced
+		switch pathMatchType {
+		case PathMatchPathPrefix:
+			pathModifier.Type = PathModifierReplacePrefixMatch
+			pathModifier.ReplacePrefixMatch = &ruleSpec.RewriteTarget
+		case PathMatchExact:
+			pathModifier.Type = PathModifierReplaceFullPath
+			pathModifier.ReplaceFullPath = &ruleSpec.RewriteTarget
+		default:
+			return nil, errors.Errorf("Rewrite target is not supported with path match type %s", pathMatchType)
+		}
+
+		rule.Filters = append(rule.Filters, HTTPRouteFilter{
+			Type: FilterURLRewrite,
+			URLRewrite: &HTTPURLRewriteFilter{
+				Path: pathModifier,
+			},
+		})
+	}
+
+	for _, backend := range ruleSpec.Backends {
+		servicePort := int32(backend.ServicePort)
+		rule.BackendRefs = append(rule.BackendRefs, HTTPBackendRef{
+			Name:   backend.ServiceName,
+			Port:   &servicePort,
+			Weight: backend.Weight,
+		})
+	}
+
+	return rule, nil
+}
+
+func (m *Manager) resolveParentRefs(tls bool) []ParentReference {
+	gatewayConfig := m.platformConfiguration.IngressConfig.GatewayAPI
+
+	listenerNames := []string{gatewayConfig.HTTPListenerName}
+	if tls {
+		listenerNames = []string{gatewayConfig.HTTPSListenerName}
+
+		// like ingresses, TLS hosts are served over plain HTTP as well unless redirecting to SSL
+		if !m.platformConfiguration.IngressConfig.EnableSSLRedirect {
+			listenerNames = append(listenerNames, gatewayConfig.HTTPListenerName)
+		}
+	}
+
+	var parentRefs []ParentReference
+	for _, listenerName := range listenerNames {
+
+		// no listener name attaches the route to all the gateway's listeners
+		if listenerName == "" {
+			return []ParentReference{
+				{
+					Name:      gatewayConfig.GatewayName,
+					Namespace: gatewayConfig.GatewayNamespace,
+				},
+			}
+		}
+
+		parentRefs = append(parentRefs, ParentReference{
+			Name:        gatewayConfig.GatewayName,
+			Namespace:   gatewayConfig.GatewayNamespace,
+			SectionName: listenerName,
+		})
+	}
+
+	return parentRefs
+}
+
+func (m *Manager) enrichLabels(spec Spec, labels map[string]string) {
+	labels[common.NuclioLabelKeyClass] = "apigateway"
+	labels[common.NuclioLabelKeyApp] = "httproute-manager"
+	labels[common.NuclioResourceLabelKeyApiGatewayName] = spec.APIGatewayName
+	labels[common.NuclioResourceLabelKeyProjectName] = spec.ProjectName
+}
+
+func toUnstructured(route *HTTPRoute) (*unstructured.Unstructured, error) {
+	encodedRoute, err := runtime.DefaultUnstructuredConverter.ToUnstructured(route)
+	if err != nil {
+		return nil, err
+	}
+
+	// status is owned by the gateway controllers
+	delete(encodedRoute, "status")
+
+	return &unstructured.Unstructured{Object: encodedRoute}, nil
+}
+
+func fromUnstructured(encodedRoute *unstructured.Unstructured) (*HTTPRoute, error) {
+	route := &HTTPRoute{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(encodedRoute.Object, route); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode HTTPRoute %s", encodedRoute.GetName())
+	}
+
+	return route, nil
+}
@@ -0,0 +1,196 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httproute
+
+import (
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+type ManagerTestSuite struct {
+	suite.Suite
+	logger                logger.Logger
+	platformConfiguration *platformconfig.Config
+	manager               *Manager
+}
+
+func (suite *ManagerTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.platformConfiguration = &platformconfig.Config{
+		IngressConfig: platformconfig.IngressConfig{
+			Kind: platformconfig.IngressKindHTTPRoute,
+			GatewayAPI: platformconfig.GatewayAPIConfig{
+				GatewayName:       "nuclio-gateway",
+				HTTPListenerName:  "http",
+				HTTPSListenerName: "https",
+			},
+		},
+	}
+
+	suite.manager, err = NewManager(suite.logger,
+		dynamicfake.NewSimpleDynamicClient(runtime.NewScheme()),
+		suite.platformConfiguration)
+	suite.Require().NoError(err)
+}
+
+func (suite *ManagerTestSuite) TestGenerateResourcesParentRefs() {
+	for _, testCase := range []struct {
+		name                string
+		tls                 bool
+		tlsSecret           string
+		enableSSLRedirect   bool
+		noHTTPSListener     bool
+		expectedSectionName []string
+	}{
+		{
+			name:                "plainHTTP",
+			expectedSectionName: []string{"http"},
+		},
+		{
+			name:                "tls",
+			tls:                 true,
+			expectedSectionName: []string{"https", "http"},
+		},
+		{
+			name:                "defaultTLSSecret",
+			tlsSecret:           "system-tls",
+			expectedSectionName: []string{"https", "http"},
+		},
+		{
+			name:                "tlsWithSSLRedirect",
+			tls:                 true,
+			enableSSLRedirect:   true,
+			expectedSectionName: []string{"https"},
+		},
+		{
+			name:                "tlsWithoutHTTPSListener",
+			tls:                 true,
+			enableSSLRedirect:   true,
+			noHTTPSListener:     true,
+			expectedSectionName: []string{""},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.platformConfiguration.IngressConfig.TLSSecret = testCase.tlsSecret
+			suite.platformConfiguration.IngressConfig.EnableSSLRedirect = testCase.enableSSLRedirect
+			suite.platformConfiguration.IngressConfig.GatewayAPI.HTTPSListenerName = "https"
+			if testCase.noHTTPSListener {
+				suite.platformConfiguration.IngressConfig.GatewayAPI.HTTPSListenerName = ""
+			}
+
+			route, err := suite.manager.GenerateResources(Spec{
+				Name:      "nuclio-my-function",
+				Namespace: "default",
+				TLS:       testCase.tls,
+				Rules: []RuleSpec{
+					{
+						Path:     "/",
+						Backends: []Backend{{ServiceName: "nuclio-my-function", ServicePort: 8080}},
+					},
+				},
+			})
+			suite.Require().NoError(err)
+
+			var sectionNames []string
+			for _, parentRef := range route.Spec.ParentRefs {
+				suite.Require().Equal("nuclio-gateway", parentRef.Name)
+				sectionNames = append(sectionNames, parentRef.SectionName)
+			}
+			suite.Require().Equal(testCase.expectedSectionName, sectionNames)
+		})
+	}
+}
+
+func (suite *ManagerTestSuite) TestGenerateResourcesRewriteTarget() {
+	route, err := suite.manager.GenerateResources(Spec{
+		Name:           "nuclio-agw-my-api",
+		Namespace:      "default",
+		APIGatewayName: "my-api",
+		Rules: []RuleSpec{
+			{
+				Path:          "/api",
+				RewriteTarget: "/",
+				Backends:      []Backend{{ServiceName: "nuclio-my-function", ServicePort: 8080}},
+			},
+			{
+				Path:          "/exact",
+				PathMatchType: PathMatchExact,
+				RewriteTarget: "/other",
+				Backends:      []Backend{{ServiceName: "nuclio-my-function", ServicePort: 8080}},
+			},
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("my-api", route.Labels["nuclio.io/apigateway-name"])
+
+	prefixRewrite := route.Spec.Rules[0].Filters[0].URLRewrite.Path
+	suite.Require().Equal(PathModifierReplacePrefixMatch, prefixRewrite.Type)
+	suite.Require().Equal("/", *prefixRewrite.ReplacePrefixMatch)
+
+	exactRewrite := route.Spec.Rules[1].Filters[0].URLRewrite.Path
+	suite.Require().Equal(PathModifierReplaceFullPath, exactRewrite.Type)
+	suite.Require().Equal("/other", *exactRewrite.ReplaceFullPath)
+
+	// rules must route somewhere
+	_, err = suite.manager.GenerateResources(Spec{
+		Name:  "nuclio-agw-my-api",
+		Rules: []RuleSpec{{Path: "/"}},
+	})
+	suite.Require().Error(err)
+}
+
+func (suite *ManagerTestSuite) TestIsAccepted() {
+	route := &HTTPRoute{}
+	suite.Require().False(IsAccepted(route))
+
+	route.Status.Parents = []RouteParentStatus{
+		{
+			ParentRef: ParentReference{Name: "nuclio-gateway"},
+			Conditions: []metav1.Condition{
+				{Type: RouteConditionAccepted, Status: metav1.ConditionTrue},
+			},
+		},
+		{
+			ParentRef: ParentReference{Name: "other-gateway"},
+			Conditions: []metav1.Condition{
+				{Type: RouteConditionAccepted, Status: metav1.ConditionFalse},
+			},
+		},
+	}
+	suite.Require().False(IsAccepted(route))
+
+	route.Status.Parents[1].Conditions[0].Status = metav1.ConditionTrue
+	suite.Require().True(IsAccepted(route))
+}
+
+func TestManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(ManagerTestSuite))
+}
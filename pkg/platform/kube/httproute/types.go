@@ -0,0 +1,162 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httproute
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	RouteConditionAccepted = "Accepted"
+
+	PathMatchExact      = "Exact"
+	PathMatchPathPrefix = "PathPrefix"
+
+	HeaderMatchExact = "Exact"
+
+	FilterRequestHeaderModifier = "RequestHeaderModifier"
+	FilterURLRewrite            = "URLRewrite"
+
+	PathModifierReplaceFullPath    = "ReplaceFullPath"
+	PathModifierReplacePrefixMatch = "ReplacePrefixMatch"
+)
+
+var GroupVersionResource = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1",
+	Resource: "httproutes",
+}
+
+// Spec describes the HTTPRoute to generate
+type Spec struct {
+	Name           string
+	Namespace      string
+	ProjectName    string
+	APIGatewayName string
+	Hostnames      []string
+	Rules          []RuleSpec
+	TLS            bool
+	Annotations    map[string]string
+	Labels         map[string]string
+}
+
+// RuleSpec describes a single routing rule of an HTTPRoute
+type RuleSpec struct {
+	Path           string
+	PathMatchType  string
+	HeaderMatches  []HeaderMatch
+	Backends       []Backend
+	RequestHeaders map[string]string
+	RewriteTarget  string
+}
+
+type HeaderMatch struct {
+	Name  string
+	Value string
+}
+
+type Backend struct {
+	ServiceName string
+	ServicePort int
+	Weight      *int32
+}
+
+// the following is the subset of the Gateway API (gateway.networking.k8s.io/v1) used by nuclio
+
+type HTTPRoute struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   HTTPRouteSpec   `json:"spec"`
+	Status HTTPRouteStatus `json:"status,omitempty"`
+}
+
+type HTTPRouteSpec struct {
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+	Hostnames  []string          `json:"hostnames,omitempty"`
+	Rules      []HTTPRouteRule   `json:"rules,omitempty"`
+}
+
+type ParentReference struct {
+	Name        string `json:"name"`
+	Namespace   string `json:"namespace,omitempty"`
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+type HTTPRouteRule struct {
+	Matches     []HTTPRouteMatch  `json:"matches,omitempty"`
+	Filters     []HTTPRouteFilter `json:"filters,omitempty"`
+	BackendRefs []HTTPBackendRef  `json:"backendRefs,omitempty"`
+}
+
+type HTTPRouteMatch struct {
+	Path    *HTTPPathMatch    `json:"path,omitempty"`
+	Headers []HTTPHeaderMatch `json:"headers,omitempty"`
+}
+
+type HTTPPathMatch struct {
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+type HTTPHeaderMatch struct {
+	Type  string `json:"type,omitempty"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HTTPRouteFilter struct {
+	Type                  string                `json:"type"`
+	RequestHeaderModifier *HTTPHeaderFilter     `json:"requestHeaderModifier,omitempty"`
+	URLRewrite            *HTTPURLRewriteFilter `json:"urlRewrite,omitempty"`
+}
+
+type HTTPHeaderFilter struct {
+	Set []HTTPHeader `json:"set,omitempty"`
+}
+
+type HTTPHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HTTPURLRewriteFilter struct {
+	Path *HTTPPathModifier `json:"path,omitempty"`
+}
+
+type HTTPPathModifier struct {
+	Type               string  `json:"type"`
+	ReplaceFullPath    *string `json:"replaceFullPath,omitempty"`
+	ReplacePrefixMatch *string `json:"replacePrefixMatch,omitempty"`
+}
+
+type HTTPBackendRef struct {
+	Name   string `json:"name"`
+	Port   *int32 `json:"port,omitempty"`
+	Weight *int32 `json:"weight,omitempty"`
+}
+
+type HTTPRouteStatus struct {
+	Parents []RouteParentStatus `json:"parents,omitempty"`
+}
+
+type RouteParentStatus struct {
+	ParentRef      ParentReference    `json:"parentRef"`
+	ControllerName string             `json:"controllerName"`
+	Conditions     []metav1.Condition `json:"conditions,omitempty"`
+}
@@ -0,0 +1,474 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keda
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform/kube"
+	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	scalerTypeKafka         = "kafka"
+	scalerTypeRabbitMQ      = "rabbitmq"
+	scalerTypeNATSJetStream = "nats-jetstream"
+	scalerTypeKinesis       = "aws-kinesis-stream"
+
+	// only jetstream consumers have a lag KEDA can scale by
+	natsModeJetStream = "jetstream"
+)
+
+// the metadata each scaler requires, either derived from the trigger or given in its annotations
+var requiredScalerMetadata = map[string][]string{
+	scalerTypeKafka:         {"bootstrapServers", "consumerGroup"},
+	scalerTypeRabbitMQ:      {"queueName"},
+	scalerTypeNATSJetStream: {"natsServerMonitoringEndpoint", "account", "stream", "consumer"},
+	scalerTypeKinesis:       {"streamName", "awsRegion"},
+}
+
+// Manager generates and applies KEDA ScaledObjects. The KEDA types are not part of the kubernetes
+// client set, so scaled objects are applied through the dynamic client
+type Manager struct {
+	logger                logger.Logger
+	dynamicClient         dynamic.Interface
+	platformConfiguration *platformconfig.Config
+}
+
+func NewManager(parentLogger logger.Logger,
+	dynamicClient dynamic.Interface,
+	platformConfiguration *platformconfig.Config) (*Manager, error) {
+	return &Manager{
+		logger:                parentLogger.GetChild("keda"),
+		dynamicClient:         dynamicClient,
+		platformConfiguration: platformConfiguration,
+	}, nil
+}
+
+// Enabled returns true if stream triggered functions are scaled by KEDA
+func (m *Manager) Enabled() bool {
+	return m.platformConfiguration.Kube.KEDA.Enabled
+}
+
+// ScalesFunction returns true if the function is scaled by a KEDA ScaledObject instead of an HPA. A function
+// whose scaler metadata can't be resolved is scaled by an HPA, with a warning
+func (m *Manager) ScalesFunction(function *nuclioio.NuclioFunction) bool {
+	scalesFunction, err := resolveScalesFunction(m.platformConfiguration, function)
+	if err != nil {
+		m.logger.WarnWith("Function can't be scaled by KEDA, falling back to an HPA",
+			"namespace", function.Namespace,
+			"name", function.Name,
+			"err", errors.RootCause(err).Error())
+	}
+
+	return scalesFunction
+}
+
+// ScalesFunction returns true if the function is scaled by a KEDA ScaledObject instead of an HPA. That is,
+// when KEDA is enabled and the function has a stream trigger whose scaler metadata resolves and is allowed to scale
+func ScalesFunction(platformConfiguration *platformconfig.Config, function *nuclioio.NuclioFunction) bool {
+	scalesFunction, _ := resolveScalesFunction(platformConfiguration, function)
+	return scalesFunction
+}
+
+func (m *Manager) GenerateResources(function *nuclioio.NuclioFunction,
+	functionLabels map[string]string) (*ScaledObject, error) {
+
+	scaleTriggers, err := resolveScaleTriggers(m.platformConfiguration, function)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve scale triggers")
+	}
+
+	if len(scaleTriggers) == 0 {
+		return nil, errors.New("Function has no triggers KEDA can scale by")
+	}
+
+	minReplicas := function.GetComputedMinReplicas()
+	maxReplicas := function.GetComputedMaxReplicas()
+
+	scaledObject := &ScaledObject{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: GroupVersionResource.GroupVersion().String(),
+			Kind:       "ScaledObject",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kube.ScaledObjectNameFromFunctionName(function.Name),
+			Namespace: function.Namespace,
+			Labels:    functionLabels,
+		},
+		Spec: ScaledObjectSpec{
+			ScaleTargetRef: &ScaleTarget{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       kube.DeploymentNameFromFunctionName(function.Name),
+			},
+
+			// a minimum of zero replicas lets KEDA scale the function to zero while its streams are idle
+			MinReplicaCount: &minReplicas,
+			MaxReplicaCount: &maxReplicas,
+			Triggers:        scaleTriggers,
+		},
+	}
+
+	if pollingInterval := int32(m.platformConfiguration.Kube.KEDA.PollingInterval); pollingInterval > 0 {
+		scaledObject.Spec.PollingInterval = &pollingInterval
+	}
+
+	if cooldownPeriod := int32(m.platformConfiguration.Kube.KEDA.CooldownPeriod); cooldownPeriod > 0 {
+		scaledObject.Spec.CooldownPeriod = &cooldownPeriod
+	}
+
+	return scaledObject, nil
+}
+
+func (m *Manager) CreateOrUpdate(ctx context.Context, scaledObject *ScaledObject) (*ScaledObject, error) {
+	m.logger.InfoWithCtx(ctx, "Creating/Updating ScaledObject", "scaledObjectName", scaledObject.Name)
+
+	scaledObjectClient := m.dynamicClient.Resource(GroupVersionResource).Namespace(scaledObject.Namespace)
+
+	encodedScaledObject, err := toUnstructured(scaledObject)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode ScaledObject")
+	}
+
+	appliedScaledObject, err := scaledObjectClient.Create(ctx, encodedScaledObject, metav1.CreateOptions{})
+	if err != nil {
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, errors.Wrap(err, "Failed to create ScaledObject")
+		}
+
+		// if the scaled object already exists - update it
+		m.logger.InfoWithCtx(ctx, "ScaledObject already exists. Updating it",
+			"scaledObjectName", scaledObject.Name)
+
+		existingScaledObject, err := scaledObjectClient.Get(ctx, scaledObject.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to get existing ScaledObject")
+		}
+
+		encodedScaledObject.SetResourceVersion(existingScaledObject.GetResourceVersion())
+		if appliedScaledObject, err = scaledObjectClient.Update(ctx,
+			encodedScaledObject,
+			metav1.UpdateOptions{}); err != nil {
+			return nil, errors.Wrap(err, "Failed to update ScaledObject")
+		}
+
+		m.logger.InfoWithCtx(ctx, "Successfully updated ScaledObject", "scaledObjectName", scaledObject.Name)
+	} else {
+		m.logger.InfoWithCtx(ctx, "Successfully created ScaledObject", "scaledObjectName", scaledObject.Name)
+	}
+
+	return fromUnstructured(appliedScaledObject)
+}
+
+func (m *Manager) Get(ctx context.Context, namespace string, name string) (*ScaledObject, error) {
+	encodedScaledObject, err := m.dynamicClient.
+		Resource(GroupVersionResource).
+		Namespace(namespace).
+		Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get ScaledObject")
+	}
+
+	return fromUnstructured(encodedScaledObject)
+}
+
+// DeleteByName deletes a ScaledObject by name, doing nothing if it does not exist
+func (m *Manager) DeleteByName(ctx context.Context, namespace string, name string) error {
+	if err := m.dynamicClient.
+		Resource(GroupVersionResource).
+		Namespace(namespace).
+		Delete(ctx, name, metav1.DeleteOptions{}); err != nil {
+
+		if !apierrors.IsNotFound(err) {
+			return errors.Wrap(err, "Failed to delete ScaledObject")
+		}
+
+		return nil
+	}
+
+	m.logger.DebugWithCtx(ctx, "Successfully deleted ScaledObject", "scaledObjectName", name)
+
+	return nil
+}
+
+// resolveScalesFunction returns whether KEDA scales the function, and an error if the function has stream
+// triggers whose scaler metadata can't be resolved (e.g. a templated nats durable name)
+func resolveScalesFunction(platformConfiguration *platformconfig.Config,
+	function *nuclioio.NuclioFunction) (bool, error) {
+	if !platformConfiguration.Kube.KEDA.Enabled {
+		return false, nil
+	}
+
+	// disabled and imported functions are kept at zero replicas, KEDA would scale them up
+	if function.Spec.Disable || function.Status.State == functionconfig.FunctionStateImported {
+		return false, nil
+	}
+
+	if function.GetComputedMinReplicas() == function.GetComputedMaxReplicas() {
+		return false, nil
+	}
+
+	scaleTriggers, err := resolveScaleTriggers(platformConfiguration, function)
+	if err != nil {
+		return false, errors.Wrap(err, "Failed to resolve scale triggers")
+	}
+
+	return len(scaleTriggers) > 0, nil
+}
+
+func resolveScaleTriggers(platformConfiguration *platformconfig.Config,
+	function *nuclioio.NuclioFunction) ([]ScaleTriggers, error) {
+	lagThreshold := strconv.Itoa(platformConfiguration.Kube.KEDA.DefaultLagThreshold)
+
+	// iterate the triggers by name, so that the generated scaled object is stable
+	var triggerNames []string
+	for triggerName := range function.Spec.Triggers {
+		triggerNames = append(triggerNames, triggerName)
+	}
+	sort.Strings(triggerNames)
+
+	var scaleTriggers []ScaleTriggers
+	for _, triggerName := range triggerNames {
+		trigger := function.Spec.Triggers[triggerName]
+		if !isScalableTrigger(trigger) {
+			continue
+		}
+
+		triggerScaleTriggers, err := resolveTriggerScaleTriggers(function, trigger, lagThreshold)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to resolve scale triggers of trigger %s", triggerName)
+		}
+
+		scaleTriggers = append(scaleTriggers, triggerScaleTriggers...)
+	}
+
+	return scaleTriggers, nil
+}
+
+func resolveTriggerScaleTriggers(function *nuclioio.NuclioFunction,
+	trigger functionconfig.Trigger,
+	lagThreshold string) ([]ScaleTriggers, error) {
+
+	var authenticationRef *AuthenticationRef
+	if authenticationRefName := trigger.Annotations[AuthenticationRefAnnotation]; authenticationRefName != "" {
+		authenticationRef = &AuthenticationRef{Name: authenticationRefName}
+	}
+
+	scalerType, scalersMetadata, err := resolveScalersMetadata(function,
+		trigger,
+		lagThreshold,
+		authenticationRef != nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve scaler metadata")
+	}
+
+	var scaleTriggers []ScaleTriggers
+	for _, scalerMetadata := range scalersMetadata {
+
+		// annotations override the metadata derived from the trigger
+		for annotationKey, annotationValue := range trigger.Annotations {
+			if annotationKey == AuthenticationRefAnnotation ||
+				!strings.HasPrefix(annotationKey, MetadataAnnotationPrefix) {
+				continue
+			}
+
+			scalerMetadata[strings.TrimPrefix(annotationKey, MetadataAnnotationPrefix)] = annotationValue
+		}
+
+		for _, requiredKey := range requiredScalerMetadata[scalerType] {
+			if scalerMetadata[requiredKey] == "" {
+				return nil, errors.Errorf("Missing %s scaler metadata: %s", scalerType, requiredKey)
+			}
+		}
+
+		scaleTriggers = append(scaleTriggers, ScaleTriggers{
+			Type:              scalerType,
+			Metadata:          scalerMetadata,
+			AuthenticationRef: authenticationRef,
+		})
+	}
+
+	return scaleTriggers, nil
+}
+
+// resolveScalersMetadata derives the KEDA scaler metadata from the trigger configuration. a trigger may
+// be scaled by more than one scaler of the same type (e.g. a kafka scaler per topic)
+func resolveScalersMetadata(function *nuclioio.NuclioFunction,
+	trigger functionconfig.Trigger,
+	lagThreshold string,
+	hasAuthenticationRef bool) (string, []map[string]string, error) {
+
+	switch trigger.Kind {
+	case "kafka-cluster", "kafka":
+		var attributes struct {
+			Brokers       []string
+			Topics        []string
+			ConsumerGroup string
+		}
+		if err := mapstructure.Decode(trigger.Attributes, &attributes); err != nil {
+			return "", nil, errors.Wrap(err, "Failed to decode kafka trigger attributes")
+		}
+
+		brokers := attributes.Brokers
+		if len(brokers) == 0 && trigger.URL != "" {
+			brokers = []string{trigger.URL}
+		}
+
+		newMetadata := func() map[string]string {
+			return map[string]string{
+				"bootstrapServers": strings.Join(brokers, ","),
+				"consumerGroup":    attributes.ConsumerGroup,
+				"lagThreshold":     lagThreshold,
+			}
+		}
+
+		// without topics, the lag of all the topics the consumer group consumes is used
+		if len(attributes.Topics) == 0 {
+			return scalerTypeKafka, []map[string]string{newMetadata()}, nil
+		}
+
+		var scalersMetadata []map[string]string
+		for _, topic := range attributes.Topics {
+			scalerMetadata := newMetadata()
+			scalerMetadata["topic"] = topic
+			scalersMetadata = append(scalersMetadata, scalerMetadata)
+		}
+
+		return scalerTypeKafka, scalersMetadata, nil
+
+	case "rabbit-mq", "rabbitMq":
+		var attributes struct {
+			QueueName string
+		}
+		if err := mapstructure.Decode(trigger.Attributes, &attributes); err != nil {
+			return "", nil, errors.Wrap(err, "Failed to decode rabbit-mq trigger attributes")
+		}
+
+		// like the trigger, default to a queue per function
+		queueName := attributes.QueueName
+		if queueName == "" {
+			queueName = "nuclio-" + function.Namespace + "-" + function.Name
+		}
+
+		scalerMetadata := map[string]string{
+			"queueName": queueName,
+			"mode":      "QueueLength",
+			"value":     lagThreshold,
+		}
+
+		// the broker url holds the credentials, so it is left for the authentication to provide if given
+		if !hasAuthenticationRef {
+			scalerMetadata["host"] = trigger.URL
+		}
+
+		return scalerTypeRabbitMQ, []map[string]string{scalerMetadata}, nil
+
+	case "nats":
+		var attributes struct {
+			StreamName  string
+			DurableName string
+		}
+		if err := mapstructure.Decode(trigger.Attributes, &attributes); err != nil {
+			return "", nil, errors.Wrap(err, "Failed to decode nats trigger attributes")
+		}
+
+		scalerMetadata := map[string]string{
+			"account":      "$G",
+			"stream":       attributes.StreamName,
+			"lagThreshold": lagThreshold,
+		}
+
+		// templated consumer names are rendered by the processor, so they must be given in the annotations
+		if !strings.Contains(attributes.DurableName, "{{") {
+			scalerMetadata["consumer"] = attributes.DurableName
+		}
+
+		return scalerTypeNATSJetStream, []map[string]string{scalerMetadata}, nil
+
+	case "kinesis":
+		var attributes struct {
+			StreamName string
+			RegionName string
+		}
+		if err := mapstructure.Decode(trigger.Attributes, &attributes); err != nil {
+			return "", nil, errors.Wrap(err, "Failed to decode kinesis trigger attributes")
+		}
+
+		return scalerTypeKinesis, []map[string]string{
+			{
+				"streamName": attributes.StreamName,
+				"awsRegion":  attributes.RegionName,
+			},
+		}, nil
+
+	default:
+		return "", nil, errors.Errorf("Unsupported trigger kind: %s", trigger.Kind)
+	}
+}
+
+func isScalableTrigger(trigger functionconfig.Trigger) bool {
+	if trigger.Disabled {
+		return false
+	}
+
+	switch trigger.Kind {
+	case "kafka-cluster", "kafka", "rabbit-mq", "rabbitMq", "kinesis":
+		return true
+	case "nats":
+		mode, _ := trigger.Attributes["mode"].(string)
+		return mode == natsModeJetStream
+	default:
+		return false
+	}
+}
+
+func toUnstructured(scaledObject *ScaledObject) (*unstructured.Unstructured, error) {
+	encodedScaledObject, err := runtime.DefaultUnstructuredConverter.ToUnstructured(scaledObject)
+	if err != nil {
+		return nil, err
+	}
+
+	// status is owned by the KEDA operator
+	delete(encodedScaledObject, "status")
+
+	return &unstructured.Unstructured{Object: encodedScaledObject}, nil
+}
+
+func fromUnstructured(encodedScaledObject *unstructured.Unstructured) (*ScaledObject, error) {
+	scaledObject := &ScaledObject{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(encodedScaledObject.Object,
+		scaledObject); err != nil {
+		return nil, errors.Wrapf(err, "Failed to decode ScaledObject %s", encodedScaledObject.GetName())
+	}
+
+	return scaledObject, nil
+}
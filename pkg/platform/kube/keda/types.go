@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keda
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+
+	// trigger annotations with this prefix are passed as the metadata of the trigger's KEDA scaler,
+	// overriding the metadata derived from the trigger configuration
+	MetadataAnnotationPrefix = "keda.nuclio.io/"
+
+	// the trigger annotation holding the name of the KEDA TriggerAuthentication of the trigger's scaler
+	AuthenticationRefAnnotation = MetadataAnnotationPrefix + "authenticationRef"
+
+	ScaledObjectConditionReady = "Ready"
+)
+
+var GroupVersionResource = schema.GroupVersionResource{
+	Group:    "keda.sh",
+	Version:  "v1alpha1",
+	Resource: "scaledobjects",
+}
+
+// the following is the subset of the KEDA API (keda.sh/v1alpha1) used by nuclio
+
+type ScaledObject struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScaledObjectSpec   `json:"spec"`
+	Status ScaledObjectStatus `json:"status,omitempty"`
+}
+
+type ScaledObjectSpec struct {
+	ScaleTargetRef  *ScaleTarget    `json:"scaleTargetRef"`
+	PollingInterval *int32          `json:"pollingInterval,omitempty"`
+	CooldownPeriod  *int32          `json:"cooldownPeriod,omitempty"`
+	MinReplicaCount *int32          `json:"minReplicaCount,omitempty"`
+	MaxReplicaCount *int32          `json:"maxReplicaCount,omitempty"`
+	Triggers        []ScaleTriggers `json:"triggers"`
+}
+
+type ScaleTarget struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Name       string `json:"name"`
+}
+
+type ScaleTriggers struct {
+	Type              string             `json:"type"`
+	Name              string             `json:"name,omitempty"`
+	Metadata          map[string]string  `json:"metadata"`
+	AuthenticationRef *AuthenticationRef `json:"authenticationRef,omitempty"`
+}
+
+type AuthenticationRef struct {
+	Name string `json:"name"`
+}
+
+type ScaledObjectStatus struct {
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+type Condition struct {
+	Type    string                 `json:"type"`
+	Status  metav1.ConditionStatus `json:"status"`
+	Reason  string                 `json:"reason,omitempty"`
+	Message string                 `json:"message,omitempty"`
+}
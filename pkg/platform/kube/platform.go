@@ -1703,10 +1703,17 @@ func (p *Platform) validateAPIGatewayConfig(ctx context.Context,
 	}
 
 	// spec
-	if err := ValidateAPIGatewaySpec(&apiGateway.Spec); err != nil {
+	if err := platform.ValidateAPIGatewaySpec(&apiGateway.Spec); err != nil {
 		return errors.Wrap(err, "Failed to validate the API-gateway spec")
 	}
 
+	// HTTPRoutes split the traffic between any number of canary upstreams
+	if !p.Config.IngressConfig.HTTPRoutesEnabled() {
+		if err := platform.ValidateAPIGatewayCanaryIngressUpstreams(&apiGateway.Spec); err != nil {
+			return errors.Wrap(err, "Failed to validate the API-gateway canary upstreams")
+		}
+	}
+
 	if existingAPIGateway != nil {
 		if existingAPIGateway.Labels[common.NuclioResourceLabelKeyProjectName] !=
 			apiGateway.Meta.Labels[common.NuclioResourceLabelKeyProjectName] {
@@ -1856,8 +1863,9 @@ func (p *Platform) validateAPIGatewayIngresses(ctx context.Context, apiGatewayCo
 	ingressNameWithCanary := IngressNameFromAPIGatewayName(apiGatewayConfig.Meta.Name, true)
 	listIngressesOptions := metav1.ListOptions{
 
-		// validate ingresses not created by this api gateway (whether it has canary deployment or not)
+		// validate ingresses not created by this api gateway (whether it has canary deployments or not)
 		FieldSelector: fmt.Sprintf("metadata.name!=%s,metadata.name!=%s", ingressName, ingressNameWithCanary),
+		LabelSelector: fmt.Sprintf("%s!=%s", common.NuclioResourceLabelKeyApiGatewayName, apiGatewayConfig.Meta.Name),
 	}
 
 	if err := p.validateIngressHostAndPathAvailability(ctx,
@@ -1448,9 +1448,17 @@ func (suite *FunctionKubePlatformTestSuite) TestUsernameLabelsEnrichment() {
 	for _, testCase := range []struct {
 		name                  string
 		fullUsername          string
+		oidc                  bool
 		expectedUsernameLabel string
 		expectedDomainLabel   string
 	}{
+		{
+			name:                  "oidc-with-name-and-domain",
+			fullUsername:          "foo@bar.com",
+			oidc:                  true,
+			expectedUsernameLabel: "foo",
+			expectedDomainLabel:   "bar.com",
+		},
 		{
 			name:                  "with-name-and-domain",
 			fullUsername:          "foo@bar.com",
@@ -1484,12 +1492,15 @@ func (suite *FunctionKubePlatformTestSuite) TestUsernameLabelsEnrichment() {
 		},
 	} {
 		suite.Run(testCase.name, func() {
-			testContext := context.WithValue(suite.ctx,
-				auth.AuthSessionContextKey,
-				&auth.IguazioSession{
+			var authSession auth.Session = &auth.IguazioSession{
+				Username: testCase.fullUsername,
+			}
+			if testCase.oidc {
+				authSession = &auth.OIDCSession{
 					Username: testCase.fullUsername,
-				},
-			)
+				}
+			}
+			testContext := context.WithValue(suite.ctx, auth.AuthSessionContextKey, authSession)
 			labels := make(map[string]string)
 			suite.platform.EnrichLabels(testContext, labels)
 
@@ -1911,14 +1922,96 @@ func (suite *APIGatewayKubePlatformTestSuite) TestAPIGatewayEnrichmentAndValidat
 			validationError: "Api gateway name 'dashboard' is reserved and cannot be used",
 		},
 		{
-			name: "ValidateNoMoreThanTwoUpstreams",
+			name: "AllowCanaryUpstreamWithMatchRule",
+			apiGatewayConfig: func() *platform.APIGatewayConfig {
+				apiGatewayConfig := suite.compileAPIGatewayConfig()
+				apiGatewayConfig.Spec.Upstreams = append(apiGatewayConfig.Spec.Upstreams,
+					suite.compileAPIGatewayUpstream("canary-func-name-1", 30, &platform.APIGatewayUpstreamMatchSpec{
+						Header:      "x-canary",
+						HeaderValue: "true",
+					}))
+				return &apiGatewayConfig
+			}(),
+		},
+		{
+			name: "ValidateSingleCanaryUpstreamForIngresses",
+			apiGatewayConfig: func() *platform.APIGatewayConfig {
+				apiGatewayConfig := suite.compileAPIGatewayConfig()
+				apiGatewayConfig.Spec.Upstreams = append(apiGatewayConfig.Spec.Upstreams,
+					suite.compileAPIGatewayUpstream("canary-func-name-1", 30, nil),
+					suite.compileAPIGatewayUpstream("canary-func-name-2", 0, &platform.APIGatewayUpstreamMatchSpec{
+						Header:      "x-canary",
+						HeaderValue: "true",
+					}))
+				return &apiGatewayConfig
+			}(),
+			validationError: "Received 2 canary upstreams, nginx ingresses support a single canary upstream " +
+				"(set the platform ingress kind to httpRoute to split the traffic between more upstreams)",
+		},
+		{
+			name: "AllowMultipleCanaryUpstreamsForHTTPRoutes",
+			setUpFunction: func() error {
+				suite.platform.Config.IngressConfig.Kind = platformconfig.IngressKindHTTPRoute
+				return nil
+			},
+			tearDownFunction: func() error {
+				suite.platform.Config.IngressConfig.Kind = platformconfig.IngressKindIngress
+				return nil
+			},
 			apiGatewayConfig: func() *platform.APIGatewayConfig {
 				apiGatewayConfig := suite.compileAPIGatewayConfig()
-				upstream := apiGatewayConfig.Spec.Upstreams[0]
-				apiGatewayConfig.Spec.Upstreams = []platform.APIGatewayUpstreamSpec{upstream, upstream, upstream}
+				apiGatewayConfig.Spec.Upstreams = append(apiGatewayConfig.Spec.Upstreams,
+					suite.compileAPIGatewayUpstream("canary-func-name-1", 30, nil),
+					suite.compileAPIGatewayUpstream("canary-func-name-2", 0, &platform.APIGatewayUpstreamMatchSpec{
+						Header:      "x-canary",
+						HeaderValue: "true",
+					}))
 				return &apiGatewayConfig
 			}(),
-			validationError: "Received more than 2 upstreams. Currently not supported",
+		},
+		{
+			name: "ValidateSinglePrimaryUpstream",
+			apiGatewayConfig: func() *platform.APIGatewayConfig {
+				apiGatewayConfig := suite.compileAPIGatewayConfig()
+				apiGatewayConfig.Spec.Upstreams = append(apiGatewayConfig.Spec.Upstreams,
+					suite.compileAPIGatewayUpstream("canary-func-name-1", 0, nil))
+				return &apiGatewayConfig
+			}(),
+			validationError: "Only one upstream may be set without a percentage or a match rule",
+		},
+		{
+			name: "ValidateCanaryPercentagesSum",
+			apiGatewayConfig: func() *platform.APIGatewayConfig {
+				apiGatewayConfig := suite.compileAPIGatewayConfig()
+				apiGatewayConfig.Spec.Upstreams = append(apiGatewayConfig.Spec.Upstreams,
+					suite.compileAPIGatewayUpstream("canary-func-name-1", 60, nil),
+					suite.compileAPIGatewayUpstream("canary-func-name-2", 50, nil))
+				return &apiGatewayConfig
+			}(),
+			validationError: "Canary upstreams percentages sum up to 110, must not exceed 100",
+		},
+		{
+			name: "ValidateUniqueUpstreamFunctions",
+			apiGatewayConfig: func() *platform.APIGatewayConfig {
+				apiGatewayConfig := suite.compileAPIGatewayConfig()
+				apiGatewayConfig.Spec.Upstreams = append(apiGatewayConfig.Spec.Upstreams,
+					suite.compileAPIGatewayUpstream("default-func-name", 20, nil))
+				return &apiGatewayConfig
+			}(),
+			validationError: "Function 'default-func-name' is used by more than one upstream",
+		},
+		{
+			name: "ValidateUpstreamMatch",
+			apiGatewayConfig: func() *platform.APIGatewayConfig {
+				apiGatewayConfig := suite.compileAPIGatewayConfig()
+				apiGatewayConfig.Spec.Upstreams = append(apiGatewayConfig.Spec.Upstreams,
+					suite.compileAPIGatewayUpstream("canary-func-name-1", 0, &platform.APIGatewayUpstreamMatchSpec{
+						Header: "x-canary",
+						Cookie: "canary",
+					}))
+				return &apiGatewayConfig
+			}(),
+			validationError: "Upstream match must not specify both a header and a cookie",
 		},
 		{
 			name: "ValidateAtLeastOneUpstream",
@@ -1998,6 +2091,7 @@ func (suite *APIGatewayKubePlatformTestSuite) TestAPIGatewayEnrichmentAndValidat
 					NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{
 						Name: "function-with-ingresses-2",
 					},
+					Percentage: 20,
 				})
 				return &apiGatewayConfig
 			}(),
@@ -2299,6 +2393,19 @@ func (suite *APIGatewayKubePlatformTestSuite) compileAPIGatewayConfig() platform
 	}
 }
 
+func (suite *APIGatewayKubePlatformTestSuite) compileAPIGatewayUpstream(functionName string,
+	percentage int,
+	match *platform.APIGatewayUpstreamMatchSpec) platform.APIGatewayUpstreamSpec {
+	return platform.APIGatewayUpstreamSpec{
+		Kind: platform.APIGatewayUpstreamKindNuclioFunction,
+		NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{
+			Name: functionName,
+		},
+		Percentage: percentage,
+		Match:      match,
+	}
+}
+
 func TestKubePlatformTestSuite(t *testing.T) {
 	suite.Run(t, new(ProjectKubePlatformTestSuite))
 	suite.Run(t, new(FunctionKubePlatformTestSuite))
@@ -28,6 +28,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/platform/kube"
 	nuclioio "github.com/nuclio/nuclio/pkg/platform/kube/apis/nuclio.io/v1beta1"
 	nuclioioclient "github.com/nuclio/nuclio/pkg/platform/kube/client/clientset/versioned"
+	"github.com/nuclio/nuclio/pkg/platform/kube/keda"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	httptrigger "github.com/nuclio/nuclio/pkg/processor/trigger/http"
 
@@ -107,6 +108,11 @@ func (n *NuclioResourceScaler) GetResources() ([]scalertypes.Resource, error) {
 	// build a list of function names that are candidates to be scaled to zero
 	for _, function := range functions.Items {
 
+		// functions scaled by KEDA are scaled to zero by it
+		if keda.ScalesFunction(n.platformConfiguration, &function) {
+			continue
+		}
+
 		// don't include functions that aren't in ready state or that min replicas is larger than zero
 		if function.GetComputedMinReplicas() <= 0 && function.Status.State == functionconfig.FunctionStateReady {
 			if function.Spec.ScaleToZero == nil {
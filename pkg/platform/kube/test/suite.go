@@ -41,7 +41,9 @@ import (
 	nuclioioclient "github.com/nuclio/nuclio/pkg/platform/kube/client/clientset/versioned"
 	"github.com/nuclio/nuclio/pkg/platform/kube/controller"
 	"github.com/nuclio/nuclio/pkg/platform/kube/functionres"
+	"github.com/nuclio/nuclio/pkg/platform/kube/httproute"
 	"github.com/nuclio/nuclio/pkg/platform/kube/ingress"
+	"github.com/nuclio/nuclio/pkg/platform/kube/keda"
 	"github.com/nuclio/nuclio/pkg/platform/kube/test/kubectlclient"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	processorsuite "github.com/nuclio/nuclio/pkg/processor/test/suite"
@@ -53,6 +55,7 @@ import (
 	networkingv1 "k8s.io/api/networking/v1"
 	kubeapierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/yaml"
@@ -646,8 +649,23 @@ func (suite *KubeTestSuite) createController() *controller.Controller {
 	suite.FunctionClientSet, err = nuclioioclient.NewForConfig(restConfig)
 	suite.Require().NoError(err)
 
+	dynamicClient, err := dynamic.NewForConfig(restConfig)
+	suite.Require().NoError(err)
+
+	// create HTTPRoute manager
+	httpRouteManager, err := httproute.NewManager(suite.Logger, dynamicClient, suite.PlatformConfiguration)
+	suite.Require().NoError(err)
+
+	// create KEDA manager
+	kedaManager, err := keda.NewManager(suite.Logger, dynamicClient, suite.PlatformConfiguration)
+	suite.Require().NoError(err)
+
 	// create a client for function deployments
-	suite.FunctionClient, err = functionres.NewLazyClient(suite.Logger, suite.KubeClientSet, suite.FunctionClientSet)
+	suite.FunctionClient, err = functionres.NewLazyClient(suite.Logger,
+		suite.KubeClientSet,
+		suite.FunctionClientSet,
+		httpRouteManager,
+		kedaManager)
 	suite.Require().NoError(err)
 
 	// create cmd runner
@@ -662,7 +680,8 @@ func (suite *KubeTestSuite) createController() *controller.Controller {
 	apigatewayresClient, err := apigatewayres.NewLazyClient(suite.Logger,
 		suite.KubeClientSet,
 		suite.FunctionClientSet,
-		ingressManager)
+		ingressManager,
+		httpRouteManager)
 	suite.Require().NoError(err)
 
 	controllerInstance, err := controller.NewController(suite.Logger,
@@ -53,10 +53,24 @@ func HPANameFromFunctionName(functionName string) string {
 	return fmt.Sprintf("nuclio-%s", functionName)
 }
 
+func ScaledObjectNameFromFunctionName(functionName string) string {
+	return fmt.Sprintf("nuclio-%s", functionName)
+}
+
 func IngressNameFromFunctionName(functionName string) string {
 	return fmt.Sprintf("nuclio-%s", functionName)
 }
 
+// HTTPRouteNameFromFunctionName returns the name of the HTTPRoute of the function host at the given index.
+// the first HTTPRoute is named like the function ingress
+func HTTPRouteNameFromFunctionName(functionName string, hostIndex int) string {
+	resourceName := IngressNameFromFunctionName(functionName)
+	if hostIndex > 0 {
+		resourceName += fmt.Sprintf("-%d", hostIndex+1)
+	}
+	return resourceName
+}
+
 func ServiceNameFromFunctionName(functionName string) string {
 	return fmt.Sprintf("nuclio-%s", functionName)
 }
@@ -0,0 +1,440 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigatewayproxy
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/platform/kube/ingress"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// Proxy serves the api gateways of the local platform, routing requests by host and path to the
+// function containers of the api gateway upstreams
+type Proxy struct {
+	logger        logger.Logger
+	platform      platform.Platform
+	configuration *platformconfig.LocalAPIGatewayProxyConfig
+	routesLock    sync.RWMutex
+	routes        []*route
+	randomizer    func(int) int
+}
+
+func NewProxy(parentLogger logger.Logger,
+	platformInstance platform.Platform,
+	configuration *platformconfig.LocalAPIGatewayProxyConfig) (*Proxy, error) {
+
+	if configuration.ResyncInterval <= 0 {
+		return nil, errors.New("Resync interval must be positive")
+	}
+
+	return &Proxy{
+		logger:        parentLogger.GetChild("apigatewayproxy"),
+		platform:      platformInstance,
+		configuration: configuration,
+		randomizer:    rand.Intn,
+	}, nil
+}
+
+// Start resolves the routes, starts listening and keeps the routes in sync with the api gateways until the
+// context is done, at which point the proxy stops serving
+func (p *Proxy) Start(ctx context.Context) error {
+
+	// listen synchronously, so that failing to bind the address fails the start
+	listener, err := net.Listen("tcp", p.configuration.ListenAddress)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to listen on %s", p.configuration.ListenAddress)
+	}
+
+	if err := p.Resync(ctx); err != nil {
+		listener.Close() // nolint: errcheck
+		return errors.Wrap(err, "Failed to resolve api gateway routes")
+	}
+
+	server := &http.Server{Handler: p}
+
+	go func() {
+		resyncTicker := time.NewTicker(p.configuration.ResyncInterval)
+		defer resyncTicker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				p.logger.DebugWith("Stopping api gateway proxy")
+				if err := server.Close(); err != nil {
+					p.logger.WarnWith("Failed to close api gateway proxy server", "err", errors.Cause(err))
+				}
+				return
+			case <-resyncTicker.C:
+				if err := p.Resync(ctx); err != nil {
+					p.logger.WarnWithCtx(ctx, "Failed to resync api gateway routes", "err", errors.Cause(err))
+				}
+			}
+		}
+	}()
+
+	p.logger.InfoWithCtx(ctx, "Starting api gateway proxy", "listenAddress", listener.Addr().String())
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			p.logger.ErrorWith("Api gateway proxy stopped serving", "err", err)
+		}
+	}()
+
+	return nil
+}
+
+// Resync resolves the routes from the api gateways and functions of the platform
+func (p *Proxy) Resync(ctx context.Context) error {
+	namespaces, err := p.platform.GetNamespaces(ctx)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get namespaces")
+	}
+
+	var routes []*route
+	for _, namespace := range namespaces {
+		namespaceRoutes, err := p.resolveNamespaceRoutes(ctx, namespace)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to resolve routes of namespace %s", namespace)
+		}
+
+		routes = append(routes, namespaceRoutes...)
+	}
+
+	// longest paths first, so that the most specific route wins
+	sort.SliceStable(routes, func(i, j int) bool {
+		return len(routes[i].path) > len(routes[j].path)
+	})
+
+	p.routesLock.Lock()
+	p.routes = routes
+	p.routesLock.Unlock()
+
+	return nil
+}
+
+// ServeHTTP routes the request to the function of one of the matching api gateway upstreams
+func (p *Proxy) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	matchingRoute := p.findRoute(request)
+	if matchingRoute == nil {
+		http.Error(responseWriter, "No api gateway matches the request", http.StatusNotFound)
+		return
+	}
+
+	if !matchingRoute.authenticate(request) {
+		responseWriter.Header().Set("WWW-Authenticate", `Basic realm="Authentication Required"`)
+		http.Error(responseWriter, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	target := matchingRoute.resolveTarget(request, p.randomizer)
+	if target.url == nil {
+		http.Error(responseWriter,
+			fmt.Sprintf("Function %s is not reachable", target.functionName),
+			http.StatusServiceUnavailable)
+		return
+	}
+
+	// rewrite the matched path prefix, like the ingress controller does
+	if target.rewriteTarget != "" {
+		request.URL.Path = joinPaths(target.rewriteTarget, strings.TrimPrefix(request.URL.Path, matchingRoute.path))
+		request.URL.RawPath = ""
+	}
+
+	reverseProxy := httputil.NewSingleHostReverseProxy(target.url)
+	reverseProxy.ErrorHandler = func(responseWriter http.ResponseWriter, request *http.Request, err error) {
+		p.logger.WarnWithCtx(request.Context(),
+			"Failed to proxy request to function",
+			"apiGateway", matchingRoute.apiGatewayName,
+			"function", target.functionName,
+			"err", err.Error())
+		http.Error(responseWriter, "Failed to reach function", http.StatusBadGateway)
+	}
+
+	reverseProxy.ServeHTTP(responseWriter, request)
+}
+
+func (p *Proxy) findRoute(request *http.Request) *route {
+	host := request.Host
+	if hostWithoutPort, _, err := net.SplitHostPort(host); err == nil {
+		host = hostWithoutPort
+	}
+
+	p.routesLock.RLock()
+	defer p.routesLock.RUnlock()
+
+	// routes are sorted by path length, the first match is the most specific one
+	for _, candidateRoute := range p.routes {
+		if candidateRoute.matches(host, request.URL.Path) {
+			return candidateRoute
+		}
+	}
+
+	return nil
+}
+
+func (p *Proxy) resolveNamespaceRoutes(ctx context.Context, namespace string) ([]*route, error) {
+	apiGateways, err := p.platform.GetAPIGateways(ctx, &platform.GetAPIGatewaysOptions{
+		Namespace: namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get api gateways")
+	}
+
+	if len(apiGateways) == 0 {
+		return nil, nil
+	}
+
+	functions, err := p.platform.GetFunctions(ctx, &platform.GetFunctionsOptions{
+		Namespace: namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get functions")
+	}
+
+	functionURLs := map[string]*url.URL{}
+	for _, function := range functions {
+		if functionURL := p.resolveFunctionURL(function); functionURL != nil {
+			functionURLs[function.GetConfig().Meta.Name] = functionURL
+		}
+	}
+
+	var routes []*route
+	for _, apiGateway := range apiGateways {
+		apiGatewayRoute, err := newRoute(apiGateway.GetConfig(), functionURLs)
+		if err != nil {
+			p.logger.WarnWithCtx(ctx,
+				"Skipping api gateway which can't be served locally",
+				"apiGateway", apiGateway.GetConfig().Meta.Name,
+				"err", errors.Cause(err))
+			continue
+		}
+
+		routes = append(routes, apiGatewayRoute)
+	}
+
+	return routes, nil
+}
+
+func (p *Proxy) resolveFunctionURL(function platform.Function) *url.URL {
+	status := function.GetStatus()
+
+	// prefer reaching the function container directly, falling back to its published port
+	for _, invocationURLs := range [][]string{status.InternalInvocationURLs, status.ExternalInvocationURLs} {
+		for _, invocationURL := range invocationURLs {
+			if !strings.Contains(invocationURL, "://") {
+				invocationURL = "http://" + invocationURL
+			}
+
+			if parsedURL, err := url.Parse(invocationURL); err == nil && parsedURL.Host != "" {
+				return parsedURL
+			}
+		}
+	}
+
+	return nil
+}
+
+type upstreamTarget struct {
+	functionName  string
+	url           *url.URL
+	percentage    int
+	match         *platform.APIGatewayUpstreamMatchSpec
+	rewriteTarget string
+}
+
+type route struct {
+	apiGatewayName string
+	host           string
+	path           string
+	basicAuth      *platform.BasicAuth
+	primary        *upstreamTarget
+	canaries       []*upstreamTarget
+}
+
+func newRoute(apiGatewayConfig *platform.APIGatewayConfig, functionURLs map[string]*url.URL) (*route, error) {
+	spec := &apiGatewayConfig.Spec
+
+	newRoute := &route{
+		apiGatewayName: apiGatewayConfig.Meta.Name,
+		host:           spec.Host,
+		path:           joinPaths("/", spec.Path),
+	}
+
+	switch spec.AuthenticationMode {
+	case "", ingress.AuthenticationModeNone:
+	case ingress.AuthenticationModeBasicAuth:
+		if spec.Authentication == nil || spec.Authentication.BasicAuth == nil {
+			return nil, errors.New("Basic auth mode requires basic auth credentials")
+		}
+		newRoute.basicAuth = spec.Authentication.BasicAuth
+	default:
+		return nil, errors.Errorf("Authentication mode %s is not supported locally", spec.AuthenticationMode)
+	}
+
+	primaryUpstream, canaryUpstreams, err := spec.ResolvePrimaryAndCanaryUpstreams()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve upstreams")
+	}
+
+	newRoute.primary = newUpstreamTarget(primaryUpstream, functionURLs)
+	for _, canaryUpstream := range canaryUpstreams {
+		newRoute.canaries = append(newRoute.canaries, newUpstreamTarget(canaryUpstream, functionURLs))
+	}
+
+	return newRoute, nil
+}
+
+func newUpstreamTarget(upstream *platform.APIGatewayUpstreamSpec, functionURLs map[string]*url.URL) *upstreamTarget {
+	newUpstreamTarget := &upstreamTarget{
+		percentage:    upstream.Percentage,
+		match:         upstream.Match,
+		rewriteTarget: upstream.RewriteTarget,
+	}
+
+	if upstream.NuclioFunction != nil {
+		newUpstreamTarget.functionName = upstream.NuclioFunction.Name
+		newUpstreamTarget.url = functionURLs[upstream.NuclioFunction.Name]
+	}
+
+	return newUpstreamTarget
+}
+
+func (r *route) matches(host string, requestPath string) bool {
+	if r.host != "" && !strings.EqualFold(r.host, host) {
+		return false
+	}
+
+	return r.path == "/" ||
+		requestPath == r.path ||
+		strings.HasPrefix(requestPath, r.path+"/")
+}
+
+func (r *route) authenticate(request *http.Request) bool {
+	if r.basicAuth == nil {
+		return true
+	}
+
+	username, password, ok := request.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	usernameMatches := subtle.ConstantTimeCompare([]byte(username), []byte(r.basicAuth.Username)) == 1
+	passwordMatches := subtle.ConstantTimeCompare([]byte(password), []byte(r.basicAuth.Password)) == 1
+
+	return usernameMatches && passwordMatches
+}
+
+// resolveTarget picks the upstream to route the request to, the same way the ingress controller does - canaries
+// matching by header or cookie come first, then canaries are picked by their weights and the primary gets the rest
+func (r *route) resolveTarget(request *http.Request, randomizer func(int) int) *upstreamTarget {
+	var weightedCanaries []*upstreamTarget
+
+	for _, canary := range r.canaries {
+		switch canary.matchRequest(request) {
+		case matchResultAlways:
+			return canary
+		case matchResultNever:
+			continue
+		}
+
+		if canary.percentage > 0 {
+			weightedCanaries = append(weightedCanaries, canary)
+		}
+	}
+
+	if len(weightedCanaries) > 0 {
+		roll := randomizer(100)
+		for _, canary := range weightedCanaries {
+			if roll < canary.percentage {
+				return canary
+			}
+			roll -= canary.percentage
+		}
+	}
+
+	return r.primary
+}
+
+type matchResult int
+
+const (
+	matchResultNone matchResult = iota
+	matchResultAlways
+	matchResultNever
+)
+
+func (t *upstreamTarget) matchRequest(request *http.Request) matchResult {
+	if t.match == nil {
+		return matchResultNone
+	}
+
+	var value string
+	switch {
+	case t.match.Header != "":
+		value = request.Header.Get(t.match.Header)
+
+		// an explicit header value must match exactly
+		if t.match.HeaderValue != "" {
+			if value == t.match.HeaderValue {
+				return matchResultAlways
+			}
+			return matchResultNone
+		}
+	case t.match.Cookie != "":
+		if cookie, err := request.Cookie(t.match.Cookie); err == nil {
+			value = cookie.Value
+		}
+	}
+
+	switch value {
+	case "always":
+		return matchResultAlways
+	case "never":
+		return matchResultNever
+	default:
+		return matchResultNone
+	}
+}
+
+func joinPaths(prefix string, suffix string) string {
+	joinedPath := path.Join(prefix, suffix)
+
+	// keep the trailing slash, it may be meaningful to the function
+	if strings.HasSuffix(suffix, "/") && !strings.HasSuffix(joinedPath, "/") {
+		joinedPath += "/"
+	}
+
+	return joinedPath
+}
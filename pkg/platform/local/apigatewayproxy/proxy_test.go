@@ -0,0 +1,385 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package apigatewayproxy
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platform"
+	"github.com/nuclio/nuclio/pkg/platform/kube/ingress"
+	mockplatform "github.com/nuclio/nuclio/pkg/platform/mock"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type ProxyTestSuite struct {
+	suite.Suite
+	logger          logger.Logger
+	ctx             context.Context
+	mockedPlatform  *mockplatform.Platform
+	proxy           *Proxy
+	functionServers []*httptest.Server
+	functions       []platform.Function
+}
+
+func (suite *ProxyTestSuite) SetupSuite() {
+	var err error
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+	suite.ctx = context.Background()
+}
+
+func (suite *ProxyTestSuite) SetupTest() {
+	var err error
+	suite.mockedPlatform = &mockplatform.Platform{}
+	suite.proxy, err = NewProxy(suite.logger, suite.mockedPlatform, &platformconfig.LocalAPIGatewayProxyConfig{
+		ListenAddress:  ":0",
+		ResyncInterval: time.Minute,
+	})
+	suite.Require().NoError(err)
+
+	suite.functionServers = nil
+	suite.functions = nil
+	for _, functionName := range []string{"primary", "canary-a", "canary-b"} {
+		suite.addFunction(functionName)
+	}
+}
+
+func (suite *ProxyTestSuite) TearDownTest() {
+	for _, functionServer := range suite.functionServers {
+		functionServer.Close()
+	}
+}
+
+func (suite *ProxyTestSuite) TestRouteByHostAndPath() {
+	suite.resync(
+		suite.compileAPIGateway("root", "some-host.com", "/", "primary"),
+		suite.compileAPIGateway("api", "some-host.com", "/api", "canary-a"),
+		suite.compileAPIGateway("other-host", "other-host.com", "/", "canary-b"))
+
+	for _, testCase := range []struct {
+		name                 string
+		host                 string
+		path                 string
+		expectedStatusCode   int
+		expectedFunctionName string
+	}{
+		{name: "root", host: "some-host.com", path: "/something", expectedFunctionName: "primary"},
+		{name: "longestPrefix", host: "some-host.com:8090", path: "/api/something", expectedFunctionName: "canary-a"},
+		{name: "prefixIsNotAWord", host: "some-host.com", path: "/apis", expectedFunctionName: "primary"},
+		{name: "otherHost", host: "other-host.com", path: "/api", expectedFunctionName: "canary-b"},
+		{name: "unknownHost", host: "unknown.com", path: "/", expectedStatusCode: http.StatusNotFound},
+	} {
+		suite.Run(testCase.name, func() {
+			statusCode, functionName, _ := suite.sendRequest(testCase.host, testCase.path, nil)
+			if testCase.expectedStatusCode != 0 {
+				suite.Require().Equal(testCase.expectedStatusCode, statusCode)
+				return
+			}
+			suite.Require().Equal(http.StatusOK, statusCode)
+			suite.Require().Equal(testCase.expectedFunctionName, functionName)
+		})
+	}
+}
+
+func (suite *ProxyTestSuite) TestBasicAuth() {
+	apiGateway := suite.compileAPIGateway("secured", "some-host.com", "/", "primary")
+	apiGateway.Spec.AuthenticationMode = ingress.AuthenticationModeBasicAuth
+	apiGateway.Spec.Authentication = &platform.APIGatewayAuthenticationSpec{
+		BasicAuth: &platform.BasicAuth{
+			Username: "some-user",
+			Password: "some-password",
+		},
+	}
+	suite.resync(apiGateway)
+
+	statusCode, _, _ := suite.sendRequest("some-host.com", "/", nil)
+	suite.Require().Equal(http.StatusUnauthorized, statusCode)
+
+	statusCode, _, _ = suite.sendRequest("some-host.com", "/", func(request *http.Request) {
+		request.SetBasicAuth("some-user", "wrong-password")
+	})
+	suite.Require().Equal(http.StatusUnauthorized, statusCode)
+
+	statusCode, functionName, _ := suite.sendRequest("some-host.com", "/", func(request *http.Request) {
+		request.SetBasicAuth("some-user", "some-password")
+	})
+	suite.Require().Equal(http.StatusOK, statusCode)
+	suite.Require().Equal("primary", functionName)
+}
+
+func (suite *ProxyTestSuite) TestCanaryWeights() {
+	apiGateway := suite.compileAPIGateway("canaries", "some-host.com", "/", "primary", "canary-a", "canary-b")
+	apiGateway.Spec.Upstreams[1].Percentage = 20
+	apiGateway.Spec.Upstreams[2].Percentage = 30
+	suite.resync(apiGateway)
+
+	for _, testCase := range []struct {
+		roll                 int
+		expectedFunctionName string
+	}{
+		{roll: 0, expectedFunctionName: "canary-a"},
+		{roll: 19, expectedFunctionName: "canary-a"},
+		{roll: 20, expectedFunctionName: "canary-b"},
+		{roll: 49, expectedFunctionName: "canary-b"},
+		{roll: 50, expectedFunctionName: "primary"},
+		{roll: 99, expectedFunctionName: "primary"},
+	} {
+		roll := testCase.roll
+		suite.proxy.randomizer = func(int) int { return roll }
+
+		statusCode, functionName, _ := suite.sendRequest("some-host.com", "/", nil)
+		suite.Require().Equal(http.StatusOK, statusCode)
+		suite.Require().Equal(testCase.expectedFunctionName, functionName, "roll %d", roll)
+	}
+}
+
+func (suite *ProxyTestSuite) TestCanaryMatch() {
+	apiGateway := suite.compileAPIGateway("canaries", "some-host.com", "/", "primary", "canary-a", "canary-b")
+	apiGateway.Spec.Upstreams[1].Percentage = 100
+	apiGateway.Spec.Upstreams[1].Match = &platform.APIGatewayUpstreamMatchSpec{Header: "X-Canary"}
+	apiGateway.Spec.Upstreams[2].Match = &platform.APIGatewayUpstreamMatchSpec{Cookie: "canary"}
+	suite.resync(apiGateway)
+	suite.proxy.randomizer = func(int) int { return 0 }
+
+	for _, testCase := range []struct {
+		name                 string
+		header               string
+		cookie               string
+		expectedFunctionName string
+	}{
+		{name: "noMatchFallsBackToWeight", expectedFunctionName: "canary-a"},
+		{name: "headerNever", header: "never", expectedFunctionName: "primary"},
+		{name: "cookieAlways", cookie: "always", expectedFunctionName: "canary-b"},
+		{name: "headerWinsByOrder", header: "always", cookie: "always", expectedFunctionName: "canary-a"},
+	} {
+		suite.Run(testCase.name, func() {
+			statusCode, functionName, _ := suite.sendRequest("some-host.com", "/", func(request *http.Request) {
+				if testCase.header != "" {
+					request.Header.Set("X-Canary", testCase.header)
+				}
+				if testCase.cookie != "" {
+					request.AddCookie(&http.Cookie{Name: "canary", Value: testCase.cookie})
+				}
+			})
+			suite.Require().Equal(http.StatusOK, statusCode)
+			suite.Require().Equal(testCase.expectedFunctionName, functionName)
+		})
+	}
+
+	// explicit header values must match exactly
+	apiGateway.Spec.Upstreams[1].Percentage = 0
+	apiGateway.Spec.Upstreams[1].Match = &platform.APIGatewayUpstreamMatchSpec{
+		Header:      "X-Region",
+		HeaderValue: "eu",
+	}
+	suite.resync(apiGateway)
+
+	_, functionName, _ := suite.sendRequest("some-host.com", "/", func(request *http.Request) {
+		request.Header.Set("X-Region", "eu")
+	})
+	suite.Require().Equal("canary-a", functionName)
+
+	_, functionName, _ = suite.sendRequest("some-host.com", "/", func(request *http.Request) {
+		request.Header.Set("X-Region", "us")
+	})
+	suite.Require().Equal("primary", functionName)
+}
+
+func (suite *ProxyTestSuite) TestRewriteTarget() {
+	apiGateway := suite.compileAPIGateway("rewrite", "some-host.com", "/api", "primary")
+	apiGateway.Spec.Upstreams[0].RewriteTarget = "/internal"
+	suite.resync(apiGateway)
+
+	statusCode, _, requestPath := suite.sendRequest("some-host.com", "/api/v1/items", nil)
+	suite.Require().Equal(http.StatusOK, statusCode)
+	suite.Require().Equal("/internal/v1/items", requestPath)
+}
+
+func (suite *ProxyTestSuite) TestUnsupportedAuthenticationModeIsSkipped() {
+	apiGateway := suite.compileAPIGateway("oauth", "some-host.com", "/", "primary")
+	apiGateway.Spec.AuthenticationMode = ingress.AuthenticationModeOauth2
+	suite.resync(apiGateway)
+
+	statusCode, _, _ := suite.sendRequest("some-host.com", "/", nil)
+	suite.Require().Equal(http.StatusNotFound, statusCode)
+}
+
+func (suite *ProxyTestSuite) TestUnreachableFunction() {
+	suite.resync(suite.compileAPIGateway("missing", "some-host.com", "/", "missing-function"))
+
+	statusCode, _, _ := suite.sendRequest("some-host.com", "/", nil)
+	suite.Require().Equal(http.StatusServiceUnavailable, statusCode)
+}
+
+func (suite *ProxyTestSuite) TestStartFailsWhenAddressIsInUse() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	defer listener.Close() // nolint: errcheck
+
+	suite.proxy.configuration.ListenAddress = listener.Addr().String()
+	err = suite.proxy.Start(suite.ctx)
+	suite.Require().Error(err)
+	suite.Require().Contains(err.Error(), "Failed to listen on")
+}
+
+func (suite *ProxyTestSuite) TestStopServingWhenContextIsDone() {
+	suite.resync(suite.compileAPIGateway("root", "some-host.com", "/", "primary"))
+
+	// find a free address to listen on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().NoError(err)
+	listenAddress := listener.Addr().String()
+	suite.Require().NoError(listener.Close())
+
+	ctx, cancel := context.WithCancel(suite.ctx)
+	suite.proxy.configuration.ListenAddress = listenAddress
+	suite.Require().NoError(suite.proxy.Start(ctx))
+
+	request, err := http.NewRequest(http.MethodGet, "http://"+listenAddress+"/something", nil)
+	suite.Require().NoError(err)
+	request.Host = "some-host.com"
+
+	response, err := http.DefaultClient.Do(request)
+	suite.Require().NoError(err)
+	response.Body.Close() // nolint: errcheck
+	suite.Require().Equal("primary", response.Header.Get("X-Function-Name"))
+
+	// once the context is done, the address is released
+	cancel()
+	suite.Require().Eventually(func() bool {
+		listener, err := net.Listen("tcp", listenAddress)
+		if err != nil {
+			return false
+		}
+		listener.Close() // nolint: errcheck
+		return true
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func (suite *ProxyTestSuite) addFunction(functionName string) {
+	functionServer := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter,
+		request *http.Request) {
+		responseWriter.Header().Set("X-Function-Name", functionName)
+		responseWriter.Write([]byte(request.URL.Path)) // nolint: errcheck
+	}))
+	suite.functionServers = append(suite.functionServers, functionServer)
+
+	function, err := platform.NewAbstractFunction(suite.logger,
+		suite.mockedPlatform,
+		&functionconfig.Config{
+			Meta: functionconfig.Meta{
+				Name:      functionName,
+				Namespace: "default-namespace",
+			},
+		},
+		&functionconfig.Status{
+			InternalInvocationURLs: []string{strings.TrimPrefix(functionServer.URL, "http://")},
+		},
+		nil)
+	suite.Require().NoError(err)
+
+	suite.functions = append(suite.functions, function)
+}
+
+func (suite *ProxyTestSuite) compileAPIGateway(name string,
+	host string,
+	path string,
+	functionNames ...string) *platform.APIGatewayConfig {
+
+	apiGatewayConfig := &platform.APIGatewayConfig{
+		Meta: platform.APIGatewayMeta{
+			Name:      name,
+			Namespace: "default-namespace",
+		},
+		Spec: platform.APIGatewaySpec{
+			Name: name,
+			Host: host,
+			Path: path,
+		},
+	}
+
+	for _, functionName := range functionNames {
+		apiGatewayConfig.Spec.Upstreams = append(apiGatewayConfig.Spec.Upstreams, platform.APIGatewayUpstreamSpec{
+			Kind: platform.APIGatewayUpstreamKindNuclioFunction,
+			NuclioFunction: &platform.NuclioFunctionAPIGatewaySpec{
+				Name: functionName,
+			},
+		})
+	}
+
+	return apiGatewayConfig
+}
+
+func (suite *ProxyTestSuite) resync(apiGatewayConfigs ...*platform.APIGatewayConfig) {
+	var apiGateways []platform.APIGateway
+	for _, apiGatewayConfig := range apiGatewayConfigs {
+		apiGateway, err := platform.NewAbstractAPIGateway(suite.logger, suite.mockedPlatform, *apiGatewayConfig)
+		suite.Require().NoError(err)
+		apiGateways = append(apiGateways, apiGateway)
+	}
+
+	// replace previously mocked api gateways
+	suite.mockedPlatform.ExpectedCalls = nil
+	suite.mockedPlatform.
+		On("GetNamespaces", mock.Anything).
+		Return([]string{"default-namespace"}, nil)
+	suite.mockedPlatform.
+		On("GetFunctions", mock.Anything, mock.Anything).
+		Return(suite.functions, nil)
+	suite.mockedPlatform.
+		On("GetAPIGateways", mock.Anything, mock.Anything).
+		Return(apiGateways, nil)
+
+	suite.Require().NoError(suite.proxy.Resync(suite.ctx))
+}
+
+func (suite *ProxyTestSuite) sendRequest(host string,
+	path string,
+	requestModifier func(*http.Request)) (int, string, string) {
+
+	request := httptest.NewRequest(http.MethodGet, "http://"+host+path, nil)
+	if requestModifier != nil {
+		requestModifier(request)
+	}
+
+	responseRecorder := httptest.NewRecorder()
+	suite.proxy.ServeHTTP(responseRecorder, request)
+
+	responseBody, err := io.ReadAll(responseRecorder.Body)
+	suite.Require().NoError(err)
+
+	return responseRecorder.Code, responseRecorder.Header().Get("X-Function-Name"), string(responseBody)
+}
+
+func TestProxyTestSuite(t *testing.T) {
+	suite.Run(t, new(ProxyTestSuite))
+}
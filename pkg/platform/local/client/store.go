@@ -45,6 +45,7 @@ const (
 	functionsDir      = baseDir + "/functions"
 	projectsDir       = baseDir + "/projects"
 	functionEventsDir = baseDir + "/function-events"
+	apiGatewaysDir    = baseDir + "/api-gateways"
 )
 
 type Store struct {
@@ -125,6 +126,20 @@ func (s *Store) DeleteProject(ctx context.Context, projectMeta *platform.Project
 		return errors.Wrap(err, "Failed to delete functions")
 	}
 
+	apiGateways, err := s.GetAPIGateways(&platform.GetAPIGatewaysOptions{
+		Namespace: projectMeta.Namespace,
+		Labels:    fmt.Sprintf("%s=%s", common.NuclioResourceLabelKeyProjectName, projectMeta.Name),
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get project api gateways")
+	}
+
+	for _, apiGateway := range apiGateways {
+		if err := s.DeleteAPIGateway(&apiGateway.GetConfig().Meta); err != nil {
+			return errors.Wrap(err, "Failed to delete api gateway")
+		}
+	}
+
 	return s.deleteResource(projectsDir, projectMeta.Namespace, projectMeta.Name)
 }
 
@@ -196,6 +211,62 @@ func (s *Store) DeleteFunctionEvent(functionEventMeta *platform.FunctionEventMet
 	return s.deleteResource(functionEventsDir, functionEventMeta.Namespace, functionEventMeta.Name)
 }
 
+//
+// API gateways
+//
+
+func (s *Store) CreateOrUpdateAPIGateway(apiGatewayConfig *platform.APIGatewayConfig) error {
+	resourcePath := s.getResourcePath(apiGatewaysDir, apiGatewayConfig.Meta.Namespace, apiGatewayConfig.Meta.Name)
+
+	// write the contents to that file name at the appropriate path
+	return s.serializeAndWriteFileContents(resourcePath, apiGatewayConfig)
+}
+
+func (s *Store) GetAPIGateways(getAPIGatewaysOptions *platform.GetAPIGatewaysOptions) ([]platform.APIGateway, error) {
+	var apiGateways []platform.APIGateway
+
+	// get label filter
+	labelsFilter := common.StringToStringMap(getAPIGatewaysOptions.Labels, "=")
+
+	rowHandler := func(row []byte) error {
+		apiGatewayConfig := platform.APIGatewayConfig{}
+
+		// unmarshal the row
+		if err := json.Unmarshal(row, &apiGatewayConfig); err != nil {
+			return errors.Wrap(err, "Failed to unmarshal api gateway")
+		}
+
+		// skip api gateways which don't have all the requested labels
+		for labelKey, labelValue := range labelsFilter {
+			if apiGatewayConfig.Meta.Labels[labelKey] != labelValue {
+				return nil
+			}
+		}
+
+		newAPIGateway, err := platform.NewAbstractAPIGateway(s.logger, s.platform, apiGatewayConfig)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create api gateway")
+		}
+
+		apiGateways = append(apiGateways, newAPIGateway)
+
+		return nil
+	}
+
+	if err := s.getResources(apiGatewaysDir,
+		getAPIGatewaysOptions.Namespace,
+		getAPIGatewaysOptions.Name,
+		rowHandler); err != nil {
+		return nil, errors.Wrap(err, "Failed to get api gateways")
+	}
+
+	return apiGateways, nil
+}
+
+func (s *Store) DeleteAPIGateway(apiGatewayMeta *platform.APIGatewayMeta) error {
+	return s.deleteResource(apiGatewaysDir, apiGatewayMeta.Namespace, apiGatewayMeta.Name)
+}
+
 //
 // Function (used only for the period before there's a docker container to represent the function)
 //
@@ -52,6 +52,7 @@ import (
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
 	nucliozap "github.com/nuclio/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 )
 
@@ -121,7 +122,12 @@ func NewPlatform(ctx context.Context,
 	}
 
 	// create a docker client
-	if newPlatform.dockerClient, err = dockerclient.NewShellClient(newPlatform.Logger, nil); err != nil {
+	var dockerClientKind dockerclient.ClientKind
+	if platformConfiguration.ContainerBuilderConfiguration != nil {
+		dockerClientKind = platformConfiguration.ContainerBuilderConfiguration.DockerClientKind
+	}
+
+	if newPlatform.dockerClient, err = dockerclient.NewClient(newPlatform.Logger, nil, dockerClientKind); err != nil {
 		return nil, errors.Wrap(err, "Failed to create a Docker client")
 	}
 
@@ -385,6 +391,12 @@ func (p *Platform) GetFunctions(ctx context.Context,
 	// enrich with build logs
 	p.EnrichFunctionsWithDeployLogStream(functions)
 
+	if getFunctionsOptions.EnrichWithAPIGateways {
+		if err = p.enrichFunctionsWithAPIGateways(ctx, functions, getFunctionsOptions.Namespace); err != nil {
+			return nil, errors.Wrap(err, "Failed to enrich functions with API gateways")
+		}
+	}
+
 	return functions, nil
 }
 
@@ -407,6 +419,11 @@ func (p *Platform) DeleteFunction(ctx context.Context, deleteFunctionOptions *pl
 		return nil
 	}
 
+	// user must clean api gateway before deleting the function
+	if err := p.validateFunctionHasNoAPIGateways(ctx, deleteFunctionOptions); err != nil {
+		return errors.Wrap(err, "Failed to validate that the function has no API gateways")
+	}
+
 	// actual function and its resources deletion
 	return p.delete(ctx, deleteFunctionOptions)
 }
@@ -695,9 +712,103 @@ func (p *Platform) GetFunctionEvents(ctx context.Context, getFunctionEventsOptio
 		functionEvents)
 }
 
-// GetAPIGateways not supported on this platform
+// CreateAPIGateway creates a new api gateway. the api gateway is served by the local api gateway proxy
+func (p *Platform) CreateAPIGateway(ctx context.Context,
+	createAPIGatewayOptions *platform.CreateAPIGatewayOptions) error {
+
+	existingAPIGateway, err := p.getAPIGateway(ctx, &createAPIGatewayOptions.APIGatewayConfig.Meta)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get api gateway")
+	}
+
+	if existingAPIGateway != nil {
+		return nuclio.NewErrConflict(fmt.Sprintf("Api gateway '%s' already exists",
+			createAPIGatewayOptions.APIGatewayConfig.Meta.Name))
+	}
+
+	// enrich
+	p.enrichAPIGatewayConfig(ctx, createAPIGatewayOptions.APIGatewayConfig, nil)
+
+	// validate
+	if err := p.validateAPIGatewayConfig(ctx,
+		createAPIGatewayOptions.APIGatewayConfig,
+		createAPIGatewayOptions.ValidateFunctionsExistence,
+		nil); err != nil {
+		return errors.Wrap(err, "Failed to validate and enrich an API-gateway name")
+	}
+
+	now := metav1.Now()
+	createAPIGatewayOptions.APIGatewayConfig.Meta.CreationTimestamp = &now
+
+	// there's nothing to provision, the proxy picks up the api gateway once it's stored
+	createAPIGatewayOptions.APIGatewayConfig.Status.State = platform.APIGatewayStateReady
+
+	return p.localStore.CreateOrUpdateAPIGateway(createAPIGatewayOptions.APIGatewayConfig)
+}
+
+// UpdateAPIGateway will update a previously existing api gateway
+func (p *Platform) UpdateAPIGateway(ctx context.Context, updateAPIGatewayOptions *platform.UpdateAPIGatewayOptions) error {
+	existingAPIGateway, err := p.getAPIGateway(ctx, &updateAPIGatewayOptions.APIGatewayConfig.Meta)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get api gateway")
+	}
+
+	if existingAPIGateway == nil {
+		return nuclio.NewErrNotFound(fmt.Sprintf("Api gateway '%s' does not exist",
+			updateAPIGatewayOptions.APIGatewayConfig.Meta.Name))
+	}
+
+	// enrich
+	p.enrichAPIGatewayConfig(ctx, updateAPIGatewayOptions.APIGatewayConfig, existingAPIGateway)
+
+	// validate
+	if err := p.validateAPIGatewayConfig(ctx,
+		updateAPIGatewayOptions.APIGatewayConfig,
+		updateAPIGatewayOptions.ValidateFunctionsExistence,
+		existingAPIGateway); err != nil {
+		return errors.Wrap(err, "Failed to validate api gateway")
+	}
+
+	updateAPIGatewayOptions.APIGatewayConfig.Meta.CreationTimestamp = existingAPIGateway.Meta.CreationTimestamp
+	updateAPIGatewayOptions.APIGatewayConfig.Status = platform.APIGatewayStatus{
+		Name:  updateAPIGatewayOptions.APIGatewayConfig.Meta.Name,
+		State: platform.APIGatewayStateReady,
+	}
+
+	return p.localStore.CreateOrUpdateAPIGateway(updateAPIGatewayOptions.APIGatewayConfig)
+}
+
+// DeleteAPIGateway will delete a previously existing api gateway
+func (p *Platform) DeleteAPIGateway(ctx context.Context, deleteAPIGatewayOptions *platform.DeleteAPIGatewayOptions) error {
+
+	// validate
+	if err := p.validateAPIGatewayMeta(&deleteAPIGatewayOptions.Meta); err != nil {
+		return errors.Wrap(err, "Failed to validate an API-gateway's metadata")
+	}
+
+	existingAPIGateway, err := p.getAPIGateway(ctx, &deleteAPIGatewayOptions.Meta)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get api gateway")
+	}
+
+	if existingAPIGateway == nil {
+		return nuclio.NewErrNotFound(fmt.Sprintf("Api gateway '%s' does not exist",
+			deleteAPIGatewayOptions.Meta.Name))
+	}
+
+	p.Logger.DebugWithCtx(ctx, "Deleting api gateway", "name", deleteAPIGatewayOptions.Meta.Name)
+
+	return p.localStore.DeleteAPIGateway(&deleteAPIGatewayOptions.Meta)
+}
+
+// GetAPIGateways will list existing api gateways
 func (p *Platform) GetAPIGateways(ctx context.Context, getAPIGatewaysOptions *platform.GetAPIGatewaysOptions) ([]platform.APIGateway, error) {
-	return nil, nil
+	apiGateways, err := p.localStore.GetAPIGateways(getAPIGatewaysOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read api gateways from a local store")
+	}
+
+	return apiGateways, nil
 }
 
 // GetExternalIPAddresses returns the external IP addresses invocations will use.
@@ -1488,3 +1599,203 @@ func (p *Platform) resolveFunctionSpecRequestMemory(functionSpec functionconfig.
 	}
 	return ""
 }
+
+func (p *Platform) getAPIGateway(ctx context.Context,
+	apiGatewayMeta *platform.APIGatewayMeta) (*platform.APIGatewayConfig, error) {
+
+	// an api gateway without a name is a new one
+	if apiGatewayMeta.Name == "" {
+		return nil, nil
+	}
+
+	apiGateways, err := p.GetAPIGateways(ctx, &platform.GetAPIGatewaysOptions{
+		Name:      apiGatewayMeta.Name,
+		Namespace: apiGatewayMeta.Namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get api gateways")
+	}
+
+	if len(apiGateways) == 0 {
+		return nil, nil
+	}
+
+	return apiGateways[0].GetConfig(), nil
+}
+
+func (p *Platform) enrichAPIGatewayConfig(ctx context.Context,
+	apiGatewayConfig *platform.APIGatewayConfig,
+	existingAPIGatewayConfig *platform.APIGatewayConfig) {
+
+	// meta
+	if apiGatewayConfig.Meta.Name == "" {
+		apiGatewayConfig.Meta.Name = apiGatewayConfig.Spec.Name
+	}
+
+	// spec
+	if apiGatewayConfig.Spec.Name == "" {
+		apiGatewayConfig.Spec.Name = apiGatewayConfig.Meta.Name
+	}
+
+	if apiGatewayConfig.Meta.Labels == nil {
+		apiGatewayConfig.Meta.Labels = map[string]string{}
+	}
+
+	// enrich project name if not exists or value is empty
+	if existingAPIGatewayConfig != nil {
+		if value, exist := apiGatewayConfig.Meta.Labels[common.NuclioResourceLabelKeyProjectName]; value == "" || !exist {
+			apiGatewayConfig.Meta.Labels[common.NuclioResourceLabelKeyProjectName] =
+				existingAPIGatewayConfig.Meta.Labels[common.NuclioResourceLabelKeyProjectName]
+		}
+	}
+
+	p.EnrichLabels(ctx, apiGatewayConfig.Meta.Labels)
+}
+
+func (p *Platform) validateAPIGatewayMeta(apiGatewayMeta *platform.APIGatewayMeta) error {
+	if apiGatewayMeta.Name == "" {
+		return nuclio.NewErrBadRequest("Api gateway name must be provided in metadata")
+	}
+
+	if apiGatewayMeta.Namespace == "" {
+		return nuclio.NewErrBadRequest("Api gateway namespace must be provided in metadata")
+	}
+
+	return nil
+}
+
+func (p *Platform) validateAPIGatewayConfig(ctx context.Context,
+	apiGatewayConfig *platform.APIGatewayConfig,
+	validateFunctionsExistence bool,
+	existingAPIGatewayConfig *platform.APIGatewayConfig) error {
+
+	// general validations
+	if apiGatewayConfig.Spec.Name != apiGatewayConfig.Meta.Name {
+		return nuclio.NewErrBadRequest("Api gateway metadata.name must match api gateway spec.name")
+	}
+
+	// not a reserved name
+	if common.StringInSlice(apiGatewayConfig.Spec.Name, p.ResolveReservedResourceNames()) {
+		return nuclio.NewErrPreconditionFailed(fmt.Sprintf("Api gateway name '%s' is reserved and cannot be used",
+			apiGatewayConfig.Spec.Name))
+	}
+
+	// meta
+	if err := p.validateAPIGatewayMeta(&apiGatewayConfig.Meta); err != nil {
+		return errors.Wrap(err, "Failed to validate API-gateway metadata")
+	}
+
+	// spec
+	if err := platform.ValidateAPIGatewaySpec(&apiGatewayConfig.Spec); err != nil {
+		return errors.Wrap(err, "Failed to validate the API-gateway spec")
+	}
+
+	if existingAPIGatewayConfig != nil {
+		if existingAPIGatewayConfig.Meta.Labels[common.NuclioResourceLabelKeyProjectName] !=
+			apiGatewayConfig.Meta.Labels[common.NuclioResourceLabelKeyProjectName] {
+			return nuclio.NewErrBadRequest("Changing project name to an existing api gateway is not allowed")
+		}
+	}
+
+	if validateFunctionsExistence {
+		for _, upstream := range apiGatewayConfig.Spec.Upstreams {
+			functions, err := p.localStore.GetFunctions(&functionconfig.Meta{
+				Name:      upstream.NuclioFunction.Name,
+				Namespace: apiGatewayConfig.Meta.Namespace,
+			})
+			if err != nil {
+				return errors.Wrap(err, "Failed to get upstream function")
+			}
+
+			if len(functions) == 0 {
+				return nuclio.NewErrPreconditionFailed(fmt.Sprintf("Function %s does not exist",
+					upstream.NuclioFunction.Name))
+			}
+		}
+	}
+
+	// the proxy routes by host and path, so these must not be taken by another api gateway
+	apiGateways, err := p.GetAPIGateways(ctx, &platform.GetAPIGatewaysOptions{
+		Namespace: apiGatewayConfig.Meta.Namespace,
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to get api gateways")
+	}
+
+	for _, apiGateway := range apiGateways {
+		otherAPIGatewayConfig := apiGateway.GetConfig()
+		if otherAPIGatewayConfig.Meta.Name == apiGatewayConfig.Meta.Name {
+			continue
+		}
+
+		if otherAPIGatewayConfig.Spec.Host == apiGatewayConfig.Spec.Host &&
+			strings.Trim(otherAPIGatewayConfig.Spec.Path, "/") == strings.Trim(apiGatewayConfig.Spec.Path, "/") {
+			return nuclio.NewErrConflict(fmt.Sprintf("Host and path are already in use by api gateway '%s'",
+				otherAPIGatewayConfig.Meta.Name))
+		}
+	}
+
+	return nil
+}
+
+func (p *Platform) generateFunctionToAPIGatewaysMapping(ctx context.Context, namespace string) (map[string][]string, error) {
+	functionToAPIGateways := map[string][]string{}
+
+	// get all api gateways in the namespace
+	apiGateways, err := p.GetAPIGateways(ctx, &platform.GetAPIGatewaysOptions{
+		Namespace: namespace,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get api gateways")
+	}
+
+	for _, apiGateway := range apiGateways {
+		for _, upstream := range apiGateway.GetConfig().Spec.Upstreams {
+			if upstream.NuclioFunction == nil {
+				continue
+			}
+
+			functionToAPIGateways[upstream.NuclioFunction.Name] =
+				append(functionToAPIGateways[upstream.NuclioFunction.Name], apiGateway.GetConfig().Meta.Name)
+		}
+	}
+
+	return functionToAPIGateways, nil
+}
+
+func (p *Platform) enrichFunctionsWithAPIGateways(ctx context.Context,
+	functions []platform.Function,
+	namespace string) error {
+
+	// no functions to enrich
+	if len(functions) == 0 {
+		return nil
+	}
+
+	functionToAPIGateways, err := p.generateFunctionToAPIGatewaysMapping(ctx, namespace)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get a function to API-gateways mapping")
+	}
+
+	for _, function := range functions {
+		function.GetStatus().APIGateways = functionToAPIGateways[function.GetConfig().Meta.Name]
+	}
+
+	return nil
+}
+
+func (p *Platform) validateFunctionHasNoAPIGateways(ctx context.Context,
+	deleteFunctionOptions *platform.DeleteFunctionOptions) error {
+
+	functionToAPIGateways, err := p.generateFunctionToAPIGatewaysMapping(ctx,
+		deleteFunctionOptions.FunctionConfig.Meta.Namespace)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get a function to API-gateways mapping")
+	}
+
+	if len(functionToAPIGateways[deleteFunctionOptions.FunctionConfig.Meta.Name]) > 0 {
+		return platform.ErrFunctionIsUsedByAPIGateways
+	}
+
+	return nil
+}
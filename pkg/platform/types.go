@@ -456,15 +456,29 @@ type NuclioFunctionAPIGatewaySpec struct {
 	Name string `json:"name,omitempty"`
 }
 
+// APIGatewayUpstreamMatchSpec routes requests to a canary upstream by a header or a cookie, regardless of weights.
+// when only a header or a cookie name is given, its value must be "always" (route to the upstream) or "never"
+type APIGatewayUpstreamMatchSpec struct {
+	Header      string `json:"header,omitempty"`
+	HeaderValue string `json:"headerValue,omitempty"`
+	Cookie      string `json:"cookie,omitempty"`
+}
+
 type APIGatewayUpstreamSpec struct {
 	Kind             APIGatewayUpstreamKind        `json:"kind,omitempty"`
 	NuclioFunction   *NuclioFunctionAPIGatewaySpec `json:"nucliofunction,omitempty"`
 	Percentage       int                           `json:"percentage,omitempty"`
+	Match            *APIGatewayUpstreamMatchSpec  `json:"match,omitempty"`
 	RewriteTarget    string                        `json:"rewriteTarget,omitempty"`
 	ExtraAnnotations map[string]string             `json:"extraAnnotations,omitempty"`
 	ExtraLabels      map[string]string             `json:"extraLabels,omitempty"`
 }
 
+// IsCanary returns true if the upstream is given traffic by weight or by a match rule
+func (s *APIGatewayUpstreamSpec) IsCanary() bool {
+	return s.Percentage != 0 || s.Match != nil
+}
+
 type APIGatewaySpec struct {
 	Host               string                        `json:"host,omitempty"`
 	Name               string                        `json:"name,omitempty"`
@@ -472,7 +486,54 @@ type APIGatewaySpec struct {
 	Path               string                        `json:"path,omitempty"`
 	AuthenticationMode ingress.AuthenticationMode    `json:"authenticationMode,omitempty"`
 	Authentication     *APIGatewayAuthenticationSpec `json:"authentication,omitempty"`
-	Upstreams          []APIGatewayUpstreamSpec      `json:"upstreams,omitempty"`
+
+	// a primary upstream and canary upstreams. when rendered as nginx ingresses, a single canary upstream
+	// is supported. any number of canary upstreams is supported when rendered as Gateway API HTTPRoutes
+	Upstreams []APIGatewayUpstreamSpec `json:"upstreams,omitempty"`
+}
+
+// ResolvePrimaryAndCanaryUpstreams returns the primary upstream, which gets the traffic that isn't routed to any
+// canary, and the canary upstreams by their order in the spec. the primary upstream is the one without a percentage
+// or a match rule. if all upstreams have a percentage, the last one without a match rule is the primary
+func (s *APIGatewaySpec) ResolvePrimaryAndCanaryUpstreams() (
+	*APIGatewayUpstreamSpec, []*APIGatewayUpstreamSpec, error) {
+
+	if len(s.Upstreams) == 0 {
+		return nil, nil, errors.New("One or more upstreams must be provided in spec")
+	}
+
+	primaryUpstreamIndex := -1
+	for upstreamIndex := range s.Upstreams {
+		if s.Upstreams[upstreamIndex].IsCanary() {
+			continue
+		}
+
+		if primaryUpstreamIndex != -1 {
+			return nil, nil, errors.New("Only one upstream may be set without a percentage or a match rule")
+		}
+		primaryUpstreamIndex = upstreamIndex
+	}
+
+	if primaryUpstreamIndex == -1 {
+		for upstreamIndex := range s.Upstreams {
+			if s.Upstreams[upstreamIndex].Match == nil {
+				primaryUpstreamIndex = upstreamIndex
+			}
+		}
+	}
+
+	if primaryUpstreamIndex == -1 {
+		return nil, nil, errors.New("One of the upstreams must be set without a match rule")
+	}
+
+	var canaryUpstreams []*APIGatewayUpstreamSpec
+	for upstreamIndex := range s.Upstreams {
+		if upstreamIndex != primaryUpstreamIndex {
+			canaryUpstreams = append(canaryUpstreams, &s.Upstreams[upstreamIndex])
+		}
+	}
+
+	return &s.Upstreams[primaryUpstreamIndex], canaryUpstreams, nil
 }
 
 type APIGatewayConfig struct {
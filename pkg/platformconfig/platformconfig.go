@@ -117,6 +117,10 @@ func NewPlatformConfig(configurationPath string) (*Config, error) {
 		config.Kube.DefaultServiceType = DefaultServiceType
 	}
 
+	if config.Kube.KEDA.DefaultLagThreshold == 0 {
+		config.Kube.KEDA.DefaultLagThreshold = DefaultKEDALagThreshold
+	}
+
 	if config.Kube.PreemptibleNodes != nil {
 		if config.Kube.PreemptibleNodes.DefaultMode == "" {
 			config.Kube.PreemptibleNodes.DefaultMode = functionconfig.RunOnPreemptibleNodesPrevent
@@ -137,6 +141,10 @@ func NewPlatformConfig(configurationPath string) (*Config, error) {
 		config.ScaleToZero.MultiTargetStrategy = scalertypes.MultiTargetStrategyRandom
 	}
 
+	if err := config.enrichIngressConfig(); err != nil {
+		return nil, errors.Wrap(err, "Failed to enrich ingress configuration")
+	}
+
 	// fall back to legacy default
 	if !AutoScaleMetricsModeIsValid(config.AutoScaleMetricsMode) {
 		config.AutoScaleMetricsMode = AutoScaleMetricsModeLegacy
@@ -197,7 +205,18 @@ func (c *Config) GetFunctionLoggerSinks(functionConfig *functionconfig.Config) (
 		loggerSinkBindings = c.Logger.Functions
 	}
 
-	return c.getLoggerSinksWithLevel(loggerSinkBindings)
+	loggerSinksWithLevel, err := c.getLoggerSinksWithLevel(loggerSinkBindings)
+	if err != nil {
+		return nil, err
+	}
+
+	// let the logger sinks know which function they log for
+	for loggerSinkName, loggerSinkWithLevel := range loggerSinksWithLevel {
+		loggerSinkWithLevel.functionMeta = &functionConfig.Meta
+		loggerSinksWithLevel[loggerSinkName] = loggerSinkWithLevel
+	}
+
+	return loggerSinksWithLevel, nil
 }
 
 func (c *Config) GetDefaultFunctionReadinessTimeout() time.Duration {
@@ -432,6 +451,33 @@ func (c *Config) enrichLocalPlatform() {
 	if c.Local.FunctionContainersHealthinessTimeout == 0 {
 		c.Local.FunctionContainersHealthinessTimeout = time.Second * 5
 	}
+
+	c.Local.APIGatewayProxy.Enabled = common.GetEnvOrDefaultBool(
+		"NUCLIO_LOCAL_API_GATEWAY_PROXY_ENABLED", c.Local.APIGatewayProxy.Enabled)
+
+	if c.Local.APIGatewayProxy.ListenAddress == "" {
+		c.Local.APIGatewayProxy.ListenAddress = ":8090"
+	}
+
+	if c.Local.APIGatewayProxy.ResyncInterval == 0 {
+		c.Local.APIGatewayProxy.ResyncInterval = time.Second * 5
+	}
+}
+
+func (c *Config) enrichIngressConfig() error {
+	switch c.IngressConfig.Kind {
+	case "":
+		c.IngressConfig.Kind = IngressKindIngress
+	case IngressKindIngress:
+	case IngressKindHTTPRoute:
+		if c.IngressConfig.GatewayAPI.GatewayName == "" {
+			return errors.New("A gateway name must be configured when generating HTTPRoutes")
+		}
+	default:
+		return errors.Errorf("Unsupported ingress kind: %s", c.IngressConfig.Kind)
+	}
+
+	return nil
 }
 
 func (c *Config) enrichOpaConfig() {
@@ -696,6 +696,61 @@ func (suite *PlatformConfigTestSuite) TestEnrichContainerResourcesPartialDefault
 	suite.Require().Empty(resources.Limits["memory"])
 }
 
+func (suite *PlatformConfigTestSuite) TestEnrichIngressConfig() {
+	for _, testCase := range []struct {
+		name          string
+		ingressConfig IngressConfig
+		expectedKind  IngressKind
+		expectError   bool
+	}{
+		{
+			name:         "defaultsToIngress",
+			expectedKind: IngressKindIngress,
+		},
+		{
+			name: "httpRoute",
+			ingressConfig: IngressConfig{
+				Kind: IngressKindHTTPRoute,
+				GatewayAPI: GatewayAPIConfig{
+					GatewayName: "nuclio-gateway",
+				},
+			},
+			expectedKind: IngressKindHTTPRoute,
+		},
+		{
+			name: "httpRouteWithoutGateway",
+			ingressConfig: IngressConfig{
+				Kind: IngressKindHTTPRoute,
+			},
+			expectError: true,
+		},
+		{
+			name: "unsupportedKind",
+			ingressConfig: IngressConfig{
+				Kind: "virtualService",
+			},
+			expectError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			platformConfig := Config{
+				IngressConfig: testCase.ingressConfig,
+			}
+
+			err := platformConfig.enrichIngressConfig()
+			if testCase.expectError {
+				suite.Require().Error(err)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedKind, platformConfig.IngressConfig.Kind)
+			suite.Require().Equal(testCase.expectedKind == IngressKindHTTPRoute,
+				platformConfig.IngressConfig.HTTPRoutesEnabled())
+		})
+	}
+}
+
 func TestRegistryTestSuite(t *testing.T) {
 	suite.Run(t, new(PlatformConfigTestSuite))
 }
@@ -39,10 +39,8 @@ const (
 type LoggerSinkKind string
 
 const (
-	LoggerSinkKindStdout      LoggerSinkKind = "stdout"
-	LoggerSinkKindAppInsights LoggerSinkKind = "appinsights"
-
-	// LoggerSinkKindElasticsearch is not supported
+	LoggerSinkKindStdout        LoggerSinkKind = "stdout"
+	LoggerSinkKindAppInsights   LoggerSinkKind = "appinsights"
 	LoggerSinkKindElasticsearch LoggerSinkKind = "elasticsearch"
 )
 
@@ -56,13 +54,19 @@ type LoggerSinkWithLevel struct {
 	Level string
 	Sink  LoggerSink
 
-	redactor *nucliozap.Redactor
+	redactor     *nucliozap.Redactor
+	functionMeta *functionconfig.Meta
 }
 
 func (l *LoggerSinkWithLevel) GetRedactingLogger() *nucliozap.Redactor {
 	return l.redactor
 }
 
+// GetFunctionMeta returns the meta of the function the logger sink logs for, nil for system logger sinks
+func (l *LoggerSinkWithLevel) GetFunctionMeta() *functionconfig.Meta {
+	return l.functionMeta
+}
+
 type LoggerSinkBinding struct {
 	Level string `json:"level,omitempty"`
 	Sink  string `json:"sink,omitempty"`
@@ -181,8 +185,24 @@ type PlatformKubeConfig struct {
 	DefaultSidecarResources          PodResourceRequirements `json:"defaultSidecarResources,omitempty"`
 	DefaultFunctionTolerations       []corev1.Toleration     `json:"defaultFunctionTolerations,omitempty"`
 	PreemptibleNodes                 *PreemptibleNodes       `json:"preemptibleNodes,omitempty"`
+	KEDA                             KEDAConfig              `json:"keda,omitempty"`
+}
+
+// KEDAConfig configures scaling stream triggered functions with KEDA ScaledObjects instead of HPAs
+type KEDAConfig struct {
+	Enabled bool `json:"enabled,omitempty"`
+
+	// how often (in seconds) KEDA checks the triggers, and how long (in seconds) it waits after the
+	// last activity before scaling to zero. KEDA's defaults are used when not set
+	PollingInterval int `json:"pollingInterval,omitempty"`
+	CooldownPeriod  int `json:"cooldownPeriod,omitempty"`
+
+	// the consumer lag / queue length per replica, unless set per trigger
+	DefaultLagThreshold int `json:"defaultLagThreshold,omitempty"`
 }
 
+const DefaultKEDALagThreshold = 10
+
 // PreemptibleNodes Holds data needed when user decided to run his function pods on a preemptible node (aka Spot node)
 type PreemptibleNodes struct {
 	DefaultMode    functionconfig.RunOnPreemptibleNodeMode `json:"defaultMode,omitempty"`
@@ -242,6 +262,14 @@ type PlatformLocalConfig struct {
 	DefaultFunctionContainerNetworkName   string                      `json:"defaultFunctionContainerNetworkName,omitempty"`
 	DefaultFunctionRestartPolicy          *dockerclient.RestartPolicy `json:"defaultFunctionRestartPolicy,omitempty"`
 	DefaultFunctionVolumes                []functionconfig.Volume     `json:"defaultFunctionVolumes,omitempty"`
+	APIGatewayProxy                       LocalAPIGatewayProxyConfig  `json:"apiGatewayProxy,omitempty"`
+}
+
+// LocalAPIGatewayProxyConfig configures the proxy serving api gateways on the local platform
+type LocalAPIGatewayProxyConfig struct {
+	Enabled        bool          `json:"enabled,omitempty"`
+	ListenAddress  string        `json:"listenAddress,omitempty"`
+	ResyncInterval time.Duration `json:"resyncInterval,omitempty"`
 }
 
 type ImageRegistryOverridesConfig struct {
@@ -259,6 +287,34 @@ type IngressConfig struct {
 	IguazioSignInURL           string   `json:"iguazioSignInURL,omitempty"`
 	AllowedAuthenticationModes []string `json:"allowedAuthenticationModes,omitempty"`
 	Oauth2ProxyURL             string   `json:"oauth2ProxyURL,omitempty"`
+
+	// Kind selects which resources expose functions and api gateways - ingresses (default) or
+	// Gateway API HTTPRoutes
+	Kind       IngressKind      `json:"kind,omitempty"`
+	GatewayAPI GatewayAPIConfig `json:"gatewayAPI,omitempty"`
+}
+
+// HTTPRoutesEnabled returns true if Gateway API HTTPRoutes are generated instead of ingresses
+func (c *IngressConfig) HTTPRoutesEnabled() bool {
+	return c.Kind == IngressKindHTTPRoute
+}
+
+type IngressKind string
+
+const (
+	IngressKindIngress   IngressKind = "ingress"
+	IngressKindHTTPRoute IngressKind = "httpRoute"
+)
+
+// GatewayAPIConfig holds the gateway to which generated HTTPRoutes attach
+type GatewayAPIConfig struct {
+	GatewayName      string `json:"gatewayName,omitempty"`
+	GatewayNamespace string `json:"gatewayNamespace,omitempty"`
+
+	// the gateway listeners routes attach to. TLS is terminated by the HTTPS listener, so routes
+	// of TLS-enabled hosts attach to it. when empty, routes attach to all the gateway's listeners
+	HTTPListenerName  string `json:"httpListenerName,omitempty"`
+	HTTPSListenerName string `json:"httpsListenerName,omitempty"`
 }
 
 type CronTriggerCreationMode string
@@ -329,6 +385,11 @@ func (sfc *SensitiveFieldsConfig) GetDefaultSensitiveFields() []string {
 		"^/spec/triggers/.+/attributes/accesscertificate$",
 		"^/spec/triggers/.+/attributes/sasl/password$",
 		"^/spec/triggers/.+/attributes/sasl/oauth/clientsecret$",
+		// - mqtt
+		"^/spec/triggers/.+/attributes/clientcertificate$",
+		"^/spec/triggers/.+/attributes/clientkey$",
+		// - http poller
+		"^/spec/triggers/.+/attributes/headers/authorization$",
 		// - kafka annotations
 		"^/metadata/annotations/nuclio.io/kafka-ca-cert$",
 		"^/metadata/annotations/nuclio.io/kafka-access-key$",
@@ -108,7 +108,7 @@ func NewAbstractRuntime(logger logger.Logger,
 
 	// create a docker client
 	if containerBuilderKind == "docker" {
-		newRuntime.DockerClient, err = dockerclient.NewShellClient(newRuntime.Logger, newRuntime.CmdRunner)
+		newRuntime.DockerClient, err = dockerclient.NewClient(newRuntime.Logger, newRuntime.CmdRunner, "")
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to create docker client")
 		}
@@ -0,0 +1,145 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevent
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"mime"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nuclio/errors"
+)
+
+// SpecVersion is the version of the CloudEvents specification outgoing events conform to
+const SpecVersion = "1.0"
+
+// StructuredContentType is the content type of an outgoing event in structured mode
+const StructuredContentType = "application/cloudevents+json"
+
+// Mode is the content mode of an outgoing event
+type Mode string
+
+const (
+
+	// ModeBinary carries the attributes in headers and the data as is in the body
+	ModeBinary Mode = "binary"
+
+	// ModeStructured carries both the attributes and the data in a JSON body
+	ModeStructured Mode = "structured"
+)
+
+// Attributes are the context attributes of an outgoing event
+type Attributes struct {
+	ID     string
+	Source string
+	Type   string
+	Time   time.Time
+}
+
+type structuredOutgoingEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            string          `json:"time,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	DataBase64      string          `json:"data_base64,omitempty"`
+}
+
+// EncodeBinaryHeaders returns the headers carrying the attributes of an event in binary mode
+func EncodeBinaryHeaders(attributes *Attributes) map[string]string {
+	encodedHeaders := map[string]string{
+		"ce-specversion": SpecVersion,
+		"ce-id":          attributes.ID,
+		"ce-source":      attributes.Source,
+		"ce-type":        attributes.Type,
+	}
+
+	if !attributes.Time.IsZero() {
+		encodedHeaders["ce-time"] = attributes.Time.UTC().Format(time.RFC3339Nano)
+	}
+
+	return encodedHeaders
+}
+
+// EncodeStructured returns the body of an event in structured mode, holding the attributes and the data
+func EncodeStructured(attributes *Attributes, dataContentType string, data []byte) ([]byte, error) {
+	event := structuredOutgoingEvent{
+		SpecVersion:     SpecVersion,
+		ID:              attributes.ID,
+		Source:          attributes.Source,
+		Type:            attributes.Type,
+		DataContentType: dataContentType,
+	}
+
+	if !attributes.Time.IsZero() {
+		event.Time = attributes.Time.UTC().Format(time.RFC3339Nano)
+	}
+
+	if len(data) > 0 {
+		switch {
+
+		// JSON data is embedded as is
+		case isJSONContentType(dataContentType) && json.Valid(data):
+			event.Data = data
+
+		// text is embedded as a string, anything else is base64 encoded
+		case isTextContentType(dataContentType) && utf8.Valid(data):
+			encodedData, err := json.Marshal(string(data))
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to encode data")
+			}
+
+			event.Data = encodedData
+		default:
+			event.DataBase64 = base64.StdEncoding.EncodeToString(data)
+		}
+	}
+
+	encodedEvent, err := json.Marshal(&event)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode event")
+	}
+
+	return encodedEvent, nil
+}
+
+func isJSONContentType(contentType string) bool {
+	mediaType := parseMediaType(contentType)
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func isTextContentType(contentType string) bool {
+	mediaType := parseMediaType(contentType)
+
+	// handlers returning strings usually don't bother setting a content type
+	return mediaType == "" || strings.HasPrefix(mediaType, "text/")
+}
+
+func parseMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	return mediaType
+}
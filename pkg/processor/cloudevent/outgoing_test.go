@@ -0,0 +1,124 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevent
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type outgoingTestSuite struct {
+	suite.Suite
+	attributes *Attributes
+}
+
+func (suite *outgoingTestSuite) SetupTest() {
+	suite.attributes = &Attributes{
+		ID:     "testID",
+		Source: "/nuclio/projects/testProject/functions/testFunction",
+		Type:   "testType",
+		Time:   time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+}
+
+func (suite *outgoingTestSuite) TestBinaryHeaders() {
+	suite.Require().Equal(map[string]string{
+		"ce-specversion": "1.0",
+		"ce-id":          "testID",
+		"ce-source":      "/nuclio/projects/testProject/functions/testFunction",
+		"ce-type":        "testType",
+		"ce-time":        "2023-01-02T03:04:05Z",
+	}, EncodeBinaryHeaders(suite.attributes))
+
+	// time is optional
+	suite.attributes.Time = time.Time{}
+	suite.Require().NotContains(EncodeBinaryHeaders(suite.attributes), "ce-time")
+}
+
+func (suite *outgoingTestSuite) TestStructured() {
+	for _, testCase := range []struct {
+		name            string
+		dataContentType string
+		data            []byte
+		expectedData    interface{}
+		expectedBase64  string
+	}{
+		{
+			name:            "JSON",
+			dataContentType: "application/json; charset=utf-8",
+			data:            []byte(`{"a": 1}`),
+			expectedData:    map[string]interface{}{"a": float64(1)},
+		},
+		{
+			name:            "InvalidJSON",
+			dataContentType: "application/json",
+			data:            []byte(`{"a"`),
+			expectedBase64:  "eyJhIg==",
+		},
+		{
+			name:            "Text",
+			dataContentType: "text/plain",
+			data:            []byte("hello"),
+			expectedData:    "hello",
+		},
+		{
+			name:         "NoContentType",
+			data:         []byte("hello"),
+			expectedData: "hello",
+		},
+		{
+			name:            "Binary",
+			dataContentType: "application/octet-stream",
+			data:            []byte{0, 1, 2},
+			expectedBase64:  "AAEC",
+		},
+		{
+			name:            "Empty",
+			dataContentType: "text/plain",
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			encodedEvent, err := EncodeStructured(suite.attributes, testCase.dataContentType, testCase.data)
+			suite.Require().NoError(err)
+
+			decodedEvent := map[string]interface{}{}
+			suite.Require().NoError(json.Unmarshal(encodedEvent, &decodedEvent))
+
+			suite.Require().Equal("1.0", decodedEvent["specversion"])
+			suite.Require().Equal("testID", decodedEvent["id"])
+			suite.Require().Equal("/nuclio/projects/testProject/functions/testFunction", decodedEvent["source"])
+			suite.Require().Equal("testType", decodedEvent["type"])
+			suite.Require().Equal("2023-01-02T03:04:05Z", decodedEvent["time"])
+			suite.Require().Equal(testCase.expectedData, decodedEvent["data"])
+
+			if testCase.expectedBase64 == "" {
+				suite.Require().NotContains(decodedEvent, "data_base64")
+			} else {
+				suite.Require().Equal(testCase.expectedBase64, decodedEvent["data_base64"])
+			}
+		})
+	}
+}
+
+func TestOutgoingTestSuite(t *testing.T) {
+	suite.Run(t, new(outgoingTestSuite))
+}
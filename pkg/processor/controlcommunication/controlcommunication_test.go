@@ -69,6 +69,23 @@ func (suite *ControlCommunicationTestSuite) TestSubscribeUnsubscribe() {
 	suite.Require().Equal(suite.broker.Consumers[0].channels[0], controlMessageChannel2)
 }
 
+func (suite *ControlCommunicationTestSuite) TestSubscribeTwice() {
+	controlMessageChannel := make(chan *ControlMessage, 2)
+
+	// subscribing the same channel again (e.g. through another worker) doesn't duplicate messages
+	for i := 0; i < 2; i++ {
+		err := suite.broker.Subscribe(WebSocketPushKind, controlMessageChannel)
+		suite.Require().NoError(err)
+	}
+
+	suite.Require().Len(suite.broker.Consumers, 1)
+	suite.Require().Len(suite.broker.Consumers[0].channels, 1)
+
+	err := suite.broker.SendToConsumers(&ControlMessage{Kind: WebSocketPushKind})
+	suite.Require().NoError(err)
+	suite.Require().Len(controlMessageChannel, 1)
+}
+
 func TestControlCommunicationTestSuite(t *testing.T) {
 	suite.Run(t, new(ControlCommunicationTestSuite))
 }
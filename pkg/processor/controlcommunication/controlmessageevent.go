@@ -22,6 +22,13 @@ import (
 	"github.com/nuclio/nuclio-sdk-go"
 )
 
+// control message events aren't received by any trigger
+type controlMessageTriggerInfoProvider struct{}
+
+func (ti *controlMessageTriggerInfoProvider) GetClass() string { return "control" }
+func (ti *controlMessageTriggerInfoProvider) GetKind() string  { return "controlMessage" }
+func (ti *controlMessageTriggerInfoProvider) GetName() string  { return "" }
+
 type ControlMessageEvent struct {
 	nuclio.AbstractEvent
 	resolvedBody *ControlMessage
@@ -41,9 +48,32 @@ func (cme *ControlMessageEvent) GetID() nuclio.ID {
 	return nuclio.ID(cme.resolvedBody.Kind)
 }
 
+// GetTriggerInfo returns a trigger info provider identifying the event as a control message
+func (cme *ControlMessageEvent) GetTriggerInfo() nuclio.TriggerInfoProvider {
+	return &controlMessageTriggerInfoProvider{}
+}
+
+// GetContentType returns the content type of the body
+func (cme *ControlMessageEvent) GetContentType() string {
+	return "application/json"
+}
+
+// GetBody returns the control message encoded as JSON, so that it's carried the same way by any event encoding
+func (cme *ControlMessageEvent) GetBody() []byte {
+	if cme.resolvedBody == nil {
+		return cme.AbstractEvent.GetBody()
+	}
+
+	encodedBody, err := json.Marshal(cme.resolvedBody)
+	if err != nil {
+		return nil
+	}
+
+	return encodedBody
+}
+
 // GetBodyObject returns the control message body of the event
 func (cme *ControlMessageEvent) GetBodyObject() interface{} {
-	eventBody := cme.GetBody()
 
 	// lazy load
 	if cme.resolvedBody != nil {
@@ -51,7 +81,7 @@ func (cme *ControlMessageEvent) GetBodyObject() interface{} {
 	}
 
 	message := &ControlMessage{}
-	if err := json.Unmarshal(eventBody, message); err != nil {
+	if err := json.Unmarshal(cme.AbstractEvent.GetBody(), message); err != nil {
 		return nil
 	}
 	cme.resolvedBody = message
@@ -27,6 +27,7 @@ type ControlMessageKind string
 
 const (
 	StreamMessageAckKind ControlMessageKind = "streamMessageAck"
+	WebSocketPushKind    ControlMessageKind = "webSocketPush"
 )
 
 // TODO: move to nuclio-sdk-go
@@ -41,6 +42,11 @@ type ControlMessageAttributesExplicitAck struct {
 	Offset    int64  `json:"offset"`
 }
 
+type ControlMessageAttributesWebSocketPush struct {
+	ConnectionID string `json:"connectionId"`
+	Body         string `json:"body"`
+}
+
 type ControlConsumer struct {
 	channels []chan *ControlMessage
 	kind     ControlMessageKind
@@ -77,8 +83,16 @@ func (c *ControlConsumer) Send(message *ControlMessage) error {
 	return nil
 }
 
-func (c *ControlConsumer) addChannel(channel chan *ControlMessage) {
-	c.channels = append(c.channels, channel)
+func (c *ControlConsumer) addChannel(channelToAdd chan *ControlMessage) {
+
+	// workers share the processor's broker, so a channel subscribed through each of them is only added once
+	for _, channel := range c.channels {
+		if channel == channelToAdd {
+			return
+		}
+	}
+
+	c.channels = append(c.channels, channelToAdd)
 }
 
 func (c *ControlConsumer) deleteChannel(channelToDelete chan *ControlMessage) {
@@ -17,11 +17,13 @@ limitations under the License.
 package appinsights
 
 import (
+	"sync"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/processor"
 	"github.com/nuclio/nuclio/pkg/processor/metricsink"
 	"github.com/nuclio/nuclio/pkg/processor/metricsink/prometheus"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
 
 	"github.com/microsoft/ApplicationInsights-Go/appinsights"
 	"github.com/nuclio/errors"
@@ -31,8 +33,11 @@ import (
 type MetricSink struct {
 	*metricsink.AbstractMetricSink
 	configuration *Configuration
-	gatherers     []prometheus.Gatherer
 	client        appinsights.TelemetryClient
+
+	// the gatherers of each trigger and its workers, keyed by trigger ID
+	gatherersLock sync.Mutex
+	gatherers     map[string][]prometheus.Gatherer
 }
 
 func newMetricSink(parentLogger logger.Logger,
@@ -60,6 +65,7 @@ func newMetricSink(parentLogger logger.Logger,
 		AbstractMetricSink: newAbstractMetricSink,
 		configuration:      configuration,
 		client:             client,
+		gatherers:          map[string][]prometheus.Gatherer{},
 	}
 
 	// create a bunch of gatherer
@@ -111,28 +117,46 @@ func (ms *MetricSink) Stop() chan struct{} {
 	return ms.AbstractMetricSink.Stop()
 }
 
-func (ms *MetricSink) createGatherers(metricProvider metricsink.MetricProvider) error {
+// AddTrigger starts gathering the metrics of a trigger created after the sink
+func (ms *MetricSink) AddTrigger(triggerInstance trigger.Trigger) error {
 
-	for _, trigger := range metricProvider.GetTriggers() {
+	// create a gatherer for the trigger
+	triggerGatherer, err := newTriggerGatherer(triggerInstance, ms.client)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create trigger gatherer")
+	}
 
-		// create a gatherer for the trigger
-		triggerGatherer, err := newTriggerGatherer(trigger, ms.client)
+	gatherers := []prometheus.Gatherer{triggerGatherer}
 
+	// now add workers
+	for _, worker := range triggerInstance.GetWorkers() {
+		workerGatherer, err := newWorkerGatherer(triggerInstance, worker, ms.client)
 		if err != nil {
-			return errors.Wrap(err, "Failed to create trigger gatherer")
+			return errors.Wrap(err, "Failed to create worker gatherer")
 		}
 
-		ms.gatherers = append(ms.gatherers, triggerGatherer)
+		gatherers = append(gatherers, workerGatherer)
+	}
 
-		// now add workers
-		for _, worker := range trigger.GetWorkers() {
-			workerGatherer, err := newWorkerGatherer(trigger, worker, ms.client)
+	ms.gatherersLock.Lock()
+	ms.gatherers[triggerInstance.GetID()] = gatherers
+	ms.gatherersLock.Unlock()
 
-			if err != nil {
-				return errors.Wrap(err, "Failed to create worker gatherer")
-			}
+	return nil
+}
 
-			ms.gatherers = append(ms.gatherers, workerGatherer)
+// RemoveTrigger stops gathering the metrics of a trigger that was deleted or replaced
+func (ms *MetricSink) RemoveTrigger(triggerInstance trigger.Trigger) {
+	ms.gatherersLock.Lock()
+	defer ms.gatherersLock.Unlock()
+
+	delete(ms.gatherers, triggerInstance.GetID())
+}
+
+func (ms *MetricSink) createGatherers(metricProvider metricsink.MetricProvider) error {
+	for _, triggerInstance := range metricProvider.GetTriggers() {
+		if err := ms.AddTrigger(triggerInstance); err != nil {
+			return err
 		}
 	}
 
@@ -140,10 +164,14 @@ func (ms *MetricSink) createGatherers(metricProvider metricsink.MetricProvider)
 }
 
 func (ms *MetricSink) gather() error {
+	ms.gatherersLock.Lock()
+	defer ms.gatherersLock.Unlock()
 
-	for _, gatherer := range ms.gatherers {
-		if err := gatherer.Gather(); err != nil {
-			return err
+	for _, gatherers := range ms.gatherers {
+		for _, gatherer := range gatherers {
+			if err := gatherer.Gather(); err != nil {
+				return err
+			}
 		}
 	}
 
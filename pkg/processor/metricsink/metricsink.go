@@ -16,7 +16,11 @@ limitations under the License.
 
 package metricsink
 
-import "github.com/nuclio/logger"
+import (
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/nuclio/logger"
+)
 
 type MetricSink interface {
 
@@ -31,6 +35,12 @@ type MetricSink interface {
 
 	// GetName returns the name of metric sink
 	GetName() string
+
+	// AddTrigger starts processing the metrics of a trigger created after the sink
+	AddTrigger(triggerInstance trigger.Trigger) error
+
+	// RemoveTrigger stops processing the metrics of a trigger that was deleted or replaced
+	RemoveTrigger(triggerInstance trigger.Trigger)
 }
 
 // AbstractMetricSink is the base struct for all metric sinks
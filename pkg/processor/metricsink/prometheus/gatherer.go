@@ -16,9 +16,136 @@ limitations under the License.
 
 package prometheus
 
+import (
+	"sync"
+
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
 // Gatherer is a reflection of an object in the processor (e.g. trigger, runtime, worker) that holds prometheus
 // metrics. when Gather() is called, the resource is queried for its primitive statistics. this way we decouple
 // prometheus metrics from the fast path
 type Gatherer interface {
 	Gather() error
 }
+
+// RegisteredGatherer is a gatherer whose metrics are registered in a registry, and can be removed from it
+type RegisteredGatherer interface {
+	Gatherer
+
+	// Unregister removes the gatherer's metrics from the registry
+	Unregister()
+}
+
+// TriggerGatherers holds the gatherers of the triggers and their workers, keyed by trigger ID. triggers
+// can be added and removed (e.g. as they're created and deleted through the web admin) while gathering
+type TriggerGatherers struct {
+	instanceName     string
+	logger           logger.Logger
+	metricRegistry   *prometheus.Registry
+	histogramBuckets *HistogramBuckets
+	lock             sync.Mutex
+	gatherers        map[string][]RegisteredGatherer
+}
+
+// NewTriggerGatherers creates trigger gatherers, registering their metrics in the given registry
+func NewTriggerGatherers(instanceName string,
+	logger logger.Logger,
+	metricRegistry *prometheus.Registry,
+	histogramBuckets *HistogramBuckets) *TriggerGatherers {
+	return &TriggerGatherers{
+		instanceName:     instanceName,
+		logger:           logger,
+		metricRegistry:   metricRegistry,
+		histogramBuckets: histogramBuckets,
+		gatherers:        map[string][]RegisteredGatherer{},
+	}
+}
+
+// AddTrigger creates the gatherers of a trigger and its workers
+func (tgs *TriggerGatherers) AddTrigger(triggerInstance trigger.Trigger) error {
+	tgs.lock.Lock()
+	defer tgs.lock.Unlock()
+
+	// create a gatherer for the trigger
+	triggerGatherer, err := NewTriggerGatherer(tgs.instanceName,
+		triggerInstance,
+		tgs.logger,
+		tgs.metricRegistry,
+		tgs.histogramBuckets)
+
+	if err != nil {
+		return errors.Wrap(err, "Failed to create trigger gatherer")
+	}
+
+	gatherers := []RegisteredGatherer{triggerGatherer}
+
+	// now add workers
+	for _, worker := range triggerInstance.GetWorkers() {
+		workerGatherer, err := NewWorkerGatherer(tgs.instanceName,
+			triggerInstance,
+			tgs.logger,
+			worker,
+			tgs.metricRegistry)
+
+		if err != nil {
+			tgs.unregister(gatherers)
+			return errors.Wrap(err, "Failed to create worker gatherer")
+		}
+
+		gatherers = append(gatherers, workerGatherer)
+	}
+
+	tgs.gatherers[triggerInstance.GetID()] = gatherers
+
+	return nil
+}
+
+// RemoveTrigger removes the gatherers of a trigger and its workers, along with their metrics
+func (tgs *TriggerGatherers) RemoveTrigger(triggerInstance trigger.Trigger) {
+	tgs.lock.Lock()
+	defer tgs.lock.Unlock()
+
+	tgs.unregister(tgs.gatherers[triggerInstance.GetID()])
+	delete(tgs.gatherers, triggerInstance.GetID())
+}
+
+// Gather gathers the metrics of all triggers. gatherings don't run concurrently, as trigger and worker
+// diffs are not atomic (swapping cur <-> prev)
+func (tgs *TriggerGatherers) Gather() error {
+	tgs.lock.Lock()
+	defer tgs.lock.Unlock()
+
+	for _, gatherers := range tgs.gatherers {
+		for _, gatherer := range gatherers {
+			if err := gatherer.Gather(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetNumGatherers returns the number of trigger and worker gatherers
+func (tgs *TriggerGatherers) GetNumGatherers() int {
+	tgs.lock.Lock()
+	defer tgs.lock.Unlock()
+
+	numGatherers := 0
+	for _, gatherers := range tgs.gatherers {
+		numGatherers += len(gatherers)
+	}
+
+	return numGatherers
+}
+
+func (tgs *TriggerGatherers) unregister(gatherers []RegisteredGatherer) {
+	for _, gatherer := range gatherers {
+		gatherer.Unregister()
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"github.com/nuclio/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// HistogramBuckets holds the upper bounds of the latency histogram buckets, in seconds
+type HistogramBuckets struct {
+	EventHandlingDuration        []float64
+	WorkerAllocationWaitDuration []float64
+}
+
+// NewHistogramBuckets validates the given buckets, defaulting to the prometheus default buckets if not provided
+func NewHistogramBuckets(eventHandlingDurationBuckets []float64,
+	workerAllocationWaitDurationBuckets []float64) (*HistogramBuckets, error) {
+
+	for _, buckets := range [][]float64{
+		eventHandlingDurationBuckets,
+		workerAllocationWaitDurationBuckets,
+	} {
+		for bucketIndex := 1; bucketIndex < len(buckets); bucketIndex++ {
+			if buckets[bucketIndex] <= buckets[bucketIndex-1] {
+				return nil, errors.Errorf("Histogram buckets must be sorted in increasing order, got %v", buckets)
+			}
+		}
+	}
+
+	if len(eventHandlingDurationBuckets) == 0 {
+		eventHandlingDurationBuckets = prometheus.DefBuckets
+	}
+
+	if len(workerAllocationWaitDurationBuckets) == 0 {
+		workerAllocationWaitDurationBuckets = prometheus.DefBuckets
+	}
+
+	return &HistogramBuckets{
+		EventHandlingDuration:        eventHandlingDurationBuckets,
+		WorkerAllocationWaitDuration: workerAllocationWaitDurationBuckets,
+	}, nil
+}
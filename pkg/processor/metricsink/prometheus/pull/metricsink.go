@@ -21,12 +21,12 @@ import (
 	"context"
 	"net/http"
 	"os"
-	"sync"
 	"text/template"
 
 	"github.com/nuclio/nuclio/pkg/processor"
 	"github.com/nuclio/nuclio/pkg/processor/metricsink"
 	"github.com/nuclio/nuclio/pkg/processor/metricsink/prometheus"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
@@ -39,10 +39,9 @@ type MetricSink struct {
 	configuration         *Configuration
 	metricRegistry        *prometheusclient.Registry
 	metricRegistryHandler http.Handler
-	gatherers             []prometheus.Gatherer
+	triggerGatherers      *prometheus.TriggerGatherers
 	httpServer            *http.Server
 	instanceName          string
-	gatherLock            sync.Locker
 }
 
 func newMetricSink(parentLogger logger.Logger,
@@ -63,7 +62,6 @@ func newMetricSink(parentLogger logger.Logger,
 		AbstractMetricSink: newAbstractMetricSink,
 		configuration:      configuration,
 		metricRegistry:     prometheusclient.NewRegistry(),
-		gatherLock:         &sync.Mutex{},
 	}
 
 	newMetricPuller.instanceName, err = newMetricPuller.getInstanceName(processorConfiguration)
@@ -80,7 +78,7 @@ func newMetricSink(parentLogger logger.Logger,
 		"env", os.Getenv("NUCLIO_FUNCTION_INSTANCE"),
 		"instanceName", newMetricPuller.instanceName,
 		"listenAddr", configuration.URL,
-		"gatherers", len(newMetricPuller.gatherers))
+		"gatherers", newMetricPuller.triggerGatherers.GetNumGatherers())
 
 	return newMetricPuller, nil
 }
@@ -142,35 +140,25 @@ func (ms *MetricSink) listen() error {
 	return nil
 }
 
-func (ms *MetricSink) createGatherers(metricProvider metricsink.MetricProvider) error {
-
-	for _, trigger := range metricProvider.GetTriggers() {
-
-		// create a gatherer for the trigger
-		triggerGatherer, err := prometheus.NewTriggerGatherer(ms.instanceName,
-			trigger,
-			ms.Logger,
-			ms.metricRegistry)
-
-		if err != nil {
-			return errors.Wrap(err, "Failed to create trigger gatherer")
-		}
-
-		ms.gatherers = append(ms.gatherers, triggerGatherer)
+// AddTrigger starts gathering the metrics of a trigger created after the sink
+func (ms *MetricSink) AddTrigger(triggerInstance trigger.Trigger) error {
+	return ms.triggerGatherers.AddTrigger(triggerInstance)
+}
 
-		// now add workers
-		for _, worker := range trigger.GetWorkers() {
-			workerGatherer, err := prometheus.NewWorkerGatherer(ms.instanceName,
-				trigger,
-				ms.Logger,
-				worker,
-				ms.metricRegistry)
+// RemoveTrigger stops gathering the metrics of a trigger that was deleted or replaced
+func (ms *MetricSink) RemoveTrigger(triggerInstance trigger.Trigger) {
+	ms.triggerGatherers.RemoveTrigger(triggerInstance)
+}
 
-			if err != nil {
-				return errors.Wrap(err, "Failed to create worker gatherer")
-			}
+func (ms *MetricSink) createGatherers(metricProvider metricsink.MetricProvider) error {
+	ms.triggerGatherers = prometheus.NewTriggerGatherers(ms.instanceName,
+		ms.Logger,
+		ms.metricRegistry,
+		ms.configuration.histogramBuckets)
 
-			ms.gatherers = append(ms.gatherers, workerGatherer)
+	for _, triggerInstance := range metricProvider.GetTriggers() {
+		if err := ms.triggerGatherers.AddTrigger(triggerInstance); err != nil {
+			return err
 		}
 	}
 
@@ -181,17 +169,8 @@ func (ms *MetricSink) createGatherers(metricProvider metricsink.MetricProvider)
 
 func (ms *MetricSink) gather() error {
 
-	// protect against concurrent gatherings, trigger and worker diffs are not atomic (swapping cur <-> prev)
-	ms.gatherLock.Lock()
-	defer ms.gatherLock.Unlock()
-
-	for _, gatherer := range ms.gatherers {
-		if err := gatherer.Gather(); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	// gatherings don't run concurrently, as trigger and worker diffs are not atomic (swapping cur <-> prev)
+	return ms.triggerGatherers.Gather()
 }
 
 func (ms *MetricSink) getInstanceName(processorConfiguration *processor.Configuration) (string, error) {
@@ -21,6 +21,7 @@ import (
 
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/processor/metricsink"
+	"github.com/nuclio/nuclio/pkg/processor/metricsink/prometheus"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/nuclio/errors"
@@ -28,7 +29,10 @@ import (
 
 type Configuration struct {
 	metricsink.Configuration
-	InstanceName string
+	InstanceName                        string
+	EventHandlingDurationBuckets        []float64
+	WorkerAllocationWaitDurationBuckets []float64
+	histogramBuckets                    *prometheus.HistogramBuckets
 }
 
 func NewConfiguration(name string, metricSinkConfiguration *platformconfig.MetricSink) (*Configuration, error) {
@@ -42,6 +46,14 @@ func NewConfiguration(name string, metricSinkConfiguration *platformconfig.Metri
 		return nil, errors.Wrap(err, "Failed to decode attributes")
 	}
 
+	histogramBuckets, err := prometheus.NewHistogramBuckets(newConfiguration.EventHandlingDurationBuckets,
+		newConfiguration.WorkerAllocationWaitDurationBuckets)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve histogram buckets")
+	}
+
+	newConfiguration.histogramBuckets = histogramBuckets
+
 	if newConfiguration.URL == "" {
 		newConfiguration.URL = ":8090"
 	}
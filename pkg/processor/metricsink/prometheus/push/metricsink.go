@@ -22,6 +22,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/processor"
 	"github.com/nuclio/nuclio/pkg/processor/metricsink"
 	"github.com/nuclio/nuclio/pkg/processor/metricsink/prometheus"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
@@ -31,9 +32,9 @@ import (
 
 type MetricSink struct {
 	*metricsink.AbstractMetricSink
-	configuration  *Configuration
-	metricRegistry *prometheusclient.Registry
-	gatherers      []prometheus.Gatherer
+	configuration    *Configuration
+	metricRegistry   *prometheusclient.Registry
+	triggerGatherers *prometheus.TriggerGatherers
 }
 
 func newMetricSink(parentLogger logger.Logger,
@@ -118,35 +119,25 @@ func (ms *MetricSink) pushPeriodically() {
 	}
 }
 
-func (ms *MetricSink) createGatherers(metricProvider metricsink.MetricProvider) error {
-
-	for _, trigger := range metricProvider.GetTriggers() {
-
-		// create a gatherer for the trigger
-		triggerGatherer, err := prometheus.NewTriggerGatherer(ms.configuration.InstanceName,
-			trigger,
-			ms.Logger,
-			ms.metricRegistry)
-
-		if err != nil {
-			return errors.Wrap(err, "Failed to create trigger gatherer")
-		}
-
-		ms.gatherers = append(ms.gatherers, triggerGatherer)
+// AddTrigger starts gathering the metrics of a trigger created after the sink
+func (ms *MetricSink) AddTrigger(triggerInstance trigger.Trigger) error {
+	return ms.triggerGatherers.AddTrigger(triggerInstance)
+}
 
-		// now add workers
-		for _, worker := range trigger.GetWorkers() {
-			workerGatherer, err := prometheus.NewWorkerGatherer(ms.configuration.InstanceName,
-				trigger,
-				ms.Logger,
-				worker,
-				ms.metricRegistry)
+// RemoveTrigger stops gathering the metrics of a trigger that was deleted or replaced
+func (ms *MetricSink) RemoveTrigger(triggerInstance trigger.Trigger) {
+	ms.triggerGatherers.RemoveTrigger(triggerInstance)
+}
 
-			if err != nil {
-				return errors.Wrap(err, "Failed to create worker gatherer")
-			}
+func (ms *MetricSink) createGatherers(metricProvider metricsink.MetricProvider) error {
+	ms.triggerGatherers = prometheus.NewTriggerGatherers(ms.configuration.InstanceName,
+		ms.Logger,
+		ms.metricRegistry,
+		ms.configuration.histogramBuckets)
 
-			ms.gatherers = append(ms.gatherers, workerGatherer)
+	for _, triggerInstance := range metricProvider.GetTriggers() {
+		if err := ms.triggerGatherers.AddTrigger(triggerInstance); err != nil {
+			return err
 		}
 	}
 
@@ -154,12 +145,5 @@ func (ms *MetricSink) createGatherers(metricProvider metricsink.MetricProvider)
 }
 
 func (ms *MetricSink) gather() error {
-
-	for _, gatherer := range ms.gatherers {
-		if err := gatherer.Gather(); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return ms.triggerGatherers.Gather()
 }
@@ -22,6 +22,7 @@ import (
 
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/processor/metricsink"
+	"github.com/nuclio/nuclio/pkg/processor/metricsink/prometheus"
 
 	"github.com/mitchellh/mapstructure"
 	"github.com/nuclio/errors"
@@ -29,10 +30,13 @@ import (
 
 type Configuration struct {
 	metricsink.Configuration
-	Interval       string
-	JobName        string
-	InstanceName   string
-	parsedInterval time.Duration
+	Interval                            string
+	JobName                             string
+	InstanceName                        string
+	EventHandlingDurationBuckets        []float64
+	WorkerAllocationWaitDurationBuckets []float64
+	parsedInterval                      time.Duration
+	histogramBuckets                    *prometheus.HistogramBuckets
 }
 
 func NewConfiguration(name string, metricSinkConfiguration *platformconfig.MetricSink) (*Configuration, error) {
@@ -46,8 +50,15 @@ func NewConfiguration(name string, metricSinkConfiguration *platformconfig.Metri
 		return nil, errors.Wrap(err, "Failed to decode attributes")
 	}
 
+	histogramBuckets, err := prometheus.NewHistogramBuckets(newConfiguration.EventHandlingDurationBuckets,
+		newConfiguration.WorkerAllocationWaitDurationBuckets)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to resolve histogram buckets")
+	}
+
+	newConfiguration.histogramBuckets = histogramBuckets
+
 	// try to parse the interval
-	var err error
 	newConfiguration.parsedInterval, err = time.ParseDuration(newConfiguration.Interval)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to parse interval")
@@ -17,6 +17,8 @@ limitations under the License.
 package prometheus
 
 import (
+	"time"
+
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 
 	"github.com/nuclio/errors"
@@ -28,21 +30,27 @@ type TriggerGatherer struct {
 	trigger                                     trigger.Trigger
 	logger                                      logger.Logger
 	handledEventsTotal                          *prometheus.CounterVec
+	eventHandlingDurationSeconds                *prometheus.HistogramVec
+	workerAllocationWaitDurationSeconds         *prometheus.HistogramVec
 	workerAllocationCount                       prometheus.Counter
 	workerAllocationTotal                       *prometheus.CounterVec
 	workerAllocationWaitDurationMilliSecondsSum prometheus.Counter
 	workerAllocationWorkersAvailablePercentage  prometheus.Counter
 	prevStatistics                              trigger.Statistics
+	metricRegistry                              *prometheus.Registry
+	collectors                                  []prometheus.Collector
 }
 
 func NewTriggerGatherer(instanceName string,
 	trigger trigger.Trigger,
 	logger logger.Logger,
-	metricRegistry *prometheus.Registry) (*TriggerGatherer, error) {
+	metricRegistry *prometheus.Registry,
+	histogramBuckets *HistogramBuckets) (*TriggerGatherer, error) {
 
 	newTriggerGatherer := &TriggerGatherer{
-		trigger: trigger,
-		logger:  logger.GetChild("gatherer"),
+		trigger:        trigger,
+		logger:         logger.GetChild("gatherer"),
+		metricRegistry: metricRegistry,
 	}
 
 	// base labels for handle events
@@ -85,18 +93,41 @@ func NewTriggerGatherer(instanceName string,
 		ConstLabels: labels,
 	})
 
+	newTriggerGatherer.eventHandlingDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:        "nuclio_processor_event_handling_duration_seconds",
+		Help:        "Duration of handling an event by a worker, by result",
+		ConstLabels: labels,
+		Buckets:     histogramBuckets.EventHandlingDuration,
+	}, []string{"result"})
+
+	newTriggerGatherer.workerAllocationWaitDurationSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:        "nuclio_processor_worker_allocation_wait_duration_seconds",
+		Help:        "Duration of waiting for a worker to be allocated, by result",
+		ConstLabels: labels,
+		Buckets:     histogramBuckets.WorkerAllocationWaitDuration,
+	}, []string{"result"})
+
 	for _, collector := range []prometheus.Collector{
 		newTriggerGatherer.handledEventsTotal,
+		newTriggerGatherer.eventHandlingDurationSeconds,
+		newTriggerGatherer.workerAllocationWaitDurationSeconds,
 		newTriggerGatherer.workerAllocationTotal,
 		newTriggerGatherer.workerAllocationCount,
 		newTriggerGatherer.workerAllocationWaitDurationMilliSecondsSum,
 		newTriggerGatherer.workerAllocationWorkersAvailablePercentage,
 	} {
 		if err := metricRegistry.Register(collector); err != nil {
+			newTriggerGatherer.Unregister()
 			return nil, errors.Wrap(err, "Failed to register collector")
 		}
+
+		newTriggerGatherer.collectors = append(newTriggerGatherer.collectors, collector)
 	}
 
+	// histograms are observed as events are handled rather than gathered, since they can't be
+	// reconstructed from the trigger's primitive statistics
+	trigger.AddObserver(newTriggerGatherer)
+
 	newTriggerGatherer.logger.DebugWith("Trigger gatherer created",
 		"triggerID", trigger.GetID(),
 		"triggerKind", trigger.GetKind())
@@ -144,3 +175,30 @@ func (tg *TriggerGatherer) Gather() error {
 
 	return nil
 }
+
+// Unregister stops observing the trigger and removes its metrics from the registry
+func (tg *TriggerGatherer) Unregister() {
+	tg.trigger.RemoveObserver(tg)
+
+	for _, collector := range tg.collectors {
+		tg.metricRegistry.Unregister(collector)
+	}
+}
+
+// ObserveEventHandled records the duration of handling an event
+func (tg *TriggerGatherer) ObserveEventHandled(duration time.Duration, success bool) {
+	tg.eventHandlingDurationSeconds.WithLabelValues(resolveResultLabel(success)).Observe(duration.Seconds())
+}
+
+// ObserveWorkerAllocation records the duration of waiting for a worker
+func (tg *TriggerGatherer) ObserveWorkerAllocation(waitDuration time.Duration, success bool) {
+	tg.workerAllocationWaitDurationSeconds.WithLabelValues(resolveResultLabel(success)).Observe(waitDuration.Seconds())
+}
+
+func resolveResultLabel(success bool) string {
+	if success {
+		return "success"
+	}
+
+	return "failure"
+}
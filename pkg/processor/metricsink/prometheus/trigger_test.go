@@ -0,0 +1,203 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/logger"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/suite"
+)
+
+type testTrigger struct {
+	trigger.AbstractTrigger
+}
+
+func (t *testTrigger) Start(checkpoint functionconfig.Checkpoint) error {
+	return nil
+}
+
+func (t *testTrigger) Stop(force bool) (functionconfig.Checkpoint, error) {
+	return nil, nil
+}
+
+func (t *testTrigger) GetConfig() map[string]interface{} {
+	return nil
+}
+
+func (t *testTrigger) GetWorkers() []*worker.Worker {
+	return nil
+}
+
+type TriggerGathererTestSuite struct {
+	suite.Suite
+	logger   logger.Logger
+	trigger  *testTrigger
+	registry *prometheus.Registry
+}
+
+func (suite *TriggerGathererTestSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.trigger = &testTrigger{
+		AbstractTrigger: trigger.AbstractTrigger{
+			ID:           "my-trigger",
+			Logger:       suite.logger,
+			Kind:         "http",
+			Name:         "my-trigger",
+			Namespace:    "default",
+			FunctionName: "my-function",
+			ProjectName:  "my-project",
+		},
+	}
+	suite.registry = prometheus.NewRegistry()
+}
+
+func (suite *TriggerGathererTestSuite) TestObserveLatencies() {
+	histogramBuckets, err := NewHistogramBuckets([]float64{0.01, 0.1, 1}, nil)
+	suite.Require().NoError(err)
+
+	_, err = NewTriggerGatherer("my-instance",
+		suite.trigger,
+		suite.logger,
+		suite.registry,
+		histogramBuckets)
+	suite.Require().NoError(err)
+
+	// observations are made by the trigger as it allocates workers and handles events
+	suite.trigger.ObserveEventHandled(5*time.Millisecond, true)
+	suite.trigger.ObserveEventHandled(50*time.Millisecond, true)
+	suite.trigger.ObserveEventHandled(2*time.Second, false)
+	suite.trigger.ObserveWorkerAllocation(time.Millisecond, true)
+
+	metricFamilies, err := suite.registry.Gather()
+	suite.Require().NoError(err)
+
+	eventHandlingDuration := suite.getHistograms(metricFamilies,
+		"nuclio_processor_event_handling_duration_seconds")
+	suite.Require().Len(eventHandlingDuration, 2)
+
+	successHistogram := eventHandlingDuration["success"]
+	suite.Require().NotNil(successHistogram)
+	suite.Require().Equal(uint64(2), successHistogram.GetSampleCount())
+	suite.Require().Len(successHistogram.GetBucket(), 3)
+	suite.Require().Equal(uint64(1), successHistogram.GetBucket()[0].GetCumulativeCount())
+	suite.Require().Equal(uint64(2), successHistogram.GetBucket()[1].GetCumulativeCount())
+
+	failureHistogram := eventHandlingDuration["failure"]
+	suite.Require().NotNil(failureHistogram)
+	suite.Require().Equal(uint64(1), failureHistogram.GetSampleCount())
+	suite.Require().Equal(uint64(0), failureHistogram.GetBucket()[2].GetCumulativeCount())
+
+	// worker allocation wait uses the default buckets
+	workerAllocationWaitDuration := suite.getHistograms(metricFamilies,
+		"nuclio_processor_worker_allocation_wait_duration_seconds")
+	suite.Require().Len(workerAllocationWaitDuration, 1)
+	suite.Require().Equal(uint64(1), workerAllocationWaitDuration["success"].GetSampleCount())
+	suite.Require().Len(workerAllocationWaitDuration["success"].GetBucket(), len(prometheus.DefBuckets))
+}
+
+func (suite *TriggerGathererTestSuite) TestAddRemoveTrigger() {
+	histogramBuckets, err := NewHistogramBuckets(nil, nil)
+	suite.Require().NoError(err)
+
+	triggerGatherers := NewTriggerGatherers("my-instance", suite.logger, suite.registry, histogramBuckets)
+
+	err = triggerGatherers.AddTrigger(suite.trigger)
+	suite.Require().NoError(err)
+	suite.Require().Equal(1, triggerGatherers.GetNumGatherers())
+
+	suite.trigger.ObserveEventHandled(time.Millisecond, true)
+
+	metricFamilies, err := suite.registry.Gather()
+	suite.Require().NoError(err)
+	suite.Require().Len(suite.getHistograms(metricFamilies,
+		"nuclio_processor_event_handling_duration_seconds"), 1)
+
+	// removing the trigger unregisters its metrics and stops observing it
+	triggerGatherers.RemoveTrigger(suite.trigger)
+	suite.Require().Equal(0, triggerGatherers.GetNumGatherers())
+
+	suite.trigger.ObserveEventHandled(time.Millisecond, true)
+
+	metricFamilies, err = suite.registry.Gather()
+	suite.Require().NoError(err)
+	suite.Require().Empty(metricFamilies)
+
+	// a trigger replacing the removed one may reuse its ID
+	replacementTrigger := &testTrigger{
+		AbstractTrigger: trigger.AbstractTrigger{
+			ID:           suite.trigger.ID,
+			Logger:       suite.logger,
+			Kind:         "http",
+			Name:         "my-trigger",
+			Namespace:    "default",
+			FunctionName: "my-function",
+			ProjectName:  "my-project",
+		},
+	}
+
+	err = triggerGatherers.AddTrigger(replacementTrigger)
+	suite.Require().NoError(err)
+	suite.Require().Equal(1, triggerGatherers.GetNumGatherers())
+}
+
+func (suite *TriggerGathererTestSuite) TestNewHistogramBucketsUnsorted() {
+	_, err := NewHistogramBuckets([]float64{1, 0.1}, nil)
+	suite.Require().Error(err)
+
+	_, err = NewHistogramBuckets(nil, []float64{0.1, 0.1})
+	suite.Require().Error(err)
+}
+
+func (suite *TriggerGathererTestSuite) getHistograms(metricFamilies []*dto.MetricFamily,
+	name string) map[string]*dto.Histogram {
+	histograms := map[string]*dto.Histogram{}
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != name {
+			continue
+		}
+
+		for _, metric := range metricFamily.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "result" {
+					histograms[label.GetValue()] = metric.GetHistogram()
+				}
+			}
+		}
+	}
+
+	return histograms
+}
+
+func TestTriggerGathererTestSuite(t *testing.T) {
+	suite.Run(t, new(TriggerGathererTestSuite))
+}
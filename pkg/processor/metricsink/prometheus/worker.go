@@ -35,6 +35,7 @@ type WorkerGatherer struct {
 	handledEventsDurationMillisecondsSum   prometheus.Counter
 	handledEventsDurationMillisecondsCount prometheus.Counter
 	logger                                 logger.Logger
+	metricRegistry                         *prometheus.Registry
 }
 
 func NewWorkerGatherer(instanceName string,
@@ -44,8 +45,9 @@ func NewWorkerGatherer(instanceName string,
 	metricRegistry *prometheus.Registry) (*WorkerGatherer, error) {
 
 	newWorkerGatherer := &WorkerGatherer{
-		worker: worker,
-		logger: logger.GetChild("gatherer"),
+		worker:         worker,
+		logger:         logger.GetChild("gatherer"),
+		metricRegistry: metricRegistry,
 	}
 
 	// base labels for handle events
@@ -76,6 +78,7 @@ func NewWorkerGatherer(instanceName string,
 	})
 
 	if err := metricRegistry.Register(newWorkerGatherer.handledEventsDurationMillisecondsCount); err != nil {
+		metricRegistry.Unregister(newWorkerGatherer.handledEventsDurationMillisecondsSum)
 		return nil, errors.Wrap(err, "Failed to register handledEventsDurationCount")
 	}
 
@@ -107,3 +110,9 @@ func (wg *WorkerGatherer) Gather() error {
 
 	return nil
 }
+
+// Unregister removes the worker's metrics from the registry
+func (wg *WorkerGatherer) Unregister() {
+	wg.metricRegistry.Unregister(wg.handledEventsDurationMillisecondsSum)
+	wg.metricRegistry.Unregister(wg.handledEventsDurationMillisecondsCount)
+}
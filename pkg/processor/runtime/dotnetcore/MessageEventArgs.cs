@@ -18,6 +18,7 @@ namespace processor
 {
     internal class MessageEventArgs : EventArgs
     {
-        public string Message { get; set; }
+        // the message, encoded as msgpack
+        public byte[] Message { get; set; }
     }
 }
\ No newline at end of file
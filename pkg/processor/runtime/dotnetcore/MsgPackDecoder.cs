@@ -0,0 +1,154 @@
+//  Copyright 2023 The Nuclio Authors.
+// 
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+// 
+//      http://www.apache.org/licenses/LICENSE-2.0
+// 
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+
+using System;
+using System.Buffers.Binary;
+using System.Collections.Generic;
+using System.Text;
+
+namespace processor
+{
+    // Decodes the subset of msgpack the processor sends events with
+    public class MsgPackDecoder
+    {
+        private readonly byte[] data;
+        private int offset;
+
+        private MsgPackDecoder(byte[] data)
+        {
+            this.data = data;
+        }
+
+        public static object Decode(byte[] data)
+        {
+            return new MsgPackDecoder(data).ReadValue();
+        }
+
+        private object ReadValue()
+        {
+            var type = data[offset++];
+
+            // fixed size types hold their value or length in the type byte
+            if (type <= 0x7f)
+                return (long)type;
+            if (type <= 0x8f)
+                return ReadMap(type - 0x80);
+            if (type <= 0x9f)
+                return ReadArray(type - 0x90);
+            if (type <= 0xbf)
+                return ReadString(type - 0xa0);
+            if (type >= 0xe0)
+                return (long)(sbyte)type;
+
+            switch (type)
+            {
+                case 0xc0:
+                    return null;
+                case 0xc2:
+                    return false;
+                case 0xc3:
+                    return true;
+                case 0xc4:
+                    return ReadBytes((int)ReadUInt(1));
+                case 0xc5:
+                    return ReadBytes((int)ReadUInt(2));
+                case 0xc6:
+                    return ReadBytes((int)ReadUInt(4));
+                case 0xca:
+                    return (double)BinaryPrimitives.ReadSingleBigEndian(ReadBytes(4));
+                case 0xcb:
+                    return BinaryPrimitives.ReadDoubleBigEndian(ReadBytes(8));
+                case 0xcc:
+                    return (long)ReadUInt(1);
+                case 0xcd:
+                    return (long)ReadUInt(2);
+                case 0xce:
+                    return (long)ReadUInt(4);
+                case 0xcf:
+                    return (long)ReadUInt(8);
+                case 0xd0:
+                    return (long)(sbyte)ReadUInt(1);
+                case 0xd1:
+                    return (long)(short)ReadUInt(2);
+                case 0xd2:
+                    return (long)(int)ReadUInt(4);
+                case 0xd3:
+                    return (long)ReadUInt(8);
+                case 0xd9:
+                    return ReadString((int)ReadUInt(1));
+                case 0xda:
+                    return ReadString((int)ReadUInt(2));
+                case 0xdb:
+                    return ReadString((int)ReadUInt(4));
+                case 0xdc:
+                    return ReadArray((int)ReadUInt(2));
+                case 0xdd:
+                    return ReadArray((int)ReadUInt(4));
+                case 0xde:
+                    return ReadMap((int)ReadUInt(2));
+                case 0xdf:
+                    return ReadMap((int)ReadUInt(4));
+                default:
+                    throw new Exception($"Unsupported msgpack type: 0x{type:x2}");
+            }
+        }
+
+        private ulong ReadUInt(int length)
+        {
+            ulong value = 0;
+            for (var i = 0; i < length; i++)
+            {
+                value = (value << 8) | data[offset++];
+            }
+            return value;
+        }
+
+        private byte[] ReadBytes(int length)
+        {
+            var bytes = new byte[length];
+            Array.Copy(data, offset, bytes, 0, length);
+            offset += length;
+            return bytes;
+        }
+
+        private string ReadString(int length)
+        {
+            var value = Encoding.UTF8.GetString(data, offset, length);
+            offset += length;
+            return value;
+        }
+
+        private List<object> ReadArray(int length)
+        {
+            var array = new List<object>(length);
+            for (var i = 0; i < length; i++)
+            {
+                array.Add(ReadValue());
+            }
+            return array;
+        }
+
+        private Dictionary<string, object> ReadMap(int length)
+        {
+            var map = new Dictionary<string, object>(length);
+            for (var i = 0; i < length; i++)
+            {
+                var key = Convert.ToString(ReadValue());
+                map[key] = ReadValue();
+            }
+            return map;
+        }
+    }
+}
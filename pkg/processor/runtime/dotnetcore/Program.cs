@@ -24,12 +24,13 @@ namespace processor
         {
 
             var socketPath = args[0];
-            var dllPath = @"/opt/nuclio/handler/handler.dll";
+            var controlSocketPath = args.Length > 1 ? args[1] : null;
+            var dllPath = Environment.GetEnvironmentVariable("NUCLIO_DOTNETCORE_HANDLER_PATH") ?? @"/opt/nuclio/handler/handler.dll";
             var handler = Environment.GetEnvironmentVariable("NUCLIO_FUNCTION_HANDLER");
             var splittedHandler = handler.Split(':');
             var typeName = splittedHandler[0];
             var methodName = splittedHandler[1];
-            var wrapper = new Wrapper(dllPath, typeName, methodName, socketPath);
+            var wrapper = new Wrapper(dllPath, typeName, methodName, socketPath, controlSocketPath);
             await Task.Delay(Timeout.Infinite);
             Console.WriteLine("Exiting...");
         }
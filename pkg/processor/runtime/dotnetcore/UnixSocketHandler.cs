@@ -13,6 +13,7 @@
 //  limitations under the License.
 
 using System;
+using System.Buffers.Binary;
 using System.Net.Sockets;
 using System.Text;
 using System.Threading.Tasks;
@@ -25,6 +26,8 @@ namespace processor
         public event EventHandler MessageReceived;
 
         private Socket _socket;
+        private readonly object _sendLock = new object();
+
         protected virtual void OnMessageReceived(EventArgs e)
         {
             var handler = MessageReceived;
@@ -36,44 +39,36 @@ namespace processor
 
         public UnixSocketHandler(string socketPath)
         {
-            ConnectAndListen(socketPath);
+            // connect before returning, so messages can be sent right away
+            _socket = new Socket(AddressFamily.Unix, SocketType.Stream, ProtocolType.Unspecified);
+            _socket.Connect(new UnixDomainSocketEndPoint(socketPath));
+
+            Task.Run(Listen);
         }
 
-        public async void SendMessage(string message)
+        public void SendMessage(string message)
         {
             var data = System.Text.Encoding.UTF8.GetBytes(message);
-            if (_socket != null)
-                await _socket.SendAsync(data, SocketFlags.None);
+
+            // messages are sent whole, and in order
+            lock (_sendLock)
+            {
+                _socket.Send(data, SocketFlags.None);
+            }
         }
 
-        private async void ConnectAndListen(string socketPath)
+        private async Task Listen()
         {
             try
             {
-                using (_socket = new Socket(AddressFamily.Unix, SocketType.Stream, ProtocolType.Unspecified))
+                using (_socket)
                 {
-                    var ep = new UnixDomainSocketEndPoint(socketPath);
-                    await _socket.ConnectAsync(ep);
-                    var clientReceives = Task.Run(async () =>
+                    // messages are prefixed by their size (4 bytes, big endian)
+                    while (true)
                     {
-                        var message = "";
-                        while (true)
-                        {
-                            var buffer = new byte[1024];
-                            int bytesReceived = await _socket.ReceiveAsync(new ArraySegment<byte>(buffer), SocketFlags.None);
-                            var messagePart = Encoding.UTF8.GetString(buffer, 0, bytesReceived);
-                            var linebreakIndex = messagePart.IndexOf('\n');
-                            if (linebreakIndex == -1) {
-                                message += messagePart;
-                            } else {
-                                message += messagePart.Substring(0, linebreakIndex);
-                                OnMessageReceived(new MessageEventArgs() { Message = message });
-                                message = "";
-                            }
-                        }
-                    });
-
-                    await clientReceives;
+                        var messageSize = BinaryPrimitives.ReadInt32BigEndian(await Receive(4));
+                        OnMessageReceived(new MessageEventArgs() { Message = await Receive(messageSize) });
+                    }
                 }
             }
             catch (Exception ex)
@@ -81,5 +76,21 @@ namespace processor
                 Console.WriteLine("Socket Error: " + ex.Message);
             }
         }
+
+        private async Task<byte[]> Receive(int length)
+        {
+            var buffer = new byte[length];
+            var received = 0;
+            while (received < length)
+            {
+                var bytesReceived = await _socket.ReceiveAsync(new ArraySegment<byte>(buffer, received, length - received), SocketFlags.None);
+                if (bytesReceived == 0)
+                {
+                    throw new Exception("Connection closed");
+                }
+                received += bytesReceived;
+            }
+            return buffer;
+        }
     }
 }
@@ -15,6 +15,7 @@
 using System;
 using System.Collections.Generic;
 using System.Text;
+using System.Text.Json;
 using Nuclio.Sdk;
 
 namespace processor
@@ -29,14 +30,15 @@ namespace processor
         private Type methodType;
 
         private ISocketHandler socketHandler;
+        private ISocketHandler controlSocketHandler;
         private Context context;
 
-        public Wrapper(string dllPath, string typeName, string methodName, string socketPath)
+        public Wrapper(string dllPath, string typeName, string methodName, string socketPath, string controlSocketPath)
         {
 
             CreateTypeAndFunction(dllPath, typeName, methodName);
 
-            InitUnixSocketHandler(socketPath);
+            InitUnixSocketHandler(socketPath, controlSocketPath);
 
             context = new Context();
             context.Logger.LogEvent += LogEvent;
@@ -52,7 +54,6 @@ namespace processor
             try
             {
                 ExecuteInitContext();
-                socketHandler.SendMessage("{ 'kind': 'wrapperInitialized', 'attributes': {'ready': 'true'}");
             }
             catch (Exception e)
             {
@@ -62,6 +63,19 @@ namespace processor
             }
 
             StartUnixSocketHandler();
+
+            // indicate that we're ready, announcing the event encoding and control communication we support
+            var capabilities = new Dictionary<string, object>
+            {
+                { "encoding", "msgpack" },
+                { "control_communication", controlSocketHandler != null },
+            };
+            socketHandler.SendMessage(string.Join(String.Empty, "s", JsonSerializer.Serialize(capabilities), Environment.NewLine));
+
+            if (controlSocketHandler != null)
+            {
+                SendControlMessage("wrapperInitialized", new Dictionary<string, object> { { "ready", "true" } });
+            }
         }
 
         private void ExecuteInitContext()
@@ -85,14 +99,52 @@ namespace processor
             }
         }
 
-        private void InitUnixSocketHandler(string socketPath)
+        private void InitUnixSocketHandler(string socketPath, string controlSocketPath)
         {
             socketHandler = new UnixSocketHandler(socketPath);
+            if (!string.IsNullOrEmpty(controlSocketPath))
+            {
+                controlSocketHandler = new UnixSocketHandler(controlSocketPath);
+            }
         }
 
         private void StartUnixSocketHandler()
         {
             socketHandler.MessageReceived += MessageReceived;
+            if (controlSocketHandler != null)
+            {
+                controlSocketHandler.MessageReceived += ControlMessageReceived;
+            }
+        }
+
+        private void SendControlMessage(string kind, Dictionary<string, object> attributes)
+        {
+            // control messages are sent as JSON lines
+            var controlMessage = new Dictionary<string, object> { { "kind", kind }, { "attributes", attributes } };
+            controlSocketHandler.SendMessage(string.Join(String.Empty, JsonSerializer.Serialize(controlMessage), Environment.NewLine));
+        }
+
+        private void ControlMessageReceived(object sender, EventArgs e)
+        {
+            var msgArgs = e as MessageEventArgs;
+            if (msgArgs != null)
+            {
+                try
+                {
+                    // the body holds the control message, encoded as JSON
+                    var controlMessageEvent = (Dictionary<string, object>)MsgPackDecoder.Decode(msgArgs.Message);
+                    using (var controlMessage = JsonDocument.Parse((byte[])controlMessageEvent["body"]))
+                    {
+                        context.Logger.DebugWith("Received control message",
+                            "kind", controlMessage.RootElement.GetProperty("Kind").GetString(),
+                            "attributes", controlMessage.RootElement.GetProperty("Attributes").GetRawText());
+                    }
+                }
+                catch (Exception ex)
+                {
+                    context.Logger.Error("Failed to handle control message: " + ex.Message);
+                }
+            }
         }
 
         private void CreateTypeAndFunction(string dllPath, string typeName, string methodName)
@@ -140,7 +192,7 @@ namespace processor
                 try
                 {
                     st.Start();
-                    var eve = NuclioSerializationHelpers<Event>.Deserialize(msgArgs.Message);
+                    var eve = DeserializeEvent(msgArgs.Message);
                     var result = InvokeFunction(context, eve);
                     response = CreateResponse(result);
                 }
@@ -158,6 +210,14 @@ namespace processor
             }
         }
 
+        private Event DeserializeEvent(byte[] message)
+        {
+            // events are sent as msgpack, re-encode them as the JSON the sdk deserializes events from (the
+            // body is encoded as base64)
+            var eventAsJson = JsonSerializer.Serialize(MsgPackDecoder.Decode(message));
+            return NuclioSerializationHelpers<Event>.Deserialize(eventAsJson);
+        }
+
         private Response CreateResponse(object value)
         {
             // Create use case for every response type. Currently supported is Response, Exception and primitive types.
@@ -74,6 +74,10 @@ func (d *dotnetcore) RunWrapper(socketPath, controlSocketPath string) (*os.Proce
 		"dotnet", wrapperDLLPath, socketPath,
 	}
 
+	if controlSocketPath != "" {
+		args = append(args, controlSocketPath)
+	}
+
 	d.Logger.DebugWith("Running wrapper", "command", strings.Join(args, " "))
 
 	cmd := exec.Command(args[0], args[1:]...)
@@ -101,3 +105,8 @@ func (d *dotnetcore) getWrapperDLLPath() string {
 func (d *dotnetcore) GetEventEncoder(writer io.Writer) rpc.EventEncoder {
 	return rpc.NewEventJSONEncoder(d.Logger, writer)
 }
+
+// WaitForStart returns true since the wrapper sends a start message once the function is initialized
+func (d *dotnetcore) WaitForStart() bool {
+	return true
+}
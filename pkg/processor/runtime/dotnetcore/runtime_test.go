@@ -0,0 +1,53 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dotnetcore
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/test/suite"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RuntimeTestSuite struct {
+	rpcsuite.TestSuite
+}
+
+func (suite *RuntimeTestSuite) SetupSuite() {
+	if _, err := exec.LookPath("dotnet"); err != nil {
+		suite.T().Skip("dotnet isn't installed")
+	}
+
+	// the wrapper and the handler in test/wrapper must be built beforehand
+	if !common.IsFile(os.Getenv("NUCLIO_DOTNETCORE_WRAPPER_PATH")) ||
+		!common.IsFile(os.Getenv("NUCLIO_DOTNETCORE_HANDLER_PATH")) {
+		suite.T().Skip("NUCLIO_DOTNETCORE_WRAPPER_PATH and NUCLIO_DOTNETCORE_HANDLER_PATH must point to built dlls")
+	}
+
+	suite.CreateRuntime = NewRuntime
+	suite.Handler = "nuclio:handler"
+}
+
+func TestRuntime(t *testing.T) {
+	suite.Run(t, new(RuntimeTestSuite))
+}
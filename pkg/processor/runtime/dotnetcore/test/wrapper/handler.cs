@@ -0,0 +1,39 @@
+//  Copyright 2023 The Nuclio Authors.
+// 
+//  Licensed under the Apache License, Version 2.0 (the "License");
+//  you may not use this file except in compliance with the License.
+//  You may obtain a copy of the License at
+// 
+//      http://www.apache.org/licenses/LICENSE-2.0
+// 
+//  Unless required by applicable law or agreed to in writing, software
+//  distributed under the License is distributed on an "AS IS" BASIS,
+//  WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+//  See the License for the specific language governing permissions and
+//  limitations under the License.
+
+
+using System;
+using Nuclio.Sdk;
+
+// used by the rpc wrapper tests
+public class nuclio
+{
+    public object handler(Context context, Event eventBase)
+    {
+        switch (eventBase.GetBody())
+        {
+            case "path":
+                return eventBase.Path;
+            default:
+                // echo the body as is, it may not be valid UTF-8
+                return new Response()
+                {
+                    StatusCode = 200,
+                    ContentType = "application/octet-stream",
+                    Body = eventBase.Body,
+                    BodyEncoding = "base64"
+                };
+        }
+    }
+}
@@ -16,28 +16,46 @@ limitations under the License.
 
 package io.nuclio.processor;
 
-import java.io.BufferedReader;
+import java.io.BufferedInputStream;
+import java.io.DataInputStream;
+import java.io.EOFException;
 import java.io.InputStream;
-import java.io.InputStreamReader;
-
-import com.google.gson.Gson;
+import java.util.Map;
 
 public class EventReader {
-    private Gson gson;
-    BufferedReader reader;
+    private DataInputStream in;
 
     public EventReader(InputStream in) {
-        this.reader = new BufferedReader(new InputStreamReader(in));
-        this.gson = GSON.createGson();
+        this.in = new DataInputStream(new BufferedInputStream(in));
+    }
+
+    /**
+     * Read the next message, encoded as msgpack and prefixed by its size (4 bytes, big endian)
+     *
+     * @return Decoded message, null if the connection was closed
+     * @throws Throwable
+     */
+    @SuppressWarnings("unchecked")
+    public Map<String, Object> nextMessage() throws Throwable {
+        int size;
+        try {
+            size = this.in.readInt();
+        } catch (EOFException e) {
+            return null;
+        }
+
+        byte[] message = new byte[size];
+        this.in.readFully(message);
 
+        return (Map<String, Object>) MsgPackDecoder.decode(message);
     }
 
     public JsonEvent next() throws Throwable {
-        String line = reader.readLine();
-        if (line == null) {
+        Map<String, Object> message = this.nextMessage();
+        if (message == null) {
             return null;
         }
 
-        return gson.fromJson(line, JsonEvent.class);
+        return new JsonEvent(message);
     }
 }
@@ -20,13 +20,11 @@ import java.nio.charset.StandardCharsets;
 import java.util.Date;
 import java.util.Map;
 
-import com.google.gson.annotations.SerializedName;
 import io.nuclio.TriggerInfo;
 
 
 public class JsonEvent implements io.nuclio.Event {
     private byte[] body;
-    @SerializedName("content-type")
     private String contentType;
     private Map<String, Object> headers;
     private Map<String, Object> fields;
@@ -42,6 +40,46 @@ public class JsonEvent implements io.nuclio.Event {
     private String type_version;
     private String version;
 
+    /**
+     * Create an event from a decoded event message
+     *
+     * @param message Event message, as sent by the processor
+     */
+    @SuppressWarnings("unchecked")
+    public JsonEvent(Map<String, Object> message) {
+        Object body = message.get("body");
+        if (body instanceof Map) {
+
+            // a structured cloud event holds an object rather than raw bytes
+            this.body = GSON.createGson().toJson(body).getBytes(StandardCharsets.UTF_8);
+        } else {
+            this.body = (byte[]) body;
+        }
+
+        this.contentType = (String) message.get("content-type");
+        this.headers = (Map<String, Object>) message.get("headers");
+        this.fields = (Map<String, Object>) message.get("fields");
+        this.id = (String) message.get("id");
+        this.method = (String) message.get("method");
+        this.path = (String) message.get("path");
+        this.url = (String) message.get("url");
+        this.timestamp = new Date(getLong(message, "timestamp") * 1000);
+        this.trigger = new Trigger((Map<String, Object>) message.get("trigger"));
+        this.shard_id = getLong(message, "shard_id");
+        this.num_shards = getLong(message, "num_shards");
+        this.type = (String) message.get("type");
+        this.type_version = (String) message.get("type_version");
+        this.version = (String) message.get("version");
+    }
+
+    private static long getLong(Map<String, Object> message, String key) {
+        Object value = message.get(key);
+        if (value instanceof Number) {
+            return ((Number) value).longValue();
+        }
+        return 0;
+    }
+
     @Override
     public byte[] getBody() {
         return this.body;
@@ -187,11 +225,16 @@ public class JsonEvent implements io.nuclio.Event {
 }
 
 class Trigger implements TriggerInfo {
-    @SerializedName("class")
     String className;
-    @SerializedName("kind")
     String kindName;
 
+    Trigger(Map<String, Object> trigger) {
+        if (trigger != null) {
+            this.className = (String) trigger.get("class");
+            this.kindName = (String) trigger.get("kind");
+        }
+    }
+
     public String getClassName() {
         return this.className;
     }
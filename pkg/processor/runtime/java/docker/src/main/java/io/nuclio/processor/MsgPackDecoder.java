@@ -0,0 +1,140 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package io.nuclio.processor;
+
+import java.nio.ByteBuffer;
+import java.nio.charset.StandardCharsets;
+import java.util.ArrayList;
+import java.util.HashMap;
+import java.util.List;
+import java.util.Map;
+
+/**
+ * Decodes the subset of msgpack the processor sends events with
+ */
+public class MsgPackDecoder {
+    private ByteBuffer buffer;
+
+    private MsgPackDecoder(byte[] data) {
+        this.buffer = ByteBuffer.wrap(data);
+    }
+
+    /**
+     * Decode a msgpack value
+     *
+     * @param data Encoded value
+     * @return Map, List, byte[], String, Long, Double, Boolean or null
+     */
+    public static Object decode(byte[] data) {
+        return new MsgPackDecoder(data).readValue();
+    }
+
+    private Object readValue() {
+        int type = this.buffer.get() & 0xff;
+
+        // fixed size types hold their value or length in the type byte
+        if (type <= 0x7f) {
+            return (long) type;
+        } else if (type <= 0x8f) {
+            return readMap(type - 0x80);
+        } else if (type <= 0x9f) {
+            return readArray(type - 0x90);
+        } else if (type <= 0xbf) {
+            return readString(type - 0xa0);
+        } else if (type >= 0xe0) {
+            return (long) (type - 0x100);
+        }
+
+        switch (type) {
+            case 0xc0:
+                return null;
+            case 0xc2:
+                return false;
+            case 0xc3:
+                return true;
+            case 0xc4:
+                return readBytes(this.buffer.get() & 0xff);
+            case 0xc5:
+                return readBytes(this.buffer.getShort() & 0xffff);
+            case 0xc6:
+                return readBytes(this.buffer.getInt());
+            case 0xca:
+                return (double) this.buffer.getFloat();
+            case 0xcb:
+                return this.buffer.getDouble();
+            case 0xcc:
+                return (long) (this.buffer.get() & 0xff);
+            case 0xcd:
+                return (long) (this.buffer.getShort() & 0xffff);
+            case 0xce:
+                return this.buffer.getInt() & 0xffffffffL;
+            case 0xcf:
+                return this.buffer.getLong();
+            case 0xd0:
+                return (long) this.buffer.get();
+            case 0xd1:
+                return (long) this.buffer.getShort();
+            case 0xd2:
+                return (long) this.buffer.getInt();
+            case 0xd3:
+                return this.buffer.getLong();
+            case 0xd9:
+                return readString(this.buffer.get() & 0xff);
+            case 0xda:
+                return readString(this.buffer.getShort() & 0xffff);
+            case 0xdb:
+                return readString(this.buffer.getInt());
+            case 0xdc:
+                return readArray(this.buffer.getShort() & 0xffff);
+            case 0xdd:
+                return readArray(this.buffer.getInt());
+            case 0xde:
+                return readMap(this.buffer.getShort() & 0xffff);
+            case 0xdf:
+                return readMap(this.buffer.getInt());
+            default:
+                throw new IllegalArgumentException(String.format("Unsupported msgpack type: 0x%x", type));
+        }
+    }
+
+    private byte[] readBytes(int length) {
+        byte[] bytes = new byte[length];
+        this.buffer.get(bytes);
+        return bytes;
+    }
+
+    private String readString(int length) {
+        return new String(readBytes(length), StandardCharsets.UTF_8);
+    }
+
+    private List<Object> readArray(int length) {
+        List<Object> array = new ArrayList<Object>(length);
+        for (int i = 0; i < length; i++) {
+            array.add(readValue());
+        }
+        return array;
+    }
+
+    private Map<String, Object> readMap(int length) {
+        Map<String, Object> map = new HashMap<String, Object>(length);
+        for (int i = 0; i < length; i++) {
+            String key = String.valueOf(readValue());
+            map.put(key, readValue());
+        }
+        return map;
+    }
+}
@@ -56,6 +56,24 @@ public class ResponseEncoder {
         this.out.flush();
     }
 
+    /**
+     * Encode the start message, announcing the event encoding and control communication the wrapper supports
+     *
+     * @param controlCommunication Whether the wrapper connected to the control socket
+     * @throws Throwable
+     */
+    public void encodeStart(boolean controlCommunication) throws Throwable {
+        this.out.write('s');
+
+        Map<String, Object> capabilities = new HashMap<String, Object>();
+        capabilities.put("encoding", "msgpack");
+        capabilities.put("control_communication", controlCommunication);
+
+        this.out.write(gson.toJson(capabilities).getBytes(StandardCharsets.UTF_8));
+        this.out.write('\n');
+        this.out.flush();
+    }
+
     public void encodeMetrics(long duration) throws Throwable {
         this.out.write('m');
 
@@ -26,15 +26,20 @@ import io.nuclio.Response;
 import java.io.*;
 import java.lang.reflect.Constructor;
 import java.net.Socket;
+import java.nio.charset.StandardCharsets;
 import java.text.SimpleDateFormat;
 import java.util.Date;
+import java.util.HashMap;
+import java.util.Map;
+
+import com.google.gson.Gson;
 
 import org.apache.commons.cli.*;
 
 public class Wrapper {
     private static boolean verbose = false;
     private static SimpleDateFormat dateFormat;
-    private static String usage = "wrapper -handler HANDLER -port PORT -workerid WORKER_ID";
+    private static String usage = "wrapper -handler HANDLER -port PORT -workerid WORKER_ID [-controlport CONTROL_PORT]";
 
     static {
         dateFormat = new SimpleDateFormat("yyyy-MM-dd HH:mm:ss");
@@ -92,6 +97,8 @@ public class Wrapper {
             options.addOption(
                     Option.builder(opt[0]).required().hasArg().desc(opt[1]).build());
         }
+        options.addOption(
+                Option.builder("controlport").hasArg().desc("control communication port").build());
         options.addOption(
                 Option.builder("verbose").desc("emit debug information").build());
 
@@ -113,6 +120,48 @@ public class Wrapper {
         }
     }
 
+    /**
+     * Send a control message to the processor, as a JSON line
+     *
+     * @param out        Control socket output stream
+     * @param kind       Control message kind
+     * @param attributes Control message attributes
+     * @throws IOException
+     */
+    private static void sendControlMessage(OutputStream out, String kind, Map<String, Object> attributes)
+            throws IOException {
+        Map<String, Object> controlMessage = new HashMap<String, Object>();
+        controlMessage.put("kind", kind);
+        controlMessage.put("attributes", attributes);
+
+        out.write((new Gson().toJson(controlMessage) + "\n").getBytes(StandardCharsets.UTF_8));
+        out.flush();
+    }
+
+    /**
+     * Log the control messages sent by the processor, until the control connection is closed
+     *
+     * @param controlMessageReader Control socket reader
+     * @param logger               Logger
+     */
+    private static void receiveControlMessages(EventReader controlMessageReader, Logger logger) {
+        Gson gson = new Gson();
+        try {
+            Map<String, Object> controlMessageEvent;
+            while ((controlMessageEvent = controlMessageReader.nextMessage()) != null) {
+
+                // the body holds the control message, encoded as JSON
+                String body = new String((byte[]) controlMessageEvent.get("body"), StandardCharsets.UTF_8);
+                Map<?, ?> controlMessage = gson.fromJson(body, Map.class);
+                logger.debugWith("Received control message",
+                        "kind", controlMessage.get("Kind"),
+                        "attributes", controlMessage.get("Attributes"));
+            }
+        } catch (Throwable e) {
+            logger.errorWith("Failed to receive control message", "error", e.toString());
+        }
+    }
+
     public static void main(String[] args) throws Throwable {
         Options options = buildOptions();
 
@@ -143,6 +192,20 @@ public class Wrapper {
 
         debugLog("port: %d", port);
 
+        // the processor accepts the control connection only after receiving the start message
+        Socket controlSock = null;
+        String controlPortValue = cmd.getOptionValue("controlport");
+        if (controlPortValue != null) {
+            int controlPort = parsePort(controlPortValue);
+            if (controlPort <= 0) {
+                System.err.format("error: bad control port - %s", controlPortValue);
+                System.exit(1);
+            }
+
+            debugLog("control port: %d", controlPort);
+            controlSock = new Socket("localhost", controlPort);
+        }
+
         Socket sock = new Socket("localhost", port);
         String workerID = cmd.getOptionValue("workerid");
         Context context = new WrapperContext(sock.getOutputStream(), workerID);
@@ -160,6 +223,20 @@ public class Wrapper {
         ResponseEncoder responseEncoder = new ResponseEncoder(sock.getOutputStream());
         EventReader eventReader = new EventReader(sock.getInputStream());
 
+        // indicate that we're ready, announcing the event encoding and control communication we support
+        responseEncoder.encodeStart(controlSock != null);
+
+        if (controlSock != null) {
+            Map<String, Object> attributes = new HashMap<String, Object>();
+            attributes.put("ready", "true");
+            sendControlMessage(controlSock.getOutputStream(), "wrapperInitialized", attributes);
+
+            EventReader controlMessageReader = new EventReader(controlSock.getInputStream());
+            Thread controlThread = new Thread(() -> receiveControlMessages(controlMessageReader, logger));
+            controlThread.setDaemon(true);
+            controlThread.start();
+        }
+
         Response response;
         Long start = 0L, end = 0L;
 
@@ -74,7 +74,7 @@ public class WrapperLogger implements Logger {
      * @param message Log message
      * @param with    With parameters
      */
-    private void log(LogLevel level, String message, Object... with) {
+    private synchronized void log(LogLevel level, String message, Object... with) {
         Map<String, Object> encodedWith = encodeWith(with);
         encodedWith.put("worker_id", this.workerID);
         Log log = new Log(level, message, encodedWith);
@@ -70,6 +70,10 @@ func (j *java) RunWrapper(port, controlPort string) (*os.Process, error) {
 		"-workerid", strconv.Itoa(j.configuration.WorkerID),
 	}...)
 
+	if controlPort != "" {
+		args = append(args, "-controlport", controlPort)
+	}
+
 	env := os.Environ()
 	env = append(env, j.GetEnvFromConfiguration()...)
 
@@ -82,6 +86,11 @@ func (j *java) RunWrapper(port, controlPort string) (*os.Process, error) {
 	return cmd.Process, cmd.Start()
 }
 
+// WaitForStart returns true since the wrapper sends a start message once the function is initialized
+func (j *java) WaitForStart() bool {
+	return true
+}
+
 // GetSocketType returns the type of socket the runtime works with (unix/tcp)
 func (j *java) GetSocketType() rpc.SocketType {
 	return rpc.TCPSocket
@@ -0,0 +1,52 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package java
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/test/suite"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RuntimeTestSuite struct {
+	rpcsuite.TestSuite
+}
+
+func (suite *RuntimeTestSuite) SetupSuite() {
+	if _, err := exec.LookPath("java"); err != nil {
+		suite.T().Skip("java isn't installed")
+	}
+
+	// the wrapper jar must be built beforehand, embedding the handler in test/wrapper
+	if !common.IsFile(os.Getenv("NUCLIO_WRAPPER_JAR")) {
+		suite.T().Skip("NUCLIO_WRAPPER_JAR must point to a built wrapper jar")
+	}
+
+	suite.CreateRuntime = NewRuntime
+	suite.Handler = "Handler"
+}
+
+func TestRuntime(t *testing.T) {
+	suite.Run(t, new(RuntimeTestSuite))
+}
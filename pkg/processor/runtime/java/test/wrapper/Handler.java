@@ -0,0 +1,40 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// used by the rpc wrapper tests, run "gradle wrapperJar" with this handler in user-handler.jar and point
+// NUCLIO_WRAPPER_JAR to the built jar before running them
+
+import io.nuclio.Context;
+import io.nuclio.Event;
+import io.nuclio.EventHandler;
+import io.nuclio.Response;
+
+import java.nio.charset.StandardCharsets;
+
+
+public class Handler implements EventHandler {
+    @Override
+    public Response handleEvent(Context context, Event event) {
+        String body = new String(event.getBody(), StandardCharsets.UTF_8);
+
+        if (body.equals("path")) {
+            return new Response().setBody(event.getPath());
+        }
+
+        // echo the body as is, it may not be valid UTF-8
+        return new Response().setBody(event.getBody());
+    }
+}
@@ -51,6 +51,10 @@ context is current call context, it contains the following:
 				- logger.infoWith: function(message, with_data)
 				- logger.debugWith: function(message, with_data)
 
+			sendControlMessage: function(kind, attributes)
+				Sends a control message to the processor (e.g. "webSocketPush" with connectionId
+				and body attributes)
+
 Event is the current event, it contains the following:
 - body: Buffer (*not* string, use event.body.toString())
 - content_type: string
@@ -28,6 +28,10 @@ const messageTypes = {
     START: 's',
 }
 
+const controlMessageKinds = {
+    WRAPPER_INITIALIZED: 'wrapperInitialized',
+}
+
 const logLevels = {
     DEBUG: 'debug',
     INFO: 'info',
@@ -51,7 +55,9 @@ let context = {
         infoWith: logWithLevel(logLevels.INFO),
         debugWith: logWithLevel(logLevels.DEBUG),
     },
+    sendControlMessage: sendControlMessage,
     _socket: undefined,
+    _controlSocket: undefined,
     _eventEmitter: new events.EventEmitter(),
 }
 
@@ -76,6 +82,14 @@ function writeMessageToProcessor(messageType, messageContents) {
     context._socket.write(`${messageType}${messageContents}\n`)
 }
 
+// control messages are written to the processor as JSON lines
+function sendControlMessage(kind, attributes = {}) {
+    if (context._controlSocket === undefined) {
+        throw new Error('Control communication is not available')
+    }
+    context._controlSocket.write(`${JSON.stringify({ kind, attributes })}\n`)
+}
+
 function logWithLevel(level) {
     return (...args) => log(level, ...args)
 }
@@ -150,7 +164,6 @@ function writeDuration(start, end) {
 async function handleEvent(handlerFunction, incomingEvent) {
     let response = {}
     try {
-        incomingEvent.body = new Buffer.from(incomingEvent['body'], 'base64')
         incomingEvent.timestamp = new Date(incomingEvent['timestamp'] * 1000)
 
         const start = new Date()
@@ -194,9 +207,134 @@ async function handleEvent(handlerFunction, incomingEvent) {
     }
 }
 
-function connectSocket(socketPath, handlerFunction) {
+function handleControlMessageEvent(controlMessageEvent) {
+
+    // the body holds the control message, encoded as JSON
+    const controlMessage = JSON.parse(controlMessageEvent.body)
+    context.logger.debugWith('Received control message', {
+        kind: controlMessage.Kind,
+        attributes: controlMessage.Attributes,
+    })
+}
+
+// decodes a single msgpack value, which is all the processor sends
+function decodeMsgPack(buffer) {
+    let offset = 0
+
+    function readInt(byteLength, signed = false) {
+        let value
+        if (byteLength === 8) {
+            value = Number(signed ? buffer.readBigInt64BE(offset) : buffer.readBigUInt64BE(offset))
+        } else {
+            value = signed ? buffer.readIntBE(offset, byteLength) : buffer.readUIntBE(offset, byteLength)
+        }
+        offset += byteLength
+        return value
+    }
+
+    function readFloat(byteLength) {
+        const value = byteLength === 8 ? buffer.readDoubleBE(offset) : buffer.readFloatBE(offset)
+        offset += byteLength
+        return value
+    }
+
+    function readBytes(length) {
+        const bytes = Buffer.from(buffer.subarray(offset, offset + length))
+        offset += length
+        return bytes
+    }
+
+    function readArray(length) {
+        const array = []
+        for (let i = 0; i < length; i++) {
+            array.push(readValue())
+        }
+        return array
+    }
+
+    function readMap(length) {
+        const map = {}
+        for (let i = 0; i < length; i++) {
+            const key = readValue()
+            map[key] = readValue()
+        }
+        return map
+    }
+
+    // decoders of the variable size types
+    const decoders = {
+        0xc0: () => null,
+        0xc2: () => false,
+        0xc3: () => true,
+        0xc4: () => readBytes(readInt(1)),
+        0xc5: () => readBytes(readInt(2)),
+        0xc6: () => readBytes(readInt(4)),
+        0xca: () => readFloat(4),
+        0xcb: () => readFloat(8),
+        0xcc: () => readInt(1),
+        0xcd: () => readInt(2),
+        0xce: () => readInt(4),
+        0xcf: () => readInt(8),
+        0xd0: () => readInt(1, true),
+        0xd1: () => readInt(2, true),
+        0xd2: () => readInt(4, true),
+        0xd3: () => readInt(8, true),
+        0xd9: () => readBytes(readInt(1)).toString(),
+        0xda: () => readBytes(readInt(2)).toString(),
+        0xdb: () => readBytes(readInt(4)).toString(),
+        0xdc: () => readArray(readInt(2)),
+        0xdd: () => readArray(readInt(4)),
+        0xde: () => readMap(readInt(2)),
+        0xdf: () => readMap(readInt(4)),
+    }
+
+    function readValue() {
+        const type = buffer.readUInt8(offset++)
+
+        // fixed size types hold their value or length in the type byte
+        if (type <= 0x7f) {
+            return type
+        } else if (type <= 0x8f) {
+            return readMap(type - 0x80)
+        } else if (type <= 0x9f) {
+            return readArray(type - 0x90)
+        } else if (type <= 0xbf) {
+            return readBytes(type - 0xa0).toString()
+        } else if (type >= 0xe0) {
+            return type - 0x100
+        } else if (decoders[type] === undefined) {
+            throw new Error(`Unsupported msgpack type: 0x${type.toString(16)}`)
+        }
+
+        return decoders[type]()
+    }
+
+    return readValue()
+}
+
+// returns a socket data listener, calling onMessage with every message read. messages are msgpack encoded
+// and prefixed by their size (4 bytes, big endian)
+function createMessageReader(onMessage) {
+    let pendingData = Buffer.alloc(0)
+
+    return data => {
+        pendingData = Buffer.concat([pendingData, data])
+
+        while (pendingData.length >= 4) {
+            const messageEnd = 4 + pendingData.readUInt32BE(0)
+            if (pendingData.length < messageEnd) {
+                return
+            }
+
+            const message = decodeMsgPack(pendingData.subarray(4, messageEnd))
+            pendingData = pendingData.subarray(messageEnd)
+            onMessage(message)
+        }
+    }
+}
+
+function createSocket(socketPath) {
     const socket = new net.Socket()
-    console.log(`socketPath = ${socketPath}`)
     if (socketPath.includes(':')) {
 
         // TCP - host:port
@@ -208,14 +346,36 @@ function connectSocket(socketPath, handlerFunction) {
         // UNIX
         socket.connect(socketPath)
     }
+    return socket
+}
+
+function connectSocket(socketPath, controlSocketPath, handlerFunction) {
+    console.log(`socketPath = ${socketPath}, controlSocketPath = ${controlSocketPath}`)
+
+    // the processor accepts the control connection only after receiving the start message
+    if (controlSocketPath) {
+        const controlSocket = createSocket(controlSocketPath)
+        context._controlSocket = controlSocket
+        controlSocket.on('data', createMessageReader(handleControlMessageEvent))
+    }
+
+    const socket = createSocket(socketPath)
     context._socket = socket
     socket.on('ready', () => {
-        writeMessageToProcessor(messageTypes.START, '')
+
+        // indicate that we're ready, announcing the event encoding and control communication we support
+        writeMessageToProcessor(messageTypes.START, JSON.stringify({
+            encoding: 'msgpack',
+            control_communication: context._controlSocket !== undefined,
+        }))
+
+        if (context._controlSocket !== undefined) {
+            sendControlMessage(controlMessageKinds.WRAPPER_INITIALIZED, { ready: 'true' })
+        }
     })
-    socket.on('data', async data => {
-        let incomingEvent = JSON.parse(data)
+    socket.on('data', createMessageReader(async incomingEvent => {
         await handleEvent(handlerFunction, incomingEvent)
-    })
+    }))
 }
 
 function executeInitContext(functionModule) {
@@ -250,7 +410,7 @@ async function findFunction(functionModule, name) {
     return functionToFind
 }
 
-function run(socketPath, handlerPath, handlerName) {
+function run(socketPath, handlerPath, handlerName, controlSocketPath) {
     if (!isValidPathRegex.test(handlerPath)) {
         throw `Invalid handler path: ${handlerPath}`
     }
@@ -263,7 +423,7 @@ function run(socketPath, handlerPath, handlerName) {
                 console.error(`Failed to init context: ${err}`)
                 throw err
             }
-            return connectSocket(socketPath, handlerFunction)
+            return connectSocket(socketPath, controlSocketPath, handlerFunction)
         })
 }
 
@@ -272,8 +432,8 @@ if (require.main === module) {
     // First two arguments are ['node', '/path/to/wrapper.js']
     const args = process.argv.slice(2)
 
-    // ['/path/to/socket', '/path/to/handler.js', 'handler']
-    if (args.length !== 3) {
+    // ['/path/to/socket', '/path/to/handler.js', 'handler', '/path/to/control/socket' (optional)]
+    if (args.length !== 3 && args.length !== 4) {
         console.error('error: wrong number of arguments')
         process.exit(1)
     }
@@ -281,8 +441,9 @@ if (require.main === module) {
     const socketPath = args[0]
     const handlerPath = args[1]
     const handlerName = args[2]
+    const controlSocketPath = args[3]
 
-    run(socketPath, handlerPath, handlerName)
+    run(socketPath, handlerPath, handlerName, controlSocketPath)
         .catch((err) => {
             console.error('Error occurred during running. Error:', err)
             process.exit(1)
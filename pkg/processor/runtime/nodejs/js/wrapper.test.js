@@ -23,6 +23,28 @@ const wrapper = rewire('./wrapper.js')
 const projectRoot = (process.env.RUN_MODE === 'CI') ? '..' : '../../../../..'
 const testFunctionsDirPath = `${projectRoot}/test/_functions`
 
+// encodes the subset of msgpack the tests send - maps, strings, buffers and small integers
+function encodeMsgPack(value) {
+    if (Buffer.isBuffer(value)) {
+        return Buffer.concat([Buffer.from([0xc4, value.length]), value])
+    } else if (typeof value === 'string') {
+        return Buffer.concat([Buffer.from([0xd9, Buffer.byteLength(value)]), Buffer.from(value)])
+    } else if (typeof value === 'number') {
+        return Buffer.from([value])
+    }
+    const entries = Object.entries(value)
+        .map(([key, entryValue]) => Buffer.concat([encodeMsgPack(key), encodeMsgPack(entryValue)]))
+    return Buffer.concat([Buffer.from([0x80 + entries.length]), ...entries])
+}
+
+// prefixes an encoded message with its size, as the processor does
+function frameMsgPack(value) {
+    const encoded = encodeMsgPack(value)
+    const size = Buffer.alloc(4)
+    size.writeUInt32BE(encoded.length)
+    return Buffer.concat([size, encoded])
+}
+
 describe('Wrapper', () => {
     describe('findFunction()', () => {
         it('should find function handler', async function () {
@@ -70,7 +92,7 @@ describe('Wrapper', () => {
                     writtenData.push(message)
                 }
             }
-            const event = { body: Buffer.from('abc').toString('base64') }
+            const event = { body: Buffer.from('abc') }
             await handleEvent(handlerFunction, event)
             const responseData = JSON.parse(writtenData[1].substring(1))
             assert.strictEqual(responseData.body, 'cba')
@@ -95,7 +117,7 @@ describe('Wrapper', () => {
             const promises = []
             for (let i = 0; i < 1000; i++) {
                 promises.push(handleEvent(handlerFunction, {
-                    body: Buffer.from('abc' + i).toString('base64'),
+                    body: Buffer.from('abc' + i),
                 }))
             }
 
@@ -109,6 +131,52 @@ describe('Wrapper', () => {
             assert.strictEqual(context._eventEmitter.listenerCount('callback'), 0)
         })
     })
+    describe('createMessageReader()', () => {
+        it('should decode messages split across chunks', () => {
+            const createMessageReader = wrapper.__get__('createMessageReader')
+            const messages = []
+            const readMessage = createMessageReader(message => messages.push(message))
+            const framed = Buffer.concat([
+                frameMsgPack({ body: Buffer.from([0x00, 0xff]), path: '/a', size: 2 }),
+                frameMsgPack({ body: Buffer.from('b') }),
+            ])
+
+            // a message split in the middle, followed by the rest of it and another message
+            readMessage(framed.subarray(0, 7))
+            assert.strictEqual(messages.length, 0)
+            readMessage(framed.subarray(7))
+
+            assert.strictEqual(messages.length, 2)
+            assert.deepStrictEqual(messages[0].body, Buffer.from([0x00, 0xff]))
+            assert.strictEqual(messages[0].path, '/a')
+            assert.strictEqual(messages[0].size, 2)
+            assert.deepStrictEqual(messages[1].body, Buffer.from('b'))
+        })
+    })
+    describe('context.sendControlMessage()', () => {
+        it('should write control message as JSON line', () => {
+            const context = wrapper.__get__('context')
+            let writtenData = ''
+            context._controlSocket = {
+                write: (message) => {
+                    writtenData = message
+                }
+            }
+            context.sendControlMessage('webSocketPush', { connectionId: 'id', body: 'hello' })
+            context._controlSocket = undefined
+
+            assert.deepStrictEqual(JSON.parse(writtenData), {
+                kind: 'webSocketPush',
+                attributes: { connectionId: 'id', body: 'hello' },
+            })
+        })
+        it('should fail without control socket', () => {
+            const context = wrapper.__get__('context')
+            assert.throws(() => {
+                context.sendControlMessage('webSocketPush', {})
+            }, Error)
+        })
+    })
     describe('initContext()', () => {
         it('should mutate context object', async () => {
             const functionModulePath = `${testFunctionsDirPath}/common/context-init/nodejs/contextinit.js`
@@ -149,20 +217,25 @@ describe('Wrapper', () => {
 
                 // set in function initContext
                 const factor = 2
-                const requestBody = {
-                    body: (new Buffer.from(number.toString())).toString('base64')
-                }
-                socket.write(new Buffer.from(JSON.stringify(requestBody)))
                 socket.on('data', data => {
-                    if (data.toString().trim() === 's') {
+                    const messages = data.toString().trim().split('\n')
 
-                        // ignore start message
-                        return
+                    // the start message announces msgpack encoding, the event is sent once it's received
+                    const startMessage = messages.find(message => message.startsWith('s'))
+                    if (startMessage !== undefined) {
+                        assert.strictEqual(JSON.parse(startMessage.substring(1)).encoding, 'msgpack')
+                        socket.write(frameMsgPack({ body: Buffer.from(number.toString()) }))
                     }
+
                     responses = [
                         ...responses,
-                        ...data.toString().trim().split('\n'),
-                    ].filter(response => response !== 's').map(response => response.substring(1))
+                        ...messages
+                            .filter(response => !response.startsWith('s'))
+                            .map(response => response.substring(1)),
+                    ]
+                    if (responses.length < 2) {
+                        return
+                    }
                     socket.end()
                     server.close()
                     assert.strictEqual(JSON.parse(responses[1]).body, (number * factor).toString())
@@ -83,6 +83,9 @@ func (n *nodejs) RunWrapper(socketPath, controlSocketPath string) (*os.Process,
 	}
 
 	args := []string{nodeExePath, wrapperScriptPath, socketPath, handlerFilePath, handlerName}
+	if controlSocketPath != "" {
+		args = append(args, controlSocketPath)
+	}
 
 	n.Logger.DebugWith("Running wrapper", "command", strings.Join(args, " "))
 
@@ -0,0 +1,56 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nodejs
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/test/suite"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RuntimeTestSuite struct {
+	rpcsuite.TestSuite
+}
+
+func (suite *RuntimeTestSuite) SetupSuite() {
+	if _, err := exec.LookPath("node"); err != nil {
+		suite.T().Skip("node isn't installed")
+	}
+
+	wrapperPath, err := filepath.Abs(filepath.Join("js", "wrapper.js"))
+	suite.Require().NoError(err)
+
+	handlerDir, err := filepath.Abs(filepath.Join("test", "wrapper"))
+	suite.Require().NoError(err)
+
+	suite.T().Setenv("NUCLIO_NODEJS_WRAPPER_PATH", wrapperPath)
+	suite.T().Setenv("NUCLIO_HANDLER_DIR", handlerDir)
+
+	suite.CreateRuntime = NewRuntime
+	suite.Handler = "handler.js:handler"
+	suite.HandlerSendsControlMessages = true
+}
+
+func TestRuntime(t *testing.T) {
+	suite.Run(t, new(RuntimeTestSuite))
+}
@@ -118,6 +118,9 @@ class Wrapper(object):
                                            worker_id,
                                            nuclio_sdk.TriggerInfo(trigger_kind, trigger_name))
 
+        # let the handler push messages to websocket connections of the http trigger
+        self._context.websocket_push = self._websocket_push
+
         # replace the default output with the process socket
         self._logger.set_handler('default', self._event_sock_wfile, JSONFormatterOverSocket())
 
@@ -141,14 +144,16 @@ class Wrapper(object):
 
                 self._event_message_length_task = None
 
-                # resolve event message
+                # resolve event message (or a list of events, when the processor sends a batch)
                 event = await self._resolve_event(self._event_sock, event_message_length)
 
                 # do not handle an event if a worker is drained
                 if not self._discard_events:
                     try:
-                        # handle event
-                        await self._handle_event(event)
+                        if isinstance(event, list):
+                            await self._handle_batch(event)
+                        else:
+                            await self._handle_event(event)
                     except BaseException as exc:
                         await self._on_handle_event_error(exc)
                 else:
@@ -201,8 +206,11 @@ class Wrapper(object):
         # register to the SIGUSR1 and SIGUSR2 signals, used to signal termination/draining respectively
         self._register_to_signal()
 
-        # indicate that we're ready
-        await self._write_packet_to_processor(self._event_sock, 's')
+        # indicate that we're ready, announcing the event encoding and control communication we support
+        await self._write_packet_to_processor(self._event_sock, 's' + self._json_encoder.encode({
+            'encoding': 'msgpack',
+            'control_communication': True,
+        }))
         await self._send_data_on_control_socket({
             'kind': 'wrapperInitialized',
             'attributes': {'ready': 'true'}
@@ -299,6 +307,22 @@ class Wrapper(object):
 
         # TODO: wait for response that processor received data
 
+    async def _websocket_push(self, connection_id, body):
+        """
+        Writes a text message to a websocket connection, identified by the X-Nuclio-Websocket-Connection-Id header
+        of its events. The message may be pushed at any time, while the connection is open
+        """
+        if isinstance(body, bytes):
+            body = body.decode('utf-8')
+
+        await self._send_data_on_control_socket({
+            'kind': 'webSocketPush',
+            'attributes': {
+                'connectionId': connection_id,
+                'body': body,
+            },
+        })
+
     def _resolve_unpacker(self):
         """
         Since this wrapper is behind the nuclio processor, in which pre-handle the traffic & request
@@ -399,6 +423,13 @@ class Wrapper(object):
         # resolve msgpack event message
         event_message = next(self._unpacker)
 
+        # a batch of events is sent as a list of event messages
+        if isinstance(event_message, list):
+            return [
+                nuclio_sdk.Event.deserialize(batch_event_message, kind=self._event_deserializer_kind)
+                for batch_event_message in event_message
+            ]
+
         # instantiate event message
         return nuclio_sdk.Event.deserialize(event_message, kind=self._event_deserializer_kind)
 
@@ -417,12 +448,7 @@ class Wrapper(object):
 
     async def _write_response_error(self, body):
         try:
-            encoded_response = self._json_encoder.encode({
-                'body': body,
-                'body_encoding': 'text',
-                'content_type': 'text/plain',
-                'status_code': 500,
-            })
+            encoded_response = self._encode_response_error(body)
 
             # try write the formatted exception back to processor
             await self._write_packet_to_processor(self._event_sock, 'r' + encoded_response)
@@ -430,7 +456,38 @@ class Wrapper(object):
             print('Failed to write message to processor after serving error detected, is socket open?\n'
                   'Exception: {0}'.format(str(exc)))
 
+    def _encode_response_error(self, body):
+        return self._json_encoder.encode({
+            'body': body,
+            'body_encoding': 'text',
+            'content_type': 'text/plain',
+            'status_code': 500,
+        })
+
     async def _handle_event(self, event):
+        encoded_response = await self._call_entrypoint(event)
+
+        # write response to the socket
+        await self._write_packet_to_processor(self._event_sock, 'r' + encoded_response)
+
+    async def _handle_batch(self, batch):
+        encoded_responses = []
+
+        # a failing event must not fail the rest of the batch, respond with an error for it instead
+        for event in batch:
+            try:
+                encoded_responses.append(await self._call_entrypoint(event))
+            except Exception as exc:
+                error_message = 'Exception caught in handler'
+                self._logger.error_with(error_message, exc=str(exc), traceback=traceback.format_exc())
+                encoded_responses.append(self._encode_response_error('{0} - "{1}": {2}'.format(error_message,
+                                                                                               exc,
+                                                                                               traceback.format_exc())))
+
+        # write the list of responses, ordered as the batch, to the socket
+        await self._write_packet_to_processor(self._event_sock, 'r[' + ','.join(encoded_responses) + ']')
+
+    async def _call_entrypoint(self, event):
 
         # take call time
         start_time = time.time()
@@ -449,10 +506,7 @@ class Wrapper(object):
                                                               entrypoint_output)
 
         # try to json encode the response
-        encoded_response = self._json_encoder.encode(response)
-
-        # write response to the socket
-        await self._write_packet_to_processor(self._event_sock, 'r' + encoded_response)
+        return self._json_encoder.encode(response)
 
     def _shutdown(self, error_code=0):
         print('Shutting down')
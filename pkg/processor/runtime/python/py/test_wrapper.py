@@ -138,6 +138,19 @@ class TestSubmitEvents(unittest.TestCase):
             self.assertEqual(recorded_event_index, recorded_event.id)
             self.assertEqual('e{}'.format(recorded_event_index), self._ensure_str(recorded_event.body))
 
+    def test_websocket_push(self):
+        """Test pushing to a websocket connection sends a control message to the processor"""
+        with unittest.mock.patch.object(self._wrapper, '_send_data_on_control_socket') as send_data_mock:
+            self._loop.run_until_complete(self._wrapper._context.websocket_push('connection-id', b'pushed'))
+
+        send_data_mock.assert_called_once_with({
+            'kind': 'webSocketPush',
+            'attributes': {
+                'connectionId': 'connection-id',
+                'body': 'pushed',
+            },
+        })
+
     def test_non_utf8_headers(self):
         """
         This test validates the expected behavior for a non-utf8 event field contents
@@ -292,6 +305,38 @@ class TestSubmitEvents(unittest.TestCase):
             self.assertEqual(recorded_event_index, recorded_event.id)
             self.assertEqual('e{}'.format(recorded_event_index), self._ensure_str(recorded_event.body))
 
+    def test_batch_events(self):
+        """Test a batch of events sent in a single message, getting a list of responses"""
+
+        def reverse_or_fail(ctx, event):
+            body = self._ensure_str(event.body)
+            if body == 'fail':
+                raise RuntimeError('failed on purpose')
+            return body[::-1]
+
+        bodies = ['e0', 'fail', 'e2']
+        batch = [nuclio_sdk.Event(_id=i, body=body) for i, body in enumerate(bodies)]
+
+        t = threading.Thread(target=self._send_batch, args=(batch,))
+        t.start()
+
+        self._wrapper._entrypoint = reverse_or_fail
+        asyncio.get_event_loop().run_until_complete(self._wrapper.serve_requests(num_requests=1))
+        t.join()
+
+        # a duration message per successful event and a single response message
+        self._wait_until_received_messages(3)
+
+        # extract the responses, ordered as the batch
+        responses = next(message['body']
+                         for message in self._unix_stream_server._messages
+                         if message['type'] == 'r')
+        self.assertEqual(len(bodies), len(responses))
+        self.assertEqual('0e', responses[0]['body'])
+        self.assertEqual(500, responses[1]['status_code'])
+        self.assertIn('failed on purpose', responses[1]['body'])
+        self.assertEqual('2e', responses[2]['body'])
+
     # to run memory profiling test, uncomment the tests below
     # and from terminal run with
     # > mprof run python -m py.test test_wrapper.py::TestSubmitEvents::test_memory_profiling_<num> --full-trace
@@ -347,6 +392,14 @@ class TestSubmitEvents(unittest.TestCase):
         # then write body content
         self._unix_stream_server._connection_socket.sendall(body)
 
+    def _send_batch(self, events):
+        self._wait_for_socket_creation()
+
+        # a batch is a msgpack list of events
+        body = msgpack.Packer().pack([self._event_to_dict(event) for event in events])
+        self._unix_stream_server._connection_socket.sendall(struct.pack(">I", len(body)))
+        self._unix_stream_server._connection_socket.sendall(body)
+
     def _get_packed_event_body_len(self, event):
         return len(msgpack.Packer().pack(self._event_to_dict(event)))
 
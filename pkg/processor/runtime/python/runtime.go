@@ -126,6 +126,11 @@ func (py *python) SupportsControlCommunication() bool {
 	return true
 }
 
+// SupportsBatching returns true since the wrapper can handle a batch of events in a single message
+func (py *python) SupportsBatching() bool {
+	return true
+}
+
 func (py *python) getHandler() string {
 	return py.configuration.Spec.Handler
 }
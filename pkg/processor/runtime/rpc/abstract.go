@@ -18,6 +18,7 @@ package rpc
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
@@ -61,6 +62,9 @@ type result struct {
 
 	DecodedBody []byte
 	err         error
+
+	// holds the per-event results when the result is of a batch
+	batch []*result
 }
 
 // AbstractRuntime is a runtime that communicates via unix domain socket
@@ -73,13 +77,24 @@ type AbstractRuntime struct {
 	resultChan        chan *result
 	functionLogger    logger.Logger
 	runtime           Runtime
-	startChan         chan struct{}
+	startChan         chan *wrapperCapabilities
 	stopChan          chan struct{}
 	cancelHandlerChan chan struct{}
 	socketType        SocketType
 	processWaiter     *processwaiter.ProcessWaiter
 }
 
+func (res *result) decodeBody() {
+	switch res.BodyEncoding {
+	case "text":
+		res.DecodedBody = []byte(res.Body)
+	case "base64":
+		res.DecodedBody, res.err = base64.StdEncoding.DecodeString(res.Body)
+	default:
+		res.err = fmt.Errorf("Unknown body encoding - %q", res.BodyEncoding)
+	}
+}
+
 type rpcLogRecord struct {
 	DateTime string                 `json:"datetime"`
 	Level    string                 `json:"level"`
@@ -102,7 +117,7 @@ func NewAbstractRuntime(logger logger.Logger,
 		AbstractRuntime: *abstractRuntime,
 		configuration:   configuration,
 		runtime:         runtimeInstance,
-		startChan:       make(chan struct{}, 1),
+		startChan:       make(chan *wrapperCapabilities, 1),
 		stopChan:        make(chan struct{}, 1),
 		socketType:      UnixSocket,
 	}
@@ -152,6 +167,53 @@ func (r *AbstractRuntime) ProcessEvent(event nuclio.Event, functionLogger logger
 	}, result.err
 }
 
+// ProcessBatch processes a batch of events in a single round trip to the wrapper
+func (r *AbstractRuntime) ProcessBatch(batch []nuclio.Event,
+	functionLogger logger.Logger) ([]*runtime.BatchEventResponse, error) {
+	if currentStatus := r.GetStatus(); currentStatus != status.Ready {
+		return nil, errors.Errorf("Processor not ready (current status: %s)", currentStatus)
+	}
+
+	r.functionLogger = functionLogger
+
+	if err := r.eventEncoder.EncodeBatch(batch); err != nil {
+		r.functionLogger = nil
+		return nil, errors.Wrapf(err, "Can't encode batch of %d events", len(batch))
+	}
+
+	batchResult, ok := <-r.resultChan
+	r.functionLogger = nil
+	if !ok {
+		msg := "Client disconnected"
+		r.Logger.Error(msg)
+		r.SetStatus(status.Error)
+		return nil, errors.New(msg)
+	}
+
+	if batchResult.err != nil {
+		return nil, batchResult.err
+	}
+
+	if len(batchResult.batch) != len(batch) {
+		return nil, errors.Errorf("Expected %d results for batch, got %d", len(batch), len(batchResult.batch))
+	}
+
+	responses := make([]*runtime.BatchEventResponse, 0, len(batchResult.batch))
+	for _, eventResult := range batchResult.batch {
+		responses = append(responses, &runtime.BatchEventResponse{
+			Response: nuclio.Response{
+				Body:        eventResult.DecodedBody,
+				ContentType: eventResult.ContentType,
+				Headers:     eventResult.Headers,
+				StatusCode:  eventResult.StatusCode,
+			},
+			ProcessError: eventResult.err,
+		})
+	}
+
+	return responses, nil
+}
+
 // Stop stops the runtime
 func (r *AbstractRuntime) Stop() error {
 	r.Logger.WarnWith("Stopping",
@@ -228,6 +290,11 @@ func (r *AbstractRuntime) SupportsControlCommunication() bool {
 	return false
 }
 
+// SupportsBatching returns true if the runtime supports processing a batch of events in a single round trip
+func (r *AbstractRuntime) SupportsBatching() bool {
+	return false
+}
+
 // Drain signals to the runtime to drain its accumulated events and waits for it to finish
 func (r *AbstractRuntime) Drain() error {
 	// we use SIGUSR2 to signal the wrapper process to drain events
@@ -297,7 +364,9 @@ func (r *AbstractRuntime) startWrapper() error {
 		return errors.Wrap(err, "Failed to create socket connection")
 	}
 
-	if r.runtime.SupportsControlCommunication() {
+	// a wrapper that sends a start message may announce it supports control communication, so it's offered a
+	// control socket as well
+	if r.runtime.SupportsControlCommunication() || r.runtime.WaitForStart() {
 		if err := r.createSocketConnection(&controlConnection); err != nil {
 			return errors.Wrap(err, "Failed to create socket connection")
 		}
@@ -327,13 +396,35 @@ func (r *AbstractRuntime) startWrapper() error {
 		"wid", r.Context.WorkerID,
 		"pid", r.wrapperProcess.Pid)
 
-	r.eventEncoder = r.runtime.GetEventEncoder(eventConnection.conn)
 	r.resultChan = make(chan *result)
 	r.cancelHandlerChan = make(chan struct{})
 	go r.eventWrapperOutputHandler(eventConnection.conn, r.resultChan)
 
+	// wait for start if required to
+	capabilities := &wrapperCapabilities{}
+	if r.runtime.WaitForStart() {
+		r.Logger.Debug("Waiting for start")
+
+		capabilities = <-r.startChan
+	}
+
+	r.eventEncoder, err = r.resolveEventEncoder(capabilities, eventConnection.conn)
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve event encoder")
+	}
+
+	controlCommunication := r.resolveControlCommunication(capabilities)
+
+	r.Logger.DebugWith("Negotiated with wrapper",
+		"wid", r.Context.WorkerID,
+		"encoding", capabilities.Encoding,
+		"controlCommunication", controlCommunication)
+
 	// control connection
-	if r.runtime.SupportsControlCommunication() {
+	if controlCommunication {
+		if controlConnection.listener == nil {
+			return errors.New("Wrapper announced control communication but wasn't offered a control socket")
+		}
 
 		r.Logger.DebugWith("Creating control connection",
 			"wid", r.Context.WorkerID)
@@ -342,7 +433,10 @@ func (r *AbstractRuntime) startWrapper() error {
 			return errors.Wrap(err, "Can't get control connection from wrapper")
 		}
 
-		r.controlEncoder = r.runtime.GetEventEncoder(controlConnection.conn)
+		r.controlEncoder, err = r.resolveEventEncoder(capabilities, controlConnection.conn)
+		if err != nil {
+			return errors.Wrap(err, "Failed to resolve control encoder")
+		}
 
 		// initialize control message broker
 		r.ControlMessageBroker = NewRpcControlMessageBroker(r.controlEncoder, r.Logger, r.configuration.ControlMessageBroker)
@@ -351,13 +445,12 @@ func (r *AbstractRuntime) startWrapper() error {
 
 		r.Logger.DebugWith("Control connection created",
 			"wid", r.Context.WorkerID)
-	}
-
-	// wait for start if required to
-	if r.runtime.WaitForStart() {
-		r.Logger.Debug("Waiting for start")
+	} else if controlConnection.listener != nil {
 
-		<-r.startChan
+		// the wrapper won't use the control socket
+		if err := controlConnection.listener.Close(); err != nil {
+			r.Logger.WarnWith("Failed to close unused control socket listener", "err", err.Error())
+		}
 	}
 
 	r.Logger.Debug("Started")
@@ -472,6 +565,13 @@ func (r *AbstractRuntime) eventWrapperOutputHandler(conn io.Reader, resultChan c
 			switch data[0] {
 			case 'r':
 
+				// a batch result holds a list of per-event results
+				if len(data) > 1 && data[1] == '[' {
+					r.unmarshalBatchResult(data[1:], unmarshalledResult)
+					resultChan <- unmarshalledResult
+					continue
+				}
+
 				// try to unmarshall the result
 				if unmarshalledResult.err = json.Unmarshal(data[1:], unmarshalledResult); unmarshalledResult.err != nil {
 					r.Logger.WarnWith("Failed to unmarshal result", "err", unmarshalledResult.err.Error())
@@ -479,14 +579,7 @@ func (r *AbstractRuntime) eventWrapperOutputHandler(conn io.Reader, resultChan c
 					continue
 				}
 
-				switch unmarshalledResult.BodyEncoding {
-				case "text":
-					unmarshalledResult.DecodedBody = []byte(unmarshalledResult.Body)
-				case "base64":
-					unmarshalledResult.DecodedBody, unmarshalledResult.err = base64.StdEncoding.DecodeString(unmarshalledResult.Body)
-				default:
-					unmarshalledResult.err = fmt.Errorf("Unknown body encoding - %q", unmarshalledResult.BodyEncoding)
-				}
+				unmarshalledResult.decodeBody()
 
 				// write back to result channel
 				resultChan <- unmarshalledResult
@@ -495,12 +588,23 @@ func (r *AbstractRuntime) eventWrapperOutputHandler(conn io.Reader, resultChan c
 			case 'l':
 				r.handleResponseLog(data[1:])
 			case 's':
-				r.handleStart()
+				r.handleStart(data[1:])
 			}
 		}
 	}
 }
 
+func (r *AbstractRuntime) unmarshalBatchResult(data []byte, batchResult *result) {
+	if batchResult.err = json.Unmarshal(data, &batchResult.batch); batchResult.err != nil {
+		r.Logger.WarnWith("Failed to unmarshal batch result", "err", batchResult.err.Error())
+		return
+	}
+
+	for _, eventResult := range batchResult.batch {
+		eventResult.decodeBody()
+	}
+}
+
 func (r *AbstractRuntime) controlOutputHandler(conn io.Reader) {
 
 	// recover from panic in case of error
@@ -613,8 +717,37 @@ func (r *AbstractRuntime) handleResponseMetric(response []byte) {
 	r.Statistics.DurationMilliSecondsSum += uint64(metrics.DurationSec * 1000)
 }
 
-func (r *AbstractRuntime) handleStart() {
-	r.startChan <- struct{}{}
+func (r *AbstractRuntime) handleStart(response []byte) {
+	capabilities := &wrapperCapabilities{}
+
+	// the start message may hold the capabilities announced by the wrapper
+	if response = bytes.TrimSpace(response); len(response) > 0 && response[0] == '{' {
+		if err := json.Unmarshal(response, capabilities); err != nil {
+			r.Logger.WarnWith("Failed to decode wrapper capabilities, using defaults", "err", err.Error())
+			capabilities = &wrapperCapabilities{}
+		}
+	}
+
+	r.startChan <- capabilities
+}
+
+// resolveEventEncoder returns an encoder for the encoding announced by the wrapper, or the runtime's default
+func (r *AbstractRuntime) resolveEventEncoder(capabilities *wrapperCapabilities, writer io.Writer) (EventEncoder, error) {
+	if capabilities.Encoding == "" {
+		return r.runtime.GetEventEncoder(writer), nil
+	}
+
+	return NewEventEncoder(capabilities.Encoding, r.Logger, writer)
+}
+
+// resolveControlCommunication returns whether the wrapper announced it supports control communication, or the
+// runtime's default
+func (r *AbstractRuntime) resolveControlCommunication(capabilities *wrapperCapabilities) bool {
+	if capabilities.ControlCommunication == nil {
+		return r.runtime.SupportsControlCommunication()
+	}
+
+	return *capabilities.ControlCommunication
 }
 
 // resolveFunctionLogger return either functionLogger if provided or root logger if not
@@ -26,6 +26,7 @@ import (
 	"net"
 	"os"
 	"os/exec"
+	"strings"
 	"testing"
 	"time"
 
@@ -37,6 +38,7 @@ import (
 
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 	"github.com/nuclio/zap"
 	"github.com/stretchr/testify/suite"
 )
@@ -95,6 +97,12 @@ func (r *testRuntime) GetEventEncoder(writer io.Writer) EventEncoder {
 	return NewEventJSONEncoder(r.Logger, writer)
 }
 
+type testRuntimeTriggerInfoProvider struct{}
+
+func (ti *testRuntimeTriggerInfoProvider) GetClass() string { return "async" }
+func (ti *testRuntimeTriggerInfoProvider) GetKind() string  { return "test" }
+func (ti *testRuntimeTriggerInfoProvider) GetName() string  { return "test" }
+
 type RuntimeSuite struct {
 	suite.Suite
 	testRuntimeInstance *testRuntime
@@ -204,6 +212,62 @@ func (suite *RuntimeSuite) TestReadControlMessage() {
 	suite.Require().Equal(controlMessage, reslovedControlMessage, "Read control message doesn't match")
 }
 
+func (suite *RuntimeSuite) TestProcessBatch() {
+	var err error
+
+	loggerInstance := suite.createLogger()
+	configInstance := suite.createConfig(loggerInstance)
+
+	suite.testRuntimeInstance, err = newTestRuntime(loggerInstance, configInstance)
+	suite.Require().NoError(err, "Can't create runtime")
+
+	err = suite.testRuntimeInstance.Start()
+	suite.Require().NoError(err, "Can't start runtime")
+
+	// act as the wrapper - read the batch and respond with a result per event
+	go func() {
+		line, err := bufio.NewReader(suite.testRuntimeInstance.eventConn).ReadBytes('\n')
+		suite.Require().NoError(err, "Can't read batch")
+
+		var batch []map[string]interface{}
+		suite.Require().NoError(json.Unmarshal(line, &batch), "Can't decode batch")
+
+		results := []string{
+			`{"status_code": 200, "content_type": "text/plain", "body": "first", "body_encoding": "text"}`,
+			`{"status_code": 500, "content_type": "text/plain", "body": "c2Vjb25k", "body_encoding": "base64"}`,
+		}
+		suite.Require().Len(batch, len(results))
+
+		_, err = suite.testRuntimeInstance.eventConn.Write([]byte("r[" + strings.Join(results, ",") + "]\n"))
+		suite.Require().NoError(err, "Can't write batch result")
+	}()
+
+	batch := []nuclio.Event{
+		&nuclio.MemoryEvent{Body: []byte("first")},
+		&nuclio.MemoryEvent{Body: []byte("second")},
+	}
+	for _, event := range batch {
+		event.SetTriggerInfoProvider(&testRuntimeTriggerInfoProvider{})
+	}
+
+	responses, err := suite.testRuntimeInstance.ProcessBatch(batch, nil)
+	suite.Require().NoError(err, "Can't process batch")
+	suite.Require().Len(responses, 2)
+
+	for index, expected := range []struct {
+		statusCode int
+		body       string
+	}{
+		{statusCode: 200, body: "first"},
+		{statusCode: 500, body: "second"},
+	} {
+		response := responses[index].Response.(nuclio.Response)
+		suite.Require().NoError(responses[index].ProcessError)
+		suite.Require().Equal(expected.statusCode, response.StatusCode)
+		suite.Require().Equal(expected.body, string(response.Body))
+	}
+}
+
 func (suite *RuntimeSuite) TearDownTest() {
 	if suite.testRuntimeInstance != nil && suite.testRuntimeInstance.wrapperProcess != nil {
 		suite.testRuntimeInstance.Stop() // nolint: errcheck
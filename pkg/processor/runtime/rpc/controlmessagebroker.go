@@ -47,7 +47,7 @@ func NewRpcControlMessageBroker(encoder EventEncoder, logger logger.Logger, abst
 	}
 }
 
-// WriteControlMessage writes control message to the control socket using MSGPack encoding
+// WriteControlMessage writes control message to the control socket using the encoding negotiated with the wrapper
 func (b *rpcControlMessageBroker) WriteControlMessage(message *controlcommunication.ControlMessage) error {
 
 	// send control message as a nuclio event, this will be handled by the wrapper
@@ -63,8 +63,7 @@ func (b *rpcControlMessageBroker) WriteControlMessage(message *controlcommunicat
 // ReadControlMessage reads from the control socket and unpacks it into a control message
 func (b *rpcControlMessageBroker) ReadControlMessage(reader *bufio.Reader) (*controlcommunication.ControlMessage, error) {
 
-	// TODO: use msgpack to decode the message
-
+	// wrappers write control messages as JSON lines, regardless of the encoding of events sent to them
 	// read data from reader
 	data, err := reader.ReadBytes('\n')
 	if err != nil {
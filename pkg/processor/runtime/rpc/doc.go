@@ -28,10 +28,24 @@ The wite protocol is line oriented where every line is a JSON object.
     - 'r' Handler reply
     - 'l' Log messages
 	- 'm' Metric messages
+	- 's' Start message
+
+# Negotiation
+A wrapper that sends a start message may follow the 's' with a JSON object announcing its capabilities, e.g.
+`s{"encoding": "msgpack", "control_communication": true}`:
+- encoding - the encoding of the events sent to the wrapper, either "json" or "msgpack"
+- control_communication - whether the wrapper connects to the control socket
+
+Anything not announced falls back to the runtime's defaults (GetEventEncoder / SupportsControlCommunication).
+The Python, NodeJS, Ruby, Java and .NET wrappers all announce msgpack, and control communication when they're
+given a control socket.
 
 # Event Encoding
 - Body is encoded in base64 (to allow binary data)
 - Timestamp is seconds since epoch
+
+When msgpack is negotiated, events are sent as msgpack maps prefixed by their size (4 bytes, big endian) and
+the body is sent as raw bytes. Wrappers always reply with JSON lines.
 */
 
 // Package rpc implmenets Python runtime
@@ -17,11 +17,32 @@ limitations under the License.
 package rpc
 
 import (
+	"io"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
 )
 
 type EventEncoder interface {
+
+	// Encode encodes a single event
 	Encode(event nuclio.Event) error
+
+	// EncodeBatch encodes a batch of events as a single message holding a list of events
+	EncodeBatch(batch []nuclio.Event) error
+}
+
+// NewEventEncoder returns an event encoder for the given encoding
+func NewEventEncoder(encoding EventEncoding, logger logger.Logger, writer io.Writer) (EventEncoder, error) {
+	switch encoding {
+	case EventEncodingJSON:
+		return NewEventJSONEncoder(logger, writer), nil
+	case EventEncodingMsgPack:
+		return NewEventMsgPackEncoder(logger, writer), nil
+	default:
+		return nil, errors.Errorf("Unsupported event encoding: %s", encoding)
+	}
 }
 
 func eventAsMap(event nuclio.Event) map[string]interface{} {
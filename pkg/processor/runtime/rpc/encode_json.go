@@ -38,6 +38,20 @@ func NewEventJSONEncoder(logger logger.Logger, writer io.Writer) *EventJSONEncod
 
 // Encode writes the JSON encoding of event to the stream, followed by a newline character
 func (e *EventJSONEncoder) Encode(event nuclio.Event) error {
+	return json.NewEncoder(e.writer).Encode(e.eventToEncode(event))
+}
+
+// EncodeBatch writes the JSON encoding of a list of events to the stream, followed by a newline character
+func (e *EventJSONEncoder) EncodeBatch(batch []nuclio.Event) error {
+	eventsToEncode := make([]map[string]interface{}, 0, len(batch))
+	for _, event := range batch {
+		eventsToEncode = append(eventsToEncode, e.eventToEncode(event))
+	}
+
+	return json.NewEncoder(e.writer).Encode(eventsToEncode)
+}
+
+func (e *EventJSONEncoder) eventToEncode(event nuclio.Event) map[string]interface{} {
 	eventToEncode := eventAsMap(event)
 
 	// if the body is map[string]interface{} we probably got a cloud event with a structured data member
@@ -48,5 +62,5 @@ func (e *EventJSONEncoder) Encode(event nuclio.Event) error {
 		eventToEncode["body"] = base64.StdEncoding.EncodeToString(event.GetBody())
 	}
 
-	return json.NewEncoder(e.writer).Encode(eventToEncode)
+	return eventToEncode
 }
@@ -20,6 +20,7 @@ package rpc
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"testing"
 	"time"
@@ -221,6 +222,30 @@ func (suite *EventJSONEncoderSuite) TestEncode() {
 	require.Equal(testEvent.GetVersion(), out["version"], "bad version")
 }
 
+func (suite *EventJSONEncoderSuite) TestEncodeBatch() {
+	require := suite.Require()
+	logger, err := nucliozap.NewNuclioZapTest("test")
+	require.NoError(err, "Can't create logger")
+
+	var buf bytes.Buffer
+	enc := NewEventJSONEncoder(logger, &buf)
+	err = enc.EncodeBatch([]nuclio.Event{&TestEvent{}, &TestEvent{}})
+	require.NoError(err, "Can't encode batch")
+
+	// make sure we got a single line holding a list of events
+	require.Equal(1, bytes.Count(buf.Bytes(), []byte("\n")), "batch must be encoded in a single line")
+
+	var out []map[string]interface{}
+	err = json.NewDecoder(&buf).Decode(&out)
+	require.NoError(err, "Can't decode batch")
+	require.Len(out, 2, "bad batch length")
+
+	for _, encodedEvent := range out {
+		require.Equal(testID, nuclio.ID(encodedEvent["id"].(string)), "bad id")
+		require.Equal(base64.StdEncoding.EncodeToString([]byte("body of proof")), encodedEvent["body"], "bad body")
+	}
+}
+
 func TestEventJSONEncoder(t *testing.T) {
 	suite.Run(t, new(EventJSONEncoderSuite))
 }
@@ -44,6 +44,20 @@ func NewEventMsgPackEncoder(logger logger.Logger, writer io.Writer) *EventMsgPac
 
 // Encode writes the JSON encoding of event to the stream, followed by a newline character
 func (e *EventMsgPackEncoder) Encode(event nuclio.Event) error {
+	return e.encode(e.eventToEncode(event))
+}
+
+// EncodeBatch writes the MsgPack encoding of a list of events to the stream, prefixed by its size
+func (e *EventMsgPackEncoder) EncodeBatch(batch []nuclio.Event) error {
+	eventsToEncode := make([]map[string]interface{}, 0, len(batch))
+	for _, event := range batch {
+		eventsToEncode = append(eventsToEncode, e.eventToEncode(event))
+	}
+
+	return e.encode(eventsToEncode)
+}
+
+func (e *EventMsgPackEncoder) eventToEncode(event nuclio.Event) map[string]interface{} {
 	eventToEncode := eventAsMap(event)
 
 	// if the body is map[string]interface{} we probably got a cloud event with a structured data member
@@ -53,8 +67,12 @@ func (e *EventMsgPackEncoder) Encode(event nuclio.Event) error {
 		eventToEncode["body"] = event.GetBody()
 	}
 
+	return eventToEncode
+}
+
+func (e *EventMsgPackEncoder) encode(objectToEncode interface{}) error {
 	e.buf.Reset()
-	if err := e.encoder.Encode(eventToEncode); err != nil {
+	if err := e.encoder.Encode(objectToEncode); err != nil {
 		return errors.Wrap(err, "Failed to encode message")
 	}
 
@@ -19,10 +19,14 @@ limitations under the License.
 package rpc
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/binary"
 	"encoding/json"
 	"io"
 	"net"
+	"os"
+	"os/exec"
 	"strings"
 	"testing"
 	"time"
@@ -30,11 +34,15 @@ import (
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/platformconfig"
 	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 	"github.com/nuclio/zap"
 	"github.com/stretchr/testify/suite"
+	"github.com/vmihailenco/msgpack/v4"
 )
 
 type RPCSuite struct {
@@ -98,3 +106,250 @@ func (suite *RPCSuite) runtimeConfiguration(loggerInstance logger.Logger) *runti
 func TestRPC(t *testing.T) {
 	suite.Run(t, new(RPCSuite))
 }
+
+// negotiatingTestRuntime acts as a wrapper that announces its capabilities in its start message
+type negotiatingTestRuntime struct {
+	*AbstractRuntime
+	wrapperProcess       *os.Process
+	eventConn            net.Conn
+	controlConn          net.Conn
+	startMessage         string
+	connectControl       bool
+	controlCommunication bool
+}
+
+func newNegotiatingTestRuntime(parentLogger logger.Logger,
+	configuration *runtime.Configuration,
+	startMessage string,
+	connectControl bool) (*negotiatingTestRuntime, error) {
+	var err error
+
+	newTestRuntime := &negotiatingTestRuntime{
+		startMessage:   startMessage,
+		connectControl: connectControl,
+	}
+
+	newTestRuntime.AbstractRuntime, err = NewAbstractRuntime(parentLogger.GetChild("logger"),
+		configuration,
+		newTestRuntime)
+
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create runtime")
+	}
+
+	return newTestRuntime, nil
+}
+
+func (r *negotiatingTestRuntime) RunWrapper(eventSocketPath, controlSocketPath string) (*os.Process, error) {
+	var err error
+	cmd := exec.Command("sleep", "999999")
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	r.wrapperProcess = cmd.Process
+
+	r.eventConn, err = net.Dial("unix", eventSocketPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.connectControl {
+		r.controlConn, err = net.Dial("unix", controlSocketPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := r.eventConn.Write([]byte(r.startMessage + "\n")); err != nil {
+		return nil, err
+	}
+
+	return cmd.Process, nil
+}
+
+func (r *negotiatingTestRuntime) GetEventEncoder(writer io.Writer) EventEncoder {
+	return NewEventJSONEncoder(r.Logger, writer)
+}
+
+func (r *negotiatingTestRuntime) WaitForStart() bool {
+	return true
+}
+
+func (r *negotiatingTestRuntime) SupportsControlCommunication() bool {
+	return r.controlCommunication
+}
+
+type NegotiationSuite struct {
+	suite.Suite
+	logger              logger.Logger
+	testRuntimeInstance *negotiatingTestRuntime
+}
+
+func (suite *NegotiationSuite) SetupTest() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("rpc-negotiation-test")
+	suite.Require().NoError(err, "Can't create logger")
+}
+
+func (suite *NegotiationSuite) TearDownTest() {
+	if suite.testRuntimeInstance != nil && suite.testRuntimeInstance.wrapperProcess != nil {
+		suite.testRuntimeInstance.Stop() // nolint: errcheck
+	}
+}
+
+func (suite *NegotiationSuite) TestDefaults() {
+
+	// a start message without capabilities
+	suite.startRuntime("s", false)
+
+	suite.Require().Nil(suite.testRuntimeInstance.GetControlMessageBroker())
+
+	// events are sent as JSON lines
+	suite.requireProcessEvent(func(reader *bufio.Reader) map[string]interface{} {
+		line, err := reader.ReadBytes('\n')
+		suite.Require().NoError(err)
+
+		decodedEvent := map[string]interface{}{}
+		suite.Require().NoError(json.Unmarshal(line, &decodedEvent))
+		return decodedEvent
+	}, "dGVzdA==")
+}
+
+func (suite *NegotiationSuite) TestMsgPackAndControlCommunication() {
+	suite.startRuntime(`s{"encoding": "msgpack", "control_communication": true}`, true)
+
+	// events are sent as size prefixed msgpack, with a raw body
+	readMsgPack := func(reader *bufio.Reader) map[string]interface{} {
+		var size int32
+		suite.Require().NoError(binary.Read(reader, binary.BigEndian, &size))
+
+		encodedEvent := make([]byte, size)
+		_, err := io.ReadFull(reader, encodedEvent)
+		suite.Require().NoError(err)
+
+		decodedEvent := map[string]interface{}{}
+		suite.Require().NoError(msgpack.Unmarshal(encodedEvent, &decodedEvent))
+		return decodedEvent
+	}
+
+	suite.requireProcessEvent(readMsgPack, "test")
+
+	controlMessageBroker := suite.testRuntimeInstance.GetControlMessageBroker()
+	suite.Require().NotNil(controlMessageBroker)
+
+	// control messages written to the wrapper use the negotiated encoding
+	controlMessage := &controlcommunication.ControlMessage{
+		Kind:       "test",
+		Attributes: map[string]interface{}{"key": "value"},
+	}
+	suite.Require().NoError(controlMessageBroker.WriteControlMessage(controlMessage))
+
+	decodedControlMessageEvent := readMsgPack(bufio.NewReader(suite.testRuntimeInstance.controlConn))
+	decodedControlMessage := &controlcommunication.ControlMessage{}
+	suite.Require().NoError(json.Unmarshal(decodedControlMessageEvent["body"].([]byte), decodedControlMessage))
+	suite.Require().Equal(controlMessage, decodedControlMessage)
+
+	// control messages written by the wrapper reach the consumers
+	controlMessageChannel := make(chan *controlcommunication.ControlMessage, 1)
+	suite.Require().NoError(controlMessageBroker.Subscribe("fromWrapper", controlMessageChannel))
+
+	_, err := suite.testRuntimeInstance.controlConn.Write([]byte(`{"kind": "fromWrapper", "attributes": {"a": "b"}}` + "\n"))
+	suite.Require().NoError(err)
+
+	select {
+	case receivedControlMessage := <-controlMessageChannel:
+		suite.Require().Equal(controlcommunication.ControlMessageKind("fromWrapper"), receivedControlMessage.Kind)
+	case <-time.After(5 * time.Second):
+		suite.Fail("Control message from wrapper wasn't received")
+	}
+}
+
+func (suite *NegotiationSuite) TestWrapperDisablesControlCommunication() {
+	var err error
+
+	suite.testRuntimeInstance, err = newNegotiatingTestRuntime(suite.logger,
+		suite.createConfig(),
+		`s{"control_communication": false}`,
+		false)
+	suite.Require().NoError(err)
+
+	// the runtime defaults to control communication, yet the wrapper doesn't support it
+	suite.testRuntimeInstance.controlCommunication = true
+
+	suite.Require().NoError(suite.testRuntimeInstance.Start())
+	suite.Require().Nil(suite.testRuntimeInstance.GetControlMessageBroker())
+}
+
+func (suite *NegotiationSuite) TestUnsupportedEncoding() {
+	var err error
+
+	suite.testRuntimeInstance, err = newNegotiatingTestRuntime(suite.logger,
+		suite.createConfig(),
+		`s{"encoding": "protobuf"}`,
+		false)
+	suite.Require().NoError(err)
+
+	suite.Require().Error(suite.testRuntimeInstance.Start())
+}
+
+func (suite *NegotiationSuite) startRuntime(startMessage string, connectControl bool) {
+	var err error
+
+	suite.testRuntimeInstance, err = newNegotiatingTestRuntime(suite.logger,
+		suite.createConfig(),
+		startMessage,
+		connectControl)
+	suite.Require().NoError(err, "Can't create runtime")
+
+	suite.Require().NoError(suite.testRuntimeInstance.Start(), "Can't start runtime")
+}
+
+// requireProcessEvent processes an event, acting as the wrapper by reading it with the given function
+func (suite *NegotiationSuite) requireProcessEvent(readEvent func(*bufio.Reader) map[string]interface{},
+	expectedBody interface{}) {
+
+	go func() {
+		decodedEvent := readEvent(bufio.NewReader(suite.testRuntimeInstance.eventConn))
+		suite.Require().Equal(expectedBody, suite.normalizeBody(decodedEvent["body"]))
+
+		_, err := suite.testRuntimeInstance.eventConn.Write([]byte(
+			`r{"status_code": 200, "content_type": "text/plain", "body": "ok", "body_encoding": "text"}` + "\n"))
+		suite.Require().NoError(err)
+	}()
+
+	event := &nuclio.MemoryEvent{Body: []byte("test")}
+	event.SetTriggerInfoProvider(&TestTriggerInfoProvider{})
+
+	response, err := suite.testRuntimeInstance.ProcessEvent(event, nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal("ok", string(response.(nuclio.Response).Body))
+}
+
+func (suite *NegotiationSuite) normalizeBody(body interface{}) interface{} {
+	if bodyBytes, isBytes := body.([]byte); isBytes {
+		return string(bodyBytes)
+	}
+
+	return body
+}
+
+func (suite *NegotiationSuite) createConfig() *runtime.Configuration {
+	return &runtime.Configuration{
+		FunctionLogger: suite.logger,
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{
+				Meta: functionconfig.Meta{
+					Namespace: "test",
+				},
+			},
+			PlatformConfig: &platformconfig.Config{
+				Kind: "docker",
+			},
+		},
+	}
+}
+
+func TestNegotiation(t *testing.T) {
+	suite.Run(t, new(NegotiationSuite))
+}
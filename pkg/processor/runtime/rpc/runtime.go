@@ -38,11 +38,17 @@ type Runtime interface {
 	// GetSocketType returns the type of socket the runtime works with (unix/tcp)
 	GetSocketType() SocketType
 
+	// GetEventEncoder returns the event encoder to use, unless the wrapper announced an encoding in its start message
 	GetEventEncoder(writer io.Writer) EventEncoder
 
-	// WaitForStart returns whether the runtime supports sending an indication that it started
+	// WaitForStart returns whether the runtime supports sending an indication that it started. Only wrappers
+	// that send a start message can announce their capabilities in it
 	WaitForStart() bool
 
-	// SupportsControlCommunication returns true if the runtime supports control communication
+	// SupportsControlCommunication returns true if the runtime supports control communication, unless the
+	// wrapper announced otherwise in its start message
 	SupportsControlCommunication() bool
+
+	// SupportsBatching returns true if the wrapper supports receiving a batch of events in a single message
+	SupportsBatching() bool
 }
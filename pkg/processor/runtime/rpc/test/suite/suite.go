@@ -0,0 +1,219 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rpcsuite
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// WrapperControlMessageKind is the kind of control message the handler sends when it receives a "control" body
+const WrapperControlMessageKind = "wrapperTest"
+
+// RuntimeCreator creates the runtime under test
+type RuntimeCreator func(parentLogger logger.Logger, configuration *runtime.Configuration) (runtime.Runtime, error)
+
+// TestSuite runs a runtime's real wrapper against the processor side of the rpc runtime. The handler is expected
+// to return the event path when it receives a "path" body, send a control message of WrapperControlMessageKind and
+// return "sent" when it receives a "control" body and echo the body back otherwise
+type TestSuite struct {
+	suite.Suite
+	Logger          logger.Logger
+	RuntimeInstance runtime.Runtime
+
+	// set by the runtime specific suite
+	CreateRuntime RuntimeCreator
+	Handler       string
+
+	// whether the handler of the runtime can send control messages
+	HandlerSendsControlMessages bool
+
+	loggerSink *syncBuffer
+}
+
+func (suite *TestSuite) SetupTest() {
+	var err error
+
+	suite.loggerSink = &syncBuffer{}
+	suite.Logger, err = nucliozap.NewNuclioZap("rpc-wrapper-test",
+		"json",
+		nil,
+		suite.loggerSink,
+		suite.loggerSink,
+		nucliozap.DebugLevel)
+	suite.Require().NoError(err, "Can't create logger")
+}
+
+func (suite *TestSuite) TearDownTest() {
+	if suite.RuntimeInstance != nil {
+		suite.RuntimeInstance.Stop() // nolint: errcheck
+		suite.RuntimeInstance = nil
+	}
+}
+
+func (suite *TestSuite) TestBinaryBody() {
+	suite.StartRuntime()
+
+	// the body isn't valid UTF-8, so it must arrive as raw bytes
+	body := []byte{0x00, 0xff, 0xfe, 'b', 'i', 'n'}
+	suite.Require().Equal(body, suite.ProcessEvent(&nuclio.MemoryEvent{Body: body}))
+}
+
+func (suite *TestSuite) TestEventFields() {
+	suite.StartRuntime()
+
+	event := &nuclio.MemoryEvent{
+		Body:    []byte("path"),
+		Path:    "/some/path",
+		Headers: map[string]interface{}{"X-Test": "value"},
+	}
+	suite.Require().Equal("/some/path", string(suite.ProcessEvent(event)))
+}
+
+func (suite *TestSuite) TestControlMessageFromProcessor() {
+	suite.StartRuntime()
+
+	controlMessageBroker := suite.RuntimeInstance.GetControlMessageBroker()
+	suite.Require().NotNil(controlMessageBroker, "Wrapper didn't negotiate control communication")
+
+	err := controlMessageBroker.WriteControlMessage(&controlcommunication.ControlMessage{
+		Kind:       "processorTest",
+		Attributes: map[string]interface{}{"key": "value"},
+	})
+	suite.Require().NoError(err)
+
+	// wrappers log the control messages they receive
+	suite.Require().Eventually(func() bool {
+		loggedMessages := suite.loggerSink.String()
+		return strings.Contains(loggedMessages, "Received control message") &&
+			strings.Contains(loggedMessages, "processorTest")
+	}, 10*time.Second, 100*time.Millisecond, "Wrapper didn't receive the control message")
+
+	// events are still handled
+	suite.Require().Equal("after control", string(suite.ProcessEvent(&nuclio.MemoryEvent{
+		Body: []byte("after control"),
+	})))
+}
+
+func (suite *TestSuite) TestControlMessageFromHandler() {
+	if !suite.HandlerSendsControlMessages {
+		suite.T().Skip("Handler can't send control messages")
+	}
+
+	suite.StartRuntime()
+
+	controlMessageBroker := suite.RuntimeInstance.GetControlMessageBroker()
+	suite.Require().NotNil(controlMessageBroker, "Wrapper didn't negotiate control communication")
+
+	controlMessageChannel := make(chan *controlcommunication.ControlMessage, 1)
+	err := controlMessageBroker.Subscribe(WrapperControlMessageKind, controlMessageChannel)
+	suite.Require().NoError(err)
+
+	suite.Require().Equal("sent", string(suite.ProcessEvent(&nuclio.MemoryEvent{Body: []byte("control")})))
+
+	select {
+	case controlMessage := <-controlMessageChannel:
+		suite.Require().Equal(map[string]interface{}{"key": "value"}, controlMessage.Attributes)
+	case <-time.After(10 * time.Second):
+		suite.Fail("Control message from handler wasn't received")
+	}
+}
+
+// StartRuntime creates the runtime and starts its wrapper
+func (suite *TestSuite) StartRuntime() {
+	var err error
+
+	suite.RuntimeInstance, err = suite.CreateRuntime(suite.Logger, suite.createConfiguration())
+	suite.Require().NoError(err, "Can't create runtime")
+
+	suite.Require().NoError(suite.RuntimeInstance.Start(), "Can't start runtime\n%s", suite.loggerSink.String())
+}
+
+// ProcessEvent processes an event through the wrapper, returning the response body
+func (suite *TestSuite) ProcessEvent(event *nuclio.MemoryEvent) []byte {
+	event.SetTriggerInfoProvider(&triggerInfoProvider{})
+
+	response, err := suite.RuntimeInstance.ProcessEvent(event, nil)
+	suite.Require().NoError(err, "Failed to process event\n%s", suite.loggerSink.String())
+
+	typedResponse, isResponse := response.(nuclio.Response)
+	suite.Require().True(isResponse)
+	suite.Require().Equal(200, typedResponse.StatusCode, string(typedResponse.Body))
+
+	return typedResponse.Body
+}
+
+func (suite *TestSuite) createConfiguration() *runtime.Configuration {
+	return &runtime.Configuration{
+		FunctionLogger: suite.Logger,
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{
+				Meta: functionconfig.Meta{
+					Name:      "rpc-wrapper-test",
+					Namespace: "test",
+				},
+				Spec: functionconfig.Spec{
+					Handler: suite.Handler,
+				},
+			},
+			PlatformConfig: &platformconfig.Config{
+				Kind: "docker",
+			},
+		},
+	}
+}
+
+type triggerInfoProvider struct{}
+
+func (ti *triggerInfoProvider) GetClass() string { return "sync" }
+func (ti *triggerInfoProvider) GetKind() string  { return "http" }
+func (ti *triggerInfoProvider) GetName() string  { return "rpc-wrapper-test" }
+
+// syncBuffer collects the logs written by the runtime and its wrapper, which are written concurrently
+type syncBuffer struct {
+	lock   sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *syncBuffer) Write(data []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.buffer.Write(data)
+}
+
+func (b *syncBuffer) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.buffer.String()
+}
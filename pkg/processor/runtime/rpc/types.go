@@ -15,3 +15,17 @@ limitations under the License.
 */
 
 package rpc
+
+// EventEncoding is the encoding of the events sent to the wrapper
+type EventEncoding string
+
+const (
+	EventEncodingJSON    EventEncoding = "json"
+	EventEncodingMsgPack EventEncoding = "msgpack"
+)
+
+// wrapperCapabilities are announced by the wrapper in its start message, overriding the runtime's defaults
+type wrapperCapabilities struct {
+	Encoding             EventEncoding `json:"encoding,omitempty"`
+	ControlCommunication *bool         `json:"control_communication,omitempty"`
+}
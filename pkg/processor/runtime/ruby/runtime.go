@@ -65,6 +65,10 @@ func (r *ruby) RunWrapper(socketPath, controlSocketPath string) (*os.Process, er
 		"--socket-path", socketPath,
 	}
 
+	if controlSocketPath != "" {
+		args = append(args, "--control-socket-path", controlSocketPath)
+	}
+
 	env := os.Environ()
 	env = append(env, r.GetEnvFromConfiguration()...)
 
@@ -80,3 +84,8 @@ func (r *ruby) RunWrapper(socketPath, controlSocketPath string) (*os.Process, er
 func (r *ruby) GetEventEncoder(writer io.Writer) rpc.EventEncoder {
 	return rpc.NewEventJSONEncoder(r.Logger, writer)
 }
+
+// WaitForStart returns true since the wrapper sends a start message once the function is initialized
+func (r *ruby) WaitForStart() bool {
+	return true
+}
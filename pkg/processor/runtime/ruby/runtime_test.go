@@ -0,0 +1,53 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ruby
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/processor/runtime/rpc/test/suite"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type RuntimeTestSuite struct {
+	rpcsuite.TestSuite
+}
+
+func (suite *RuntimeTestSuite) SetupSuite() {
+	if _, err := exec.LookPath("ruby"); err != nil {
+		suite.T().Skip("ruby isn't installed")
+	}
+
+	wrapperPath, err := filepath.Abs("wrapper.rb")
+	suite.Require().NoError(err)
+
+	suite.T().Setenv("NUCLIO_WRAPPER_PATH", wrapperPath)
+
+	// the handler file is required relative to the wrapper
+	suite.CreateRuntime = NewRuntime
+	suite.Handler = "test/wrapper/handler:handler"
+	suite.HandlerSendsControlMessages = true
+}
+
+func TestRuntime(t *testing.T) {
+	suite.Run(t, new(RuntimeTestSuite))
+}
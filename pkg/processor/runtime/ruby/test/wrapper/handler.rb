@@ -0,0 +1,28 @@
+#
+# Copyright 2023 The Nuclio Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+
+# used by the rpc wrapper tests
+def handler(context, event)
+  case event.body
+  when 'path'
+    event.path
+  when 'control'
+    context.send_control_message('wrapperTest', { key: 'value' })
+    'sent'
+  else
+    ByteBuffer.new(event.body)
+  end
+end
@@ -56,10 +56,80 @@ class Context
   attr_reader :logger
   attr_accessor :user_data
 
-  def initialize(logger)
+  def initialize(logger, control_socket = nil)
     @logger = logger
+    @control_socket = control_socket
     @user_data = nil
   end
+
+  # sends a control message to the processor (e.g. 'webSocketPush' with connectionId and body attributes)
+  def send_control_message(kind, attributes = {})
+    raise 'Control communication is not available' if @control_socket.nil?
+
+    @control_socket.puts({ kind: kind, attributes: attributes }.to_json)
+  end
+end
+
+# decodes the subset of msgpack the processor sends events with
+class MsgPackDecoder
+  UINT_FORMATS = { 1 => 'C', 2 => 'n', 4 => 'N', 8 => 'Q>' }.freeze
+  INT_FORMATS = { 1 => 'c', 2 => 's>', 4 => 'l>', 8 => 'q>' }.freeze
+
+  def initialize(data)
+    @data = data
+    @offset = 0
+  end
+
+  def decode
+    type = read_bytes(1).ord
+    case type
+    when 0x00..0x7f then type
+    when 0x80..0x8f then read_map(type - 0x80)
+    when 0x90..0x9f then read_array(type - 0x90)
+    when 0xa0..0xbf then read_string(type - 0xa0)
+    when 0xe0..0xff then type - 0x100
+    when 0xc0 then nil
+    when 0xc2 then false
+    when 0xc3 then true
+    when 0xc4..0xc6 then read_bytes(read_uint(1 << (type - 0xc4)))
+    when 0xca then read_bytes(4).unpack1('g')
+    when 0xcb then read_bytes(8).unpack1('G')
+    when 0xcc..0xcf then read_uint(1 << (type - 0xcc))
+    when 0xd0..0xd3 then read_int(1 << (type - 0xd0))
+    when 0xd9..0xdb then read_string(read_uint(1 << (type - 0xd9)))
+    when 0xdc..0xdd then read_array(read_uint(2 << (type - 0xdc)))
+    when 0xde..0xdf then read_map(read_uint(2 << (type - 0xde)))
+    else raise "Unsupported msgpack type: 0x#{type.to_s(16)}"
+    end
+  end
+
+  private
+
+  def read_bytes(length)
+    bytes = @data.byteslice(@offset, length)
+    @offset += length
+    bytes
+  end
+
+  def read_uint(length)
+    read_bytes(length).unpack1(UINT_FORMATS[length])
+  end
+
+  def read_int(length)
+    read_bytes(length).unpack1(INT_FORMATS[length])
+  end
+
+  def read_string(length)
+    read_bytes(length).force_encoding(Encoding::UTF_8)
+  end
+
+  def read_array(length)
+    Array.new(length) { decode }
+  end
+
+  def read_map(length)
+    Array.new(length) { [decode, decode] }.to_h
+  end
 end
 
 class ByteBuffer
@@ -112,29 +182,48 @@ def response_info_from_output(handler_output)
   end
 end
 
-def parse_event(input)
-  json = JSON.parse(input)
-  trigger = Trigger.new(class_name: json['trigger']['class'], kind: json['trigger']['kind'])
+# reads a msgpack encoded message, prefixed by its size (4 bytes, big endian)
+def read_message(socket)
+  size = socket.read(4)
+  return nil if size.nil?
+
+  MsgPackDecoder.new(socket.read(size.unpack1('N'))).decode
+end
+
+def parse_event(message)
+  trigger = Trigger.new(class_name: message['trigger']['class'], kind: message['trigger']['kind'])
   Event.new(
-    body: Base64.decode64(json['body']),
-    content_type: json['content_type'],
-    headers: json['headers'],
-    fields: json['fields'],
-    id: json['id'],
-    method: json['method'],
-    path: json['path'],
-    url: json['url'],
-    timestamp: DateTime.strptime(json['timestamp'].to_s, '%s'),
+    body: message['body'],
+    content_type: message['content_type'],
+    headers: message['headers'],
+    fields: message['fields'],
+    id: message['id'],
+    method: message['method'],
+    path: message['path'],
+    url: message['url'],
+    timestamp: DateTime.strptime(message['timestamp'].to_s, '%s'),
     trigger: trigger,
-    version: json['version']
+    version: message['version']
   )
 end
 
+def receive_control_messages(control_socket, logger)
+  while (control_message_event = read_message(control_socket))
+
+    # the body holds the control message, encoded as JSON
+    control_message = JSON.parse(control_message_event['body'])
+    logger.debug('Received control message',
+                 kind: control_message['Kind'],
+                 attributes: control_message['Attributes'])
+  end
+end
+
 if $PROGRAM_NAME == __FILE__
   options = {}
   OptionParser.new do |opt|
     opt.on('--handler HANDLER') { |o| options[:handler] = o }
     opt.on('--socket-path SOCKET_PATH') { |o| options[:socket_path] = o }
+    opt.on('--control-socket-path CONTROL_SOCKET_PATH') { |o| options[:control_socket_path] = o }
   end.parse!
 
   file, method_name = options[:handler].split(':')
@@ -142,19 +231,27 @@ if $PROGRAM_NAME == __FILE__
   require_relative file
 
   socket = UNIXSocket.new(options[:socket_path])
+  control_socket = UNIXSocket.new(options[:control_socket_path]) if options[:control_socket_path]
   logger = Logger.new(socket)
-  context = Context.new(logger)
+  context = Context.new(logger, control_socket)
 
   # check if init_context function is defined and execute it
   if defined?(init_context)
       send("init_context", context)
   end
-  socket.puts "s#"
 
-  while input = socket.gets
+  # indicate that we're ready, announcing the event encoding and control communication we support
+  socket.puts "s#{{ encoding: 'msgpack', control_communication: !control_socket.nil? }.to_json}"
+
+  unless control_socket.nil?
+    context.send_control_message('wrapperInitialized', { ready: 'true' })
+    Thread.new { receive_control_messages(control_socket, logger) }
+  end
+
+  while message = read_message(socket)
     startTime = Process.clock_gettime(Process::CLOCK_MONOTONIC)
     begin
-      event = parse_event(input)
+      event = parse_event(message)
       res = send(method_name, context, event)
       encoded = response_from_output(res)
     rescue StandardError => e
@@ -75,6 +75,23 @@ type Runtime interface {
 	GetControlMessageBroker() controlcommunication.ControlMessageBroker
 }
 
+// BatchProcessor is implemented by runtimes that can receive a batch of events in a single round trip
+type BatchProcessor interface {
+
+	// ProcessBatch receives a batch of events and processes them at the specific runtime, returning a
+	// response per event (in the order of the batch)
+	ProcessBatch(batch []nuclio.Event, functionLogger logger.Logger) ([]*BatchEventResponse, error)
+
+	// SupportsBatching returns true if the runtime is able to process batches
+	SupportsBatching() bool
+}
+
+// BatchEventResponse holds the outcome of a single event that was processed as part of a batch
+type BatchEventResponse struct {
+	Response     interface{}
+	ProcessError error
+}
+
 // AbstractRuntime is the base for all runtimes
 type AbstractRuntime struct {
 	Logger               logger.Logger
@@ -0,0 +1,204 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// a process that exited sooner than this after being started is restarted only once this has passed,
+// so that a command which exits immediately doesn't spin
+const persistentProcessMinUptime = time.Second
+
+type persistentProcess struct {
+	logger    logger.Logger
+	framing   Framing
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	stdout    *os.File
+	reader    *bufio.Reader
+	startTime time.Time
+	exited    chan struct{}
+	stopped   atomic.Bool
+	stopOnce  sync.Once
+}
+
+func newPersistentProcess(parentLogger logger.Logger,
+	functionLogger logger.Logger,
+	framing Framing,
+	cmd *exec.Cmd) (*persistentProcess, error) {
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create stdin pipe")
+	}
+
+	// stdout and stderr are read through pipes we own rather than through cmd's pipes, as the latter
+	// are closed once the process exits - possibly before everything the process wrote was read
+	stdoutReader, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create stdout pipe")
+	}
+
+	stderrReader, stderrWriter, err := os.Pipe()
+	if err != nil {
+		stdoutReader.Close() // nolint: errcheck
+		stdoutWriter.Close() // nolint: errcheck
+		return nil, errors.Wrap(err, "Failed to create stderr pipe")
+	}
+
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+
+	startErr := cmd.Start()
+
+	// the process holds its own copies of the write ends
+	stdoutWriter.Close() // nolint: errcheck
+	stderrWriter.Close() // nolint: errcheck
+
+	if startErr != nil {
+		stdoutReader.Close() // nolint: errcheck
+		stderrReader.Close() // nolint: errcheck
+		return nil, errors.Wrap(startErr, "Failed to start process")
+	}
+
+	newProcess := &persistentProcess{
+		logger:    parentLogger.GetChild("process"),
+		framing:   framing,
+		cmd:       cmd,
+		stdin:     stdin,
+		stdout:    stdoutReader,
+		reader:    bufio.NewReader(stdoutReader),
+		startTime: time.Now(),
+		exited:    make(chan struct{}),
+	}
+
+	go newProcess.forwardStderr(functionLogger, stderrReader)
+
+	return newProcess, nil
+}
+
+// exchange writes the event body to the process and reads its response
+func (p *persistentProcess) exchange(body []byte) ([]byte, error) {
+	if err := p.writeMessage(body); err != nil {
+		return nil, errors.Wrap(err, "Failed to write event to process")
+	}
+
+	response, err := p.readMessage()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read response from process")
+	}
+
+	return response, nil
+}
+
+func (p *persistentProcess) writeMessage(body []byte) error {
+	var message []byte
+
+	switch p.framing {
+	case FramingLengthPrefixed:
+		message = make([]byte, 4+len(body))
+		binary.BigEndian.PutUint32(message, uint32(len(body)))
+		copy(message[4:], body)
+	default:
+		message = make([]byte, 0, len(body)+1)
+		message = append(message, body...)
+		message = append(message, '\n')
+	}
+
+	_, err := p.stdin.Write(message)
+	return err
+}
+
+func (p *persistentProcess) readMessage() ([]byte, error) {
+	switch p.framing {
+	case FramingLengthPrefixed:
+		header := make([]byte, 4)
+		if _, err := io.ReadFull(p.reader, header); err != nil {
+			return nil, err
+		}
+
+		message := make([]byte, binary.BigEndian.Uint32(header))
+		if _, err := io.ReadFull(p.reader, message); err != nil {
+			return nil, err
+		}
+
+		return message, nil
+	default:
+		line, err := p.reader.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		return bytes.TrimSuffix(line, []byte("\r")), nil
+	}
+}
+
+// wait blocks until the process exits and returns its exit error
+func (p *persistentProcess) wait() error {
+	err := p.cmd.Wait()
+	close(p.exited)
+
+	return err
+}
+
+// stop kills the process and waits for it to exit. an in flight exchange fails once the process is stopped
+func (p *persistentProcess) stop() {
+	p.stopOnce.Do(func() {
+		p.stopped.Store(true)
+
+		if err := p.cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			p.logger.WarnWith("Failed to kill process", "pid", p.cmd.Process.Pid, "err", err)
+		}
+
+		<-p.exited
+
+		// unblock readers in case a child of the process still holds stdout open
+		p.stdout.Close() // nolint: errcheck
+	})
+}
+
+func (p *persistentProcess) isStopped() bool {
+	return p.stopped.Load()
+}
+
+func (p *persistentProcess) forwardStderr(functionLogger logger.Logger, stderr *os.File) {
+	defer stderr.Close() // nolint: errcheck
+
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			continue
+		}
+
+		functionLogger.InfoWith(line, "pid", p.cmd.Process.Pid)
+	}
+}
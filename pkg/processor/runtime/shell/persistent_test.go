@@ -0,0 +1,273 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package shell
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/platformconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+type PersistentTestSuite struct {
+	suite.Suite
+	logger                logger.Logger
+	handlerDir            string
+	previousHandlerDirEnv string
+}
+
+func (suite *PersistentTestSuite) SetupSuite() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.handlerDir = suite.T().TempDir()
+	for name, script := range map[string]string{
+
+		// keeps state across events
+		"counter.sh": `i=0
+while read line; do
+  i=$((i+1))
+  echo "$i:$line"
+done
+`,
+
+		// handles a single event and exits
+		"once.sh": `read line
+echo "once:$line"
+exit 1
+`,
+
+		// never responds
+		"hang.sh": `while read line; do
+  sleep 10
+done
+`,
+	} {
+		err = os.WriteFile(path.Join(suite.handlerDir, name), []byte(script), 0644)
+		suite.Require().NoError(err)
+	}
+
+	suite.previousHandlerDirEnv = os.Getenv("NUCLIO_SHELL_HANDLER_DIR")
+	err = os.Setenv("NUCLIO_SHELL_HANDLER_DIR", suite.handlerDir)
+	suite.Require().NoError(err)
+}
+
+func (suite *PersistentTestSuite) TearDownSuite() {
+	suite.Require().NoError(os.Setenv("NUCLIO_SHELL_HANDLER_DIR", suite.previousHandlerDirEnv))
+}
+
+func (suite *PersistentTestSuite) TestConfigurationDefaults() {
+	configuration, err := NewConfiguration(suite.getRuntimeConfiguration("cat", nil, ""))
+	suite.Require().NoError(err)
+	suite.Require().Equal(ModeFork, configuration.Mode)
+	suite.Require().Equal(FramingLine, configuration.Framing)
+	suite.Require().Zero(configuration.eventTimeout)
+
+	// event timeout falls back to the function's
+	configuration, err = NewConfiguration(suite.getRuntimeConfiguration("cat", map[string]interface{}{
+		"mode":    "Persistent",
+		"framing": "lengthprefixed",
+	}, "3s"))
+	suite.Require().NoError(err)
+	suite.Require().Equal(ModePersistent, configuration.Mode)
+	suite.Require().Equal(FramingLengthPrefixed, configuration.Framing)
+	suite.Require().Equal(3*time.Second, configuration.eventTimeout)
+
+	configuration, err = NewConfiguration(suite.getRuntimeConfiguration("cat", map[string]interface{}{
+		"mode":         "persistent",
+		"eventTimeout": "500ms",
+	}, "3s"))
+	suite.Require().NoError(err)
+	suite.Require().Equal(500*time.Millisecond, configuration.eventTimeout)
+}
+
+func (suite *PersistentTestSuite) TestConfigurationInvalid() {
+	for _, attributes := range []map[string]interface{}{
+		{"mode": "daemon"},
+		{"mode": "persistent", "framing": "json"},
+		{"mode": "persistent", "eventTimeout": "soon"},
+		{"mode": "persistent", "eventTimeout": "-1s"},
+	} {
+		_, err := NewConfiguration(suite.getRuntimeConfiguration("cat", attributes, ""))
+		suite.Require().Error(err, "attributes: %v", attributes)
+	}
+}
+
+func (suite *PersistentTestSuite) TestLineFraming() {
+	shellRuntime := suite.createRuntime("cat", map[string]interface{}{"mode": "persistent"})
+
+	pid := suite.getPid(shellRuntime)
+	for _, body := range []string{"first", "second", ""} {
+		suite.requireResponse(shellRuntime, body, body)
+	}
+
+	// same process handled all events
+	suite.Require().Equal(pid, suite.getPid(shellRuntime))
+
+	// newlines would desync the process
+	_, err := shellRuntime.ProcessEvent(&nuclio.MemoryEvent{Body: []byte("multi\nline")}, suite.logger)
+	suite.Require().Error(err)
+	suite.Require().Equal(http.StatusBadRequest, err.(*nuclio.ErrorWithStatusCode).StatusCode())
+}
+
+func (suite *PersistentTestSuite) TestLengthPrefixedFraming() {
+	shellRuntime := suite.createRuntime("cat", map[string]interface{}{
+		"mode":    "persistent",
+		"framing": "lengthPrefixed",
+	})
+
+	for _, body := range []string{"multi\nline", "", string([]byte{0, 1, 2, 3})} {
+		suite.requireResponse(shellRuntime, body, body)
+	}
+}
+
+func (suite *PersistentTestSuite) TestState() {
+	shellRuntime := suite.createRuntime("counter.sh", map[string]interface{}{"mode": "persistent"})
+
+	suite.requireResponse(shellRuntime, "a", "1:a")
+	suite.requireResponse(shellRuntime, "b", "2:b")
+	suite.requireResponse(shellRuntime, "c", "3:c")
+}
+
+func (suite *PersistentTestSuite) TestRestartOnExit() {
+	shellRuntime := suite.createRuntime("once.sh", map[string]interface{}{"mode": "persistent"})
+
+	pid := suite.getPid(shellRuntime)
+	suite.requireResponse(shellRuntime, "a", "once:a")
+
+	// a new process is started once the previous one exits
+	suite.Require().Eventually(func() bool {
+		process := suite.getProcess(shellRuntime)
+		return process != nil && process.cmd.Process.Pid != pid
+	}, 5*time.Second, 50*time.Millisecond)
+
+	suite.requireResponse(shellRuntime, "b", "once:b")
+}
+
+func (suite *PersistentTestSuite) TestEventTimeout() {
+	shellRuntime := suite.createRuntime("hang.sh", map[string]interface{}{
+		"mode":         "persistent",
+		"eventTimeout": "200ms",
+	})
+
+	pid := suite.getPid(shellRuntime)
+
+	_, err := shellRuntime.ProcessEvent(&nuclio.MemoryEvent{Body: []byte("a")}, suite.logger)
+	suite.Require().Error(err)
+	suite.Require().Equal(http.StatusRequestTimeout, err.(*nuclio.ErrorWithStatusCode).StatusCode())
+
+	// the timed out process was stopped and the next event is handled by a new one
+	suite.Require().Nil(suite.getProcess(shellRuntime))
+	suite.Require().NotEqual(pid, suite.getPid(shellRuntime))
+}
+
+func (suite *PersistentTestSuite) TestRestart() {
+	shellRuntime := suite.createRuntime("hang.sh", map[string]interface{}{"mode": "persistent"})
+
+	pid := suite.getPid(shellRuntime)
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		suite.Require().NoError(shellRuntime.Restart())
+	}()
+
+	// the in flight event is failed by the restart
+	_, err := shellRuntime.ProcessEvent(&nuclio.MemoryEvent{Body: []byte("a")}, suite.logger)
+	suite.Require().Error(err)
+	suite.Require().Equal(http.StatusRequestTimeout, err.(*nuclio.ErrorWithStatusCode).StatusCode())
+
+	suite.Require().Eventually(func() bool {
+		process := suite.getProcess(shellRuntime)
+		return process != nil && process.cmd.Process.Pid != pid
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func (suite *PersistentTestSuite) createRuntime(handler string, attributes map[string]interface{}) *shell {
+	configuration, err := NewConfiguration(suite.getRuntimeConfiguration(handler, attributes, ""))
+	suite.Require().NoError(err)
+
+	runtimeInstance, err := NewRuntime(suite.logger, configuration)
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(runtimeInstance.Start())
+	suite.T().Cleanup(func() {
+		suite.Require().NoError(runtimeInstance.Stop())
+	})
+
+	return runtimeInstance.(*shell)
+}
+
+func (suite *PersistentTestSuite) requireResponse(shellRuntime *shell, body string, expectedBody string) {
+	response, err := shellRuntime.ProcessEvent(&nuclio.MemoryEvent{Body: []byte(body)}, suite.logger)
+	suite.Require().NoError(err)
+
+	typedResponse := response.(nuclio.Response)
+	suite.Require().Equal(http.StatusOK, typedResponse.StatusCode, string(typedResponse.Body))
+	suite.Require().Equal(expectedBody, string(typedResponse.Body))
+}
+
+func (suite *PersistentTestSuite) getProcess(shellRuntime *shell) *persistentProcess {
+	shellRuntime.processLock.Lock()
+	defer shellRuntime.processLock.Unlock()
+
+	return shellRuntime.process
+}
+
+// getPid returns the pid of the current process, starting one if needed
+func (suite *PersistentTestSuite) getPid(shellRuntime *shell) int {
+	process, err := shellRuntime.getPersistentProcess()
+	suite.Require().NoError(err)
+
+	return process.cmd.Process.Pid
+}
+
+func (suite *PersistentTestSuite) getRuntimeConfiguration(handler string,
+	attributes map[string]interface{},
+	eventTimeout string) *runtime.Configuration {
+	return &runtime.Configuration{
+		FunctionLogger: suite.logger,
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{
+				Spec: functionconfig.Spec{
+					Handler:           handler,
+					RuntimeAttributes: attributes,
+					EventTimeout:      eventTimeout,
+				},
+			},
+			PlatformConfig: &platformconfig.Config{},
+		},
+	}
+}
+
+func TestPersistentTestSuite(t *testing.T) {
+	suite.Run(t, new(PersistentTestSuite))
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package shell
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/http"
@@ -24,6 +25,7 @@ import (
 	"os/exec"
 	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
@@ -45,6 +47,11 @@ type shell struct {
 	commandInPath  bool
 	ctx            context.Context
 	restartChannel chan struct{}
+
+	// persistent mode
+	process       *persistentProcess
+	processLock   sync.Mutex
+	lifecycleLock sync.Mutex
 }
 
 // NewRuntime returns a new shell runtime
@@ -89,6 +96,10 @@ func NewRuntime(parentLogger logger.Logger, configuration *Configuration) (runti
 }
 
 func (s *shell) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	if s.configuration.persistent() {
+		return s.processEventPersistent(event)
+	}
+
 	command := []string{s.command}
 	command = append(command, s.getCommandArguments(event)...)
 
@@ -266,6 +277,13 @@ func (s *shell) getEnvFromEvent(event nuclio.Event) []string {
 }
 
 func (s *shell) Restart() error {
+	if s.configuration.persistent() {
+		s.lifecycleLock.Lock()
+		defer s.lifecycleLock.Unlock()
+
+		return s.restartPersistent()
+	}
+
 	if err := s.Stop(); err != nil {
 		return errors.Wrap(err, "Failed to stop runtime")
 	}
@@ -275,10 +293,29 @@ func (s *shell) Restart() error {
 }
 
 func (s *shell) Start() error {
+	if s.configuration.persistent() {
+		s.lifecycleLock.Lock()
+		defer s.lifecycleLock.Unlock()
+
+		return s.startPersistent()
+	}
+
 	s.SetStatus(status.Ready)
 	return nil
 }
 
+func (s *shell) Stop() error {
+	if s.configuration.persistent() {
+		s.lifecycleLock.Lock()
+		defer s.lifecycleLock.Unlock()
+
+		s.stopPersistent()
+		return nil
+	}
+
+	return s.AbstractRuntime.Stop()
+}
+
 func (s *shell) SupportsRestart() bool {
 	return true
 }
@@ -299,3 +336,217 @@ func (s *shell) commandIsInPath() (bool, error) {
 
 	return false, nil
 }
+
+func (s *shell) processEventPersistent(event nuclio.Event) (interface{}, error) {
+
+	// with line framing a newline would end the event prematurely and desync the process
+	if s.configuration.Framing == FramingLine && bytes.ContainsAny(event.GetBody(), "\r\n") {
+		return nil, nuclio.NewErrBadRequest("Event body must not contain newlines when using line framing")
+	}
+
+	process, err := s.getPersistentProcess()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get persistent process")
+	}
+
+	s.Logger.DebugWith("Sending event to persistent process",
+		"name", s.configuration.Meta.Name,
+		"eventID", event.GetID(),
+		"bodyLen", len(event.GetBody()),
+		"pid", process.cmd.Process.Pid,
+		"eventTimeout", s.configuration.eventTimeout)
+
+	type exchangeResult struct {
+		body []byte
+		err  error
+	}
+
+	// save timestamp
+	startTime := time.Now()
+
+	resultChan := make(chan exchangeResult, 1)
+	go func() {
+		body, err := process.exchange(event.GetBody())
+		resultChan <- exchangeResult{body: body, err: err}
+	}()
+
+	var timeoutChan <-chan time.Time
+	if s.configuration.eventTimeout > 0 {
+		timeoutTimer := time.NewTimer(s.configuration.eventTimeout)
+		defer timeoutTimer.Stop()
+		timeoutChan = timeoutTimer.C
+	}
+
+	select {
+	case result := <-resultChan:
+		if result.err != nil {
+
+			// the process was stopped while handling the event (e.g. restarted due to a timeout)
+			if process.isStopped() {
+				return nil, nuclio.NewErrRequestTimeout("Failed waiting for function execution")
+			}
+
+			s.Logger.ErrorWith("Failed to exchange event with persistent process",
+				"name", s.configuration.Meta.Name,
+				"version", s.configuration.Spec.Version,
+				"eventID", event.GetID(),
+				"pid", process.cmd.Process.Pid,
+				"err", result.err)
+
+			// the process can't be trusted to be in sync anymore, a new one is started for the next event
+			s.stopPersistentProcess(process)
+
+			return nuclio.Response{
+				StatusCode: http.StatusInternalServerError,
+				Headers:    s.configuration.ResponseHeaders,
+				Body:       []byte(fmt.Sprintf(ResponseErrorFormat, result.err, "")),
+			}, nil
+		}
+
+		// calculate call duration
+		callDuration := time.Since(startTime)
+
+		// add duration to sum
+		s.Statistics.DurationMilliSecondsSum += uint64(callDuration.Nanoseconds() / 1000000)
+		s.Statistics.DurationMilliSecondsCount++
+
+		s.Logger.DebugWith("Persistent process responded",
+			"eventID", event.GetID(),
+			"callDuration", callDuration)
+
+		return nuclio.Response{
+			StatusCode: http.StatusOK,
+			Headers:    s.configuration.ResponseHeaders,
+			Body:       result.body,
+		}, nil
+
+	case <-timeoutChan:
+		s.Logger.WarnWith("Persistent process timed out handling event, stopping it",
+			"name", s.configuration.Meta.Name,
+			"eventID", event.GetID(),
+			"pid", process.cmd.Process.Pid,
+			"eventTimeout", s.configuration.eventTimeout)
+
+		s.stopPersistentProcess(process)
+
+		return nil, nuclio.NewErrRequestTimeout("Failed waiting for function execution")
+	}
+}
+
+func (s *shell) startPersistent() error {
+	if _, err := s.getPersistentProcess(); err != nil {
+		s.SetStatus(status.Error)
+		return errors.Wrap(err, "Failed to start persistent process")
+	}
+
+	s.SetStatus(status.Ready)
+	return nil
+}
+
+func (s *shell) stopPersistent() {
+	s.processLock.Lock()
+	process := s.process
+	s.process = nil
+	s.processLock.Unlock()
+
+	if process != nil {
+		process.stop()
+	}
+
+	s.SetStatus(status.Stopped)
+}
+
+func (s *shell) restartPersistent() error {
+	s.Logger.Warn("Restarting persistent process")
+
+	s.stopPersistent()
+	return s.startPersistent()
+}
+
+// getPersistentProcess returns the running persistent process, starting one if there's none
+func (s *shell) getPersistentProcess() (*persistentProcess, error) {
+	s.processLock.Lock()
+	defer s.processLock.Unlock()
+
+	if s.process != nil {
+		return s.process, nil
+	}
+
+	command := []string{s.command}
+	command = append(command, strings.Split(s.configuration.Arguments, " ")...)
+
+	var cmd *exec.Cmd
+	if s.commandInPath {
+
+		// exec so that the command replaces sh and is the process we stop
+		cmd = exec.Command("sh", "-c", "exec "+strings.Join(command, " "))
+	} else {
+		cmd = exec.Command("sh", command...)
+	}
+
+	cmd.Env = s.env
+
+	process, err := newPersistentProcess(s.Logger, s.FunctionLogger, s.configuration.Framing, cmd)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create persistent process")
+	}
+
+	s.Logger.InfoWith("Started persistent process",
+		"name", s.configuration.Meta.Name,
+		"command", command,
+		"framing", s.configuration.Framing,
+		"pid", process.cmd.Process.Pid)
+
+	s.process = process
+
+	go s.waitForPersistentProcess(process)
+
+	return process, nil
+}
+
+// stopPersistentProcess stops the given process, clearing it if it's still the current one
+func (s *shell) stopPersistentProcess(process *persistentProcess) {
+	s.processLock.Lock()
+	if s.process == process {
+		s.process = nil
+	}
+	s.processLock.Unlock()
+
+	process.stop()
+}
+
+// waitForPersistentProcess restarts the runtime if the process exits without being stopped
+func (s *shell) waitForPersistentProcess(process *persistentProcess) {
+	err := process.wait()
+	if process.isStopped() {
+		return
+	}
+
+	s.Logger.WarnWith("Persistent process exited unexpectedly",
+		"name", s.configuration.Meta.Name,
+		"pid", process.cmd.Process.Pid,
+		"err", err)
+
+	// don't spin on a command that exits right away
+	if uptime := time.Since(process.startTime); uptime < persistentProcessMinUptime {
+		time.Sleep(persistentProcessMinUptime - uptime)
+	}
+
+	s.lifecycleLock.Lock()
+	defer s.lifecycleLock.Unlock()
+
+	// the runtime was stopped or the process was already replaced in the meantime
+	s.processLock.Lock()
+	currentProcess := s.process
+	s.processLock.Unlock()
+
+	if currentProcess != process || s.GetStatus() == status.Stopped {
+		return
+	}
+
+	if err := s.restartPersistent(); err != nil {
+		s.Logger.ErrorWith("Failed to restart after persistent process exited",
+			"name", s.configuration.Meta.Name,
+			"err", err)
+	}
+}
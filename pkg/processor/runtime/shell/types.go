@@ -17,6 +17,9 @@ limitations under the License.
 package shell
 
 import (
+	"strings"
+	"time"
+
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 
 	"github.com/mitchellh/mapstructure"
@@ -25,10 +28,39 @@ import (
 
 const ResponseErrorFormat = "Failed to run shell command.\nError: %s\nOutput:%s"
 
+type Mode string
+
+const (
+
+	// ModeFork forks the command for every event
+	ModeFork Mode = "fork"
+
+	// ModePersistent starts the command once and exchanges events over its stdin / stdout
+	ModePersistent Mode = "persistent"
+)
+
+type Framing string
+
+const (
+
+	// FramingLine delimits events and responses with a newline
+	FramingLine Framing = "line"
+
+	// FramingLengthPrefixed prefixes events and responses with their length as a 4 byte big endian integer
+	FramingLengthPrefixed Framing = "lengthPrefixed"
+)
+
 type Configuration struct {
 	*runtime.Configuration
 	Arguments       string
 	ResponseHeaders map[string]interface{}
+
+	// persistent mode
+	Mode         Mode
+	Framing      Framing
+	EventTimeout string
+
+	eventTimeout time.Duration
 }
 
 func NewConfiguration(runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
@@ -41,5 +73,54 @@ func NewConfiguration(runtimeConfiguration *runtime.Configuration) (*Configurati
 		return nil, errors.Wrap(err, "Failed to decode attributes")
 	}
 
+	if err := newConfiguration.populateDefaults(); err != nil {
+		return nil, errors.Wrap(err, "Failed to populate defaults")
+	}
+
 	return &newConfiguration, nil
 }
+
+func (c *Configuration) populateDefaults() error {
+	switch Mode(strings.ToLower(string(c.Mode))) {
+	case "", ModeFork:
+		c.Mode = ModeFork
+	case ModePersistent:
+		c.Mode = ModePersistent
+	default:
+		return errors.Errorf("Unsupported mode: %s", c.Mode)
+	}
+
+	switch strings.ToLower(string(c.Framing)) {
+	case "", strings.ToLower(string(FramingLine)):
+		c.Framing = FramingLine
+	case strings.ToLower(string(FramingLengthPrefixed)):
+		c.Framing = FramingLengthPrefixed
+	default:
+		return errors.Errorf("Unsupported framing: %s", c.Framing)
+	}
+
+	// fall back to the function's event timeout
+	eventTimeout := c.EventTimeout
+	if eventTimeout == "" {
+		eventTimeout = c.Spec.EventTimeout
+	}
+
+	if eventTimeout != "" {
+		parsedEventTimeout, err := time.ParseDuration(eventTimeout)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to parse event timeout: %s", eventTimeout)
+		}
+
+		if parsedEventTimeout < 0 {
+			return errors.Errorf("Event timeout must not be negative: %s", eventTimeout)
+		}
+
+		c.eventTimeout = parsedEventTimeout
+	}
+
+	return nil
+}
+
+func (c *Configuration) persistent() bool {
+	return c.Mode == ModePersistent
+}
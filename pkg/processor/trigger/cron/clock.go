@@ -0,0 +1,35 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cron
+
+import "time"
+
+// clock provides the current time and timers, allowing tests to control the passing of time
+type clock interface {
+	Now() time.Time
+	After(duration time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (rc realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (rc realClock) After(duration time.Duration) <-chan time.Time {
+	return time.After(duration)
+}
@@ -20,20 +20,149 @@ package cron
 
 import (
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 	nucliozap "github.com/nuclio/zap"
 	cronlib "github.com/robfig/cron/v3"
 	"github.com/stretchr/testify/suite"
 )
 
+// fakeClock is a clock whose time only passes when the test advances it
+type fakeClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	deadline time.Time
+	channel  chan time.Time
+}
+
+func (fc *fakeClock) Now() time.Time {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	return fc.now
+}
+
+func (fc *fakeClock) After(duration time.Duration) <-chan time.Time {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	channel := make(chan time.Time, 1)
+	if duration <= 0 {
+		channel <- fc.now
+		return channel
+	}
+
+	fc.timers = append(fc.timers, &fakeTimer{
+		deadline: fc.now.Add(duration),
+		channel:  channel,
+	})
+
+	return channel
+}
+
+func (fc *fakeClock) advance(duration time.Duration) {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	fc.now = fc.now.Add(duration)
+
+	var pendingTimers []*fakeTimer
+	for _, timer := range fc.timers {
+		if timer.deadline.After(fc.now) {
+			pendingTimers = append(pendingTimers, timer)
+			continue
+		}
+
+		timer.channel <- fc.now
+	}
+
+	fc.timers = pendingTimers
+}
+
+func (fc *fakeClock) getTimerCount() int {
+	fc.lock.Lock()
+	defer fc.lock.Unlock()
+
+	return len(fc.timers)
+}
+
+// mockRuntime blocks each event until it is released, restarted or the test ends
+type mockRuntime struct {
+	runtime.Runtime
+	lock            sync.Mutex
+	release         chan struct{}
+	done            chan struct{}
+	supportsRestart bool
+	runs            int
+	activeRuns      int
+	maxActiveRuns   int
+	restarts        int
+}
+
+func (mr *mockRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	mr.lock.Lock()
+	mr.runs++
+	mr.activeRuns++
+	if mr.activeRuns > mr.maxActiveRuns {
+		mr.maxActiveRuns = mr.activeRuns
+	}
+	mr.lock.Unlock()
+
+	select {
+	case <-mr.release:
+	case <-mr.done:
+	}
+
+	mr.lock.Lock()
+	mr.activeRuns--
+	mr.lock.Unlock()
+
+	return nil, nil
+}
+
+func (mr *mockRuntime) SupportsRestart() bool {
+	return mr.supportsRestart
+}
+
+func (mr *mockRuntime) Restart() error {
+	mr.lock.Lock()
+	mr.restarts++
+	mr.lock.Unlock()
+
+	// the restarted run ends
+	mr.release <- struct{}{}
+
+	return nil
+}
+
+func (mr *mockRuntime) getCounters() (int, int, int) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	return mr.runs, mr.maxActiveRuns, mr.restarts
+}
+
 type TestSuite struct {
 	suite.Suite
-	trigger cron
-	logger  logger.Logger
+	trigger        cron
+	logger         logger.Logger
+	clock          *fakeClock
+	runtime        *mockRuntime
+	runningTrigger *cron
 }
 
 func (suite *TestSuite) SetupSuite() {
@@ -41,8 +170,219 @@ func (suite *TestSuite) SetupSuite() {
 }
 
 func (suite *TestSuite) SetupTest() {
-	suite.trigger = cron{}
+	suite.trigger = cron{
+		configuration: &Configuration{
+			MissedRunsPolicy: MissedRunsPolicyFireOnce,
+		},
+		clock: realClock{},
+	}
 	suite.trigger.Logger = suite.logger.GetChild("cron")
+
+	suite.clock = &fakeClock{
+		now: time.Date(2024, 1, 1, 0, 30, 0, 0, time.UTC),
+	}
+	suite.runtime = &mockRuntime{
+		release: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	suite.runningTrigger = nil
+}
+
+func (suite *TestSuite) TearDownTest() {
+	close(suite.runtime.done)
+
+	if suite.runningTrigger != nil {
+		suite.runningTrigger.Stop(false) // nolint: errcheck
+	}
+}
+
+func (suite *TestSuite) TestConfigurationDefaults() {
+	configuration, err := suite.createConfiguration(map[string]interface{}{
+		"interval": "1m",
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(ConcurrencyPolicyForbid, configuration.ConcurrencyPolicy)
+	suite.Require().Equal(MissedRunsPolicyFireOnce, configuration.MissedRunsPolicy)
+	suite.Require().Zero(configuration.jitter)
+
+	// policies are case-insensitive, as they are for kubernetes cron jobs
+	configuration, err = suite.createConfiguration(map[string]interface{}{
+		"interval":          "1m",
+		"concurrencyPolicy": "replace",
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(ConcurrencyPolicyReplace, configuration.ConcurrencyPolicy)
+}
+
+func (suite *TestSuite) TestInvalidConfiguration() {
+	for _, attributes := range []map[string]interface{}{
+		{"timezone": "Not/AZone"},
+		{"jitter": "-1s"},
+		{"jitter": "soon"},
+		{"concurrencyPolicy": "Sometimes"},
+		{"missedRunsPolicy": "fire-twice"},
+	} {
+		attributes["schedule"] = "*/5 * * * *"
+
+		_, err := suite.createConfiguration(attributes)
+		suite.Require().Error(err, "Attributes: %v", attributes)
+	}
+}
+
+func (suite *TestSuite) TestTimezone() {
+	triggerInstance := suite.createTrigger(map[string]interface{}{
+		"schedule": "0 9 * * *",
+		"timezone": "Asia/Tokyo",
+	}, 1)
+
+	// 09:30 in Tokyo, so the next run is tomorrow at 09:00 Tokyo time
+	nextEventSubmitTime, nextEventSubmitDelay, events := triggerInstance.getNextEventSubmit(suite.clock.Now())
+	suite.Require().True(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC).Equal(nextEventSubmitTime))
+	suite.Require().Equal(23*time.Hour+30*time.Minute, nextEventSubmitDelay)
+	suite.Require().Equal(1, events)
+}
+
+func (suite *TestSuite) TestJitter() {
+	triggerInstance := suite.createTrigger(map[string]interface{}{
+		"interval": "1m",
+		"jitter":   "10s",
+	}, 1)
+
+	jittered := false
+	for attempt := 0; attempt < 100; attempt++ {
+		lastEventSubmitTime := suite.clock.Now()
+		nextEventSubmitTime, nextEventSubmitDelay, events := triggerInstance.getNextEventSubmit(lastEventSubmitTime)
+
+		// jitter delays the run, but not the time the following runs are calculated from
+		suite.Require().Equal(lastEventSubmitTime.Add(time.Minute), nextEventSubmitTime)
+		suite.Require().GreaterOrEqual(nextEventSubmitDelay, time.Minute)
+		suite.Require().Less(nextEventSubmitDelay, time.Minute+10*time.Second)
+		suite.Require().Equal(1, events)
+
+		jittered = jittered || nextEventSubmitDelay != time.Minute
+	}
+
+	suite.Require().True(jittered)
+}
+
+func (suite *TestSuite) TestMissedRunsPolicy() {
+	lastEventSubmitTime := suite.clock.Now().Add(-3*time.Minute - 30*time.Second)
+
+	for _, testCase := range []struct {
+		missedRunsPolicy            MissedRunsPolicy
+		expectedNextEventSubmitTime time.Time
+		expectedDelay               time.Duration
+		expectedEvents              int
+	}{
+		{MissedRunsPolicyFireOnce, lastEventSubmitTime.Add(3 * time.Minute), 0, 1},
+		{MissedRunsPolicyFireAll, lastEventSubmitTime.Add(3 * time.Minute), 0, 3},
+		{MissedRunsPolicySkip, lastEventSubmitTime.Add(4 * time.Minute), 30 * time.Second, 1},
+	} {
+		triggerInstance := suite.createTrigger(map[string]interface{}{
+			"interval":         "1m",
+			"missedRunsPolicy": string(testCase.missedRunsPolicy),
+		}, 1)
+
+		nextEventSubmitTime, nextEventSubmitDelay, events := triggerInstance.getNextEventSubmit(lastEventSubmitTime)
+		suite.Require().Equal(testCase.expectedNextEventSubmitTime, nextEventSubmitTime, testCase.missedRunsPolicy)
+		suite.Require().Equal(testCase.expectedDelay, nextEventSubmitDelay, testCase.missedRunsPolicy)
+		suite.Require().Equal(testCase.expectedEvents, events, testCase.missedRunsPolicy)
+	}
+}
+
+func (suite *TestSuite) TestConcurrencyPolicyForbid() {
+	suite.startTrigger(map[string]interface{}{
+		"interval": "1m",
+	}, 2)
+
+	suite.advanceToNextRun(time.Minute)
+	suite.waitForRuns(1)
+
+	// runs due while the first one is running are missed, and fired once it ends
+	suite.clock.advance(3 * time.Minute)
+	suite.runtime.release <- struct{}{}
+	suite.waitForRuns(2)
+	suite.runtime.release <- struct{}{}
+
+	suite.Require().Eventually(func() bool {
+		return suite.clock.getTimerCount() > 0
+	}, 5*time.Second, time.Millisecond)
+
+	runs, maxActiveRuns, _ := suite.runtime.getCounters()
+	suite.Require().Equal(2, runs)
+	suite.Require().Equal(1, maxActiveRuns)
+}
+
+func (suite *TestSuite) TestConcurrencyPolicyAllow() {
+	suite.startTrigger(map[string]interface{}{
+		"interval":          "1m",
+		"concurrencyPolicy": "Allow",
+	}, 2)
+
+	suite.advanceToNextRun(time.Minute)
+	suite.waitForRuns(1)
+
+	suite.advanceToNextRun(time.Minute)
+	suite.waitForRuns(2)
+
+	_, maxActiveRuns, restarts := suite.runtime.getCounters()
+	suite.Require().Equal(2, maxActiveRuns)
+	suite.Require().Zero(restarts)
+}
+
+func (suite *TestSuite) TestConcurrencyPolicyReplace() {
+	suite.runtime.supportsRestart = true
+
+	suite.startTrigger(map[string]interface{}{
+		"interval":          "1m",
+		"concurrencyPolicy": "Replace",
+	}, 1)
+
+	suite.advanceToNextRun(time.Minute)
+	suite.waitForRuns(1)
+
+	// the second run restarts the worker handling the first one, taking its place
+	suite.advanceToNextRun(time.Minute)
+	suite.waitForRuns(2)
+
+	_, maxActiveRuns, restarts := suite.runtime.getCounters()
+	suite.Require().Equal(1, maxActiveRuns)
+	suite.Require().Equal(1, restarts)
+}
+
+func (suite *TestSuite) TestStopAndStartAgain() {
+	suite.startTrigger(map[string]interface{}{
+		"interval": "1m",
+	}, 1)
+
+	suite.advanceToNextRun(time.Minute)
+	suite.waitForRuns(1)
+	suite.runtime.release <- struct{}{}
+
+	_, err := suite.runningTrigger.Stop(false)
+	suite.Require().NoError(err)
+
+	// the stopped trigger left its pending timer behind
+	suite.Require().Equal(1, suite.clock.getTimerCount())
+
+	// restarted triggers are stopped and started on the same instance
+	err = suite.runningTrigger.Start(nil)
+	suite.Require().NoError(err)
+
+	suite.Require().Eventually(func() bool {
+		return suite.clock.getTimerCount() == 2
+	}, 5*time.Second, time.Millisecond)
+
+	suite.clock.advance(time.Minute)
+	suite.waitForRuns(2)
+	suite.runtime.release <- struct{}{}
+
+	_, err = suite.runningTrigger.Stop(false)
+	suite.Require().NoError(err)
+
+	// stopping a stopped trigger does nothing
+	_, err = suite.runningTrigger.Stop(false)
+	suite.Require().NoError(err)
 }
 
 func (suite *TestSuite) TestScheduleBackwardsCompatibility() {
@@ -87,7 +427,7 @@ func (suite *TestSuite) TestGetInterval() {
 
 		// test delay
 		lastRuntime := time.Now().Add(-test.lastTimeDifference)
-		nextEventDelay := suite.trigger.getNextEventSubmitDelay(suite.trigger.schedule, lastRuntime)
+		_, nextEventDelay, _ := suite.trigger.getNextEventSubmit(lastRuntime)
 
 		suite.Require().Conditionf(func() (success bool) {
 			return nextEventDelay <= delay
@@ -95,7 +435,7 @@ func (suite *TestSuite) TestGetInterval() {
 
 		// test misses ticks
 		lastRuntime = time.Now().Add(-test.lastTimeDifference)
-		missedTicks := suite.trigger.getMissedTicks(suite.trigger.schedule, lastRuntime)
+		missedTicks, _ := suite.trigger.getMissedRuns(lastRuntime, time.Now())
 		expectedMissedTicks := int(test.lastTimeDifference / delay)
 		suite.Require().EqualValues(expectedMissedTicks, missedTicks)
 	}
@@ -107,7 +447,7 @@ func (suite *TestSuite) TestGetMissedTicksScheduleHandlesNoMisses() {
 	suite.Assert().NoError(err, "Invalid interval string")
 
 	lastRuntime := time.Now()
-	missedTicks := suite.trigger.getMissedTicks(suite.trigger.schedule, lastRuntime)
+	missedTicks, _ := suite.trigger.getMissedRuns(lastRuntime, time.Now())
 
 	suite.Assert().EqualValues(0, missedTicks)
 }
@@ -121,7 +461,7 @@ func (suite *TestSuite) TestGetMissedTicksScheduleCountsMisses() {
 	suite.Require().NoError(err)
 
 	lastRuntime := time.Now().Add(-lastTimeDifference)
-	missedTicks := suite.trigger.getMissedTicks(suite.trigger.schedule, lastRuntime)
+	missedTicks, _ := suite.trigger.getMissedRuns(lastRuntime, time.Now())
 
 	suite.Assert().EqualValues(2, missedTicks)
 }
@@ -133,7 +473,7 @@ func (suite *TestSuite) TestGetNextEventSubmitDelayScheduleNoMisses() {
 	suite.Assert().NoError(err, "Invalid interval string")
 
 	lastRuntime := time.Now()
-	nextEventDelay := suite.trigger.getNextEventSubmitDelay(suite.trigger.schedule, lastRuntime)
+	_, nextEventDelay, _ := suite.trigger.getNextEventSubmit(lastRuntime)
 
 	expectedEventDelay, err := time.ParseDuration("5m")
 	suite.Assert().NoError(err, "Invalid interval string")
@@ -156,7 +496,7 @@ func (suite *TestSuite) TestGetNextEventSubmitDelayScheduleRunsImmediatelyOnMiss
 	suite.Require().NoError(err)
 
 	lastRuntime := time.Now().Add(-lastTimeDifference)
-	nextEventDelay := suite.trigger.getNextEventSubmitDelay(suite.trigger.schedule, lastRuntime)
+	_, nextEventDelay, _ := suite.trigger.getNextEventSubmit(lastRuntime)
 
 	suite.Assert().EqualValues(0, nextEventDelay)
 }
@@ -186,6 +526,64 @@ func (suite *TestSuite) getInterval(delay string) (cronlib.Schedule, error) {
 	return cronlib.ConstantDelaySchedule{Delay: delayDuration}, nil
 }
 
+func (suite *TestSuite) createConfiguration(attributes map[string]interface{}) (*Configuration, error) {
+	return NewConfiguration("test", &functionconfig.Trigger{
+		Kind:       "cron",
+		Attributes: attributes,
+	}, &runtime.Configuration{
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{},
+		},
+	})
+}
+
+func (suite *TestSuite) createTrigger(attributes map[string]interface{}, numWorkers int) *cron {
+	configuration, err := suite.createConfiguration(attributes)
+	suite.Require().NoError(err)
+
+	var workers []*worker.Worker
+	for workerIdx := 0; workerIdx < numWorkers; workerIdx++ {
+		workerInstance, err := worker.NewWorker(suite.logger, workerIdx, suite.runtime)
+		suite.Require().NoError(err)
+
+		workers = append(workers, workerInstance)
+	}
+
+	workerAllocator, err := worker.NewFixedPoolWorkerAllocator(suite.logger, workers)
+	suite.Require().NoError(err)
+
+	triggerInstance, err := newTrigger(suite.logger, workerAllocator, configuration, nil)
+	suite.Require().NoError(err)
+
+	cronTrigger := triggerInstance.(*cron)
+	cronTrigger.clock = suite.clock
+
+	return cronTrigger
+}
+
+func (suite *TestSuite) startTrigger(attributes map[string]interface{}, numWorkers int) {
+	suite.runningTrigger = suite.createTrigger(attributes, numWorkers)
+
+	err := suite.runningTrigger.Start(nil)
+	suite.Require().NoError(err)
+}
+
+// advanceToNextRun waits for the trigger to wait for its next run, and then advances the clock
+func (suite *TestSuite) advanceToNextRun(duration time.Duration) {
+	suite.Require().Eventually(func() bool {
+		return suite.clock.getTimerCount() > 0
+	}, 5*time.Second, time.Millisecond)
+
+	suite.clock.advance(duration)
+}
+
+func (suite *TestSuite) waitForRuns(expectedRuns int) {
+	suite.Require().Eventually(func() bool {
+		runs, _, _ := suite.runtime.getCounters()
+		return runs == expectedRuns
+	}, 5*time.Second, time.Millisecond)
+}
+
 func TestCronSuite(t *testing.T) {
 	suite.Run(t, new(TestSuite))
 }
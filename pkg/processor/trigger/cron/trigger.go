@@ -17,7 +17,9 @@ limitations under the License.
 package cron
 
 import (
+	"math/rand"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
@@ -37,10 +39,15 @@ const (
 
 type cron struct {
 	trigger.AbstractTrigger
-	configuration *Configuration
-	tickMethod    int
-	schedule      cronlib.Schedule
-	stop          chan int
+	configuration     *Configuration
+	tickMethod        int
+	schedule          cronlib.Schedule
+	clock             clock
+	stop              chan int
+	stopped           chan struct{}
+	runsWaitGroup     sync.WaitGroup
+	activeWorkers     map[*worker.Worker]struct{}
+	activeWorkersLock sync.Mutex
 }
 
 func newTrigger(logger logger.Logger,
@@ -62,7 +69,8 @@ func newTrigger(logger logger.Logger,
 	newTrigger := cron{
 		AbstractTrigger: abstractTrigger,
 		configuration:   configuration,
-		stop:            make(chan int),
+		clock:           realClock{},
+		activeWorkers:   map[*worker.Worker]struct{}{},
 	}
 
 	newTrigger.AbstractTrigger.Trigger = &newTrigger
@@ -84,12 +92,28 @@ func newTrigger(logger logger.Logger,
 }
 
 func (c *cron) Start(checkpoint functionconfig.Checkpoint) error {
-	go c.handleEvents()
+
+	// the channels are created per start, as a stopped trigger may be started again (e.g. when restarted)
+	c.stop = make(chan int)
+	c.stopped = make(chan struct{})
+
+	go c.handleEvents(c.stop, c.stopped)
 	return nil
 }
 
 func (c *cron) Stop(force bool) (functionconfig.Checkpoint, error) {
+	if c.stop == nil {
+		return nil, nil
+	}
+
 	close(c.stop)
+	c.stop = nil
+
+	// wait for the runs in progress to end, unless forced
+	if !force {
+		<-c.stopped
+		c.runsWaitGroup.Wait()
+	}
 
 	return nil, nil
 }
@@ -98,72 +122,72 @@ func (c *cron) GetConfig() map[string]interface{} {
 	return common.StructureToMap(c.configuration)
 }
 
-func (c *cron) handleEvents() {
-	lastRunTime := time.Now()
-	stop := false
+func (c *cron) handleEvents(stop chan int, stopped chan struct{}) {
+	defer close(stopped)
+
+	// runs are scheduled relative to when they were due rather than when they actually happened,
+	// so that a late run (e.g. due to jitter) doesn't shift the ones after it
+	lastEventSubmitTime := c.clock.Now()
 
 	for {
+		nextEventSubmitTime, nextEventSubmitDelay, events := c.getNextEventSubmit(lastEventSubmitTime)
+
 		select {
-		case <-c.stop:
+		case <-stop:
 			c.Logger.Info("Cron trigger stop signal received")
-			stop = true
-		default:
-			c.waitAndSubmitNextEvent(lastRunTime, c.schedule, c.handleTick) // nolint: errcheck
-
-			lastRunTime = time.Now()
+			return
+		case <-c.clock.After(nextEventSubmitDelay):
 		}
 
-		if stop {
-			break
+		for eventIdx := 0; eventIdx < events; eventIdx++ {
+			c.submitEvent()
 		}
+
+		lastEventSubmitTime = nextEventSubmitTime
 	}
 }
 
-func (c *cron) waitAndSubmitNextEvent(lastEventSubmitTime time.Time, schedule cronlib.Schedule, eventSubmitter func()) error {
-	nextEventSubmitDelay := c.getNextEventSubmitDelay(schedule, lastEventSubmitTime)
-	time.Sleep(nextEventSubmitDelay)
-
-	eventSubmitter()
-
-	return nil
-}
+// getNextEventSubmit returns when the next submit is due, how long to wait for it and how many events to submit
+// then. a due time in the past is returned for missed runs, so that the runs after them are calculated from it
+func (c *cron) getNextEventSubmit(lastEventSubmitTime time.Time) (time.Time, time.Duration, int) {
+	now := c.clock.Now()
 
-func (c *cron) getNextEventSubmitDelay(schedule cronlib.Schedule, lastEventSubmitTime time.Time) time.Duration {
-	var delay time.Duration
+	// check how many runs we missed
+	missedRuns, lastMissedRunTime := c.getMissedRuns(lastEventSubmitTime, now)
+	if missedRuns == 0 {
+		nextEventSubmitTime := c.calculateNextEventSubmittingTime(lastEventSubmitTime)
 
-	// get when the next submit _should_ happen (might be in the past if we missed it)
-	nextEventSubmitTime := c.calculateNextEventSubmittingTime(lastEventSubmitTime)
+		return nextEventSubmitTime, nextEventSubmitTime.Sub(now) + c.getJitter(), 1
+	}
 
-	// check how many events we missed
-	missedTicks := c.getMissedTicks(schedule, nextEventSubmitTime)
+	c.Logger.InfoWith("Missed runs",
+		"missedRuns", missedRuns,
+		"missedRunsPolicy", c.configuration.MissedRunsPolicy)
 
-	// if we missed some runs, return zero delay (aka, execute now)
-	if missedTicks > 0 {
-		c.Logger.InfoWith("Missed runs",
-			"missedRuns", missedTicks)
-		delay = 0
-	} else {
-		delay = time.Until(nextEventSubmitTime)
+	switch c.configuration.MissedRunsPolicy {
+	case MissedRunsPolicyFireAll:
+		return lastMissedRunTime, 0, missedRuns
+	case MissedRunsPolicySkip:
+		return c.getNextEventSubmit(lastMissedRunTime)
+	default:
+		return lastMissedRunTime, 0, 1
 	}
-
-	return delay
 }
 
-func (c *cron) getMissedTicks(schedule cronlib.Schedule, eventSubmitTime time.Time) int {
-	var missedTicks int
+// getMissedRuns returns the number of runs that were due after the last submit (up to and including now),
+// and when the last of them was due
+func (c *cron) getMissedRuns(lastEventSubmitTime time.Time, now time.Time) (int, time.Time) {
+	var missedRuns int
 
-	for eventSubmitTime.Before(time.Now()) {
-		eventSubmitTime = c.calculateNextEventSubmittingTime(eventSubmitTime)
-		missedTicks++
-	}
+	for {
+		nextEventSubmitTime := c.calculateNextEventSubmittingTime(lastEventSubmitTime)
+		if nextEventSubmitTime.IsZero() || nextEventSubmitTime.After(now) {
+			return missedRuns, lastEventSubmitTime
+		}
 
-	// last "missed" tick shouldn't count, as it wouldn't have happened yet (as we passed time.Now())
-	// can't have missed less than 0 ticks
-	if missedTicks > 0 {
-		missedTicks--
+		lastEventSubmitTime = nextEventSubmitTime
+		missedRuns++
 	}
-
-	return missedTicks
 }
 
 func (c *cron) calculateNextEventSubmittingTime(lastEventSubmitTime time.Time) time.Time {
@@ -174,15 +198,108 @@ func (c *cron) calculateNextEventSubmittingTime(lastEventSubmitTime time.Time) t
 		delay := c.schedule.(cronlib.ConstantDelaySchedule).Delay
 		return lastEventSubmitTime.Add(delay)
 	default:
-		return time.Now()
+		return c.clock.Now()
+	}
+}
+
+// getJitter returns a random delay to add to a run, spreading runs of the same schedule across replicas
+func (c *cron) getJitter() time.Duration {
+	if c.configuration.jitter == 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(c.configuration.jitter)))
+}
+
+// submitEvent submits an event according to the concurrency policy
+func (c *cron) submitEvent() {
+	switch c.configuration.ConcurrencyPolicy {
+	case ConcurrencyPolicyAllow:
+		c.submitEventAsync()
+	case ConcurrencyPolicyReplace:
+		c.restartActiveWorkers()
+		c.submitEventAsync()
+	default:
+
+		// block until the run ends, so that runs due in the meantime are handled as missed
+		c.processEvent()
+	}
+}
+
+func (c *cron) submitEventAsync() {
+	c.runsWaitGroup.Add(1)
+
+	go func() {
+		defer c.runsWaitGroup.Done()
+
+		c.processEvent()
+	}()
+}
+
+func (c *cron) processEvent() {
+	var submitError error
+
+	defer c.HandleSubmitPanic(nil, &submitError)
+
+	workerInstance, err := c.AllocateWorker(
+		time.Duration(*c.configuration.WorkerAvailabilityTimeoutMilliseconds) * time.Millisecond)
+	if err != nil {
+		c.UpdateStatistics(false)
+		c.Logger.WarnWith("Failed to allocate worker", "err", err.Error())
+		return
+	}
+
+	c.setWorkerActive(workerInstance, true)
+
+	defer func() {
+		c.setWorkerActive(workerInstance, false)
+		c.WorkerAllocator.Release(workerInstance)
+	}()
+
+	// submit a copy of the event, as submitting sets its ID and runs may be concurrent
+	event := c.configuration.Event
+
+	if _, err := c.SubmitEventToWorker(nil, workerInstance, &event); err != nil {
+		c.Logger.DebugWith("Failed to process event", "err", err.Error())
+	}
+}
+
+func (c *cron) setWorkerActive(workerInstance *worker.Worker, active bool) {
+	c.activeWorkersLock.Lock()
+	defer c.activeWorkersLock.Unlock()
+
+	if active {
+		c.activeWorkers[workerInstance] = struct{}{}
+	} else {
+		delete(c.activeWorkers, workerInstance)
 	}
 }
 
-func (c *cron) handleTick() {
-	c.AllocateWorkerAndSubmitEvent( // nolint: errcheck
-		&c.configuration.Event,
-		c.Logger,
-		10*time.Second)
+// restartActiveWorkers restarts the workers handling previous runs, so that their runs are replaced
+func (c *cron) restartActiveWorkers() {
+	var activeWorkers []*worker.Worker
+
+	c.activeWorkersLock.Lock()
+	for workerInstance := range c.activeWorkers {
+		activeWorkers = append(activeWorkers, workerInstance)
+	}
+	c.activeWorkersLock.Unlock()
+
+	for _, workerInstance := range activeWorkers {
+		if !workerInstance.SupportsRestart() {
+			c.Logger.WarnWith("Runtime does not support restart, previous run will not be replaced",
+				"workerIndex", workerInstance.GetIndex())
+			continue
+		}
+
+		c.Logger.InfoWith("Restarting worker to replace previous run", "workerIndex", workerInstance.GetIndex())
+
+		if err := workerInstance.Restart(); err != nil {
+			c.Logger.WarnWith("Failed to restart worker",
+				"workerIndex", workerInstance.GetIndex(),
+				"err", err.Error())
+		}
+	}
 }
 
 func (c *cron) setInterval(encodedInterval string) error {
@@ -217,7 +334,22 @@ func (c *cron) setSchedule(encodedSchedule string) error {
 		return errors.Wrapf(err, "Failed to parse schedule from cron trigger configuration: %+v", encodedSchedule)
 	}
 
-	c.Logger.InfoWith("Set cron trigger schedule", "schedule", c.schedule)
+	// evaluate the schedule in the configured timezone rather than the container's local time
+	if specSchedule, isSpecSchedule := c.schedule.(*cronlib.SpecSchedule); isSpecSchedule &&
+		c.configuration.Timezone != "" {
+		specSchedule.Location, err = time.LoadLocation(c.configuration.Timezone)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to load timezone %s", c.configuration.Timezone)
+		}
+	}
+
+	if c.schedule.Next(c.clock.Now()).IsZero() {
+		return errors.Errorf("Schedule %s never fires", encodedSchedule)
+	}
+
+	c.Logger.InfoWith("Set cron trigger schedule",
+		"schedule", c.schedule,
+		"timezone", c.configuration.Timezone)
 	return nil
 }
 
@@ -17,6 +17,12 @@ limitations under the License.
 package cron
 
 import (
+	"strings"
+	"time"
+
+	// embed the timezone database, as processor images don't necessarily have one
+	_ "time/tzdata"
+
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
@@ -25,11 +31,48 @@ import (
 	"github.com/nuclio/errors"
 )
 
+// ConcurrencyPolicy determines what happens when a run is due while a previous one is still running
+type ConcurrencyPolicy string
+
+const (
+
+	// ConcurrencyPolicyAllow starts the new run alongside the previous one
+	ConcurrencyPolicyAllow ConcurrencyPolicy = "Allow"
+
+	// ConcurrencyPolicyForbid waits for the previous run to end, treating the runs that were due
+	// in the meantime as missed
+	ConcurrencyPolicyForbid ConcurrencyPolicy = "Forbid"
+
+	// ConcurrencyPolicyReplace restarts the worker handling the previous run and starts the new one
+	ConcurrencyPolicyReplace ConcurrencyPolicy = "Replace"
+)
+
+// MissedRunsPolicy determines what happens to runs that were due while the trigger could not submit them
+type MissedRunsPolicy string
+
+const (
+
+	// MissedRunsPolicyFireOnce submits a single event for all of the missed runs
+	MissedRunsPolicyFireOnce MissedRunsPolicy = "fire-once"
+
+	// MissedRunsPolicyFireAll submits an event for each of the missed runs
+	MissedRunsPolicyFireAll MissedRunsPolicy = "fire-all"
+
+	// MissedRunsPolicySkip drops the missed runs and waits for the next one
+	MissedRunsPolicySkip MissedRunsPolicy = "skip"
+)
+
 type Configuration struct {
 	trigger.Configuration
-	Schedule string
-	Interval string
-	Event    Event
+	Schedule          string
+	Interval          string
+	Timezone          string
+	Jitter            string
+	ConcurrencyPolicy ConcurrencyPolicy
+	MissedRunsPolicy  MissedRunsPolicy
+	Event             Event
+
+	jitter time.Duration
 }
 
 func NewConfiguration(id string,
@@ -49,5 +92,46 @@ func NewConfiguration(id string,
 		return nil, errors.Wrap(err, "Failed to decode attributes")
 	}
 
+	if err := newConfiguration.ParseDurationOrDefault(&trigger.DurationConfigField{
+		Name:    "jitter",
+		Value:   newConfiguration.Jitter,
+		Field:   &newConfiguration.jitter,
+		Default: 0,
+	}); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse jitter")
+	}
+
+	if newConfiguration.jitter < 0 {
+		return nil, errors.New("Jitter must not be negative")
+	}
+
+	if newConfiguration.Timezone != "" {
+		if _, err := time.LoadLocation(newConfiguration.Timezone); err != nil {
+			return nil, errors.Wrapf(err, "Failed to load timezone %s", newConfiguration.Timezone)
+		}
+	}
+
+	// accept the policy in any case, same as when running as a kubernetes cron job
+	switch strings.ToLower(string(newConfiguration.ConcurrencyPolicy)) {
+	case "":
+		newConfiguration.ConcurrencyPolicy = ConcurrencyPolicyForbid
+	case "allow":
+		newConfiguration.ConcurrencyPolicy = ConcurrencyPolicyAllow
+	case "forbid":
+		newConfiguration.ConcurrencyPolicy = ConcurrencyPolicyForbid
+	case "replace":
+		newConfiguration.ConcurrencyPolicy = ConcurrencyPolicyReplace
+	default:
+		return nil, errors.Errorf("Unsupported concurrency policy: %s", newConfiguration.ConcurrencyPolicy)
+	}
+
+	switch newConfiguration.MissedRunsPolicy {
+	case "":
+		newConfiguration.MissedRunsPolicy = MissedRunsPolicyFireOnce
+	case MissedRunsPolicyFireOnce, MissedRunsPolicyFireAll, MissedRunsPolicySkip:
+	default:
+		return nil, errors.Errorf("Unsupported missed runs policy: %s", newConfiguration.MissedRunsPolicy)
+	}
+
 	return &newConfiguration, nil
 }
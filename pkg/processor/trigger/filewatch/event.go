@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filewatch
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// Event holds a change to a file
+type Event struct {
+	nuclio.AbstractEvent
+	path         string
+	relativePath string
+	operation    Operation
+	size         int64
+	modTime      time.Time
+	body         []byte
+}
+
+// GetBody returns the file contents, if configured to include them
+func (e *Event) GetBody() []byte {
+	return e.body
+}
+
+// GetPath returns the path of the file
+func (e *Event) GetPath() string {
+	return e.path
+}
+
+// GetMethod returns the operation - created, modified or deleted
+func (e *Event) GetMethod() string {
+	return string(e.operation)
+}
+
+// GetTimestamp returns the modification time of the file
+func (e *Event) GetTimestamp() time.Time {
+	return e.modTime
+}
+
+func (e *Event) GetHeaders() map[string]interface{} {
+	return map[string]interface{}{
+		"Operation":    string(e.operation),
+		"Size":         e.size,
+		"RelativePath": e.relativePath,
+	}
+}
+
+func (e *Event) GetHeader(key string) interface{} {
+	return e.GetHeaders()[key]
+}
+
+func (e *Event) GetHeaderString(key string) string {
+	switch typedValue := e.GetHeader(key).(type) {
+	case string:
+		return typedValue
+	case int64:
+		return strconv.FormatInt(typedValue, 10)
+	default:
+		return ""
+	}
+}
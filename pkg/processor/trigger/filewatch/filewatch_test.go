@@ -0,0 +1,304 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filewatch
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// mockRuntime records the events it processes, failing those of files named "bad*" and responding with an
+// error status code to those of files named "error*"
+type mockRuntime struct {
+	runtime.Runtime
+	processedEvents []string
+	lock            sync.Mutex
+}
+
+func (mr *mockRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	mr.processedEvents = append(mr.processedEvents, strings.Join([]string{
+		event.GetMethod(),
+		event.GetHeaderString("RelativePath"),
+		string(event.GetBody()),
+	}, ":"))
+
+	if strings.HasPrefix(filepath.Base(event.GetPath()), "bad") {
+		return nil, errors.New("Failed to process event")
+	}
+
+	if strings.HasPrefix(filepath.Base(event.GetPath()), "error") {
+		return nuclio.Response{StatusCode: http.StatusInternalServerError}, nil
+	}
+
+	return nil, nil
+}
+
+func (mr *mockRuntime) getProcessedEvents() []string {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	return append([]string{}, mr.processedEvents...)
+}
+
+type TestSuite struct {
+	suite.Suite
+	logger  logger.Logger
+	runtime *mockRuntime
+	path    string
+	trigger *filewatch
+}
+
+func (suite *TestSuite) SetupSuite() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *TestSuite) SetupTest() {
+	suite.runtime = &mockRuntime{}
+	suite.path = suite.T().TempDir()
+	suite.trigger = nil
+}
+
+func (suite *TestSuite) TearDownTest() {
+	if suite.trigger != nil {
+		suite.trigger.Stop(false) // nolint: errcheck
+	}
+}
+
+func (suite *TestSuite) TestNotify() {
+	suite.testCreateModifyDelete(WatchModeNotify)
+}
+
+func (suite *TestSuite) TestPoll() {
+	suite.testCreateModifyDelete(WatchModePoll)
+}
+
+func (suite *TestSuite) TestDoneAndFailedDirectories() {
+	suite.startTrigger(map[string]interface{}{
+		"mode":            "notify",
+		"patterns":        []string{"*.csv"},
+		"doneDirectory":   filepath.Join(suite.path, "done"),
+		"failedDirectory": filepath.Join(suite.path, "failed"),
+	})
+
+	suite.writeFile("good.csv", "1")
+	suite.writeFile("bad.csv", "2")
+	suite.writeFile("error.csv", "3")
+	suite.writeFile("ignored.txt", "4")
+
+	suite.waitForProcessedEvents([]string{
+		"created:bad.csv:",
+		"created:error.csv:",
+		"created:good.csv:",
+	})
+
+	// files are moved once handled, without emitting deleted events
+	suite.Require().FileExists(filepath.Join(suite.path, "done", "good.csv"))
+	suite.Require().FileExists(filepath.Join(suite.path, "failed", "bad.csv"))
+	suite.Require().FileExists(filepath.Join(suite.path, "failed", "error.csv"))
+	suite.Require().NoFileExists(filepath.Join(suite.path, "good.csv"))
+	suite.Require().FileExists(filepath.Join(suite.path, "ignored.txt"))
+}
+
+func (suite *TestSuite) TestRecursiveWithExistingFiles() {
+	suite.writeFile("existing", "1")
+
+	suite.startTrigger(map[string]interface{}{
+		"mode":            "notify",
+		"recursive":       true,
+		"processExisting": true,
+		"operations":      []string{"created"},
+	})
+
+	suite.waitForProcessedEvents([]string{"created:existing:"})
+
+	// files created in new directories are picked up
+	err := os.MkdirAll(filepath.Join(suite.path, "a", "b"), 0755)
+	suite.Require().NoError(err)
+	suite.writeFile(filepath.Join("a", "b", "nested"), "2")
+
+	suite.waitForProcessedEvents([]string{
+		"created:a/b/nested:",
+		"created:existing:",
+	})
+}
+
+func (suite *TestSuite) TestNewConfiguration() {
+	for _, testCase := range []struct {
+		name        string
+		attributes  map[string]interface{}
+		expectError bool
+	}{
+		{
+			name: "valid",
+			attributes: map[string]interface{}{
+				"path":     "/data",
+				"patterns": []string{"*.csv", "reports/*.json"},
+			},
+		},
+		{
+			name:        "missingPath",
+			attributes:  map[string]interface{}{},
+			expectError: true,
+		},
+		{
+			name: "invalidPattern",
+			attributes: map[string]interface{}{
+				"path":     "/data",
+				"patterns": []string{"[a-"},
+			},
+			expectError: true,
+		},
+		{
+			name: "invalidOperation",
+			attributes: map[string]interface{}{
+				"path":       "/data",
+				"operations": []string{"renamed"},
+			},
+			expectError: true,
+		},
+		{
+			name: "doneDirectoryIsPath",
+			attributes: map[string]interface{}{
+				"path":          "/data",
+				"doneDirectory": "/data/",
+			},
+			expectError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			configuration, err := NewConfiguration("test", &functionconfig.Trigger{
+				Kind:       "filewatch",
+				Attributes: testCase.attributes,
+			}, suite.createRuntimeConfiguration())
+
+			if testCase.expectError {
+				suite.Require().Error(err)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().Equal(WatchModeAuto, configuration.Mode)
+			suite.Require().Equal(500*time.Millisecond, configuration.debounce)
+			suite.Require().True(configuration.isFileMatched("a.csv"))
+			suite.Require().True(configuration.isFileMatched("sub/a.csv"))
+			suite.Require().True(configuration.isFileMatched("reports/a.json"))
+			suite.Require().False(configuration.isFileMatched("a.json"))
+		})
+	}
+}
+
+func (suite *TestSuite) testCreateModifyDelete(mode WatchMode) {
+	suite.startTrigger(map[string]interface{}{
+		"mode":        string(mode),
+		"includeBody": true,
+	})
+
+	suite.writeFile("file", "1")
+	suite.waitForProcessedEvents([]string{"created:file:1"})
+
+	// make sure the modification time changes
+	modTime := time.Now().Add(time.Minute)
+	suite.writeFile("file", "22")
+	err := os.Chtimes(filepath.Join(suite.path, "file"), modTime, modTime)
+	suite.Require().NoError(err)
+
+	suite.waitForProcessedEvents([]string{"created:file:1", "modified:file:22"})
+
+	err = os.Remove(filepath.Join(suite.path, "file"))
+	suite.Require().NoError(err)
+
+	suite.waitForProcessedEvents([]string{"created:file:1", "modified:file:22", "deleted:file:"})
+}
+
+func (suite *TestSuite) startTrigger(attributes map[string]interface{}) {
+	attributes["path"] = suite.path
+	attributes["debounce"] = "50ms"
+	attributes["pollInterval"] = "50ms"
+
+	configuration, err := NewConfiguration("test", &functionconfig.Trigger{
+		Kind:       "filewatch",
+		Attributes: attributes,
+	}, suite.createRuntimeConfiguration())
+	suite.Require().NoError(err)
+
+	workerInstance, err := worker.NewWorker(suite.logger, 0, suite.runtime)
+	suite.Require().NoError(err)
+
+	workerAllocator, err := worker.NewFixedPoolWorkerAllocator(suite.logger, []*worker.Worker{workerInstance})
+	suite.Require().NoError(err)
+
+	triggerInstance, err := newTrigger(suite.logger, workerAllocator, configuration, nil)
+	suite.Require().NoError(err)
+
+	suite.trigger = triggerInstance.(*filewatch)
+
+	err = suite.trigger.Start(nil)
+	suite.Require().NoError(err)
+}
+
+func (suite *TestSuite) writeFile(relativePath string, contents string) {
+	err := os.WriteFile(filepath.Join(suite.path, relativePath), []byte(contents), 0644)
+	suite.Require().NoError(err)
+}
+
+// waitForProcessedEvents waits for the events, in any order
+func (suite *TestSuite) waitForProcessedEvents(expectedEvents []string) {
+	suite.Require().Eventually(func() bool {
+		return len(suite.runtime.getProcessedEvents()) >= len(expectedEvents)
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// give unexpected events a chance to show up
+	time.Sleep(200 * time.Millisecond)
+
+	suite.Require().ElementsMatch(expectedEvents, suite.runtime.getProcessedEvents())
+}
+
+func (suite *TestSuite) createRuntimeConfiguration() *runtime.Configuration {
+	return &runtime.Configuration{
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{},
+		},
+	}
+}
+
+func TestFileWatchSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
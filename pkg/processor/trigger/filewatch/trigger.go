@@ -0,0 +1,416 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filewatch
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+type filewatch struct {
+	trigger.AbstractTrigger
+	configuration *Configuration
+	watcher       watcher
+
+	// the files that events were emitted for, and their state at that time
+	knownFilesLock sync.Mutex
+	knownFiles     map[string]fileState
+
+	debounceTimersLock sync.Mutex
+	debounceTimers     map[string]*time.Timer
+
+	changedPathsChan  chan string
+	stopChan          chan struct{}
+	handlersWaitGroup sync.WaitGroup
+}
+
+func newTrigger(logger logger.Logger,
+	workerAllocator worker.Allocator,
+	configuration *Configuration,
+	restartTriggerChan chan trigger.Trigger) (trigger.Trigger, error) {
+
+	abstractTrigger, err := trigger.NewAbstractTrigger(logger,
+		workerAllocator,
+		&configuration.Configuration,
+		"async",
+		"filewatch",
+		configuration.Name,
+		restartTriggerChan)
+	if err != nil {
+		return nil, errors.New("Failed to create abstract trigger")
+	}
+
+	newTrigger := filewatch{
+		AbstractTrigger: abstractTrigger,
+		configuration:   configuration,
+	}
+	newTrigger.AbstractTrigger.Trigger = &newTrigger
+
+	return &newTrigger, nil
+}
+
+func (f *filewatch) Start(checkpoint functionconfig.Checkpoint) error {
+	f.Logger.InfoWith("Starting",
+		"path", f.configuration.Path,
+		"mode", f.configuration.Mode,
+		"recursive", f.configuration.Recursive)
+
+	info, err := os.Stat(f.configuration.Path)
+	if err != nil {
+		return errors.Wrap(err, "Failed to stat path")
+	}
+
+	if !info.IsDir() {
+		return errors.Errorf("Path %s is not a directory", f.configuration.Path)
+	}
+
+	for _, directory := range []string{
+		f.configuration.DoneDirectory,
+		f.configuration.FailedDirectory,
+	} {
+		if directory == "" {
+			continue
+		}
+
+		if err := os.MkdirAll(directory, 0755); err != nil {
+			return errors.Wrapf(err, "Failed to create directory %s", directory)
+		}
+	}
+
+	// watch before looking for existing files, so that no change is missed
+	f.watcher, err = f.createWatcher()
+	if err != nil {
+		return errors.Wrap(err, "Failed to create watcher")
+	}
+
+	f.knownFiles = map[string]fileState{}
+	f.debounceTimers = map[string]*time.Timer{}
+
+	// existing files are either known, or handled as if they were just created
+	var existingPaths []string
+	if err := walkFiles(f.configuration, func(path string, info fs.FileInfo) {
+		if !f.configuration.isFileMatched(f.getRelativePath(path)) {
+			return
+		}
+
+		if f.configuration.ProcessExisting {
+			existingPaths = append(existingPaths, path)
+		} else {
+			f.knownFiles[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+		}
+	}); err != nil {
+		f.watcher.stop()
+		return errors.Wrap(err, "Failed to walk path")
+	}
+
+	f.changedPathsChan = make(chan string)
+	f.stopChan = make(chan struct{})
+
+	go f.debounceChangedPaths()
+
+	if err := f.watcher.start(f.changedPathsChan); err != nil {
+		return errors.Wrap(err, "Failed to start watcher")
+	}
+
+	for _, existingPath := range existingPaths {
+		f.debounce(existingPath)
+	}
+
+	return nil
+}
+
+func (f *filewatch) Stop(force bool) (functionconfig.Checkpoint, error) {
+	if f.stopChan == nil {
+		return nil, nil
+	}
+
+	f.watcher.stop()
+	close(f.stopChan)
+	f.stopChan = nil
+
+	// events that weren't emitted yet are dropped
+	f.debounceTimersLock.Lock()
+	for path, debounceTimer := range f.debounceTimers {
+		if debounceTimer.Stop() {
+			f.handlersWaitGroup.Done()
+		}
+		delete(f.debounceTimers, path)
+	}
+	f.debounceTimersLock.Unlock()
+
+	if !force {
+		f.handlersWaitGroup.Wait()
+	}
+
+	return nil, nil
+}
+
+func (f *filewatch) GetConfig() map[string]interface{} {
+	return common.StructureToMap(f.configuration)
+}
+
+func (f *filewatch) createWatcher() (watcher, error) {
+	switch f.configuration.Mode {
+	case WatchModePoll:
+		return newPollWatcher(f.Logger, f.configuration)
+
+	case WatchModeNotify:
+		return newNotifyWatcher(f.Logger, f.configuration)
+
+	default:
+		notifyWatcher, err := newNotifyWatcher(f.Logger, f.configuration)
+		if err == nil {
+			return notifyWatcher, nil
+		}
+
+		f.Logger.WarnWith("Failed to create notify watcher, falling back to polling",
+			"err", errors.Cause(err).Error())
+
+		return newPollWatcher(f.Logger, f.configuration)
+	}
+}
+
+func (f *filewatch) debounceChangedPaths() {
+	stopChan := f.stopChan
+
+	for {
+		select {
+		case changedPath := <-f.changedPathsChan:
+			f.debounce(changedPath)
+		case <-stopChan:
+			return
+		}
+	}
+}
+
+// debounce handles the path once it goes unchanged for the debounce duration
+func (f *filewatch) debounce(path string) {
+	f.debounceTimersLock.Lock()
+	defer f.debounceTimersLock.Unlock()
+
+	// if the timer didn't fire yet, push it back. otherwise the path is being handled, and will be handled again
+	if debounceTimer, found := f.debounceTimers[path]; found && debounceTimer.Stop() {
+		debounceTimer.Reset(f.configuration.debounce)
+		return
+	}
+
+	var debounceTimer *time.Timer
+
+	f.handlersWaitGroup.Add(1)
+	debounceTimer = time.AfterFunc(f.configuration.debounce, func() {
+		defer f.handlersWaitGroup.Done()
+
+		f.debounceTimersLock.Lock()
+		if f.debounceTimers[path] == debounceTimer {
+			delete(f.debounceTimers, path)
+		}
+		f.debounceTimersLock.Unlock()
+
+		f.handlePath(path)
+	})
+
+	f.debounceTimers[path] = debounceTimer
+}
+
+// handlePath resolves what happened to the path since events were last emitted for it, and emits them
+func (f *filewatch) handlePath(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			f.Logger.WarnWith("Failed to stat path", "path", path, "err", err.Error())
+			return
+		}
+
+		// the path may have been a file or a directory holding files
+		for _, deletedPath := range f.forgetFiles(path) {
+			f.handleFile(&Event{
+				path:         deletedPath,
+				relativePath: f.getRelativePath(deletedPath),
+				operation:    OperationDeleted,
+				modTime:      time.Now(),
+			})
+		}
+
+		return
+	}
+
+	if !info.Mode().IsRegular() {
+		return
+	}
+
+	relativePath := f.getRelativePath(path)
+	if !f.configuration.isFileMatched(relativePath) {
+		return
+	}
+
+	currentState := fileState{size: info.Size(), modTime: info.ModTime()}
+
+	f.knownFilesLock.Lock()
+	previousState, known := f.knownFiles[path]
+	f.knownFiles[path] = currentState
+	f.knownFilesLock.Unlock()
+
+	event := Event{
+		path:         path,
+		relativePath: relativePath,
+		operation:    OperationCreated,
+		size:         currentState.size,
+		modTime:      currentState.modTime,
+	}
+
+	if known {
+		if previousState.equals(currentState) {
+			return
+		}
+
+		event.operation = OperationModified
+	}
+
+	f.handleFile(&event)
+}
+
+func (f *filewatch) handleFile(event *Event) {
+	if !f.configuration.isOperationEnabled(event.operation) {
+		return
+	}
+
+	if event.operation != OperationDeleted && f.configuration.IncludeBody {
+		if event.size > f.configuration.MaxBodySize {
+			f.Logger.WarnWith("File is larger than the max body size, not including body",
+				"path", event.path,
+				"size", event.size,
+				"maxBodySize", f.configuration.MaxBodySize)
+		} else {
+			body, err := os.ReadFile(event.path)
+			if err != nil {
+				f.Logger.WarnWith("Failed to read file", "path", event.path, "err", err.Error())
+				return
+			}
+
+			event.body = body
+		}
+	}
+
+	f.Logger.DebugWith("Emitting event", "path", event.path, "operation", event.operation)
+
+	response, submitError, processError := f.AllocateWorkerAndSubmitEvent(event,
+		f.Logger,
+		time.Duration(*f.configuration.WorkerAvailabilityTimeoutMilliseconds)*time.Millisecond)
+
+	if event.operation == OperationDeleted {
+		return
+	}
+
+	// move the file out of the way, if configured to
+	destinationDirectory := f.configuration.DoneDirectory
+	if submitError != nil || trigger.EventFailed(response, processError) {
+		destinationDirectory = f.configuration.FailedDirectory
+	}
+
+	if destinationDirectory == "" {
+		return
+	}
+
+	if err := f.moveFile(event.path, filepath.Join(destinationDirectory, event.relativePath)); err != nil {
+		f.Logger.WarnWith("Failed to move file",
+			"path", event.path,
+			"destinationDirectory", destinationDirectory,
+			"err", errors.GetErrorStackString(err, 10))
+		return
+	}
+
+	// the file is gone, don't emit a deleted event for it
+	f.forgetFiles(event.path)
+}
+
+// forgetFiles removes the file, or the files under the directory, from the known files and returns them
+func (f *filewatch) forgetFiles(path string) []string {
+	f.knownFilesLock.Lock()
+	defer f.knownFilesLock.Unlock()
+
+	var forgottenPaths []string
+
+	directoryPrefix := path + string(filepath.Separator)
+	for knownPath := range f.knownFiles {
+		if knownPath == path || strings.HasPrefix(knownPath, directoryPrefix) {
+			forgottenPaths = append(forgottenPaths, knownPath)
+			delete(f.knownFiles, knownPath)
+		}
+	}
+
+	return forgottenPaths
+}
+
+func (f *filewatch) getRelativePath(path string) string {
+	relativePath, err := filepath.Rel(f.configuration.Path, path)
+	if err != nil {
+		return filepath.Base(path)
+	}
+
+	return relativePath
+}
+
+// moveFile moves the file, copying it if it can't be renamed (e.g. when the destination is on another volume)
+func (f *filewatch) moveFile(sourcePath string, destinationPath string) error {
+	if err := os.MkdirAll(filepath.Dir(destinationPath), 0755); err != nil {
+		return errors.Wrap(err, "Failed to create destination directory")
+	}
+
+	if err := os.Rename(sourcePath, destinationPath); err == nil {
+		return nil
+	}
+
+	sourceFile, err := os.Open(sourcePath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to open file")
+	}
+
+	defer sourceFile.Close() // nolint: errcheck
+
+	destinationFile, err := os.Create(destinationPath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create destination file")
+	}
+
+	if _, err := io.Copy(destinationFile, sourceFile); err != nil {
+		destinationFile.Close() // nolint: errcheck
+		return errors.Wrap(err, "Failed to copy file")
+	}
+
+	if err := destinationFile.Close(); err != nil {
+		return errors.Wrap(err, "Failed to close destination file")
+	}
+
+	if err := os.Remove(sourcePath); err != nil {
+		return errors.Wrap(err, "Failed to remove file")
+	}
+
+	return nil
+}
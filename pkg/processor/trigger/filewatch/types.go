@@ -0,0 +1,229 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filewatch
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+type WatchMode string
+
+const (
+	WatchModeAuto   WatchMode = "auto"
+	WatchModeNotify WatchMode = "notify"
+	WatchModePoll   WatchMode = "poll"
+)
+
+type Operation string
+
+const (
+	OperationCreated  Operation = "created"
+	OperationModified Operation = "modified"
+	OperationDeleted  Operation = "deleted"
+)
+
+type Configuration struct {
+	trigger.Configuration
+
+	// the directory to watch
+	Path      string
+	Recursive bool
+
+	// glob patterns a file must match (any) and must not match (all). patterns without a path
+	// separator are matched against the file name, others against the path relative to Path
+	Patterns       []string
+	IgnorePatterns []string
+
+	// the operations to emit events for (defaults to all)
+	Operations []Operation
+
+	// notify uses inotify (or the platform's equivalent), poll scans the directory periodically
+	// and auto falls back to poll when notify isn't available
+	Mode         WatchMode
+	PollInterval string
+
+	// how long a file must go unchanged before an event is emitted for it
+	Debounce string
+
+	// emit "created" events for the files found when the trigger starts
+	ProcessExisting bool
+
+	IncludeBody bool
+	MaxBodySize int64
+
+	// where to move files once handled. may be under Path, in which case they're not watched
+	DoneDirectory   string
+	FailedDirectory string
+
+	// resolved fields
+	pollInterval time.Duration
+	debounce     time.Duration
+}
+
+func NewConfiguration(id string,
+	triggerConfiguration *functionconfig.Trigger,
+	runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
+	newConfiguration := Configuration{}
+
+	// create base
+	baseConfiguration, err := trigger.NewConfiguration(id, triggerConfiguration, runtimeConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create trigger configuration")
+	}
+	newConfiguration.Configuration = *baseConfiguration
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Attributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	if newConfiguration.Path == "" {
+		return nil, errors.New("Path must be provided")
+	}
+
+	// resolve directories so that paths can be compared
+	for _, directory := range []*string{
+		&newConfiguration.Path,
+		&newConfiguration.DoneDirectory,
+		&newConfiguration.FailedDirectory,
+	} {
+		if *directory == "" {
+			continue
+		}
+
+		if *directory, err = filepath.Abs(*directory); err != nil {
+			return nil, errors.Wrapf(err, "Failed to resolve directory %s", *directory)
+		}
+	}
+
+	if newConfiguration.DoneDirectory == newConfiguration.Path || newConfiguration.FailedDirectory == newConfiguration.Path {
+		return nil, errors.New("Done and failed directories must differ from the watched path")
+	}
+
+	for _, pattern := range append(newConfiguration.Patterns, newConfiguration.IgnorePatterns...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "Invalid pattern %s", pattern)
+		}
+	}
+
+	if len(newConfiguration.Operations) == 0 {
+		newConfiguration.Operations = []Operation{OperationCreated, OperationModified, OperationDeleted}
+	}
+
+	for _, operation := range newConfiguration.Operations {
+		switch operation {
+		case OperationCreated, OperationModified, OperationDeleted:
+		default:
+			return nil, errors.Errorf("Unsupported operation: %s", operation)
+		}
+	}
+
+	switch newConfiguration.Mode {
+	case "":
+		newConfiguration.Mode = WatchModeAuto
+	case WatchModeAuto, WatchModeNotify, WatchModePoll:
+	default:
+		return nil, errors.Errorf("Unsupported mode: %s", newConfiguration.Mode)
+	}
+
+	if newConfiguration.MaxBodySize == 0 {
+		newConfiguration.MaxBodySize = 10 * 1024 * 1024
+	}
+
+	for _, durationConfigField := range []trigger.DurationConfigField{
+		{
+			Name:    "poll interval",
+			Value:   newConfiguration.PollInterval,
+			Field:   &newConfiguration.pollInterval,
+			Default: 5 * time.Second,
+		},
+		{
+			Name:    "debounce",
+			Value:   newConfiguration.Debounce,
+			Field:   &newConfiguration.debounce,
+			Default: 500 * time.Millisecond,
+		},
+	} {
+		if err = newConfiguration.ParseDurationOrDefault(&durationConfigField); err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse %s", durationConfigField.Name)
+		}
+	}
+
+	if newConfiguration.pollInterval <= 0 {
+		return nil, errors.New("Poll interval must be positive")
+	}
+
+	return &newConfiguration, nil
+}
+
+// isOperationEnabled returns whether events should be emitted for the operation
+func (c *Configuration) isOperationEnabled(operation Operation) bool {
+	for _, enabledOperation := range c.Operations {
+		if enabledOperation == operation {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isFileMatched returns whether the file, given by its path relative to the watched path, matches the patterns
+func (c *Configuration) isFileMatched(relativePath string) bool {
+	for _, ignorePattern := range c.IgnorePatterns {
+		if c.isPatternMatched(ignorePattern, relativePath) {
+			return false
+		}
+	}
+
+	if len(c.Patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range c.Patterns {
+		if c.isPatternMatched(pattern, relativePath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Configuration) isPatternMatched(pattern string, relativePath string) bool {
+	name := relativePath
+
+	// patterns without a separator match the file name
+	if !strings.Contains(pattern, "/") {
+		name = filepath.Base(relativePath)
+	}
+
+	matched, _ := filepath.Match(pattern, filepath.ToSlash(name))
+	return matched
+}
+
+// isDirectoryExcluded returns whether the directory holds handled files, which are not watched
+func (c *Configuration) isDirectoryExcluded(directory string) bool {
+	return directory != "" && (directory == c.DoneDirectory || directory == c.FailedDirectory)
+}
@@ -0,0 +1,303 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filewatch
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+)
+
+// watcher reports the paths that may have changed under the watched path
+type watcher interface {
+
+	// start reporting changed paths into the channel
+	start(changedPathsChan chan<- string) error
+
+	// stop reporting
+	stop()
+}
+
+// fileState is what's compared to tell whether a file was modified
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+func (fs fileState) equals(other fileState) bool {
+	return fs.size == other.size && fs.modTime.Equal(other.modTime)
+}
+
+// walkFiles calls the callback for each regular file under the configured path, skipping the
+// directories of handled files (and subdirectories unless recursive)
+func walkFiles(configuration *Configuration, callback func(path string, info fs.FileInfo)) error {
+	return filepath.WalkDir(configuration.Path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+
+			// files may be removed while walking
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		if entry.IsDir() {
+			if path != configuration.Path && (!configuration.Recursive || configuration.isDirectoryExcluded(path)) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil
+		}
+
+		callback(path, info)
+		return nil
+	})
+}
+
+// notifyWatcher is notified of changes by the OS (inotify on linux)
+type notifyWatcher struct {
+	logger          logger.Logger
+	configuration   *Configuration
+	fsnotifyWatcher *fsnotify.Watcher
+	stopChan        chan struct{}
+}
+
+func newNotifyWatcher(parentLogger logger.Logger, configuration *Configuration) (*notifyWatcher, error) {
+	fsnotifyWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create watcher")
+	}
+
+	newWatcher := notifyWatcher{
+		logger:          parentLogger.GetChild("notify"),
+		configuration:   configuration,
+		fsnotifyWatcher: fsnotifyWatcher,
+		stopChan:        make(chan struct{}),
+	}
+
+	if err := newWatcher.addDirectory(configuration.Path, nil); err != nil {
+		fsnotifyWatcher.Close() // nolint: errcheck
+		return nil, errors.Wrap(err, "Failed to watch path")
+	}
+
+	return &newWatcher, nil
+}
+
+func (nw *notifyWatcher) start(changedPathsChan chan<- string) error {
+	go func() {
+		for {
+			select {
+			case event, ok := <-nw.fsnotifyWatcher.Events:
+				if !ok {
+					return
+				}
+
+				nw.handleEvent(event, changedPathsChan)
+
+			case err, ok := <-nw.fsnotifyWatcher.Errors:
+				if !ok {
+					return
+				}
+
+				nw.logger.WarnWith("Got watcher error", "err", err.Error())
+
+			case <-nw.stopChan:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (nw *notifyWatcher) stop() {
+	close(nw.stopChan)
+	nw.fsnotifyWatcher.Close() // nolint: errcheck
+}
+
+func (nw *notifyWatcher) handleEvent(event fsnotify.Event, changedPathsChan chan<- string) {
+
+	// a directory was created - watch it, and report the files that were created in it before it was watched
+	if event.Has(fsnotify.Create) && nw.configuration.Recursive {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			if nw.configuration.isDirectoryExcluded(event.Name) {
+				return
+			}
+
+			if err := nw.addDirectory(event.Name, changedPathsChan); err != nil {
+				nw.logger.WarnWith("Failed to watch directory", "path", event.Name, "err", err.Error())
+			}
+
+			return
+		}
+	}
+
+	// attribute changes (e.g. chmod) don't change contents
+	if event.Op == fsnotify.Chmod {
+		return
+	}
+
+	nw.report(event.Name, changedPathsChan)
+}
+
+func (nw *notifyWatcher) report(path string, changedPathsChan chan<- string) {
+	select {
+	case changedPathsChan <- path:
+	case <-nw.stopChan:
+	}
+}
+
+// addDirectory watches the directory (and its subdirectories, if recursive). if a channel is given, the files found
+// in the directories are reported to it
+func (nw *notifyWatcher) addDirectory(directory string, changedPathsChan chan<- string) error {
+	return filepath.WalkDir(directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		if !entry.IsDir() {
+			if changedPathsChan != nil && entry.Type().IsRegular() {
+				nw.report(path, changedPathsChan)
+			}
+
+			return nil
+		}
+
+		if path != directory && (!nw.configuration.Recursive || nw.configuration.isDirectoryExcluded(path)) {
+			return filepath.SkipDir
+		}
+
+		if err := nw.fsnotifyWatcher.Add(path); err != nil {
+			return errors.Wrapf(err, "Failed to watch directory %s", path)
+		}
+
+		return nil
+	})
+}
+
+// pollWatcher scans the watched path periodically, for file systems that don't support notifications (e.g. NFS)
+type pollWatcher struct {
+	logger        logger.Logger
+	configuration *Configuration
+	fileStates    map[string]fileState
+	stopChan      chan struct{}
+}
+
+func newPollWatcher(parentLogger logger.Logger, configuration *Configuration) (*pollWatcher, error) {
+	newWatcher := pollWatcher{
+		logger:        parentLogger.GetChild("poll"),
+		configuration: configuration,
+		stopChan:      make(chan struct{}),
+	}
+
+	// take the initial snapshot, to compare the first scan with
+	fileStates, err := newWatcher.scan()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to scan path")
+	}
+
+	newWatcher.fileStates = fileStates
+
+	return &newWatcher, nil
+}
+
+func (pw *pollWatcher) start(changedPathsChan chan<- string) error {
+	go func() {
+		ticker := time.NewTicker(pw.configuration.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				fileStates, err := pw.scan()
+				if err != nil {
+					pw.logger.WarnWith("Failed to scan path", "err", err.Error())
+					continue
+				}
+
+				for _, path := range pw.getChangedPaths(fileStates) {
+					select {
+					case changedPathsChan <- path:
+					case <-pw.stopChan:
+						return
+					}
+				}
+
+				pw.fileStates = fileStates
+
+			case <-pw.stopChan:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+func (pw *pollWatcher) stop() {
+	close(pw.stopChan)
+}
+
+func (pw *pollWatcher) scan() (map[string]fileState, error) {
+	fileStates := map[string]fileState{}
+
+	if err := walkFiles(pw.configuration, func(path string, info fs.FileInfo) {
+		fileStates[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+	}); err != nil {
+		return nil, errors.Wrap(err, "Failed to walk path")
+	}
+
+	return fileStates, nil
+}
+
+// getChangedPaths returns the paths that were added, changed or removed since the previous scan
+func (pw *pollWatcher) getChangedPaths(fileStates map[string]fileState) []string {
+	var changedPaths []string
+
+	for path, state := range fileStates {
+		if previousState, found := pw.fileStates[path]; !found || !previousState.equals(state) {
+			changedPaths = append(changedPaths, path)
+		}
+	}
+
+	for path := range pw.fileStates {
+		if _, found := fileStates[path]; !found {
+			changedPaths = append(changedPaths, path)
+		}
+	}
+
+	return changedPaths
+}
@@ -0,0 +1,49 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"github.com/nuclio/errors"
+)
+
+// rawCodec passes messages as is, leaving their encoding (usually protobuf) to the handler
+type rawCodec struct{}
+
+func (rc rawCodec) Marshal(message interface{}) ([]byte, error) {
+	switch typedMessage := message.(type) {
+	case []byte:
+		return typedMessage, nil
+	case *[]byte:
+		return *typedMessage, nil
+	default:
+		return nil, errors.Errorf("Unsupported message type: %T", message)
+	}
+}
+
+func (rc rawCodec) Unmarshal(data []byte, message interface{}) error {
+	typedMessage, ok := message.(*[]byte)
+	if !ok {
+		return errors.Errorf("Unsupported message type: %T", message)
+	}
+
+	*typedMessage = append([]byte{}, data...)
+	return nil
+}
+
+func (rc rawCodec) Name() string {
+	return "raw"
+}
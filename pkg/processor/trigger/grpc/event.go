@@ -0,0 +1,92 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nuclio/nuclio-sdk-go"
+	"google.golang.org/grpc/metadata"
+)
+
+// Event is a unary or server-streaming gRPC call
+type Event struct {
+	nuclio.AbstractEvent
+	fullMethod string
+	body       []byte
+	headers    map[string]interface{}
+}
+
+func newEvent(fullMethod string, body []byte, incomingMetadata metadata.MD) *Event {
+	headers := map[string]interface{}{}
+	for key, values := range incomingMetadata {
+		if len(values) > 0 {
+			headers[key] = values[0]
+		}
+	}
+
+	return &Event{
+		fullMethod: fullMethod,
+		body:       body,
+		headers:    headers,
+	}
+}
+
+// GetBody returns the request message, as encoded by the client
+func (e *Event) GetBody() []byte {
+	return e.body
+}
+
+// GetPath returns the full method name (e.g. /package.Service/Method)
+func (e *Event) GetPath() string {
+	return e.fullMethod
+}
+
+// GetMethod returns the method name, without its service
+func (e *Event) GetMethod() string {
+	return e.fullMethod[strings.LastIndex(e.fullMethod, "/")+1:]
+}
+
+// GetContentType returns the content type of the call (e.g. application/grpc+proto)
+func (e *Event) GetContentType() string {
+	return e.GetHeaderString("content-type")
+}
+
+// GetHeaders returns the call's metadata, keeping the first value of each key
+func (e *Event) GetHeaders() map[string]interface{} {
+	return e.headers
+}
+
+// GetHeader returns the metadata value of the given key. keys are lowercase
+func (e *Event) GetHeader(key string) interface{} {
+	return e.headers[strings.ToLower(key)]
+}
+
+// GetHeaderByteSlice returns the metadata value of the given key as a byte slice
+func (e *Event) GetHeaderByteSlice(key string) []byte {
+	return []byte(e.GetHeaderString(key))
+}
+
+// GetHeaderString returns the metadata value of the given key as a string
+func (e *Event) GetHeaderString(key string) string {
+	if value := e.GetHeader(key); value != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return ""
+}
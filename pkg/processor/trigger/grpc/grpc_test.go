@@ -0,0 +1,262 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	nethttp "net/http"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// mockRuntime responds according to the called method
+type mockRuntime struct {
+	runtime.Runtime
+}
+
+func (mr *mockRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	switch event.GetMethod() {
+	case "Echo":
+		return nuclio.Response{
+			Body: append([]byte(event.GetPath()+":"+event.GetHeaderString("x-request")+":"), event.GetBody()...),
+			Headers: map[string]interface{}{
+				"X-Reply":      "yes",
+				"Content-Type": "text/plain",
+			},
+		}, nil
+
+	case "Missing":
+		return nil, nuclio.NewErrNotFound("no such item")
+
+	case "Throttled":
+		return nuclio.Response{
+			StatusCode: nethttp.StatusTooManyRequests,
+			Body:       []byte("slow down"),
+		}, nil
+
+	case "Fail":
+		return nil, errors.New("something broke")
+
+	case "Stream", "Truncated":
+		var body []byte
+		for _, message := range []string{"one", "two", "three"} {
+			body = binary.BigEndian.AppendUint32(body, uint32(len(message)))
+			body = append(body, message...)
+		}
+
+		if event.GetMethod() == "Truncated" {
+			body = body[:len(body)-1]
+		}
+
+		return body, nil
+	}
+
+	return string(event.GetBody()), nil
+}
+
+type TestSuite struct {
+	suite.Suite
+	logger     logger.Logger
+	trigger    *grpc
+	connection *grpclib.ClientConn
+}
+
+func (suite *TestSuite) SetupSuite() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	configuration, err := NewConfiguration("test", &functionconfig.Trigger{
+		Kind: "grpc",
+		URL:  "127.0.0.1:0",
+		Attributes: map[string]interface{}{
+			"serverStreamingMethods": []string{"/test.Service/Stream", "/test.Service/Trunc*"},
+		},
+	}, &runtime.Configuration{
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{},
+		},
+	})
+	suite.Require().NoError(err)
+
+	workerInstance, err := worker.NewWorker(suite.logger, 0, &mockRuntime{})
+	suite.Require().NoError(err)
+
+	workerAllocator, err := worker.NewFixedPoolWorkerAllocator(suite.logger, []*worker.Worker{workerInstance})
+	suite.Require().NoError(err)
+
+	triggerInstance, err := newTrigger(suite.logger, workerAllocator, configuration, nil)
+	suite.Require().NoError(err)
+
+	suite.trigger = triggerInstance.(*grpc)
+
+	err = suite.trigger.Start(nil)
+	suite.Require().NoError(err)
+
+	suite.connection, err = grpclib.Dial(suite.trigger.listener.Addr().String(),
+		grpclib.WithTransportCredentials(insecure.NewCredentials()),
+		grpclib.WithDefaultCallOptions(grpclib.ForceCodec(rawCodec{})))
+	suite.Require().NoError(err)
+}
+
+func (suite *TestSuite) TearDownSuite() {
+	suite.connection.Close()  // nolint: errcheck
+	suite.trigger.Stop(false) // nolint: errcheck
+}
+
+func (suite *TestSuite) TestUnary() {
+	var response []byte
+	var responseMetadata metadata.MD
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "X-Request", "hello")
+
+	err := suite.connection.Invoke(ctx,
+		"/test.Service/Echo",
+		[]byte("body"),
+		&response,
+		grpclib.Header(&responseMetadata))
+	suite.Require().NoError(err)
+	suite.Require().Equal("/test.Service/Echo:hello:body", string(response))
+
+	// headers become metadata, except those reserved by gRPC
+	suite.Require().Equal([]string{"yes"}, responseMetadata.Get("x-reply"))
+	suite.Require().Equal([]string{"application/grpc+raw"}, responseMetadata.Get("content-type"))
+}
+
+func (suite *TestSuite) TestStatusCodes() {
+	for _, testCase := range []struct {
+		method          string
+		expectedCode    codes.Code
+		expectedMessage string
+	}{
+		{"/test.Service/Missing", codes.NotFound, "no such item"},
+		{"/test.Service/Throttled", codes.ResourceExhausted, "slow down"},
+		{"/test.Service/Fail", codes.Internal, "something broke"},
+	} {
+		var response []byte
+
+		err := suite.connection.Invoke(context.Background(), testCase.method, []byte{}, &response)
+		suite.Require().Equal(testCase.expectedCode, grpcstatus.Code(err), testCase.method)
+		suite.Require().Equal(testCase.expectedMessage, grpcstatus.Convert(err).Message(), testCase.method)
+	}
+}
+
+func (suite *TestSuite) TestServerStreaming() {
+	suite.Require().Equal([]string{"one", "two", "three"}, suite.receiveStream("/test.Service/Stream"))
+
+	// a method that isn't configured as server-streaming returns the body as a single message
+	suite.Require().Len(suite.receiveStream("/test.Service/Other"), 1)
+}
+
+func (suite *TestSuite) TestServerStreamingTruncatedResponse() {
+	stream := suite.openStream("/test.Service/Truncated")
+
+	var message []byte
+	err := stream.RecvMsg(&message)
+	suite.Require().Equal(codes.Internal, grpcstatus.Code(err))
+}
+
+func (suite *TestSuite) TestStatistics() {
+	statisticsBefore := *suite.trigger.GetStatistics()
+
+	var response []byte
+	err := suite.connection.Invoke(context.Background(), "/test.Service/Echo", []byte{}, &response)
+	suite.Require().NoError(err)
+
+	err = suite.connection.Invoke(context.Background(), "/test.Service/Fail", []byte{}, &response)
+	suite.Require().Error(err)
+
+	statisticsAfter := *suite.trigger.GetStatistics()
+	suite.Require().Equal(statisticsBefore.EventsHandledSuccessTotal+1, statisticsAfter.EventsHandledSuccessTotal)
+	suite.Require().Equal(statisticsBefore.EventsHandledFailureTotal+1, statisticsAfter.EventsHandledFailureTotal)
+}
+
+func (suite *TestSuite) TestSplitMessages() {
+	messages, err := splitMessages(nil)
+	suite.Require().NoError(err)
+	suite.Require().Empty(messages)
+
+	messages, err = splitMessages([]byte{0, 0, 0, 0, 0, 0, 0, 1, 'a'})
+	suite.Require().NoError(err)
+	suite.Require().Equal([][]byte{{}, []byte("a")}, messages)
+
+	_, err = splitMessages([]byte{0, 0})
+	suite.Require().Error(err)
+
+	_, err = splitMessages([]byte{0, 0, 0, 2, 'a'})
+	suite.Require().Error(err)
+}
+
+func (suite *TestSuite) openStream(fullMethod string) grpclib.ClientStream {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	suite.T().Cleanup(cancel)
+
+	stream, err := suite.connection.NewStream(ctx,
+		&grpclib.StreamDesc{ServerStreams: true},
+		fullMethod)
+	suite.Require().NoError(err)
+
+	err = stream.SendMsg([]byte("request"))
+	suite.Require().NoError(err)
+
+	err = stream.CloseSend()
+	suite.Require().NoError(err)
+
+	return stream
+}
+
+func (suite *TestSuite) receiveStream(fullMethod string) []string {
+	var messages []string
+
+	stream := suite.openStream(fullMethod)
+	for {
+		var message []byte
+
+		err := stream.RecvMsg(&message)
+		if err == io.EOF {
+			return messages
+		}
+
+		suite.Require().NoError(err)
+		messages = append(messages, string(message))
+	}
+}
+
+func TestGRPCSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
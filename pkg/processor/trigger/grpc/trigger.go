@@ -0,0 +1,283 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	nethttp "net/http"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+type grpc struct {
+	trigger.AbstractTrigger
+	configuration *Configuration
+	server        *grpclib.Server
+	listener      net.Listener
+}
+
+func newTrigger(logger logger.Logger,
+	workerAllocator worker.Allocator,
+	configuration *Configuration,
+	restartTriggerChan chan trigger.Trigger) (trigger.Trigger, error) {
+
+	// calls are handled concurrently, so we need an allocator that supports multiple go-routines
+	if !workerAllocator.Shareable() {
+		return nil, errors.New("gRPC trigger requires a shareable worker allocator")
+	}
+
+	abstractTrigger, err := trigger.NewAbstractTrigger(logger,
+		workerAllocator,
+		&configuration.Configuration,
+		"sync",
+		"grpc",
+		configuration.Name,
+		restartTriggerChan)
+	if err != nil {
+		return nil, errors.New("Failed to create abstract trigger")
+	}
+
+	newTrigger := grpc{
+		AbstractTrigger: abstractTrigger,
+		configuration:   configuration,
+	}
+
+	newTrigger.AbstractTrigger.Trigger = &newTrigger
+	return &newTrigger, nil
+}
+
+func (g *grpc) Start(checkpoint functionconfig.Checkpoint) error {
+	var err error
+
+	g.Logger.InfoWith("Starting",
+		"listenAddress", g.configuration.URL,
+		"maxReceiveMessageSize", g.configuration.MaxReceiveMessageSize,
+		"maxSendMessageSize", g.configuration.MaxSendMessageSize,
+		"serverStreamingMethods", g.configuration.ServerStreamingMethods)
+
+	g.listener, err = net.Listen("tcp", g.configuration.URL)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to listen on %s", g.configuration.URL)
+	}
+
+	// the trigger doesn't know the function's services, so it handles all methods as unknown ones
+	g.server = grpclib.NewServer(
+		grpclib.ForceServerCodec(rawCodec{}),
+		grpclib.UnknownServiceHandler(g.handleStream),
+		grpclib.MaxRecvMsgSize(g.configuration.MaxReceiveMessageSize),
+		grpclib.MaxSendMsgSize(g.configuration.MaxSendMessageSize))
+
+	go g.server.Serve(g.listener) // nolint: errcheck
+
+	return nil
+}
+
+func (g *grpc) Stop(force bool) (functionconfig.Checkpoint, error) {
+	g.Logger.Debug("Shutting down")
+
+	if g.server != nil {
+
+		// unless forced, let the calls in progress end
+		if force {
+			g.server.Stop()
+		} else {
+			g.server.GracefulStop()
+		}
+	}
+
+	return nil, nil
+}
+
+func (g *grpc) GetConfig() map[string]interface{} {
+	return common.StructureToMap(g.configuration)
+}
+
+func (g *grpc) handleStream(server interface{}, stream grpclib.ServerStream) error {
+	fullMethod, _ := grpclib.MethodFromServerStream(stream)
+
+	// both unary and server-streaming calls carry a single request message
+	var body []byte
+	if err := stream.RecvMsg(&body); err != nil {
+		g.UpdateStatistics(false)
+
+		if err == io.EOF {
+			return grpcstatus.Error(codes.InvalidArgument, "Request message is missing")
+		}
+
+		return err
+	}
+
+	incomingMetadata, _ := metadata.FromIncomingContext(stream.Context())
+
+	response, submitError, processError := g.AllocateWorkerAndSubmitEvent(newEvent(fullMethod, body, incomingMetadata),
+		nil,
+		time.Duration(*g.configuration.WorkerAvailabilityTimeoutMilliseconds)*time.Millisecond)
+
+	return g.sendResponse(stream, fullMethod, response, submitError, processError)
+}
+
+// sendResponse formats the handler's response the way the http trigger does, translating its status
+// code to a gRPC one and its headers to metadata
+func (g *grpc) sendResponse(stream grpclib.ServerStream,
+	fullMethod string,
+	response interface{},
+	submitError error,
+	processError error) error {
+	resolvedResponse := trigger.ResolveResponse(response, submitError, processError)
+
+	// if we failed to submit the event to a worker
+	if submitError != nil {
+		if resolvedResponse.StatusCode == nethttp.StatusServiceUnavailable {
+			return grpcstatus.Error(codes.Unavailable, "No available workers")
+		}
+
+		// something else - most likely a bug
+		g.Logger.WarnWith("Failed to submit event", "err", submitError)
+		return grpcstatus.Error(codes.Internal, "Failed to submit event")
+	}
+
+	if len(resolvedResponse.Headers) > 0 {
+		if err := stream.SetHeader(headersToMetadata(resolvedResponse.Headers)); err != nil {
+			g.Logger.WarnWith("Failed to set response metadata", "err", err.Error())
+		}
+	}
+
+	body := resolvedResponse.Body
+
+	statusCode := resolvedResponse.StatusCode
+	if statusCode == 0 {
+		statusCode = nethttp.StatusOK
+	}
+
+	if code := statusCodeToCode(statusCode); code != codes.OK {
+		return grpcstatus.Error(code, string(body))
+	}
+
+	messages := [][]byte{body}
+	if g.configuration.isServerStreamingMethod(fullMethod) {
+		var err error
+
+		messages, err = splitMessages(body)
+		if err != nil {
+			g.Logger.WarnWith("Failed to split response into messages",
+				"method", fullMethod,
+				"err", err.Error())
+			return grpcstatus.Error(codes.Internal, "Failed to split response into messages")
+		}
+	}
+
+	for _, message := range messages {
+		if err := stream.SendMsg(message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// headersToMetadata converts response headers to metadata, dropping those reserved by gRPC
+func headersToMetadata(headers map[string][]string) metadata.MD {
+	responseMetadata := metadata.MD{}
+
+	for headerKey, headerValues := range headers {
+		lowercaseHeaderKey := strings.ToLower(headerKey)
+		if lowercaseHeaderKey == "content-type" ||
+			strings.HasPrefix(lowercaseHeaderKey, "grpc-") ||
+			strings.HasPrefix(lowercaseHeaderKey, ":") {
+			continue
+		}
+
+		responseMetadata.Append(lowercaseHeaderKey, headerValues...)
+	}
+
+	return responseMetadata
+}
+
+// splitMessages splits the response of a server-streaming method into its messages, each prefixed
+// by its length as a 4 byte big-endian integer
+func splitMessages(body []byte) ([][]byte, error) {
+	var messages [][]byte
+
+	for len(body) > 0 {
+		if len(body) < 4 {
+			return nil, errors.New("Message length is truncated")
+		}
+
+		messageLength := binary.BigEndian.Uint32(body)
+		body = body[4:]
+
+		if uint64(messageLength) > uint64(len(body)) {
+			return nil, errors.Errorf("Message length %d exceeds the remaining %d bytes", messageLength, len(body))
+		}
+
+		messages = append(messages, body[:messageLength])
+		body = body[messageLength:]
+	}
+
+	return messages, nil
+}
+
+// statusCodeToCode translates the HTTP status code of a response to a gRPC code
+func statusCodeToCode(statusCode int) codes.Code {
+	switch statusCode {
+	case nethttp.StatusBadRequest:
+		return codes.InvalidArgument
+	case nethttp.StatusUnauthorized:
+		return codes.Unauthenticated
+	case nethttp.StatusForbidden:
+		return codes.PermissionDenied
+	case nethttp.StatusNotFound:
+		return codes.NotFound
+	case nethttp.StatusRequestTimeout, nethttp.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case nethttp.StatusConflict:
+		return codes.AlreadyExists
+	case nethttp.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case nethttp.StatusRequestEntityTooLarge, nethttp.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499: // client closed request
+		return codes.Canceled
+	case nethttp.StatusNotImplemented:
+		return codes.Unimplemented
+	case nethttp.StatusServiceUnavailable:
+		return codes.Unavailable
+	}
+
+	switch {
+	case statusCode < nethttp.StatusBadRequest:
+		return codes.OK
+	case statusCode < nethttp.StatusInternalServerError:
+		return codes.Unknown
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package grpc
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+const DefaultMaxMessageSize = 4 * 1024 * 1024
+
+type Configuration struct {
+	trigger.Configuration
+
+	// the maximal size of a request and of a response message
+	MaxReceiveMessageSize int
+	MaxSendMessageSize    int
+
+	// patterns of full method names (e.g. /package.Service/*) whose responses are streamed
+	ServerStreamingMethods []string
+}
+
+func NewConfiguration(id string,
+	triggerConfiguration *functionconfig.Trigger,
+	runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
+	newConfiguration := Configuration{}
+
+	// create base
+	baseConfiguration, err := trigger.NewConfiguration(id, triggerConfiguration, runtimeConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create trigger configuration")
+	}
+	newConfiguration.Configuration = *baseConfiguration
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Attributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	if newConfiguration.URL == "" {
+		newConfiguration.URL = fmt.Sprintf(":%d", functionconfig.DefaultGRPCPort)
+	}
+
+	if newConfiguration.MaxReceiveMessageSize == 0 {
+		newConfiguration.MaxReceiveMessageSize = DefaultMaxMessageSize
+	}
+
+	if newConfiguration.MaxSendMessageSize == 0 {
+		newConfiguration.MaxSendMessageSize = DefaultMaxMessageSize
+	}
+
+	if newConfiguration.MaxReceiveMessageSize < 0 || newConfiguration.MaxSendMessageSize < 0 {
+		return nil, errors.New("Maximal message sizes must not be negative")
+	}
+
+	for _, pattern := range newConfiguration.ServerStreamingMethods {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "Invalid server streaming method pattern: %s", pattern)
+		}
+	}
+
+	return &newConfiguration, nil
+}
+
+func (c *Configuration) isServerStreamingMethod(fullMethod string) bool {
+	for _, pattern := range c.ServerStreamingMethods {
+		if matched, _ := path.Match(pattern, fullMethod); matched {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"bytes"
+	nethttp "net/http"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/processor/cloudevent"
+
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
+)
+
+// wrapResponseInCloudEvent wraps the response of the handler in a cloud event, in the configured mode
+func (h *http) wrapResponseInCloudEvent(ctx *fasthttp.RequestCtx) {
+
+	// only successful responses are emitted as events
+	if statusCode := ctx.Response.StatusCode(); statusCode < nethttp.StatusOK || statusCode >= nethttp.StatusMultipleChoices {
+		return
+	}
+
+	// the handler already responded with a cloud event
+	if len(ctx.Response.Header.Peek("ce-specversion")) > 0 ||
+		bytes.HasPrefix(ctx.Response.Header.ContentType(), []byte(cloudevent.StructuredContentType)) {
+		return
+	}
+
+	attributes := &cloudevent.Attributes{
+		ID:     uuid.New().String(),
+		Source: h.configuration.CloudEvents.Source,
+		Type:   h.configuration.CloudEvents.Type,
+		Time:   time.Now(),
+	}
+
+	switch h.configuration.CloudEvents.Mode {
+	case cloudevent.ModeStructured:
+		encodedEvent, err := cloudevent.EncodeStructured(attributes,
+			string(ctx.Response.Header.ContentType()),
+			ctx.Response.Body())
+		if err != nil {
+			h.Logger.WarnWith("Failed to encode response as a cloud event", "err", err)
+			ctx.Response.ResetBody()
+			ctx.Response.SetStatusCode(nethttp.StatusInternalServerError)
+			return
+		}
+
+		ctx.Response.SetBodyRaw(encodedEvent)
+		ctx.SetContentType(cloudevent.StructuredContentType)
+
+	default:
+		for headerKey, headerValue := range cloudevent.EncodeBinaryHeaders(attributes) {
+			ctx.Response.Header.Set(headerKey, headerValue)
+		}
+	}
+}
@@ -20,12 +20,18 @@ package http
 
 import (
 	"context"
+	"encoding/json"
 	"net"
 	nethttp "net/http"
 	"testing"
 
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/common/headers"
 	"github.com/nuclio/nuclio/pkg/common/status"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/cloudevent"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/trigger/http/cors"
 
@@ -210,6 +216,185 @@ func (suite *TestSuite) TestInternalHealthiness() {
 	}
 }
 
+func (suite *TestSuite) TestCloudEventsConfiguration() {
+	for _, testCase := range []struct {
+		name           string
+		attributes     map[string]interface{}
+		labels         map[string]string
+		expectedMode   cloudevent.Mode
+		expectedType   string
+		expectedSource string
+		expectError    bool
+	}{
+		{
+			name:           "defaults",
+			attributes:     map[string]interface{}{"enabled": true},
+			expectedMode:   cloudevent.ModeBinary,
+			expectedType:   DefaultCloudEventType,
+			expectedSource: "/nuclio/functions/test-function",
+		},
+		{
+			name:           "projectSource",
+			attributes:     map[string]interface{}{"enabled": true, "mode": "Structured"},
+			labels:         map[string]string{common.NuclioResourceLabelKeyProjectName: "test-project"},
+			expectedMode:   cloudevent.ModeStructured,
+			expectedType:   DefaultCloudEventType,
+			expectedSource: "/nuclio/projects/test-project/functions/test-function",
+		},
+		{
+			name: "custom",
+			attributes: map[string]interface{}{
+				"enabled": true,
+				"type":    "com.example.order.created",
+				"source":  "/orders",
+			},
+			expectedMode:   cloudevent.ModeBinary,
+			expectedType:   "com.example.order.created",
+			expectedSource: "/orders",
+		},
+		{
+			name:        "invalidMode",
+			attributes:  map[string]interface{}{"enabled": true, "mode": "batched"},
+			expectError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			configuration, err := suite.createCloudEventsConfiguration(testCase.attributes, testCase.labels)
+			if testCase.expectError {
+				suite.Require().Error(err)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedMode, configuration.CloudEvents.Mode)
+			suite.Require().Equal(testCase.expectedType, configuration.CloudEvents.Type)
+			suite.Require().Equal(testCase.expectedSource, configuration.CloudEvents.Source)
+		})
+	}
+}
+
+func (suite *TestSuite) TestCloudEventsResponse() {
+	for _, testCase := range []struct {
+		name        string
+		mode        string
+		statusCode  int
+		contentType string
+		headers     map[string]string
+		body        string
+		expectEvent bool
+	}{
+		{
+			name:        "binary",
+			mode:        "binary",
+			statusCode:  nethttp.StatusOK,
+			contentType: "application/json",
+			body:        `{"a": 1}`,
+			expectEvent: true,
+		},
+		{
+			name:        "structured",
+			mode:        "structured",
+			statusCode:  nethttp.StatusCreated,
+			contentType: "application/json",
+			body:        `{"a": 1}`,
+			expectEvent: true,
+		},
+		{
+			name:        "failedResponse",
+			mode:        "binary",
+			statusCode:  nethttp.StatusBadRequest,
+			contentType: "text/plain",
+			body:        "bad request",
+		},
+		{
+			name:        "alreadyCloudEvent",
+			mode:        "structured",
+			statusCode:  nethttp.StatusOK,
+			contentType: "application/json",
+			headers:     map[string]string{"ce-specversion": "1.0"},
+			body:        `{"a": 1}`,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			configuration, err := suite.createCloudEventsConfiguration(map[string]interface{}{
+				"enabled": true,
+				"mode":    testCase.mode,
+				"type":    "test-type",
+			}, nil)
+			suite.Require().NoError(err)
+
+			triggerInstance := &http{
+				AbstractTrigger: trigger.AbstractTrigger{
+					Logger: suite.logger,
+				},
+				configuration: configuration,
+			}
+
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Response.SetStatusCode(testCase.statusCode)
+			ctx.Response.SetBodyString(testCase.body)
+			ctx.SetContentType(testCase.contentType)
+			for headerKey, headerValue := range testCase.headers {
+				ctx.Response.Header.Set(headerKey, headerValue)
+			}
+
+			triggerInstance.wrapResponseInCloudEvent(ctx)
+
+			// status code is never changed
+			suite.Require().Equal(testCase.statusCode, ctx.Response.StatusCode())
+
+			if !testCase.expectEvent {
+				suite.Require().Equal(testCase.body, string(ctx.Response.Body()))
+				suite.Require().Equal(testCase.contentType, string(ctx.Response.Header.ContentType()))
+				suite.Require().Empty(ctx.Response.Header.Peek("ce-id"))
+				return
+			}
+
+			if testCase.mode == "binary" {
+				suite.Require().Equal(testCase.body, string(ctx.Response.Body()))
+				suite.Require().Equal(testCase.contentType, string(ctx.Response.Header.ContentType()))
+				suite.Require().Equal("1.0", string(ctx.Response.Header.Peek("ce-specversion")))
+				suite.Require().Equal("test-type", string(ctx.Response.Header.Peek("ce-type")))
+				suite.Require().Equal("/nuclio/functions/test-function", string(ctx.Response.Header.Peek("ce-source")))
+				suite.Require().NotEmpty(ctx.Response.Header.Peek("ce-id"))
+				suite.Require().NotEmpty(ctx.Response.Header.Peek("ce-time"))
+				return
+			}
+
+			suite.Require().Equal(cloudevent.StructuredContentType, string(ctx.Response.Header.ContentType()))
+			suite.Require().Empty(ctx.Response.Header.Peek("ce-id"))
+
+			structuredEvent := map[string]interface{}{}
+			suite.Require().NoError(json.Unmarshal(ctx.Response.Body(), &structuredEvent))
+			suite.Require().Equal("1.0", structuredEvent["specversion"])
+			suite.Require().Equal("test-type", structuredEvent["type"])
+			suite.Require().Equal("/nuclio/functions/test-function", structuredEvent["source"])
+			suite.Require().Equal(testCase.contentType, structuredEvent["datacontenttype"])
+			suite.Require().Equal(map[string]interface{}{"a": float64(1)}, structuredEvent["data"])
+			suite.Require().NotEmpty(structuredEvent["id"])
+		})
+	}
+}
+
+func (suite *TestSuite) createCloudEventsConfiguration(cloudEventsAttributes map[string]interface{},
+	labels map[string]string) (*Configuration, error) {
+	return NewConfiguration("test", &functionconfig.Trigger{
+		Kind: "http",
+		Attributes: map[string]interface{}{
+			"cloudEvents": cloudEventsAttributes,
+		},
+	}, &runtime.Configuration{
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{
+				Meta: functionconfig.Meta{
+					Name:   "test-function",
+					Labels: labels,
+				},
+			},
+		},
+	})
+}
+
 func (suite *TestSuite) serveDummyHTTPServer(handler fasthttp.RequestHandler) {
 	go func() {
 		suite.fastDummyHTTPServerStarted = true
@@ -24,15 +24,18 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/common/headers"
 	"github.com/nuclio/nuclio/pkg/common/status"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
 
+	"github.com/fasthttp/websocket"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
 	"github.com/nuclio/nuclio-sdk-go"
@@ -55,6 +58,11 @@ type http struct {
 	answering          []uint64 // flag the worker is answering
 	server             *fasthttp.Server
 	internalHealthPath []byte
+
+	webSocketUpgrader               *websocket.FastHTTPUpgrader
+	webSocketConnections            map[string]*webSocketConnection
+	webSocketConnectionsLock        sync.Mutex
+	webSocketPushControlMessageChan chan *controlcommunication.ControlMessage
 }
 
 func newTrigger(logger logger.Logger,
@@ -98,9 +106,16 @@ func newTrigger(logger logger.Logger,
 		timeouts:           make([]uint64, numWorkers),
 		answering:          make([]uint64, numWorkers),
 		internalHealthPath: []byte(InternalHealthPath),
+
+		webSocketConnections: map[string]*webSocketConnection{},
 	}
 
 	newTrigger.AbstractTrigger.Trigger = &newTrigger
+
+	if configuration.webSocketEnabled() {
+		newTrigger.webSocketUpgrader = newTrigger.createWebSocketUpgrader()
+	}
+
 	newTrigger.allocateEvents(numWorkers)
 	return &newTrigger, nil
 }
@@ -111,7 +126,8 @@ func (h *http) Start(checkpoint functionconfig.Checkpoint) error {
 		"readBufferSize", h.configuration.ReadBufferSize,
 		"maxRequestBodySize", h.configuration.MaxRequestBodySize,
 		"reduceMemoryUsage", h.configuration.ReduceMemoryUsage,
-		"cors", h.configuration.CORS)
+		"cors", h.configuration.CORS,
+		"webSocket", h.configuration.WebSocket)
 
 	h.server = &fasthttp.Server{
 		Handler:            h.onRequestFromFastHTTP(),
@@ -122,6 +138,12 @@ func (h *http) Start(checkpoint functionconfig.Checkpoint) error {
 		ReduceMemoryUsage:  h.configuration.ReduceMemoryUsage,
 	}
 
+	if h.configuration.webSocketEnabled() {
+		if err := h.startWebSocketPushHandler(); err != nil {
+			return errors.Wrap(err, "Failed to start websocket push handler")
+		}
+	}
+
 	// start listening
 	go h.server.ListenAndServe(h.configuration.URL) // nolint: errcheck
 
@@ -134,6 +156,9 @@ func (h *http) Stop(force bool) (functionconfig.Checkpoint, error) {
 
 	h.status = status.Stopped
 
+	// upgraded connections aren't closed by the server
+	h.stopWebSocket()
+
 	if h.server != nil {
 		err := h.server.Shutdown()
 
@@ -189,7 +214,7 @@ func (h *http) AllocateWorkerAndSubmitEvent(ctx *fasthttp.RequestCtx,
 	defer h.HandleSubmitPanic(workerInstance, &submitError)
 
 	// allocate a worker
-	workerInstance, err := h.WorkerAllocator.Allocate(timeout)
+	workerInstance, err := h.AllocateWorker(timeout)
 	if err != nil {
 		h.UpdateStatistics(false)
 		return nil, false, errors.Wrap(err, "Failed to allocate worker"), nil
@@ -404,6 +429,12 @@ func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	// messages of upgraded connections are handled by the connection
+	if h.isWebSocketUpgrade(ctx) {
+		h.upgradeToWebSocket(ctx)
+		return
+	}
+
 	// attach the context to the event
 	// get the log level required
 	responseLogLevel := ctx.Request.Header.Peek(headers.LogLevel)
@@ -504,6 +535,9 @@ func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
+	// file streams aren't wrapped in cloud events
+	wrapInCloudEvent := h.configuration.cloudEventsEnabled()
+
 	// format the response into the context, based on its type
 	switch typedResponse := response.(type) {
 	case nuclio.Response:
@@ -544,6 +578,7 @@ func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 			}
 
 			ctx.Response.SetBodyStream(fileResponse, -1)
+			wrapInCloudEvent = false
 		} else {
 			// set body
 			ctx.Response.SetBodyRaw(typedResponse.Body)
@@ -565,6 +600,10 @@ func (h *http) handleRequest(ctx *fasthttp.RequestCtx) {
 	case string:
 		ctx.WriteString(typedResponse) // nolint: errcheck
 	}
+
+	if wrapInCloudEvent {
+		h.wrapResponseInCloudEvent(ctx)
+	}
 }
 
 func (h *http) allocateEvents(size int) {
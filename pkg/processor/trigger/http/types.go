@@ -17,7 +17,13 @@ limitations under the License.
 package http
 
 import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/cloudevent"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/trigger/http/cors"
@@ -29,6 +35,8 @@ import (
 const DefaultReadBufferSize = 16 * 1024
 const DefaultMaxRequestBodySize = 4 * 1024 * 1024
 const InternalHealthPath = "/__internal/health"
+const DefaultWebSocketIdleTimeout = 5 * time.Minute
+const DefaultCloudEventType = "io.nuclio.response"
 
 type Configuration struct {
 	trigger.Configuration
@@ -43,6 +51,38 @@ type Configuration struct {
 
 	// Used to disable port publishing for the HTTP trigger on docker platform
 	DisablePortPublishing bool
+
+	WebSocket *WebSocket
+
+	CloudEvents *CloudEvents
+}
+
+// CloudEvents wraps handler responses in CloudEvents
+type CloudEvents struct {
+	Enabled bool
+
+	// binary (default) or structured
+	Mode cloudevent.Mode
+
+	// the type of the emitted events (defaults to io.nuclio.response)
+	Type string
+
+	// the source of the emitted events (defaults to one derived from the function and its project)
+	Source string
+}
+
+type WebSocket struct {
+
+	// requests to these paths may be upgraded to websocket connections
+	Paths []string
+
+	// connections on which no message was received or sent for this long are closed
+	IdleTimeout string
+
+	// the maximal size of an inbound message (defaults to the max request body size)
+	MaxMessageSize int64
+
+	idleTimeout time.Duration
 }
 
 func NewConfiguration(id string,
@@ -77,9 +117,66 @@ func NewConfiguration(id string,
 	if newConfiguration.CORS != nil && newConfiguration.CORS.Enabled {
 		newConfiguration.CORS = createCORSConfiguration(newConfiguration.CORS)
 	}
+
+	if newConfiguration.WebSocket != nil {
+		if err := newConfiguration.ParseDurationOrDefault(&trigger.DurationConfigField{
+			Name:    "websocket idle timeout",
+			Value:   newConfiguration.WebSocket.IdleTimeout,
+			Field:   &newConfiguration.WebSocket.idleTimeout,
+			Default: DefaultWebSocketIdleTimeout,
+		}); err != nil {
+			return nil, errors.Wrap(err, "Failed to parse websocket idle timeout")
+		}
+
+		if newConfiguration.WebSocket.MaxMessageSize == 0 {
+			newConfiguration.WebSocket.MaxMessageSize = int64(newConfiguration.MaxRequestBodySize)
+		}
+	}
+
+	if newConfiguration.CloudEvents != nil && newConfiguration.CloudEvents.Enabled {
+		if err := newConfiguration.populateCloudEventsDefaults(); err != nil {
+			return nil, errors.Wrap(err, "Failed to populate cloud events defaults")
+		}
+	}
+
 	return &newConfiguration, nil
 }
 
+func (c *Configuration) populateCloudEventsDefaults() error {
+	switch cloudevent.Mode(strings.ToLower(string(c.CloudEvents.Mode))) {
+	case "", cloudevent.ModeBinary:
+		c.CloudEvents.Mode = cloudevent.ModeBinary
+	case cloudevent.ModeStructured:
+		c.CloudEvents.Mode = cloudevent.ModeStructured
+	default:
+		return errors.Errorf("Unsupported cloud events mode: %s", c.CloudEvents.Mode)
+	}
+
+	if c.CloudEvents.Type == "" {
+		c.CloudEvents.Type = DefaultCloudEventType
+	}
+
+	if c.CloudEvents.Source == "" {
+		c.CloudEvents.Source = c.getDefaultCloudEventSource()
+	}
+
+	return nil
+}
+
+// getDefaultCloudEventSource returns a source identifying the function, within its project if it has one
+func (c *Configuration) getDefaultCloudEventSource() string {
+	if c.RuntimeConfiguration == nil || c.RuntimeConfiguration.Configuration == nil {
+		return "/nuclio"
+	}
+
+	meta := c.RuntimeConfiguration.Meta
+	if projectName := meta.Labels[common.NuclioResourceLabelKeyProjectName]; projectName != "" {
+		return fmt.Sprintf("/nuclio/projects/%s/functions/%s", projectName, meta.Name)
+	}
+
+	return fmt.Sprintf("/nuclio/functions/%s", meta.Name)
+}
+
 func createCORSConfiguration(corsConfiguration *cors.CORS) *cors.CORS {
 
 	// take defaults
@@ -117,3 +214,25 @@ func createCORSConfiguration(corsConfiguration *cors.CORS) *cors.CORS {
 func (c *Configuration) corsEnabled() bool {
 	return c.CORS != nil && c.CORS.Enabled
 }
+
+func (c *Configuration) cloudEventsEnabled() bool {
+	return c.CloudEvents != nil && c.CloudEvents.Enabled
+}
+
+func (c *Configuration) webSocketEnabled() bool {
+	return c.WebSocket != nil && len(c.WebSocket.Paths) > 0
+}
+
+func (c *Configuration) isWebSocketPath(path string) bool {
+	if !c.webSocketEnabled() {
+		return false
+	}
+
+	for _, webSocketPath := range c.WebSocket.Paths {
+		if webSocketPath == path {
+			return true
+		}
+	}
+
+	return false
+}
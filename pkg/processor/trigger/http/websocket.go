@@ -0,0 +1,393 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	nethttp "net/http"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common/headers"
+	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/fasthttp/websocket"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/valyala/fasthttp"
+)
+
+// webSocketConnection is an upgraded connection, served by a single worker for its lifetime so that
+// its messages are handled in order
+type webSocketConnection struct {
+	id             string
+	conn           *websocket.Conn
+	workerInstance *worker.Worker
+	method         string
+	path           string
+	headers        map[string]interface{}
+	fields         map[string]interface{}
+	idleTimeout    time.Duration
+	writeLock      sync.Mutex
+}
+
+// write writes a message to the connection. writes may come from both the handler's responses and pushes
+func (wsc *webSocketConnection) write(messageType int, data []byte) error {
+	wsc.writeLock.Lock()
+	defer wsc.writeLock.Unlock()
+
+	wsc.extendIdleTimeout()
+
+	return wsc.conn.WriteMessage(messageType, data)
+}
+
+func (wsc *webSocketConnection) extendIdleTimeout() {
+	deadline := time.Now().Add(wsc.idleTimeout)
+
+	wsc.conn.SetReadDeadline(deadline)  // nolint: errcheck
+	wsc.conn.SetWriteDeadline(deadline) // nolint: errcheck
+}
+
+// webSocketEvent is a message received on a websocket connection
+type webSocketEvent struct {
+	nuclio.AbstractEvent
+	connection  *webSocketConnection
+	messageType int
+	body        []byte
+	timestamp   time.Time
+}
+
+// GetContentType returns text/plain for text messages and application/octet-stream for binary ones
+func (e *webSocketEvent) GetContentType() string {
+	if e.messageType == websocket.TextMessage {
+		return "text/plain"
+	}
+
+	return "application/octet-stream"
+}
+
+// GetBody returns the message
+func (e *webSocketEvent) GetBody() []byte {
+	return e.body
+}
+
+// GetHeaderByteSlice returns the header by name as a byte slice
+func (e *webSocketEvent) GetHeaderByteSlice(key string) []byte {
+	return []byte(e.GetHeaderString(key))
+}
+
+// GetHeader returns the header by name as an interface{}
+func (e *webSocketEvent) GetHeader(key string) interface{} {
+	return e.connection.headers[nethttp.CanonicalHeaderKey(key)]
+}
+
+// GetHeaders returns the headers of the upgrade request, along with the connection ID
+func (e *webSocketEvent) GetHeaders() map[string]interface{} {
+	return e.connection.headers
+}
+
+// GetHeaderString returns the header by name as a string
+func (e *webSocketEvent) GetHeaderString(key string) string {
+	if value, found := e.GetHeader(key).(string); found {
+		return value
+	}
+
+	return ""
+}
+
+// GetMethod returns the method of the upgrade request
+func (e *webSocketEvent) GetMethod() string {
+	return e.connection.method
+}
+
+// GetPath returns the path of the upgrade request
+func (e *webSocketEvent) GetPath() string {
+	return e.connection.path
+}
+
+// GetFieldByteSlice returns the query argument of the upgrade request by name as a byte slice
+func (e *webSocketEvent) GetFieldByteSlice(key string) []byte {
+	return []byte(e.GetFieldString(key))
+}
+
+// GetFieldString returns the query argument of the upgrade request by name as a string
+func (e *webSocketEvent) GetFieldString(key string) string {
+	if value, found := e.connection.fields[key].(string); found {
+		return value
+	}
+
+	return ""
+}
+
+// GetFields returns the query arguments of the upgrade request
+func (e *webSocketEvent) GetFields() map[string]interface{} {
+	return e.connection.fields
+}
+
+// GetTimestamp returns when the message was received
+func (e *webSocketEvent) GetTimestamp() time.Time {
+	return e.timestamp
+}
+
+func (h *http) createWebSocketUpgrader() *websocket.FastHTTPUpgrader {
+	webSocketUpgrader := &websocket.FastHTTPUpgrader{}
+
+	// when cors is enabled, the origin was already checked against the allowed ones. otherwise, only
+	// same origin requests are upgraded
+	if h.configuration.corsEnabled() {
+		webSocketUpgrader.CheckOrigin = func(ctx *fasthttp.RequestCtx) bool {
+			return true
+		}
+	}
+
+	return webSocketUpgrader
+}
+
+func (h *http) isWebSocketUpgrade(ctx *fasthttp.RequestCtx) bool {
+	return h.webSocketUpgrader != nil &&
+		h.configuration.isWebSocketPath(string(ctx.Path())) &&
+		websocket.FastHTTPIsWebSocketUpgrade(ctx)
+}
+
+func (h *http) upgradeToWebSocket(ctx *fasthttp.RequestCtx) {
+	workerInstance, err := h.AllocateWorker(
+		time.Duration(*h.configuration.WorkerAvailabilityTimeoutMilliseconds) * time.Millisecond)
+	if err != nil {
+		h.UpdateStatistics(false)
+		ctx.Response.SetStatusCode(nethttp.StatusServiceUnavailable)
+		return
+	}
+
+	// the request can't be accessed once upgraded, so keep what the events need
+	connection := &webSocketConnection{
+		id:             uuid.New().String(),
+		workerInstance: workerInstance,
+		method:         string(ctx.Method()),
+		path:           string(ctx.Path()),
+		headers:        map[string]interface{}{},
+		fields:         map[string]interface{}{},
+		idleTimeout:    h.configuration.WebSocket.idleTimeout,
+	}
+
+	ctx.Request.Header.VisitAll(func(key, value []byte) {
+		connection.headers[string(key)] = string(value)
+	})
+
+	ctx.QueryArgs().VisitAll(func(key, value []byte) {
+		connection.fields[string(key)] = string(value)
+	})
+
+	connection.headers[headers.WebSocketConnectionID] = connection.id
+
+	if err := h.webSocketUpgrader.Upgrade(ctx, func(conn *websocket.Conn) {
+		connection.conn = conn
+		h.serveWebSocketConnection(connection)
+	}); err != nil {
+
+		// the upgrader already responded with the reason
+		h.Logger.DebugWith("Failed to upgrade to websocket", "path", connection.path, "err", err.Error())
+		h.WorkerAllocator.Release(workerInstance)
+		h.UpdateStatistics(false)
+	}
+}
+
+func (h *http) serveWebSocketConnection(connection *webSocketConnection) {
+	h.Logger.DebugWith("WebSocket connection opened",
+		"connectionID", connection.id,
+		"path", connection.path,
+		"workerIndex", connection.workerInstance.GetIndex())
+
+	h.addWebSocketConnection(connection)
+
+	defer func() {
+		h.removeWebSocketConnection(connection)
+		connection.conn.Close() // nolint: errcheck
+		h.WorkerAllocator.Release(connection.workerInstance)
+
+		h.Logger.DebugWith("WebSocket connection closed", "connectionID", connection.id)
+	}()
+
+	connection.conn.SetReadLimit(h.configuration.WebSocket.MaxMessageSize)
+
+	// pings keep the connection from being idle
+	connection.conn.SetPingHandler(func(data string) error {
+		connection.extendIdleTimeout()
+
+		err := connection.conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		if err == websocket.ErrCloseSent {
+			return nil
+		}
+
+		return err
+	})
+
+	connection.extendIdleTimeout()
+
+	for {
+		messageType, message, err := connection.conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				h.Logger.DebugWith("Stopped reading from websocket connection",
+					"connectionID", connection.id,
+					"err", err.Error())
+			}
+
+			return
+		}
+
+		connection.extendIdleTimeout()
+
+		// the next message is read only once this one is handled, keeping the connection's messages in order
+		h.handleWebSocketMessage(connection, messageType, message)
+
+		// handling the message may have taken longer than the idle timeout
+		connection.extendIdleTimeout()
+	}
+}
+
+// handleWebSocketMessage submits a message to the connection's worker, writing back a non-empty response
+func (h *http) handleWebSocketMessage(connection *webSocketConnection, messageType int, message []byte) {
+	response, processError := h.SubmitEventToWorker(nil, connection.workerInstance, &webSocketEvent{
+		connection:  connection,
+		messageType: messageType,
+		body:        message,
+		timestamp:   time.Now(),
+	})
+
+	if processError != nil {
+		h.Logger.DebugWith("Failed to process websocket message",
+			"connectionID", connection.id,
+			"err", processError.Error())
+		return
+	}
+
+	var body []byte
+
+	switch typedResponse := response.(type) {
+	case *nuclio.Response:
+		body = typedResponse.Body
+	case nuclio.Response:
+		body = typedResponse.Body
+	case []byte:
+		body = typedResponse
+	case string:
+		body = []byte(typedResponse)
+	}
+
+	if len(body) == 0 {
+		return
+	}
+
+	// respond with the same kind of message
+	if err := connection.write(messageType, body); err != nil {
+		h.Logger.DebugWith("Failed to write response to websocket connection",
+			"connectionID", connection.id,
+			"err", err.Error())
+	}
+}
+
+func (h *http) addWebSocketConnection(connection *webSocketConnection) {
+	h.webSocketConnectionsLock.Lock()
+	defer h.webSocketConnectionsLock.Unlock()
+
+	h.webSocketConnections[connection.id] = connection
+}
+
+func (h *http) removeWebSocketConnection(connection *webSocketConnection) {
+	h.webSocketConnectionsLock.Lock()
+	defer h.webSocketConnectionsLock.Unlock()
+
+	delete(h.webSocketConnections, connection.id)
+}
+
+func (h *http) getWebSocketConnection(connectionID string) *webSocketConnection {
+	h.webSocketConnectionsLock.Lock()
+	defer h.webSocketConnectionsLock.Unlock()
+
+	return h.webSocketConnections[connectionID]
+}
+
+// startWebSocketPushHandler listens for messages that the handler pushes to connections
+func (h *http) startWebSocketPushHandler() error {
+
+	// pushes arrive through the workers' control communication, which not all runtimes support
+	for _, workerInstance := range h.WorkerAllocator.GetWorkers() {
+		if workerInstance.GetRuntime().GetControlMessageBroker() == nil {
+			h.Logger.Debug("Runtime doesn't support control communication, websocket pushes are disabled")
+			return nil
+		}
+	}
+
+	h.webSocketPushControlMessageChan = make(chan *controlcommunication.ControlMessage)
+	if err := h.SubscribeToControlMessageKind(controlcommunication.WebSocketPushKind,
+		h.webSocketPushControlMessageChan); err != nil {
+		return errors.Wrap(err, "Failed to subscribe to websocket push control messages")
+	}
+
+	go h.webSocketPushHandler(h.webSocketPushControlMessageChan)
+
+	return nil
+}
+
+func (h *http) webSocketPushHandler(controlMessageChan chan *controlcommunication.ControlMessage) {
+	for controlMessage := range controlMessageChan {
+		pushAttributes := &controlcommunication.ControlMessageAttributesWebSocketPush{}
+		if err := mapstructure.Decode(controlMessage.Attributes, pushAttributes); err != nil {
+			h.Logger.WarnWith("Failed decoding control message attributes", "err", err.Error())
+			continue
+		}
+
+		// the connection may have been closed, or belong to another trigger
+		connection := h.getWebSocketConnection(pushAttributes.ConnectionID)
+		if connection == nil {
+			h.Logger.DebugWith("Received push for an unknown websocket connection",
+				"connectionID", pushAttributes.ConnectionID)
+			continue
+		}
+
+		if err := connection.write(websocket.TextMessage, []byte(pushAttributes.Body)); err != nil {
+			h.Logger.DebugWith("Failed to push to websocket connection",
+				"connectionID", connection.id,
+				"err", err.Error())
+		}
+	}
+}
+
+// stopWebSocket stops listening for pushes and closes the open connections
+func (h *http) stopWebSocket() {
+	if h.webSocketPushControlMessageChan != nil {
+		if err := h.UnsubscribeFromControlMessageKind(controlcommunication.WebSocketPushKind,
+			h.webSocketPushControlMessageChan); err != nil {
+			h.Logger.WarnWith("Failed to unsubscribe from websocket push control messages", "err", err.Error())
+		}
+
+		close(h.webSocketPushControlMessageChan)
+		h.webSocketPushControlMessageChan = nil
+	}
+
+	h.webSocketConnectionsLock.Lock()
+	defer h.webSocketConnectionsLock.Unlock()
+
+	for _, connection := range h.webSocketConnections {
+		connection.conn.WriteControl(websocket.CloseMessage, // nolint: errcheck
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, ""),
+			time.Now().Add(time.Second))
+		connection.conn.Close() // nolint: errcheck
+	}
+}
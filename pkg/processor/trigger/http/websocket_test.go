@@ -0,0 +1,333 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package http
+
+import (
+	"fmt"
+	"net"
+	nethttp "net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common/headers"
+	"github.com/nuclio/nuclio/pkg/common/status"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/fasthttp/websocket"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttputil"
+)
+
+// mockRuntime responds with the message and what it knows about its connection
+type mockRuntime struct {
+	runtime.Runtime
+	index                int
+	controlMessageBroker controlcommunication.ControlMessageBroker
+}
+
+func (mr *mockRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	switch string(event.GetBody()) {
+	case "silent":
+		return nil, nil
+	case "fail":
+		return nil, errors.New("Failed to process message")
+	case "slow":
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return nuclio.Response{
+		Body: []byte(strings.Join([]string{
+			event.GetHeaderString(headers.WebSocketConnectionID),
+			event.GetPath(),
+			event.GetFieldString("name"),
+			event.GetContentType(),
+			fmt.Sprintf("worker%d", mr.index),
+			string(event.GetBody()),
+		}, ":")),
+	}, nil
+}
+
+func (mr *mockRuntime) GetControlMessageBroker() controlcommunication.ControlMessageBroker {
+	return mr.controlMessageBroker
+}
+
+type WebSocketTestSuite struct {
+	suite.Suite
+	logger               logger.Logger
+	listener             *fasthttputil.InmemoryListener
+	controlMessageBroker *controlcommunication.AbstractControlMessageBroker
+	trigger              *http
+}
+
+func (suite *WebSocketTestSuite) SetupSuite() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+}
+
+func (suite *WebSocketTestSuite) SetupTest() {
+	suite.controlMessageBroker = controlcommunication.NewAbstractControlMessageBroker()
+	suite.listener = fasthttputil.NewInmemoryListener()
+	suite.trigger = nil
+}
+
+func (suite *WebSocketTestSuite) TearDownTest() {
+	if suite.trigger != nil {
+		suite.trigger.stopWebSocket()
+	}
+
+	suite.listener.Close() // nolint: errcheck
+}
+
+func (suite *WebSocketTestSuite) TestMessages() {
+	suite.startTrigger(1, "1m")
+
+	connection := suite.dial("/ws?name=test")
+	connectionID := suite.readConnectionID(connection)
+
+	// responses are of the same kind as the message
+	suite.writeAndRead(connection,
+		websocket.BinaryMessage,
+		"bytes",
+		connectionID+":/ws:test:application/octet-stream:worker0:bytes")
+
+	// empty responses and failures aren't written, and the connection stays open
+	for _, message := range []string{"silent", "fail"} {
+		err := connection.WriteMessage(websocket.TextMessage, []byte(message))
+		suite.Require().NoError(err)
+	}
+
+	suite.writeAndRead(connection,
+		websocket.TextMessage,
+		"text",
+		connectionID+":/ws:test:text/plain:worker0:text")
+}
+
+func (suite *WebSocketTestSuite) TestWorkerAffinity() {
+	suite.startTrigger(2, "1m")
+
+	var workers []string
+	for connectionIdx := 0; connectionIdx < 2; connectionIdx++ {
+		connection := suite.dial("/ws")
+
+		// all of the connection's messages are handled by the same worker
+		var connectionWorkers []string
+		for messageIdx := 0; messageIdx < 3; messageIdx++ {
+			err := connection.WriteMessage(websocket.TextMessage, []byte("message"))
+			suite.Require().NoError(err)
+
+			_, response, err := connection.ReadMessage()
+			suite.Require().NoError(err)
+
+			connectionWorkers = append(connectionWorkers, strings.Split(string(response), ":")[4])
+		}
+
+		suite.Require().Equal([]string{connectionWorkers[0], connectionWorkers[0], connectionWorkers[0]},
+			connectionWorkers)
+		workers = append(workers, connectionWorkers[0])
+	}
+
+	suite.Require().ElementsMatch([]string{"worker0", "worker1"}, workers)
+
+	// each connection occupies a worker, so no more connections can be served
+	_, response, err := suite.createDialer().Dial("ws://test/ws", nil)
+	suite.Require().Error(err)
+	suite.Require().Equal(nethttp.StatusServiceUnavailable, response.StatusCode)
+}
+
+func (suite *WebSocketTestSuite) TestMessageOrder() {
+	suite.startTrigger(2, "1m")
+
+	connection := suite.dial("/ws")
+	connectionID := suite.readConnectionID(connection)
+
+	// a slow message delays the ones that follow it rather than being overtaken by them
+	messages := []string{"slow", "1", "2", "3"}
+	for _, message := range messages {
+		err := connection.WriteMessage(websocket.TextMessage, []byte(message))
+		suite.Require().NoError(err)
+	}
+
+	for _, message := range messages {
+		_, response, err := connection.ReadMessage()
+		suite.Require().NoError(err)
+		suite.Require().True(strings.HasSuffix(string(response), ":"+message))
+		suite.Require().True(strings.HasPrefix(string(response), connectionID))
+	}
+}
+
+func (suite *WebSocketTestSuite) TestPush() {
+	suite.startTrigger(1, "1m")
+
+	connection := suite.dial("/ws")
+	connectionID := suite.readConnectionID(connection)
+
+	for _, pushedConnectionID := range []string{"unknown", connectionID} {
+		err := suite.controlMessageBroker.SendToConsumers(&controlcommunication.ControlMessage{
+			Kind: controlcommunication.WebSocketPushKind,
+			Attributes: map[string]interface{}{
+				"connectionId": pushedConnectionID,
+				"body":         "pushed",
+			},
+		})
+		suite.Require().NoError(err)
+	}
+
+	messageType, message, err := connection.ReadMessage()
+	suite.Require().NoError(err)
+	suite.Require().Equal(websocket.TextMessage, messageType)
+	suite.Require().Equal("pushed", string(message))
+}
+
+func (suite *WebSocketTestSuite) TestIdleTimeout() {
+	suite.startTrigger(1, "200ms")
+
+	connection := suite.dial("/ws")
+
+	// pings keep the connection open
+	for pingIdx := 0; pingIdx < 3; pingIdx++ {
+		time.Sleep(100 * time.Millisecond)
+
+		err := connection.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second))
+		suite.Require().NoError(err)
+	}
+
+	suite.readConnectionID(connection)
+
+	// a message handled for longer than the idle timeout doesn't close the connection
+	err := connection.WriteMessage(websocket.TextMessage, []byte("slow"))
+	suite.Require().NoError(err)
+
+	_, response, err := connection.ReadMessage()
+	suite.Require().NoError(err)
+	suite.Require().True(strings.HasSuffix(string(response), ":slow"))
+
+	// the connection is closed once idle, releasing its worker for another connection
+	_, _, err = connection.ReadMessage()
+	suite.Require().Error(err)
+
+	suite.Require().Eventually(func() bool {
+		return suite.trigger.WorkerAllocator.GetNumWorkersAvailable() == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	suite.readConnectionID(suite.dial("/ws"))
+}
+
+func (suite *WebSocketTestSuite) startTrigger(numWorkers int, idleTimeout string) {
+	workerAvailabilityTimeoutMilliseconds := 100
+
+	configuration, err := NewConfiguration("test", &functionconfig.Trigger{
+		Kind:                                  "http",
+		WorkerAvailabilityTimeoutMilliseconds: &workerAvailabilityTimeoutMilliseconds,
+		Attributes: map[string]interface{}{
+			"webSocket": map[string]interface{}{
+				"paths":       []string{"/ws"},
+				"idleTimeout": idleTimeout,
+			},
+		},
+	}, &runtime.Configuration{
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{},
+		},
+	})
+	suite.Require().NoError(err)
+
+	var workers []*worker.Worker
+	for workerIdx := 0; workerIdx < numWorkers; workerIdx++ {
+		workerInstance, err := worker.NewWorker(suite.logger, workerIdx, &mockRuntime{
+			index:                workerIdx,
+			controlMessageBroker: suite.controlMessageBroker,
+		})
+		suite.Require().NoError(err)
+
+		workers = append(workers, workerInstance)
+	}
+
+	workerAllocator, err := worker.NewFixedPoolWorkerAllocator(suite.logger, workers)
+	suite.Require().NoError(err)
+
+	triggerInstance, err := newTrigger(suite.logger, workerAllocator, configuration, nil)
+	suite.Require().NoError(err)
+
+	suite.trigger = triggerInstance.(*http)
+	suite.trigger.status = status.Ready
+
+	err = suite.trigger.startWebSocketPushHandler()
+	suite.Require().NoError(err)
+
+	go fasthttp.Serve(suite.listener, suite.trigger.onRequestFromFastHTTP()) // nolint: errcheck
+}
+
+func (suite *WebSocketTestSuite) createDialer() *websocket.Dialer {
+	return &websocket.Dialer{
+		NetDial: func(network, addr string) (net.Conn, error) {
+			return suite.listener.Dial()
+		},
+	}
+}
+
+func (suite *WebSocketTestSuite) dial(path string) *websocket.Conn {
+	connection, _, err := suite.createDialer().Dial("ws://test"+path, nil)
+	suite.Require().NoError(err)
+
+	suite.T().Cleanup(func() {
+		connection.Close() // nolint: errcheck
+	})
+
+	return connection
+}
+
+func (suite *WebSocketTestSuite) readConnectionID(connection *websocket.Conn) string {
+	err := connection.WriteMessage(websocket.TextMessage, []byte("hello"))
+	suite.Require().NoError(err)
+
+	_, response, err := connection.ReadMessage()
+	suite.Require().NoError(err)
+
+	connectionID := strings.Split(string(response), ":")[0]
+	suite.Require().NotEmpty(connectionID)
+
+	return connectionID
+}
+
+func (suite *WebSocketTestSuite) writeAndRead(connection *websocket.Conn,
+	messageType int,
+	message string,
+	expectedResponse string) {
+
+	err := connection.WriteMessage(messageType, []byte(message))
+	suite.Require().NoError(err)
+
+	responseType, response, err := connection.ReadMessage()
+	suite.Require().NoError(err)
+	suite.Require().Equal(messageType, responseType)
+	suite.Require().Equal(expectedResponse, string(response))
+}
+
+func TestWebSocketSuite(t *testing.T) {
+	suite.Run(t, new(WebSocketTestSuite))
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor"
 	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/trigger/kafka/scram"
 	"github.com/nuclio/nuclio/pkg/processor/trigger/kafka/tokenprovider/oauth"
@@ -212,6 +213,10 @@ func (k *kafka) Cleanup(session sarama.ConsumerGroupSession) error {
 func (k *kafka) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
 	var submitError error
 
+	if functionconfig.BatchModeEnabled(k.configuration.Batch) {
+		return k.consumeClaimInBatches(session, claim)
+	}
+
 	submittedEventInstance := submittedEvent{
 		done: make(chan error),
 	}
@@ -245,9 +250,7 @@ consumptionLoop:
 		case message := <-claim.Messages():
 
 			// allocate a worker for this topic/partition
-			workerInstance, cookie, err := k.partitionWorkerAllocator.AllocateWorker(claim.Topic(),
-				int(claim.Partition()),
-				nil)
+			workerInstance, cookie, err := k.allocatePartitionWorker(claim)
 			if err != nil {
 				// If all workers are terminated, we don't want to stop consumption to avoid Kafka reconnection
 				// and give some time to the explicitAckHandler to process the last control messages.
@@ -323,6 +326,167 @@ consumptionLoop:
 	return submitError
 }
 
+func (k *kafka) consumeClaimInBatches(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	explicitAckControlMessageChan := make(chan *controlcommunication.ControlMessage)
+
+	// listen for explicit ack messages if enabled
+	if functionconfig.ExplicitAckEnabled(k.configuration.ExplicitAckMode) {
+		if err := k.SubscribeToControlMessageKind(controlcommunication.StreamMessageAckKind, explicitAckControlMessageChan); err != nil {
+			return errors.Wrap(err, "Failed to subscribe to explicit ack control messages")
+		}
+
+		go k.explicitAckHandler(session, explicitAckControlMessageChan, claim.Partition())
+	}
+
+	k.Logger.DebugWith("Starting claim consumption in batches",
+		"partition", claim.Partition(),
+		"batchSize", k.configuration.Batch.BatchSize,
+		"batchTimeout", k.configuration.BatchTimeout.String())
+
+	var consumptionErr error
+	for {
+		messages, stopConsumption := k.collectMessageBatch(session, claim)
+
+		// messages of an interrupted batch are not marked, and will be consumed again after rebalance
+		if stopConsumption {
+			break
+		}
+
+		if consumptionErr = k.submitMessageBatch(session, claim, messages); consumptionErr != nil {
+			break
+		}
+	}
+
+	k.Logger.DebugWith("Claim consumption in batches stopped", "partition", claim.Partition())
+
+	// unsubscribe channel from the streamAck control message kind before closing it
+	if err := k.UnsubscribeFromControlMessageKind(controlcommunication.StreamMessageAckKind, explicitAckControlMessageChan); err != nil {
+		k.Logger.WarnWith("Failed to unsubscribe channel from control message kind", "err", err)
+	}
+
+	close(explicitAckControlMessageChan)
+
+	return consumptionErr
+}
+
+// collectMessageBatch waits for a message and collects the messages following it, until either the batch is
+// full or the batch timeout has passed. returns true if consumption should stop
+func (k *kafka) collectMessageBatch(session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim) ([]*sarama.ConsumerMessage, bool) {
+	messages := make([]*sarama.ConsumerMessage, 0, k.configuration.Batch.BatchSize)
+
+	// wait for the first message of the batch
+	select {
+	case message, ok := <-claim.Messages():
+		if !ok {
+			return nil, true
+		}
+		messages = append(messages, message)
+	case <-session.Context().Done():
+		return nil, true
+	}
+
+	batchTimer := time.NewTimer(k.configuration.BatchTimeout)
+	defer batchTimer.Stop()
+
+	for len(messages) < k.configuration.Batch.BatchSize {
+		select {
+		case message, ok := <-claim.Messages():
+			if !ok {
+				return messages, true
+			}
+			messages = append(messages, message)
+		case <-batchTimer.C:
+			return messages, false
+		case <-session.Context().Done():
+			return messages, true
+		}
+	}
+
+	return messages, false
+}
+
+func (k *kafka) submitMessageBatch(session sarama.ConsumerGroupSession,
+	claim sarama.ConsumerGroupClaim,
+	messages []*sarama.ConsumerMessage) error {
+
+	// allocate a worker for this topic/partition
+	workerInstance, cookie, err := k.allocatePartitionWorker(claim)
+	if err != nil {
+		if errors.Is(err, worker.ErrAllWorkersAreTerminated) {
+			return nil
+		}
+		return errors.Wrap(err, "Failed to allocate worker")
+	}
+
+	batch := make([]nuclio.Event, 0, len(messages))
+	for _, message := range messages {
+		batch = append(batch, &Event{kafkaMessage: message})
+	}
+
+	type batchResult struct {
+		responses []*runtime.BatchEventResponse
+		err       error
+	}
+
+	// submit in a goroutine so that we can respond to rebalancing while the batch is being handled
+	batchResultChan := make(chan batchResult, 1)
+	go func() {
+		responses, err := k.SubmitEventBatchToWorker(nil, workerInstance, batch)
+		batchResultChan <- batchResult{responses, err}
+	}()
+
+	var result batchResult
+	select {
+	case result = <-batchResultChan:
+	case <-session.Context().Done():
+		k.Logger.DebugWith("Got signal to stop consumption while handling batch",
+			"wait", k.configuration.maxWaitHandlerDuringRebalance.String(),
+			"partition", claim.Partition())
+
+		select {
+		case result = <-batchResultChan:
+		case <-time.After(k.configuration.maxWaitHandlerDuringRebalance):
+			k.Logger.DebugWith("Timed out waiting for batch handling to complete",
+				"partition", claim.Partition())
+
+			if err := k.cancelEventHandling(workerInstance, claim); err != nil {
+				return errors.Wrap(err, "Failed to cancel batch handling")
+			}
+
+			if err := k.partitionWorkerAllocator.ReleaseWorker(cookie, workerInstance); err != nil {
+				return errors.Wrap(err, "Failed to release worker")
+			}
+
+			return nil
+		}
+	}
+
+	if result.err != nil {
+		k.Logger.DebugWith("Batch processing error",
+			"partition", claim.Partition(),
+			"batchSize", len(batch),
+			"err", result.err)
+	} else {
+
+		// mark the offset of each event that should be acked
+		for eventIndex, response := range result.responses {
+			if trigger.BatchEventShouldBeAcked(k.configuration.ExplicitAckMode, response) {
+				session.MarkOffset(messages[eventIndex].Topic,
+					messages[eventIndex].Partition,
+					messages[eventIndex].Offset+1-int64(k.configuration.ackWindowSize),
+					"")
+			}
+		}
+	}
+
+	if err := k.partitionWorkerAllocator.ReleaseWorker(cookie, workerInstance); err != nil {
+		return errors.Wrap(err, "Failed to release worker")
+	}
+
+	return nil
+}
+
 func (k *kafka) drainOnRebalance(session sarama.ConsumerGroupSession,
 	claim sarama.ConsumerGroupClaim,
 	workerInstance *worker.Worker,
@@ -463,6 +627,17 @@ func (k *kafka) eventSubmitter(claim sarama.ConsumerGroupClaim, submittedEventCh
 		"partition", claim.Partition())
 }
 
+// allocatePartitionWorker allocates a worker for the topic/partition of the claim
+func (k *kafka) allocatePartitionWorker(claim sarama.ConsumerGroupClaim) (*worker.Worker, interface{}, error) {
+	startTime := time.Now()
+	workerInstance, cookie, err := k.partitionWorkerAllocator.AllocateWorker(claim.Topic(),
+		int(claim.Partition()),
+		nil)
+	k.ObserveWorkerAllocation(time.Since(startTime), err == nil)
+
+	return workerInstance, cookie, err
+}
+
 func (k *kafka) cancelEventHandling(workerInstance *worker.Worker,
 	claim sarama.ConsumerGroupClaim) error {
 	if workerInstance.SupportsRestart() {
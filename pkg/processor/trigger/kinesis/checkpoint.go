@@ -0,0 +1,219 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kinesis
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	"github.com/nuclio/errors"
+)
+
+// the checkpoint of a shard that was read to its end
+const shardEndCheckpoint = "SHARD_END"
+
+var errLeaseConflict = errors.New("Lease was modified by another replica")
+
+// shardLease holds the checkpoint of a shard, and which replica reads it
+type shardLease struct {
+	ShardID string
+
+	// the sequence number of the last handled record, or shardEndCheckpoint
+	Checkpoint string
+
+	// the replica reading the shard, and until when. a lease whose expiration has passed may be
+	// taken by any replica
+	Owner           string
+	LeaseExpiration time.Time
+
+	// incremented on every update, so that concurrent updates are detected
+	LeaseCounter int64
+}
+
+func (sl *shardLease) isFinished() bool {
+	return sl.Checkpoint == shardEndCheckpoint
+}
+
+func (sl *shardLease) isOwned(now time.Time) bool {
+	return sl.Owner != "" && now.Before(sl.LeaseExpiration)
+}
+
+// checkpointStore persists shard leases
+type checkpointStore interface {
+
+	// getLeases returns the leases of all shards
+	getLeases() ([]*shardLease, error)
+
+	// putLease writes the lease if its counter in the store is still previousLeaseCounter (zero for a
+	// lease that doesn't exist yet), or returns errLeaseConflict
+	putLease(lease *shardLease, previousLeaseCounter int64) error
+}
+
+func newCheckpointStore(configuration *Configuration, dynamoDBClient dynamodbiface.DynamoDBAPI) checkpointStore {
+	switch configuration.CheckpointStore {
+	case CheckpointStoreKindDynamoDB:
+		return &dynamoDBCheckpointStore{
+			client:       dynamoDBClient,
+			tableName:    configuration.CheckpointTable,
+			consumerName: configuration.ConsumerName,
+		}
+	default:
+		return newMemoryCheckpointStore()
+	}
+}
+
+type memoryCheckpointStore struct {
+	leases     map[string]shardLease
+	leasesLock sync.Mutex
+}
+
+func newMemoryCheckpointStore() *memoryCheckpointStore {
+	return &memoryCheckpointStore{
+		leases: map[string]shardLease{},
+	}
+}
+
+func (mcs *memoryCheckpointStore) getLeases() ([]*shardLease, error) {
+	mcs.leasesLock.Lock()
+	defer mcs.leasesLock.Unlock()
+
+	var leases []*shardLease
+	for _, lease := range mcs.leases {
+		lease := lease
+		leases = append(leases, &lease)
+	}
+
+	return leases, nil
+}
+
+func (mcs *memoryCheckpointStore) putLease(lease *shardLease, previousLeaseCounter int64) error {
+	mcs.leasesLock.Lock()
+	defer mcs.leasesLock.Unlock()
+
+	if mcs.leases[lease.ShardID].LeaseCounter != previousLeaseCounter {
+		return errLeaseConflict
+	}
+
+	mcs.leases[lease.ShardID] = *lease
+	return nil
+}
+
+// dynamoDBCheckpointStore keeps the leases in a table whose partition key is "consumer" and sort key
+// is "shardId", both strings, so that multiple consumers can share a table
+type dynamoDBCheckpointStore struct {
+	client       dynamodbiface.DynamoDBAPI
+	tableName    string
+	consumerName string
+}
+
+func (dcs *dynamoDBCheckpointStore) getLeases() ([]*shardLease, error) {
+	var leases []*shardLease
+
+	queryInput := &dynamodb.QueryInput{
+		TableName:              aws.String(dcs.tableName),
+		KeyConditionExpression: aws.String("consumer = :consumer"),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":consumer": {S: aws.String(dcs.consumerName)},
+		},
+		ConsistentRead: aws.Bool(true),
+	}
+
+	if err := dcs.client.QueryPages(queryInput, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			leases = append(leases, dcs.itemToLease(item))
+		}
+		return true
+	}); err != nil {
+		return nil, errors.Wrapf(err, "Failed to query leases from table %s", dcs.tableName)
+	}
+
+	return leases, nil
+}
+
+func (dcs *dynamoDBCheckpointStore) putLease(lease *shardLease, previousLeaseCounter int64) error {
+	putItemInput := &dynamodb.PutItemInput{
+		TableName: aws.String(dcs.tableName),
+		Item:      dcs.leaseToItem(lease),
+	}
+
+	if previousLeaseCounter == 0 {
+		putItemInput.ConditionExpression = aws.String("attribute_not_exists(shardId)")
+	} else {
+		putItemInput.ConditionExpression = aws.String("leaseCounter = :previousLeaseCounter")
+		putItemInput.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":previousLeaseCounter": {N: aws.String(strconv.FormatInt(previousLeaseCounter, 10))},
+		}
+	}
+
+	if _, err := dcs.client.PutItem(putItemInput); err != nil {
+		if awsError, ok := err.(awserr.Error); ok &&
+			awsError.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+			return errLeaseConflict
+		}
+
+		return errors.Wrapf(err, "Failed to put lease of shard %s in table %s", lease.ShardID, dcs.tableName)
+	}
+
+	return nil
+}
+
+func (dcs *dynamoDBCheckpointStore) leaseToItem(lease *shardLease) map[string]*dynamodb.AttributeValue {
+	item := map[string]*dynamodb.AttributeValue{
+		"consumer":        {S: aws.String(dcs.consumerName)},
+		"shardId":         {S: aws.String(lease.ShardID)},
+		"leaseCounter":    {N: aws.String(strconv.FormatInt(lease.LeaseCounter, 10))},
+		"leaseExpiration": {N: aws.String(strconv.FormatInt(lease.LeaseExpiration.UnixMilli(), 10))},
+	}
+
+	// unset attributes are omitted
+	if lease.Checkpoint != "" {
+		item["checkpoint"] = &dynamodb.AttributeValue{S: aws.String(lease.Checkpoint)}
+	}
+	if lease.Owner != "" {
+		item["owner"] = &dynamodb.AttributeValue{S: aws.String(lease.Owner)}
+	}
+
+	return item
+}
+
+func (dcs *dynamoDBCheckpointStore) itemToLease(item map[string]*dynamodb.AttributeValue) *shardLease {
+	lease := &shardLease{}
+
+	if attributeValue, exists := item["shardId"]; exists {
+		lease.ShardID = aws.StringValue(attributeValue.S)
+	}
+	if attributeValue, exists := item["checkpoint"]; exists {
+		lease.Checkpoint = aws.StringValue(attributeValue.S)
+	}
+	if attributeValue, exists := item["owner"]; exists {
+		lease.Owner = aws.StringValue(attributeValue.S)
+	}
+	if attributeValue, exists := item["leaseCounter"]; exists {
+		lease.LeaseCounter, _ = strconv.ParseInt(aws.StringValue(attributeValue.N), 10, 64)
+	}
+	if attributeValue, exists := item["leaseExpiration"]; exists {
+		leaseExpirationMilliseconds, _ := strconv.ParseInt(aws.StringValue(attributeValue.N), 10, 64)
+		lease.LeaseExpiration = time.UnixMilli(leaseExpirationMilliseconds)
+	}
+
+	return lease
+}
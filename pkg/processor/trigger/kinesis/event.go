@@ -17,15 +17,53 @@ limitations under the License.
 package kinesis
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
+	awskinesis "github.com/aws/aws-sdk-go/service/kinesis"
 	"github.com/nuclio/nuclio-sdk-go"
 )
 
-// Event allows access to the event body
+// Event allows access to the Kinesis record
 type Event struct {
 	nuclio.AbstractEvent
-	body []byte
+	record  *awskinesis.Record
+	shardID string
 }
 
 func (e *Event) GetBody() []byte {
-	return e.body
+	return e.record.Data
+}
+
+func (e *Event) GetSize() int {
+	return len(e.record.Data)
+}
+
+// GetShardID returns the numeric part of the shard id (e.g. 3 for "shardId-000000000003")
+func (e *Event) GetShardID() int {
+	shardNumber, err := strconv.Atoi(e.shardID[strings.LastIndex(e.shardID, "-")+1:])
+	if err != nil {
+		return -1
+	}
+
+	return shardNumber
+}
+
+// GetTimestamp returns the approximate time at which the record was added to the stream
+func (e *Event) GetTimestamp() time.Time {
+	if e.record.ApproximateArrivalTimestamp == nil {
+		return time.Time{}
+	}
+
+	return *e.record.ApproximateArrivalTimestamp
+}
+
+// GetPath returns the partition key of the record
+func (e *Event) GetPath() string {
+	if e.record.PartitionKey == nil {
+		return ""
+	}
+
+	return *e.record.PartitionKey
 }
@@ -45,12 +45,18 @@ func (f *factory) Create(parentLogger logger.Logger,
 		return nil, errors.Wrap(err, "Failed to create configuration")
 	}
 
+	// shards share the workers. when shards are listed explicitly, each gets a worker as before
+	numWorkers := configuration.NumWorkers
+	if len(configuration.Shards) > numWorkers {
+		numWorkers = len(configuration.Shards)
+	}
+
 	// get or create worker allocator
 	workerAllocator, err := f.GetWorkerAllocator(triggerConfiguration.WorkerAllocatorName,
 		namedWorkerAllocators,
 		func() (worker.Allocator, error) {
 			return worker.WorkerFactorySingleton.CreateFixedPoolWorkerAllocator(triggerLogger,
-				len(configuration.Shards),
+				numWorkers,
 				runtimeConfiguration)
 		})
 
@@ -0,0 +1,476 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kinesis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+	awskinesis "github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// mockKinesisClient serves shards whose records have sequence numbers "1", "2", ... and whose iterators
+// are "<shard id>/<index of the next record>"
+type mockKinesisClient struct {
+	kinesisiface.KinesisAPI
+	shards                 []*awskinesis.Shard
+	records                map[string][][]byte
+	closedShards           map[string]bool
+	getShardIteratorInputs []*awskinesis.GetShardIteratorInput
+	lock                   sync.Mutex
+}
+
+func (mkc *mockKinesisClient) ListShards(input *awskinesis.ListShardsInput) (*awskinesis.ListShardsOutput, error) {
+
+	// return a shard per page
+	shardIndex := 0
+	if input.NextToken != nil {
+		shardIndex, _ = strconv.Atoi(*input.NextToken)
+	}
+
+	listShardsOutput := &awskinesis.ListShardsOutput{
+		Shards: mkc.shards[shardIndex : shardIndex+1],
+	}
+	if shardIndex+1 < len(mkc.shards) {
+		listShardsOutput.NextToken = aws.String(strconv.Itoa(shardIndex + 1))
+	}
+
+	return listShardsOutput, nil
+}
+
+func (mkc *mockKinesisClient) GetShardIterator(input *awskinesis.GetShardIteratorInput) (*awskinesis.GetShardIteratorOutput, error) {
+	mkc.lock.Lock()
+	defer mkc.lock.Unlock()
+
+	mkc.getShardIteratorInputs = append(mkc.getShardIteratorInputs, input)
+
+	recordIndex := 0
+	switch aws.StringValue(input.ShardIteratorType) {
+	case awskinesis.ShardIteratorTypeLatest:
+		recordIndex = len(mkc.records[*input.ShardId])
+	case awskinesis.ShardIteratorTypeAfterSequenceNumber:
+		recordIndex, _ = strconv.Atoi(*input.StartingSequenceNumber)
+	}
+
+	return &awskinesis.GetShardIteratorOutput{
+		ShardIterator: aws.String(fmt.Sprintf("%s/%d", *input.ShardId, recordIndex)),
+	}, nil
+}
+
+func (mkc *mockKinesisClient) GetRecords(input *awskinesis.GetRecordsInput) (*awskinesis.GetRecordsOutput, error) {
+	mkc.lock.Lock()
+	defer mkc.lock.Unlock()
+
+	shardID, recordIndexString, _ := strings.Cut(*input.ShardIterator, "/")
+	recordIndex, _ := strconv.Atoi(recordIndexString)
+
+	getRecordsOutput := &awskinesis.GetRecordsOutput{}
+	for index, data := range mkc.records[shardID][recordIndex:] {
+		getRecordsOutput.Records = append(getRecordsOutput.Records, &awskinesis.Record{
+			Data:           data,
+			SequenceNumber: aws.String(strconv.Itoa(recordIndex + index + 1)),
+			PartitionKey:   aws.String("key"),
+		})
+	}
+
+	if !mkc.closedShards[shardID] {
+		getRecordsOutput.NextShardIterator = aws.String(fmt.Sprintf("%s/%d", shardID, len(mkc.records[shardID])))
+	}
+
+	return getRecordsOutput, nil
+}
+
+func (mkc *mockKinesisClient) getGetShardIteratorInputs() []*awskinesis.GetShardIteratorInput {
+	mkc.lock.Lock()
+	defer mkc.lock.Unlock()
+
+	return mkc.getShardIteratorInputs
+}
+
+// mockRuntime records the bodies of the events it processes
+type mockRuntime struct {
+	runtime.Runtime
+	processedBodies []string
+	lock            sync.Mutex
+}
+
+func (mr *mockRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	mr.processedBodies = append(mr.processedBodies, string(event.GetBody()))
+	return nil, nil
+}
+
+func (mr *mockRuntime) getProcessedBodies() []string {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	return append([]string{}, mr.processedBodies...)
+}
+
+type mockDynamoDBClient struct {
+	dynamodbiface.DynamoDBAPI
+	items        []map[string]*dynamodb.AttributeValue
+	putItemInput *dynamodb.PutItemInput
+	putItemError error
+}
+
+func (mdc *mockDynamoDBClient) QueryPages(input *dynamodb.QueryInput,
+	handler func(*dynamodb.QueryOutput, bool) bool) error {
+	handler(&dynamodb.QueryOutput{Items: mdc.items}, true)
+	return nil
+}
+
+func (mdc *mockDynamoDBClient) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	mdc.putItemInput = input
+	return &dynamodb.PutItemOutput{}, mdc.putItemError
+}
+
+type TestSuite struct {
+	suite.Suite
+	logger          logger.Logger
+	kinesisClient   *mockKinesisClient
+	checkpointStore *memoryCheckpointStore
+	runtime         *mockRuntime
+	triggers        []*kinesis
+}
+
+func (suite *TestSuite) SetupSuite() {
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+}
+
+func (suite *TestSuite) SetupTest() {
+	suite.kinesisClient = &mockKinesisClient{
+		records:      map[string][][]byte{},
+		closedShards: map[string]bool{},
+	}
+	suite.checkpointStore = newMemoryCheckpointStore()
+	suite.runtime = &mockRuntime{}
+	suite.triggers = nil
+}
+
+func (suite *TestSuite) TearDownTest() {
+	for _, kinesisTrigger := range suite.triggers {
+		if kinesisTrigger.stop != nil {
+			kinesisTrigger.Stop(false) // nolint: errcheck
+		}
+	}
+}
+
+func (suite *TestSuite) TestReadsChildShardsAfterTheirParent() {
+	suite.kinesisClient.shards = []*awskinesis.Shard{
+		{ShardId: aws.String("shardId-000000000002"), ParentShardId: aws.String("shardId-000000000000")},
+		{ShardId: aws.String("shardId-000000000001"), ParentShardId: aws.String("shardId-000000000000")},
+		{ShardId: aws.String("shardId-000000000000")},
+	}
+	suite.kinesisClient.records["shardId-000000000000"] = [][]byte{[]byte("a"), []byte("b")}
+	suite.kinesisClient.records["shardId-000000000001"] = [][]byte{[]byte("c")}
+	suite.kinesisClient.records["shardId-000000000002"] = [][]byte{[]byte("d")}
+	suite.kinesisClient.closedShards["shardId-000000000000"] = true
+
+	// the parent was partially read before it was split
+	suite.Require().NoError(suite.checkpointStore.putLease(&shardLease{
+		ShardID:      "shardId-000000000000",
+		Checkpoint:   "0",
+		LeaseCounter: 1,
+	}, 0))
+
+	kinesisTrigger := suite.createTrigger(nil)
+	suite.Require().NoError(kinesisTrigger.Start(nil))
+
+	suite.Require().Eventually(func() bool {
+		return len(suite.runtime.getProcessedBodies()) == 4
+	}, 5*time.Second, 50*time.Millisecond)
+
+	processedBodies := suite.runtime.getProcessedBodies()
+	suite.Require().Equal([]string{"a", "b"}, processedBodies[:2])
+	suite.Require().ElementsMatch([]string{"c", "d"}, processedBodies[2:])
+
+	leases := suite.getLeases()
+	suite.Require().Equal(shardEndCheckpoint, leases["shardId-000000000000"].Checkpoint)
+	suite.Require().Empty(leases["shardId-000000000000"].Owner)
+	suite.Require().Equal("1", leases["shardId-000000000001"].Checkpoint)
+
+	// children of a read parent start at their beginning, though the configured iterator type is LATEST
+	for _, getShardIteratorInput := range suite.kinesisClient.getGetShardIteratorInputs() {
+		if *getShardIteratorInput.ShardId != "shardId-000000000000" {
+			suite.Require().Equal(awskinesis.ShardIteratorTypeTrimHorizon, *getShardIteratorInput.ShardIteratorType)
+		}
+	}
+}
+
+func (suite *TestSuite) TestResumesFromCheckpoint() {
+	suite.kinesisClient.shards = []*awskinesis.Shard{
+		{ShardId: aws.String("shardId-000000000000")},
+	}
+	suite.kinesisClient.records["shardId-000000000000"] = [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+
+	suite.Require().NoError(suite.checkpointStore.putLease(&shardLease{
+		ShardID:      "shardId-000000000000",
+		Checkpoint:   "2",
+		LeaseCounter: 1,
+	}, 0))
+
+	kinesisTrigger := suite.createTrigger(nil)
+	suite.Require().NoError(kinesisTrigger.Start(nil))
+
+	suite.Require().Eventually(func() bool {
+		return suite.getLeases()["shardId-000000000000"].Checkpoint == "3"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	suite.Require().Equal([]string{"c"}, suite.runtime.getProcessedBodies())
+
+	getShardIteratorInput := suite.kinesisClient.getGetShardIteratorInputs()[0]
+	suite.Require().Equal(awskinesis.ShardIteratorTypeAfterSequenceNumber, *getShardIteratorInput.ShardIteratorType)
+	suite.Require().Equal("2", *getShardIteratorInput.StartingSequenceNumber)
+}
+
+func (suite *TestSuite) TestReadsConfiguredShards() {
+	suite.kinesisClient.records["shard-1"] = [][]byte{[]byte("a")}
+	suite.kinesisClient.records["shard-2"] = [][]byte{[]byte("b")}
+
+	kinesisTrigger := suite.createTrigger([]string{"shard-1"})
+	kinesisTrigger.configuration.IteratorType = awskinesis.ShardIteratorTypeTrimHorizon
+	suite.Require().NoError(kinesisTrigger.Start(nil))
+
+	suite.Require().Eventually(func() bool {
+		return len(suite.runtime.getProcessedBodies()) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	suite.Require().Equal([]string{"a"}, suite.runtime.getProcessedBodies())
+	suite.Require().Len(suite.getLeases(), 1)
+}
+
+func (suite *TestSuite) TestBalancesShardsAcrossReplicas() {
+	for shardIndex := 0; shardIndex < 4; shardIndex++ {
+		suite.kinesisClient.shards = append(suite.kinesisClient.shards, &awskinesis.Shard{
+			ShardId: aws.String(fmt.Sprintf("shardId-00000000000%d", shardIndex)),
+		})
+	}
+
+	firstTrigger := suite.createTrigger(nil)
+	suite.Require().NoError(firstTrigger.Start(nil))
+	suite.Require().Equal(4, firstTrigger.getNumOwnedShards())
+
+	// the second replica takes shards from the first until they're even
+	secondTrigger := suite.createTrigger(nil)
+	suite.Require().NoError(secondTrigger.Start(nil))
+
+	suite.Require().Eventually(func() bool {
+		return firstTrigger.getNumOwnedShards() == 2 && secondTrigger.getNumOwnedShards() == 2
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// once a replica stops, the other takes over its shards
+	_, err := secondTrigger.Stop(false)
+	suite.Require().NoError(err)
+	suite.triggers = suite.triggers[:1]
+
+	for _, lease := range suite.getLeases() {
+		suite.Require().NotEqual(secondTrigger.replicaID, lease.Owner)
+	}
+
+	suite.Require().Eventually(func() bool {
+		return firstTrigger.getNumOwnedShards() == 4
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func (suite *TestSuite) TestDynamoDBCheckpointStore() {
+	dynamoDBClient := &mockDynamoDBClient{}
+	store := &dynamoDBCheckpointStore{
+		client:       dynamoDBClient,
+		tableName:    "checkpoints",
+		consumerName: "consumer",
+	}
+
+	lease := &shardLease{
+		ShardID:         "shardId-000000000000",
+		Checkpoint:      "1234",
+		Owner:           "replica",
+		LeaseExpiration: time.UnixMilli(time.Now().UnixMilli()),
+		LeaseCounter:    3,
+	}
+
+	// updating a lease is conditioned on its previous counter
+	suite.Require().NoError(store.putLease(lease, 2))
+	suite.Require().Equal("leaseCounter = :previousLeaseCounter", *dynamoDBClient.putItemInput.ConditionExpression)
+	suite.Require().Equal("2", *dynamoDBClient.putItemInput.ExpressionAttributeValues[":previousLeaseCounter"].N)
+	suite.Require().Equal("consumer", *dynamoDBClient.putItemInput.Item["consumer"].S)
+
+	// and creating one on its absence
+	suite.Require().NoError(store.putLease(lease, 0))
+	suite.Require().Equal("attribute_not_exists(shardId)", *dynamoDBClient.putItemInput.ConditionExpression)
+
+	dynamoDBClient.items = []map[string]*dynamodb.AttributeValue{dynamoDBClient.putItemInput.Item}
+	leases, err := store.getLeases()
+	suite.Require().NoError(err)
+	suite.Require().Equal([]*shardLease{lease}, leases)
+
+	dynamoDBClient.putItemError = awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "Conflict", nil)
+	suite.Require().Equal(errLeaseConflict, store.putLease(lease, 2))
+}
+
+func (suite *TestSuite) TestNewConfiguration() {
+	for _, testCase := range []struct {
+		name          string
+		attributes    map[string]interface{}
+		expectedError bool
+	}{
+		{
+			name:       "defaults",
+			attributes: map[string]interface{}{"streamName": "stream"},
+		},
+		{
+			name: "dynamodb",
+			attributes: map[string]interface{}{
+				"streamName":      "stream",
+				"checkpointStore": "dynamodb",
+				"checkpointTable": "checkpoints",
+				"leaseDuration":   "1m",
+			},
+		},
+		{
+			name: "dynamodbWithoutTable",
+			attributes: map[string]interface{}{
+				"streamName":      "stream",
+				"checkpointStore": "dynamodb",
+			},
+			expectedError: true,
+		},
+		{
+			name: "unsupportedStore",
+			attributes: map[string]interface{}{
+				"streamName":      "stream",
+				"checkpointStore": "etcd",
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalidLeaseDuration",
+			attributes: map[string]interface{}{
+				"streamName":    "stream",
+				"leaseDuration": "forever",
+			},
+			expectedError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			configuration, err := NewConfiguration("id", &functionconfig.Trigger{
+				Kind:       "kinesis",
+				Attributes: testCase.attributes,
+			}, suite.createRuntimeConfiguration())
+			if testCase.expectedError {
+				suite.Require().Error(err)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().Equal("namespace-function-id", configuration.ConsumerName)
+		})
+	}
+}
+
+func (suite *TestSuite) createTrigger(shards []string) *kinesis {
+	configuration, err := NewConfiguration("id", &functionconfig.Trigger{
+		Kind: "kinesis",
+		Attributes: map[string]interface{}{
+			"streamName":    "stream",
+			"shards":        shards,
+			"pollingPeriod": "10ms",
+			"leaseDuration": "300ms",
+		},
+	}, suite.createRuntimeConfiguration())
+	suite.Require().NoError(err)
+
+	workerInstance, err := worker.NewWorker(suite.logger, 0, suite.runtime)
+	suite.Require().NoError(err)
+
+	workerAllocator, err := worker.NewFixedPoolWorkerAllocator(suite.logger, []*worker.Worker{workerInstance})
+	suite.Require().NoError(err)
+
+	abstractTrigger, err := trigger.NewAbstractTrigger(suite.logger,
+		workerAllocator,
+		&configuration.Configuration,
+		"async",
+		"kinesis",
+		"kinesis",
+		nil)
+	suite.Require().NoError(err)
+
+	kinesisTrigger := &kinesis{
+		AbstractTrigger: abstractTrigger,
+		configuration:   configuration,
+		kinesisClient:   suite.kinesisClient,
+		checkpointStore: suite.checkpointStore,
+		replicaID:       resolveReplicaID(),
+		ownedShards:     map[string]*shard{},
+	}
+	kinesisTrigger.AbstractTrigger.Trigger = kinesisTrigger
+
+	suite.triggers = append(suite.triggers, kinesisTrigger)
+	return kinesisTrigger
+}
+
+func (suite *TestSuite) createRuntimeConfiguration() *runtime.Configuration {
+	return &runtime.Configuration{
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{
+				Meta: functionconfig.Meta{
+					Name:      "function",
+					Namespace: "namespace",
+				},
+			},
+		},
+	}
+}
+
+func (suite *TestSuite) getLeases() map[string]*shardLease {
+	leases, err := suite.checkpointStore.getLeases()
+	suite.Require().NoError(err)
+
+	leasesByShardID := map[string]*shardLease{}
+	for _, lease := range leases {
+		leasesByShardID[lease.ShardID] = lease
+	}
+
+	return leasesByShardID
+}
+
+func TestKinesisSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
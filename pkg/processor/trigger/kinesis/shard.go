@@ -18,152 +18,277 @@ package kinesis
 
 import (
 	"fmt"
-	"strings"
+	"sync"
 	"time"
 
-	"github.com/nuclio/nuclio/pkg/processor/worker"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	awskinesis "github.com/aws/aws-sdk-go/service/kinesis"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
-	kinesisclient "github.com/sendgridlabs/go-kinesis"
+	"github.com/nuclio/nuclio-sdk-go"
 )
 
-var errIteratorExpired = errors.New("IteratorExpired")
-
 type shard struct {
 	logger         logger.Logger
 	kinesisTrigger *kinesis
 	shardID        string
-	worker         *worker.Worker
-}
 
-func newShard(parentLogger logger.Logger, kinesisTrigger *kinesis, shardID string) (*shard, error) {
-	var err error
+	// the lease this replica holds on the shard, as last written to the store
+	lease     *shardLease
+	leaseLock sync.Mutex
 
-	newShard := &shard{
-		logger:         parentLogger.GetChild(fmt.Sprintf("shard-%s", shardID)),
-		kinesisTrigger: kinesisTrigger,
-		shardID:        shardID,
-	}
+	// where to start reading when the shard has no checkpoint
+	initialIteratorType string
 
-	newShard.worker, err = kinesisTrigger.WorkerAllocator.Allocate(0)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to allocate worker")
-	}
+	stop     chan struct{}
+	stopOnce sync.Once
+}
 
-	return newShard, nil
+func newShard(parentLogger logger.Logger,
+	kinesisTrigger *kinesis,
+	lease *shardLease,
+	initialIteratorType string) *shard {
+	return &shard{
+		logger:              parentLogger.GetChild(fmt.Sprintf("shard-%s", lease.ShardID)),
+		kinesisTrigger:      kinesisTrigger,
+		shardID:             lease.ShardID,
+		lease:               lease,
+		initialIteratorType: initialIteratorType,
+		stop:                make(chan struct{}),
+	}
 }
 
-func (s *shard) readFromShard() error {
+func (s *shard) readFromShard() {
 	var err error
 
+	// start where the previous owner of the shard stopped
+	lastRecordSequenceNumber := s.getLease().Checkpoint
+
 	s.logger.DebugWith("Starting to read from shard",
 		"pollingPeriod", s.kinesisTrigger.configuration.PollingPeriod,
-		"iteratorType", s.kinesisTrigger.configuration.IteratorType)
+		"iteratorType", s.initialIteratorType,
+		"checkpoint", lastRecordSequenceNumber)
 
-	// prepare args for get records
-	getRecordArgs := kinesisclient.NewArgs()
+	var shardIterator *string
 
-	var getRecordsResponse *kinesisclient.GetRecordsResp
-	lastRecordSequenceNumber := ""
+	// when batching, events are accumulated across polls until the batch is full or the batch timeout has passed
+	batchingEnabled := functionconfig.BatchModeEnabled(s.kinesisTrigger.configuration.Batch)
+	var pendingEvents []nuclio.Event
+	var pendingSince time.Time
 
-	for {
+	for !s.isStopped() {
 
-		// get next records
-		getRecordsResponse, err = s.getNextRecords(getRecordArgs, getRecordsResponse, lastRecordSequenceNumber)
+		// create an iterator after the last handled record
+		if shardIterator == nil {
+			shardIterator, err = s.getShardIterator(lastRecordSequenceNumber)
+			if err != nil {
+				s.logger.WarnWith("Failed to get shard iterator", "err", errors.GetErrorStackString(err, 5))
+				s.wait(s.kinesisTrigger.configuration.pollingPeriodDuration)
+				continue
+			}
+		}
 
+		getRecordsOutput, err := s.kinesisTrigger.kinesisClient.GetRecords(&awskinesis.GetRecordsInput{
+			ShardIterator: shardIterator,
+		})
 		if err != nil {
 
-			// if there was an error other than iterator expired, wait a bit
-			if err != errIteratorExpired {
-				s.logger.WarnWith("Failed to get next records", "err", errors.GetErrorStackString(err, 5))
-				time.Sleep(s.kinesisTrigger.configuration.pollingPeriodDuration)
+			// an expired iterator is recreated after the last handled record right away. pending events
+			// are read again
+			if awsError, ok := err.(awserr.Error); ok && awsError.Code() == awskinesis.ErrCodeExpiredIteratorException {
+				shardIterator = nil
+				pendingEvents = nil
+				continue
 			}
 
+			s.logger.WarnWith("Failed to get next records", "err", errors.GetErrorStackString(err, 5))
+			s.wait(s.kinesisTrigger.configuration.pollingPeriodDuration)
 			continue
 		}
 
-		// if we got records, handle them
-		if len(getRecordsResponse.Records) > 0 {
-			for _, record := range getRecordsResponse.Records {
-				event := Event{
-					body: record.Data,
+		handledRecordSequenceNumber := lastRecordSequenceNumber
+		allRecordsHandled := true
+
+		if batchingEnabled {
+			for _, record := range getRecordsOutput.Records {
+				if len(pendingEvents) == 0 {
+					pendingSince = time.Now()
 				}
 
-				// process the event, don't really do anything with response
-				s.kinesisTrigger.SubmitEventToWorker(nil, s.worker, &event) // nolint: errcheck
+				pendingEvents = append(pendingEvents, s.createEvent(record))
 			}
 
-			// save last sequence number in the batch. we might need to create a shard iterator at this
-			// sequence number
-			lastRecordSequenceNumber = getRecordsResponse.Records[len(getRecordsResponse.Records)-1].SequenceNumber
+			// a closed shard has no more records to wait for
+			flush := getRecordsOutput.NextShardIterator == nil
+			pendingEvents, handledRecordSequenceNumber, allRecordsHandled = s.submitPendingEvents(pendingEvents,
+				pendingSince,
+				flush,
+				handledRecordSequenceNumber)
 
+			// the records that weren't submitted are read again
+			if !allRecordsHandled {
+				pendingEvents = nil
+			}
 		} else {
-			time.Sleep(s.kinesisTrigger.configuration.pollingPeriodDuration)
+			handledRecordSequenceNumber, allRecordsHandled = s.submitRecords(getRecordsOutput.Records,
+				handledRecordSequenceNumber)
+		}
+
+		// checkpoint the handled records
+		if handledRecordSequenceNumber != lastRecordSequenceNumber {
+			if err := s.kinesisTrigger.checkpointShard(s, handledRecordSequenceNumber); err != nil {
+				if err == errLeaseConflict {
+					s.logger.InfoWith("Shard lease was taken by another replica, stopping")
+					s.kinesisTrigger.disownShard(s)
+					return
+				}
+
+				s.logger.WarnWith("Failed to checkpoint shard", "err", errors.GetErrorStackString(err, 5))
+			}
+
+			lastRecordSequenceNumber = handledRecordSequenceNumber
+		}
+
+		if !allRecordsHandled {
+			shardIterator = nil
+			s.wait(s.kinesisTrigger.configuration.pollingPeriodDuration)
+			continue
+		}
+
+		// the shard was closed by a split or merge and was read to its end
+		if getRecordsOutput.NextShardIterator == nil {
+			s.logger.InfoWith("Finished reading closed shard")
+
+			if err := s.kinesisTrigger.finishShard(s); err != nil {
+				s.logger.WarnWith("Failed to mark shard as finished", "err", errors.GetErrorStackString(err, 5))
+			}
+			return
+		}
+
+		shardIterator = getRecordsOutput.NextShardIterator
+
+		if len(getRecordsOutput.Records) == 0 {
+			s.wait(s.kinesisTrigger.configuration.pollingPeriodDuration)
 		}
 	}
 }
 
-func (s *shard) getNextRecords(getRecordArgs *kinesisclient.RequestArgs,
-	getRecordsResponse *kinesisclient.GetRecordsResp,
-	lastRecordSequenceNumber string) (*kinesisclient.GetRecordsResp, error) {
+// submitRecords submits records one by one, stopping at the first record that couldn't be submitted.
+// returns the sequence number of the last submitted record, and whether all were submitted
+func (s *shard) submitRecords(records []*awskinesis.Record, lastRecordSequenceNumber string) (string, bool) {
+	for _, record := range records {
+
+		// process the event, don't really do anything with response
+		_, submitError, _ := s.kinesisTrigger.AllocateWorkerAndSubmitEvent(s.createEvent(record),
+			nil,
+			s.kinesisTrigger.getWorkerAvailabilityTimeout())
+		if submitError != nil {
+			s.logger.WarnWith("Failed to submit record", "err", submitError.Error())
+			return lastRecordSequenceNumber, false
+		}
 
-	// get the shard iterator
-	shardIterator, err := s.getShardIterator(getRecordsResponse, lastRecordSequenceNumber)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to get shard iterator")
+		lastRecordSequenceNumber = aws.StringValue(record.SequenceNumber)
 	}
 
-	// set shard iterator
-	getRecordArgs.Add("ShardIterator", shardIterator)
+	return lastRecordSequenceNumber, true
+}
 
-	// try to get records
-	getRecordsResponse, err = s.kinesisTrigger.kinesisClient.GetRecords(getRecordArgs)
-	if err != nil {
+// submitPendingEvents submits full batches out of the pending events, as well as the remaining events if the
+// batch timeout has passed or flush is set. returns the events that are still pending, the sequence number of
+// the last submitted event, and whether all batches were submitted
+func (s *shard) submitPendingEvents(pendingEvents []nuclio.Event,
+	pendingSince time.Time,
+	flush bool,
+	lastRecordSequenceNumber string) ([]nuclio.Event, string, bool) {
+	batchSize := s.kinesisTrigger.configuration.Batch.BatchSize
+
+	for len(pendingEvents) >= batchSize ||
+		(len(pendingEvents) > 0 && (flush || time.Since(pendingSince) >= s.kinesisTrigger.configuration.BatchTimeout)) {
+
+		currentBatchSize := batchSize
+		if len(pendingEvents) < currentBatchSize {
+			currentBatchSize = len(pendingEvents)
+		}
 
-		// if the error denotes an expired iterator, force recreation of iterator by nullifying the
-		// records response. getShardIterator() will produce an iterator based off of the last successful
-		// read sequence number
-		if strings.Contains(err.Error(), "Iterator expired") {
-			return nil, errIteratorExpired
+		// process the batch, don't really do anything with responses
+		if _, err := s.kinesisTrigger.AllocateWorkerAndSubmitEventBatch(pendingEvents[:currentBatchSize],
+			nil,
+			s.kinesisTrigger.getWorkerAvailabilityTimeout()); err != nil {
+			s.logger.WarnWith("Failed to submit batch", "batchSize", currentBatchSize, "err", err.Error())
+			return pendingEvents, lastRecordSequenceNumber, false
 		}
 
-		return nil, errors.Wrap(err, "Failed to get records")
+		lastRecordSequenceNumber = aws.StringValue(pendingEvents[currentBatchSize-1].(*Event).record.SequenceNumber)
+		pendingEvents = pendingEvents[currentBatchSize:]
 	}
 
-	return getRecordsResponse, nil
+	return pendingEvents, lastRecordSequenceNumber, true
 }
 
-func (s *shard) getShardIterator(lastRecordsResponse *kinesisclient.GetRecordsResp,
-	lastRecordSequenceNumber string) (string, error) {
-
-	// if there's a response we need to create the iterator from, use that first
-	if lastRecordsResponse != nil {
-		return lastRecordsResponse.NextShardIterator, nil
+func (s *shard) getShardIterator(lastRecordSequenceNumber string) (*string, error) {
+	getShardIteratorInput := &awskinesis.GetShardIteratorInput{
+		StreamName: aws.String(s.kinesisTrigger.configuration.StreamName),
+		ShardId:    aws.String(s.shardID),
 	}
 
-	getShardIteratorArgs := kinesisclient.NewArgs()
-	getShardIteratorArgs.Add("StreamName", s.kinesisTrigger.configuration.StreamName)
-	getShardIteratorArgs.Add("ShardId", s.shardID)
-
 	if lastRecordSequenceNumber == "" {
 
-		// if there's no records response and no record sequence number, this must be the first time. use
-		// the iterator type specified in the configuration
-		getShardIteratorArgs.Add("ShardIteratorType", s.kinesisTrigger.configuration.IteratorType)
-		s.logger.DebugWith("Creating initial iterator", "type", s.kinesisTrigger.configuration.IteratorType)
+		// if there's no record sequence number, this must be the first time the shard is read. use
+		// the initial iterator type
+		getShardIteratorInput.ShardIteratorType = aws.String(s.initialIteratorType)
+		s.logger.DebugWith("Creating initial iterator", "type", s.initialIteratorType)
 	} else {
 
 		// if a sequence number was passed, get a shard iterator at that point
-		getShardIteratorArgs.Add("ShardIteratorType", "AFTER_SEQUENCE_NUMBER")
-		getShardIteratorArgs.Add("StartingSequenceNumber", lastRecordSequenceNumber)
+		getShardIteratorInput.ShardIteratorType = aws.String(awskinesis.ShardIteratorTypeAfterSequenceNumber)
+		getShardIteratorInput.StartingSequenceNumber = aws.String(lastRecordSequenceNumber)
 		s.logger.DebugWith("Creating iterator at sequence", "seq", lastRecordSequenceNumber)
 	}
 
-	getShardIteratorResponse, err := s.kinesisTrigger.kinesisClient.GetShardIterator(getShardIteratorArgs)
+	getShardIteratorOutput, err := s.kinesisTrigger.kinesisClient.GetShardIterator(getShardIteratorInput)
 	if err != nil {
-		return "", errors.Wrap(err, "Failed to get shard iterator")
+		return nil, errors.Wrap(err, "Failed to get shard iterator")
+	}
+
+	return getShardIteratorOutput.ShardIterator, nil
+}
+
+func (s *shard) createEvent(record *awskinesis.Record) *Event {
+	return &Event{
+		record:  record,
+		shardID: s.shardID,
 	}
+}
+
+func (s *shard) getLease() *shardLease {
+	s.leaseLock.Lock()
+	defer s.leaseLock.Unlock()
+
+	return s.lease
+}
 
-	return getShardIteratorResponse.ShardIterator, nil
+func (s *shard) signalStop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+func (s *shard) isStopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait sleeps for the given duration, or until the shard is stopped
+func (s *shard) wait(duration time.Duration) {
+	select {
+	case <-time.After(duration):
+	case <-s.stop:
+	}
 }
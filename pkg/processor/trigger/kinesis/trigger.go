@@ -17,22 +17,46 @@ limitations under the License.
 package kinesis
 
 import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
 	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	awskinesis "github.com/aws/aws-sdk-go/service/kinesis"
+	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
-	kinesisclient "github.com/sendgridlabs/go-kinesis"
 )
 
 type kinesis struct {
 	trigger.AbstractTrigger
-	configuration *Configuration
-	kinesisAuth   kinesisclient.Auth
-	kinesisClient kinesisclient.KinesisClient
-	shards        []*shard
+	configuration   *Configuration
+	kinesisClient   kinesisiface.KinesisAPI
+	checkpointStore checkpointStore
+
+	// identifies this replica as the owner of shard leases
+	replicaID string
+
+	// the shards whose leases this replica holds, by shard id
+	ownedShards     map[string]*shard
+	ownedShardsLock sync.Mutex
+
+	// the shards of the stream, as last discovered
+	streamShards       []*awskinesis.Shard
+	lastShardDiscovery time.Time
+
+	stop            chan struct{}
+	coordinatorDone chan struct{}
+	shardsWaitGroup sync.WaitGroup
 }
 
 func newTrigger(parentLogger logger.Logger,
@@ -55,33 +79,36 @@ func newTrigger(parentLogger logger.Logger,
 	newTrigger := &kinesis{
 		AbstractTrigger: abstractTrigger,
 		configuration:   configuration,
+		replicaID:       resolveReplicaID(),
+		ownedShards:     map[string]*shard{},
 	}
 	newTrigger.AbstractTrigger.Trigger = newTrigger
-	newTrigger.kinesisAuth = kinesisclient.NewAuth(configuration.AccessKeyID,
-		configuration.SecretAccessKey,
-		"")
 
-	switch endpoint := configuration.URL; {
-	case endpoint != "":
-		newTrigger.kinesisClient = kinesisclient.NewWithEndpoint(newTrigger.kinesisAuth,
-			configuration.RegionName,
-			endpoint)
-	default:
-		newTrigger.kinesisClient = kinesisclient.New(newTrigger.kinesisAuth, configuration.RegionName)
+	awsConfig := &aws.Config{
+		Region: aws.String(configuration.RegionName),
+	}
+	if configuration.AccessKeyID != "" {
+		awsConfig.Credentials = credentials.NewStaticCredentials(configuration.AccessKeyID,
+			configuration.SecretAccessKey,
+			"")
 	}
 
-	// iterate over shards and create
-	for _, shardID := range configuration.Shards {
+	awsSession, err := session.NewSession(awsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create AWS session")
+	}
 
-		// create the shard
-		shard, err := newShard(newTrigger.Logger, newTrigger, shardID)
-		if err != nil {
-			return nil, errors.Wrap(err, "Failed to create shard")
-		}
+	kinesisConfig := aws.NewConfig()
+	if configuration.URL != "" {
+		kinesisConfig = kinesisConfig.WithEndpoint(configuration.URL)
+	}
+	newTrigger.kinesisClient = awskinesis.New(awsSession, kinesisConfig)
 
-		// add shard
-		newTrigger.shards = append(newTrigger.shards, shard)
+	dynamoDBConfig := aws.NewConfig()
+	if configuration.CheckpointStoreURL != "" {
+		dynamoDBConfig = dynamoDBConfig.WithEndpoint(configuration.CheckpointStoreURL)
 	}
+	newTrigger.checkpointStore = newCheckpointStore(configuration, dynamodb.New(awsSession, dynamoDBConfig))
 
 	return newTrigger, nil
 }
@@ -89,27 +116,411 @@ func newTrigger(parentLogger logger.Logger,
 func (k *kinesis) Start(checkpoint functionconfig.Checkpoint) error {
 	k.Logger.InfoWith("Starting",
 		"streamName", k.configuration.StreamName,
-		"shards", k.configuration.Shards)
+		"shards", k.configuration.Shards,
+		"checkpointStore", k.configuration.CheckpointStore,
+		"consumerName", k.configuration.ConsumerName,
+		"replicaID", k.replicaID)
 
-	for _, shardInstance := range k.shards {
+	k.stop = make(chan struct{})
+	k.coordinatorDone = make(chan struct{})
 
-		// start reading from shard
-		go func(shardInstance *shard) {
-			if err := shardInstance.readFromShard(); err != nil {
-				k.Logger.ErrorWith("Failed to read from shard", "err", err)
-			}
-		}(shardInstance)
+	// take the first leases right away, rather than after the first renewal interval
+	if err := k.balanceLeases(); err != nil {
+		k.Logger.WarnWith("Failed to balance shard leases", "err", errors.GetErrorStackString(err, 5))
 	}
 
+	go k.coordinate()
+
 	return nil
 }
 
 func (k *kinesis) Stop(force bool) (functionconfig.Checkpoint, error) {
+	close(k.stop)
+	<-k.coordinatorDone
+
+	// stop reading and hand the shards over to the other replicas
+	k.ownedShardsLock.Lock()
+	for _, shardInstance := range k.ownedShards {
+		shardInstance.signalStop()
+	}
+	k.ownedShardsLock.Unlock()
+
+	k.shardsWaitGroup.Wait()
 
-	// TODO
+	for _, shardInstance := range k.ownedShards {
+		if err := k.releaseLease(shardInstance); err != nil {
+			k.Logger.WarnWith("Failed to release shard lease",
+				"shardID", shardInstance.shardID,
+				"err", err.Error())
+		}
+	}
+
+	k.ownedShards = map[string]*shard{}
 	return nil, nil
 }
 
 func (k *kinesis) GetConfig() map[string]interface{} {
 	return common.StructureToMap(k.configuration)
 }
+
+// coordinate periodically renews the leases this replica holds, and takes leases of shards that aren't
+// read or that are read by replicas holding more than their share
+func (k *kinesis) coordinate() {
+	defer close(k.coordinatorDone)
+
+	ticker := time.NewTicker(k.configuration.leaseDuration / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			k.renewLeases()
+
+			if err := k.balanceLeases(); err != nil {
+				k.Logger.WarnWith("Failed to balance shard leases", "err", errors.GetErrorStackString(err, 5))
+			}
+
+		case <-k.stop:
+			return
+		}
+	}
+}
+
+func (k *kinesis) balanceLeases() error {
+	streamShards, err := k.getStreamShards()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get stream shards")
+	}
+
+	leases, err := k.checkpointStore.getLeases()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get shard leases")
+	}
+
+	leasesByShardID := map[string]*shardLease{}
+	for _, lease := range leases {
+		leasesByShardID[lease.ShardID] = lease
+	}
+
+	// create the leases of new shards. if another replica created one first, it'll be considered next time
+	for _, streamShard := range streamShards {
+		shardID := aws.StringValue(streamShard.ShardId)
+		if _, exists := leasesByShardID[shardID]; exists {
+			continue
+		}
+
+		lease := &shardLease{
+			ShardID:      shardID,
+			LeaseCounter: 1,
+		}
+		if err := k.checkpointStore.putLease(lease, 0); err != nil {
+			if err != errLeaseConflict {
+				return errors.Wrapf(err, "Failed to create lease of shard %s", shardID)
+			}
+			continue
+		}
+
+		leasesByShardID[shardID] = lease
+	}
+
+	readableLeases := k.getReadableLeases(streamShards, leasesByShardID)
+	if len(readableLeases) == 0 {
+		return nil
+	}
+
+	// each replica that owns leases (including this one) should read an equal share of the shards
+	now := time.Now()
+	leasesByOwner := map[string][]*shardLease{
+		k.replicaID: {},
+	}
+	for _, lease := range readableLeases {
+		if lease.isOwned(now) {
+			leasesByOwner[lease.Owner] = append(leasesByOwner[lease.Owner], lease)
+		}
+	}
+
+	targetNumLeases := (len(readableLeases) + len(leasesByOwner) - 1) / len(leasesByOwner)
+	numOwnedLeases := k.getNumOwnedShards()
+
+	// take leases that no one owns
+	for _, lease := range readableLeases {
+		if numOwnedLeases >= targetNumLeases {
+			break
+		}
+
+		if lease.isOwned(now) {
+			continue
+		}
+
+		if err := k.acquireLease(lease, streamShards); err != nil {
+			k.Logger.DebugWith("Failed to acquire shard lease", "shardID", lease.ShardID, "err", err.Error())
+			continue
+		}
+
+		numOwnedLeases++
+	}
+
+	// take a lease from the replica that holds the most, one at a time so that replicas converge gradually
+	if numOwnedLeases < targetNumLeases {
+		var mostLoadedOwnerLeases []*shardLease
+		for owner, ownerLeases := range leasesByOwner {
+			if owner != k.replicaID && len(ownerLeases) > len(mostLoadedOwnerLeases) {
+				mostLoadedOwnerLeases = ownerLeases
+			}
+		}
+
+		if len(mostLoadedOwnerLeases) > targetNumLeases {
+			lease := mostLoadedOwnerLeases[0]
+
+			k.Logger.InfoWith("Taking shard lease from another replica",
+				"shardID", lease.ShardID,
+				"owner", lease.Owner)
+
+			if err := k.acquireLease(lease, streamShards); err != nil {
+				k.Logger.DebugWith("Failed to take shard lease", "shardID", lease.ShardID, "err", err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// getReadableLeases returns the leases of shards that weren't read to their end, and whose parents were.
+// records of a key are thus handled in order across splits and merges
+func (k *kinesis) getReadableLeases(streamShards []*awskinesis.Shard,
+	leasesByShardID map[string]*shardLease) []*shardLease {
+
+	streamShardIDs := map[string]bool{}
+	for _, streamShard := range streamShards {
+		streamShardIDs[aws.StringValue(streamShard.ShardId)] = true
+	}
+
+	var readableLeases []*shardLease
+	for _, streamShard := range streamShards {
+		lease, exists := leasesByShardID[aws.StringValue(streamShard.ShardId)]
+		if !exists || lease.isFinished() {
+			continue
+		}
+
+		parentsFinished := true
+		for _, parentShardID := range k.getParentShardIDs(streamShard) {
+
+			// parents that aren't in the stream anymore have expired, and there's nothing left to read from them
+			if !streamShardIDs[parentShardID] {
+				continue
+			}
+
+			if parentLease, exists := leasesByShardID[parentShardID]; !exists || !parentLease.isFinished() {
+				parentsFinished = false
+				break
+			}
+		}
+
+		if parentsFinished {
+			readableLeases = append(readableLeases, lease)
+		}
+	}
+
+	return readableLeases
+}
+
+func (k *kinesis) getParentShardIDs(streamShard *awskinesis.Shard) []string {
+	var parentShardIDs []string
+
+	for _, parentShardID := range []*string{streamShard.ParentShardId, streamShard.AdjacentParentShardId} {
+		if parentShardID != nil {
+			parentShardIDs = append(parentShardIDs, *parentShardID)
+		}
+	}
+
+	return parentShardIDs
+}
+
+// getStreamShards returns the configured shards, or the shards of the stream as discovered in the last
+// discovery interval
+func (k *kinesis) getStreamShards() ([]*awskinesis.Shard, error) {
+	if len(k.configuration.Shards) > 0 {
+		var streamShards []*awskinesis.Shard
+		for _, shardID := range k.configuration.Shards {
+			streamShards = append(streamShards, &awskinesis.Shard{
+				ShardId: aws.String(shardID),
+			})
+		}
+
+		return streamShards, nil
+	}
+
+	if k.streamShards != nil && time.Since(k.lastShardDiscovery) < k.configuration.shardDiscoveryInterval {
+		return k.streamShards, nil
+	}
+
+	var streamShards []*awskinesis.Shard
+	listShardsInput := &awskinesis.ListShardsInput{
+		StreamName: aws.String(k.configuration.StreamName),
+	}
+
+	for {
+		listShardsOutput, err := k.kinesisClient.ListShards(listShardsInput)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed to list shards of stream %s", k.configuration.StreamName)
+		}
+
+		streamShards = append(streamShards, listShardsOutput.Shards...)
+		if listShardsOutput.NextToken == nil {
+			break
+		}
+
+		// the stream name can't be passed along with a token
+		listShardsInput = &awskinesis.ListShardsInput{
+			NextToken: listShardsOutput.NextToken,
+		}
+	}
+
+	if len(streamShards) != len(k.streamShards) {
+		k.Logger.InfoWith("Discovered stream shards", "numShards", len(streamShards))
+	}
+
+	k.streamShards = streamShards
+	k.lastShardDiscovery = time.Now()
+
+	return streamShards, nil
+}
+
+func (k *kinesis) acquireLease(lease *shardLease, streamShards []*awskinesis.Shard) error {
+	acquiredLease := *lease
+	acquiredLease.Owner = k.replicaID
+	acquiredLease.LeaseExpiration = time.Now().Add(k.configuration.leaseDuration)
+	acquiredLease.LeaseCounter++
+
+	if err := k.checkpointStore.putLease(&acquiredLease, lease.LeaseCounter); err != nil {
+		return errors.Wrap(err, "Failed to put lease")
+	}
+
+	// children of shards that were read start at their beginning, so that no record is skipped
+	initialIteratorType := k.configuration.IteratorType
+	for _, streamShard := range streamShards {
+		if aws.StringValue(streamShard.ShardId) == lease.ShardID && len(k.getParentShardIDs(streamShard)) > 0 {
+			initialIteratorType = awskinesis.ShardIteratorTypeTrimHorizon
+		}
+	}
+
+	shardInstance := newShard(k.Logger, k, &acquiredLease, initialIteratorType)
+
+	k.ownedShardsLock.Lock()
+	k.ownedShards[lease.ShardID] = shardInstance
+	k.ownedShardsLock.Unlock()
+
+	k.Logger.InfoWith("Acquired shard lease",
+		"shardID", lease.ShardID,
+		"checkpoint", acquiredLease.Checkpoint)
+
+	k.shardsWaitGroup.Add(1)
+	go func() {
+		defer k.shardsWaitGroup.Done()
+		shardInstance.readFromShard()
+	}()
+
+	return nil
+}
+
+func (k *kinesis) renewLeases() {
+	k.ownedShardsLock.Lock()
+	ownedShards := make([]*shard, 0, len(k.ownedShards))
+	for _, shardInstance := range k.ownedShards {
+		ownedShards = append(ownedShards, shardInstance)
+	}
+	k.ownedShardsLock.Unlock()
+
+	for _, shardInstance := range ownedShards {
+		err := k.updateLease(shardInstance, func(lease *shardLease) {
+			lease.LeaseExpiration = time.Now().Add(k.configuration.leaseDuration)
+		})
+
+		switch {
+		case err == errLeaseConflict:
+			k.Logger.InfoWith("Shard lease was taken by another replica", "shardID", shardInstance.shardID)
+			k.disownShard(shardInstance)
+
+		case err != nil:
+			k.Logger.WarnWith("Failed to renew shard lease", "shardID", shardInstance.shardID, "err", err.Error())
+
+			// another replica may take the shard by now
+			if time.Now().After(shardInstance.getLease().LeaseExpiration) {
+				k.disownShard(shardInstance)
+			}
+		}
+	}
+}
+
+// checkpointShard records the sequence number up to which a shard was handled
+func (k *kinesis) checkpointShard(shardInstance *shard, sequenceNumber string) error {
+	return k.updateLease(shardInstance, func(lease *shardLease) {
+		lease.Checkpoint = sequenceNumber
+	})
+}
+
+// finishShard records that a shard was read to its end, so that its children can be read
+func (k *kinesis) finishShard(shardInstance *shard) error {
+	k.disownShard(shardInstance)
+
+	return k.updateLease(shardInstance, func(lease *shardLease) {
+		lease.Checkpoint = shardEndCheckpoint
+		lease.Owner = ""
+		lease.LeaseExpiration = time.Time{}
+	})
+}
+
+func (k *kinesis) releaseLease(shardInstance *shard) error {
+	return k.updateLease(shardInstance, func(lease *shardLease) {
+		lease.Owner = ""
+		lease.LeaseExpiration = time.Time{}
+	})
+}
+
+// updateLease applies an update to the lease of a shard, provided no other replica modified it since
+func (k *kinesis) updateLease(shardInstance *shard, update func(lease *shardLease)) error {
+	shardInstance.leaseLock.Lock()
+	defer shardInstance.leaseLock.Unlock()
+
+	updatedLease := *shardInstance.lease
+	update(&updatedLease)
+	updatedLease.LeaseCounter++
+
+	if err := k.checkpointStore.putLease(&updatedLease, shardInstance.lease.LeaseCounter); err != nil {
+		return err
+	}
+
+	shardInstance.lease = &updatedLease
+	return nil
+}
+
+// disownShard stops reading a shard. the shard stops on its own time, and its lease is left as is
+func (k *kinesis) disownShard(shardInstance *shard) {
+	k.ownedShardsLock.Lock()
+	if k.ownedShards[shardInstance.shardID] == shardInstance {
+		delete(k.ownedShards, shardInstance.shardID)
+	}
+	k.ownedShardsLock.Unlock()
+
+	shardInstance.signalStop()
+}
+
+func (k *kinesis) getNumOwnedShards() int {
+	k.ownedShardsLock.Lock()
+	defer k.ownedShardsLock.Unlock()
+
+	return len(k.ownedShards)
+}
+
+func (k *kinesis) getWorkerAvailabilityTimeout() time.Duration {
+	return time.Duration(*k.configuration.WorkerAvailabilityTimeoutMilliseconds) * time.Millisecond
+}
+
+func resolveReplicaID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "replica"
+	}
+
+	return fmt.Sprintf("%s-%s", hostname, common.GenerateRandomString(8, common.SmallLettersAndNumbers))
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package kinesis
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/functionconfig"
@@ -27,16 +28,48 @@ import (
 	"github.com/nuclio/errors"
 )
 
+type CheckpointStoreKind string
+
+const (
+
+	// CheckpointStoreKindMemory keeps checkpoints and leases in memory, so a restarted replica reads from
+	// the configured iterator type and each replica reads all shards
+	CheckpointStoreKindMemory CheckpointStoreKind = "memory"
+
+	// CheckpointStoreKindDynamoDB keeps checkpoints and leases in a DynamoDB (or compatible) table, shared
+	// by all replicas
+	CheckpointStoreKindDynamoDB CheckpointStoreKind = "dynamodb"
+)
+
 type Configuration struct {
 	trigger.Configuration
-	AccessKeyID           string
-	SecretAccessKey       string
-	RegionName            string
-	StreamName            string
-	Shards                []string
+	AccessKeyID     string
+	SecretAccessKey string
+	RegionName      string
+	StreamName      string
+
+	// the shards to read from. if empty, the shards of the stream are discovered periodically
+	Shards                 []string
+	ShardDiscoveryInterval string
+
 	IteratorType          string
 	PollingPeriod         string
 	pollingPeriodDuration time.Duration
+
+	// where the per-shard checkpoints and leases are kept
+	CheckpointStore    CheckpointStoreKind
+	CheckpointTable    string
+	CheckpointStoreURL string
+
+	// identifies the checkpoints and leases of this consumer in the store (default: derived from the
+	// function and trigger)
+	ConsumerName string
+
+	// how long a replica owns a shard without renewing its lease
+	LeaseDuration string
+
+	shardDiscoveryInterval time.Duration
+	leaseDuration          time.Duration
 }
 
 func NewConfiguration(id string,
@@ -74,9 +107,57 @@ func NewConfiguration(id string,
 		return nil, errors.Wrap(err, "Failed to parse polling period duration")
 	}
 
+	if newConfiguration.CheckpointStore == "" {
+		newConfiguration.CheckpointStore = CheckpointStoreKindMemory
+	}
+
+	switch newConfiguration.CheckpointStore {
+	case CheckpointStoreKindMemory:
+	case CheckpointStoreKindDynamoDB:
+		if newConfiguration.CheckpointTable == "" {
+			return nil, errors.New("Checkpoint table must be set when checkpointing to DynamoDB")
+		}
+	default:
+		return nil, errors.Errorf("Unsupported checkpoint store: %s", newConfiguration.CheckpointStore)
+	}
+
+	if newConfiguration.ConsumerName == "" {
+		newConfiguration.ConsumerName = newConfiguration.resolveConsumerName()
+	}
+
+	for _, durationConfigField := range []trigger.DurationConfigField{
+		{
+			Name:    "shard discovery interval",
+			Value:   newConfiguration.ShardDiscoveryInterval,
+			Field:   &newConfiguration.shardDiscoveryInterval,
+			Default: time.Minute,
+		},
+		{
+			Name:    "lease duration",
+			Value:   newConfiguration.LeaseDuration,
+			Field:   &newConfiguration.leaseDuration,
+			Default: 30 * time.Second,
+		},
+	} {
+		if err = newConfiguration.ParseDurationOrDefault(&durationConfigField); err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse %s", durationConfigField.Name)
+		}
+	}
+
 	return &newConfiguration, nil
 }
 
+func (c *Configuration) resolveConsumerName() string {
+	if c.RuntimeConfiguration == nil || c.RuntimeConfiguration.Configuration == nil {
+		return c.ID
+	}
+
+	return fmt.Sprintf("%s-%s-%s",
+		c.RuntimeConfiguration.Meta.Namespace,
+		c.RuntimeConfiguration.Meta.Name,
+		c.ID)
+}
+
 func (c *Configuration) validateIteratorType(iteratorType string) error {
 	switch iteratorType {
 	case "TRIM_HORIZON", "LATEST":
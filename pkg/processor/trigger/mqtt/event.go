@@ -19,19 +19,22 @@ package mqtt
 import (
 	"strconv"
 
-	mqttclient "github.com/eclipse/paho.mqtt.golang"
 	"github.com/nuclio/nuclio-sdk-go"
 )
 
 // Event allows access to the MQTT message
 type Event struct {
 	nuclio.AbstractEvent
-	message mqttclient.Message
-	url     string
+	url         string
+	topic       string
+	body        []byte
+	messageID   uint16
+	contentType string
+	headers     map[string]interface{}
 }
 
 func (e *Event) GetBody() []byte {
-	return e.message.Payload()
+	return e.body
 }
 
 // GetURL returns the topic of the event
@@ -41,15 +44,46 @@ func (e *Event) GetURL() string {
 
 // GetPath returns the topic of the event
 func (e *Event) GetPath() string {
-	return e.message.Topic()
+	return e.topic
 }
 
 // GetTopic returns the topic of the event
 func (e *Event) GetTopic() string {
-	return e.message.Topic()
+	return e.topic
 }
 
 // GetID returns the message ID
 func (e *Event) GetID() nuclio.ID {
-	return nuclio.ID(strconv.Itoa(int(e.message.MessageID())))
+	return nuclio.ID(strconv.Itoa(int(e.messageID)))
+}
+
+// GetContentType returns the content type of the message (MQTT 5 only)
+func (e *Event) GetContentType() string {
+	return e.contentType
+}
+
+// GetHeaders returns the user properties of the message (MQTT 5 only)
+func (e *Event) GetHeaders() map[string]interface{} {
+	return e.headers
+}
+
+// GetHeader returns a user property of the message (MQTT 5 only)
+func (e *Event) GetHeader(key string) interface{} {
+	return e.headers[key]
+}
+
+// GetHeaderString returns a user property of the message as a string (MQTT 5 only)
+func (e *Event) GetHeaderString(key string) string {
+	value, _ := e.headers[key].(string)
+	return value
+}
+
+// GetHeaderByteSlice returns a user property of the message as a byte slice (MQTT 5 only)
+func (e *Event) GetHeaderByteSlice(key string) []byte {
+	value, found := e.headers[key].(string)
+	if !found {
+		return nil
+	}
+
+	return []byte(value)
 }
@@ -0,0 +1,177 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/nuclio/errors"
+)
+
+const connectV5Timeout = 30 * time.Second
+
+func (t *AbstractTrigger) connectV5() error {
+	brokerURL, err := t.configuration.getBrokerURL()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get broker URL")
+	}
+
+	clientConfig := autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{brokerURL},
+		KeepAlive:                     30,
+		CleanStartOnInitialConnection: true,
+		ConnectUsername:               t.configuration.Username,
+		ConnectPassword:               []byte(t.configuration.Password),
+
+		// subscribe on every connection, as the session ends when the connection is lost
+		OnConnectionUp: func(connectionManager *autopaho.ConnectionManager, connack *paho.Connack) {
+			if err := t.createSubscriptionsV5(connectionManager); err != nil {
+				t.Logger.WarnWith("Failed to create subscriptions", "err", errors.GetErrorStackString(err, 10))
+			}
+		},
+		OnConnectError: func(err error) {
+			t.Logger.WarnWith("Failed to connect to broker", "err", err.Error())
+		},
+		ClientConfig: paho.ClientConfig{
+			ClientID: t.configuration.ClientID,
+			OnPublishReceived: []func(paho.PublishReceived) (bool, error){
+				t.handlePublish,
+			},
+		},
+	}
+
+	if t.configuration.TLS.Enable {
+		clientConfig.TlsCfg, err = t.createTLSConfig()
+		if err != nil {
+			return errors.Wrap(err, "Failed to create TLS configuration")
+		}
+	}
+
+	t.Logger.InfoWith("Creating client",
+		"brokerUrl", t.configuration.URL,
+		"clientID", t.configuration.ClientID,
+		"username", t.configuration.Username,
+		"protocolVersion", t.configuration.ProtocolVersion)
+
+	// the connection manager reconnects in the background until disconnected
+	t.connectionManager, err = autopaho.NewConnection(context.Background(), clientConfig)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create client")
+	}
+
+	connectContext, cancel := context.WithTimeout(context.Background(), connectV5Timeout)
+	defer cancel()
+
+	if err := t.connectionManager.AwaitConnection(connectContext); err != nil {
+		return errors.Wrap(err, "Failed to connect to broker")
+	}
+
+	return nil
+}
+
+func (t *AbstractTrigger) createSubscriptionsV5(connectionManager *autopaho.ConnectionManager) error {
+	t.Logger.InfoWith("Creating subscriptions",
+		"subscriptions", t.configuration.Subscriptions,
+		"sharedSubscriptionGroup", t.configuration.SharedSubscriptionGroup)
+
+	subscribe := &paho.Subscribe{}
+	for _, subscription := range t.configuration.Subscriptions {
+		subscribe.Subscriptions = append(subscribe.Subscriptions, paho.SubscribeOptions{
+			Topic: t.configuration.getSubscriptionTopic(subscription.Topic),
+			QoS:   byte(subscription.QOS),
+		})
+	}
+
+	subscribeContext, cancel := context.WithTimeout(context.Background(), connectV5Timeout)
+	defer cancel()
+
+	suback, err := connectionManager.Subscribe(subscribeContext, subscribe)
+	if err != nil {
+		return errors.Wrap(err, "Failed to subscribe to topics")
+	}
+
+	// reason codes of 0x80 and above indicate failure
+	for subscriptionIndex, reasonCode := range suback.Reasons {
+		if reasonCode >= 0x80 {
+			return errors.Errorf("Subscription to %s was rejected with reason code 0x%x",
+				subscribe.Subscriptions[subscriptionIndex].Topic,
+				reasonCode)
+		}
+	}
+
+	return nil
+}
+
+func (t *AbstractTrigger) handlePublish(publishReceived paho.PublishReceived) (bool, error) {
+	publish := publishReceived.Packet
+
+	event := &Event{
+		url:       t.configuration.URL,
+		topic:     publish.Topic,
+		body:      publish.Payload,
+		messageID: publish.PacketID,
+	}
+
+	responseTopic := t.configuration.ResponseTopic
+	var correlationData []byte
+
+	if publish.Properties != nil {
+		event.contentType = publish.Properties.ContentType
+		event.headers = userPropertiesToHeaders(publish.Properties.User)
+
+		// requests may ask to be answered on a specific topic
+		if publish.Properties.ResponseTopic != "" {
+			responseTopic = publish.Properties.ResponseTopic
+			correlationData = publish.Properties.CorrelationData
+		}
+	}
+
+	t.dispatchEvent(event, func(response interface{}, submitError error, processError error) {
+		if responseTopic == "" {
+			return
+		}
+
+		responsePublish := t.createResponsePublish(responseTopic, correlationData, response, submitError, processError)
+
+		// publish through the queue, as waiting for the publish from within the handler may block the client
+		if err := t.connectionManager.PublishViaQueue(context.Background(), &autopaho.QueuePublish{
+			Publish: responsePublish,
+		}); err != nil {
+			t.Logger.WarnWith("Failed to publish response", "topic", responseTopic, "err", err.Error())
+		}
+	})
+
+	return true, nil
+}
+
+// userPropertiesToHeaders maps the user properties of a message to event headers. When a property
+// appears more than once, the first value is used
+func userPropertiesToHeaders(userProperties paho.UserProperties) map[string]interface{} {
+	eventHeaders := map[string]interface{}{}
+
+	for _, userProperty := range userProperties {
+		if _, found := eventHeaders[userProperty.Key]; !found {
+			eventHeaders[userProperty.Key] = userProperty.Value
+		}
+	}
+
+	return eventHeaders
+}
@@ -0,0 +1,396 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mqtt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common/headers"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/eclipse/paho.golang/paho"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// mockRuntime blocks each event until it is released, responding with the event's body or failing
+// when the body is "fail"
+type mockRuntime struct {
+	runtime.Runtime
+	release chan struct{}
+}
+
+func (mr *mockRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	<-mr.release
+
+	if string(event.GetBody()) == "fail" {
+		return nil, nuclio.NewErrBadRequest("Invalid body")
+	}
+
+	return event.GetBody(), nil
+}
+
+type dispatchResult struct {
+	response     interface{}
+	submitError  error
+	processError error
+}
+
+type TestSuite struct {
+	suite.Suite
+	trigger AbstractTrigger
+}
+
+func (suite *TestSuite) SetupTest() {
+	logger, err := nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+
+	suite.trigger = AbstractTrigger{}
+	suite.trigger.Logger = logger
+	suite.trigger.configuration = &Configuration{}
+}
+
+func (suite *TestSuite) TestNewConfiguration() {
+	for _, testCase := range []struct {
+		name                      string
+		attributes                map[string]interface{}
+		expectedProtocolVersion   int
+		expectedSubscriptionTopic string
+		expectError               bool
+	}{
+		{
+			name:                      "defaults",
+			attributes:                map[string]interface{}{},
+			expectedProtocolVersion:   ProtocolVersion311,
+			expectedSubscriptionTopic: "a/b",
+		},
+		{
+			name: "sharedSubscription",
+			attributes: map[string]interface{}{
+				"protocolVersion":         5,
+				"sharedSubscriptionGroup": "my-function",
+			},
+			expectedProtocolVersion:   ProtocolVersion5,
+			expectedSubscriptionTopic: "$share/my-function/a/b",
+		},
+		{
+			name: "invalidProtocolVersion",
+			attributes: map[string]interface{}{
+				"protocolVersion": 6,
+			},
+			expectError: true,
+		},
+		{
+			name: "invalidSharedSubscriptionGroup",
+			attributes: map[string]interface{}{
+				"sharedSubscriptionGroup": "my/function",
+			},
+			expectError: true,
+		},
+		{
+			name: "wildcardResponseTopic",
+			attributes: map[string]interface{}{
+				"responseTopic": "responses/#",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalidResponseQOS",
+			attributes: map[string]interface{}{
+				"responseQOS": 3,
+			},
+			expectError: true,
+		},
+		{
+			name: "clientCertificateWithoutKey",
+			attributes: map[string]interface{}{
+				"clientCertificate": "cert",
+			},
+			expectError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			configuration, err := NewConfiguration("test", &functionconfig.Trigger{
+				Kind:       "mqtt",
+				URL:        "broker:1883",
+				Attributes: testCase.attributes,
+			}, suite.createRuntimeConfiguration())
+
+			if testCase.expectError {
+				suite.Require().Error(err)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().Equal(testCase.expectedProtocolVersion, configuration.ProtocolVersion)
+			suite.Require().Equal(testCase.expectedSubscriptionTopic, configuration.getSubscriptionTopic("a/b"))
+		})
+	}
+}
+
+func (suite *TestSuite) TestNewConfigurationFromMountedSecrets() {
+	secretPath := suite.T().TempDir()
+	caCert, _ := suite.createCertificate()
+
+	err := os.WriteFile(filepath.Join(secretPath, "ca.crt"), []byte(caCert+"\n"), 0600)
+	suite.Require().NoError(err)
+
+	err = os.WriteFile(filepath.Join(secretPath, "password"), []byte("secret\n"), 0600)
+	suite.Require().NoError(err)
+
+	configuration, err := NewConfiguration("test", &functionconfig.Trigger{
+		Kind:     "mqtt",
+		URL:      "broker:8883",
+		Username: "user",
+		Password: "password",
+		Attributes: map[string]interface{}{
+			"secretPath": secretPath,
+			"caCert":     "ca.crt",
+		},
+	}, suite.createRuntimeConfiguration())
+	suite.Require().NoError(err)
+
+	suite.Require().Equal(strings.TrimSpace(caCert), configuration.CACert)
+	suite.Require().Equal("secret", configuration.Password)
+
+	// a CA implies tls
+	suite.Require().True(configuration.TLS.Enable)
+
+	brokerURL, err := configuration.getBrokerURL()
+	suite.Require().NoError(err)
+	suite.Require().Equal("tls://broker:8883", brokerURL.String())
+}
+
+func (suite *TestSuite) TestGetBrokerURL() {
+	for _, testCase := range []struct {
+		url               string
+		tlsEnabled        bool
+		expectedBrokerURL string
+	}{
+		{url: "broker:1883", expectedBrokerURL: "tcp://broker:1883"},
+		{url: "broker:8883", tlsEnabled: true, expectedBrokerURL: "tls://broker:8883"},
+		{url: "ssl://broker:8883", tlsEnabled: true, expectedBrokerURL: "ssl://broker:8883"},
+		{url: "ws://broker:80/mqtt", expectedBrokerURL: "ws://broker:80/mqtt"},
+	} {
+		configuration := Configuration{}
+		configuration.Trigger = &functionconfig.Trigger{URL: testCase.url}
+		configuration.TLS.Enable = testCase.tlsEnabled
+
+		brokerURL, err := configuration.getBrokerURL()
+		suite.Require().NoError(err)
+		suite.Require().Equal(testCase.expectedBrokerURL, brokerURL.String())
+	}
+}
+
+func (suite *TestSuite) TestCreateTLSConfig() {
+	certificate, key := suite.createCertificate()
+
+	suite.trigger.configuration.CACert = certificate
+	suite.trigger.configuration.ClientCertificate = certificate
+	suite.trigger.configuration.ClientKey = key
+	suite.trigger.configuration.TLS.MinimumVersion = "1.3"
+
+	tlsConfig, err := suite.trigger.createTLSConfig()
+	suite.Require().NoError(err)
+	suite.Require().NotNil(tlsConfig.RootCAs)
+	suite.Require().Len(tlsConfig.Certificates, 1)
+	suite.Require().Equal(uint16(tls.VersionTLS13), tlsConfig.MinVersion)
+
+	// invalid CA
+	suite.trigger.configuration.CACert = "not a certificate"
+	_, err = suite.trigger.createTLSConfig()
+	suite.Require().Error(err)
+}
+
+func (suite *TestSuite) TestCreateResponsePublish() {
+	suite.trigger.configuration.ResponseQOS = 1
+
+	responsePublish := suite.trigger.createResponsePublish("responses",
+		[]byte("correlation"),
+		&nuclio.Response{
+			StatusCode:  http.StatusCreated,
+			ContentType: "application/json",
+			Headers: map[string]interface{}{
+				"x-tags": []string{"a", "b"},
+			},
+			Body: []byte(`{"a": 1}`),
+		},
+		nil,
+		nil)
+
+	suite.Require().Equal("responses", responsePublish.Topic)
+	suite.Require().Equal(byte(1), responsePublish.QoS)
+	suite.Require().Equal(`{"a": 1}`, string(responsePublish.Payload))
+	suite.Require().Equal("correlation", string(responsePublish.Properties.CorrelationData))
+	suite.Require().Equal("application/json", responsePublish.Properties.ContentType)
+	suite.Require().Equal("201", responsePublish.Properties.User.Get(headers.StatusCode))
+	suite.Require().Equal([]string{"a", "b"}, responsePublish.Properties.User.GetAll("x-tags"))
+
+	// errors carry only a status code
+	responsePublish = suite.trigger.createResponsePublish("responses",
+		nil,
+		nil,
+		errors.Wrap(worker.ErrNoAvailableWorkers, "Failed to allocate worker"),
+		nil)
+
+	suite.Require().Empty(responsePublish.Payload)
+	suite.Require().Equal("503", responsePublish.Properties.User.Get(headers.StatusCode))
+}
+
+func (suite *TestSuite) TestDispatchEvent() {
+	mockRuntimeInstance := &mockRuntime{
+		release: make(chan struct{}),
+	}
+	suite.createWorkerAllocator(mockRuntimeInstance, 2)
+
+	results := make(chan dispatchResult, 3)
+	respond := func(response interface{}, submitError error, processError error) {
+		results <- dispatchResult{response, submitError, processError}
+	}
+
+	// dispatching doesn't wait for the handler, so both events are handled at the same time
+	suite.trigger.dispatchEvent(&Event{topic: "t", body: []byte("ok")}, respond)
+	suite.trigger.dispatchEvent(&Event{topic: "t", body: []byte("fail")}, respond)
+
+	// with all workers busy, the next event is dropped once the allocation times out
+	suite.trigger.dispatchEvent(&Event{topic: "t", body: []byte("dropped")}, respond)
+
+	result := <-results
+	suite.Require().ErrorIs(result.submitError, worker.ErrNoAvailableWorkers)
+
+	mockRuntimeInstance.release <- struct{}{}
+	mockRuntimeInstance.release <- struct{}{}
+
+	var responses []string
+	var processErrors []error
+	for resultIdx := 0; resultIdx < 2; resultIdx++ {
+		result = <-results
+		suite.Require().NoError(result.submitError)
+
+		if result.processError != nil {
+			processErrors = append(processErrors, result.processError)
+			continue
+		}
+
+		responses = append(responses, string(result.response.([]byte)))
+	}
+
+	suite.Require().Equal([]string{"ok"}, responses)
+	suite.Require().Len(processErrors, 1)
+}
+
+func (suite *TestSuite) TestCreateResponsePayload() {
+	suite.Require().Equal([]byte("ok"), createResponsePayload([]byte("ok"), nil, nil))
+
+	// failures are published with their error message, or with their status text if they have none
+	suite.Require().Equal([]byte("Invalid body"),
+		createResponsePayload(nil, nil, nuclio.NewErrBadRequest("Invalid body")))
+	suite.Require().Equal([]byte("Service Unavailable"),
+		createResponsePayload(nil, errors.Wrap(worker.ErrNoAvailableWorkers, "Failed to allocate worker"), nil))
+	suite.Require().Equal([]byte("Internal Server Error"),
+		createResponsePayload(nuclio.Response{StatusCode: http.StatusInternalServerError}, nil, nil))
+}
+
+func (suite *TestSuite) TestUserPropertiesToHeaders() {
+	userProperties := paho.UserProperties{}
+	userProperties.Add("a", "1").Add("b", "2").Add("a", "3")
+
+	event := Event{
+		headers: userPropertiesToHeaders(userProperties),
+	}
+
+	suite.Require().Equal(map[string]interface{}{"a": "1", "b": "2"}, event.GetHeaders())
+	suite.Require().Equal("2", event.GetHeaderString("b"))
+	suite.Require().Equal([]byte("1"), event.GetHeaderByteSlice("a"))
+	suite.Require().Empty(event.GetHeaderString("c"))
+}
+
+func (suite *TestSuite) createWorkerAllocator(runtimeInstance runtime.Runtime, numWorkers int) {
+	var workers []*worker.Worker
+	for workerIdx := 0; workerIdx < numWorkers; workerIdx++ {
+		workerInstance, err := worker.NewWorker(suite.trigger.Logger, workerIdx, runtimeInstance)
+		suite.Require().NoError(err)
+
+		workers = append(workers, workerInstance)
+	}
+
+	workerAllocator, err := worker.NewFixedPoolWorkerAllocator(suite.trigger.Logger, workers)
+	suite.Require().NoError(err)
+
+	workerAvailabilityTimeoutMilliseconds := 50
+	suite.trigger.WorkerAllocator = workerAllocator
+	suite.trigger.configuration.Trigger = &functionconfig.Trigger{
+		WorkerAvailabilityTimeoutMilliseconds: &workerAvailabilityTimeoutMilliseconds,
+	}
+}
+
+func (suite *TestSuite) createRuntimeConfiguration() *runtime.Configuration {
+	return &runtime.Configuration{
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{},
+		},
+	}
+}
+
+// createCertificate returns a self signed certificate and its key, PEM encoded
+func (suite *TestSuite) createCertificate() (string, string) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().NoError(err)
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
+	suite.Require().NoError(err)
+
+	encodedPrivateKey, err := x509.MarshalECPrivateKey(privateKey)
+	suite.Require().NoError(err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: encodedPrivateKey}))
+}
+
+func TestMQTTSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
@@ -17,13 +17,20 @@ limitations under the License.
 package mqtt
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/common/headers"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
 
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
 	mqttclient "github.com/eclipse/paho.mqtt.golang"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
@@ -34,6 +41,9 @@ type AbstractTrigger struct {
 	configuration *Configuration
 	MQTTClient    mqttclient.Client
 
+	// used instead of MQTTClient when the protocol version is 5
+	connectionManager *autopaho.ConnectionManager
+
 	// TODO: allow configuring per-topic worker allocators to allow for things like:
 	// - in-order handling of a topic messages (unique worker allocator with 1 worker for a topic)
 	// - disallowing parallel handling of topics (e.g. topic1, topic2 share worker allocator so that only one handler
@@ -83,6 +93,10 @@ func (t *AbstractTrigger) GetConfig() map[string]interface{} {
 func (t *AbstractTrigger) Connect() error {
 	t.Logger.InfoWith("Connecting")
 
+	if t.configuration.ProtocolVersion == ProtocolVersion5 {
+		return t.connectV5()
+	}
+
 	clientOptions, err := t.createClientOptions()
 	if err != nil {
 		return errors.Wrap(err, "Failed to create client options")
@@ -118,7 +132,12 @@ func (t *AbstractTrigger) createClient(clientOptions *mqttclient.ClientOptions)
 func (t *AbstractTrigger) createClientOptions() (*mqttclient.ClientOptions, error) {
 	clientOptions := mqttclient.NewClientOptions()
 
-	clientOptions.AddBroker(t.configuration.URL)
+	brokerURL, err := t.configuration.getBrokerURL()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get broker URL")
+	}
+
+	clientOptions.AddBroker(brokerURL.String())
 	clientOptions.SetProtocolVersion(uint(t.configuration.ProtocolVersion))
 
 	if t.configuration.Username != "" {
@@ -131,9 +150,60 @@ func (t *AbstractTrigger) createClientOptions() (*mqttclient.ClientOptions, erro
 
 	clientOptions.SetClientID(t.configuration.ClientID)
 
+	if t.configuration.TLS.Enable {
+		tlsConfig, err := t.createTLSConfig()
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create TLS configuration")
+		}
+
+		clientOptions.SetTLSConfig(tlsConfig)
+	}
+
 	return clientOptions, nil
 }
 
+func (t *AbstractTrigger) createTLSConfig() (*tls.Config, error) {
+	t.Logger.DebugWith("Creating TLS configuration",
+		"minimumVersion", t.configuration.TLS.MinimumVersion,
+		"insecureSkipVerify", t.configuration.TLS.InsecureSkipVerify,
+		"caCertLen", len(t.configuration.CACert),
+		"clientCertificateLen", len(t.configuration.ClientCertificate))
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: t.configuration.TLS.InsecureSkipVerify, // nolint: gosec
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	switch t.configuration.TLS.MinimumVersion {
+	case "", "1.2":
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, errors.Errorf("Unsupported TLS minimum version: %s", t.configuration.TLS.MinimumVersion)
+	}
+
+	if t.configuration.CACert != "" {
+		caCertPool := x509.NewCertPool()
+		if !caCertPool.AppendCertsFromPEM([]byte(t.configuration.CACert)) {
+			return nil, errors.New("Failed to parse CA certificate")
+		}
+
+		tlsConfig.RootCAs = caCertPool
+	}
+
+	if t.configuration.ClientCertificate != "" {
+		keyPair, err := tls.X509KeyPair([]byte(t.configuration.ClientCertificate),
+			[]byte(t.configuration.ClientKey))
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to create X.509 key pair")
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{keyPair}
+	}
+
+	return tlsConfig, nil
+}
+
 func (t *AbstractTrigger) createSubscriptions(clientOptions *mqttclient.ClientOptions) error {
 	t.Logger.InfoWith("Creating subscriptions",
 		"subscriptions", t.configuration.Subscriptions)
@@ -152,40 +222,118 @@ func (t *AbstractTrigger) subscriptionsToFilters(subscriptions []Subscription) m
 
 	// add filter
 	for _, subscription := range subscriptions {
-		filters[subscription.Topic] = byte(subscription.QOS)
+		filters[t.configuration.getSubscriptionTopic(subscription.Topic)] = byte(subscription.QOS)
 	}
 
 	return filters
 }
 
 func (t *AbstractTrigger) handleMessage(client mqttclient.Client, message mqttclient.Message) {
+	event := &Event{
+		url:       t.configuration.URL,
+		topic:     message.Topic(),
+		body:      message.Payload(),
+		messageID: message.MessageID(),
+	}
+
+	t.dispatchEvent(event, func(response interface{}, submitError error, processError error) {
+		if t.configuration.ResponseTopic == "" {
+			return
+		}
+
+		// don't wait for the publish to complete, as waiting from within a message handler may block
+		// the client from handling acknowledgements
+		token := client.Publish(t.configuration.ResponseTopic,
+			byte(t.configuration.ResponseQOS),
+			false,
+			createResponsePayload(response, submitError, processError))
+		go func() {
+			if token.Wait() && token.Error() != nil {
+				t.Logger.WarnWith("Failed to publish response",
+					"topic", t.configuration.ResponseTopic,
+					"err", token.Error().Error())
+			}
+		}()
+	})
+}
+
+// dispatchEvent allocates a worker for the event and processes it in the background, passing the handler's
+// response to respond. the client keeps handling packets (e.g. acknowledgements and pings) while the handler
+// runs, and messages are handled concurrently by as many workers as there are. workers are allocated by
+// the order messages arrive, so once all workers are busy the client waits for one to be released
+func (t *AbstractTrigger) dispatchEvent(event *Event,
+	respond func(response interface{}, submitError error, processError error)) {
 
 	// get a worker for this message
-	workerInstance, workerAllocator, err := t.allocateWorker(message)
+	workerInstance, workerAllocator, err := t.allocateWorker(event.topic)
 	if err != nil {
-		t.Logger.WarnWith("Failed to allocate worker, message dropped", "topic", message.Topic())
+		t.Logger.WarnWith("Failed to allocate worker, message dropped", "topic", event.topic)
+		respond(nil, err, nil)
 		return
 	}
 
-	//nolint: errcheck
-	t.SubmitEventToWorker(nil,
-		workerInstance,
-		&Event{
-			message: message,
-			url:     t.configuration.URL,
-		})
+	go func() {
+		response, processError := t.SubmitEventToWorker(nil, workerInstance, event)
+
+		workerAllocator.Release(workerInstance)
+
+		respond(response, nil, processError)
+	}()
+}
+
+// createResponsePayload creates the payload of a response published over MQTT 3, whose messages have no
+// properties to carry the status code. failures without a body of their own are published with the status text
+func createResponsePayload(response interface{}, submitError error, processError error) []byte {
+	resolvedResponse := trigger.ResolveResponse(response, submitError, processError)
+
+	if len(resolvedResponse.Body) == 0 && resolvedResponse.StatusCode >= http.StatusBadRequest {
+		return []byte(http.StatusText(resolvedResponse.StatusCode))
+	}
+
+	return resolvedResponse.Body
+}
+
+// createResponsePublish creates the message holding the handler's response. The status code, content type
+// and response headers are carried as properties, which are only sent over MQTT 5
+func (t *AbstractTrigger) createResponsePublish(topic string,
+	correlationData []byte,
+	response interface{},
+	submitError error,
+	processError error) *paho.Publish {
+	responsePublish := &paho.Publish{
+		Topic: topic,
+		QoS:   byte(t.configuration.ResponseQOS),
+		Properties: &paho.PublishProperties{
+			CorrelationData: correlationData,
+		},
+	}
+
+	resolvedResponse := trigger.ResolveResponse(response, submitError, processError)
+
+	for headerKey, headerValues := range resolvedResponse.Headers {
+		for _, headerValue := range headerValues {
+			responsePublish.Properties.User.Add(headerKey, headerValue)
+		}
+	}
+
+	if resolvedResponse.StatusCode != 0 {
+		responsePublish.Properties.User.Add(headers.StatusCode, strconv.Itoa(resolvedResponse.StatusCode))
+	}
+
+	responsePublish.Properties.ContentType = resolvedResponse.ContentType
+	responsePublish.Payload = resolvedResponse.Body
 
-	workerAllocator.Release(workerInstance)
+	return responsePublish
 }
 
-func (t *AbstractTrigger) allocateWorker(message mqttclient.Message) (*worker.Worker, worker.Allocator, error) {
+func (t *AbstractTrigger) allocateWorker(topic string) (*worker.Worker, worker.Allocator, error) {
 	var workerAllocator worker.Allocator
 
 	// if there's a per-topic worker allocator, first get worker allocator
 	if t.perTopicWorkerAllocator != nil {
 
 		// try to get the worker allocator
-		workerAllocator = t.perTopicWorkerAllocator[message.Topic()]
+		workerAllocator = t.perTopicWorkerAllocator[topic]
 	}
 
 	// if there's no allocated worker allocator (either because per topic worker allocator is not enabled, or it
@@ -197,7 +345,9 @@ func (t *AbstractTrigger) allocateWorker(message mqttclient.Message) (*worker.Wo
 	workerAvailabilityTimeout := time.Duration(*t.configuration.WorkerAvailabilityTimeoutMilliseconds) * time.Millisecond
 
 	// try to allocate the worker
+	startTime := time.Now()
 	workerInstance, err := workerAllocator.Allocate(workerAvailabilityTimeout)
+	t.ObserveWorkerAllocation(time.Since(startTime), err == nil)
 
 	return workerInstance, workerAllocator, err
 }
@@ -17,6 +17,12 @@ limitations under the License.
 package mqtt
 
 import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nuclio/nuclio/pkg/common"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
@@ -25,6 +31,12 @@ import (
 	"github.com/nuclio/errors"
 )
 
+const (
+	ProtocolVersion31  = 3
+	ProtocolVersion311 = 4
+	ProtocolVersion5   = 5
+)
+
 type Subscription struct {
 	Topic string
 	QOS   int
@@ -35,6 +47,26 @@ type Configuration struct {
 	Subscriptions   []Subscription
 	ClientID        string
 	ProtocolVersion int
+
+	// when set, subscribe through "$share/<group>/<topic>" so that replicas share the messages
+	SharedSubscriptionGroup string
+
+	// when set, the handler response is published to this topic. MQTT 5 messages that carry a
+	// response topic are answered on that topic instead
+	ResponseTopic string
+	ResponseQOS   int
+
+	TLS struct {
+		Enable             bool
+		InsecureSkipVerify bool
+		MinimumVersion     string
+	}
+
+	// certificates may be passed as contents or as paths (relative to SecretPath) of mounted secrets
+	CACert            string
+	ClientCertificate string
+	ClientKey         string
+	SecretPath        string
 }
 
 func NewConfiguration(id string,
@@ -55,8 +87,92 @@ func NewConfiguration(id string,
 	}
 
 	if newConfiguration.ProtocolVersion == 0 {
-		newConfiguration.ProtocolVersion = 4
+		newConfiguration.ProtocolVersion = ProtocolVersion311
+	}
+
+	switch newConfiguration.ProtocolVersion {
+	case ProtocolVersion31, ProtocolVersion311, ProtocolVersion5:
+	default:
+		return nil, errors.Errorf("Unsupported protocol version: %d", newConfiguration.ProtocolVersion)
+	}
+
+	if strings.ContainsAny(newConfiguration.SharedSubscriptionGroup, "/+#") {
+		return nil, errors.Errorf("Shared subscription group must not contain '/', '+' or '#': %s",
+			newConfiguration.SharedSubscriptionGroup)
+	}
+
+	if strings.ContainsAny(newConfiguration.ResponseTopic, "+#") {
+		return nil, errors.Errorf("Response topic must not contain wildcards: %s", newConfiguration.ResponseTopic)
+	}
+
+	if newConfiguration.ResponseQOS < 0 || newConfiguration.ResponseQOS > 2 {
+		return nil, errors.Errorf("Response QOS must be 0, 1 or 2: %d", newConfiguration.ResponseQOS)
+	}
+
+	if err := newConfiguration.populateValuesFromMountedSecrets(); err != nil {
+		return nil, errors.Wrap(err, "Failed to populate configuration from secrets")
+	}
+
+	// a CA or a client certificate implies tls
+	if newConfiguration.CACert != "" || newConfiguration.ClientCertificate != "" {
+		newConfiguration.TLS.Enable = true
+	}
+
+	if (newConfiguration.ClientCertificate == "") != (newConfiguration.ClientKey == "") {
+		return nil, errors.New("Client certificate and client key must be provided together")
 	}
 
 	return &newConfiguration, nil
 }
+
+// getSubscriptionTopic returns the topic filter to subscribe to, prefixed for shared subscriptions if needed
+func (c *Configuration) getSubscriptionTopic(topic string) string {
+	if c.SharedSubscriptionGroup == "" {
+		return topic
+	}
+
+	return "$share/" + c.SharedSubscriptionGroup + "/" + topic
+}
+
+// getBrokerURL returns the broker URL, defaulting the scheme by whether TLS is enabled
+func (c *Configuration) getBrokerURL() (*url.URL, error) {
+	brokerURL := c.URL
+
+	if !strings.Contains(brokerURL, "://") {
+		if c.TLS.Enable {
+			brokerURL = "tls://" + brokerURL
+		} else {
+			brokerURL = "tcp://" + brokerURL
+		}
+	}
+
+	parsedBrokerURL, err := url.Parse(brokerURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse broker URL %s", c.URL)
+	}
+
+	return parsedBrokerURL, nil
+}
+
+// populateValuesFromMountedSecrets will populate sensitive configuration fields from mounted secrets, if the field is a path
+func (c *Configuration) populateValuesFromMountedSecrets() error {
+	for _, sensitiveField := range []*string{
+		&c.CACert,
+		&c.ClientCertificate,
+		&c.ClientKey,
+		&c.Password,
+	} {
+		filePath := filepath.Join(c.SecretPath, *sensitiveField)
+
+		// if the file doesn't exist, the field holds the value itself
+		if *sensitiveField != "" && common.FileExists(filePath) {
+			contents, err := os.ReadFile(filePath)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to read file %s", filePath)
+			}
+			*sensitiveField = strings.TrimSpace(string(contents))
+		}
+	}
+
+	return nil
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package nats
 
 import (
+	"time"
+
 	natsio "github.com/nats-io/nats.go"
 	"github.com/nuclio/nuclio-sdk-go"
 )
@@ -25,6 +27,9 @@ import (
 type Event struct {
 	nuclio.AbstractEvent
 	natsMessage *natsio.Msg
+
+	// set only for messages consumed from JetStream
+	metadata *natsio.MsgMetadata
 }
 
 func (e *Event) GetBody() []byte {
@@ -53,3 +58,26 @@ func (e *Event) GetSize() int {
 func (e *Event) NATSMessage() *natsio.Msg {
 	return e.natsMessage
 }
+
+// GetOffset returns the stream sequence of a JetStream message
+func (e *Event) GetOffset() int {
+	if e.metadata == nil {
+		return 0
+	}
+
+	return int(e.metadata.Sequence.Stream)
+}
+
+// GetTopic returns the subject on which the message was published
+func (e *Event) GetTopic() string {
+	return e.natsMessage.Subject
+}
+
+// GetTimestamp returns the time at which a JetStream message was stored in the stream
+func (e *Event) GetTimestamp() time.Time {
+	if e.metadata == nil {
+		return e.AbstractEvent.GetTimestamp()
+	}
+
+	return e.metadata.Timestamp
+}
@@ -0,0 +1,455 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package nats
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common/headers"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natsio "github.com/nats-io/nats.go"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	streamName  = "events"
+	durableName = "test-durable"
+)
+
+type TestSuite struct {
+	suite.Suite
+	logger           logger.Logger
+	natsServer       *server.Server
+	natsConnection   *natsio.Conn
+	jetStreamContext natsio.JetStreamContext
+	trigger          *nats
+}
+
+func (suite *TestSuite) SetupSuite() {
+	var err error
+
+	suite.logger, _ = nucliozap.NewNuclioZapTest("test")
+
+	// run an embedded server with jetstream enabled
+	suite.natsServer, err = server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  suite.T().TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	suite.Require().NoError(err)
+
+	go suite.natsServer.Start()
+	suite.Require().True(suite.natsServer.ReadyForConnections(5 * time.Second))
+}
+
+func (suite *TestSuite) TearDownSuite() {
+	suite.natsServer.Shutdown()
+}
+
+func (suite *TestSuite) SetupTest() {
+	var err error
+
+	suite.natsConnection, err = natsio.Connect(suite.natsServer.ClientURL())
+	suite.Require().NoError(err)
+
+	suite.jetStreamContext, err = suite.natsConnection.JetStream()
+	suite.Require().NoError(err)
+
+	_, err = suite.jetStreamContext.AddStream(&natsio.StreamConfig{
+		Name:     streamName,
+		Subjects: []string{"events.>"},
+	})
+	suite.Require().NoError(err)
+
+	suite.trigger = suite.createTrigger([]string{"events.created"}, functionconfig.ExplicitAckModeDisable, 3)
+}
+
+func (suite *TestSuite) TearDownTest() {
+	for _, natsSubscription := range suite.trigger.natsSubscriptions {
+		natsSubscription.Unsubscribe() // nolint: errcheck
+	}
+
+	suite.Require().NoError(suite.jetStreamContext.DeleteStream(streamName))
+	suite.natsConnection.Close()
+}
+
+func (suite *TestSuite) TestNewConfiguration() {
+	for _, testCase := range []struct {
+		name          string
+		attributes    map[string]interface{}
+		expectedError bool
+	}{
+		{
+			name: "core",
+			attributes: map[string]interface{}{
+				"topic": "events.created",
+			},
+		},
+		{
+			name: "jetstreamMultipleSubjects",
+			attributes: map[string]interface{}{
+				"mode":       "jetstream",
+				"topic":      "events.created",
+				"topics":     []string{"events.deleted"},
+				"maxDeliver": -1,
+				"ackWait":    "1m",
+			},
+		},
+		{
+			name:          "noTopics",
+			attributes:    map[string]interface{}{},
+			expectedError: true,
+		},
+		{
+			name: "invalidMode",
+			attributes: map[string]interface{}{
+				"topic": "events.created",
+				"mode":  "streaming",
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalidDeliverPolicy",
+			attributes: map[string]interface{}{
+				"topic":         "events.created",
+				"mode":          "jetstream",
+				"deliverPolicy": "first",
+			},
+			expectedError: true,
+		},
+		{
+			name: "invalidAckWait",
+			attributes: map[string]interface{}{
+				"topic":   "events.created",
+				"mode":    "jetstream",
+				"ackWait": "a while",
+			},
+			expectedError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			configuration, err := NewConfiguration("id", &functionconfig.Trigger{
+				Kind:       "nats",
+				Attributes: testCase.attributes,
+			}, suite.createRuntimeConfiguration())
+			if testCase.expectedError {
+				suite.Require().Error(err)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().NotEmpty(configuration.Topics)
+			suite.Require().Equal("events.created", configuration.Topics[0])
+			suite.Require().NotNil(configuration.MaxDeliver)
+		})
+	}
+}
+
+func (suite *TestSuite) TestExplicitAckRequiresJetStream() {
+	_, err := NewConfiguration("id", &functionconfig.Trigger{
+		Kind:            "nats",
+		ExplicitAckMode: functionconfig.ExplicitAckModeEnable,
+		Attributes: map[string]interface{}{
+			"topic": "events.created",
+		},
+	}, suite.createRuntimeConfiguration())
+	suite.Require().Error(err)
+}
+
+func (suite *TestSuite) TestCreateConsumerPerSubject() {
+	multiSubjectTrigger := suite.createTrigger([]string{"events.created", "events.*.deleted"},
+		functionconfig.ExplicitAckModeDisable,
+		3)
+	defer func() {
+		for _, natsSubscription := range multiSubjectTrigger.natsSubscriptions {
+			natsSubscription.Unsubscribe() // nolint: errcheck
+		}
+	}()
+
+	for consumerName, subject := range map[string]string{
+		durableName + "-events_created":     "events.created",
+		durableName + "-events_any_deleted": "events.*.deleted",
+	} {
+		consumerInfo, err := suite.jetStreamContext.ConsumerInfo(streamName, consumerName)
+		suite.Require().NoError(err)
+		suite.Require().Equal(subject, consumerInfo.Config.FilterSubject)
+		suite.Require().Equal(natsio.AckExplicitPolicy, consumerInfo.Config.AckPolicy)
+		suite.Require().Equal(3, consumerInfo.Config.MaxDeliver)
+	}
+}
+
+func (suite *TestSuite) TestConsumerOutlivesSubscription() {
+	suite.publish("events.created", "first")
+
+	// re-subscribing binds to the same durable consumer, which kept the message
+	for _, natsSubscription := range suite.trigger.natsSubscriptions {
+		suite.Require().NoError(natsSubscription.Unsubscribe())
+	}
+	suite.trigger.natsSubscriptions = nil
+	suite.Require().NoError(suite.trigger.createJetStreamSubscriptions(durableName))
+
+	event := suite.fetch()
+	suite.Require().Equal("first", string(event.GetBody()))
+	suite.Require().Equal(1, event.GetOffset())
+	suite.Require().Equal("events.created", event.GetTopic())
+}
+
+func (suite *TestSuite) TestSettleAcksProcessedMessage() {
+	suite.publish("events.created", "processed")
+
+	event := suite.fetch()
+	suite.trigger.settleMessage(event, nuclio.Response{}, nil, nil)
+
+	suite.requireNumAckPending(0)
+	suite.requireNoMessages()
+}
+
+func (suite *TestSuite) TestSettleRedeliversFailedMessage() {
+	suite.publish("events.created", "failed")
+
+	event := suite.fetch()
+	suite.trigger.settleMessage(event, nil, nil, errors.New("Handler failed"))
+
+	redeliveredEvent := suite.fetch()
+	suite.Require().Equal(event.GetOffset(), redeliveredEvent.GetOffset())
+	suite.Require().EqualValues(2, redeliveredEvent.metadata.NumDelivered)
+}
+
+func (suite *TestSuite) TestSettleRedeliversErrorResponse() {
+	suite.publish("events.created", "failed")
+
+	// RPC runtimes report handler exceptions as a 500 response with no error
+	event := suite.fetch()
+	suite.trigger.settleMessage(event, nuclio.Response{StatusCode: http.StatusInternalServerError}, nil, nil)
+
+	redeliveredEvent := suite.fetch()
+	suite.Require().Equal(event.GetOffset(), redeliveredEvent.GetOffset())
+	suite.Require().EqualValues(2, redeliveredEvent.metadata.NumDelivered)
+}
+
+func (suite *TestSuite) TestSettleRedeliversUnsubmittedMessage() {
+	suite.publish("events.created", "unsubmitted")
+
+	event := suite.fetch()
+	suite.trigger.settleMessage(event, nil, errors.New("No worker available"), nil)
+
+	redeliveredEvent := suite.fetch()
+	suite.Require().Equal(event.GetOffset(), redeliveredEvent.GetOffset())
+}
+
+func (suite *TestSuite) TestSettleTerminatesMessageOnLastDelivery() {
+	suite.publish("events.created", "poison")
+
+	for delivery := 1; delivery <= 3; delivery++ {
+		event := suite.fetch()
+		suite.Require().EqualValues(delivery, event.metadata.NumDelivered)
+		suite.Require().Equal(delivery == 3, suite.trigger.deliveriesExhausted(event))
+		suite.trigger.settleMessage(event, nil, nil, errors.New("Handler failed"))
+	}
+
+	suite.requireNumAckPending(0)
+	suite.requireNoMessages()
+}
+
+func (suite *TestSuite) TestSettleLeavesNoAckMessageForExplicitAck() {
+	suite.trigger.configuration.ExplicitAckMode = functionconfig.ExplicitAckModeEnable
+	controlMessageChan := make(chan *controlcommunication.ControlMessage)
+	go suite.trigger.explicitAckHandler(controlMessageChan)
+	defer close(controlMessageChan)
+
+	suite.publish("events.created", "acked")
+	suite.publish("events.created", "not acked")
+
+	// the first message is acked implicitly, the second is left for the handler
+	suite.trigger.settleMessage(suite.fetch(), nuclio.Response{}, nil, nil)
+	noAckEvent := suite.fetch()
+	suite.trigger.settleMessage(noAckEvent, nuclio.Response{
+		Headers: map[string]interface{}{
+			headers.StreamNoAck: true,
+		},
+	}, nil, nil)
+
+	suite.requireNumAckPending(1)
+
+	// an ack for a message that isn't pending is ignored
+	controlMessageChan <- suite.createExplicitAckControlMessage("events.created", 1)
+	suite.requireNumAckPending(1)
+
+	controlMessageChan <- suite.createExplicitAckControlMessage("events.created", noAckEvent.GetOffset())
+	suite.requireNumAckPending(0)
+	suite.Require().Empty(suite.trigger.pendingMessages)
+}
+
+func (suite *TestSuite) TestSettleLeavesMessageInExplicitOnlyMode() {
+	suite.trigger.configuration.ExplicitAckMode = functionconfig.ExplicitAckModeExplicitOnly
+
+	suite.publish("events.created", "explicit")
+	event := suite.fetch()
+	suite.trigger.settleMessage(event, nuclio.Response{}, nil, nil)
+
+	suite.requireNumAckPending(1)
+	suite.Require().Len(suite.trigger.pendingMessages, 1)
+}
+
+func (suite *TestSuite) TestCreateReplyMessage() {
+	replyMessage := suite.trigger.createReplyMessage("reply",
+		&nuclio.Response{
+			StatusCode:  http.StatusCreated,
+			ContentType: "application/json",
+			Body:        []byte(`{"id": 1}`),
+			Headers: map[string]interface{}{
+				"X-Tags": []string{"a", "b"},
+			},
+		},
+		nil,
+		nil)
+
+	suite.Require().Equal("reply", replyMessage.Subject)
+	suite.Require().Equal(`{"id": 1}`, string(replyMessage.Data))
+	suite.Require().Equal("201", replyMessage.Header.Get(headers.StatusCode))
+	suite.Require().Equal("application/json", replyMessage.Header.Get("Content-Type"))
+	suite.Require().Equal([]string{"a", "b"}, replyMessage.Header.Values("X-Tags"))
+
+	// errors carry only a status code
+	replyMessage = suite.trigger.createReplyMessage("reply",
+		nil,
+		errors.Wrap(worker.ErrNoAvailableWorkers, "Failed to allocate worker"),
+		nil)
+
+	suite.Require().Empty(replyMessage.Data)
+	suite.Require().Equal("503", replyMessage.Header.Get(headers.StatusCode))
+}
+
+func (suite *TestSuite) TestReply() {
+	requestSubscription, err := suite.natsConnection.Subscribe("rpc.echo", func(natsMessage *natsio.Msg) {
+		suite.trigger.reply(natsMessage, nuclio.Response{
+			Body: append([]byte("echo: "), natsMessage.Data...),
+		}, nil, nil)
+	})
+	suite.Require().NoError(err)
+	defer requestSubscription.Unsubscribe() // nolint: errcheck
+
+	replyMessage, err := suite.natsConnection.Request("rpc.echo", []byte("hello"), 5*time.Second)
+	suite.Require().NoError(err)
+	suite.Require().Equal("echo: hello", string(replyMessage.Data))
+}
+
+func (suite *TestSuite) createTrigger(subjects []string,
+	explicitAckMode functionconfig.ExplicitAckMode,
+	maxDeliver int) *nats {
+
+	natsTrigger := &nats{
+		AbstractTrigger: trigger.AbstractTrigger{
+			Logger: suite.logger,
+		},
+		configuration: &Configuration{
+			Configuration: trigger.Configuration{
+				Trigger: &functionconfig.Trigger{
+					ExplicitAckMode: explicitAckMode,
+				},
+			},
+			Topics:        subjects,
+			Mode:          ModeJetStream,
+			MaxDeliver:    &maxDeliver,
+			deliverPolicy: natsio.DeliverAllPolicy,
+			ackWait:       30 * time.Second,
+		},
+		natsConnection:  suite.natsConnection,
+		pendingMessages: map[string]*natsio.Msg{},
+	}
+
+	suite.Require().NoError(natsTrigger.createJetStreamSubscriptions(durableName))
+	return natsTrigger
+}
+
+func (suite *TestSuite) createRuntimeConfiguration() *runtime.Configuration {
+	return &runtime.Configuration{
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{},
+		},
+	}
+}
+
+func (suite *TestSuite) publish(subject string, body string) {
+	_, err := suite.jetStreamContext.Publish(subject, []byte(body))
+	suite.Require().NoError(err)
+}
+
+func (suite *TestSuite) fetch() *Event {
+	natsMessages, err := suite.trigger.natsSubscriptions[0].Fetch(1, natsio.MaxWait(5*time.Second))
+	suite.Require().NoError(err)
+	suite.Require().Len(natsMessages, 1)
+
+	metadata, err := natsMessages[0].Metadata()
+	suite.Require().NoError(err)
+
+	return &Event{
+		natsMessage: natsMessages[0],
+		metadata:    metadata,
+	}
+}
+
+func (suite *TestSuite) requireNoMessages() {
+	_, err := suite.trigger.natsSubscriptions[0].Fetch(1, natsio.MaxWait(500*time.Millisecond))
+	suite.Require().True(errors.Is(err, natsio.ErrTimeout) || errors.Is(err, context.DeadlineExceeded),
+		fmt.Sprintf("Expected no messages, got error: %v", err))
+}
+
+func (suite *TestSuite) requireNumAckPending(expectedNumAckPending int) {
+	suite.Require().Eventually(func() bool {
+		consumerInfo, err := suite.jetStreamContext.ConsumerInfo(streamName, durableName)
+		suite.Require().NoError(err)
+
+		return consumerInfo.NumAckPending == expectedNumAckPending
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func (suite *TestSuite) createExplicitAckControlMessage(topic string,
+	offset int) *controlcommunication.ControlMessage {
+	return &controlcommunication.ControlMessage{
+		Kind: controlcommunication.StreamMessageAckKind,
+		Attributes: map[string]interface{}{
+			"topic":     topic,
+			"partition": 0,
+			"offset":    offset,
+		},
+	}
+}
+
+func TestNATSSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
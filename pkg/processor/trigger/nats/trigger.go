@@ -18,15 +18,23 @@ package nats
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"text/template"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/common/headers"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
 
+	"github.com/mitchellh/mapstructure"
 	natsio "github.com/nats-io/nats.go"
 	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
@@ -34,9 +42,21 @@ import (
 
 type nats struct {
 	trigger.AbstractTrigger
-	configuration    *Configuration
-	stop             chan bool
-	natsSubscription *natsio.Subscription
+	configuration     *Configuration
+	stop              chan bool
+	natsConnection    *natsio.Conn
+	natsSubscriptions []*natsio.Subscription
+
+	// jetstream consumers fetch until the context is cancelled
+	consumersContext    context.Context
+	cancelConsumers     context.CancelFunc
+	consumersWaitGroup  sync.WaitGroup
+	jetStreamMaxDeliver int
+
+	// messages that were handled but are left for the handler to ack explicitly, by subject and sequence
+	pendingMessages               map[string]*natsio.Msg
+	pendingMessagesLock           sync.Mutex
+	explicitAckControlMessageChan chan *controlcommunication.ControlMessage
 }
 
 func newTrigger(parentLogger logger.Logger,
@@ -58,6 +78,7 @@ func newTrigger(parentLogger logger.Logger,
 		AbstractTrigger: abstractTrigger,
 		configuration:   configuration,
 		stop:            make(chan bool),
+		pendingMessages: map[string]*natsio.Msg{},
 	}
 	newTrigger.AbstractTrigger.Trigger = newTrigger
 
@@ -79,57 +100,80 @@ func (n *nats) validateConfiguration() error {
 		return errors.New("Invalid URL. Must begin with 'nats://'")
 	}
 
+	if *n.configuration.MaxDeliver == 0 {
+		return errors.New("Max deliver must not be zero. Set a negative value for unlimited deliveries")
+	}
+
 	return nil
 }
 
 func (n *nats) Start(checkpoint functionconfig.Checkpoint) error {
-	queueName := n.configuration.QueueName
-	if queueName == "" {
-		queueName = "{{.Namespace}}.{{.Name}}-{{.Id}}"
-	}
+	var err error
+
+	n.Logger.InfoWith("Starting",
+		"serverURL", n.configuration.URL,
+		"topics", n.configuration.Topics,
+		"mode", n.configuration.Mode)
 
-	queueNameTemplate, err := template.New("queueName").Parse(queueName)
+	n.natsConnection, err = natsio.Connect(n.configuration.URL)
 	if err != nil {
-		return errors.Wrap(err, "Failed to create queueName template")
+		return errors.Wrapf(err, "Can't connect to NATS server %s", n.configuration.URL)
 	}
 
-	var queueNameTemplateBuffer bytes.Buffer
-	err = queueNameTemplate.Execute(&queueNameTemplateBuffer, &map[string]interface{}{
-		"Namespace":   n.configuration.RuntimeConfiguration.Meta.Namespace,
-		"Name":        n.configuration.RuntimeConfiguration.Meta.Name,
-		"Id":          n.configuration.ID,
-		"Labels":      n.configuration.RuntimeConfiguration.Meta.Labels,
-		"Annotations": n.configuration.RuntimeConfiguration.Meta.Annotations,
-	})
-	if err != nil {
-		return errors.Wrap(err, "Failed to execute queueName template")
+	if n.configuration.Mode == ModeJetStream {
+		return n.startJetStream()
 	}
 
-	queueName = queueNameTemplateBuffer.String()
-	n.Logger.InfoWith("Starting",
-		"serverURL", n.configuration.URL,
-		"topic", n.configuration.Topic,
-		"queueName", queueName)
+	return n.startCore()
+}
+
+func (n *nats) Stop(force bool) (functionconfig.Checkpoint, error) {
+	if n.configuration.Mode == ModeJetStream {
+		n.stopJetStream()
+	} else {
+		n.stop <- true
+	}
 
-	natsConnection, err := natsio.Connect(n.configuration.URL)
+	for _, natsSubscription := range n.natsSubscriptions {
+		if err := natsSubscription.Unsubscribe(); err != nil {
+			return nil, errors.Wrapf(err, "Failed to unsubscribe from %s", natsSubscription.Subject)
+		}
+	}
+
+	n.natsConnection.Close()
+	return nil, nil
+}
+
+func (n *nats) GetConfig() map[string]interface{} {
+	return common.StructureToMap(n.configuration)
+}
+
+func (n *nats) startCore() error {
+	queueName := n.configuration.QueueName
+	if queueName == "" {
+		queueName = "{{.Namespace}}.{{.Name}}-{{.Id}}"
+	}
+
+	queueName, err := n.renderName("queueName", queueName)
 	if err != nil {
-		return errors.Wrapf(err, "Can't connect to NATS server %s", n.configuration.URL)
+		return errors.Wrap(err, "Failed to render queue name")
 	}
 
+	// all subjects are delivered to the same channel
 	messageChan := make(chan *natsio.Msg, 64)
-	n.natsSubscription, err = natsConnection.ChanQueueSubscribe(n.configuration.Topic, n.configuration.QueueName, messageChan)
-	if err != nil {
-		return errors.Wrapf(err, "Can't subscribe to topic %q in queue %q", n.configuration.Topic, queueName)
+	for _, topic := range n.configuration.Topics {
+		natsSubscription, err := n.natsConnection.ChanQueueSubscribe(topic, queueName, messageChan)
+		if err != nil {
+			return errors.Wrapf(err, "Can't subscribe to topic %q in queue %q", topic, queueName)
+		}
+
+		n.natsSubscriptions = append(n.natsSubscriptions, natsSubscription)
 	}
+
 	go n.listenForMessages(messageChan)
 	return nil
 }
 
-func (n *nats) Stop(force bool) (functionconfig.Checkpoint, error) {
-	n.stop <- true
-	return nil, n.natsSubscription.Unsubscribe()
-}
-
 func (n *nats) listenForMessages(messageChan chan *natsio.Msg) {
 	for {
 		select {
@@ -142,22 +186,20 @@ func (n *nats) listenForMessages(messageChan chan *natsio.Msg) {
 					natsMessage: natsMessage,
 				}
 
-				// allocate a worker
-				workerInstance, err := n.WorkerAllocator.Allocate(time.Duration(*n.configuration.WorkerAvailabilityTimeoutMilliseconds) * time.Millisecond)
-				if err != nil {
-					n.UpdateStatistics(false)
-					n.Logger.ErrorWith("Failed to allocate worker", "error", err)
-					return
+				response, submitError, processError := n.AllocateWorkerAndSubmitEvent(event,
+					nil,
+					n.getWorkerAvailabilityTimeout())
+				if submitError != nil {
+					n.Logger.ErrorWith("Failed to submit event", "error", submitError)
 				}
-
-				// submit the event to the worker, don't really do anything with response
-				_, processErr := n.SubmitEventToWorker(nil, workerInstance, event)
-				if processErr != nil {
-					n.Logger.ErrorWith("Can't process event", "error", processErr)
+				if processError != nil {
+					n.Logger.ErrorWith("Can't process event", "error", processError)
 				}
 
-				// release the worker
-				n.WorkerAllocator.Release(workerInstance)
+				// the publisher waits for a response
+				if natsMessage.Reply != "" {
+					n.reply(natsMessage, response, submitError, processError)
+				}
 			}()
 
 		case <-n.stop:
@@ -166,6 +208,392 @@ func (n *nats) listenForMessages(messageChan chan *natsio.Msg) {
 	}
 }
 
-func (n *nats) GetConfig() map[string]interface{} {
-	return common.StructureToMap(n.configuration)
+// reply publishes the outcome of handling a request to its reply subject
+func (n *nats) reply(natsMessage *natsio.Msg,
+	response interface{},
+	submitError error,
+	processError error) {
+	replyMessage := n.createReplyMessage(natsMessage.Reply, response, submitError, processError)
+
+	if err := n.natsConnection.PublishMsg(replyMessage); err != nil {
+		n.Logger.WarnWith("Failed to publish reply",
+			"subject", natsMessage.Subject,
+			"reply", natsMessage.Reply,
+			"err", err.Error())
+	}
+}
+
+// createReplyMessage formats the handler's response the way the http trigger does, carrying the
+// status code and content type as headers
+func (n *nats) createReplyMessage(replySubject string,
+	response interface{},
+	submitError error,
+	processError error) *natsio.Msg {
+	replyMessage := natsio.NewMsg(replySubject)
+
+	resolvedResponse := trigger.ResolveResponse(response, submitError, processError)
+
+	for headerKey, headerValues := range resolvedResponse.Headers {
+		for _, headerValue := range headerValues {
+			replyMessage.Header.Add(headerKey, headerValue)
+		}
+	}
+
+	if resolvedResponse.ContentType != "" {
+		replyMessage.Header.Set("Content-Type", resolvedResponse.ContentType)
+	}
+
+	if resolvedResponse.StatusCode != 0 {
+		replyMessage.Header.Set(headers.StatusCode, strconv.Itoa(resolvedResponse.StatusCode))
+	}
+
+	replyMessage.Data = resolvedResponse.Body
+
+	return replyMessage
+}
+
+func (n *nats) startJetStream() error {
+	durableName := n.configuration.DurableName
+	if durableName == "" {
+		durableName = "{{.Namespace}}-{{.Name}}-{{.Id}}"
+	}
+
+	durableName, err := n.renderName("durableName", durableName)
+	if err != nil {
+		return errors.Wrap(err, "Failed to render durable name")
+	}
+
+	if err := n.createJetStreamSubscriptions(durableName); err != nil {
+		return errors.Wrap(err, "Failed to create JetStream subscriptions")
+	}
+
+	// acks sent by the handler arrive through the workers' control communication
+	if functionconfig.ExplicitAckEnabled(n.configuration.ExplicitAckMode) {
+		n.explicitAckControlMessageChan = make(chan *controlcommunication.ControlMessage)
+		if err := n.SubscribeToControlMessageKind(controlcommunication.StreamMessageAckKind,
+			n.explicitAckControlMessageChan); err != nil {
+			return errors.Wrap(err, "Failed to subscribe to explicit ack control messages")
+		}
+
+		go n.explicitAckHandler(n.explicitAckControlMessageChan)
+	}
+
+	n.consumersContext, n.cancelConsumers = context.WithCancel(context.Background())
+	for _, natsSubscription := range n.natsSubscriptions {
+		n.consumersWaitGroup.Add(1)
+		go n.consumeJetStreamSubscription(natsSubscription)
+	}
+
+	return nil
+}
+
+func (n *nats) stopJetStream() {
+
+	// stop fetching and wait for the messages in flight to be settled
+	n.cancelConsumers()
+	n.consumersWaitGroup.Wait()
+
+	if n.explicitAckControlMessageChan != nil {
+		if err := n.UnsubscribeFromControlMessageKind(controlcommunication.StreamMessageAckKind,
+			n.explicitAckControlMessageChan); err != nil {
+			n.Logger.WarnWith("Failed to unsubscribe from explicit ack control messages", "err", err.Error())
+		}
+
+		close(n.explicitAckControlMessageChan)
+		n.explicitAckControlMessageChan = nil
+	}
+}
+
+// createJetStreamSubscriptions binds a pull subscription to a durable consumer per subject, creating the
+// consumers if needed. consumers are created explicitly so that unsubscribing doesn't delete them
+func (n *nats) createJetStreamSubscriptions(durableName string) error {
+	jetStreamContext, err := n.natsConnection.JetStream()
+	if err != nil {
+		return errors.Wrap(err, "Failed to create JetStream context")
+	}
+
+	// nats counts unlimited deliveries as -1
+	n.jetStreamMaxDeliver = *n.configuration.MaxDeliver
+	if n.jetStreamMaxDeliver < 0 {
+		n.jetStreamMaxDeliver = -1
+	}
+
+	for _, subject := range n.configuration.Topics {
+		streamName := n.configuration.StreamName
+		if streamName == "" {
+			streamName, err = jetStreamContext.StreamNameBySubject(subject)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to find a stream for subject %s", subject)
+			}
+		}
+
+		consumerName := n.resolveConsumerName(durableName, subject)
+		consumerConfig := &natsio.ConsumerConfig{
+			Durable:       consumerName,
+			FilterSubject: subject,
+			DeliverPolicy: n.configuration.deliverPolicy,
+			AckPolicy:     natsio.AckExplicitPolicy,
+			AckWait:       n.configuration.ackWait,
+			MaxDeliver:    n.jetStreamMaxDeliver,
+		}
+
+		consumerInfo, err := jetStreamContext.ConsumerInfo(streamName, consumerName)
+		switch {
+		case errors.Is(err, natsio.ErrConsumerNotFound):
+			if _, err := jetStreamContext.AddConsumer(streamName, consumerConfig); err != nil {
+				return errors.Wrapf(err, "Failed to create consumer %s on stream %s", consumerName, streamName)
+			}
+		case err != nil:
+			return errors.Wrapf(err, "Failed to get consumer %s on stream %s", consumerName, streamName)
+		default:
+
+			// the deliver policy of an existing consumer can't be changed
+			consumerConfig.DeliverPolicy = consumerInfo.Config.DeliverPolicy
+			if _, err := jetStreamContext.UpdateConsumer(streamName, consumerConfig); err != nil {
+				return errors.Wrapf(err, "Failed to update consumer %s on stream %s", consumerName, streamName)
+			}
+		}
+
+		natsSubscription, err := jetStreamContext.PullSubscribe(subject,
+			consumerName,
+			natsio.Bind(streamName, consumerName))
+		if err != nil {
+			return errors.Wrapf(err, "Failed to subscribe to subject %s", subject)
+		}
+
+		n.Logger.DebugWith("Subscribed to JetStream consumer",
+			"subject", subject,
+			"stream", streamName,
+			"consumer", consumerName)
+
+		n.natsSubscriptions = append(n.natsSubscriptions, natsSubscription)
+	}
+
+	return nil
+}
+
+func (n *nats) consumeJetStreamSubscription(natsSubscription *natsio.Subscription) {
+	defer n.consumersWaitGroup.Done()
+
+	for {
+		fetchContext, cancelFetch := context.WithTimeout(n.consumersContext, n.configuration.fetchMaxWait)
+		natsMessages, err := natsSubscription.Fetch(n.configuration.FetchBatchSize, natsio.Context(fetchContext))
+		cancelFetch()
+
+		// stopped
+		if n.consumersContext.Err() != nil {
+
+			// messages we've fetched but won't handle are redelivered
+			for _, natsMessage := range natsMessages {
+				n.nakMessage(natsMessage, 0)
+			}
+			return
+		}
+
+		if err != nil {
+			if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, natsio.ErrTimeout) {
+				n.Logger.WarnWith("Failed to fetch messages",
+					"subject", natsSubscription.Subject,
+					"err", err.Error())
+
+				// don't spin on a persistent error
+				time.Sleep(time.Second)
+			}
+
+			continue
+		}
+
+		// handle the batch concurrently and fetch the next one once it's settled
+		messagesWaitGroup := sync.WaitGroup{}
+		messagesWaitGroup.Add(len(natsMessages))
+
+		for _, natsMessage := range natsMessages {
+			go func(natsMessage *natsio.Msg) {
+				defer messagesWaitGroup.Done()
+				n.processJetStreamMessage(natsMessage)
+			}(natsMessage)
+		}
+
+		messagesWaitGroup.Wait()
+	}
+}
+
+func (n *nats) processJetStreamMessage(natsMessage *natsio.Msg) {
+	event := &Event{
+		natsMessage: natsMessage,
+	}
+
+	metadata, err := natsMessage.Metadata()
+	if err != nil {
+		n.Logger.WarnWith("Failed to get message metadata",
+			"subject", natsMessage.Subject,
+			"err", err.Error())
+	}
+	event.metadata = metadata
+
+	response, submitError, processError := n.AllocateWorkerAndSubmitEvent(event,
+		nil,
+		n.getWorkerAvailabilityTimeout())
+
+	n.settleMessage(event, response, submitError, processError)
+}
+
+// settleMessage acks, naks or terminates a message according to the outcome of its handling
+func (n *nats) settleMessage(event *Event, response interface{}, submitError error, processError error) {
+	natsMessage := event.natsMessage
+
+	// RPC runtimes report handler failures as error responses rather than errors
+	handleError := trigger.EventError(response, processError)
+
+	switch {
+	case submitError != nil:
+		n.Logger.WarnWith("Failed to submit message, redelivering",
+			"subject", natsMessage.Subject,
+			"err", submitError.Error())
+
+		// no worker handled the message, so there's no reason to delay its redelivery
+		n.nakMessage(natsMessage, 0)
+
+	case handleError != nil:
+		if n.deliveriesExhausted(event) {
+			n.Logger.WarnWith("Failed to process message, terminating it after its last delivery",
+				"subject", natsMessage.Subject,
+				"offset", event.GetOffset(),
+				"err", handleError.Error())
+
+			if err := natsMessage.Term(); err != nil {
+				n.Logger.WarnWith("Failed to terminate message", "subject", natsMessage.Subject, "err", err.Error())
+			}
+			return
+		}
+
+		n.Logger.DebugWith("Failed to process message, redelivering",
+			"subject", natsMessage.Subject,
+			"offset", event.GetOffset(),
+			"err", handleError.Error())
+
+		n.nakMessage(natsMessage, n.configuration.nakDelay)
+
+	case !trigger.EventShouldBeAcked(n.configuration.ExplicitAckMode, response):
+
+		// the handler will ack the message through the control communication
+		n.addPendingMessage(event)
+
+	default:
+		if err := natsMessage.Ack(); err != nil {
+			n.Logger.WarnWith("Failed to ack message", "subject", natsMessage.Subject, "err", err.Error())
+		}
+	}
+}
+
+// explicitAckHandler acks the pending messages that the handler acks through the control communication
+func (n *nats) explicitAckHandler(controlMessageChan chan *controlcommunication.ControlMessage) {
+	n.Logger.InfoWith("Listening for explicit ack control messages")
+
+	for streamAckControlMessage := range controlMessageChan {
+		explicitAckAttributes := &controlcommunication.ControlMessageAttributesExplicitAck{}
+		if err := mapstructure.Decode(streamAckControlMessage.Attributes, explicitAckAttributes); err != nil {
+			n.Logger.WarnWith("Failed decoding control message attributes", "err", err.Error())
+			continue
+		}
+
+		natsMessage := n.popPendingMessage(explicitAckAttributes.Topic, explicitAckAttributes.Offset)
+		if natsMessage == nil {
+			n.Logger.DebugWith("Received explicit ack for an unknown message",
+				"topic", explicitAckAttributes.Topic,
+				"offset", explicitAckAttributes.Offset)
+			continue
+		}
+
+		if err := natsMessage.Ack(); err != nil {
+			n.Logger.WarnWith("Failed to ack message on explicit ack request",
+				"topic", explicitAckAttributes.Topic,
+				"offset", explicitAckAttributes.Offset,
+				"err", err.Error())
+		}
+	}
+
+	n.Logger.InfoWith("Stopped listening for explicit ack control messages")
+}
+
+func (n *nats) addPendingMessage(event *Event) {
+	n.pendingMessagesLock.Lock()
+	defer n.pendingMessagesLock.Unlock()
+
+	// a redelivered message replaces its previous delivery, which can no longer be acked
+	n.pendingMessages[n.getPendingMessageKey(event.GetTopic(), int64(event.GetOffset()))] = event.natsMessage
+}
+
+func (n *nats) popPendingMessage(subject string, offset int64) *natsio.Msg {
+	n.pendingMessagesLock.Lock()
+	defer n.pendingMessagesLock.Unlock()
+
+	pendingMessageKey := n.getPendingMessageKey(subject, offset)
+	natsMessage := n.pendingMessages[pendingMessageKey]
+	delete(n.pendingMessages, pendingMessageKey)
+
+	return natsMessage
+}
+
+func (n *nats) getPendingMessageKey(subject string, offset int64) string {
+	return fmt.Sprintf("%s/%d", subject, offset)
+}
+
+func (n *nats) nakMessage(natsMessage *natsio.Msg, delay time.Duration) {
+	var err error
+
+	if delay > 0 {
+		err = natsMessage.NakWithDelay(delay)
+	} else {
+		err = natsMessage.Nak()
+	}
+
+	if err != nil {
+		n.Logger.WarnWith("Failed to nak message", "subject", natsMessage.Subject, "err", err.Error())
+	}
+}
+
+// deliveriesExhausted returns whether the server won't redeliver the message again
+func (n *nats) deliveriesExhausted(event *Event) bool {
+	if n.jetStreamMaxDeliver < 0 || event.metadata == nil {
+		return false
+	}
+
+	return event.metadata.NumDelivered >= uint64(n.jetStreamMaxDeliver)
+}
+
+// resolveConsumerName returns the name of the durable consumer of a subject. with multiple subjects, each
+// gets its own consumer
+func (n *nats) resolveConsumerName(durableName string, subject string) string {
+	if len(n.configuration.Topics) == 1 {
+		return durableName
+	}
+
+	// consumer names can't contain '.', '*' or '>'
+	return fmt.Sprintf("%s-%s", durableName, strings.NewReplacer(".", "_", "*", "any", ">", "all").Replace(subject))
+}
+
+func (n *nats) renderName(templateName string, nameTemplate string) (string, error) {
+	parsedTemplate, err := template.New(templateName).Parse(nameTemplate)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to create %s template", templateName)
+	}
+
+	var templateBuffer bytes.Buffer
+	err = parsedTemplate.Execute(&templateBuffer, &map[string]interface{}{
+		"Namespace":   n.configuration.RuntimeConfiguration.Meta.Namespace,
+		"Name":        n.configuration.RuntimeConfiguration.Meta.Name,
+		"Id":          n.configuration.ID,
+		"Labels":      n.configuration.RuntimeConfiguration.Meta.Labels,
+		"Annotations": n.configuration.RuntimeConfiguration.Meta.Annotations,
+	})
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed to execute %s template", templateName)
+	}
+
+	return templateBuffer.String(), nil
+}
+
+func (n *nats) getWorkerAvailabilityTimeout() time.Duration {
+	return time.Duration(*n.configuration.WorkerAvailabilityTimeoutMilliseconds) * time.Millisecond
 }
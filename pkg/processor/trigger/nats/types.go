@@ -17,18 +17,72 @@ limitations under the License.
 package nats
 
 import (
+	"time"
+
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/trigger"
 
 	"github.com/mitchellh/mapstructure"
+	natsio "github.com/nats-io/nats.go"
 	"github.com/nuclio/errors"
 )
 
+type Mode string
+
+const (
+
+	// ModeCore subscribes to subjects with a queue group. messages published while no replica
+	// is subscribed are lost
+	ModeCore Mode = "core"
+
+	// ModeJetStream consumes subjects through durable JetStream pull consumers, acknowledging
+	// each message according to the handler's outcome
+	ModeJetStream Mode = "jetstream"
+)
+
+const (
+	DefaultMaxDeliver = 5
+)
+
 type Configuration struct {
 	trigger.Configuration
 	Topic     string
 	QueueName string
+
+	// additional subjects to subscribe to, on top of Topic
+	Topics []string
+
+	// core (default) or jetstream
+	Mode Mode
+
+	// the stream holding the subjects. if not set, it is looked up per subject
+	StreamName string
+
+	// the name of the durable consumer (a Go template, like the queue name). with multiple subjects,
+	// a consumer is created per subject, its name suffixed by the subject
+	DurableName string
+
+	// where a newly created consumer starts - "all" (default), "new" or "last"
+	DeliverPolicy string
+
+	// the number of times a message is delivered before it is terminated. negative for unlimited
+	MaxDeliver *int
+
+	// how long the server waits for an ack before redelivering the message
+	AckWait string
+
+	// how long to delay the redelivery of a message whose handling failed
+	NakDelay string
+
+	// how many messages to pull at once, and how long to wait for them to arrive
+	FetchBatchSize int
+	FetchMaxWait   string
+
+	deliverPolicy natsio.DeliverPolicy
+	ackWait       time.Duration
+	nakDelay      time.Duration
+	fetchMaxWait  time.Duration
 }
 
 func NewConfiguration(id string,
@@ -48,7 +102,85 @@ func NewConfiguration(id string,
 		return nil, errors.Wrap(err, "Failed to decode attributes")
 	}
 
-	// TODO: validate
+	// the topic and topics are joined into a single list of subjects
+	if newConfiguration.Topic != "" {
+		newConfiguration.Topics = append([]string{newConfiguration.Topic}, newConfiguration.Topics...)
+	}
+
+	if len(newConfiguration.Topics) == 0 {
+		return nil, errors.New("At least one topic must be specified")
+	}
+
+	// defaults
+	if newConfiguration.Mode == "" {
+		newConfiguration.Mode = ModeCore
+	}
+	if newConfiguration.DeliverPolicy == "" {
+		newConfiguration.DeliverPolicy = "all"
+	}
+	if newConfiguration.MaxDeliver == nil {
+		defaultMaxDeliver := DefaultMaxDeliver
+		newConfiguration.MaxDeliver = &defaultMaxDeliver
+	}
+	if newConfiguration.FetchBatchSize == 0 {
+		newConfiguration.FetchBatchSize = newConfiguration.NumWorkers
+	}
+
+	if err := newConfiguration.PopulateExplicitAckMode("", triggerConfiguration.ExplicitAckMode); err != nil {
+		return nil, errors.Wrap(err, "Failed to populate explicit ack mode")
+	}
+
+	switch newConfiguration.Mode {
+	case ModeCore:
+		if functionconfig.ExplicitAckEnabled(newConfiguration.ExplicitAckMode) {
+			return nil, errors.New("Explicit ack mode is supported only in jetstream mode")
+		}
+	case ModeJetStream:
+		newConfiguration.deliverPolicy, err = newConfiguration.resolveDeliverPolicy(newConfiguration.DeliverPolicy)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to resolve deliver policy")
+		}
+	default:
+		return nil, errors.Errorf("Unsupported mode: %s", newConfiguration.Mode)
+	}
+
+	for _, durationConfigField := range []trigger.DurationConfigField{
+		{
+			Name:    "ack wait",
+			Value:   newConfiguration.AckWait,
+			Field:   &newConfiguration.ackWait,
+			Default: 30 * time.Second,
+		},
+		{
+			Name:    "nak delay",
+			Value:   newConfiguration.NakDelay,
+			Field:   &newConfiguration.nakDelay,
+			Default: 0,
+		},
+		{
+			Name:    "fetch max wait",
+			Value:   newConfiguration.FetchMaxWait,
+			Field:   &newConfiguration.fetchMaxWait,
+			Default: 5 * time.Second,
+		},
+	} {
+		if err = newConfiguration.ParseDurationOrDefault(&durationConfigField); err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse %s", durationConfigField.Name)
+		}
+	}
 
 	return &newConfiguration, nil
 }
+
+func (c *Configuration) resolveDeliverPolicy(deliverPolicy string) (natsio.DeliverPolicy, error) {
+	switch deliverPolicy {
+	case "all":
+		return natsio.DeliverAllPolicy, nil
+	case "new":
+		return natsio.DeliverNewPolicy, nil
+	case "last":
+		return natsio.DeliverLastPolicy, nil
+	default:
+		return natsio.DeliverAllPolicy, errors.Errorf("Unsupported deliver policy: %s", deliverPolicy)
+	}
+}
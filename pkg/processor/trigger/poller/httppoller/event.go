@@ -0,0 +1,56 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httppoller
+
+import (
+	"time"
+
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// Event holds a single item read from the polled URL
+type Event struct {
+	nuclio.AbstractEvent
+	body      []byte
+	id        string
+	url       string
+	timestamp time.Time
+}
+
+// GetBody returns the item, JSON encoded
+func (e *Event) GetBody() []byte {
+	return e.body
+}
+
+func (e *Event) GetContentType() string {
+	return "application/json"
+}
+
+// GetID returns the item ID, if an ID path is configured
+func (e *Event) GetID() nuclio.ID {
+	return nuclio.ID(e.id)
+}
+
+// GetURL returns the URL the item was read from
+func (e *Event) GetURL() string {
+	return e.url
+}
+
+// GetTimestamp returns the time the item was read
+func (e *Event) GetTimestamp() time.Time {
+	return e.timestamp
+}
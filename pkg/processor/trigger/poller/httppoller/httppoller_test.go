@@ -0,0 +1,381 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httppoller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	nucliozap "github.com/nuclio/zap"
+	"github.com/stretchr/testify/suite"
+)
+
+// mockRuntime records the IDs of the events it processes
+type mockRuntime struct {
+	runtime.Runtime
+	processedIDs   []string
+	failIDs        map[string]bool
+	errorStatusIDs map[string]bool
+	lock           sync.Mutex
+}
+
+func (mr *mockRuntime) ProcessEvent(event nuclio.Event, functionLogger logger.Logger) (interface{}, error) {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	eventID := string(event.GetID())
+
+	// fail once
+	if mr.failIDs[eventID] {
+		delete(mr.failIDs, eventID)
+		return nil, errors.New("Failed to process event")
+	}
+
+	// fail once, the way RPC runtimes report handler exceptions
+	if mr.errorStatusIDs[eventID] {
+		delete(mr.errorStatusIDs, eventID)
+		return nuclio.Response{StatusCode: http.StatusInternalServerError}, nil
+	}
+
+	mr.processedIDs = append(mr.processedIDs, eventID)
+	return nil, nil
+}
+
+func (mr *mockRuntime) getProcessedIDs() []string {
+	mr.lock.Lock()
+	defer mr.lock.Unlock()
+
+	return append([]string{}, mr.processedIDs...)
+}
+
+// mockServer serves pages of items, keyed by cursor
+type mockServer struct {
+	pages        map[string][]map[string]interface{}
+	nextCursors  map[string]string
+	etag         string
+	notModifieds int
+	lock         sync.Mutex
+}
+
+func (ms *mockServer) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	if request.Header.Get("Authorization") != "Bearer token" {
+		responseWriter.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if ms.etag != "" {
+		if request.Header.Get("If-None-Match") == ms.etag {
+			ms.notModifieds++
+			responseWriter.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		responseWriter.Header().Set("ETag", ms.etag)
+	}
+
+	cursor := request.URL.Query().Get("cursor")
+
+	encodedBody, _ := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{
+			"items": ms.pages[cursor],
+		},
+		"meta": map[string]interface{}{
+			"next": ms.nextCursors[cursor],
+		},
+	})
+
+	responseWriter.Write(encodedBody) // nolint: errcheck
+}
+
+func (ms *mockServer) setPage(cursor string, itemIDs []int, nextCursor string) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	items := []map[string]interface{}{}
+	for _, itemID := range itemIDs {
+		items = append(items, map[string]interface{}{"id": itemID})
+	}
+
+	ms.pages[cursor] = items
+	ms.nextCursors[cursor] = nextCursor
+}
+
+func (ms *mockServer) setETag(etag string) {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	ms.etag = etag
+}
+
+func (ms *mockServer) getNotModifieds() int {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+
+	return ms.notModifieds
+}
+
+type TestSuite struct {
+	suite.Suite
+	logger     logger.Logger
+	runtime    *mockRuntime
+	server     *mockServer
+	httpServer *httptest.Server
+	trigger    *httpPoller
+}
+
+func (suite *TestSuite) SetupSuite() {
+	var err error
+
+	suite.logger, err = nucliozap.NewNuclioZapTest("test")
+	suite.Require().NoError(err)
+}
+
+func (suite *TestSuite) SetupTest() {
+	suite.runtime = &mockRuntime{
+		failIDs:        map[string]bool{},
+		errorStatusIDs: map[string]bool{},
+	}
+
+	suite.server = &mockServer{
+		pages:       map[string][]map[string]interface{}{},
+		nextCursors: map[string]string{},
+	}
+
+	suite.httpServer = httptest.NewServer(suite.server)
+	suite.trigger = nil
+}
+
+func (suite *TestSuite) TearDownTest() {
+	if suite.trigger != nil {
+		suite.trigger.Stop(true) // nolint: errcheck
+	}
+
+	suite.httpServer.Close()
+}
+
+func (suite *TestSuite) TestPollPages() {
+	suite.server.setPage("", []int{1, 2}, "p2")
+	suite.server.setPage("p2", []int{3}, "")
+
+	suite.startTrigger(nil)
+	suite.waitForProcessedIDs([]string{"1", "2", "3"})
+
+	// an item added to the last page is picked up, without emitting the page's other items again
+	suite.server.setPage("p2", []int{3, 4}, "")
+	suite.waitForProcessedIDs([]string{"1", "2", "3", "4"})
+
+	checkpointState := suite.stopTrigger()
+	suite.Require().Equal("p2", checkpointState.Cursor)
+	suite.Require().Equal([]string{"1", "2", "3", "4"}, checkpointState.SeenItemIDs)
+}
+
+func (suite *TestSuite) TestNotModified() {
+	suite.server.setETag(`"v1"`)
+	suite.server.setPage("", []int{1}, "")
+
+	suite.startTrigger(nil)
+	suite.waitForProcessedIDs([]string{"1"})
+
+	// following polls are not modified
+	suite.Require().Eventually(func() bool {
+		return suite.server.getNotModifieds() > 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	checkpointState := suite.stopTrigger()
+	suite.Require().Equal(`"v1"`, checkpointState.ETag)
+}
+
+func (suite *TestSuite) TestFailedItemsAreRetried() {
+	suite.server.setETag(`"v1"`)
+	suite.server.setPage("", []int{1, 2, 3}, "")
+	suite.runtime.failIDs["2"] = true
+
+	suite.startTrigger(nil)
+
+	// the ETag isn't stored while an item failed, so the document is read again and the failed item retried
+	suite.waitForProcessedIDs([]string{"1", "3", "2"})
+}
+
+func (suite *TestSuite) TestErrorResponseItemsAreRetried() {
+	suite.server.setETag(`"v1"`)
+	suite.server.setPage("", []int{1, 2, 3}, "")
+	suite.runtime.errorStatusIDs["2"] = true
+
+	suite.startTrigger(nil)
+
+	// an error response fails the item just like an error does, so it's retried
+	suite.waitForProcessedIDs([]string{"1", "3", "2"})
+}
+
+func (suite *TestSuite) TestResumeFromCheckpoint() {
+	suite.server.setPage("", []int{1, 2}, "p2")
+	suite.server.setPage("p2", []int{3, 4}, "")
+
+	encodedState, err := json.Marshal(state{
+		Cursor:      "p2",
+		SeenItemIDs: []string{"3"},
+	})
+	suite.Require().NoError(err)
+
+	checkpoint := string(encodedState)
+	suite.startTrigger(&checkpoint)
+	suite.waitForProcessedIDs([]string{"4"})
+}
+
+func (suite *TestSuite) TestNewConfiguration() {
+	for _, testCase := range []struct {
+		name        string
+		url         string
+		attributes  map[string]interface{}
+		expectError bool
+	}{
+		{
+			name: "valid",
+			url:  "http://example.com/items",
+			attributes: map[string]interface{}{
+				"itemsPath":  "$.data.items",
+				"idPath":     ".id",
+				"cursorPath": "$.meta.next",
+			},
+		},
+		{
+			name:        "missingURL",
+			attributes:  map[string]interface{}{},
+			expectError: true,
+		},
+		{
+			name: "invalidPath",
+			url:  "http://example.com/items",
+			attributes: map[string]interface{}{
+				"itemsPath": "$.data[",
+			},
+			expectError: true,
+		},
+		{
+			name: "invalidRequestTimeout",
+			url:  "http://example.com/items",
+			attributes: map[string]interface{}{
+				"requestTimeout": "soon",
+			},
+			expectError: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			configuration, err := NewConfiguration("test", &functionconfig.Trigger{
+				Kind:       "httppoller",
+				URL:        testCase.url,
+				Attributes: testCase.attributes,
+			}, suite.createRuntimeConfiguration())
+
+			if testCase.expectError {
+				suite.Require().Error(err)
+				return
+			}
+
+			suite.Require().NoError(err)
+			suite.Require().Equal(60000, configuration.IntervalMs)
+			suite.Require().Equal(10, configuration.MaxPages)
+			suite.Require().Equal(30*time.Second, configuration.requestTimeout)
+		})
+	}
+}
+
+func (suite *TestSuite) startTrigger(checkpoint functionconfig.Checkpoint) {
+	configuration, err := NewConfiguration("test", &functionconfig.Trigger{
+		Kind: "httppoller",
+		URL:  suite.httpServer.URL,
+		Attributes: map[string]interface{}{
+			"intervalMs": 50,
+			"headers": map[string]string{
+				"Authorization": "Bearer token",
+			},
+			"itemsPath":       "$.data.items",
+			"idPath":          ".id",
+			"cursorPath":      "$.meta.next",
+			"cursorParameter": "cursor",
+		},
+	}, suite.createRuntimeConfiguration())
+	suite.Require().NoError(err)
+
+	workerInstance, err := worker.NewWorker(suite.logger, 0, suite.runtime)
+	suite.Require().NoError(err)
+
+	workerAllocator, err := worker.NewFixedPoolWorkerAllocator(suite.logger, []*worker.Worker{workerInstance})
+	suite.Require().NoError(err)
+
+	triggerInstance, err := newTrigger(suite.logger, workerAllocator, configuration, nil)
+	suite.Require().NoError(err)
+
+	suite.trigger = triggerInstance.(*httpPoller)
+
+	err = suite.trigger.Start(checkpoint)
+	suite.Require().NoError(err)
+}
+
+func (suite *TestSuite) stopTrigger() state {
+	checkpoint, err := suite.trigger.Stop(false)
+	suite.Require().NoError(err)
+	suite.Require().NotNil(checkpoint)
+
+	suite.trigger = nil
+
+	var checkpointState state
+	err = json.Unmarshal([]byte(*checkpoint), &checkpointState)
+	suite.Require().NoError(err)
+
+	return checkpointState
+}
+
+func (suite *TestSuite) waitForProcessedIDs(expectedIDs []string) {
+	suite.Require().Eventually(func() bool {
+		return len(suite.runtime.getProcessedIDs()) >= len(expectedIDs)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// give duplicates a chance to show up
+	time.Sleep(200 * time.Millisecond)
+
+	suite.Require().Equal(expectedIDs, suite.runtime.getProcessedIDs())
+}
+
+func (suite *TestSuite) createRuntimeConfiguration() *runtime.Configuration {
+	return &runtime.Configuration{
+		Configuration: &processor.Configuration{
+			Config: functionconfig.Config{},
+		},
+	}
+}
+
+func TestHTTPPollerSuite(t *testing.T) {
+	suite.Run(t, new(TestSuite))
+}
@@ -0,0 +1,511 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httppoller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/trigger/poller"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// state is what the poller needs to resume polling, stored as the trigger's checkpoint
+type state struct {
+	Cursor       string   `json:"cursor,omitempty"`
+	ETag         string   `json:"etag,omitempty"`
+	LastModified string   `json:"lastModified,omitempty"`
+	SeenItemIDs  []string `json:"seenItemIDs,omitempty"`
+}
+
+// cycle holds the state reached by a single poll, applied only once all of its items were handled
+type cycle struct {
+	cursor       string
+	etag         string
+	lastModified string
+	failed       bool
+}
+
+type httpPoller struct {
+	poller.AbstractPoller
+	configuration *Configuration
+	httpClient    *http.Client
+	itemsPath     *jsonpath.JSONPath
+	idPath        *jsonpath.JSONPath
+	cursorPath    *jsonpath.JSONPath
+
+	stateLock   sync.Mutex
+	state       state
+	seenItemIDs map[string]struct{}
+	cycle       *cycle
+}
+
+func newTrigger(logger logger.Logger,
+	workerAllocator worker.Allocator,
+	configuration *Configuration,
+	restartTriggerChan chan trigger.Trigger) (trigger.Trigger, error) {
+
+	abstractPoller, err := poller.NewAbstractPoller(logger, workerAllocator, &configuration.Configuration, restartTriggerChan)
+	if err != nil {
+		return nil, errors.New("Failed to create abstract poller")
+	}
+
+	newTrigger := httpPoller{
+		AbstractPoller: *abstractPoller,
+		configuration:  configuration,
+		httpClient:     &http.Client{Timeout: configuration.requestTimeout},
+		seenItemIDs:    map[string]struct{}{},
+	}
+	newTrigger.AbstractTrigger.Trigger = &newTrigger
+
+	// register self as the poller (to allow parent to call child functions)
+	newTrigger.SetPoller(&newTrigger)
+
+	// paths were validated when the configuration was created
+	newTrigger.itemsPath, _ = newJSONPath(configuration.ItemsPath)
+	newTrigger.idPath, _ = newJSONPath(configuration.IDPath)
+	newTrigger.cursorPath, _ = newJSONPath(configuration.CursorPath)
+
+	return &newTrigger, nil
+}
+
+// Start resumes polling from the checkpoint, if given
+func (hp *httpPoller) Start(checkpoint functionconfig.Checkpoint) error {
+	if checkpoint != nil && *checkpoint != "" {
+		var checkpointState state
+		if err := json.Unmarshal([]byte(*checkpoint), &checkpointState); err != nil {
+			return errors.Wrap(err, "Failed to decode checkpoint")
+		}
+
+		hp.stateLock.Lock()
+		hp.state = checkpointState
+		hp.seenItemIDs = map[string]struct{}{}
+		for _, itemID := range checkpointState.SeenItemIDs {
+			hp.seenItemIDs[itemID] = struct{}{}
+		}
+		hp.stateLock.Unlock()
+
+		hp.Logger.InfoWith("Resuming from checkpoint",
+			"cursor", checkpointState.Cursor,
+			"seenItems", len(checkpointState.SeenItemIDs))
+	}
+
+	return hp.AbstractPoller.Start(checkpoint)
+}
+
+// Stop stops polling and returns the state reached as the checkpoint
+func (hp *httpPoller) Stop(force bool) (functionconfig.Checkpoint, error) {
+	if _, err := hp.AbstractPoller.Stop(force); err != nil {
+		return nil, errors.Wrap(err, "Failed to stop poller")
+	}
+
+	hp.stateLock.Lock()
+	defer hp.stateLock.Unlock()
+
+	// if the last cycle was handled completely, include it
+	if !force {
+		hp.applyCycle()
+	}
+
+	encodedState, err := json.Marshal(hp.state)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode checkpoint")
+	}
+
+	checkpoint := string(encodedState)
+	return &checkpoint, nil
+}
+
+func (hp *httpPoller) GetConfig() map[string]interface{} {
+	return common.StructureToMap(hp.configuration)
+}
+
+// GetNewEvents reads the pages of the URL, writing an event per new item into the channel
+func (hp *httpPoller) GetNewEvents(eventsChan chan nuclio.Event) error {
+
+	// the previous cycle's events were all handled by now
+	hp.stateLock.Lock()
+	hp.applyCycle()
+	currentState := hp.state
+	hp.stateLock.Unlock()
+
+	newCycle, err := hp.poll(currentState, eventsChan)
+	if err != nil {
+		hp.Logger.WarnWith("Failed to poll", "url", hp.configuration.URL, "err", errors.GetErrorStackString(err, 10))
+	}
+
+	hp.stateLock.Lock()
+	if hp.cycle == nil {
+		hp.cycle = newCycle
+	} else {
+
+		// items were handled (and perhaps failed) while we were reading
+		hp.cycle.cursor = newCycle.cursor
+		hp.cycle.etag = newCycle.etag
+		hp.cycle.lastModified = newCycle.lastModified
+		hp.cycle.failed = hp.cycle.failed || newCycle.failed
+	}
+	hp.stateLock.Unlock()
+
+	// we're done. add a "nil" into the channel to indicate where the cycle completes
+	eventsChan <- nil
+
+	return err
+}
+
+// PostProcessEvents remembers the items that were handled, so that they won't be emitted again
+func (hp *httpPoller) PostProcessEvents(events []nuclio.Event, responses []interface{}, eventErrors []error) {
+	hp.stateLock.Lock()
+	defer hp.stateLock.Unlock()
+
+	if hp.cycle == nil {
+		hp.cycle = &cycle{}
+	}
+
+	for eventIdx, event := range events {
+
+		// RPC runtimes report handler failures as error responses rather than errors
+		if trigger.EventFailed(responses[eventIdx], eventErrors[eventIdx]) {
+			hp.cycle.failed = true
+			continue
+		}
+
+		if itemID := string(event.GetID()); itemID != "" {
+			hp.addSeenItemID(itemID)
+		}
+	}
+}
+
+// poll reads pages starting at the current state's cursor, returning the state reached
+func (hp *httpPoller) poll(currentState state, eventsChan chan nuclio.Event) (*cycle, error) {
+
+	// unless the poll completes, the cycle keeps the current state
+	newCycle := &cycle{
+		cursor:       currentState.Cursor,
+		etag:         currentState.ETag,
+		lastModified: currentState.LastModified,
+	}
+
+	cursor := currentState.Cursor
+
+	for page := 0; page < hp.configuration.MaxPages; page++ {
+		request, err := hp.createRequest(cursor)
+		if err != nil {
+			newCycle.failed = true
+			return newCycle, errors.Wrap(err, "Failed to create request")
+		}
+
+		// only the first page is conditional, as the following pages are read only if it changed
+		if page == 0 {
+			if currentState.ETag != "" {
+				request.Header.Set("If-None-Match", currentState.ETag)
+			}
+
+			if currentState.LastModified != "" {
+				request.Header.Set("If-Modified-Since", currentState.LastModified)
+			}
+		}
+
+		document, responseHeader, err := hp.getDocument(request)
+		if err != nil {
+			newCycle.failed = true
+			return newCycle, errors.Wrap(err, "Failed to get document")
+		}
+
+		// not modified
+		if document == nil {
+			hp.Logger.DebugWith("Document not modified", "url", request.URL.String())
+			return newCycle, nil
+		}
+
+		if page == 0 {
+			newCycle.etag = responseHeader.Get("ETag")
+			newCycle.lastModified = responseHeader.Get("Last-Modified")
+		}
+
+		items, err := hp.getItems(document)
+		if err != nil {
+			newCycle.failed = true
+			return newCycle, errors.Wrap(err, "Failed to get items")
+		}
+
+		now := time.Now()
+		for _, item := range items {
+			event, err := hp.createEvent(item, request.URL.String(), now)
+			if err != nil {
+				newCycle.failed = true
+				return newCycle, errors.Wrap(err, "Failed to create event")
+			}
+
+			if event != nil {
+				eventsChan <- event
+			}
+		}
+
+		nextCursor, err := hp.getCursor(document)
+		if err != nil {
+			newCycle.failed = true
+			return newCycle, errors.Wrap(err, "Failed to get cursor")
+		}
+
+		// resume from the last page read, to pick up items added to it
+		if nextCursor == "" || nextCursor == cursor {
+			newCycle.cursor = cursor
+			return newCycle, nil
+		}
+
+		newCycle.cursor = nextCursor
+
+		// an empty page means we caught up
+		if len(items) == 0 {
+			return newCycle, nil
+		}
+
+		cursor = nextCursor
+	}
+
+	return newCycle, nil
+}
+
+func (hp *httpPoller) createRequest(cursor string) (*http.Request, error) {
+	requestURL := hp.configuration.URL
+
+	if cursor != "" {
+
+		// a cursor that's a URL is requested as is
+		if strings.HasPrefix(cursor, "http://") || strings.HasPrefix(cursor, "https://") {
+			requestURL = cursor
+		} else if hp.configuration.CursorParameter != "" {
+			parsedURL, err := url.Parse(requestURL)
+			if err != nil {
+				return nil, errors.Wrap(err, "Failed to parse URL")
+			}
+
+			query := parsedURL.Query()
+			query.Set(hp.configuration.CursorParameter, cursor)
+			parsedURL.RawQuery = query.Encode()
+			requestURL = parsedURL.String()
+		}
+	}
+
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create request")
+	}
+
+	request.Header.Set("Accept", "application/json")
+
+	for headerKey, headerValue := range hp.configuration.Headers {
+		request.Header.Set(headerKey, headerValue)
+	}
+
+	if hp.configuration.Username != "" || hp.configuration.Password != "" {
+		request.SetBasicAuth(hp.configuration.Username, hp.configuration.Password)
+	}
+
+	return request, nil
+}
+
+// getDocument returns the decoded response, or nil if it was not modified
+func (hp *httpPoller) getDocument(request *http.Request) (interface{}, http.Header, error) {
+	response, err := hp.httpClient.Do(request)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to send request")
+	}
+
+	defer response.Body.Close() // nolint: errcheck
+
+	if response.StatusCode == http.StatusNotModified {
+		return nil, response.Header, nil
+	}
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to read response body")
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, nil, errors.Errorf("Got unexpected status code %d: %s", response.StatusCode, string(responseBody))
+	}
+
+	// keep numbers as is, so that large IDs won't lose precision
+	decoder := json.NewDecoder(bytes.NewReader(responseBody))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, nil, errors.Wrap(err, "Failed to decode response body")
+	}
+
+	return document, response.Header, nil
+}
+
+func (hp *httpPoller) getItems(document interface{}) ([]interface{}, error) {
+	itemsDocument := document
+
+	if hp.itemsPath != nil {
+		value, found, err := findJSONPathValue(hp.itemsPath, document)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to find items")
+		}
+
+		if !found || value == nil {
+			return nil, nil
+		}
+
+		itemsDocument = value
+	}
+
+	if items, isArray := itemsDocument.([]interface{}); isArray {
+		return items, nil
+	}
+
+	// without an items path, a single item may be returned
+	if hp.itemsPath == nil {
+		return []interface{}{itemsDocument}, nil
+	}
+
+	return nil, errors.Errorf("Expected items to be an array, got %T", itemsDocument)
+}
+
+func (hp *httpPoller) getCursor(document interface{}) (string, error) {
+	if hp.cursorPath == nil {
+		return "", nil
+	}
+
+	value, found, err := findJSONPathValue(hp.cursorPath, document)
+	if err != nil {
+		return "", errors.Wrap(err, "Failed to find cursor")
+	}
+
+	if !found || value == nil {
+		return "", nil
+	}
+
+	return fmt.Sprint(value), nil
+}
+
+// createEvent creates an event from the item, or returns nil if the item was already handled
+func (hp *httpPoller) createEvent(item interface{}, url string, timestamp time.Time) (*Event, error) {
+	event := Event{
+		url:       url,
+		timestamp: timestamp,
+	}
+
+	if hp.idPath != nil {
+		value, found, err := findJSONPathValue(hp.idPath, item)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to find item ID")
+		}
+
+		if found && value != nil {
+			event.id = fmt.Sprint(value)
+		}
+
+		hp.stateLock.Lock()
+		_, seen := hp.seenItemIDs[event.id]
+		hp.stateLock.Unlock()
+
+		if event.id != "" && seen {
+			return nil, nil
+		}
+	}
+
+	body, err := json.Marshal(item)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to encode item")
+	}
+
+	event.body = body
+
+	return &event, nil
+}
+
+// applyCycle moves the state forward to where the last cycle reached, unless some of its items failed,
+// in which case they're read again on the next poll. must be called while holding the state lock
+func (hp *httpPoller) applyCycle() {
+	if hp.cycle == nil {
+		return
+	}
+
+	if !hp.cycle.failed {
+		hp.state.Cursor = hp.cycle.cursor
+		hp.state.ETag = hp.cycle.etag
+		hp.state.LastModified = hp.cycle.lastModified
+	}
+
+	hp.cycle = nil
+}
+
+// addSeenItemID remembers an item ID, forgetting the oldest one if needed. must be called while
+// holding the state lock
+func (hp *httpPoller) addSeenItemID(itemID string) {
+	if _, seen := hp.seenItemIDs[itemID]; seen {
+		return
+	}
+
+	hp.seenItemIDs[itemID] = struct{}{}
+	hp.state.SeenItemIDs = append(hp.state.SeenItemIDs, itemID)
+
+	for len(hp.state.SeenItemIDs) > hp.configuration.MaxSeenItems {
+		delete(hp.seenItemIDs, hp.state.SeenItemIDs[0])
+		hp.state.SeenItemIDs = hp.state.SeenItemIDs[1:]
+	}
+}
+
+// newJSONPath parses a JSON path (e.g. "$.data.items" or ".data.items"), returning nil for an empty path
+func newJSONPath(path string) (*jsonpath.JSONPath, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	parsedJSONPath := jsonpath.New(path).AllowMissingKeys(true)
+	if err := parsedJSONPath.Parse("{" + path + "}"); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse JSON path")
+	}
+
+	return parsedJSONPath, nil
+}
+
+func findJSONPathValue(parsedJSONPath *jsonpath.JSONPath, document interface{}) (interface{}, bool, error) {
+	results, err := parsedJSONPath.FindResults(document)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "Failed to evaluate JSON path")
+	}
+
+	if len(results) == 0 || len(results[0]) == 0 {
+		return nil, false, nil
+	}
+
+	return results[0][0].Interface(), true, nil
+}
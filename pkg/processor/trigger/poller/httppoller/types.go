@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httppoller
+
+import (
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
+	"github.com/nuclio/nuclio/pkg/processor/trigger/poller"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/nuclio/errors"
+)
+
+type Configuration struct {
+	poller.Configuration
+
+	// headers sent with every request (e.g. Authorization). the trigger's username and password,
+	// if set, are sent as basic auth
+	Headers map[string]string
+
+	// JSON path of the items array in the response. if empty, a response holding an array is
+	// treated as the items array and any other response as a single item
+	ItemsPath string
+
+	// JSON path of the item ID, relative to the item. when set, items that were already handled
+	// are not emitted again
+	IDPath string
+
+	// JSON path of the cursor to the next page in the response. the cursor is sent in the
+	// CursorParameter query parameter or, if the cursor is a URL, requested as is
+	CursorPath      string
+	CursorParameter string
+
+	// the maximum number of pages to read in a single poll
+	MaxPages int
+
+	// the number of handled item IDs to remember
+	MaxSeenItems int
+
+	RequestTimeout string
+	requestTimeout time.Duration
+}
+
+func NewConfiguration(id string,
+	triggerConfiguration *functionconfig.Trigger,
+	runtimeConfiguration *runtime.Configuration) (*Configuration, error) {
+	newConfiguration := Configuration{}
+
+	// create base
+	pollerConfiguration, err := poller.NewConfiguration(id, triggerConfiguration, runtimeConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read poller configuration")
+	}
+
+	newConfiguration.Configuration = *pollerConfiguration
+
+	// parse attributes
+	if err := mapstructure.Decode(newConfiguration.Configuration.Attributes, &newConfiguration); err != nil {
+		return nil, errors.Wrap(err, "Failed to decode attributes")
+	}
+
+	if newConfiguration.URL == "" {
+		return nil, errors.New("URL must be provided")
+	}
+
+	if newConfiguration.IntervalMs == 0 {
+		newConfiguration.IntervalMs = 60000
+	}
+
+	if newConfiguration.MaxPages == 0 {
+		newConfiguration.MaxPages = 10
+	}
+
+	if newConfiguration.MaxSeenItems == 0 {
+		newConfiguration.MaxSeenItems = 1000
+	}
+
+	if newConfiguration.MaxPages < 0 || newConfiguration.MaxSeenItems < 0 {
+		return nil, errors.New("Max pages and max seen items must not be negative")
+	}
+
+	if err := newConfiguration.ParseDurationOrDefault(&trigger.DurationConfigField{
+		Name:    "request timeout",
+		Value:   newConfiguration.RequestTimeout,
+		Field:   &newConfiguration.requestTimeout,
+		Default: 30 * time.Second,
+	}); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse request timeout")
+	}
+
+	// make sure the paths parse
+	for _, path := range []string{
+		newConfiguration.ItemsPath,
+		newConfiguration.IDPath,
+		newConfiguration.CursorPath,
+	} {
+		if _, err := newJSONPath(path); err != nil {
+			return nil, errors.Wrapf(err, "Failed to parse JSON path %s", path)
+		}
+	}
+
+	return &newConfiguration, nil
+}
@@ -32,6 +32,8 @@ type AbstractPoller struct {
 	trigger.AbstractTrigger
 	configuration *Configuration
 	poller        Poller
+	stopChan      chan struct{}
+	stoppedChan   chan struct{}
 }
 
 func NewAbstractPoller(logger logger.Logger,
@@ -62,25 +64,40 @@ func (ap *AbstractPoller) SetPoller(poller Poller) {
 }
 
 func (ap *AbstractPoller) Start(checkpoint functionconfig.Checkpoint) error {
+	ap.stopChan = make(chan struct{})
+	ap.stoppedChan = make(chan struct{})
 
-	// process one cycle at a time (don't getNewEvents again while processing)
-	go ap.getEventsSingleCycle()
+	// process one cycle at a time (don't getNewEvents again while processing). the channels are passed
+	// rather than read from the poller, as Stop resets them
+	go ap.getEventsSingleCycle(ap.stopChan, ap.stoppedChan)
 
 	return nil
 }
 
+// Stop stops polling once the current cycle completes. unless forced, waits for the cycle to complete
 func (ap *AbstractPoller) Stop(force bool) (functionconfig.Checkpoint, error) {
+	if ap.stopChan == nil {
+		return nil, nil
+	}
+
+	close(ap.stopChan)
+	ap.stopChan = nil
+
+	if !force {
+		<-ap.stoppedChan
+	}
 
-	// TODO
 	return nil, nil
 }
 
 // in this strategy, we trigger getNewEvents once, process all the events it creates (while getNewEvents is producing
 // and only then re-trigger getNewEvents. in the future we'll probably have getNewEvents producing in the background
-func (ap *AbstractPoller) getEventsSingleCycle() {
+func (ap *AbstractPoller) getEventsSingleCycle(stopChan chan struct{}, stoppedChan chan struct{}) {
 	var eventBatch []nuclio.Event
 	var err error
 
+	defer close(stoppedChan)
+
 	eventsChan := make(chan nuclio.Event)
 
 	for {
@@ -100,9 +117,12 @@ func (ap *AbstractPoller) getEventsSingleCycle() {
 				time.Duration(ap.configuration.MaxBatchWaitMs)*time.Millisecond)
 
 			if err != nil {
+				ap.Logger.WarnWith("Failed to wait for event batch", "err", err.Error())
 				continue
+			}
 
-				// TODO
+			if len(eventBatch) == 0 {
+				continue
 			}
 
 			ap.Logger.DebugWith("Got events", "num", len(eventBatch))
@@ -111,18 +131,28 @@ func (ap *AbstractPoller) getEventsSingleCycle() {
 			// eventResponses, submitError, eventErrors := ap.AllocateWorkerAndSubmitEvents(eventBatch, 10 * time.Second)
 			eventResponses, submitError, eventErrors := ap.AllocateWorkerAndSubmitEvents(eventBatch, nil, 10*time.Second)
 
+			// let the poller know that none of the events were processed
 			if submitError != nil {
-				continue
+				ap.Logger.WarnWith("Failed to submit events", "num", len(eventBatch), "err", submitError.Error())
 
-				// TODO
+				eventResponses = make([]interface{}, len(eventBatch))
+				eventErrors = make([]error, len(eventBatch))
+				for eventIdx := range eventErrors {
+					eventErrors[eventIdx] = submitError
+				}
 			}
 
 			// post process the events
 			ap.poller.PostProcessEvents(eventBatch, eventResponses, eventErrors)
 		}
 
-		// wait the interva
-		time.Sleep(time.Duration(ap.configuration.IntervalMs) * time.Millisecond)
+		// wait the interval, unless stopped
+		select {
+		case <-stopChan:
+			ap.Logger.DebugWith("Poller stopped")
+			return
+		case <-time.After(time.Duration(ap.configuration.IntervalMs) * time.Millisecond):
+		}
 	}
 }
 
@@ -155,7 +185,7 @@ func (ap *AbstractPoller) waitForEventBatch(eventsChan chan nuclio.Event,
 				events = append(events, receivedEvent)
 
 				// check if we reached max size. if so we're done
-				if len(events) > maxBatchSize {
+				if len(events) >= maxBatchSize {
 					done = true
 				}
 			}
@@ -50,7 +50,17 @@ func NewConfiguration(id string,
 		return nil, errors.Wrap(err, "Failed to decode attributes")
 	}
 
-	// TODO: validate
+	if newConfiguration.MaxBatchSize == 0 {
+		newConfiguration.MaxBatchSize = 1
+	}
+
+	if newConfiguration.MaxBatchWaitMs == 0 {
+		newConfiguration.MaxBatchWaitMs = 1000
+	}
+
+	if newConfiguration.IntervalMs < 0 || newConfiguration.MaxBatchSize < 0 || newConfiguration.MaxBatchWaitMs < 0 {
+		return nil, errors.New("Interval, max batch size and max batch wait must not be negative")
+	}
 
 	return &newConfiguration, nil
 }
@@ -19,13 +19,18 @@ limitations under the License.
 package rabbitmq
 
 import (
+	"context"
+	"net/http"
 	"testing"
 
 	"github.com/nuclio/nuclio/pkg/processor"
 	"github.com/nuclio/nuclio/pkg/processor/runtime"
 
+	"github.com/nuclio/errors"
 	"github.com/nuclio/logger"
+	"github.com/nuclio/nuclio-sdk-go"
 	nucliozap "github.com/nuclio/zap"
+	amqp "github.com/rabbitmq/amqp091-go"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -34,10 +39,61 @@ const (
 	queueName    = "test_queue"
 )
 
+type mockAcknowledger struct {
+	acked    bool
+	nacked   bool
+	requeued bool
+}
+
+func (ma *mockAcknowledger) Ack(tag uint64, multiple bool) error {
+	ma.acked = true
+	return nil
+}
+
+func (ma *mockAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	ma.nacked = true
+	ma.requeued = requeue
+	return nil
+}
+
+func (ma *mockAcknowledger) Reject(tag uint64, requeue bool) error {
+	return ma.Nack(tag, false, requeue)
+}
+
+type publishedMessage struct {
+	exchange   string
+	routingKey string
+	publishing amqp.Publishing
+}
+
+type mockPublisher struct {
+	published  []publishedMessage
+	publishErr error
+}
+
+func (mp *mockPublisher) PublishWithContext(ctx context.Context,
+	exchange string,
+	key string,
+	mandatory bool,
+	immediate bool,
+	message amqp.Publishing) error {
+	if mp.publishErr != nil {
+		return mp.publishErr
+	}
+
+	mp.published = append(mp.published, publishedMessage{
+		exchange:   exchange,
+		routingKey: key,
+		publishing: message,
+	})
+	return nil
+}
+
 type TestSuite struct {
 	suite.Suite
-	trigger rabbitMq
-	logger  logger.Logger
+	trigger   rabbitMq
+	logger    logger.Logger
+	publisher *mockPublisher
 }
 
 func (suite *TestSuite) SetupSuite() {
@@ -48,11 +104,16 @@ func (suite *TestSuite) SetupTest() {
 	suite.trigger = rabbitMq{}
 	suite.trigger.Logger = suite.logger.GetChild("rabbitMQ")
 
+	maxRedeliveries := 2
 	suite.trigger.configuration = &Configuration{
-		QueueName:    queueName,
-		ExchangeName: exchangeName,
-		Topics:       []string{"t1", "t2"},
+		QueueName:       queueName,
+		ExchangeName:    exchangeName,
+		Topics:          []string{"t1", "t2"},
+		MaxRedeliveries: &maxRedeliveries,
 	}
+
+	suite.publisher = &mockPublisher{}
+	suite.trigger.setBrokerPublisher(suite.publisher)
 }
 
 func (suite *TestSuite) TestSetEmptyParametersNoChange() {
@@ -85,6 +146,132 @@ func (suite *TestSuite) TestSetEmptyParametersMakesNoChange() {
 	suite.EqualValues(suite.trigger.configuration.Topics, []string{})
 }
 
+func (suite *TestSuite) TestSettleMessageAcksHandledMessage() {
+	message, acknowledger := suite.createMessage(nil)
+
+	suite.trigger.settleMessage(message, nil, nil, nil)
+	suite.Require().True(acknowledger.acked)
+	suite.Require().False(acknowledger.nacked)
+}
+
+func (suite *TestSuite) TestSettleMessageRequeuesUnsubmittedMessage() {
+	message, acknowledger := suite.createMessage(nil)
+
+	suite.trigger.settleMessage(message, nil, errors.New("no workers"), nil)
+	suite.Require().True(acknowledger.nacked)
+	suite.Require().True(acknowledger.requeued)
+	suite.Require().Empty(suite.publisher.published)
+}
+
+func (suite *TestSuite) TestSettleMessageRedeliversFailedMessage() {
+	message, acknowledger := suite.createMessage(amqp.Table{redeliveryCountHeader: int32(1)})
+
+	suite.trigger.settleMessage(message, nil, nil, errors.New("handler failed"))
+
+	// republished to the queue with an incremented count, original is acked
+	suite.Require().True(acknowledger.acked)
+	suite.Require().Len(suite.publisher.published, 1)
+	suite.Require().Equal("", suite.publisher.published[0].exchange)
+	suite.Require().Equal(queueName, suite.publisher.published[0].routingKey)
+	suite.Require().Equal(int32(2), suite.publisher.published[0].publishing.Headers[redeliveryCountHeader])
+	suite.Require().Equal([]byte("body"), suite.publisher.published[0].publishing.Body)
+	suite.Require().Equal("some-header-value", suite.publisher.published[0].publishing.Headers["some-header"])
+}
+
+func (suite *TestSuite) TestSettleMessageRedeliversErrorResponse() {
+	message, acknowledger := suite.createMessage(amqp.Table{redeliveryCountHeader: int32(1)})
+
+	// RPC runtimes report handler exceptions as a 500 response with no error
+	suite.trigger.settleMessage(message,
+		nuclio.Response{StatusCode: http.StatusInternalServerError},
+		nil,
+		nil)
+	suite.Require().True(acknowledger.acked)
+	suite.Require().Len(suite.publisher.published, 1)
+	suite.Require().Equal(int32(2), suite.publisher.published[0].publishing.Headers[redeliveryCountHeader])
+
+	// and once the message exceeds its redeliveries, it's dead-lettered with the status code as the reason
+	suite.trigger.configuration.DeadLetterExchangeName = "dlx"
+	message, _ = suite.createMessage(amqp.Table{redeliveryCountHeader: int32(2)})
+
+	suite.trigger.settleMessage(message,
+		&nuclio.Response{StatusCode: http.StatusInternalServerError},
+		nil,
+		nil)
+	suite.Require().Len(suite.publisher.published, 2)
+	suite.Require().Equal("dlx", suite.publisher.published[1].exchange)
+	suite.Require().Equal("Handler responded with status code 500",
+		suite.publisher.published[1].publishing.Headers[deadLetterReasonHeader])
+}
+
+func (suite *TestSuite) TestSettleMessageRequeuesFailedMessageOfQuorumQueue() {
+	message, acknowledger := suite.createMessage(amqp.Table{deliveryCountHeader: int64(1)})
+
+	suite.trigger.settleMessage(message, nil, nil, errors.New("handler failed"))
+	suite.Require().True(acknowledger.nacked)
+	suite.Require().True(acknowledger.requeued)
+	suite.Require().Empty(suite.publisher.published)
+}
+
+func (suite *TestSuite) TestSettleMessageDeadLettersPoisonedMessage() {
+	suite.trigger.configuration.DeadLetterExchangeName = "dlx"
+	message, acknowledger := suite.createMessage(amqp.Table{redeliveryCountHeader: int32(2)})
+
+	suite.trigger.settleMessage(message, nil, nil, errors.New("handler failed"))
+	suite.Require().True(acknowledger.acked)
+	suite.Require().Len(suite.publisher.published, 1)
+
+	deadLetter := suite.publisher.published[0]
+	suite.Require().Equal("dlx", deadLetter.exchange)
+	suite.Require().Equal("t1", deadLetter.routingKey)
+	suite.Require().Equal("handler failed", deadLetter.publishing.Headers[deadLetterReasonHeader])
+	suite.Require().Equal(exchangeName, deadLetter.publishing.Headers[deadLetterOriginalExchangeHeader])
+
+	// fails to publish to the dead-letter exchange, the message is kept
+	suite.publisher.publishErr = errors.New("channel closed")
+	message, acknowledger = suite.createMessage(amqp.Table{redeliveryCountHeader: int32(2)})
+
+	suite.trigger.settleMessage(message, nil, nil, errors.New("handler failed"))
+	suite.Require().True(acknowledger.nacked)
+	suite.Require().True(acknowledger.requeued)
+}
+
+func (suite *TestSuite) TestSettleMessageRejectsPoisonedMessageWithoutDeadLetterExchange() {
+	message, acknowledger := suite.createMessage(amqp.Table{redeliveryCountHeader: int32(2)})
+
+	suite.trigger.settleMessage(message, nil, nil, errors.New("handler failed"))
+	suite.Require().True(acknowledger.nacked)
+	suite.Require().False(acknowledger.requeued)
+	suite.Require().Empty(suite.publisher.published)
+}
+
+func (suite *TestSuite) TestSettleMessageUnlimitedRedeliveries() {
+	unlimitedRedeliveries := -1
+	suite.trigger.configuration.MaxRedeliveries = &unlimitedRedeliveries
+	message, _ := suite.createMessage(amqp.Table{redeliveryCountHeader: int32(1000)})
+
+	suite.trigger.settleMessage(message, nil, nil, errors.New("handler failed"))
+	suite.Require().Len(suite.publisher.published, 1)
+	suite.Require().Equal(int32(1001), suite.publisher.published[0].publishing.Headers[redeliveryCountHeader])
+}
+
+func (suite *TestSuite) createMessage(headers amqp.Table) (*amqp.Delivery, *mockAcknowledger) {
+	acknowledger := &mockAcknowledger{}
+	if headers == nil {
+		headers = amqp.Table{}
+	}
+	headers["some-header"] = "some-header-value"
+
+	return &amqp.Delivery{
+		Acknowledger: acknowledger,
+		Headers:      headers,
+		Exchange:     exchangeName,
+		RoutingKey:   "t1",
+		MessageId:    "some-message-id",
+		Body:         []byte("body"),
+	}, acknowledger
+}
+
 func TestRabbitMQSuite(t *testing.T) {
 	suite.Run(t, new(TestSuite))
 }
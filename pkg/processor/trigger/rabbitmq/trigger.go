@@ -17,9 +17,11 @@ limitations under the License.
 package rabbitmq
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
@@ -33,9 +35,18 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// publisher publishes messages to the broker (implemented by amqp.Channel)
+type publisher interface {
+	PublishWithContext(ctx context.Context,
+		exchange string,
+		key string,
+		mandatory bool,
+		immediate bool,
+		message amqp.Publishing) error
+}
+
 type rabbitMq struct {
 	trigger.AbstractTrigger
-	event                      Event
 	configuration              *Configuration
 	consumerName               string
 	brokerConn                 *amqp.Connection
@@ -44,6 +55,14 @@ type rabbitMq struct {
 	brokerInputMessagesChannel <-chan amqp.Delivery
 	stopChan                   chan struct{}
 	connectionErrorChan        chan *amqp.Error
+
+	// bounds the number of messages (or batches) processed concurrently to the number of workers
+	inFlightSemaphore chan struct{}
+	inFlightWaitGroup sync.WaitGroup
+
+	// replaced on reconnect, while messages are settled by the workers
+	brokerPublisher     publisher
+	brokerPublisherLock sync.RWMutex
 }
 
 func newTrigger(parentLogger logger.Logger,
@@ -88,6 +107,7 @@ func (rmq *rabbitMq) Start(checkpoint functionconfig.Checkpoint) error {
 		"brokerUrl", rmq.configuration.URL)
 
 	rmq.stopChan = make(chan struct{})
+	rmq.inFlightSemaphore = make(chan struct{}, rmq.configuration.NumWorkers)
 
 	if err := rmq.createBrokerResources(); err != nil {
 		return errors.Wrap(err, "Failed to create broker resources")
@@ -104,6 +124,9 @@ func (rmq *rabbitMq) Stop(force bool) (functionconfig.Checkpoint, error) {
 	// stop listening for messages
 	close(rmq.stopChan)
 
+	// let messages being processed settle before closing the channel
+	rmq.inFlightWaitGroup.Wait()
+
 	// close broker
 	if err := rmq.brokerChannel.Close(); err != nil {
 		rmq.Logger.WarnWith("Failed to close broker channel", "err", err.Error())
@@ -166,6 +189,27 @@ func (rmq *rabbitMq) getConnectionConfig() *amqp.Config {
 }
 
 func (rmq *rabbitMq) handleBrokerMessages() {
+	var (
+		pendingMessages []amqp.Delivery
+		batchTimer      *time.Timer
+		batchTimerChan  <-chan time.Time
+	)
+
+	batchingEnabled := functionconfig.BatchModeEnabled(rmq.configuration.Batch)
+
+	flushPendingMessages := func() {
+		if batchTimer != nil {
+			batchTimer.Stop()
+			batchTimer, batchTimerChan = nil, nil
+		}
+
+		messages := pendingMessages
+		rmq.processInBackground(func() {
+			rmq.processMessageBatch(messages)
+		})
+		pendingMessages = nil
+	}
+
 	for {
 		select {
 		case err := <-rmq.connectionErrorChan:
@@ -180,7 +224,26 @@ func (rmq *rabbitMq) handleBrokerMessages() {
 			rmq.Logger.DebugWith("Stopping consumption from queue", "queueName", rmq.configuration.QueueName)
 			return
 		case message := <-rmq.brokerInputMessagesChannel:
-			rmq.processMessage(&message)
+			if !batchingEnabled {
+				message := message
+				rmq.processInBackground(func() {
+					rmq.processMessage(&message)
+				})
+				continue
+			}
+
+			// start the batch timeout once the first message of the batch arrives
+			pendingMessages = append(pendingMessages, message)
+			if len(pendingMessages) == 1 {
+				batchTimer = time.NewTimer(rmq.configuration.BatchTimeout)
+				batchTimerChan = batchTimer.C
+			}
+
+			if len(pendingMessages) >= rmq.configuration.Batch.BatchSize {
+				flushPendingMessages()
+			}
+		case <-batchTimerChan:
+			flushPendingMessages()
 		}
 	}
 }
@@ -228,6 +291,7 @@ func (rmq *rabbitMq) connect() error {
 	if err != nil {
 		return errors.Wrap(err, "Failed to create channel")
 	}
+	rmq.setBrokerPublisher(rmq.brokerChannel)
 	rmq.Logger.DebugWith("Created broker channel")
 	return nil
 }
@@ -260,6 +324,21 @@ func (rmq *rabbitMq) createTopics() error {
 	}
 	rmq.Logger.DebugWith("Declared exchange", "exchangeName", rmq.configuration.ExchangeName)
 
+	// create the dead-letter exchange, to which poisoned messages are routed
+	if rmq.configuration.DeadLetterExchangeName != "" {
+		if err := rmq.brokerChannel.ExchangeDeclare(rmq.configuration.DeadLetterExchangeName,
+			"topic",
+			rmq.configuration.DurableExchange,
+			false,
+			false,
+			false,
+			nil); err != nil {
+			return errors.Wrap(err, "Failed to declare dead-letter exchange")
+		}
+		rmq.Logger.DebugWith("Declared dead-letter exchange",
+			"exchangeName", rmq.configuration.DeadLetterExchangeName)
+	}
+
 	rmq.brokerQueue, err = rmq.brokerChannel.QueueDeclare(
 		rmq.configuration.QueueName,    // queue name (account  + function name)
 		rmq.configuration.DurableQueue, // durable  TBD: change to true if/when we bind to persistent storage
@@ -338,25 +417,245 @@ func (rmq *rabbitMq) handleConnectionError(handleErr *amqp.Error) error {
 	return nil
 }
 
+// processInBackground processes in a goroutine, blocking while all workers are busy
+func (rmq *rabbitMq) processInBackground(process func()) {
+	rmq.inFlightSemaphore <- struct{}{}
+	rmq.inFlightWaitGroup.Add(1)
+
+	go func() {
+		defer func() {
+			<-rmq.inFlightSemaphore
+			rmq.inFlightWaitGroup.Done()
+		}()
+
+		process()
+	}()
+}
+
 func (rmq *rabbitMq) processMessage(message *amqp.Delivery) {
 
-	// bind to delivery
+	// create an event per message, as messages are processed concurrently
+	event := &Event{message: message}
+	event.SetID(nuclio.ID(message.MessageId))
 
-	// TODO: when moving to multiworkers - need to create event per message
-	rmq.event.message = message
-	rmq.event.SetID(nuclio.ID(message.MessageId))
+	// submit to worker
+	response, submitError, processError := rmq.AllocateWorkerAndSubmitEvent(event,
+		nil,
+		rmq.getWorkerAvailabilityTimeout())
+
+	rmq.settleMessage(message, response, submitError, processError)
+}
+
+func (rmq *rabbitMq) processMessageBatch(messages []amqp.Delivery) {
+	batch := make([]nuclio.Event, 0, len(messages))
+	for messageIndex := range messages {
+		batch = append(batch, &Event{message: &messages[messageIndex]})
+	}
 
 	// submit to worker
-	_, submitError, _ := rmq.AllocateWorkerAndSubmitEvent(&rmq.event, nil, 10*time.Second)
+	responses, submitError := rmq.AllocateWorkerAndSubmitEventBatch(batch,
+		nil,
+		rmq.getWorkerAvailabilityTimeout())
+	if submitError != nil {
+		rmq.Logger.WarnWith("Failed to submit batch to worker", "batchSize", len(batch), "err", submitError)
+		for messageIndex := range messages {
+			rmq.settleMessage(&messages[messageIndex], nil, submitError, nil)
+		}
+		return
+	}
 
-	// ack the message if we didn't fail to submit
-	if submitError == nil {
-		message.Ack(false) // nolint: errcheck
-	} else {
-		rmq.Logger.WarnWith("Failed to submit to worker", "err", submitError)
+	// settle each message according to its own response
+	for messageIndex := range messages {
+		if messageIndex >= len(responses) {
+			rmq.settleMessage(&messages[messageIndex], nil, nil, errors.New("Batch response is missing"))
+			continue
+		}
+
+		rmq.settleMessage(&messages[messageIndex],
+			responses[messageIndex].Response,
+			nil,
+			responses[messageIndex].ProcessError)
 	}
 }
 
+// settleMessage acks a handled message. messages that failed to be handled (including those the handler
+// responded to with an error status code) are redelivered, and once they exceed their redeliveries - dead-lettered
+func (rmq *rabbitMq) settleMessage(message *amqp.Delivery,
+	response interface{},
+	submitError error,
+	processError error) {
+
+	// RPC runtimes report handler failures as error responses rather than errors
+	handleError := trigger.EventError(response, processError)
+
+	switch {
+	case submitError != nil:
+
+		// the message wasn't handled at all, so this doesn't count as a redelivery
+		rmq.Logger.WarnWith("Failed to submit to worker, requeueing message", "err", submitError.Error())
+		if err := message.Nack(false, true); err != nil {
+			rmq.Logger.WarnWith("Failed to nack message", "err", err.Error())
+		}
+
+	case handleError == nil:
+		if err := message.Ack(false); err != nil {
+			rmq.Logger.WarnWith("Failed to ack message", "err", err.Error())
+		}
+
+	default:
+		redeliveryCount := rmq.getRedeliveryCount(message)
+		maxRedeliveries := *rmq.configuration.MaxRedeliveries
+
+		if maxRedeliveries < 0 || redeliveryCount < maxRedeliveries {
+			rmq.Logger.DebugWith("Failed to handle message, redelivering",
+				"messageID", message.MessageId,
+				"redeliveryCount", redeliveryCount,
+				"err", handleError.Error())
+			rmq.redeliverMessage(message, redeliveryCount)
+			return
+		}
+
+		rmq.Logger.WarnWith("Message exceeded its redeliveries, dead-lettering",
+			"messageID", message.MessageId,
+			"redeliveryCount", redeliveryCount,
+			"deadLetterExchangeName", rmq.configuration.DeadLetterExchangeName,
+			"err", handleError.Error())
+		rmq.deadLetterMessage(message, redeliveryCount, handleError)
+	}
+}
+
+func (rmq *rabbitMq) redeliverMessage(message *amqp.Delivery, redeliveryCount int) {
+
+	// quorum queues count deliveries on their own, so the message can simply be requeued
+	if _, found := message.Headers[deliveryCountHeader]; found {
+		if err := message.Nack(false, true); err != nil {
+			rmq.Logger.WarnWith("Failed to nack message", "err", err.Error())
+		}
+		return
+	}
+
+	// otherwise, the count is kept on a copy of the message, republished to the queue
+	publishing := rmq.messageToPublishing(message)
+	publishing.Headers[redeliveryCountHeader] = int32(redeliveryCount + 1)
+
+	if err := rmq.getBrokerPublisher().PublishWithContext(context.Background(),
+		"",
+		rmq.configuration.QueueName,
+		false,
+		false,
+		publishing); err != nil {
+		rmq.Logger.WarnWith("Failed to republish message, requeueing", "err", err.Error())
+		if err := message.Nack(false, true); err != nil {
+			rmq.Logger.WarnWith("Failed to nack message", "err", err.Error())
+		}
+		return
+	}
+
+	if err := message.Ack(false); err != nil {
+		rmq.Logger.WarnWith("Failed to ack republished message", "err", err.Error())
+	}
+}
+
+func (rmq *rabbitMq) deadLetterMessage(message *amqp.Delivery, redeliveryCount int, processError error) {
+
+	// let the broker route the message to the queue's dead-letter exchange, if it has one
+	if rmq.configuration.DeadLetterExchangeName == "" {
+		if err := message.Nack(false, false); err != nil {
+			rmq.Logger.WarnWith("Failed to reject message", "err", err.Error())
+		}
+		return
+	}
+
+	routingKey := rmq.configuration.DeadLetterRoutingKey
+	if routingKey == "" {
+		routingKey = message.RoutingKey
+	}
+
+	publishing := rmq.messageToPublishing(message)
+	publishing.Headers[redeliveryCountHeader] = int32(redeliveryCount)
+	publishing.Headers[deadLetterReasonHeader] = processError.Error()
+	publishing.Headers[deadLetterOriginalExchangeHeader] = message.Exchange
+	publishing.Headers[deadLetterOriginalRoutingKeyHeader] = message.RoutingKey
+
+	if err := rmq.getBrokerPublisher().PublishWithContext(context.Background(),
+		rmq.configuration.DeadLetterExchangeName,
+		routingKey,
+		false,
+		false,
+		publishing); err != nil {
+
+		// don't lose the message, it'll be dead-lettered on its next failure
+		rmq.Logger.WarnWith("Failed to publish message to dead-letter exchange, requeueing", "err", err.Error())
+		if err := message.Nack(false, true); err != nil {
+			rmq.Logger.WarnWith("Failed to nack message", "err", err.Error())
+		}
+		return
+	}
+
+	if err := message.Ack(false); err != nil {
+		rmq.Logger.WarnWith("Failed to ack dead-lettered message", "err", err.Error())
+	}
+}
+
+func (rmq *rabbitMq) getBrokerPublisher() publisher {
+	rmq.brokerPublisherLock.RLock()
+	defer rmq.brokerPublisherLock.RUnlock()
+
+	return rmq.brokerPublisher
+}
+
+func (rmq *rabbitMq) setBrokerPublisher(brokerPublisher publisher) {
+	rmq.brokerPublisherLock.Lock()
+	defer rmq.brokerPublisherLock.Unlock()
+
+	rmq.brokerPublisher = brokerPublisher
+}
+
+func (rmq *rabbitMq) getRedeliveryCount(message *amqp.Delivery) int {
+	for _, headerName := range []string{deliveryCountHeader, redeliveryCountHeader} {
+		switch typedCount := message.Headers[headerName].(type) {
+		case int:
+			return typedCount
+		case int16:
+			return int(typedCount)
+		case int32:
+			return int(typedCount)
+		case int64:
+			return int(typedCount)
+		}
+	}
+
+	return 0
+}
+
+func (rmq *rabbitMq) messageToPublishing(message *amqp.Delivery) amqp.Publishing {
+	headers := amqp.Table{}
+	for headerName, headerValue := range message.Headers {
+		headers[headerName] = headerValue
+	}
+
+	return amqp.Publishing{
+		Headers:         headers,
+		ContentType:     message.ContentType,
+		ContentEncoding: message.ContentEncoding,
+		DeliveryMode:    message.DeliveryMode,
+		Priority:        message.Priority,
+		CorrelationId:   message.CorrelationId,
+		ReplyTo:         message.ReplyTo,
+		Expiration:      message.Expiration,
+		MessageId:       message.MessageId,
+		Timestamp:       message.Timestamp,
+		Type:            message.Type,
+		UserId:          message.UserId,
+		AppId:           message.AppId,
+		Body:            message.Body,
+	}
+}
+
+func (rmq *rabbitMq) getWorkerAvailabilityTimeout() time.Duration {
+	return time.Duration(*rmq.configuration.WorkerAvailabilityTimeoutMilliseconds) * time.Millisecond
+}
+
 func (rmq *rabbitMq) getConsumerName() (string, error) {
 	var consumerName string
 	var err error
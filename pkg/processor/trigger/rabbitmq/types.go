@@ -27,6 +27,21 @@ import (
 	"github.com/nuclio/errors"
 )
 
+const (
+	DefaultMaxRedeliveries = 3
+
+	// set by quorum queues, which count deliveries on their own
+	deliveryCountHeader = "x-delivery-count"
+
+	// set by the trigger on messages it republishes for redelivery
+	redeliveryCountHeader = "x-nuclio-redelivery-count"
+
+	// set by the trigger on dead-lettered messages
+	deadLetterReasonHeader             = "x-nuclio-dead-letter-reason"
+	deadLetterOriginalExchangeHeader   = "x-nuclio-original-exchange"
+	deadLetterOriginalRoutingKeyHeader = "x-nuclio-original-routing-key"
+)
+
 type Configuration struct {
 	trigger.Configuration
 	ExchangeName      string
@@ -38,6 +53,15 @@ type Configuration struct {
 	DurableExchange   bool
 	DurableQueue      bool
 
+	// how many times a message whose handling failed is redelivered before it's dead-lettered. negative
+	// for unlimited redeliveries
+	MaxRedeliveries *int
+
+	// where to route messages that exceeded their redeliveries. if not set, messages are rejected, which
+	// routes them to the queue's own dead-letter exchange (if any)
+	DeadLetterExchangeName string
+	DeadLetterRoutingKey   string
+
 	reconnectDuration time.Duration
 	reconnectInterval time.Duration
 }
@@ -67,6 +91,10 @@ func NewConfiguration(id string,
 	if newConfiguration.ReconnectInterval == "" {
 		newConfiguration.ReconnectInterval = "15s"
 	}
+	if newConfiguration.MaxRedeliveries == nil {
+		defaultMaxRedeliveries := DefaultMaxRedeliveries
+		newConfiguration.MaxRedeliveries = &defaultMaxRedeliveries
+	}
 
 	newConfiguration.reconnectDuration, err = time.ParseDuration(newConfiguration.ReconnectDuration)
 	if err != nil {
@@ -0,0 +1,145 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+)
+
+// Response is the response to an event, resolved the way the http trigger resolves it, for triggers
+// replying over protocols other than HTTP
+type Response struct {
+
+	// StatusCode is zero when neither an error nor the handler set one
+	StatusCode  int
+	ContentType string
+	Headers     map[string][]string
+	Body        []byte
+}
+
+// ResolveResponse resolves the response to an event from the handler's response, the error submitting
+// the event to a worker and the error returned by the handler
+func ResolveResponse(response interface{}, submitError error, processError error) *Response {
+
+	// if we failed to submit the event to a worker
+	if submitError != nil {
+		switch errors.Cause(submitError) {
+
+		// no available workers
+		case worker.ErrNoAvailableWorkers, worker.ErrAllWorkersAreTerminated:
+			return &Response{StatusCode: http.StatusServiceUnavailable}
+
+			// something else - most likely a bug
+		default:
+			return &Response{StatusCode: http.StatusInternalServerError}
+		}
+	}
+
+	if processError != nil {
+		statusCode := http.StatusInternalServerError
+
+		// check if the user returned an error with a status code
+		switch typedError := processError.(type) {
+		case nuclio.ErrorWithStatusCode:
+			statusCode = typedError.StatusCode()
+		case *nuclio.ErrorWithStatusCode:
+			statusCode = typedError.StatusCode()
+		}
+
+		return &Response{
+			StatusCode: statusCode,
+			Body:       []byte(processError.Error()),
+		}
+	}
+
+	switch typedResponse := response.(type) {
+	case *nuclio.Response:
+		return ResolveResponse(*typedResponse, nil, nil)
+
+	case nuclio.Response:
+		return &Response{
+			StatusCode:  typedResponse.StatusCode,
+			ContentType: typedResponse.ContentType,
+			Headers:     FlattenHeaders(typedResponse.Headers),
+			Body:        typedResponse.Body,
+		}
+
+	case []byte:
+		return &Response{Body: typedResponse}
+
+	case string:
+		return &Response{Body: []byte(typedResponse)}
+	}
+
+	return &Response{}
+}
+
+// EventFailed returns whether handling an event failed, either because the handler returned an error or
+// because it responded with an error status code, as RPC runtimes do when the handler raises
+func EventFailed(response interface{}, processError error) bool {
+	return EventError(response, processError) != nil
+}
+
+// EventError returns the reason handling an event failed (see EventFailed), or nil if it succeeded
+func EventError(response interface{}, processError error) error {
+	if processError != nil {
+		return processError
+	}
+
+	statusCode := 0
+
+	switch typedResponse := response.(type) {
+	case *nuclio.Response:
+		statusCode = typedResponse.StatusCode
+	case nuclio.Response:
+		statusCode = typedResponse.StatusCode
+	}
+
+	if statusCode >= http.StatusBadRequest {
+		return errors.Errorf("Handler responded with status code %d", statusCode)
+	}
+
+	return nil
+}
+
+// FlattenHeaders converts the headers of a handler's response to string values. Header values
+// that are neither strings, ints nor string slices are formatted with fmt.Sprint
+func FlattenHeaders(headers map[string]interface{}) map[string][]string {
+	flattenedHeaders := make(map[string][]string, len(headers))
+
+	for headerKey, headerValue := range headers {
+		switch typedHeaderValue := headerValue.(type) {
+		case string:
+			flattenedHeaders[headerKey] = []string{typedHeaderValue}
+		case int:
+			flattenedHeaders[headerKey] = []string{strconv.Itoa(typedHeaderValue)}
+		case []string:
+			flattenedHeaders[headerKey] = typedHeaderValue
+		default:
+			flattenedHeaders[headerKey] = []string{fmt.Sprint(typedHeaderValue)}
+		}
+	}
+
+	return flattenedHeaders
+}
@@ -0,0 +1,178 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
+	"github.com/stretchr/testify/suite"
+)
+
+type ResponseTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ResponseTestSuite) TestResolveResponse() {
+	for _, testCase := range []struct {
+		name             string
+		response         interface{}
+		submitError      error
+		processError     error
+		expectedResponse *Response
+	}{
+		{
+			name: "response",
+			response: nuclio.Response{
+				StatusCode:  http.StatusCreated,
+				ContentType: "application/json",
+				Body:        []byte(`{"id": 1}`),
+				Headers: map[string]interface{}{
+					"X-Request-Id": "abc",
+					"X-Count":      3,
+					"X-Tags":       []string{"a", "b"},
+					"X-Enabled":    true,
+				},
+			},
+			expectedResponse: &Response{
+				StatusCode:  http.StatusCreated,
+				ContentType: "application/json",
+				Body:        []byte(`{"id": 1}`),
+				Headers: map[string][]string{
+					"X-Request-Id": {"abc"},
+					"X-Count":      {"3"},
+					"X-Tags":       {"a", "b"},
+					"X-Enabled":    {"true"},
+				},
+			},
+		},
+		{
+			name:     "responsePointer",
+			response: &nuclio.Response{Body: []byte("pointer")},
+			expectedResponse: &Response{
+				Headers: map[string][]string{},
+				Body:    []byte("pointer"),
+			},
+		},
+		{
+			name:             "bytes",
+			response:         []byte("bytes"),
+			expectedResponse: &Response{Body: []byte("bytes")},
+		},
+		{
+			name:             "string",
+			response:         "string",
+			expectedResponse: &Response{Body: []byte("string")},
+		},
+		{
+			name:             "nil",
+			expectedResponse: &Response{},
+		},
+		{
+			name:         "processErrorWithStatusCode",
+			processError: nuclio.NewErrBadRequest("Missing field"),
+			expectedResponse: &Response{
+				StatusCode: http.StatusBadRequest,
+				Body:       []byte("Missing field"),
+			},
+		},
+		{
+			name:         "processError",
+			processError: errors.New("Handler failed"),
+			expectedResponse: &Response{
+				StatusCode: http.StatusInternalServerError,
+				Body:       []byte("Handler failed"),
+			},
+		},
+		{
+			name:             "noAvailableWorkers",
+			submitError:      errors.Wrap(worker.ErrNoAvailableWorkers, "Failed to allocate worker"),
+			expectedResponse: &Response{StatusCode: http.StatusServiceUnavailable},
+		},
+		{
+			name:             "allWorkersAreTerminated",
+			submitError:      worker.ErrAllWorkersAreTerminated,
+			expectedResponse: &Response{StatusCode: http.StatusServiceUnavailable},
+		},
+		{
+			name:             "submitError",
+			submitError:      errors.New("Something broke"),
+			expectedResponse: &Response{StatusCode: http.StatusInternalServerError},
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.Require().Equal(testCase.expectedResponse,
+				ResolveResponse(testCase.response, testCase.submitError, testCase.processError))
+		})
+	}
+}
+
+func (suite *ResponseTestSuite) TestEventFailed() {
+	for _, testCase := range []struct {
+		name           string
+		response       interface{}
+		processError   error
+		expectedFailed bool
+	}{
+		{
+			name:           "success",
+			response:       nuclio.Response{StatusCode: http.StatusOK},
+			expectedFailed: false,
+		},
+		{
+			name:           "noStatusCode",
+			response:       []byte("body"),
+			expectedFailed: false,
+		},
+		{
+			name:           "errorStatusCode",
+			response:       nuclio.Response{StatusCode: http.StatusInternalServerError},
+			expectedFailed: true,
+		},
+		{
+			name:           "errorStatusCodePointer",
+			response:       &nuclio.Response{StatusCode: http.StatusBadRequest},
+			expectedFailed: true,
+		},
+		{
+			name:           "processError",
+			processError:   errors.New("Handler failed"),
+			expectedFailed: true,
+		},
+	} {
+		suite.Run(testCase.name, func() {
+			suite.Require().Equal(testCase.expectedFailed, EventFailed(testCase.response, testCase.processError))
+		})
+	}
+}
+
+func (suite *ResponseTestSuite) TestEventError() {
+	suite.Require().NoError(EventError(nuclio.Response{StatusCode: http.StatusOK}, nil))
+	suite.Require().EqualError(EventError(nuclio.Response{StatusCode: http.StatusBadGateway}, nil),
+		"Handler responded with status code 502")
+	suite.Require().EqualError(EventError(nil, errors.New("Handler failed")), "Handler failed")
+}
+
+func TestResponseTestSuite(t *testing.T) {
+	suite.Run(t, new(ResponseTestSuite))
+}
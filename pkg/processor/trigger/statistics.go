@@ -0,0 +1,139 @@
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+)
+
+const (
+	DefaultStatisticsWindowDuration = time.Minute
+	maxStatisticsWindowSamples      = 60
+)
+
+// StatisticsSample is a copy of a trigger's statistics, and of its workers' runtime statistics (keyed by
+// worker index), taken at a point in time
+type StatisticsSample struct {
+	TakenAt                 time.Time
+	Statistics              Statistics
+	WorkerRuntimeStatistics map[int]runtime.Statistics
+}
+
+// DiffFrom returns the trigger statistics and the sum of the workers' runtime statistics between the baseline
+// and this sample. counters that went backwards (e.g. since a worker's runtime was recreated) are counted
+// from zero rather than underflowing
+func (ss *StatisticsSample) DiffFrom(baseline *StatisticsSample) (Statistics, runtime.Statistics) {
+	current := &ss.Statistics.WorkerAllocatorStatistics
+	previous := &baseline.Statistics.WorkerAllocatorStatistics
+
+	diffStatistics := Statistics{
+		EventsHandledSuccessTotal: counterDiff(ss.Statistics.EventsHandledSuccessTotal,
+			baseline.Statistics.EventsHandledSuccessTotal),
+		EventsHandledFailureTotal: counterDiff(ss.Statistics.EventsHandledFailureTotal,
+			baseline.Statistics.EventsHandledFailureTotal),
+		WorkerAllocatorStatistics: worker.AllocatorStatistics{
+			WorkerAllocationCount: counterDiff(current.WorkerAllocationCount,
+				previous.WorkerAllocationCount),
+			WorkerAllocationSuccessImmediateTotal: counterDiff(current.WorkerAllocationSuccessImmediateTotal,
+				previous.WorkerAllocationSuccessImmediateTotal),
+			WorkerAllocationSuccessAfterWaitTotal: counterDiff(current.WorkerAllocationSuccessAfterWaitTotal,
+				previous.WorkerAllocationSuccessAfterWaitTotal),
+			WorkerAllocationTimeoutTotal: counterDiff(current.WorkerAllocationTimeoutTotal,
+				previous.WorkerAllocationTimeoutTotal),
+			WorkerAllocationWaitDurationMilliSecondsSum: counterDiff(current.WorkerAllocationWaitDurationMilliSecondsSum,
+				previous.WorkerAllocationWaitDurationMilliSecondsSum),
+
+			// a gauge rather than a counter
+			WorkerAllocationWorkersAvailablePercentage: current.WorkerAllocationWorkersAvailablePercentage,
+		},
+	}
+
+	diffRuntimeStatistics := runtime.Statistics{}
+	for workerIndex, currentRuntimeStatistics := range ss.WorkerRuntimeStatistics {
+		previousRuntimeStatistics := baseline.WorkerRuntimeStatistics[workerIndex]
+
+		// the sum and count go backwards together, so they're either both diffed or both counted from zero
+		if currentRuntimeStatistics.DurationMilliSecondsCount < previousRuntimeStatistics.DurationMilliSecondsCount ||
+			currentRuntimeStatistics.DurationMilliSecondsSum < previousRuntimeStatistics.DurationMilliSecondsSum {
+			previousRuntimeStatistics = runtime.Statistics{}
+		}
+
+		diffRuntimeStatistics.DurationMilliSecondsSum += currentRuntimeStatistics.DurationMilliSecondsSum -
+			previousRuntimeStatistics.DurationMilliSecondsSum
+		diffRuntimeStatistics.DurationMilliSecondsCount += currentRuntimeStatistics.DurationMilliSecondsCount -
+			previousRuntimeStatistics.DurationMilliSecondsCount
+	}
+
+	return diffStatistics, diffRuntimeStatistics
+}
+
+// StatisticsWindow keeps samples of a trigger's statistics over a fixed window, so that the rates derived
+// from them cover the same period no matter how often, or by how many clients, the statistics are read
+type StatisticsWindow struct {
+	duration time.Duration
+	lock     sync.Mutex
+	samples  []*StatisticsSample
+}
+
+// NewStatisticsWindow creates a statistics window of the given duration
+func NewStatisticsWindow(duration time.Duration) *StatisticsWindow {
+	return &StatisticsWindow{
+		duration: duration,
+	}
+}
+
+// Add adds a sample to the window and returns the sample rates should be derived from - the newest sample
+// taken at least the window's duration earlier, or the oldest sample if none is that old. returns nil if
+// there are no earlier samples
+func (sw *StatisticsWindow) Add(sample *StatisticsSample) *StatisticsSample {
+	sw.lock.Lock()
+	defer sw.lock.Unlock()
+
+	windowStart := sample.TakenAt.Add(-sw.duration)
+
+	// drop the samples that are older than the newest one before the window starts
+	for len(sw.samples) > 1 && !sw.samples[1].TakenAt.After(windowStart) {
+		sw.samples = sw.samples[1:]
+	}
+
+	var baseline *StatisticsSample
+	if len(sw.samples) > 0 {
+		baseline = sw.samples[0]
+	}
+
+	// keep a bounded number of samples, regardless of how often the statistics are read
+	if len(sw.samples) == 0 ||
+		sample.TakenAt.Sub(sw.samples[len(sw.samples)-1].TakenAt) >= sw.duration/maxStatisticsWindowSamples {
+		sw.samples = append(sw.samples, sample)
+	}
+
+	return baseline
+}
+
+func counterDiff(current uint64, previous uint64) uint64 {
+
+	// the counter was reset
+	if current < previous {
+		return current
+	}
+
+	return current - previous
+}
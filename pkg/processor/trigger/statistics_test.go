@@ -0,0 +1,142 @@
+//go:build test_unit
+
+/*
+Copyright 2023 The Nuclio Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package trigger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StatisticsTestSuite struct {
+	suite.Suite
+	startTime time.Time
+}
+
+func (suite *StatisticsTestSuite) SetupTest() {
+	suite.startTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (suite *StatisticsTestSuite) TestWindowBaseline() {
+	statisticsWindow := NewStatisticsWindow(time.Minute)
+
+	// no earlier sample
+	suite.Require().Nil(statisticsWindow.Add(suite.createSample(0, 0)))
+
+	// within the window, the baseline is the oldest sample
+	suite.Require().Equal(suite.startTime, statisticsWindow.Add(suite.createSample(10*time.Second, 10)).TakenAt)
+	suite.Require().Equal(suite.startTime, statisticsWindow.Add(suite.createSample(30*time.Second, 30)).TakenAt)
+
+	// past the window, the baseline is the newest sample taken at least the window's duration earlier
+	suite.Require().Equal(suite.startTime.Add(10*time.Second),
+		statisticsWindow.Add(suite.createSample(80*time.Second, 80)).TakenAt)
+
+	// after a long pause, the baseline is the last sample taken before it
+	suite.Require().Equal(suite.startTime.Add(80*time.Second),
+		statisticsWindow.Add(suite.createSample(time.Hour, 100)).TakenAt)
+}
+
+func (suite *StatisticsTestSuite) TestWindowIndependentOfReaders() {
+	statisticsWindow := NewStatisticsWindow(time.Minute)
+	statisticsWindow.Add(suite.createSample(0, 0))
+
+	// one reader polls frequently, yet another reader polling at the same time gets the same baseline
+	for offset := time.Second; offset <= 2*time.Minute; offset += time.Second {
+		baseline := statisticsWindow.Add(suite.createSample(offset, uint64(offset.Seconds())))
+
+		if offset >= time.Minute {
+			sameTimeBaseline := statisticsWindow.Add(suite.createSample(offset, uint64(offset.Seconds())))
+			suite.Require().Equal(baseline, sameTimeBaseline)
+
+			// the baseline is always about a window earlier
+			suite.Require().InDelta(time.Minute.Seconds(),
+				suite.startTime.Add(offset).Sub(baseline.TakenAt).Seconds(),
+				2)
+		}
+	}
+
+	// the number of samples kept is bounded
+	suite.Require().LessOrEqual(len(statisticsWindow.samples), maxStatisticsWindowSamples+2)
+}
+
+func (suite *StatisticsTestSuite) TestSampleDiff() {
+	baseline := &StatisticsSample{
+		Statistics: Statistics{
+			EventsHandledSuccessTotal: 10,
+			EventsHandledFailureTotal: 5,
+			WorkerAllocatorStatistics: worker.AllocatorStatistics{
+				WorkerAllocationCount:                      15,
+				WorkerAllocationWorkersAvailablePercentage: 50,
+			},
+		},
+		WorkerRuntimeStatistics: map[int]runtime.Statistics{
+			0: {DurationMilliSecondsSum: 100, DurationMilliSecondsCount: 10},
+			1: {DurationMilliSecondsSum: 200, DurationMilliSecondsCount: 5},
+		},
+	}
+
+	current := &StatisticsSample{
+		Statistics: Statistics{
+			EventsHandledSuccessTotal: 25,
+
+			// went backwards
+			EventsHandledFailureTotal: 2,
+			WorkerAllocatorStatistics: worker.AllocatorStatistics{
+				WorkerAllocationCount:                      27,
+				WorkerAllocationWorkersAvailablePercentage: 20,
+			},
+		},
+		WorkerRuntimeStatistics: map[int]runtime.Statistics{
+			0: {DurationMilliSecondsSum: 150, DurationMilliSecondsCount: 12},
+
+			// worker 1's runtime was recreated
+			1: {DurationMilliSecondsSum: 30, DurationMilliSecondsCount: 3},
+
+			// a worker with no baseline
+			2: {DurationMilliSecondsSum: 10, DurationMilliSecondsCount: 1},
+		},
+	}
+
+	diffStatistics, diffRuntimeStatistics := current.DiffFrom(baseline)
+	suite.Require().Equal(uint64(15), diffStatistics.EventsHandledSuccessTotal)
+	suite.Require().Equal(uint64(2), diffStatistics.EventsHandledFailureTotal)
+	suite.Require().Equal(uint64(12), diffStatistics.WorkerAllocatorStatistics.WorkerAllocationCount)
+	suite.Require().Equal(uint64(20), diffStatistics.WorkerAllocatorStatistics.WorkerAllocationWorkersAvailablePercentage)
+	suite.Require().Equal(runtime.Statistics{
+		DurationMilliSecondsSum:   50 + 30 + 10,
+		DurationMilliSecondsCount: 2 + 3 + 1,
+	}, diffRuntimeStatistics)
+}
+
+func (suite *StatisticsTestSuite) createSample(offset time.Duration, eventsHandledSuccessTotal uint64) *StatisticsSample {
+	return &StatisticsSample{
+		TakenAt: suite.startTime.Add(offset),
+		Statistics: Statistics{
+			EventsHandledSuccessTotal: eventsHandledSuccessTotal,
+		},
+	}
+}
+
+func TestStatisticsTestSuite(t *testing.T) {
+	suite.Run(t, new(StatisticsTestSuite))
+}
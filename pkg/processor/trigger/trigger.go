@@ -23,8 +23,10 @@ import (
 	"time"
 
 	"github.com/nuclio/nuclio/pkg/common"
+	"github.com/nuclio/nuclio/pkg/common/headers"
 	"github.com/nuclio/nuclio/pkg/functionconfig"
 	"github.com/nuclio/nuclio/pkg/processor/controlcommunication"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
 	"github.com/nuclio/nuclio/pkg/processor/worker"
 
 	"github.com/google/uuid"
@@ -68,6 +70,9 @@ type Trigger interface {
 	// GetStatistics returns the trigger statistics
 	GetStatistics() *Statistics
 
+	// GetStatisticsWindow returns the window of statistics samples rates are derived from
+	GetStatisticsWindow() *StatisticsWindow
+
 	// GetWorkers gets direct access to workers for things like housekeeping / management
 	// TODO: locks and such when relevant
 	GetWorkers() []*worker.Worker
@@ -92,6 +97,23 @@ type Trigger interface {
 
 	// SignalWorkersToTerminate signal to all workers that the processor is about to stop working
 	SignalWorkersToTerminate() error
+
+	// AddObserver adds an observer that is notified of event handling and worker allocation latencies
+	AddObserver(observer Observer)
+
+	// RemoveObserver removes an observer added with AddObserver
+	RemoveObserver(observer Observer)
+}
+
+// Observer is notified of latencies on the fast path, as they happen. implementations must be cheap and
+// safe for concurrent use
+type Observer interface {
+
+	// ObserveEventHandled is called after an event was handled by a worker
+	ObserveEventHandled(duration time.Duration, success bool)
+
+	// ObserveWorkerAllocation is called after a worker was allocated (or failed to be allocated) for an event
+	ObserveWorkerAllocation(waitDuration time.Duration, success bool)
 }
 
 // AbstractTrigger implements common trigger operations
@@ -111,6 +133,11 @@ type AbstractTrigger struct {
 	FunctionName    string
 	ProjectName     string
 	restartChan     chan Trigger
+
+	statisticsWindow *StatisticsWindow
+
+	// holds []Observer, read on the fast path
+	observers atomic.Value
 }
 
 func NewAbstractTrigger(logger logger.Logger,
@@ -132,16 +159,17 @@ func NewAbstractTrigger(logger logger.Logger,
 	}
 
 	return AbstractTrigger{
-		Logger:          logger,
-		ID:              configuration.ID,
-		WorkerAllocator: allocator,
-		Class:           class,
-		Kind:            kind,
-		Name:            name,
-		Namespace:       configuration.RuntimeConfiguration.Meta.Namespace,
-		FunctionName:    configuration.RuntimeConfiguration.Meta.Name,
-		ProjectName:     configuration.RuntimeConfiguration.Meta.Labels[common.NuclioResourceLabelKeyProjectName],
-		restartChan:     restartTriggerChan,
+		Logger:           logger,
+		ID:               configuration.ID,
+		WorkerAllocator:  allocator,
+		Class:            class,
+		Kind:             kind,
+		Name:             name,
+		Namespace:        configuration.RuntimeConfiguration.Meta.Namespace,
+		FunctionName:     configuration.RuntimeConfiguration.Meta.Name,
+		ProjectName:      configuration.RuntimeConfiguration.Meta.Labels[common.NuclioResourceLabelKeyProjectName],
+		restartChan:      restartTriggerChan,
+		statisticsWindow: NewStatisticsWindow(DefaultStatisticsWindowDuration),
 	}, nil
 }
 
@@ -165,6 +193,15 @@ func (at *AbstractTrigger) GetName() string {
 	return at.Name
 }
 
+// AllocateWorker allocates a worker from the trigger's worker allocator, notifying the observers
+func (at *AbstractTrigger) AllocateWorker(timeout time.Duration) (*worker.Worker, error) {
+	startTime := time.Now()
+	workerInstance, err := at.WorkerAllocator.Allocate(timeout)
+	at.ObserveWorkerAllocation(time.Since(startTime), err == nil)
+
+	return workerInstance, err
+}
+
 // AllocateWorkerAndSubmitEvent submits event to allocated worker
 func (at *AbstractTrigger) AllocateWorkerAndSubmitEvent(event nuclio.Event,
 	functionLogger logger.Logger,
@@ -175,7 +212,7 @@ func (at *AbstractTrigger) AllocateWorkerAndSubmitEvent(event nuclio.Event,
 	defer at.HandleSubmitPanic(workerInstance, &submitError)
 
 	// allocate a worker
-	workerInstance, err := at.WorkerAllocator.Allocate(timeout)
+	workerInstance, err := at.AllocateWorker(timeout)
 	if err != nil {
 		at.UpdateStatistics(false)
 
@@ -203,7 +240,7 @@ func (at *AbstractTrigger) AllocateWorkerAndSubmitEvents(events []nuclio.Event,
 	eventErrors := make([]error, 0, len(events))
 
 	// allocate a worker
-	workerInstance, err := at.WorkerAllocator.Allocate(timeout)
+	workerInstance, err := at.AllocateWorker(timeout)
 	if err != nil {
 		at.UpdateStatistics(false)
 
@@ -226,6 +263,30 @@ func (at *AbstractTrigger) AllocateWorkerAndSubmitEvents(events []nuclio.Event,
 	return eventResponses, nil, eventErrors
 }
 
+// AllocateWorkerAndSubmitEventBatch submits a batch of events to an allocated worker
+func (at *AbstractTrigger) AllocateWorkerAndSubmitEventBatch(batch []nuclio.Event,
+	functionLogger logger.Logger,
+	timeout time.Duration) (responses []*runtime.BatchEventResponse, submitError error) {
+	var workerInstance *worker.Worker
+
+	defer at.HandleSubmitPanic(workerInstance, &submitError)
+
+	// allocate a worker
+	workerInstance, err := at.AllocateWorker(timeout)
+	if err != nil {
+		at.UpdateStatistics(false)
+
+		return nil, errors.Wrap(err, "Failed to allocate worker")
+	}
+
+	responses, submitError = at.SubmitEventBatchToWorker(functionLogger, workerInstance, batch)
+
+	// release worker when we're done
+	at.WorkerAllocator.Release(workerInstance)
+
+	return
+}
+
 // GetWorkers returns the list of workers
 func (at *AbstractTrigger) GetWorkers() []*worker.Worker {
 	return at.WorkerAllocator.GetWorkers()
@@ -240,6 +301,11 @@ func (at *AbstractTrigger) GetStatistics() *Statistics {
 	return &at.Statistics
 }
 
+// GetStatisticsWindow returns the window of statistics samples rates are derived from
+func (at *AbstractTrigger) GetStatisticsWindow() *StatisticsWindow {
+	return at.statisticsWindow
+}
+
 // GetID returns user given ID for this trigger
 func (at *AbstractTrigger) GetID() string {
 	return at.ID
@@ -294,13 +360,93 @@ func (at *AbstractTrigger) SubmitEventToWorker(functionLogger logger.Logger,
 		return nil, err
 	}
 
+	startTime := time.Now()
 	response, processError = workerInstance.ProcessEvent(event, functionLogger)
+	success := !EventFailed(response, processError)
+	at.ObserveEventHandled(time.Since(startTime), success)
 
-	// increment statistics based on results. if process error is nil, we successfully handled
-	at.UpdateStatistics(processError == nil)
+	// increment statistics based on results. an error or an error status code means the event failed
+	at.UpdateStatistics(success)
 	return
 }
 
+// SubmitEventBatchToWorker submits a batch of events to a worker and returns a response per event. the
+// returned error indicates that the batch as a whole failed to be processed
+func (at *AbstractTrigger) SubmitEventBatchToWorker(functionLogger logger.Logger,
+	workerInstance *worker.Worker,
+	batch []nuclio.Event) ([]*runtime.BatchEventResponse, error) {
+
+	// unlike single events, batched events are not wrapped as cloud events since each worker holds
+	// a single cloud event instance
+	for _, event := range batch {
+		event.SetID(nuclio.ID(uuid.New().String()))
+		event.SetTriggerInfoProvider(at)
+	}
+
+	startTime := time.Now()
+	responses, err := workerInstance.ProcessEventBatch(batch, functionLogger)
+
+	// each event in the batch waited for the entire batch to be handled
+	batchDuration := time.Since(startTime)
+
+	if err != nil {
+		for range batch {
+			at.ObserveEventHandled(batchDuration, false)
+			at.UpdateStatistics(false)
+		}
+
+		return nil, errors.Wrap(err, "Failed to process batch")
+	}
+
+	// increment statistics based on the result of each event
+	for _, response := range responses {
+		success := !EventFailed(response.Response, response.ProcessError)
+		at.ObserveEventHandled(batchDuration, success)
+		at.UpdateStatistics(success)
+	}
+
+	return responses, nil
+}
+
+// BatchEventShouldBeAcked returns whether an event that was processed as part of a batch should be acked
+// (i.e. have its offset committed), according to the explicit ack mode of the trigger
+func BatchEventShouldBeAcked(explicitAckMode functionconfig.ExplicitAckMode,
+	response *runtime.BatchEventResponse) bool {
+
+	if response.ProcessError != nil {
+		return false
+	}
+
+	return EventShouldBeAcked(explicitAckMode, response.Response)
+}
+
+// EventShouldBeAcked returns whether an event that was processed successfully should be acked, according to
+// the explicit ack mode of the trigger and the response of the handler
+func EventShouldBeAcked(explicitAckMode functionconfig.ExplicitAckMode, response interface{}) bool {
+	switch explicitAckMode {
+	case functionconfig.ExplicitAckModeExplicitOnly:
+
+		// offsets are only committed by the explicit ack handler
+		return false
+
+	case functionconfig.ExplicitAckModeEnable:
+		var responseHeaders map[string]interface{}
+		switch typedResponse := response.(type) {
+		case nuclio.Response:
+			responseHeaders = typedResponse.Headers
+		case *nuclio.Response:
+			responseHeaders = typedResponse.Headers
+		}
+
+		// the handler asked not to ack the event
+		if noAck, ok := responseHeaders[headers.StreamNoAck].(bool); ok && noAck {
+			return false
+		}
+	}
+
+	return true
+}
+
 // TimeoutWorker times out a worker
 func (at *AbstractTrigger) TimeoutWorker(worker *worker.Worker) error {
 	return nil
@@ -315,6 +461,50 @@ func (at *AbstractTrigger) UpdateStatistics(success bool) {
 	}
 }
 
+// AddObserver adds an observer that is notified of event handling and worker allocation latencies. observers
+// may be added and removed while the trigger runs, but not concurrently with one another
+func (at *AbstractTrigger) AddObserver(observer Observer) {
+	currentObservers, _ := at.observers.Load().([]Observer)
+
+	// copy on write, so that the fast path can read the observers without locking
+	observers := make([]Observer, 0, len(currentObservers)+1)
+	observers = append(observers, currentObservers...)
+	observers = append(observers, observer)
+
+	at.observers.Store(observers)
+}
+
+// RemoveObserver removes an observer added with AddObserver
+func (at *AbstractTrigger) RemoveObserver(observer Observer) {
+	currentObservers, _ := at.observers.Load().([]Observer)
+
+	// copy on write, as in AddObserver
+	observers := make([]Observer, 0, len(currentObservers))
+	for _, currentObserver := range currentObservers {
+		if currentObserver != observer {
+			observers = append(observers, currentObserver)
+		}
+	}
+
+	at.observers.Store(observers)
+}
+
+// ObserveEventHandled notifies the observers that an event was handled
+func (at *AbstractTrigger) ObserveEventHandled(duration time.Duration, success bool) {
+	observers, _ := at.observers.Load().([]Observer)
+	for _, observer := range observers {
+		observer.ObserveEventHandled(duration, success)
+	}
+}
+
+// ObserveWorkerAllocation notifies the observers that a worker allocation completed
+func (at *AbstractTrigger) ObserveWorkerAllocation(waitDuration time.Duration, success bool) {
+	observers, _ := at.observers.Load().([]Observer)
+	for _, observer := range observers {
+		observer.ObserveWorkerAllocation(waitDuration, success)
+	}
+}
+
 // Restart signals the processor to start the trigger restart procedure
 func (at *AbstractTrigger) Restart() error {
 	at.Logger.Warn("Restart called in trigger", "triggerKind", at.GetKind(), "triggerName", at.GetName())
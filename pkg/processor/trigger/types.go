@@ -54,6 +54,9 @@ type Configuration struct {
 
 	// a unique trigger ID
 	ID string
+
+	// the maximal time to wait for a batch to fill up, relevant only when batching is enabled
+	BatchTimeout time.Duration
 }
 
 func NewConfiguration(id string,
@@ -81,6 +84,12 @@ func NewConfiguration(id string,
 	}
 	runtimeConfiguration.WorkerTerminationTimeout = workerTerminationTimeout
 
+	if functionconfig.BatchModeEnabled(triggerConfiguration.Batch) {
+		if err := configuration.populateBatchConfiguration(); err != nil {
+			return nil, errors.Wrap(err, "Failed to populate batch configuration")
+		}
+	}
+
 	return configuration, nil
 }
 
@@ -170,6 +179,33 @@ func (c *Configuration) ResolveWorkerAllocationMode(modeFromAttributes, modeFrom
 	return partitionworker.AllocationModePool
 }
 
+func (c *Configuration) populateBatchConfiguration() error {
+	if c.Batch.BatchSize < 0 {
+		return errors.Errorf("Batch size must be positive, got %d", c.Batch.BatchSize)
+	}
+
+	if c.Batch.BatchSize == 0 {
+		c.Batch.BatchSize = functionconfig.DefaultBatchSize
+	}
+
+	if c.Batch.Timeout == "" {
+		c.Batch.Timeout = functionconfig.DefaultBatchTimeout
+	}
+
+	batchTimeout, err := time.ParseDuration(c.Batch.Timeout)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse batch timeout")
+	}
+
+	if batchTimeout <= 0 {
+		return errors.Errorf("Batch timeout must be positive, got %s", c.Batch.Timeout)
+	}
+
+	c.BatchTimeout = batchTimeout
+
+	return nil
+}
+
 type Statistics struct {
 	EventsHandledSuccessTotal uint64
 	EventsHandledFailureTotal uint64
@@ -170,6 +170,10 @@ func (vs *v3iostream) Cleanup(session streamconsumergroup.Session) error {
 func (vs *v3iostream) ConsumeClaim(session streamconsumergroup.Session, claim streamconsumergroup.Claim) error {
 	var submitError error
 
+	if functionconfig.BatchModeEnabled(vs.configuration.Batch) {
+		return vs.consumeClaimInBatches(session, claim)
+	}
+
 	submittedEventInstance := submittedEvent{
 		done: make(chan error),
 	}
@@ -202,7 +206,7 @@ func (vs *v3iostream) ConsumeClaim(session streamconsumergroup.Session, claim st
 			record := &recordBatch.Records[recordIndex]
 
 			// allocate a worker for this topic/partition
-			workerInstance, cookie, err := vs.partitionWorkerAllocator.AllocateWorker(vs.topic, claim.GetShardID(), nil)
+			workerInstance, cookie, err := vs.allocatePartitionWorker(claim)
 			if err != nil {
 				return errors.Wrap(err, "Failed to allocate worker")
 			}
@@ -244,6 +248,145 @@ func (vs *v3iostream) ConsumeClaim(session streamconsumergroup.Session, claim st
 	return submitError
 }
 
+func (vs *v3iostream) consumeClaimInBatches(session streamconsumergroup.Session, claim streamconsumergroup.Claim) error {
+	explicitAckControlMessageChan := make(chan *controlcommunication.ControlMessage)
+
+	vs.Logger.DebugWith("Starting claim consumption in batches",
+		"shardID", claim.GetShardID(),
+		"batchSize", vs.configuration.Batch.BatchSize,
+		"batchTimeout", vs.configuration.BatchTimeout.String())
+
+	commitRecordFuncHandler := vs.resolveCommitRecordFuncHandler(session)
+
+	// listen for explicit ack messages if enabled
+	if functionconfig.ExplicitAckEnabled(vs.configuration.ExplicitAckMode) {
+		if err := vs.SubscribeToControlMessageKind(controlcommunication.StreamMessageAckKind, explicitAckControlMessageChan); err != nil {
+			return errors.Wrap(err, "Failed to subscribe to explicit ack control messages")
+		}
+
+		go vs.explicitAckHandler(explicitAckControlMessageChan, commitRecordFuncHandler)
+	}
+
+	var (
+		consumptionErr error
+		pendingRecords []*v3io.StreamRecord
+	)
+
+	recordBatchChan := claim.GetRecordBatchChan()
+	recordBatchChanClosed := false
+
+	for !recordBatchChanClosed {
+		pendingRecords, recordBatchChanClosed = vs.collectRecordBatch(recordBatchChan, pendingRecords)
+
+		// records that were not submitted when consumption stopped are not committed, and will be consumed again
+		if recordBatchChanClosed || len(pendingRecords) == 0 {
+			break
+		}
+
+		batchSize := vs.configuration.Batch.BatchSize
+		if len(pendingRecords) < batchSize {
+			batchSize = len(pendingRecords)
+		}
+
+		if consumptionErr = vs.submitRecordBatch(claim, pendingRecords[:batchSize], commitRecordFuncHandler); consumptionErr != nil {
+			break
+		}
+
+		pendingRecords = pendingRecords[batchSize:]
+	}
+
+	vs.Logger.DebugWith("Claim consumption in batches stopped", "shardID", claim.GetShardID())
+
+	// unsubscribe channel from the streamAck control message kind before closing it
+	if err := vs.UnsubscribeFromControlMessageKind(controlcommunication.StreamMessageAckKind, explicitAckControlMessageChan); err != nil {
+		vs.Logger.WarnWith("Failed to unsubscribe channel from control message kind", "err", err)
+	}
+
+	close(explicitAckControlMessageChan)
+
+	return consumptionErr
+}
+
+// collectRecordBatch adds records to the pending records until there are enough records for a batch or the batch
+// timeout has passed. returns true if the record batch channel was closed
+func (vs *v3iostream) collectRecordBatch(recordBatchChan <-chan *streamconsumergroup.RecordBatch,
+	pendingRecords []*v3io.StreamRecord) ([]*v3io.StreamRecord, bool) {
+
+	appendRecords := func(recordBatch *streamconsumergroup.RecordBatch) {
+		for recordIndex := 0; recordIndex < len(recordBatch.Records); recordIndex++ {
+			pendingRecords = append(pendingRecords, &recordBatch.Records[recordIndex])
+		}
+	}
+
+	// wait for the first records
+	for len(pendingRecords) == 0 {
+		recordBatch, ok := <-recordBatchChan
+		if !ok {
+			return pendingRecords, true
+		}
+		appendRecords(recordBatch)
+	}
+
+	batchTimer := time.NewTimer(vs.configuration.BatchTimeout)
+	defer batchTimer.Stop()
+
+	for len(pendingRecords) < vs.configuration.Batch.BatchSize {
+		select {
+		case recordBatch, ok := <-recordBatchChan:
+			if !ok {
+				return pendingRecords, true
+			}
+			appendRecords(recordBatch)
+		case <-batchTimer.C:
+			return pendingRecords, false
+		}
+	}
+
+	return pendingRecords, false
+}
+
+func (vs *v3iostream) submitRecordBatch(claim streamconsumergroup.Claim,
+	records []*v3io.StreamRecord,
+	commitRecordFuncHandler func(*v3io.StreamRecord)) error {
+
+	// allocate a worker for this topic/partition
+	workerInstance, cookie, err := vs.allocatePartitionWorker(claim)
+	if err != nil {
+		return errors.Wrap(err, "Failed to allocate worker")
+	}
+
+	batch := make([]nuclio.Event, 0, len(records))
+	for _, record := range records {
+		batch = append(batch, &Event{
+			record:     record,
+			StreamPath: claim.GetStreamPath(),
+		})
+	}
+
+	responses, err := vs.SubmitEventBatchToWorker(nil, workerInstance, batch)
+	if err != nil {
+		vs.Logger.DebugWith("Batch processing error",
+			"shardID", claim.GetShardID(),
+			"batchSize", len(batch),
+			"err", err)
+	} else {
+
+		// commit each record that should be acked
+		for recordIndex, response := range responses {
+			if trigger.BatchEventShouldBeAcked(vs.configuration.ExplicitAckMode, response) {
+				commitRecordFuncHandler(records[recordIndex])
+			}
+		}
+	}
+
+	// release the worker from whence it came
+	if err := vs.partitionWorkerAllocator.ReleaseWorker(cookie, workerInstance); err != nil {
+		return errors.Wrap(err, "Failed to release worker")
+	}
+
+	return nil
+}
+
 func (vs *v3iostream) Abort(session streamconsumergroup.Session) error {
 	vs.Logger.Warn("Abort called in trigger", "triggerKind", vs.GetKind(), "triggerName", vs.GetName())
 
@@ -303,6 +446,15 @@ func (vs *v3iostream) eventSubmitter(claim streamconsumergroup.Claim, submittedE
 	vs.Logger.DebugWith("Event submitter stopped", "shardID", claim.GetShardID())
 }
 
+// allocatePartitionWorker allocates a worker for the shard of the claim
+func (vs *v3iostream) allocatePartitionWorker(claim streamconsumergroup.Claim) (*worker.Worker, interface{}, error) {
+	startTime := time.Now()
+	workerInstance, cookie, err := vs.partitionWorkerAllocator.AllocateWorker(vs.topic, claim.GetShardID(), nil)
+	vs.ObserveWorkerAllocation(time.Since(startTime), err == nil)
+
+	return workerInstance, cookie, err
+}
+
 func (vs *v3iostream) newConsumerGroupMember() (streamconsumergroup.Member, error) {
 
 	v3ioContext, err := v3iohttp.NewContext(vs.Logger,
@@ -17,12 +17,22 @@ limitations under the License.
 package resource
 
 import (
+	"encoding/json"
+	"io"
 	"net/http"
+	"sync/atomic"
+	"time"
 
+	"github.com/nuclio/nuclio/pkg/functionconfig"
+	"github.com/nuclio/nuclio/pkg/processor/runtime"
+	"github.com/nuclio/nuclio/pkg/processor/trigger"
 	"github.com/nuclio/nuclio/pkg/processor/webadmin"
+	"github.com/nuclio/nuclio/pkg/processor/worker"
 	"github.com/nuclio/nuclio/pkg/restful"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/nuclio/errors"
+	"github.com/nuclio/nuclio-sdk-go"
 )
 
 type triggersResource struct {
@@ -32,8 +42,7 @@ type triggersResource struct {
 func (tr *triggersResource) GetAll(request *http.Request) (map[string]restful.Attributes, error) {
 	triggers := map[string]restful.Attributes{}
 
-	// iterate over triggers
-	// TODO: when this is dynamic (create/delete support), add some locking
+	// iterate over triggers. the processor returns a copy, so triggers may be created / deleted meanwhile
 	for _, trigger := range tr.getProcessor().GetTriggers() {
 		configuration := trigger.GetConfig()
 
@@ -62,10 +71,59 @@ func (tr *triggersResource) GetByID(request *http.Request, id string) (restful.A
 	return nil, nil
 }
 
+// Create creates a trigger from the trigger configuration in the body and starts it
+func (tr *triggersResource) Create(request *http.Request) (string, restful.Attributes, error) {
+	triggerConfiguration, err := tr.getTriggerConfigurationFromRequest(request)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if triggerConfiguration.Name == "" {
+		return "", nil, nuclio.NewErrBadRequest("Trigger name must be provided")
+	}
+
+	triggerInstance, err := tr.getProcessor().CreateTrigger(triggerConfiguration.Name, triggerConfiguration)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "Failed to create trigger")
+	}
+
+	return tr.getTriggerAttributes(triggerInstance)
+}
+
+// Update replaces a trigger with one created from the trigger configuration in the body
+func (tr *triggersResource) Update(request *http.Request, id string) (restful.Attributes, error) {
+	triggerConfiguration, err := tr.getTriggerConfigurationFromRequest(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if triggerConfiguration.Name != "" && triggerConfiguration.Name != id {
+		return nil, nuclio.NewErrBadRequest("Trigger name is different from request id")
+	}
+
+	triggerInstance, err := tr.getProcessor().UpdateTrigger(id, triggerConfiguration)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to update trigger")
+	}
+
+	_, attributes, err := tr.getTriggerAttributes(triggerInstance)
+	return attributes, err
+}
+
+// Delete stops a trigger and removes it. if "force" is passed, the trigger does not wait for
+// in-flight events to be handled
+func (tr *triggersResource) Delete(request *http.Request, id string) error {
+	force := tr.GetURLParamBoolOrDefault(request, "force", false)
+
+	if err := tr.getProcessor().DeleteTrigger(id, force); err != nil {
+		return errors.Wrap(err, "Failed to delete trigger")
+	}
+
+	return nil
+}
+
 // GetCustomRoutes returns a list of custom routes for the resource
 func (tr *triggersResource) GetCustomRoutes() ([]restful.CustomRoute, error) {
-
-	// just for demonstration. when stats are supported, this will be wired
 	return []restful.CustomRoute{
 		{
 			Pattern:   "/{id}/stats",
@@ -78,16 +136,182 @@ func (tr *triggersResource) GetCustomRoutes() ([]restful.CustomRoute, error) {
 func (tr *triggersResource) getStatistics(request *http.Request) (*restful.CustomRouteFuncResponse, error) {
 	resourceID := chi.URLParam(request, "id")
 
+	triggerInstance := tr.getTriggerByID(resourceID)
+	if triggerInstance == nil {
+		return &restful.CustomRouteFuncResponse{
+			Single:     true,
+			StatusCode: http.StatusNotFound,
+		}, nuclio.NewErrNotFound("Trigger not found")
+	}
+
 	return &restful.CustomRouteFuncResponse{
 		ResourceType: "statistics",
 		Resources: map[string]restful.Attributes{
-			resourceID: {"stats": "example"},
+			resourceID: tr.getTriggerStatisticsAttributes(triggerInstance),
 		},
 		Single:     true,
 		StatusCode: http.StatusOK,
 	}, nil
 }
 
+func (tr *triggersResource) getTriggerByID(id string) trigger.Trigger {
+	for _, triggerInstance := range tr.getProcessor().GetTriggers() {
+		if triggerInstance.GetID() == id {
+			return triggerInstance
+		}
+	}
+
+	return nil
+}
+
+// getTriggerStatisticsAttributes returns the trigger's current statistics, along with rates derived from
+// the difference between the current statistics and the ones sampled at the start of the trigger's window
+func (tr *triggersResource) getTriggerStatisticsAttributes(triggerInstance trigger.Trigger) restful.Attributes {
+
+	// take a copy of the current statistics, so that they won't change while we're working on them
+	currentSample := &trigger.StatisticsSample{
+		TakenAt:                 time.Now(),
+		Statistics:              tr.copyTriggerStatistics(triggerInstance.GetStatistics()),
+		WorkerRuntimeStatistics: map[int]runtime.Statistics{},
+	}
+
+	var workersAttributes []restful.Attributes
+	for _, workerInstance := range triggerInstance.GetWorkers() {
+		workerStatistics := workerInstance.GetStatistics()
+		runtimeStatistics := runtime.Statistics{}
+
+		if workerRuntime := workerInstance.GetRuntime(); workerRuntime != nil {
+			runtimeStatistics = tr.copyRuntimeStatistics(workerRuntime.GetStatistics())
+		}
+
+		currentSample.WorkerRuntimeStatistics[workerInstance.GetIndex()] = runtimeStatistics
+
+		workersAttributes = append(workersAttributes, restful.Attributes{
+			"index":                     workerInstance.GetIndex(),
+			"status":                    workerInstance.GetStatus().String(),
+			"eventsHandledSuccess":      atomic.LoadUint64(&workerStatistics.EventsHandledSuccess),
+			"eventsHandledError":        atomic.LoadUint64(&workerStatistics.EventsHandledError),
+			"durationMilliSecondsSum":   runtimeStatistics.DurationMilliSecondsSum,
+			"durationMilliSecondsCount": runtimeStatistics.DurationMilliSecondsCount,
+			"durationMilliSecondsAverage": tr.getAverage(runtimeStatistics.DurationMilliSecondsSum,
+				runtimeStatistics.DurationMilliSecondsCount),
+		})
+	}
+
+	attributes := restful.Attributes{
+		"kind":                      triggerInstance.GetKind(),
+		"name":                      triggerInstance.GetName(),
+		"eventsHandledSuccessTotal": currentSample.Statistics.EventsHandledSuccessTotal,
+		"eventsHandledFailureTotal": currentSample.Statistics.EventsHandledFailureTotal,
+		"workerAllocator": tr.getAllocatorStatisticsAttributes(
+			&currentSample.Statistics.WorkerAllocatorStatistics),
+		"workers": workersAttributes,
+	}
+
+	// the window is kept on the trigger, so a replaced trigger starts over with a window of its own
+	statisticsWindow := triggerInstance.GetStatisticsWindow()
+	if statisticsWindow == nil {
+		return attributes
+	}
+
+	// rates can only be derived once the window holds an earlier sample to diff from
+	if baselineSample := statisticsWindow.Add(currentSample); baselineSample != nil {
+		attributes["rates"] = tr.getRatesAttributes(baselineSample, currentSample)
+	}
+
+	return attributes
+}
+
+func (tr *triggersResource) getRatesAttributes(baselineSample *trigger.StatisticsSample,
+	currentSample *trigger.StatisticsSample) restful.Attributes {
+
+	periodSeconds := currentSample.TakenAt.Sub(baselineSample.TakenAt).Seconds()
+	diffStatistics, diffRuntimeStatistics := currentSample.DiffFrom(baselineSample)
+	diffAllocatorStatistics := diffStatistics.WorkerAllocatorStatistics
+
+	return restful.Attributes{
+		"periodSeconds":                 periodSeconds,
+		"eventsHandledSuccessPerSecond": tr.getRate(diffStatistics.EventsHandledSuccessTotal, periodSeconds),
+		"eventsHandledFailurePerSecond": tr.getRate(diffStatistics.EventsHandledFailureTotal, periodSeconds),
+		"workerAllocationsPerSecond":    tr.getRate(diffAllocatorStatistics.WorkerAllocationCount, periodSeconds),
+		"workerAllocationTimeoutsPerSecond": tr.getRate(diffAllocatorStatistics.WorkerAllocationTimeoutTotal,
+			periodSeconds),
+		"workerAllocationWaitDurationMilliSecondsAverage": tr.getAverage(
+			diffAllocatorStatistics.WorkerAllocationWaitDurationMilliSecondsSum,
+			diffAllocatorStatistics.WorkerAllocationSuccessAfterWaitTotal),
+		"eventDurationMilliSecondsAverage": tr.getAverage(diffRuntimeStatistics.DurationMilliSecondsSum,
+			diffRuntimeStatistics.DurationMilliSecondsCount),
+	}
+}
+
+func (tr *triggersResource) getAllocatorStatisticsAttributes(
+	allocatorStatistics *worker.AllocatorStatistics) restful.Attributes {
+	return restful.Attributes{
+		"workerAllocationCount":                       allocatorStatistics.WorkerAllocationCount,
+		"workerAllocationSuccessImmediateTotal":       allocatorStatistics.WorkerAllocationSuccessImmediateTotal,
+		"workerAllocationSuccessAfterWaitTotal":       allocatorStatistics.WorkerAllocationSuccessAfterWaitTotal,
+		"workerAllocationTimeoutTotal":                allocatorStatistics.WorkerAllocationTimeoutTotal,
+		"workerAllocationWaitDurationMilliSecondsSum": allocatorStatistics.WorkerAllocationWaitDurationMilliSecondsSum,
+		"workerAllocationWorkersAvailablePercentage":  allocatorStatistics.WorkerAllocationWorkersAvailablePercentage,
+	}
+}
+
+func (tr *triggersResource) copyTriggerStatistics(statistics *trigger.Statistics) trigger.Statistics {
+
+	// diffing from an empty statistics object atomically loads all the counters
+	return statistics.DiffFrom(&trigger.Statistics{})
+}
+
+func (tr *triggersResource) copyRuntimeStatistics(statistics *runtime.Statistics) runtime.Statistics {
+	return statistics.DiffFrom(&runtime.Statistics{})
+}
+
+func (tr *triggersResource) getRate(count uint64, periodSeconds float64) float64 {
+	if periodSeconds <= 0 {
+		return 0
+	}
+
+	return float64(count) / periodSeconds
+}
+
+func (tr *triggersResource) getAverage(sum uint64, count uint64) float64 {
+	if count == 0 {
+		return 0
+	}
+
+	return float64(sum) / float64(count)
+}
+
+func (tr *triggersResource) getTriggerConfigurationFromRequest(request *http.Request) (
+	*functionconfig.Trigger, error) {
+
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read body")
+	}
+
+	triggerConfiguration := functionconfig.Trigger{}
+	if err := json.Unmarshal(body, &triggerConfiguration); err != nil {
+		return nil, nuclio.WrapErrBadRequest(errors.Wrap(err, "Failed to parse JSON body"))
+	}
+
+	if triggerConfiguration.Kind == "" {
+		return nil, nuclio.NewErrBadRequest("Trigger kind must be provided")
+	}
+
+	return &triggerConfiguration, nil
+}
+
+func (tr *triggersResource) getTriggerAttributes(triggerInstance trigger.Trigger) (
+	string, restful.Attributes, error) {
+	configuration := triggerInstance.GetConfig()
+
+	// extract the ID from the configuration (get and remove)
+	id := tr.extractIDFromConfiguration(configuration)
+
+	return id, configuration, nil
+}
+
 func (tr *triggersResource) extractIDFromConfiguration(configuration map[string]interface{}) string {
 	id := configuration["ID"].(string)
 
@@ -101,6 +325,9 @@ var triggers = &triggersResource{
 	resource: newResource("triggers", []restful.ResourceMethod{
 		restful.ResourceMethodGetList,
 		restful.ResourceMethodGetDetail,
+		restful.ResourceMethodCreate,
+		restful.ResourceMethodUpdate,
+		restful.ResourceMethodDelete,
 	}),
 }
 
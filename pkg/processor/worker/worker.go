@@ -68,27 +68,52 @@ func (w *Worker) ProcessEvent(event nuclio.Event, functionLogger logger.Logger)
 	response, err := w.runtime.ProcessEvent(event, functionLogger)
 	w.eventTime = nil
 
-	// check if there was a processing error. if so, log it
-	if err != nil {
-		atomic.AddUint64(&w.statistics.EventsHandledError, 1)
-	} else {
-		success := true
+	w.updateStatistics(response, err)
 
-		switch typedResponse := response.(type) {
-		case *nuclio.Response:
-			success = typedResponse.StatusCode < http.StatusBadRequest
-		case nuclio.Response:
-			success = typedResponse.StatusCode < http.StatusBadRequest
-		}
+	return response, err
+}
 
-		if success {
-			atomic.AddUint64(&w.statistics.EventsHandledSuccess, 1)
-		} else {
-			atomic.AddUint64(&w.statistics.EventsHandledError, 1)
+// ProcessEventBatch sends a batch of events to the associated runtime. If the runtime can't process
+// batches, the events are sent to it one by one. The returned error indicates that the batch as a whole
+// could not be processed, per-event errors are returned in the responses
+func (w *Worker) ProcessEventBatch(batch []nuclio.Event,
+	functionLogger logger.Logger) ([]*runtime.BatchEventResponse, error) {
+
+	if !w.SupportsBatching() {
+		responses := make([]*runtime.BatchEventResponse, 0, len(batch))
+		for _, event := range batch {
+			response, err := w.ProcessEvent(event, functionLogger)
+			responses = append(responses, &runtime.BatchEventResponse{
+				Response:     response,
+				ProcessError: err,
+			})
 		}
+
+		return responses, nil
 	}
 
-	return response, err
+	w.eventTime = clock.Now()
+
+	// process the entire batch at the runtime
+	responses, err := w.runtime.(runtime.BatchProcessor).ProcessBatch(batch, functionLogger)
+	w.eventTime = nil
+
+	if err != nil {
+		atomic.AddUint64(&w.statistics.EventsHandledError, uint64(len(batch)))
+		return nil, err
+	}
+
+	for _, response := range responses {
+		w.updateStatistics(response.Response, response.ProcessError)
+	}
+
+	return responses, nil
+}
+
+// SupportsBatching returns true if the underlying runtime can process a batch of events in a single round trip
+func (w *Worker) SupportsBatching() bool {
+	batchProcessor, isBatchProcessor := w.runtime.(runtime.BatchProcessor)
+	return isBatchProcessor && batchProcessor.SupportsBatching()
 }
 
 // GetStatistics returns a pointer to the statistics object. This must not be modified by the reader
@@ -180,3 +205,27 @@ func (w *Worker) Subscribe(kind controlcommunication.ControlMessageKind, channel
 func (w *Worker) Unsubscribe(kind controlcommunication.ControlMessageKind, channel chan *controlcommunication.ControlMessage) error {
 	return w.runtime.GetControlMessageBroker().Unsubscribe(kind, channel)
 }
+
+func (w *Worker) updateStatistics(response interface{}, err error) {
+
+	// check if there was a processing error
+	if err != nil {
+		atomic.AddUint64(&w.statistics.EventsHandledError, 1)
+		return
+	}
+
+	success := true
+
+	switch typedResponse := response.(type) {
+	case *nuclio.Response:
+		success = typedResponse.StatusCode < http.StatusBadRequest
+	case nuclio.Response:
+		success = typedResponse.StatusCode < http.StatusBadRequest
+	}
+
+	if success {
+		atomic.AddUint64(&w.statistics.EventsHandledSuccess, 1)
+	} else {
+		atomic.AddUint64(&w.statistics.EventsHandledError, 1)
+	}
+}
@@ -100,6 +100,20 @@ func (mr *MockRuntime) GetControlMessageBroker() controlcommunication.ControlMes
 	return nil
 }
 
+type MockBatchRuntime struct {
+	MockRuntime
+}
+
+func (mbr *MockBatchRuntime) ProcessBatch(batch []nuclio.Event,
+	functionLogger logger.Logger) ([]*runtime.BatchEventResponse, error) {
+	args := mbr.Called(batch, functionLogger)
+	return args.Get(0).([]*runtime.BatchEventResponse), args.Error(1)
+}
+
+func (mbr *MockBatchRuntime) SupportsBatching() bool {
+	return true
+}
+
 type WorkerTestSuite struct {
 	suite.Suite
 	logger logger.Logger
@@ -128,6 +142,48 @@ func (suite *WorkerTestSuite) TestProcessEvent() {
 	suite.Require().NotNil(event.GetID())
 }
 
+func (suite *WorkerTestSuite) TestProcessEventBatch() {
+	mockRuntime := MockBatchRuntime{}
+	worker, _ := NewWorker(suite.logger, 100, &mockRuntime)
+	batch := []nuclio.Event{&nuclio.AbstractEvent{}, &nuclio.AbstractEvent{}}
+
+	// the whole batch is expected to be passed to the runtime at once
+	mockRuntime.On("ProcessBatch", batch, suite.logger).Return([]*runtime.BatchEventResponse{
+		{Response: nuclio.Response{StatusCode: 200}},
+		{Response: nuclio.Response{StatusCode: 500}},
+	}, nil).Once()
+
+	responses, err := worker.ProcessEventBatch(batch, suite.logger)
+	suite.Require().NoError(err)
+	suite.Require().Len(responses, 2)
+
+	mockRuntime.AssertExpectations(suite.T())
+
+	// statistics are updated per event
+	suite.Require().Equal(uint64(1), worker.GetStatistics().EventsHandledSuccess)
+	suite.Require().Equal(uint64(1), worker.GetStatistics().EventsHandledError)
+}
+
+func (suite *WorkerTestSuite) TestProcessEventBatchWithoutBatchingSupport() {
+	mockRuntime := MockRuntime{}
+	worker, _ := NewWorker(suite.logger, 100, &mockRuntime)
+	batch := []nuclio.Event{&nuclio.AbstractEvent{}, &nuclio.AbstractEvent{}}
+
+	// the runtime doesn't support batching, so events are expected to be processed one by one
+	mockRuntime.On("ProcessEvent", mock.Anything, suite.logger).Return("response", nil).Twice()
+
+	responses, err := worker.ProcessEventBatch(batch, suite.logger)
+	suite.Require().NoError(err)
+	suite.Require().Len(responses, 2)
+
+	for _, response := range responses {
+		suite.Require().Equal("response", response.Response)
+		suite.Require().NoError(response.ProcessError)
+	}
+
+	mockRuntime.AssertExpectations(suite.T())
+}
+
 // In order for 'go test' to run this suite, we need to create
 // a normal test function and pass our suite to suite.Run
 func TestWorkerTestSuite(t *testing.T) {
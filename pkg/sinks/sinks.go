@@ -19,6 +19,7 @@ package sinks
 import (
 	// import all sinks
 	_ "github.com/nuclio/nuclio/pkg/loggersink/appinsights"
+	_ "github.com/nuclio/nuclio/pkg/loggersink/elasticsearch"
 	_ "github.com/nuclio/nuclio/pkg/loggersink/stdout"
 	_ "github.com/nuclio/nuclio/pkg/processor/metricsink/appinsights"
 	_ "github.com/nuclio/nuclio/pkg/processor/metricsink/prometheus/pull"